@@ -7,6 +7,94 @@ set -e
 
 ALLOWED_DOMAINS_FILE="${FIREWALL_CONFIG_FILE:-/home/addt/.addt/firewall/allowed-domains.txt}"
 
+# The firewall layers, in the order they are checked: each has its own
+# address and port rules and its own sets of resolved addresses
+FIREWALL_LAYERS="project global extension defaults"
+
+# allow_address adds an address, or "address,port", to the allowed sets of
+# a layer ("address@layer"; the defaults layer without one); ttl is in
+# seconds, empty for no timeout
+allow_address() {
+    local entry="$1" ttl="$2" ip port family layer=defaults
+    if [ "${entry%@*}" != "$entry" ]; then
+        layer="${entry##*@}"
+        entry="${entry%@*}"
+    fi
+    case " $FIREWALL_LAYERS " in
+        *" $layer "*) ;;
+        *) return 0 ;;
+    esac
+    ip="${entry%%,*}"
+    port=""
+    [ "$entry" != "$ip" ] && port="${entry#*,}"
+    if [[ $ip =~ ^[0-9]+\.[0-9]+\.[0-9]+\.[0-9]+$ ]]; then
+        family=4
+    elif [[ $ip =~ ^[0-9a-fA-F:]+$ ]]; then
+        family=6
+    else
+        return 0
+    fi
+    [ -z "$port" ] || [[ $port =~ ^[0-9]+$ ]] || return 0
+
+    local set element="$ip"
+    case "$family:${port:+port}" in
+        4:) set="allowed_ips_$layer" ;;
+        6:) set="allowed_ips6_$layer" ;;
+        4:port) set="allowed_ip_ports_$layer"; element="$ip . $port" ;;
+        6:port) set="allowed_ip6_ports_$layer"; element="$ip . $port" ;;
+    esac
+    local timeout=""
+    [ -n "$ttl" ] && timeout=" timeout ${ttl}s"
+
+    if command -v nft >/dev/null 2>&1; then
+        nft add element inet addt_filter "$set" "{ $element$timeout }" 2>/dev/null || true
+    elif command -v ipset >/dev/null 2>&1 && ipset list "$set" >/dev/null 2>&1; then
+        if [ -n "$port" ]; then
+            ipset add -exist "$set" "$ip,tcp:$port" 2>/dev/null || true
+            ipset add -exist "$set" "$ip,udp:$port" 2>/dev/null || true
+        else
+            ipset add -exist "$set" "$ip" 2>/dev/null || true
+        fi
+    else
+        local ipt=iptables
+        [ "$family" = 6 ] && ipt=ip6tables
+        command -v "$ipt" >/dev/null 2>&1 || return 0
+        local chain="addt_$layer"
+        if [ -n "$port" ]; then
+            for proto in tcp udp; do
+                $ipt -C "$chain" -d "$ip" -p "$proto" --dport "$port" -j ACCEPT 2>/dev/null ||
+                    $ipt -A "$chain" -d "$ip" -p "$proto" --dport "$port" -j ACCEPT 2>/dev/null || true
+            done
+        else
+            $ipt -C "$chain" -d "$ip" -j ACCEPT 2>/dev/null || $ipt -A "$chain" -d "$ip" -j ACCEPT 2>/dev/null || true
+        fi
+    fi
+}
+
+# Called from the host as "init-firewall.sh --allow <ttl-seconds> <ip>[,<port>]@<layer>..."
+# when an allowed name resolves through the addt DNS resolver
+if [ "$1" = "--allow" ]; then
+    TTL="$2"
+    shift 2
+    for entry in "$@"; do
+        allow_address "$entry" "$TTL"
+    done
+    exit 0
+fi
+
+# Called from the host as "init-firewall.sh --reload" by 'addt firewall
+# apply', with the session's settings as KEY=VALUE lines on stdin: the
+# ruleset is rendered again from them, without restarting the agent
+RELOAD=false
+if [ "$1" = "--reload" ]; then
+    RELOAD=true
+    while IFS= read -r line || [ -n "$line" ]; do
+        case "$line" in
+            ADDT_*=*) export "${line?}" ;;
+        esac
+    done
+fi
+
 # Check if firewall is disabled
 if [ "${ADDT_FIREWALL_MODE}" = "off" ] || [ "${ADDT_FIREWALL_MODE}" = "disabled" ]; then
     echo "Firewall: Disabled by configuration"
@@ -32,12 +120,100 @@ fi
 
 # Create allowed IPs storage
 ALLOWED_IPS=""
+DNS_LOCKED=false
+
+# With the host-side egress proxy (ADDT_EGRESS_PROXY=host:port), the proxy
+# is the only allowed destination; it checks every connection's hostname
+# against the firewall rules, so no domains are resolved here
+PROXY_IP=""
+PROXY_PORT=""
+if [ -n "${ADDT_EGRESS_PROXY}" ]; then
+    PROXY_HOST="${ADDT_EGRESS_PROXY%:*}"
+    PROXY_PORT="${ADDT_EGRESS_PROXY##*:}"
+    PROXY_IP=$(getent ahostsv4 "$PROXY_HOST" 2>/dev/null | awk 'NR==1 {print $1}')
+    if [ -z "$PROXY_IP" ]; then
+        echo "Firewall: Warning - cannot resolve egress proxy host $PROXY_HOST, falling back to IP allowlist"
+    fi
+fi
 
-# Read domains from config file
-if [ -f "$ALLOWED_DOMAINS_FILE" ]; then
-    echo "Firewall: Loading allowed domains from $ALLOWED_DOMAINS_FILE"
+# With the host-side DNS resolver (ADDT_DNS_RESOLVER=host:port), all DNS
+# traffic is redirected to it; it only answers for allowed names
+DNS_IP=""
+DNS_PORT=""
+if [ -n "${ADDT_DNS_RESOLVER}" ]; then
+    DNS_HOST="${ADDT_DNS_RESOLVER%:*}"
+    DNS_PORT="${ADDT_DNS_RESOLVER##*:}"
+    DNS_IP=$(getent ahostsv4 "$DNS_HOST" 2>/dev/null | awk 'NR==1 {print $1}')
+    if [ -z "$DNS_IP" ]; then
+        echo "Firewall: Warning - cannot resolve DNS resolver host $DNS_HOST, blocking DNS"
+    fi
+fi
+
+# With host services (ADDT_HOST_SERVICES=<name>=<address>:<port>:<forwarder
+# port>,...), the host-side forwarder's port for each is allowed, so only
+# the services named in ports.host_services are reachable on the host
+HOST_SERVICES_IP=""
+HOST_SERVICE_PORTS=""
+if [ -n "${ADDT_HOST_SERVICES}" ]; then
+    HOST_SERVICES_HOST="${ADDT_HOST_SERVICES_HOST:-host.docker.internal}"
+    HOST_SERVICES_IP=$(getent ahostsv4 "$HOST_SERVICES_HOST" 2>/dev/null | awk 'NR==1 {print $1}')
+    IFS=',' read -ra HOST_SERVICE_ENTRIES <<< "$ADDT_HOST_SERVICES"
+    for entry in "${HOST_SERVICE_ENTRIES[@]}"; do
+        HOST_SERVICE_PORTS="$HOST_SERVICE_PORTS ${entry##*:}"
+    done
+    if [ -z "$HOST_SERVICES_IP" ]; then
+        echo "Firewall: Warning - cannot resolve host services host $HOST_SERVICES_HOST, blocking host services"
+    fi
+fi
+
+# Offline (ADDT_OFFLINE_MIRROR=host:port), the host-side package mirror is
+# the only allowed destination: no domains are resolved and DNS is blocked.
+# A mirror reached through a mounted socket (unix:<path>) needs no rule.
+MIRROR_IP=""
+MIRROR_PORT=""
+if [ -n "${ADDT_OFFLINE_MIRROR}" ] && [ "${ADDT_OFFLINE_MIRROR#unix:}" = "${ADDT_OFFLINE_MIRROR}" ]; then
+    MIRROR_HOST="${ADDT_OFFLINE_MIRROR%:*}"
+    MIRROR_PORT="${ADDT_OFFLINE_MIRROR##*:}"
+    MIRROR_IP=$(getent ahostsv4 "$MIRROR_HOST" 2>/dev/null | awk 'NR==1 {print $1}')
+    if [ -z "$MIRROR_IP" ]; then
+        echo "Firewall: Warning - cannot resolve package mirror host $MIRROR_HOST, blocking all traffic"
+    fi
+fi
 
-    # Read domains, filter comments and empty lines
+# With the API proxy (ADDT_API_PROXY=host:port), its port on the host is
+# allowed: the tools' API keys stay there, and the entrypoint bridges
+# 127.0.0.1:4874 to it
+API_PROXY_IP=""
+API_PROXY_PORT=""
+if [ -n "${ADDT_API_PROXY}" ]; then
+    API_PROXY_HOST="${ADDT_API_PROXY%:*}"
+    API_PROXY_PORT="${ADDT_API_PROXY##*:}"
+    API_PROXY_IP=$(getent ahostsv4 "$API_PROXY_HOST" 2>/dev/null | awk 'NR==1 {print $1}')
+    if [ -z "$API_PROXY_IP" ]; then
+        echo "Firewall: Warning - cannot resolve API proxy host $API_PROXY_HOST, blocking the API proxy"
+    fi
+fi
+
+# With the git credential helper (ADDT_GIT_CREDENTIALS=host:port), its port
+# on the host is allowed; the entrypoint bridges 127.0.0.1:4875 to it
+GIT_CREDENTIALS_IP=""
+GIT_CREDENTIALS_PORT=""
+if [ -n "${ADDT_GIT_CREDENTIALS}" ]; then
+    GIT_CREDENTIALS_HOST="${ADDT_GIT_CREDENTIALS%:*}"
+    GIT_CREDENTIALS_PORT="${ADDT_GIT_CREDENTIALS##*:}"
+    GIT_CREDENTIALS_IP=$(getent ahostsv4 "$GIT_CREDENTIALS_HOST" 2>/dev/null | awk 'NR==1 {print $1}')
+    if [ -z "$GIT_CREDENTIALS_IP" ]; then
+        echo "Firewall: Warning - cannot resolve git credential helper host $GIT_CREDENTIALS_HOST, blocking it"
+    fi
+fi
+
+# resolve_allowed_domains resolves the names in a domains file to the
+# allowed addresses (A and AAAA) of the defaults layer. A "host:port" line
+# allows only that port; wildcard, network and negated rules can't be
+# resolved here and are skipped (network rules arrive through
+# ADDT_FIREWALL_RULES instead).
+resolve_allowed_domains() {
+    local domain host port ips ip
     while IFS= read -r domain || [ -n "$domain" ]; do
         # Skip comments and empty lines
         [[ "$domain" =~ ^[[:space:]]*# ]] && continue
@@ -45,29 +221,44 @@ if [ -f "$ALLOWED_DOMAINS_FILE" ]; then
 
         # Trim whitespace
         domain=$(echo "$domain" | xargs)
+        [[ "$domain" == *[\*/!\[]* ]] && continue
+        host="${domain%%:*}"
+        port=""
+        [ "$host" != "$domain" ] && port="${domain#*:}"
 
         # Resolve domain to IPs
         echo "  Resolving: $domain"
 
         # Try dig first (preferred)
         if command -v dig >/dev/null 2>&1; then
-            IPS=$(dig +short "$domain" A | grep -E '^[0-9]+\.' || true)
+            ips="$(dig +short "$host" A | grep -E '^[0-9]+\.' || true) $(dig +short "$host" AAAA | grep -E '^[0-9a-fA-F]*:' || true)"
         # Fallback to host
         elif command -v host >/dev/null 2>&1; then
-            IPS=$(host "$domain" | grep "has address" | awk '{print $4}' || true)
+            ips=$(host "$host" | awk '/has (IPv6 )?address/ {print $NF}' || true)
         else
             echo "  Warning: No DNS tools available (dig/host)"
             continue
         fi
 
         # Add IPs to list
-        for ip in $IPS; do
-            if [[ $ip =~ ^[0-9]+\.[0-9]+\.[0-9]+\.[0-9]+$ ]]; then
-                ALLOWED_IPS="$ALLOWED_IPS $ip"
-                echo "    Added: $ip"
+        for ip in $ips; do
+            if [ -n "$port" ]; then
+                ip="$ip,$port"
             fi
+            ALLOWED_IPS="$ALLOWED_IPS $ip"
+            echo "    Added: $ip"
         done
-    done < "$ALLOWED_DOMAINS_FILE"
+    done < "$1"
+}
+
+# Read domains from config file
+if [ -n "$PROXY_IP" ]; then
+    echo "Firewall: Forcing traffic through egress proxy at $PROXY_IP:$PROXY_PORT"
+elif [ -n "${ADDT_OFFLINE_MIRROR}" ]; then
+    echo "Firewall: Offline, allowing only the package mirror"
+elif [ -f "$ALLOWED_DOMAINS_FILE" ]; then
+    echo "Firewall: Loading allowed domains from $ALLOWED_DOMAINS_FILE"
+    resolve_allowed_domains "$ALLOWED_DOMAINS_FILE"
 else
     echo "Firewall: Warning - No allowed domains file found at $ALLOWED_DOMAINS_FILE"
     echo "Firewall: Creating default configuration..."
@@ -117,125 +308,341 @@ EOF
 
     # Re-read the freshly created file to resolve domains
     echo "Firewall: Resolving default domains..."
-    while IFS= read -r domain || [ -n "$domain" ]; do
-        [[ "$domain" =~ ^[[:space:]]*# ]] && continue
-        [[ -z "$domain" ]] && continue
-        domain=$(echo "$domain" | xargs)
-        echo "  Resolving: $domain"
-        if command -v dig >/dev/null 2>&1; then
-            IPS_RESOLVED=$(dig +short "$domain" A | grep -E '^[0-9]+\.' || true)
-        elif command -v host >/dev/null 2>&1; then
-            IPS_RESOLVED=$(host "$domain" | grep "has address" | awk '{print $4}' || true)
+    resolve_allowed_domains "$ALLOWED_DOMAINS_FILE"
+fi
+
+# nft_rule adds a rule to the output chain of the nftables ruleset being
+# built, which is applied in one transaction
+NFT_RULES=""
+nft_rule() {
+    NFT_RULES="$NFT_RULES
+add rule inet addt_filter output $*"
+}
+
+# nft_address_rule adds one of the host's address and port rules
+# (ADDT_FIREWALL_RULES entries: "<layer> <accept|drop> <proto> <port>
+# <network>", "-" for any)
+nft_address_rule() {
+    local verdict="$1" proto="$2" port="$3" network="$4" match=""
+    case "$network" in
+        -) ;;
+        *:*) match="ip6 daddr $network" ;;
+        *) match="ip daddr $network" ;;
+    esac
+    if [ "$port" != "-" ]; then
+        if [ "$proto" = "-" ]; then
+            match="$match meta l4proto { tcp, udp } th dport $port"
         else
+            match="$match $proto dport $port"
+        fi
+    fi
+    # shellcheck disable=SC2086
+    nft_rule $match "$verdict"
+}
+
+# ipt_address_rule is nft_address_rule for iptables and ip6tables, adding
+# to a layer's chain
+ipt_address_rule() {
+    local chain="$1" target=ACCEPT proto="$3" port="$4" network="$5" ipt dest p
+    [ "$2" = "drop" ] && target=DROP
+    for ipt in iptables ip6tables; do
+        command -v "$ipt" >/dev/null 2>&1 || continue
+        case "$ipt:$network" in
+            iptables:*:*|ip6tables:[0-9]*.*) continue ;;
+        esac
+        dest=""
+        [ "$network" != "-" ] && dest="-d $network"
+        if [ "$port" = "-" ]; then
+            # shellcheck disable=SC2086
+            $ipt -A "$chain" $dest -j "$target" 2>/dev/null || true
             continue
         fi
-        for ip in $IPS_RESOLVED; do
-            if [[ $ip =~ ^[0-9]+\.[0-9]+\.[0-9]+\.[0-9]+$ ]]; then
-                ALLOWED_IPS="$ALLOWED_IPS $ip"
-                echo "    Added: $ip"
+        for p in tcp udp; do
+            if [ "$proto" = "-" ] || [ "$proto" = "$p" ]; then
+                # shellcheck disable=SC2086
+                $ipt -A "$chain" $dest -p "$p" --dport "$port" -j "$target" 2>/dev/null || true
             fi
         done
-    done < "$ALLOWED_DOMAINS_FILE"
-fi
+    done
+}
+
+# Address and port rules from the firewall config, in precedence order
+# within each layer
+ADDRESS_RULES=$(echo "${ADDT_FIREWALL_RULES}" | tr ',' '\n')
 
 # Configure firewall rules
 if [ "$USE_NFTABLES" = true ]; then
     echo "Firewall: Configuring nftables rules..."
 
-    # Flush existing rules
-    nft flush ruleset 2>/dev/null || true
+    # Start from an empty ruleset; a reload only replaces addt's own
+    # tables, each in one transaction, so the old rules stay in force
+    # until the new ones are complete
+    if [ "$RELOAD" != true ]; then
+        nft flush ruleset 2>/dev/null || true
+    fi
+
+    # Redirect every DNS query to the addt resolver, in a table of its own
+    # since nat support is optional
+    if [ -n "$DNS_IP" ]; then
+        if nft -f - 2>/dev/null <<EOF
+add table ip addt_nat
+flush table ip addt_nat
+add chain ip addt_nat output { type nat hook output priority -100; }
+add rule ip addt_nat output udp dport 53 dnat to $DNS_IP:$DNS_PORT
+add rule ip addt_nat output tcp dport 53 dnat to $DNS_IP:$DNS_PORT
+EOF
+        then
+            DNS_LOCKED=true
+        else
+            echo "Firewall: Warning - cannot redirect DNS (no nat support), blocking DNS"
+        fi
+    else
+        nft delete table ip addt_nat 2>/dev/null || true
+    fi
 
-    # Create table and chain
-    nft add table inet addt_filter 2>/dev/null || true
-    nft add chain inet addt_filter output "{ type filter hook output priority 0; policy drop; }" 2>/dev/null || true
+    # Table, chain and the allowed IP sets (resolved IPs are added with a
+    # timeout as the DNS resolver answers; the _ports sets hold addresses
+    # allowed on one port only). Flushing the table empties its chain and
+    # sets in the same transaction that fills them again.
+    NFT_RULES="add table inet addt_filter
+flush table inet addt_filter
+add chain inet addt_filter output { type filter hook output priority 0; policy drop; }"
+    for layer in $FIREWALL_LAYERS; do
+        NFT_RULES="$NFT_RULES
+add set inet addt_filter allowed_ips_$layer { type ipv4_addr; flags timeout; }
+add set inet addt_filter allowed_ips6_$layer { type ipv6_addr; flags timeout; }
+add set inet addt_filter allowed_ip_ports_$layer { type ipv4_addr . inet_service; flags timeout; }
+add set inet addt_filter allowed_ip6_ports_$layer { type ipv6_addr . inet_service; flags timeout; }"
+    done
 
     # Allow loopback
-    nft add rule inet addt_filter output oifname "lo" accept
+    nft_rule oifname "lo" accept
 
     # Allow established/related connections
-    nft add rule inet addt_filter output ct state established,related accept
+    nft_rule ct state established,related accept
+
+    # Allow DNS only to the addt resolver
+    if [ "$DNS_LOCKED" = true ]; then
+        nft_rule ip daddr "$DNS_IP" udp dport "$DNS_PORT" accept
+        nft_rule ip daddr "$DNS_IP" tcp dport "$DNS_PORT" accept
+    elif [ -z "${ADDT_DNS_RESOLVER}" ] && [ -z "${ADDT_OFFLINE_MIRROR}" ]; then
+        nft_rule udp dport 53 accept
+        nft_rule tcp dport 53 accept
+    fi
 
-    # Allow DNS
-    nft add rule inet addt_filter output udp dport 53 accept
-    nft add rule inet addt_filter output tcp dport 53 accept
+    # Allow the egress proxy
+    if [ -n "$PROXY_IP" ]; then
+        nft_rule ip daddr "$PROXY_IP" tcp dport "$PROXY_PORT" accept
+    fi
 
-    # Allow whitelisted IPs
-    for ip in $ALLOWED_IPS; do
-        nft add rule inet addt_filter output ip daddr "$ip" accept 2>/dev/null || true
+    # Allow the package mirror
+    if [ -n "$MIRROR_IP" ]; then
+        nft_rule ip daddr "$MIRROR_IP" tcp dport "$MIRROR_PORT" accept
+    fi
+
+    # Allow the API proxy
+    if [ -n "$API_PROXY_IP" ]; then
+        nft_rule ip daddr "$API_PROXY_IP" tcp dport "$API_PROXY_PORT" accept
+    fi
+
+    # Allow the git credential helper
+    if [ -n "$GIT_CREDENTIALS_IP" ]; then
+        nft_rule ip daddr "$GIT_CREDENTIALS_IP" tcp dport "$GIT_CREDENTIALS_PORT" accept
+    fi
+
+    # Allow the host service forwarder
+    if [ -n "$HOST_SERVICES_IP" ]; then
+        for port in $HOST_SERVICE_PORTS; do
+            nft_rule ip daddr "$HOST_SERVICES_IP" tcp dport "$port" accept
+        done
+    fi
+
+    # Each layer's address and port rules, then the addresses its allowed
+    # names resolved to, so the first layer with a match decides
+    for layer in $FIREWALL_LAYERS; do
+        while read -r rule_layer verdict proto port network; do
+            [ "$rule_layer" = "$layer" ] && nft_address_rule "$verdict" "$proto" "$port" "$network"
+        done <<< "$ADDRESS_RULES"
+        nft_rule ip daddr "@allowed_ips_$layer" accept
+        nft_rule ip6 daddr "@allowed_ips6_$layer" accept
+        nft_rule ip daddr . th dport "@allowed_ip_ports_$layer" accept
+        nft_rule ip6 daddr . th dport "@allowed_ip6_ports_$layer" accept
     done
 
     # Log and handle based on mode
     if [ "${ADDT_FIREWALL_MODE}" = "strict" ] || [ "${ADDT_FIREWALL_MODE}" = "enabled" ]; then
-        nft add rule inet addt_filter output log prefix \"ADDT-FIREWALL-BLOCKED: \" level warn
+        nft_rule log prefix \"ADDT-FIREWALL-BLOCKED: \" level warn
         echo "Firewall: Strict mode enabled - blocking all non-whitelisted traffic"
     elif [ "${ADDT_FIREWALL_MODE}" = "permissive" ]; then
-        nft add rule inet addt_filter output log prefix \"ADDT-FIREWALL-WOULD-BLOCK: \" level warn
-        nft add rule inet addt_filter output accept
+        nft_rule log prefix \"ADDT-FIREWALL-WOULD-BLOCK: \" level warn
+        nft_rule accept
         echo "Firewall: Permissive mode enabled - logging but allowing all traffic"
     else
         # Default to strict
-        nft add rule inet addt_filter output log prefix \"ADDT-FIREWALL-BLOCKED: \" level warn
+        nft_rule log prefix \"ADDT-FIREWALL-BLOCKED: \" level warn
         echo "Firewall: Default strict mode enabled"
     fi
 
+    if ! echo "$NFT_RULES" | nft -f -; then
+        echo "Firewall: Error - cannot apply the nftables rules"
+        exit 1
+    fi
+
+    # Allow whitelisted IPs
+    for ip in $ALLOWED_IPS; do
+        allow_address "$ip" ""
+    done
+
 elif [ "$USE_IPTABLES" = true ]; then
     echo "Firewall: Configuring iptables rules..."
 
-    # Create ipset for allowed IPs (if available)
-    if command -v ipset >/dev/null 2>&1; then
-        ipset create allowed_ips hash:ip hashsize 4096 maxelem 65536 2>/dev/null || true
+    # ip6tables gets the same rules where it is available, so IPv6 isn't
+    # left open
+    IPTABLES="iptables"
+    if command -v ip6tables >/dev/null 2>&1; then
+        IPTABLES="iptables ip6tables"
+    fi
 
-        # Add IPs to ipset
-        for ip in $ALLOWED_IPS; do
-            ipset add allowed_ips "$ip" 2>/dev/null || true
+    # Create ipsets for allowed IPs (if available)
+    if command -v ipset >/dev/null 2>&1; then
+        for layer in $FIREWALL_LAYERS; do
+            ipset create "allowed_ips_$layer" hash:ip hashsize 4096 maxelem 65536 2>/dev/null || true
+            ipset create "allowed_ips6_$layer" hash:ip family inet6 hashsize 4096 maxelem 65536 2>/dev/null || true
+            ipset create "allowed_ip_ports_$layer" hash:ip,port hashsize 4096 maxelem 65536 2>/dev/null || true
+            ipset create "allowed_ip6_ports_$layer" hash:ip,port family inet6 hashsize 4096 maxelem 65536 2>/dev/null || true
+            # A reload starts from empty sets, like nftables
+            for set in allowed_ips allowed_ips6 allowed_ip_ports allowed_ip6_ports; do
+                ipset flush "${set}_$layer" 2>/dev/null || true
+            done
         done
-
         USE_IPSET=true
     else
         USE_IPSET=false
     fi
 
-    # Flush existing rules
-    iptables -F OUTPUT 2>/dev/null || true
+    # Flush existing rules, with an empty chain for each layer. The policy
+    # drops first, so a reload never leaves egress open while the chain is
+    # rebuilt; the rebuilt chain ends in its own verdict.
+    for ipt in $IPTABLES; do
+        $ipt -P OUTPUT DROP
+        $ipt -F OUTPUT 2>/dev/null || true
+        for layer in $FIREWALL_LAYERS; do
+            $ipt -N "addt_$layer" 2>/dev/null || $ipt -F "addt_$layer" 2>/dev/null || true
+        done
 
-    # Allow loopback
-    iptables -A OUTPUT -o lo -j ACCEPT
+        # Allow loopback
+        $ipt -A OUTPUT -o lo -j ACCEPT
 
-    # Allow established/related connections
-    iptables -A OUTPUT -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT
+        # Allow established/related connections
+        $ipt -A OUTPUT -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT
+    done
 
-    # Allow DNS (needed for resolution)
-    iptables -A OUTPUT -p udp --dport 53 -j ACCEPT
-    iptables -A OUTPUT -p tcp --dport 53 -j ACCEPT
+    # Allow DNS only to the addt resolver: redirect every query to it
+    iptables -t nat -F OUTPUT 2>/dev/null || true
+    if [ -n "$DNS_IP" ]; then
+        if iptables -t nat -A OUTPUT -p udp --dport 53 -j DNAT --to-destination "$DNS_IP:$DNS_PORT" 2>/dev/null &&
+            iptables -t nat -A OUTPUT -p tcp --dport 53 -j DNAT --to-destination "$DNS_IP:$DNS_PORT" 2>/dev/null; then
+            iptables -A OUTPUT -d "$DNS_IP" -p udp --dport "$DNS_PORT" -j ACCEPT
+            iptables -A OUTPUT -d "$DNS_IP" -p tcp --dport "$DNS_PORT" -j ACCEPT
+            DNS_LOCKED=true
+        else
+            echo "Firewall: Warning - cannot redirect DNS (no nat support), blocking DNS"
+        fi
+    elif [ -z "${ADDT_DNS_RESOLVER}" ] && [ -z "${ADDT_OFFLINE_MIRROR}" ]; then
+        for ipt in $IPTABLES; do
+            $ipt -A OUTPUT -p udp --dport 53 -j ACCEPT
+            $ipt -A OUTPUT -p tcp --dport 53 -j ACCEPT
+        done
+    fi
 
-    # Allow traffic to whitelisted IPs
-    if [ "$USE_IPSET" = true ]; then
-        iptables -A OUTPUT -m set --match-set allowed_ips dst -j ACCEPT
-    else
-        # Fallback: add individual rules for each IP
-        for ip in $ALLOWED_IPS; do
-            iptables -A OUTPUT -d "$ip" -j ACCEPT 2>/dev/null || true
+    # Allow the egress proxy
+    if [ -n "$PROXY_IP" ]; then
+        iptables -A OUTPUT -d "$PROXY_IP" -p tcp --dport "$PROXY_PORT" -j ACCEPT
+    fi
+
+    # Allow the package mirror
+    if [ -n "$MIRROR_IP" ]; then
+        iptables -A OUTPUT -d "$MIRROR_IP" -p tcp --dport "$MIRROR_PORT" -j ACCEPT
+    fi
+
+    # Allow the API proxy
+    if [ -n "$API_PROXY_IP" ]; then
+        iptables -A OUTPUT -d "$API_PROXY_IP" -p tcp --dport "$API_PROXY_PORT" -j ACCEPT
+    fi
+
+    # Allow the git credential helper
+    if [ -n "$GIT_CREDENTIALS_IP" ]; then
+        iptables -A OUTPUT -d "$GIT_CREDENTIALS_IP" -p tcp --dport "$GIT_CREDENTIALS_PORT" -j ACCEPT
+    fi
+
+    # Allow the host service forwarder
+    if [ -n "$HOST_SERVICES_IP" ]; then
+        for port in $HOST_SERVICE_PORTS; do
+            iptables -A OUTPUT -d "$HOST_SERVICES_IP" -p tcp --dport "$port" -j ACCEPT
         done
     fi
 
+    # Each layer's chain holds its address and port rules, then the
+    # addresses its allowed names resolved to; the chains are checked in
+    # order, so the first layer with a match decides
+    for layer in $FIREWALL_LAYERS; do
+        while read -r rule_layer verdict proto port network; do
+            [ "$rule_layer" = "$layer" ] && ipt_address_rule "addt_$layer" "$verdict" "$proto" "$port" "$network"
+        done <<< "$ADDRESS_RULES"
+        if [ "$USE_IPSET" = true ]; then
+            iptables -A "addt_$layer" -m set --match-set "allowed_ips_$layer" dst -j ACCEPT
+            iptables -A "addt_$layer" -m set --match-set "allowed_ip_ports_$layer" dst,dst -j ACCEPT
+            if command -v ip6tables >/dev/null 2>&1; then
+                ip6tables -A "addt_$layer" -m set --match-set "allowed_ips6_$layer" dst -j ACCEPT
+                ip6tables -A "addt_$layer" -m set --match-set "allowed_ip6_ports_$layer" dst,dst -j ACCEPT
+            fi
+        fi
+        for ipt in $IPTABLES; do
+            $ipt -A OUTPUT -j "addt_$layer"
+        done
+    done
+    # Without ipset, allow_address adds one rule per address to its
+    # layer's chain
+    for ip in $ALLOWED_IPS; do
+        allow_address "$ip" ""
+    done
+
     # Log and drop/accept based on mode
+    for ipt in $IPTABLES; do
+        if [ "${ADDT_FIREWALL_MODE}" = "permissive" ]; then
+            $ipt -A OUTPUT -j LOG --log-prefix "ADDT-FIREWALL-WOULD-BLOCK: " --log-level 4
+            $ipt -A OUTPUT -j ACCEPT
+        else
+            $ipt -A OUTPUT -j LOG --log-prefix "ADDT-FIREWALL-BLOCKED: " --log-level 4
+            $ipt -A OUTPUT -j DROP
+        fi
+    done
     if [ "${ADDT_FIREWALL_MODE}" = "strict" ] || [ "${ADDT_FIREWALL_MODE}" = "enabled" ]; then
-        iptables -A OUTPUT -j LOG --log-prefix "ADDT-FIREWALL-BLOCKED: " --log-level 4
-        iptables -A OUTPUT -j DROP
         echo "Firewall: Strict mode enabled - blocking all non-whitelisted traffic"
     elif [ "${ADDT_FIREWALL_MODE}" = "permissive" ]; then
-        iptables -A OUTPUT -j LOG --log-prefix "ADDT-FIREWALL-WOULD-BLOCK: " --log-level 4
-        iptables -A OUTPUT -j ACCEPT
         echo "Firewall: Permissive mode enabled - logging but allowing all traffic"
     else
         # Default to strict
-        iptables -A OUTPUT -j LOG --log-prefix "ADDT-FIREWALL-BLOCKED: " --log-level 4
-        iptables -A OUTPUT -j DROP
         echo "Firewall: Default strict mode enabled"
     fi
 fi
 
+# Point the resolver config at the addt resolver, so queries don't start
+# out to a loopback stub that can't be redirected off the host
+if [ "$DNS_LOCKED" = true ]; then
+    { grep -v '^nameserver' /etc/resolv.conf 2>/dev/null; echo "nameserver $DNS_IP"; } > /tmp/resolv.conf.addt &&
+        cat /tmp/resolv.conf.addt > /etc/resolv.conf 2>/dev/null || true
+    rm -f /tmp/resolv.conf.addt
+    echo "Firewall: DNS only through the addt resolver at $DNS_IP:$DNS_PORT"
+fi
+
 # Show summary
 IP_COUNT=$(echo "$ALLOWED_IPS" | wc -w)
-echo "Firewall: Initialized with $IP_COUNT whitelisted IPs"
+if [ -n "$PROXY_IP" ]; then
+    echo "Firewall: Initialized, egress only through the proxy"
+elif [ -n "${ADDT_OFFLINE_MIRROR}" ]; then
+    echo "Firewall: Initialized offline, egress only to the package mirror"
+else
+    echo "Firewall: Initialized with $IP_COUNT whitelisted IPs"
+fi
 echo "Firewall: Mode: ${ADDT_FIREWALL_MODE:-strict}"
@@ -63,6 +63,11 @@ var DaytonaDockerfile []byte
 //go:embed daytona/daytona-entrypoint.sh
 var DaytonaEntrypoint []byte
 
+// Sandbox provider assets (extensions are installed with docker/install.sh)
+//
+//go:embed sandbox/sandbox-entrypoint.sh
+var SandboxEntrypoint []byte
+
 // Security assets
 //
 //go:embed seccomp/restrictive.json
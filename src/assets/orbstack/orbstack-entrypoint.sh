@@ -36,6 +36,16 @@ if [ "$(id -u)" = "0" ]; then
         /usr/local/bin/init-firewall.sh
     fi
 
+    # Trust the egress proxy's CA when it intercepts TLS
+    if [ -n "${ADDT_CA_CERT}" ] && [ -f "${ADDT_CA_CERT}" ]; then
+        debug_log "Installing egress proxy CA from ${ADDT_CA_CERT}"
+        mkdir -p /usr/local/share/ca-certificates
+        cp "${ADDT_CA_CERT}" /usr/local/share/ca-certificates/addt-ca.crt
+        if ! update-ca-certificates >/dev/null 2>&1; then
+            cat "${ADDT_CA_CERT}" >> /etc/ssl/certs/ca-certificates.crt
+        fi
+    fi
+
     # Start Docker daemon if in DinD mode
     if [ "$ADDT_DOCKER_DIND_ENABLE" = "true" ]; then
         debug_log "DinD mode enabled, starting Docker daemon (as root)"
@@ -84,6 +94,72 @@ if [ -f "$HOME/.gitconfig.host" ]; then
     debug_log "Copied .gitconfig.host to .gitconfig"
 fi
 
+# Bridge host services (ports.host_services): <name>.host.addt resolves to
+# a loopback address where socat forwards the service's port to the
+# host-side forwarder. A bridge left from an earlier session of a persistent
+# container still holds its address, and starting another one fails quietly.
+if [ -n "$ADDT_HOST_SERVICES" ]; then
+    if command -v socat >/dev/null 2>&1; then
+        IFS=',' read -ra HOST_SERVICE_ENTRIES <<< "$ADDT_HOST_SERVICES"
+        for entry in "${HOST_SERVICE_ENTRIES[@]}"; do
+            # <name>=<address>:<port>:<forwarder port>
+            HS_NAME="${entry%%=*}"
+            IFS=':' read -r HS_ADDR HS_PORT HS_FORWARD <<< "${entry#*=}"
+            debug_log "Bridging $HS_NAME.host.addt:$HS_PORT ($HS_ADDR) to ${ADDT_HOST_SERVICES_HOST:-host.docker.internal}:$HS_FORWARD"
+            setsid socat TCP-LISTEN:"$HS_PORT",bind="$HS_ADDR",fork,reuseaddr \
+                  TCP:"${ADDT_HOST_SERVICES_HOST:-host.docker.internal}":"$HS_FORWARD" 2>/dev/null &
+        done
+    else
+        echo "Warning: socat not found, host services unavailable"
+    fi
+fi
+
+# Bridge the package mirror (offline mode) to 127.0.0.1:4873, where the
+# npm, pip, uv and go registry settings point. The mirror is on the host
+# (ADDT_OFFLINE_MIRROR=host:port) or behind a mounted socket (unix:<path>).
+if [ -n "$ADDT_OFFLINE_MIRROR" ]; then
+    if command -v socat >/dev/null 2>&1; then
+        if [ "${ADDT_OFFLINE_MIRROR#unix:}" != "$ADDT_OFFLINE_MIRROR" ]; then
+            MIRROR_TARGET="UNIX-CONNECT:${ADDT_OFFLINE_MIRROR#unix:}"
+        else
+            MIRROR_TARGET="TCP:$ADDT_OFFLINE_MIRROR"
+        fi
+        debug_log "Bridging package mirror 127.0.0.1:4873 to $MIRROR_TARGET"
+        setsid socat TCP-LISTEN:4873,bind=127.0.0.1,fork,reuseaddr "$MIRROR_TARGET" 2>/dev/null &
+    else
+        echo "Warning: socat not found, package mirror unavailable"
+    fi
+fi
+
+# Bridge the API proxy (security.api_proxy) to 127.0.0.1:4874, where the
+# tools' base URLs point; it adds the API keys the container doesn't have
+if [ -n "$ADDT_API_PROXY" ]; then
+    if command -v socat >/dev/null 2>&1; then
+        debug_log "Bridging API proxy 127.0.0.1:4874 to $ADDT_API_PROXY"
+        setsid socat TCP-LISTEN:4874,bind=127.0.0.1,fork,reuseaddr TCP:"$ADDT_API_PROXY" 2>/dev/null &
+    else
+        echo "Warning: socat not found, API proxy unavailable"
+    fi
+fi
+
+# Serve GitHub credentials from the host (github.scope_token): git asks the
+# host-side credential helper, bridged to 127.0.0.1:4875, which answers only
+# for the repos the session may use. GH_TOKEN isn't in the container.
+if [ -n "$ADDT_GIT_CREDENTIALS" ]; then
+    if command -v socat >/dev/null 2>&1; then
+        debug_log "Bridging git credential helper 127.0.0.1:4875 to $ADDT_GIT_CREDENTIALS"
+        setsid socat TCP-LISTEN:4875,bind=127.0.0.1,fork,reuseaddr TCP:"$ADDT_GIT_CREDENTIALS" 2>/dev/null &
+        # Only this helper answers for github.com, and it's told the repo
+        git config --global --replace-all credential.https://github.com.helper ''
+        git config --global --add credential.https://github.com.helper \
+            "!f() { test \"\$1\" = get || exit 0; { echo token=$ADDT_GIT_CREDENTIAL_TOKEN; cat; } | socat -t 30 - TCP:127.0.0.1:4875; }; f"
+        git config --global credential.https://github.com.useHttpPath true
+    else
+        echo "Warning: socat not found, git credentials unavailable"
+    fi
+    unset ADDT_GIT_CREDENTIAL_TOKEN
+fi
+
 # Set up SSH agent proxy via TCP (macOS + podman: Unix sockets can't be mounted)
 # The host runs an SSH proxy on TCP; socat bridges it to a local Unix socket.
 if [ -n "$ADDT_SSH_PROXY_HOST" ] && [ -n "$ADDT_SSH_PROXY_PORT" ]; then
@@ -253,7 +329,8 @@ if [ -n "$ADDT_CREDENTIAL_VARS" ]; then
     unset ADDT_CREDENTIAL_VARS
 fi
 
-# Scope GH_TOKEN to allowed repos via git credential-cache
+# Scope GH_TOKEN to allowed repos via git credential-cache, for providers
+# without the host-side credential helper (which keeps GH_TOKEN out)
 # Inspired by: https://github.com/IngmarKrusch/claude-docker
 if [ "$ADDT_GITHUB_SCOPE_TOKEN" = "true" ] && [ -n "$GH_TOKEN" ]; then
     debug_log "Scoping GH_TOKEN to allowed repos via git credential-cache"
@@ -344,6 +421,41 @@ IMPORTANT:
 - Always remind the user to use the host port in their browser"
 fi
 
+if [ -n "$ADDT_HOST_SERVICE_MAP" ]; then
+    # Parse host services (format: "postgres:5432,redis:6379")
+    [ -n "$ADDT_SYSTEM_PROMPT" ] && ADDT_SYSTEM_PROMPT+="
+
+"
+    ADDT_SYSTEM_PROMPT+="# Host Services
+
+These services on the user's machine are reachable from this container:
+"
+    IFS=',' read -ra SERVICES <<< "$ADDT_HOST_SERVICE_MAP"
+    for service in "${SERVICES[@]}"; do
+        SERVICE_NAME="${service%%:*}"
+        SERVICE_PORT="${service##*:}"
+        ADDT_SYSTEM_PROMPT+="- $SERVICE_NAME: $SERVICE_NAME.host.addt:$SERVICE_PORT
+"
+    done
+
+    ADDT_SYSTEM_PROMPT+="
+IMPORTANT:
+- Connect to these services by the hostnames above, not localhost
+- Other services on the user's machine are not reachable from this container"
+fi
+
+if [ "$ADDT_OFFLINE" = "true" ]; then
+    [ -n "$ADDT_SYSTEM_PROMPT" ] && ADDT_SYSTEM_PROMPT+="
+
+"
+    ADDT_SYSTEM_PROMPT+="# Offline
+
+This container is offline: the internet is not reachable. npm, pip, uv and go install packages through a local mirror (already configured); packages the mirror doesn't have can't be installed.
+IMPORTANT:
+- Don't change the registry or proxy settings of package managers
+- If a package is unavailable, tell the user to run 'addt cache warm <lockfile>' on their machine"
+fi
+
 # Set npm global prefix to user-owned directory (so addt user can install/uninstall without sudo)
 export NPM_CONFIG_PREFIX="$HOME/.npm-global"
 mkdir -p "$NPM_CONFIG_PREFIX"
@@ -1,20 +1,27 @@
 package main
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
 	"embed"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
-	"os/exec"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/jedi4ever/addt/cmd"
 	"github.com/jedi4ever/addt/config"
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/provider"
 )
 
 //go:embed static/*
@@ -35,16 +42,32 @@ type SessionManager struct {
 	mu       sync.RWMutex
 	sessions map[string]*Session
 	runtime  string // "docker" or "podman"
+	prov     provider.Provider
+	provErr  error // why prov could not be created
 }
 
 // NewSessionManager creates a new session manager
 func NewSessionManager() *SessionManager {
 	runtime := config.DetectContainerRuntime()
 	log.Printf("Using container runtime: %s", runtime)
+	prov, err := cmd.NewExistingProvider(runtime, &provider.Config{})
+	if err != nil {
+		log.Printf("Failed to create %s provider: %v", runtime, err)
+	}
 	return &SessionManager{
 		sessions: make(map[string]*Session),
 		runtime:  runtime,
+		prov:     prov,
+		provErr:  err,
+	}
+}
+
+// getProvider returns the provider, or why it is unavailable
+func (sm *SessionManager) getProvider() (provider.Provider, error) {
+	if sm.prov == nil {
+		return nil, fmt.Errorf("no %s provider: %w", sm.runtime, sm.provErr)
 	}
+	return sm.prov, nil
 }
 
 // List returns all sessions
@@ -52,45 +75,34 @@ func (sm *SessionManager) List() []*Session {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
-	// Get sessions from docker/podman
-	sessions := sm.getContainerSessions()
-	return sessions
+	return sm.getContainerSessions()
 }
 
-// getContainerSessions queries docker/podman for addt containers
+// getContainerSessions lists the provider's persistent environments
 func (sm *SessionManager) getContainerSessions() []*Session {
 	var sessions []*Session
 
-	// Use the detected runtime
-	cmd := exec.Command(sm.runtime, "ps", "-a", "--filter", "name=addt-", "--format", "{{.Names}}\t{{.Status}}\t{{.Labels}}")
-	output, err := cmd.Output()
+	prov, err := sm.getProvider()
 	if err != nil {
-		log.Printf("Failed to list containers with %s: %v", sm.runtime, err)
+		log.Printf("Failed to list sessions: %v", err)
+		return sessions
+	}
+	envs, err := prov.List()
+	if err != nil {
+		log.Printf("Failed to list sessions with %s: %v", sm.runtime, err)
 		return sessions
 	}
 
-	// Parse output
-	lines := splitLines(string(output))
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		parts := splitTabs(line)
-		if len(parts) < 2 {
-			continue
-		}
-
-		name := parts[0]
-		statusStr := parts[1]
-
+	for _, env := range envs {
+		// Container runtimes report "Up 2 hours", the others "running"
 		status := "stopped"
-		if containsString(statusStr, "Up") {
+		if containsString(env.Status, "Up") || env.Status == "running" {
 			status = "running"
 		}
 
 		sessions = append(sessions, &Session{
-			ID:     name,
-			Name:   name,
+			ID:     env.Name,
+			Name:   env.Name,
 			Status: status,
 		})
 	}
@@ -100,9 +112,11 @@ func (sm *SessionManager) getContainerSessions() []*Session {
 
 // Start starts a new or existing session
 func (sm *SessionManager) Start(name, workDir string) (*Session, error) {
-	// Start container using the detected runtime
-	cmd := exec.Command(sm.runtime, "start", name)
-	if err := cmd.Run(); err != nil {
+	prov, err := sm.getProvider()
+	if err != nil {
+		return nil, err
+	}
+	if err := prov.Start(name); err != nil {
 		return nil, fmt.Errorf("failed to start session with %s: %w", sm.runtime, err)
 	}
 
@@ -117,8 +131,11 @@ func (sm *SessionManager) Start(name, workDir string) (*Session, error) {
 
 // Stop stops a session
 func (sm *SessionManager) Stop(name string) error {
-	cmd := exec.Command(sm.runtime, "stop", name)
-	if err := cmd.Run(); err != nil {
+	prov, err := sm.getProvider()
+	if err != nil {
+		return err
+	}
+	if err := prov.Stop(name); err != nil {
 		return fmt.Errorf("failed to stop session with %s: %w", sm.runtime, err)
 	}
 	return nil
@@ -126,8 +143,11 @@ func (sm *SessionManager) Stop(name string) error {
 
 // Remove removes a session
 func (sm *SessionManager) Remove(name string) error {
-	cmd := exec.Command(sm.runtime, "rm", "-f", name)
-	if err := cmd.Run(); err != nil {
+	prov, err := sm.getProvider()
+	if err != nil {
+		return err
+	}
+	if err := prov.Remove(name); err != nil {
 		return fmt.Errorf("failed to remove session with %s: %w", sm.runtime, err)
 	}
 	return nil
@@ -138,17 +158,21 @@ type Server struct {
 	sm       *SessionManager
 	upgrader websocket.Upgrader
 	port     int
+	token    string // required by the API; only the URL printed at startup has it
 }
 
 // NewServer creates a new server
 func NewServer(port int) *Server {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		log.Fatalf("Failed to generate token: %v", err)
+	}
 	return &Server{
-		sm:   NewSessionManager(),
-		port: port,
+		sm:    NewSessionManager(),
+		port:  port,
+		token: hex.EncodeToString(token),
 		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true // Allow all origins for local use
-			},
+			CheckOrigin: sameOrigin,
 		},
 	}
 }
@@ -210,6 +234,83 @@ func (s *Server) handleSessionRemove(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// requireToken lets only requests with the orchestrator's token, from its
+// own pages, through. Containers can reach the host's loopback through
+// host.docker.internal, but never see the token, so they can't open a
+// shell in another session or approve their own signatures. Browsers
+// can't set headers on WebSocket requests, so the terminal sends the
+// token as a query parameter.
+func (s *Server) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !sameOrigin(r) {
+			http.Error(w, "cross-origin request refused", http.StatusForbidden)
+			return
+		}
+		token := r.Header.Get("X-Addt-Token")
+		if token == "" {
+			token = r.URL.Query().Get("token")
+		}
+		if s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			http.Error(w, "token required", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// sameOrigin reports whether a request comes from a page this server
+// served, or, without an Origin header, not from a browser page at all
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// handleSignRequests returns the signatures waiting for approval under
+// ssh.sign_policy or gpg.sign_policy confirm with security.sign_prompt web
+func (s *Server) handleSignRequests(w http.ResponseWriter, r *http.Request) {
+	pending, err := security.PendingSignRequests()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if pending == nil {
+		pending = []security.PendingSignRequest{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pending)
+}
+
+// handleSignAnswer answers a signature request with once, grant or deny.
+// It only takes a JSON body, which other sites' pages can't send without
+// a CORS preflight this server doesn't answer.
+func (s *Server) handleSignAnswer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "JSON body required", http.StatusUnsupportedMediaType)
+		return
+	}
+	var body struct {
+		ID     string `json:"id"`
+		Answer string `json:"answer"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if err := security.AnswerSignRequest(body.ID, security.SignAnswer(body.Answer)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
 // handleTerminal handles WebSocket connections for terminal access
 func (s *Server) handleTerminal(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Query().Get("name")
@@ -225,16 +326,32 @@ func (s *Server) handleTerminal(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Close()
 
-	// Start container exec with PTY using the detected runtime
-	cmd := exec.Command(s.sm.runtime, "exec", "-it", name, "/bin/bash")
-	ptmx, err := startPty(cmd)
+	prov, err := s.sm.getProvider()
+	if err != nil {
+		conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("Error: %v", err)))
+		return
+	}
+
+	// Exec a shell on the slave side of a PTY; the master relays to the
+	// WebSocket
+	ptmx, tty, err := openPty()
 	if err != nil {
-		log.Printf("Failed to start PTY with %s: %v", s.sm.runtime, err)
+		log.Printf("Failed to open PTY: %v", err)
 		conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("Error: %v", err)))
 		return
 	}
 	defer ptmx.Close()
 
+	go func() {
+		stdio := provider.Stdio{Stdin: tty, Stdout: tty, Stderr: tty}
+		if err := prov.Exec(name, []string{"/bin/bash"}, true, stdio); err != nil {
+			log.Printf("Exec in %s ended: %v", name, err)
+		}
+		// Ends the PTY reader and, through the closed WebSocket, the writer
+		tty.Close()
+		conn.Close()
+	}()
+
 	// Read from PTY, write to WebSocket
 	go func() {
 		buf := make([]byte, 4096)
@@ -249,7 +366,8 @@ func (s *Server) handleTerminal(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	// Read from WebSocket, write to PTY
+	// Read from WebSocket, write to PTY. Closing the master on return
+	// hangs up the shell.
 	for {
 		_, message, err := conn.ReadMessage()
 		if err != nil {
@@ -259,31 +377,50 @@ func (s *Server) handleTerminal(w http.ResponseWriter, r *http.Request) {
 			break
 		}
 	}
-
-	cmd.Process.Kill()
 }
 
-// Run starts the server
-func (s *Server) Run() error {
+// routes returns the server's handlers
+func (s *Server) routes() (*http.ServeMux, error) {
 	mux := http.NewServeMux()
 
 	// API routes
-	mux.HandleFunc("/api/sessions", s.handleSessions)
-	mux.HandleFunc("/api/sessions/start", s.handleSessionStart)
-	mux.HandleFunc("/api/sessions/stop", s.handleSessionStop)
-	mux.HandleFunc("/api/sessions/remove", s.handleSessionRemove)
-	mux.HandleFunc("/api/terminal", s.handleTerminal)
+	mux.HandleFunc("/api/sessions", s.requireToken(s.handleSessions))
+	mux.HandleFunc("/api/sessions/start", s.requireToken(s.handleSessionStart))
+	mux.HandleFunc("/api/sessions/stop", s.requireToken(s.handleSessionStop))
+	mux.HandleFunc("/api/sessions/remove", s.requireToken(s.handleSessionRemove))
+	mux.HandleFunc("/api/terminal", s.requireToken(s.handleTerminal))
+	mux.HandleFunc("/api/sign-requests", s.requireToken(s.handleSignRequests))
+	mux.HandleFunc("/api/sign-requests/answer", s.requireToken(s.handleSignAnswer))
 
 	// Static files
 	staticFS, err := fs.Sub(staticFiles, "static")
 	if err != nil {
-		return fmt.Errorf("failed to get static files: %w", err)
+		return nil, fmt.Errorf("failed to get static files: %w", err)
 	}
 	mux.Handle("/", http.FileServer(http.FS(staticFS)))
+	return mux, nil
+}
+
+// Run starts the server on the loopback interface
+func (s *Server) Run() error {
+	mux, err := s.routes()
+	if err != nil {
+		return err
+	}
+
+	addr := fmt.Sprintf("127.0.0.1:%d", s.port)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	// Sessions refuse web sign prompts while nothing listens here
+	if err := security.RegisterSignOrchestrator(addr); err != nil {
+		log.Printf("Failed to register for signature requests: %v", err)
+	}
 
-	addr := fmt.Sprintf(":%d", s.port)
-	fmt.Printf("addt-orchestrator running at http://localhost%s (runtime: %s)\n", addr, s.sm.runtime)
-	return http.ListenAndServe(addr, mux)
+	// The token goes in the fragment, which browsers don't send
+	fmt.Printf("addt-orchestrator running at http://%s/#token=%s (runtime: %s)\n", addr, s.token, s.sm.runtime)
+	return http.Serve(listener, mux)
 }
 
 // Helper functions
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -92,3 +95,75 @@ func TestContainsString(t *testing.T) {
 		}
 	}
 }
+
+func TestSignRequestsRequireToken(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+	s := &Server{token: "secret"}
+	mux, err := s.routes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method, path, token string
+		want                int
+	}{
+		{http.MethodPost, "/api/sign-requests/answer", "", http.StatusUnauthorized},
+		{http.MethodPost, "/api/sign-requests/answer", "guess", http.StatusUnauthorized},
+		{http.MethodGet, "/api/sign-requests", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/sign-requests", "secret", http.StatusOK},
+		// With the token the answer reaches the request check
+		{http.MethodPost, "/api/sign-requests/answer", "secret", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"id":"00ff","answer":"grant"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if tt.token != "" {
+			req.Header.Set("X-Addt-Token", tt.token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s with token %q: got %d, want %d", tt.method, tt.path, tt.token, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestAPIRequiresTokenAndSameOrigin(t *testing.T) {
+	s := &Server{token: "secret"}
+	mux, err := s.routes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path, token, origin string
+		want                int
+	}{
+		{"/api/terminal?name=other", "", "", http.StatusUnauthorized},
+		{"/api/sessions/start?name=other", "", "", http.StatusUnauthorized},
+		{"/api/sessions/stop?name=other", "guess", "", http.StatusUnauthorized},
+		{"/api/sessions/remove?name=other", "", "", http.StatusUnauthorized},
+		{"/api/sessions", "", "", http.StatusUnauthorized},
+		// Other sites' pages are refused even with the token
+		{"/api/terminal?name=other&token=secret", "", "http://evil.example", http.StatusForbidden},
+		{"/api/sessions/start?name=other", "secret", "http://evil.example", http.StatusForbidden},
+		// With the token, from the served host, requests reach the handler
+		{"/api/terminal?token=secret", "", "http://127.0.0.1:8080", http.StatusBadRequest},
+		{"/api/sessions/start", "secret", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:8080"+tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("X-Addt-Token", tt.token)
+		}
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s with token %q, origin %q: got %d, want %d", tt.path, tt.token, tt.origin, rec.Code, tt.want)
+		}
+	}
+}
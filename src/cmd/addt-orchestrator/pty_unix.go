@@ -4,12 +4,11 @@ package main
 
 import (
 	"os"
-	"os/exec"
 
 	"github.com/creack/pty"
 )
 
-// startPty starts a command with a PTY
-func startPty(cmd *exec.Cmd) (*os.File, error) {
-	return pty.Start(cmd)
+// openPty opens a PTY and returns its master and slave ends
+func openPty() (*os.File, *os.File, error) {
+	return pty.Open()
 }
@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/config/security"
+)
+
+// filter selects audit events; its zero value selects them all
+type filter struct {
+	containers []string  // container names, '*' globs allowed
+	types      []string  // event types or categories such as "network"
+	deniedOnly bool      // only events that denied something
+	since      time.Time // events at or after
+	until      time.Time // events before
+}
+
+// match reports whether an event passes the filter
+func (f filter) match(e security.AuditEvent) bool {
+	if f.deniedOnly && e.Allowed {
+		return false
+	}
+	if !f.since.IsZero() && e.Timestamp.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && !e.Timestamp.Before(f.until) {
+		return false
+	}
+	if len(f.containers) > 0 && !matchAny(f.containers, e.Container, matchContainer) {
+		return false
+	}
+	if len(f.types) > 0 && !matchAny(f.types, string(e.Type), matchType) {
+		return false
+	}
+	return true
+}
+
+// apply returns the events that pass the filter
+func (f filter) apply(events []security.AuditEvent) []security.AuditEvent {
+	var result []security.AuditEvent
+	for _, e := range events {
+		if f.match(e) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func matchAny(patterns []string, value string, match func(pattern, value string) bool) bool {
+	for _, p := range patterns {
+		if match(p, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchContainer matches a container name against a name or glob
+func matchContainer(pattern, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return ok || (err != nil && pattern == name)
+}
+
+// matchType matches an event type against a type ("network_denied") or a
+// category, the part before the last underscore ("network", "gpg_decrypt")
+func matchType(pattern, eventType string) bool {
+	return eventType == pattern || strings.HasPrefix(eventType, pattern+"_")
+}
+
+// validType checks that a --type value names at least one event type
+func validType(pattern string) error {
+	for _, t := range security.AuditEventTypes() {
+		if matchType(pattern, string(t)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown event type %q (see 'addt audit types')", pattern)
+}
+
+// parseTime parses a --since or --until value: a duration before now such
+// as "90m", "24h" or "7d", a date, or an RFC 3339 timestamp
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if strings.HasSuffix(value, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil && days >= 0 {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use e.g. 24h, 7d, 2006-01-02 or an RFC 3339 timestamp)", value)
+}
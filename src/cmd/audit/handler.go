@@ -0,0 +1,239 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/config/security"
+)
+
+// options are the flags of the audit commands
+type options struct {
+	filter
+	file   string // --file: audit log to read (default: the configured one)
+	limit  int    // -n: number of most recent events, 0 for all
+	follow bool   // tail -f
+	format string // export --format: csv or json
+	output string // export -o: file to write (default: stdout)
+}
+
+// HandleCommand handles the audit subcommand
+func HandleCommand(args []string, cfg *security.Config) {
+	cmd := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case "list", "ls", "tail", "summary", "export":
+	case "types":
+		for _, t := range security.AuditEventTypes() {
+			fmt.Println(t)
+		}
+		return
+	case "help", "--help", "-h":
+		printHelp()
+		return
+	default:
+		fmt.Printf("Unknown audit command: %s\n", cmd)
+		printHelp()
+		os.Exit(1)
+	}
+
+	opts, err := parseOptions(cmd, args, time.Now())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Println("Run 'addt audit help' for usage")
+		os.Exit(1)
+	}
+	logPath := opts.file
+	if logPath == "" {
+		if logPath, err = security.AuditLogPath(cfg); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if cmd == "tail" && opts.follow {
+		if err := followLog(logPath, opts, os.Stdout); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	events := loadEvents(logPath, cfg)
+	events = opts.apply(events)
+	if opts.limit > 0 && len(events) > opts.limit {
+		events = events[len(events)-opts.limit:]
+	}
+
+	switch cmd {
+	case "summary":
+		printSummary(os.Stdout, events)
+	case "export":
+		if err := exportEvents(events, opts.format, opts.output); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		if len(events) == 0 {
+			fmt.Println("No matching audit events")
+			return
+		}
+		printEvents(os.Stdout, events)
+	}
+}
+
+// loadEvents reads all events of the audit log, exiting when there is none
+func loadEvents(logPath string, cfg *security.Config) []security.AuditEvent {
+	file, err := os.Open(logPath)
+	if os.IsNotExist(err) {
+		fmt.Printf("No audit log at %s\n", logPath)
+		if !cfg.AuditLog {
+			fmt.Println("Enable it with 'addt config set security.audit_log true -g'")
+		}
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	events, err := security.ReadAuditEvents(file)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", logPath, err)
+		os.Exit(1)
+	}
+	return events
+}
+
+// parseOptions parses the flags of an audit command
+func parseOptions(cmd string, args []string, now time.Time) (options, error) {
+	opts := options{format: "csv"}
+	if cmd == "tail" {
+		opts.limit = 20
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(arg, "=")
+		takesValue := false
+		switch name {
+		case "--container", "-c", "--type", "-t", "--since", "--until", "--file", "-n", "--limit", "--format", "-o", "--output":
+			takesValue = true
+		}
+		if takesValue && !hasValue {
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("%s requires a value", name)
+			}
+			value = args[i+1]
+			i++
+		} else if !takesValue && hasValue {
+			return opts, fmt.Errorf("%s takes no value", name)
+		}
+
+		var err error
+		switch name {
+		case "--container", "-c":
+			opts.containers = append(opts.containers, splitList(value)...)
+		case "--type", "-t":
+			for _, t := range splitList(value) {
+				if err := validType(t); err != nil {
+					return opts, err
+				}
+				opts.types = append(opts.types, t)
+			}
+		case "--denied":
+			opts.deniedOnly = true
+		case "--since":
+			opts.since, err = parseTime(value, now)
+		case "--until":
+			opts.until, err = parseTime(value, now)
+		case "--file":
+			opts.file = value
+		case "-n", "--limit":
+			opts.limit, err = strconv.Atoi(value)
+			if err == nil && opts.limit < 0 {
+				err = fmt.Errorf("invalid %s %d", name, opts.limit)
+			}
+		case "-f", "--follow":
+			if cmd != "tail" {
+				return opts, fmt.Errorf("%s only works with tail", name)
+			}
+			opts.follow = true
+		case "--format":
+			if cmd != "export" {
+				return opts, fmt.Errorf("%s only works with export", name)
+			}
+			if value != "csv" && value != "json" {
+				return opts, fmt.Errorf("unknown format %q (use csv or json)", value)
+			}
+			opts.format = value
+		case "-o", "--output":
+			if cmd != "export" {
+				return opts, fmt.Errorf("%s only works with export", name)
+			}
+			opts.output = value
+		default:
+			return opts, fmt.Errorf("unknown flag %s", arg)
+		}
+		if err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+// splitList splits a comma-separated flag value
+func splitList(value string) []string {
+	var result []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func printHelp() {
+	fmt.Println(`addt audit - View the security audit log
+
+Usage: addt audit [command] [filters]
+
+Commands:
+  list                     Show matching events (default)
+  tail [-n N] [-f]         Show the last N events (default 20); -f keeps
+                           showing new ones as they are logged
+  summary                  Count events by type and container, and list the
+                           most denied destinations
+  export [--format csv|json] [-o <file>]
+                           Write matching events as CSV (default) or JSON
+  types                    List the event types
+
+Filters:
+  --container, -c <name>   Events of a container; '*' globs, comma-separated
+  --type, -t <type>        Events of a type (network_denied) or a category
+                           (network, dns, request, ssh, gpg, secrets, mount,
+                           yolo, container, host_service, git_credential);
+                           comma-separated
+  --denied                 Only events that denied something
+  --since <time>           Events at or after a time: 24h, 7d, 2006-01-02,
+                           or an RFC 3339 timestamp
+  --until <time>           Events before a time
+  -n, --limit <N>          Only the N most recent matching events
+  --file <path>            Read another audit log
+
+The log is written when security.audit_log is true, to
+security.audit_log_file (default: ~/.addt/audit.log).
+
+Examples:
+  addt audit tail -f --denied
+  addt audit list --container 'addt-*' --type network,dns --since 2h
+  addt audit summary --since 7d
+  addt audit export --format json --since 2026-01-01 -o audit.json`)
+}
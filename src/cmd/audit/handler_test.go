@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jedi4ever/addt/config/security"
+)
+
+func TestParseOptions(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	opts, err := parseOptions("list", []string{"-c", "addt-a,addt-b*", "--type=network", "--denied", "--since", "2d", "-n", "5"}, now)
+	if err != nil {
+		t.Fatalf("parseOptions() error = %v", err)
+	}
+	if !reflect.DeepEqual(opts.containers, []string{"addt-a", "addt-b*"}) || !reflect.DeepEqual(opts.types, []string{"network"}) {
+		t.Errorf("containers = %v, types = %v", opts.containers, opts.types)
+	}
+	if !opts.deniedOnly || opts.limit != 5 || !opts.since.Equal(now.AddDate(0, 0, -2)) {
+		t.Errorf("opts = %+v", opts)
+	}
+
+	if opts, _ := parseOptions("tail", nil, now); opts.limit != 20 {
+		t.Errorf("tail limit = %d, want 20", opts.limit)
+	}
+	if opts, _ := parseOptions("export", []string{"--format", "json", "-o", "out.json"}, now); opts.format != "json" || opts.output != "out.json" {
+		t.Errorf("export opts = %+v", opts)
+	}
+
+	for _, args := range [][]string{
+		{"--type", "bogus"},
+		{"--since", "yesterday"},
+		{"-f"},
+		{"--format", "xml"},
+		{"--container"},
+		{"--denied=yes"},
+		{"--what"},
+	} {
+		if _, err := parseOptions("list", args, now); err == nil {
+			t.Errorf("parseOptions(%v) expected error", args)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"90m", now.Add(-90 * time.Minute)},
+		{"7d", now.AddDate(0, 0, -7)},
+		{"2026-01-02T03:04:05Z", time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"2026-01-02", time.Date(2026, 1, 2, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := parseTime(tt.value, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseTime(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	at := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	event := security.AuditEvent{Timestamp: at, Type: security.AuditNetworkDenied, Container: "addt-20260110-1"}
+	tests := []struct {
+		name string
+		f    filter
+		want bool
+	}{
+		{"zero filter", filter{}, true},
+		{"category", filter{types: []string{"network"}}, true},
+		{"exact type", filter{types: []string{"network_denied"}}, true},
+		{"other category", filter{types: []string{"dns", "net"}}, false},
+		{"container glob", filter{containers: []string{"addt-2026*"}}, true},
+		{"other container", filter{containers: []string{"addt-other"}}, false},
+		{"denied only", filter{deniedOnly: true}, true},
+		{"since", filter{since: at.Add(-time.Hour)}, true},
+		{"since after", filter{since: at.Add(time.Hour)}, false},
+		{"until is exclusive", filter{until: at}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.f.match(event); got != tt.want {
+			t.Errorf("%s: match() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
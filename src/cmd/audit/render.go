@@ -0,0 +1,245 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/config/security"
+)
+
+// printEvents prints events one per line, oldest first
+func printEvents(out io.Writer, events []security.AuditEvent) {
+	for _, e := range events {
+		fmt.Fprintln(out, formatEvent(e))
+	}
+}
+
+// formatEvent formats an event on one line, e.g.
+// "2026-01-02 15:04:05  network_denied    addt-20260102-150400-1  evil.com:443  not in allowlist"
+func formatEvent(e security.AuditEvent) string {
+	container := e.Container
+	if container == "" {
+		container = "-"
+	}
+	line := fmt.Sprintf("%s  %-20s  %-28s  %s", e.Timestamp.Local().Format("2006-01-02 15:04:05"), e.Type, container, e.Subject())
+	if e.Reason != "" {
+		line += "  (" + e.Reason + ")"
+	}
+	return strings.TrimRight(line, " ")
+}
+
+// printSummary prints event counts by type and by container, and the
+// subjects denied most often
+func printSummary(out io.Writer, events []security.AuditEvent) {
+	if len(events) == 0 {
+		fmt.Fprintln(out, "No matching audit events")
+		return
+	}
+	first, last := events[0].Timestamp, events[0].Timestamp
+	byType := make(map[string]int)
+	denied := make(map[string]int)
+	type containerCount struct{ events, denied int }
+	byContainer := make(map[string]*containerCount)
+	for _, e := range events {
+		if e.Timestamp.Before(first) {
+			first = e.Timestamp
+		}
+		if e.Timestamp.After(last) {
+			last = e.Timestamp
+		}
+		byType[string(e.Type)]++
+		name := e.Container
+		if name == "" {
+			name = "-"
+		}
+		if byContainer[name] == nil {
+			byContainer[name] = &containerCount{}
+		}
+		byContainer[name].events++
+		if !e.Allowed {
+			byContainer[name].denied++
+			denied[string(e.Type)+" "+e.Subject()]++
+		}
+	}
+
+	fmt.Fprintf(out, "%d events from %s to %s\n", len(events),
+		first.Local().Format("2006-01-02 15:04:05"), last.Local().Format("2006-01-02 15:04:05"))
+
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "By type:")
+	for _, c := range sortedCounts(byType) {
+		fmt.Fprintf(out, "  %-24s %d\n", c.key, c.count)
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "By container:")
+	names := make([]string, 0, len(byContainer))
+	for name := range byContainer {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := byContainer[name]
+		fmt.Fprintf(out, "  %-32s %d events, %d denied\n", name, c.events, c.denied)
+	}
+
+	if len(denied) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "Most denied:")
+		counts := sortedCounts(denied)
+		if len(counts) > 10 {
+			counts = counts[:10]
+		}
+		for _, c := range counts {
+			fmt.Fprintf(out, "  %5d  %s\n", c.count, c.key)
+		}
+	}
+}
+
+type keyCount struct {
+	key   string
+	count int
+}
+
+// sortedCounts returns counts from the highest, ties by key
+func sortedCounts(counts map[string]int) []keyCount {
+	result := make([]keyCount, 0, len(counts))
+	for k, n := range counts {
+		result = append(result, keyCount{k, n})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].count != result[j].count {
+			return result[i].count > result[j].count
+		}
+		return result[i].key < result[j].key
+	})
+	return result
+}
+
+// exportEvents writes events as csv or json to a file, or to stdout when
+// path is empty
+func exportEvents(events []security.AuditEvent, format, path string) error {
+	out := io.Writer(os.Stdout)
+	if path != "" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	var err error
+	if format == "json" {
+		err = writeJSON(out, events)
+	} else {
+		err = writeCSV(out, events)
+	}
+	if err == nil && path != "" {
+		fmt.Fprintf(os.Stderr, "Exported %d events to %s\n", len(events), path)
+	}
+	return err
+}
+
+// csvHeader names the columns writeCSV writes
+var csvHeader = []string{"timestamp", "type", "container", "extension", "allowed", "host", "key_id", "comment", "source", "target", "secrets", "reason"}
+
+// writeCSV writes events as CSV with a header row
+func writeCSV(out io.Writer, events []security.AuditEvent) error {
+	w := csv.NewWriter(out)
+	w.Write(csvHeader)
+	for _, e := range events {
+		w.Write([]string{
+			e.Timestamp.UTC().Format(time.RFC3339Nano),
+			string(e.Type),
+			e.Container,
+			e.Extension,
+			strconv.FormatBool(e.Allowed),
+			e.Host,
+			e.KeyID,
+			e.Comment,
+			e.Source,
+			e.Target,
+			strings.Join(e.Secrets, ";"),
+			e.Reason,
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// writeJSON writes events as an indented JSON array
+func writeJSON(out io.Writer, events []security.AuditEvent) error {
+	if events == nil {
+		events = []security.AuditEvent{}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(events)
+}
+
+// followLog prints the last events of the log, then the matching events
+// appended to it until interrupted. A log that doesn't exist yet is waited
+// for; one that gets truncated is read again from the start.
+func followLog(path string, opts options, out io.Writer) error {
+	var offset int64
+	var partial []byte
+	first := true
+	for {
+		data, size, err := readFrom(path, offset)
+		if err != nil {
+			return err
+		}
+		if size < offset {
+			offset, partial = 0, nil
+			continue
+		}
+		offset = size
+
+		data = append(partial, data...)
+		end := strings.LastIndexByte(string(data), '\n') + 1
+		partial = append([]byte(nil), data[end:]...)
+		events, _ := security.ReadAuditEvents(strings.NewReader(string(data[:end])))
+		events = opts.apply(events)
+		if first && opts.limit > 0 && len(events) > opts.limit {
+			events = events[len(events)-opts.limit:]
+		}
+		first = false
+		printEvents(out, events)
+
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// readFrom reads a file from an offset; it returns the file's size, and no
+// data for a file that doesn't exist
+func readFrom(path string, offset int64) ([]byte, int64, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	if info.Size() <= offset {
+		return nil, info.Size(), nil
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	data, err := io.ReadAll(io.LimitReader(file, info.Size()-offset))
+	return data, info.Size(), err
+}
@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jedi4ever/addt/config/security"
+)
+
+func testEvents() []security.AuditEvent {
+	at := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	return []security.AuditEvent{
+		{Timestamp: at, Type: security.AuditContainerStart, Container: "addt-a", Extension: "claude", Allowed: true, Reason: "run"},
+		{Timestamp: at.Add(time.Second), Type: security.AuditNetworkDenied, Container: "addt-a", Host: "evil.example:443", Reason: "not allowed"},
+		{Timestamp: at.Add(2 * time.Second), Type: security.AuditNetworkDenied, Container: "addt-b", Host: "evil.example:443", Reason: "not allowed"},
+		{Timestamp: at.Add(3 * time.Second), Type: security.AuditSecretsInjected, Container: "addt-b", Secrets: []string{"A_KEY", "B_KEY"}, Allowed: true, Reason: "isolated"},
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeCSV(&buf, testEvents()); err != nil {
+		t.Fatalf("writeCSV() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || lines[0] != strings.Join(csvHeader, ",") {
+		t.Fatalf("csv = %q", buf.String())
+	}
+	if want := "2026-01-10T12:00:03Z,secrets_injected,addt-b,,true,,,,,,A_KEY;B_KEY,isolated"; lines[4] != want {
+		t.Errorf("row = %q, want %q", lines[4], want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, nil); err != nil || strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("writeJSON(nil) = %q, %v", buf.String(), err)
+	}
+	buf.Reset()
+	writeJSON(&buf, testEvents())
+	var events []security.AuditEvent
+	if err := json.Unmarshal(buf.Bytes(), &events); err != nil || len(events) != 4 {
+		t.Errorf("round trip = %+v, %v", events, err)
+	}
+}
+
+func TestPrintSummary(t *testing.T) {
+	var buf bytes.Buffer
+	printSummary(&buf, testEvents())
+	out := buf.String()
+	for _, want := range []string{
+		"4 events from",
+		"  network_denied           2\n",
+		"  addt-a                           2 events, 1 denied\n",
+		"      2  network_denied evil.example:443\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestReadFrom(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if data, size, err := readFrom(path, 0); data != nil || size != 0 || err != nil {
+		t.Errorf("readFrom(missing) = %q, %d, %v", data, size, err)
+	}
+	os.WriteFile(path, []byte("line one\nline two\n"), 0600)
+	data, size, err := readFrom(path, 9)
+	if err != nil || string(data) != "line two\n" || size != 18 {
+		t.Errorf("readFrom(9) = %q, %d, %v", data, size, err)
+	}
+}
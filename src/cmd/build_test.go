@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"io"
 	"testing"
 
 	"github.com/jedi4ever/addt/provider"
@@ -29,6 +30,19 @@ func (m *mockProvider) GenerateEphemeralName() string                      { ret
 func (m *mockProvider) GetStatus(cfg *provider.Config, name string) string { return "test" }
 func (m *mockProvider) GetName() string                                    { return "mock" }
 func (m *mockProvider) GetExtensionEnvVars(imageName string) []string      { return nil }
+func (m *mockProvider) Exec(name string, cmd []string, tty bool, stdio provider.Stdio) error {
+	return nil
+}
+func (m *mockProvider) Logs(name string, follow bool, out io.Writer) error     { return nil }
+func (m *mockProvider) CopyTo(name, hostPath, envPath string) error            { return nil }
+func (m *mockProvider) CopyFrom(name, envPath, hostPath string) error          { return nil }
+func (m *mockProvider) Inspect(name string) (*provider.EnvironmentInfo, error) { return nil, nil }
+func (m *mockProvider) Snapshot(name, tag string, checkpoint bool) (*provider.Snapshot, error) {
+	return nil, nil
+}
+func (m *mockProvider) Restore(name, tag string) error                         { return nil }
+func (m *mockProvider) ListSnapshots(name string) ([]provider.Snapshot, error) { return nil, nil }
+func (m *mockProvider) RemoveSnapshot(name, tag string) error                  { return nil }
 
 func (m *mockProvider) DetermineImageName() string {
 	m.imageNameCalled = true
@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/util"
+)
+
+// ecosystems are the package mirror's cache directories
+var ecosystems = []string{"npm", "pypi", "go"}
+
+// HandleCommand handles the cache subcommand
+func HandleCommand(args []string) {
+	cmd := "list"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case "warm":
+		handleWarm(args)
+	case "list", "ls", "info":
+		printUsage(security.MirrorDir())
+	case "clean":
+		handleClean(args)
+	case "path":
+		fmt.Println(security.MirrorDir())
+	case "help", "--help", "-h":
+		printHelp()
+	default:
+		fmt.Printf("Unknown cache command: %s\n", cmd)
+		printHelp()
+		os.Exit(1)
+	}
+}
+
+// handleWarm caches the packages the lockfiles pin
+func handleWarm(lockfiles []string) {
+	if len(lockfiles) == 0 {
+		fmt.Println("Usage: addt cache warm <lockfile>...")
+		fmt.Println("Lockfiles: package-lock.json, npm-shrinkwrap.json, requirements*.txt, go.sum")
+		os.Exit(1)
+	}
+
+	mirror := security.NewPackageMirror("cache", security.MirrorDir(), true)
+	failed := false
+	for _, lockfile := range lockfiles {
+		result, err := mirror.Warm(lockfile)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			failed = true
+			continue
+		}
+		fmt.Printf("✓ %s: %d %s files cached\n", lockfile, result.Files-len(result.Failed), result.Ecosystem)
+		if len(result.Failed) > 0 {
+			fmt.Printf("  %d unavailable:\n", len(result.Failed))
+			for _, p := range result.Failed {
+				fmt.Printf("    %s\n", p)
+			}
+			failed = true
+		}
+	}
+	stats := mirror.Stats()
+	fmt.Printf("Fetched %d, already cached %d\n", stats.Fetched, stats.Cached)
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// handleClean removes the cache of one ecosystem, or all of them
+func handleClean(args []string) {
+	targets := ecosystems
+	if len(args) > 0 {
+		targets = nil
+		for _, arg := range args {
+			if !isEcosystem(arg) {
+				fmt.Printf("Error: unknown cache %q (use %s)\n", arg, strings.Join(ecosystems, ", "))
+				os.Exit(1)
+			}
+			targets = append(targets, arg)
+		}
+	}
+
+	dir := security.MirrorDir()
+	for _, eco := range targets {
+		if err := os.RemoveAll(filepath.Join(dir, eco)); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Removed the %s cache\n", eco)
+	}
+}
+
+func isEcosystem(name string) bool {
+	for _, eco := range ecosystems {
+		if eco == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ecosystemUsage is how much of the cache an ecosystem takes
+type ecosystemUsage struct {
+	Name  string
+	Files int
+	Bytes int64
+}
+
+// cacheUsage counts the cached files and bytes of each ecosystem
+func cacheUsage(dir string) []ecosystemUsage {
+	var usage []ecosystemUsage
+	for _, eco := range ecosystems {
+		u := ecosystemUsage{Name: eco}
+		filepath.WalkDir(filepath.Join(dir, eco), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(path, ".cache") {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				u.Files++
+				u.Bytes += info.Size()
+			}
+			return nil
+		})
+		usage = append(usage, u)
+	}
+	return usage
+}
+
+// printUsage shows the size of the cache
+func printUsage(dir string) {
+	fmt.Printf("Package cache: %s\n\n", dir)
+	fmt.Printf("%-8s %8s %10s\n", "CACHE", "FILES", "SIZE")
+	var files int
+	var bytes int64
+	for _, u := range cacheUsage(dir) {
+		fmt.Printf("%-8s %8d %10s\n", u.Name, u.Files, util.FormatBytes(u.Bytes))
+		files += u.Files
+		bytes += u.Bytes
+	}
+	fmt.Printf("%-8s %8d %10s\n", "total", files, util.FormatBytes(bytes))
+}
+
+func printHelp() {
+	fmt.Println(`addt cache - Manage the offline package cache
+
+Usage: addt cache <command>
+
+Commands:
+  list                     Show the size of the npm, PyPI and Go caches (default)
+  warm <lockfile>...       Cache the packages a lockfile pins, for offline
+                           sessions: package-lock.json, npm-shrinkwrap.json,
+                           requirements*.txt (name==version) or go.sum
+  clean [npm|pypi|go]      Remove the cache, or one ecosystem's
+  path                     Print the cache directory
+
+Offline sessions (offline.enabled, or 'addt run --offline') install packages
+through a host-side mirror backed by this cache. With offline.fetch false,
+only cached packages can be installed.
+
+Examples:
+  addt cache warm package-lock.json
+  addt cache warm requirements.txt go.sum
+  addt cache clean npm`)
+}
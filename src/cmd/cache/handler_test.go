@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheUsage(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"npm/left-pad.cache":                             "{}",
+		"npm/left-pad/-/left-pad-1.3.0.tgz.cache":        "tarball",
+		"npm/.download-123":                              "partial",
+		"go/proxy/golang.org/x/text/@v/v0.3.0.mod.cache": "module",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(data), 0644)
+	}
+
+	usage := cacheUsage(dir)
+	if len(usage) != 3 {
+		t.Fatalf("cacheUsage() = %v, want npm, pypi and go", usage)
+	}
+	want := map[string]ecosystemUsage{
+		"npm":  {Name: "npm", Files: 2, Bytes: 9},
+		"pypi": {Name: "pypi"},
+		"go":   {Name: "go", Files: 1, Bytes: 6},
+	}
+	for _, u := range usage {
+		if u != want[u.Name] {
+			t.Errorf("usage of %s = %+v, want %+v", u.Name, u, want[u.Name])
+		}
+	}
+}
+
+func TestIsEcosystem(t *testing.T) {
+	for _, name := range []string{"npm", "pypi", "go"} {
+		if !isEcosystem(name) {
+			t.Errorf("isEcosystem(%q) = false", name)
+		}
+	}
+	if isEcosystem("maven") {
+		t.Error("isEcosystem(maven) = true")
+	}
+}
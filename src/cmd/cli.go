@@ -55,10 +55,11 @@ func printAddtSubcommandUsage() {
 	fmt.Println("  update <ext> [version]    Update extension to latest or specific version")
 	fmt.Println("  build [--build-arg ...]   Build the container image")
 	fmt.Println("  shell                     Open bash shell in container")
-	fmt.Println("  containers <subcommand>   Manage containers (list, stop, rm, clean)")
+	fmt.Println("  containers <subcommand>   Manage containers (list, exec, logs, cp, snapshot, restore, rm, ...)")
 	fmt.Println("  firewall <subcommand>     Manage firewall (list, add, remove, reset)")
 	fmt.Println("  extensions <subcommand>   Manage extensions (list, info, new)")
 	fmt.Println("  config <subcommand>       Manage config (global, project, extension)")
+	fmt.Println("  cache <subcommand>        Manage the offline package cache (list, warm, clean)")
 	fmt.Println("  cli <subcommand>          Manage addt CLI (update)")
 	fmt.Println("  version                   Show version info")
 }
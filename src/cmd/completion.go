@@ -99,13 +99,15 @@ _addt_completions() {
         cword=$COMP_CWORD
     fi
 
-    local commands="run update build shell containers config profile extensions firewall completion doctor version cli"
+    local commands="run update build shell containers diff apply discard config profile extensions firewall audit cache completion doctor version cli"
     local config_cmds="list get set unset audit extension path"
     local profile_cmds="list show apply"
     local profile_names="%s"
-    local containers_cmds="list clean"
-    local firewall_cmds="global project"
+    local containers_cmds="list exec logs cp inspect usage resume snapshot restore snapshots stop rm clean"
+    local firewall_cmds="global project learn check apply"
     local firewall_actions="list allow deny remove"
+    local audit_cmds="list tail summary export types"
+    local cache_cmds="list warm clean path"
     local extensions_cmds="list info new"
     local extensions="%s"
     local config_keys="%s"
@@ -131,6 +133,12 @@ _addt_completions() {
                 firewall)
                     COMPREPLY=($(compgen -W "${firewall_cmds}" -- "${cur}"))
                     ;;
+                audit)
+                    COMPREPLY=($(compgen -W "${audit_cmds}" -- "${cur}"))
+                    ;;
+                cache)
+                    COMPREPLY=($(compgen -W "${cache_cmds}" -- "${cur}"))
+                    ;;
                 extensions)
                     COMPREPLY=($(compgen -W "${extensions_cmds}" -- "${cur}"))
                     ;;
@@ -161,6 +169,16 @@ _addt_completions() {
                 firewall)
                     COMPREPLY=($(compgen -W "${firewall_actions}" -- "${cur}"))
                     ;;
+                cache)
+                    case "${prev}" in
+                        warm)
+                            COMPREPLY=($(compgen -f -- "${cur}"))
+                            ;;
+                        clean)
+                            COMPREPLY=($(compgen -W "npm pypi go" -- "${cur}"))
+                            ;;
+                    esac
+                    ;;
                 extensions)
                     case "${prev}" in
                         info)
@@ -185,7 +203,7 @@ func zshCompletion() string {
 	return fmt.Sprintf(`#compdef addt
 
 _addt() {
-    local -a commands extensions config_cmds profile_cmds profile_names containers_cmds firewall_cmds firewall_actions extensions_cmds config_keys
+    local -a commands extensions config_cmds profile_cmds profile_names containers_cmds firewall_cmds firewall_actions audit_cmds extensions_cmds config_keys
 
     commands=(
         'run:Run an agent in a container'
@@ -193,10 +211,15 @@ _addt() {
         'build:Build container image for an agent'
         'shell:Open a shell in a container'
         'containers:Manage containers'
+        'diff:Show changes in the workspace overlay'
+        'apply:Apply workspace overlay changes'
+        'discard:Discard workspace overlay changes'
         'config:Manage configuration'
         'profile:Apply configuration presets'
         'extensions:Manage extensions'
         'firewall:Manage firewall rules'
+        'audit:View the security audit log'
+        'cache:Manage the offline package cache'
         'completion:Generate shell completions'
         'doctor:Check system health'
         'version:Show version information'
@@ -225,12 +248,26 @@ _addt() {
 
     containers_cmds=(
         'list:List containers'
+        'exec:Run a command in a container'
+        'logs:Show container output'
+        'cp:Copy files to or from a container'
+        'inspect:Show container details'
+        'usage:Show network usage per destination'
+        'resume:Resume a session paused by an egress quota'
+        'snapshot:Save a container snapshot'
+        'restore:Restore a container snapshot'
+        'snapshots:List, remove and prune snapshots'
+        'stop:Stop a container'
+        'rm:Remove a container'
         'clean:Remove all addt containers'
     )
 
     firewall_cmds=(
         'global:Manage global firewall rules'
         'project:Manage project firewall rules'
+        'learn:Review destinations recorded by run --firewall-learn'
+        'check:Show whether the rules allow a destination'
+        'apply:Reload the rules in running sessions'
     )
 
     firewall_actions=(
@@ -240,6 +277,21 @@ _addt() {
         'remove:Remove a rule'
     )
 
+    audit_cmds=(
+        'list:Show matching audit events'
+        'tail:Show the last audit events'
+        'summary:Summarize audit events'
+        'export:Export audit events as CSV or JSON'
+        'types:List audit event types'
+    )
+
+    cache_cmds=(
+        'list:Show the size of the package cache'
+        'warm:Cache the packages a lockfile pins'
+        'clean:Remove cached packages'
+        'path:Print the cache directory'
+    )
+
     extensions_cmds=(
         'list:List available extensions'
         'info:Show extension details'
@@ -275,6 +327,12 @@ _addt() {
                 firewall)
                     _describe -t firewall_cmds 'firewall commands' firewall_cmds
                     ;;
+                audit)
+                    _describe -t audit_cmds 'audit commands' audit_cmds
+                    ;;
+                cache)
+                    _describe -t cache_cmds 'cache commands' cache_cmds
+                    ;;
                 extensions)
                     _describe -t extensions_cmds 'extension commands' extensions_cmds
                     ;;
@@ -337,10 +395,15 @@ func fishCompletion() string {
 	sb.WriteString("complete -c addt -n '__fish_use_subcommand' -a 'build' -d 'Build container image for an agent'\n")
 	sb.WriteString("complete -c addt -n '__fish_use_subcommand' -a 'shell' -d 'Open a shell in a container'\n")
 	sb.WriteString("complete -c addt -n '__fish_use_subcommand' -a 'containers' -d 'Manage containers'\n")
+	sb.WriteString("complete -c addt -n '__fish_use_subcommand' -a 'diff' -d 'Show changes in the workspace overlay'\n")
+	sb.WriteString("complete -c addt -n '__fish_use_subcommand' -a 'apply' -d 'Apply workspace overlay changes'\n")
+	sb.WriteString("complete -c addt -n '__fish_use_subcommand' -a 'discard' -d 'Discard workspace overlay changes'\n")
 	sb.WriteString("complete -c addt -n '__fish_use_subcommand' -a 'config' -d 'Manage configuration'\n")
 	sb.WriteString("complete -c addt -n '__fish_use_subcommand' -a 'profile' -d 'Apply configuration presets'\n")
 	sb.WriteString("complete -c addt -n '__fish_use_subcommand' -a 'extensions' -d 'Manage extensions'\n")
 	sb.WriteString("complete -c addt -n '__fish_use_subcommand' -a 'firewall' -d 'Manage firewall rules'\n")
+	sb.WriteString("complete -c addt -n '__fish_use_subcommand' -a 'audit' -d 'View the security audit log'\n")
+	sb.WriteString("complete -c addt -n '__fish_use_subcommand' -a 'cache' -d 'Manage the offline package cache'\n")
 	sb.WriteString("complete -c addt -n '__fish_use_subcommand' -a 'completion' -d 'Generate shell completions'\n")
 	sb.WriteString("complete -c addt -n '__fish_use_subcommand' -a 'doctor' -d 'Check system health'\n")
 	sb.WriteString("complete -c addt -n '__fish_use_subcommand' -a 'version' -d 'Show version information'\n")
@@ -391,6 +454,17 @@ func fishCompletion() string {
 	// Containers subcommands
 	sb.WriteString("# Containers subcommands\n")
 	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from containers' -a 'list' -d 'List containers'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from containers' -a 'exec' -d 'Run a command in a container'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from containers' -a 'logs' -d 'Show container output'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from containers' -a 'cp' -d 'Copy files to or from a container'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from containers' -a 'inspect' -d 'Show container details'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from containers' -a 'usage' -d 'Show network usage per destination'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from containers' -a 'resume' -d 'Resume a session paused by an egress quota'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from containers' -a 'snapshot' -d 'Save a container snapshot'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from containers' -a 'restore' -d 'Restore a container snapshot'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from containers' -a 'snapshots' -d 'List, remove and prune snapshots'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from containers' -a 'stop' -d 'Stop a container'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from containers' -a 'rm' -d 'Remove a container'\n")
 	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from containers' -a 'clean' -d 'Remove all addt containers'\n")
 	sb.WriteString("\n")
 
@@ -398,6 +472,26 @@ func fishCompletion() string {
 	sb.WriteString("# Firewall subcommands\n")
 	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from firewall' -a 'global' -d 'Manage global firewall rules'\n")
 	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from firewall' -a 'project' -d 'Manage project firewall rules'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from firewall' -a 'learn' -d 'Review destinations recorded by run --firewall-learn'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from firewall' -a 'check' -d 'Show whether the rules allow a destination'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from firewall' -a 'apply' -d 'Reload the rules in running sessions'\n")
+	sb.WriteString("\n")
+
+	// Audit subcommands
+	sb.WriteString("# Audit subcommands\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from audit' -a 'list' -d 'Show matching audit events'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from audit' -a 'tail' -d 'Show the last audit events'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from audit' -a 'summary' -d 'Summarize audit events'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from audit' -a 'export' -d 'Export audit events as CSV or JSON'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from audit' -a 'types' -d 'List audit event types'\n")
+	sb.WriteString("\n")
+
+	// Cache subcommands
+	sb.WriteString("# Cache subcommands\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from cache' -a 'list' -d 'Show the size of the package cache'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from cache' -a 'warm' -d 'Cache the packages a lockfile pins'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from cache' -a 'clean' -d 'Remove cached packages'\n")
+	sb.WriteString("complete -c addt -n '__fish_seen_subcommand_from cache' -a 'path' -d 'Print the cache directory'\n")
 	sb.WriteString("\n")
 
 	// Extensions subcommands
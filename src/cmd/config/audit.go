@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	cfgtypes "github.com/jedi4ever/addt/config"
+	"github.com/jedi4ever/addt/config/security"
 )
 
 // ResolvedKey holds a config key with its resolved value and source.
@@ -22,9 +23,12 @@ type GroupPosture struct {
 }
 
 // AuditGroup defines a security audit category with its keys and evaluator.
+// Derived adds values computed from the config files rather than read from
+// a single key.
 type AuditGroup struct {
 	Name     string
 	Keys     []string
+	Derived  func(projectCfg, globalCfg *cfgtypes.GlobalConfig) []ResolvedKey
 	Evaluate func(resolved map[string]ResolvedKey) GroupPosture
 }
 
@@ -52,7 +56,10 @@ func GetAuditGroups() []AuditGroup {
 				"firewall.mode",
 				"security.network_mode",
 				"docker.dind.enable",
+				"ports.host_services",
+				"offline.enabled",
 			},
+			Derived:  deriveFirewallRules,
 			Evaluate: evaluateNetwork,
 		},
 		{
@@ -60,6 +67,8 @@ func GetAuditGroups() []AuditGroup {
 			Keys: []string{
 				"workdir.automount",
 				"workdir.readonly",
+				"workdir.overlay",
+				"workdir.worktree",
 				"security.read_only_rootfs",
 				"config.automount",
 				"config.readonly",
@@ -71,9 +80,14 @@ func GetAuditGroups() []AuditGroup {
 			Keys: []string{
 				"ssh.forward_keys",
 				"ssh.forward_mode",
+				"ssh.sign_policy",
+				"ssh.allowed_hosts",
 				"github.forward_token",
 				"github.scope_token",
+				"github.hold_token",
+				"github.app_id",
 				"security.isolate_secrets",
+				"security.api_proxy",
 			},
 			Evaluate: evaluateCredentials,
 		},
@@ -147,17 +161,111 @@ func evaluateNetwork(resolved map[string]ResolvedKey) GroupPosture {
 		tags = append(tags, "dind:on")
 	}
 
-	secure := fwOn && strings.EqualFold(fwMode, "strict") && netMode == "none" && !strings.EqualFold(dind, "true")
+	// Host ports the container can reach, e.g. "host-services:postgres:5432"
+	if hostServices := val(resolved, "ports.host_services"); hostServices != "" && hostServices != "-" {
+		tags = append(tags, "host-services:"+hostServices)
+	}
+
+	// Only the package mirrors are reachable
+	if strings.EqualFold(val(resolved, "offline.enabled"), "true") {
+		tags = append(tags, "offline:on")
+	}
+
+	// Rules that open up whole networks, ports or TLDs, or that don't parse
+	rules := val(resolved, "firewall.rules")
+	rulesOK := true
+	for _, problem := range []string{"broad", "invalid"} {
+		if strings.Contains(rules, problem+":") {
+			tags = append(tags, "rules:"+problem)
+			rulesOK = false
+		}
+	}
+
+	secure := fwOn && strings.EqualFold(fwMode, "strict") && netMode == "none" && !strings.EqualFold(dind, "true") && rulesOK
 	return GroupPosture{Secure: secure, Tags: tags}
 }
 
+// deriveFirewallRules summarizes the firewall rules in the config files,
+// e.g. "5 rules, broad: *:443", naming allow rules that are broad and
+// rules that don't parse
+func deriveFirewallRules(projectCfg, globalCfg *cfgtypes.GlobalConfig) []ResolvedKey {
+	var allowed, denied []string
+	source := "default"
+	for _, layer := range []struct {
+		name string
+		cfg  *cfgtypes.GlobalConfig
+	}{{"global", globalCfg}, {"project", projectCfg}} {
+		if layer.cfg == nil {
+			continue
+		}
+		n := len(allowed) + len(denied)
+		if fw := layer.cfg.Firewall; fw != nil {
+			allowed = append(allowed, fw.Allowed...)
+			denied = append(denied, fw.Denied...)
+		}
+		for _, ext := range layer.cfg.Extensions {
+			if ext != nil {
+				allowed = append(allowed, ext.FirewallAllowed...)
+				denied = append(denied, ext.FirewallDenied...)
+			}
+		}
+		if len(allowed)+len(denied) > n {
+			source = layer.name
+		}
+	}
+	total := len(allowed) + len(denied)
+	if total == 0 {
+		return []ResolvedKey{{Key: "firewall.rules", Value: "defaults", Source: source}}
+	}
+
+	var broad, invalid []string
+	for _, r := range allowed {
+		if security.IsRequestRule(r) {
+			rule, err := security.ParseRequestRule(r)
+			if err != nil {
+				invalid = append(invalid, r)
+			} else if !rule.Negate && rule.Host.Broad() {
+				broad = append(broad, r)
+			}
+			continue
+		}
+		rule, err := security.ParseFirewallRule(r)
+		if err != nil {
+			invalid = append(invalid, r)
+		} else if !rule.Negate && rule.Broad() {
+			broad = append(broad, r)
+		}
+	}
+	for _, r := range denied {
+		if err := security.ValidateFirewallRule(r); err != nil {
+			invalid = append(invalid, r)
+		}
+	}
+
+	value := fmt.Sprintf("%d rules", total)
+	if len(broad) > 0 {
+		value += ", broad: " + strings.Join(broad, " ")
+	}
+	if len(invalid) > 0 {
+		value += ", invalid: " + strings.Join(invalid, " ")
+	}
+	return []ResolvedKey{{Key: "firewall.rules", Value: value, Source: source}}
+}
+
 func evaluateFilesystem(resolved map[string]ResolvedKey) GroupPosture {
 	workdirRo := val(resolved, "workdir.readonly")
+	workdirOverlay := val(resolved, "workdir.overlay")
+	workdirWorktree := val(resolved, "workdir.worktree")
 	rootfsRo := val(resolved, "security.read_only_rootfs")
 
+	// An overlay keeps the agent's writes off the project until applied
 	var tags []string
 	if strings.EqualFold(workdirRo, "true") {
 		tags = append(tags, "workdir:ro")
+	} else if strings.EqualFold(workdirWorktree, "true") {
+		tags = append(tags, "workdir:worktree")
+	} else if strings.EqualFold(workdirOverlay, "true") {
+		tags = append(tags, "workdir:overlay")
 	} else {
 		tags = append(tags, "workdir:rw")
 	}
@@ -167,7 +275,9 @@ func evaluateFilesystem(resolved map[string]ResolvedKey) GroupPosture {
 		tags = append(tags, "rootfs:rw")
 	}
 
-	secure := strings.EqualFold(workdirRo, "true") && strings.EqualFold(rootfsRo, "true")
+	workdirProtected := strings.EqualFold(workdirRo, "true") ||
+		(strings.EqualFold(workdirOverlay, "true") && !strings.EqualFold(workdirWorktree, "true"))
+	secure := workdirProtected && strings.EqualFold(rootfsRo, "true")
 	return GroupPosture{Secure: secure, Tags: tags}
 }
 
@@ -180,18 +290,34 @@ func evaluateCredentials(resolved map[string]ResolvedKey) GroupPosture {
 
 	var tags []string
 
-	// SSH tag
+	// SSH tag; confirming signatures or limiting hosts puts agent
+	// forwarding behind the proxy
+	limitHosts := val(resolved, "ssh.allowed_hosts") != ""
+	if (strings.EqualFold(val(resolved, "ssh.sign_policy"), "confirm") || limitHosts) && strings.EqualFold(sshMode, "agent") {
+		sshMode = "proxy"
+	}
 	if !strings.EqualFold(sshFwd, "true") {
 		tags = append(tags, "ssh:off")
+	} else if strings.EqualFold(sshMode, "proxy") && strings.EqualFold(val(resolved, "ssh.sign_policy"), "confirm") {
+		tags = append(tags, "ssh:confirm")
 	} else if strings.EqualFold(sshMode, "proxy") {
 		tags = append(tags, "ssh:proxy")
 	} else {
 		tags = append(tags, "ssh:"+sshMode)
 	}
+	if strings.EqualFold(sshFwd, "true") && strings.EqualFold(sshMode, "proxy") && limitHosts {
+		tags = append(tags, "ssh:hosts")
+	}
 
-	// GitHub tag
-	if !strings.EqualFold(ghFwd, "true") {
+	// GitHub tag; with an App, git gets its repo-scoped tokens from the
+	// host. A held GH_TOKEN stays there, but git is handed the token
+	// itself, which isn't scoped.
+	if appID := val(resolved, "github.app_id"); strings.EqualFold(ghScope, "true") && appID != "" && appID != "-" {
+		tags = append(tags, "github:app")
+	} else if !strings.EqualFold(ghFwd, "true") {
 		tags = append(tags, "github:off")
+	} else if strings.EqualFold(ghScope, "true") && strings.EqualFold(val(resolved, "github.hold_token"), "true") {
+		tags = append(tags, "github:held-unscoped")
 	} else if strings.EqualFold(ghScope, "true") {
 		tags = append(tags, "github:scoped")
 	} else {
@@ -205,6 +331,11 @@ func evaluateCredentials(resolved map[string]ResolvedKey) GroupPosture {
 		tags = append(tags, "secrets:shared")
 	}
 
+	// API keys stay on the host behind the API proxy
+	if strings.EqualFold(val(resolved, "security.api_proxy"), "true") {
+		tags = append(tags, "api:proxy")
+	}
+
 	sshOk := !strings.EqualFold(sshFwd, "true") || strings.EqualFold(sshMode, "proxy")
 	ghOk := !strings.EqualFold(ghFwd, "true") || strings.EqualFold(ghScope, "true")
 	secretsOk := strings.EqualFold(isolate, "true")
@@ -327,6 +458,12 @@ func RunAudit(projectCfg, globalCfg *cfgtypes.GlobalConfig) AuditResult {
 			resolved[keyName] = rk
 			orderedKeys = append(orderedKeys, rk)
 		}
+		if g.Derived != nil {
+			for _, rk := range g.Derived(projectCfg, globalCfg) {
+				resolved[rk.Key] = rk
+				orderedKeys = append(orderedKeys, rk)
+			}
+		}
 
 		posture := g.Evaluate(resolved)
 		if !posture.Secure {
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"testing"
 
 	cfgtypes "github.com/jedi4ever/addt/config"
@@ -66,6 +67,73 @@ func TestNetworkPosture_Relaxed(t *testing.T) {
 	}
 }
 
+func TestNetworkPosture_BroadFirewallRules(t *testing.T) {
+	resolved := makeResolved(map[string]string{
+		"firewall.enabled":      "true",
+		"firewall.mode":         "strict",
+		"security.network_mode": "none",
+		"docker.dind.enable":    "false",
+		"firewall.rules":        "3 rules, broad: *:443",
+	})
+	posture := evaluateNetwork(resolved)
+	if posture.Secure {
+		t.Errorf("expected relaxed posture with a broad rule, got secure; tags: %v", posture.Tags)
+	}
+	if !strings.Contains(strings.Join(posture.Tags, " "), "rules:broad") {
+		t.Errorf("expected rules:broad tag, got %v", posture.Tags)
+	}
+}
+
+func TestNetworkPosture_HostServices(t *testing.T) {
+	resolved := makeResolved(map[string]string{
+		"firewall.enabled":      "true",
+		"firewall.mode":         "strict",
+		"security.network_mode": "",
+		"docker.dind.enable":    "false",
+		"ports.host_services":   "postgres:5432,redis:6379",
+	})
+	posture := evaluateNetwork(resolved)
+	if !strings.Contains(strings.Join(posture.Tags, " "), "host-services:postgres:5432,redis:6379") {
+		t.Errorf("expected host-services tag, got %v", posture.Tags)
+	}
+}
+
+func TestNetworkPosture_Offline(t *testing.T) {
+	resolved := makeResolved(map[string]string{
+		"firewall.enabled":      "true",
+		"firewall.mode":         "strict",
+		"security.network_mode": "none",
+		"docker.dind.enable":    "false",
+		"offline.enabled":       "true",
+	})
+	posture := evaluateNetwork(resolved)
+	if !posture.Secure {
+		t.Errorf("expected secure posture offline, got relaxed; tags: %v", posture.Tags)
+	}
+	if !strings.Contains(strings.Join(posture.Tags, " "), "offline:on") {
+		t.Errorf("expected offline:on tag, got %v", posture.Tags)
+	}
+}
+
+func TestDeriveFirewallRules(t *testing.T) {
+	globalCfg := &cfgtypes.GlobalConfig{
+		Firewall: &cfgtypes.FirewallSettings{Allowed: []string{"*.githubusercontent.com", "!tcp/22", "GET api.github.com/repos/ourorg/*"}},
+	}
+	projectCfg := &cfgtypes.GlobalConfig{
+		Firewall: &cfgtypes.FirewallSettings{Allowed: []string{"10.0.0.0/8", "host:http", "POST *"}, Denied: []string{"*", "DELETE api.github.com"}},
+	}
+	got := deriveFirewallRules(projectCfg, globalCfg)
+	want := ResolvedKey{Key: "firewall.rules", Value: "8 rules, broad: 10.0.0.0/8 POST *, invalid: host:http", Source: "project"}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("deriveFirewallRules() = %+v, want %+v", got, want)
+	}
+
+	got = deriveFirewallRules(&cfgtypes.GlobalConfig{}, &cfgtypes.GlobalConfig{})
+	if len(got) != 1 || got[0].Value != "defaults" {
+		t.Errorf("deriveFirewallRules(empty) = %+v, want defaults", got)
+	}
+}
+
 func TestFilesystemPosture_Secure(t *testing.T) {
 	resolved := makeResolved(map[string]string{
 		"workdir.automount":         "true",
@@ -94,6 +162,24 @@ func TestFilesystemPosture_Relaxed(t *testing.T) {
 	}
 }
 
+func TestFilesystemPosture_Overlay(t *testing.T) {
+	resolved := makeResolved(map[string]string{
+		"workdir.automount":         "true",
+		"workdir.readonly":          "false",
+		"workdir.overlay":           "true",
+		"security.read_only_rootfs": "true",
+		"config.automount":          "false",
+		"config.readonly":           "false",
+	})
+	posture := evaluateFilesystem(resolved)
+	if !posture.Secure {
+		t.Errorf("expected secure posture, got relaxed; tags: %v", posture.Tags)
+	}
+	if posture.Tags[0] != "workdir:overlay" {
+		t.Errorf("expected workdir:overlay tag, got %v", posture.Tags)
+	}
+}
+
 func TestCredentialsPosture_Secure(t *testing.T) {
 	resolved := makeResolved(map[string]string{
 		"ssh.forward_keys":         "true",
@@ -122,6 +208,71 @@ func TestCredentialsPosture_Relaxed(t *testing.T) {
 	}
 }
 
+func TestCredentialsPosture_APIProxy(t *testing.T) {
+	resolved := makeResolved(map[string]string{
+		"ssh.forward_keys":         "false",
+		"github.forward_token":     "false",
+		"security.isolate_secrets": "true",
+		"security.api_proxy":       "true",
+	})
+	posture := evaluateCredentials(resolved)
+	if !strings.Contains(strings.Join(posture.Tags, " "), "api:proxy") {
+		t.Errorf("expected api:proxy tag, got %v", posture.Tags)
+	}
+}
+
+func TestCredentialsPosture_SSHConfirm(t *testing.T) {
+	resolved := makeResolved(map[string]string{
+		"ssh.forward_keys":         "true",
+		"ssh.forward_mode":         "agent",
+		"ssh.sign_policy":          "confirm",
+		"github.forward_token":     "false",
+		"security.isolate_secrets": "true",
+	})
+	posture := evaluateCredentials(resolved)
+	if !strings.Contains(strings.Join(posture.Tags, " "), "ssh:confirm") {
+		t.Errorf("expected ssh:confirm tag, got %v", posture.Tags)
+	}
+	if !posture.Secure {
+		t.Errorf("expected secure posture, got relaxed; tags: %v", posture.Tags)
+	}
+}
+
+func TestCredentialsPosture_SSHAllowedHosts(t *testing.T) {
+	resolved := makeResolved(map[string]string{
+		"ssh.forward_keys":         "true",
+		"ssh.forward_mode":         "agent",
+		"ssh.allowed_hosts":        "github.com",
+		"github.forward_token":     "false",
+		"security.isolate_secrets": "true",
+	})
+	posture := evaluateCredentials(resolved)
+	tags := strings.Join(posture.Tags, " ")
+	if !strings.Contains(tags, "ssh:proxy") || !strings.Contains(tags, "ssh:hosts") {
+		t.Errorf("expected ssh:proxy and ssh:hosts tags, got %v", posture.Tags)
+	}
+	if !posture.Secure {
+		t.Errorf("expected secure posture, got relaxed; tags: %v", posture.Tags)
+	}
+}
+
+func TestCredentialsPosture_GitHubApp(t *testing.T) {
+	resolved := makeResolved(map[string]string{
+		"ssh.forward_keys":         "false",
+		"github.forward_token":     "false",
+		"github.scope_token":       "true",
+		"github.app_id":            "12345",
+		"security.isolate_secrets": "true",
+	})
+	posture := evaluateCredentials(resolved)
+	if !strings.Contains(strings.Join(posture.Tags, " "), "github:app") {
+		t.Errorf("expected github:app tag, got %v", posture.Tags)
+	}
+	if !posture.Secure {
+		t.Errorf("expected secure posture, got relaxed; tags: %v", posture.Tags)
+	}
+}
+
 func TestLimitsPosture_Secure(t *testing.T) {
 	resolved := makeResolved(map[string]string{
 		"container.cpus":      "2",
@@ -249,3 +400,17 @@ func TestRunAudit_AllDefaults(t *testing.T) {
 		}
 	}
 }
+
+func TestCredentialsPosture_GitHubHeld(t *testing.T) {
+	resolved := makeResolved(map[string]string{
+		"ssh.forward_keys":         "false",
+		"github.forward_token":     "true",
+		"github.scope_token":       "true",
+		"github.hold_token":        "true",
+		"security.isolate_secrets": "true",
+	})
+	posture := evaluateCredentials(resolved)
+	if !strings.Contains(strings.Join(posture.Tags, " "), "github:held-unscoped") {
+		t.Errorf("expected github:held-unscoped tag, got %v", posture.Tags)
+	}
+}
@@ -12,15 +12,16 @@ func TestKeyValidation(t *testing.T) {
 		"container.cpus", "container.memory",
 		"docker.dind.enable", "docker.dind.mode",
 		"env_file_load", "env_file",
-		"firewall.enabled", "firewall.mode",
+		"firewall.enabled", "firewall.mode", "firewall.proxy", "firewall.intercept",
+		"firewall.max_egress", "firewall.max_requests", "firewall.quota_action",
 		"github.forward_token", "github.token_source",
 		"gpg.forward", "gpg.allowed_key_ids",
 		"log.enabled", "log.output", "log.file", "log.dir", "log.level", "log.modules",
 		"log.rotate", "log.max_size", "log.max_files",
-		"node_version", "go_version",
-		"persistent", "ports.forward", "ports.expose", "ports.inject_system_prompt", "ports.range_start",
+		"node_version", "go_version", "offline.enabled", "offline.fetch",
+		"persistent", "ports.forward", "ports.expose", "ports.inject_system_prompt", "ports.host_services", "ports.range_start",
 		"vm.cpus", "vm.memory",
-		"workdir.path", "workdir.automount", "workdir.readonly",
+		"workdir.path", "workdir.automount", "workdir.readonly", "workdir.overlay", "workdir.worktree", "workdir.worktree_name",
 	}
 
 	for _, key := range validKeys {
@@ -196,6 +197,10 @@ func TestGetDefaultValue(t *testing.T) {
 		{"node_version", "22"},
 		{"firewall.enabled", "false"},
 		{"firewall.mode", "strict"},
+		{"firewall.proxy", "true"},
+		{"firewall.intercept", "false"},
+		{"firewall.quota_action", "pause"},
+		{"offline.fetch", "true"},
 		{"persistent", "false"},
 		{"workdir.automount", "true"},
 	}
@@ -12,9 +12,9 @@ func TestRegistryLoadsAllKeys(t *testing.T) {
 	if len(allKeyDefs) == 0 {
 		t.Fatal("allKeyDefs is empty, YAML not loaded")
 	}
-	// We expect 78 keys total
-	if len(allKeyDefs) != 78 {
-		t.Errorf("expected 78 key defs, got %d", len(allKeyDefs))
+	// We expect 100 keys total
+	if len(allKeyDefs) != 100 {
+		t.Errorf("expected 100 key defs, got %d", len(allKeyDefs))
 	}
 }
 
@@ -81,8 +81,8 @@ func TestRegistryIsValidKey(t *testing.T) {
 
 func TestRegistryGetKeys(t *testing.T) {
 	keys := registryGetKeys()
-	if len(keys) != 78 {
-		t.Errorf("registryGetKeys() returned %d keys, want 78", len(keys))
+	if len(keys) != 100 {
+		t.Errorf("registryGetKeys() returned %d keys, want 100", len(keys))
 	}
 	// Verify sorted
 	for i := 1; i < len(keys); i++ {
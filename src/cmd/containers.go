@@ -1,10 +1,18 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/core"
 	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/util"
 )
 
 // HandleContainersCommand handles the containers subcommand using a provider
@@ -25,9 +33,9 @@ func HandleContainersCommand(prov provider.Provider, cfg *provider.Config, args
 			os.Exit(1)
 		}
 		fmt.Printf("Persistent %s environments:\n", prov.GetName())
-		fmt.Println("NAME\t\t\t\tSTATUS\t\tCREATED")
+		fmt.Println("NAME\t\t\t\tSTATUS\t\tCREATED\t\tNETWORK")
 		for _, env := range envs {
-			fmt.Printf("%s\t%s\t%s\n", env.Name, env.Status, env.CreatedAt)
+			fmt.Printf("%s\t%s\t%s\t%s\n", env.Name, env.Status, env.CreatedAt, listNetworkUsage(env.Name))
 		}
 	case "stop":
 		if len(args) < 2 {
@@ -47,6 +55,66 @@ func HandleContainersCommand(prov provider.Provider, cfg *provider.Config, args
 			fmt.Printf("Error removing environment: %v\n", err)
 			os.Exit(1)
 		}
+		removeWorktree(args[1])
+		security.RemoveNetworkUsage(args[1])
+		security.RemoveSessionTokens(args[1])
+	case "usage":
+		if len(args) < 2 {
+			fmt.Println("Usage: addt containers usage <name>")
+			os.Exit(1)
+		}
+		printNetworkUsage(args[1])
+	case "resume":
+		if len(args) < 2 {
+			fmt.Println("Usage: addt containers resume <name>")
+			os.Exit(1)
+		}
+		if !prov.IsRunning(args[1]) {
+			fmt.Printf("Error: %s is not running\n", args[1])
+			os.Exit(1)
+		}
+		if err := security.RequestEgressResume(args[1]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Asked %s to resume with another egress quota\n", args[1])
+	case "exec":
+		handleContainersExec(prov, args[1:])
+	case "logs":
+		handleContainersLogs(prov, args[1:])
+	case "cp":
+		handleContainersCp(prov, args[1:])
+	case "inspect":
+		if len(args) < 2 {
+			fmt.Println("Usage: addt containers inspect <name>")
+			os.Exit(1)
+		}
+		info, err := prov.Inspect(args[1])
+		if err != nil {
+			fmt.Printf("Error inspecting environment: %v\n", err)
+			os.Exit(1)
+		}
+		data, _ := json.MarshalIndent(info, "", "  ")
+		fmt.Println(string(data))
+	case "snapshot":
+		handleContainersSnapshot(prov, args[1:])
+	case "restore":
+		if len(args) != 3 {
+			fmt.Println("Usage: addt containers restore <name> <tag>")
+			os.Exit(1)
+		}
+		name, tag := args[1], args[2]
+		if err := prov.Restore(name, tag); err != nil {
+			fmt.Printf("Error restoring snapshot: %v\n", err)
+			os.Exit(1)
+		}
+		if prov.Exists(name) {
+			fmt.Printf("✓ Restored %s from snapshot %s\n", name, tag)
+		} else {
+			fmt.Printf("✓ %s will be recreated from snapshot %s on its next run\n", name, tag)
+		}
+	case "snapshots":
+		handleContainersSnapshots(prov, args[1:])
 	case "clean":
 		envs, err := prov.List()
 		if err != nil {
@@ -65,6 +133,9 @@ func HandleContainersCommand(prov provider.Provider, cfg *provider.Config, args
 				fmt.Printf("Failed to remove: %s (%v)\n", env.Name, err)
 			} else {
 				fmt.Printf("Removed: %s\n", env.Name)
+				removeWorktree(env.Name)
+				security.RemoveNetworkUsage(env.Name)
+				security.RemoveSessionTokens(env.Name)
 			}
 		}
 		if len(failed) > 0 {
@@ -78,18 +149,309 @@ func HandleContainersCommand(prov provider.Provider, cfg *provider.Config, args
 	}
 }
 
+// handleContainersExec runs a command in a running environment, with a
+// TTY when -t is given: exec [-t] <name> [--] <command>...
+func handleContainersExec(prov provider.Provider, args []string) {
+	tty := false
+	if len(args) > 0 && args[0] == "-t" {
+		tty = true
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		fmt.Println("Usage: addt containers exec [-t] <name> [--] <command>...")
+		os.Exit(1)
+	}
+	name, command := args[0], args[1:]
+	if command[0] == "--" {
+		command = command[1:]
+	}
+	if len(command) == 0 {
+		fmt.Println("Usage: addt containers exec [-t] <name> [--] <command>...")
+		os.Exit(1)
+	}
+
+	if err := prov.Exec(name, command, tty, provider.Stdio{}); err != nil {
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// handleContainersLogs prints an environment's output: logs [-f] <name>
+func handleContainersLogs(prov provider.Provider, args []string) {
+	follow := false
+	if len(args) > 0 && (args[0] == "-f" || args[0] == "--follow") {
+		follow = true
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		fmt.Println("Usage: addt containers logs [-f] <name>")
+		os.Exit(1)
+	}
+	if err := prov.Logs(args[0], follow, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// handleContainersCp copies between the host and an environment, naming
+// the environment side <name>:<path> like docker cp
+func handleContainersCp(prov provider.Provider, args []string) {
+	if len(args) != 2 {
+		fmt.Println("Usage: addt containers cp <name>:<path> <host-path>")
+		fmt.Println("       addt containers cp <host-path> <name>:<path>")
+		os.Exit(1)
+	}
+	src, dst := args[0], args[1]
+	srcName, srcPath, srcInEnv := splitEnvPath(src)
+	dstName, dstPath, dstInEnv := splitEnvPath(dst)
+
+	var err error
+	switch {
+	case srcInEnv && !dstInEnv:
+		err = prov.CopyFrom(srcName, srcPath, dst)
+	case dstInEnv && !srcInEnv:
+		err = prov.CopyTo(dstName, src, dstPath)
+	default:
+		fmt.Println("Exactly one of source and destination must be <name>:<path>")
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Error copying: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// handleContainersSnapshot saves a snapshot: snapshot [--checkpoint] <name> [tag]
+func handleContainersSnapshot(prov provider.Provider, args []string) {
+	checkpoint := false
+	if len(args) > 0 && args[0] == "--checkpoint" {
+		checkpoint = true
+		args = args[1:]
+	}
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Println("Usage: addt containers snapshot [--checkpoint] <name> [tag]")
+		os.Exit(1)
+	}
+	tag := provider.DefaultSnapshotTag(time.Now())
+	if len(args) == 2 {
+		tag = args[1]
+	}
+	snapshot, err := prov.Snapshot(args[0], tag, checkpoint)
+	if err != nil {
+		fmt.Printf("Error creating snapshot: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Saved snapshot %s of %s (%s)\n", snapshot.Tag, snapshot.Environment, snapshot.Method)
+}
+
+// handleContainersSnapshots lists, removes and prunes snapshots
+func handleContainersSnapshots(prov provider.Provider, args []string) {
+	action := "list"
+	if len(args) > 0 && (args[0] == "rm" || args[0] == "remove" || args[0] == "prune" || args[0] == "list" || args[0] == "ls") {
+		action, args = args[0], args[1:]
+	}
+
+	switch action {
+	case "list", "ls":
+		if len(args) > 1 {
+			fmt.Println("Usage: addt containers snapshots [name]")
+			os.Exit(1)
+		}
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		snapshots, err := prov.ListSnapshots(name)
+		if err != nil {
+			fmt.Printf("Error listing snapshots: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("NAME\t\t\t\tTAG\t\tMETHOD\t\tCREATED")
+		for _, s := range snapshots {
+			fmt.Printf("%s\t%s\t%s\t%s\n", s.Environment, s.Tag, s.Method, s.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+		}
+	case "rm", "remove":
+		if len(args) != 2 {
+			fmt.Println("Usage: addt containers snapshots rm <name> <tag>")
+			os.Exit(1)
+		}
+		if err := prov.RemoveSnapshot(args[0], args[1]); err != nil {
+			fmt.Printf("Error removing snapshot: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed: %s %s\n", args[0], args[1])
+	case "prune":
+		handleSnapshotsPrune(prov, args)
+	}
+}
+
+// handleSnapshotsPrune removes snapshots of environments that no longer
+// exist, and with --keep N all but each environment's N newest:
+// snapshots prune [name] [--keep N]
+func handleSnapshotsPrune(prov provider.Provider, args []string) {
+	usage := func() {
+		fmt.Println("Usage: addt containers snapshots prune [name] [--keep N]")
+		os.Exit(1)
+	}
+	name, keep := "", 0
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--keep" && i+1 < len(args):
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 1 {
+				usage()
+			}
+			keep = n
+			i++
+		case name == "" && !strings.HasPrefix(args[i], "-"):
+			name = args[i]
+		default:
+			usage()
+		}
+	}
+
+	snapshots, err := prov.ListSnapshots(name)
+	if err != nil {
+		fmt.Printf("Error listing snapshots: %v\n", err)
+		os.Exit(1)
+	}
+	prune := snapshotsToPrune(snapshots, prov.Exists, keep)
+	if len(prune) == 0 {
+		fmt.Println("No snapshots to prune")
+		return
+	}
+	failed := 0
+	for _, s := range prune {
+		if err := prov.RemoveSnapshot(s.Environment, s.Tag); err != nil {
+			failed++
+			fmt.Printf("Failed to remove: %s %s (%v)\n", s.Environment, s.Tag, err)
+		} else {
+			fmt.Printf("Removed: %s %s\n", s.Environment, s.Tag)
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("Failed to remove %d snapshot(s)\n", failed)
+		os.Exit(1)
+	}
+}
+
+// snapshotsToPrune picks the snapshots of environments that no longer
+// exist and, when keep > 0, all but the keep newest of the others.
+// snapshots are grouped by environment, oldest first (as ListSnapshots
+// returns them).
+func snapshotsToPrune(snapshots []provider.Snapshot, exists func(string) bool, keep int) []provider.Snapshot {
+	count := make(map[string]int)
+	for _, s := range snapshots {
+		count[s.Environment]++
+	}
+	var prune []provider.Snapshot
+	seen := make(map[string]int)
+	for _, s := range snapshots {
+		seen[s.Environment]++
+		switch {
+		case !exists(s.Environment):
+			prune = append(prune, s)
+		case keep > 0 && count[s.Environment]-seen[s.Environment] >= keep:
+			prune = append(prune, s)
+		}
+	}
+	return prune
+}
+
+// splitEnvPath splits "<name>:<path>". Host paths are recognized by a
+// leading "/" or "." or a missing colon, as in docker cp.
+func splitEnvPath(arg string) (name, path string, ok bool) {
+	if strings.HasPrefix(arg, "/") || strings.HasPrefix(arg, ".") {
+		return "", "", false
+	}
+	name, path, ok = strings.Cut(arg, ":")
+	if !ok || name == "" || path == "" {
+		return "", "", false
+	}
+	return name, path, true
+}
+
 func printContainersHelp() {
 	fmt.Println(`Usage: addt containers [command]
 
 Commands:
-  list, ls      List all persistent containers
-  stop <name>   Stop a persistent container
-  rm <name>     Remove a persistent container
-  clean         Remove all persistent containers
+  list, ls                  List all persistent containers
+  stop <name>               Stop a persistent container
+  rm <name>                 Remove a persistent container
+  usage <name>              Show a container's network usage per destination
+  resume <name>             Resume a session paused by an egress quota
+  exec [-t] <name> -- <cmd> Run a command in a running container
+  logs [-f] <name>          Show a container's output
+  cp <src> <dst>            Copy files; one side is <name>:<path>
+  inspect <name>            Show a container's details as JSON
+  snapshot [--checkpoint] <name> [tag]
+                            Save a snapshot (tag defaults to a timestamp)
+  restore <name> <tag>      Replace a container with a snapshot
+  snapshots [name]          List snapshots
+  snapshots rm <name> <tag> Remove a snapshot
+  snapshots prune [name] [--keep N]
+                            Remove snapshots of removed containers, and
+                            all but the N newest of the others
+  clean                     Remove all persistent containers
 
 Examples:
   addt containers list
+  addt containers exec -t my-container -- bash
+  addt containers cp my-container:/workspace/out.log .
+  addt containers snapshot my-container before-upgrade
+  addt containers restore my-container before-upgrade
   addt containers stop my-container
   addt containers rm my-container
   addt containers clean`)
 }
+
+// listNetworkUsage summarizes a container's last session network usage for
+// the list, or "-" without one
+func listNetworkUsage(name string) string {
+	if summary := core.NetworkUsageSummary(name); summary != "" {
+		return summary
+	}
+	return "-"
+}
+
+// printNetworkUsage prints a container's last session network usage, per
+// destination
+func printNetworkUsage(name string) {
+	usage, err := security.LoadNetworkUsage(name)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if usage == nil {
+		fmt.Printf("No network usage recorded for %s (it's recorded by the egress proxy of firewalled sessions)\n", name)
+		return
+	}
+	fmt.Printf("Session started %s, updated %s\n", usage.Started.Local().Format("2006-01-02 15:04:05"), usage.Updated.Local().Format("2006-01-02 15:04:05"))
+	fmt.Println("SENT\t\tRECEIVED\tREQUESTS\tHOST")
+	for _, d := range usage.Destinations {
+		fmt.Printf("%-10s\t%-10s\t%d\t\t%s\n", util.FormatBytes(d.BytesOut), util.FormatBytes(d.BytesIn), d.Requests, d.Host)
+	}
+	fmt.Printf("Total: %s\n", usage.Total())
+}
+
+// removeWorktree removes the git worktree of a removed environment, if it
+// had one. A worktree with uncommitted changes is kept, as is a branch with
+// commits the checkout doesn't have.
+func removeWorktree(name string) {
+	w, err := core.RemoveWorktree(name)
+	if w == nil {
+		return
+	}
+	if err != nil {
+		fmt.Printf("⚠ Kept worktree %s: %v\n", w.Dir, err)
+		return
+	}
+	fmt.Printf("Removed worktree %s\n", w.Dir)
+	if w.BranchExists() {
+		fmt.Printf("Kept branch %s with unmerged commits\n", w.Branch)
+	}
+}
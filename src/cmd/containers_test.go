@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jedi4ever/addt/provider"
+)
+
+func TestSplitEnvPath(t *testing.T) {
+	tests := []struct {
+		arg      string
+		wantName string
+		wantPath string
+		wantOK   bool
+	}{
+		{"addt-persistent-x-1234:/workspace/out.log", "addt-persistent-x-1234", "/workspace/out.log", true},
+		{"my-container:notes.txt", "my-container", "notes.txt", true},
+		{"./a:b", "", "", false},
+		{"/tmp/a:b", "", "", false},
+		{"out.log", "", "", false},
+		{":/workspace", "", "", false},
+		{"my-container:", "", "", false},
+	}
+
+	for _, tc := range tests {
+		name, path, ok := splitEnvPath(tc.arg)
+		if name != tc.wantName || path != tc.wantPath || ok != tc.wantOK {
+			t.Errorf("splitEnvPath(%q) = (%q, %q, %v), want (%q, %q, %v)", tc.arg, name, path, ok, tc.wantName, tc.wantPath, tc.wantOK)
+		}
+	}
+}
+
+func TestSnapshotsToPrune(t *testing.T) {
+	snapshots := []provider.Snapshot{
+		{Environment: "addt-persistent-a", Tag: "1"},
+		{Environment: "addt-persistent-a", Tag: "2"},
+		{Environment: "addt-persistent-a", Tag: "3"},
+		{Environment: "addt-persistent-gone", Tag: "1"},
+	}
+	exists := func(name string) bool { return name != "addt-persistent-gone" }
+
+	tags := func(prune []provider.Snapshot) []string {
+		var got []string
+		for _, s := range prune {
+			got = append(got, s.Environment+":"+s.Tag)
+		}
+		return got
+	}
+	if got := tags(snapshotsToPrune(snapshots, exists, 0)); !reflect.DeepEqual(got, []string{"addt-persistent-gone:1"}) {
+		t.Errorf("prune without keep = %v", got)
+	}
+	want := []string{"addt-persistent-a:1", "addt-persistent-gone:1"}
+	if got := tags(snapshotsToPrune(snapshots, exists, 2)); !reflect.DeepEqual(got, want) {
+		t.Errorf("prune --keep 2 = %v, want %v", got, want)
+	}
+}
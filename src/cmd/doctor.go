@@ -86,6 +86,10 @@ func runAllChecks() []DoctorCheck {
 	// Container runtime checks
 	checks = append(checks, checkDocker())
 	checks = append(checks, checkPodman())
+	checks = append(checks, checkNerdctl())
+	if runtime.GOOS == "linux" {
+		checks = append(checks, checkBwrap())
+	}
 
 	// Git check
 	checks = append(checks, checkGit())
@@ -180,6 +184,84 @@ func checkPodman() DoctorCheck {
 	return check
 }
 
+func checkNerdctl() DoctorCheck {
+	check := DoctorCheck{Name: "nerdctl"}
+
+	nerdctlPath, err := exec.LookPath("nerdctl")
+	if err != nil {
+		check.Status = "warn"
+		check.Message = "not installed (optional)"
+		check.Fix = "Install nerdctl from https://github.com/containerd/nerdctl/releases"
+		return check
+	}
+
+	// Get nerdctl version (works without containerd)
+	cmd := exec.Command(nerdctlPath, "--version")
+	output, err := cmd.Output()
+	if err != nil {
+		check.Status = "warn"
+		check.Message = "installed but not working"
+		check.Fix = "Check nerdctl installation: nerdctl info"
+		return check
+	}
+	// Parse "nerdctl version X.Y.Z" -> "X.Y.Z"
+	version := strings.TrimPrefix(strings.TrimSpace(string(output)), "nerdctl version ")
+
+	// containerd must be reachable for nerdctl to run anything
+	if err := exec.Command(nerdctlPath, "info").Run(); err != nil {
+		check.Status = "warn"
+		check.Message = fmt.Sprintf("installed (v%s) but containerd not running", version)
+		check.Fix = "Run: containerd-rootless-setuptool.sh install (rootless) or sudo systemctl start containerd"
+		return check
+	}
+
+	check.Status = "ok"
+	check.Message = fmt.Sprintf("running (v%s)", version)
+
+	// Image builds need BuildKit
+	if _, err := exec.LookPath("buildctl"); err != nil {
+		check.Status = "warn"
+		check.Message += ", BuildKit missing"
+		check.Fix = "Run: containerd-rootless-setuptool.sh install-buildkit"
+	}
+
+	return check
+}
+
+func checkBwrap() DoctorCheck {
+	check := DoctorCheck{Name: "bubblewrap"}
+
+	bwrapPath, err := exec.LookPath("bwrap")
+	if err != nil {
+		check.Status = "warn"
+		check.Message = "not installed (optional, for ADDT_PROVIDER=sandbox)"
+		check.Fix = "Install bubblewrap (e.g. apt install bubblewrap)"
+		return check
+	}
+
+	output, err := exec.Command(bwrapPath, "--version").Output()
+	if err != nil {
+		check.Status = "warn"
+		check.Message = "installed but not working"
+		check.Fix = "Check bubblewrap installation: bwrap --version"
+		return check
+	}
+	// Parse "bubblewrap X.Y.Z" -> "X.Y.Z"
+	version := strings.TrimPrefix(strings.TrimSpace(string(output)), "bubblewrap ")
+
+	// The sandbox provider needs unprivileged user namespaces
+	if err := exec.Command(bwrapPath, "--unshare-user", "--ro-bind", "/", "/", "true").Run(); err != nil {
+		check.Status = "warn"
+		check.Message = fmt.Sprintf("installed (v%s) but user namespaces are disabled", version)
+		check.Fix = "Run: sudo sysctl kernel.unprivileged_userns_clone=1 (or allow bwrap in AppArmor)"
+		return check
+	}
+
+	check.Status = "ok"
+	check.Message = fmt.Sprintf("available (v%s)", version)
+	return check
+}
+
 func checkGit() DoctorCheck {
 	check := DoctorCheck{Name: "Git"}
 
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	extcmd "github.com/jedi4ever/addt/cmd/extensions"
+	"github.com/jedi4ever/addt/config"
+	"github.com/jedi4ever/addt/core"
+	"github.com/jedi4ever/addt/util"
+	"github.com/jedi4ever/addt/util/terminal"
+)
+
+// isFanoutFlag reports whether a run argument starts a fan-out
+func isFanoutFlag(arg string) bool {
+	return arg == "--fanout" || strings.HasPrefix(arg, "--fanout=")
+}
+
+// parseFanoutArgs parses run --fanout <N|ext1,ext2,...> [extension] [args...].
+// A count runs that many attempts of the extension that follows it; a list
+// runs each extension in it, numbering those named more than once.
+func parseFanoutArgs(args []string) ([]core.FanoutMember, []string, error) {
+	if len(args) == 0 || !isFanoutFlag(args[0]) {
+		return nil, nil, fmt.Errorf("expected --fanout")
+	}
+	spec := strings.TrimPrefix(args[0], "--fanout=")
+	rest := args[1:]
+	if args[0] == "--fanout" {
+		if len(rest) == 0 {
+			return nil, nil, fmt.Errorf("--fanout requires a count or a list of extensions")
+		}
+		spec, rest = rest[0], rest[1:]
+	}
+
+	var extensions []string
+	if n, err := strconv.Atoi(spec); err == nil {
+		if n < 1 {
+			return nil, nil, fmt.Errorf("invalid fan-out count %d", n)
+		}
+		if len(rest) == 0 {
+			return nil, nil, fmt.Errorf("--fanout %d requires an extension", n)
+		}
+		for i := 0; i < n; i++ {
+			extensions = append(extensions, rest[0])
+		}
+		rest = rest[1:]
+	} else {
+		for _, ext := range strings.Split(spec, ",") {
+			if ext = strings.TrimSpace(ext); ext != "" {
+				extensions = append(extensions, ext)
+			}
+		}
+		if len(extensions) == 0 {
+			return nil, nil, fmt.Errorf("--fanout requires a count or a list of extensions")
+		}
+	}
+
+	count := map[string]int{}
+	for _, ext := range extensions {
+		count[ext]++
+	}
+	seen := map[string]int{}
+	members := make([]core.FanoutMember, len(extensions))
+	for i, ext := range extensions {
+		seen[ext]++
+		label := ext
+		if count[ext] > 1 {
+			label = fmt.Sprintf("%s#%d", ext, seen[ext])
+		}
+		members[i] = core.FanoutMember{Label: label, Extension: ext}
+	}
+	return members, rest, nil
+}
+
+// HandleFanoutCommand runs several agents on the same arguments at once,
+// compares their workspaces and keeps the ones picked:
+// run --fanout <N|ext1,ext2,...> [extension] [args...]
+func HandleFanoutCommand(args []string, version, defaultNodeVersion, defaultGoVersion, defaultUvVersion string, defaultPortRangeStart int) {
+	members, rest, err := parseFanoutArgs(args)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Println("Usage: addt run --fanout <count> <extension> [args...]")
+		fmt.Println("       addt run --fanout <ext1,ext2,...> [args...]")
+		os.Exit(1)
+	}
+
+	// Build each extension's image up front, so members don't build the
+	// same image at once and durations compare the agents alone
+	var runner *core.Runner
+	built := map[string]bool{}
+	for _, m := range members {
+		if built[m.Extension] {
+			continue
+		}
+		built[m.Extension] = true
+		if !extcmd.Exists(m.Extension) {
+			fmt.Printf("Error: extension '%s' does not exist\n", m.Extension)
+			fmt.Println("Run 'addt extensions list' to see available extensions")
+			os.Exit(1)
+		}
+		os.Setenv("ADDT_EXTENSIONS", m.Extension)
+		os.Setenv("ADDT_COMMAND", extcmd.GetEntrypoint(m.Extension))
+
+		cfg := config.LoadConfig(version, defaultNodeVersion, defaultGoVersion, defaultUvVersion, defaultPortRangeStart)
+		if runner == nil {
+			cfg.LogDir = util.ExpandTilde(cfg.LogDir)
+			util.InitLoggerFull(cfg.LogFile, cfg.LogDir, cfg.LogOutput, cfg.LogEnabled, cfg.LogLevel, cfg.LogModules, cfg.LogRotate, cfg.LogMaxSize, cfg.LogMaxFiles)
+		}
+		providerCfg := newProviderConfig(cfg)
+		prov, err := NewProvider(cfg.Provider, providerCfg)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := prov.Initialize(providerCfg); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		providerCfg.ImageName = prov.DetermineImageName()
+		if err := prov.BuildIfNeeded(false, false); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if runner == nil {
+			runner = core.NewRunner(prov, providerCfg)
+		}
+	}
+
+	fmt.Printf("Running %d agents: %s\n", len(members), fanoutLabels(members))
+	results, err := runner.Fanout(members, rest, os.Stdout)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println()
+	for _, line := range formatFanoutTable(results) {
+		fmt.Println(line)
+	}
+	fmt.Println()
+
+	keep := make([]bool, len(results))
+	for i := range keep {
+		keep[i] = true
+	}
+	if terminal.IsTerminal() {
+		keep = promptFanoutChoice(results)
+	}
+	for i, res := range results {
+		if keep[i] {
+			continue
+		}
+		if err := res.Discard(); err != nil {
+			fmt.Printf("Error discarding %s: %v\n", res.Label, err)
+			continue
+		}
+		if res.Stat.Files > 0 {
+			fmt.Printf("✓ Discarded %s\n", res.Label)
+		}
+	}
+	for i, res := range results {
+		if keep[i] {
+			printFanoutReview(res)
+		}
+	}
+
+	for _, res := range results {
+		if res.ExitCode == 0 {
+			return
+		}
+	}
+	os.Exit(1)
+}
+
+// fanoutLabels lists the members' labels
+func fanoutLabels(members []core.FanoutMember) string {
+	labels := make([]string, len(members))
+	for i, m := range members {
+		labels[i] = m.Label
+	}
+	return strings.Join(labels, ", ")
+}
+
+// formatFanoutTable lays the results out for comparison, one row each
+func formatFanoutTable(results []core.FanoutResult) []string {
+	width := len("AGENT")
+	for _, res := range results {
+		if len(res.Label) > width {
+			width = len(res.Label)
+		}
+	}
+	lines := []string{fmt.Sprintf("%-3s %-*s %-5s %-9s %-6s %-12s %s", "#", width, "AGENT", "EXIT", "DURATION", "FILES", "+/-", "WORKSPACE")}
+	for i, res := range results {
+		workspace := "-"
+		if res.Stat.Files > 0 {
+			workspace = fanoutWorkspace(res)
+		}
+		lines = append(lines, fmt.Sprintf("%-3d %-*s %-5d %-9s %-6d %-12s %s",
+			i+1, width, res.Label, res.ExitCode, res.Duration.Round(time.Second),
+			res.Stat.Files, fmt.Sprintf("+%d/-%d", res.Stat.Insertions, res.Stat.Deletions), workspace))
+	}
+	return lines
+}
+
+// fanoutWorkspace names where a member's changes are: its overlay, or its
+// worktree's branch
+func fanoutWorkspace(res core.FanoutResult) string {
+	if res.Isolation == core.FanoutWorktree {
+		return res.Branch
+	}
+	return res.Name
+}
+
+// promptFanoutChoice asks which members' workspaces to keep
+func promptFanoutChoice(results []core.FanoutResult) []bool {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Printf("Keep which workspace? [1-%d, all, none] (default: all): ", len(results))
+		input, err := reader.ReadString('\n')
+		keep, perr := parseFanoutChoice(input, len(results))
+		if perr == nil {
+			return keep
+		}
+		fmt.Printf("Error: %v\n", perr)
+		if err != nil {
+			keep, _ = parseFanoutChoice("all", len(results))
+			return keep
+		}
+	}
+}
+
+// parseFanoutChoice parses the answer to the keep prompt: a member's
+// number, several separated by commas, "all" or "none"
+func parseFanoutChoice(input string, n int) ([]bool, error) {
+	keep := make([]bool, n)
+	input = strings.ToLower(strings.TrimSpace(input))
+	switch input {
+	case "", "all", "a":
+		for i := range keep {
+			keep[i] = true
+		}
+		return keep, nil
+	case "none", "n":
+		return keep, nil
+	}
+	for _, field := range strings.Split(input, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || i < 1 || i > n {
+			return nil, fmt.Errorf("invalid choice %q", strings.TrimSpace(field))
+		}
+		keep[i-1] = true
+	}
+	return keep, nil
+}
+
+// printFanoutReview tells how to review and take a kept member's changes
+func printFanoutReview(res core.FanoutResult) {
+	if res.Stat.Files == 0 {
+		return
+	}
+	if res.Isolation == core.FanoutWorktree {
+		fmt.Printf("%s: review with 'git diff HEAD...%s', merge with 'git merge %s'", res.Label, res.Branch, res.Branch)
+		if w, err := core.LoadWorktree(res.Name); err == nil {
+			fmt.Printf(" (uncommitted changes in %s)", w.Dir)
+		}
+		fmt.Println()
+		return
+	}
+	fmt.Printf("%s: review with 'addt diff --overlay %s', then 'addt apply --overlay %s'\n", res.Label, res.Name, res.Name)
+}
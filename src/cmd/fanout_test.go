@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jedi4ever/addt/core"
+)
+
+func TestParseFanoutArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantLabels []string
+		wantRest   []string
+		wantErr    bool
+	}{
+		{"list", []string{"--fanout", "claude,codex,gemini", "Fix it"}, []string{"claude", "codex", "gemini"}, []string{"Fix it"}, false},
+		{"list equals", []string{"--fanout=claude,codex"}, []string{"claude", "codex"}, []string{}, false},
+		{"repeated", []string{"--fanout", "claude,codex,claude"}, []string{"claude#1", "codex", "claude#2"}, []string{}, false},
+		{"count", []string{"--fanout", "3", "claude", "-p", "Fix it"}, []string{"claude#1", "claude#2", "claude#3"}, []string{"-p", "Fix it"}, false},
+		{"count without extension", []string{"--fanout=2"}, nil, nil, true},
+		{"zero count", []string{"--fanout", "0", "claude"}, nil, nil, true},
+		{"missing spec", []string{"--fanout"}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members, rest, err := parseFanoutArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFanoutArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var labels []string
+			for _, m := range members {
+				labels = append(labels, m.Label)
+			}
+			if !reflect.DeepEqual(labels, tt.wantLabels) {
+				t.Errorf("labels = %v, want %v", labels, tt.wantLabels)
+			}
+			if !reflect.DeepEqual(rest, tt.wantRest) {
+				t.Errorf("rest = %v, want %v", rest, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestParseFanoutChoice(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []bool
+		wantErr bool
+	}{
+		{"\n", []bool{true, true, true}, false},
+		{"all", []bool{true, true, true}, false},
+		{"none", []bool{false, false, false}, false},
+		{"2", []bool{false, true, false}, false},
+		{"1, 3", []bool{true, false, true}, false},
+		{"4", nil, true},
+		{"x", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseFanoutChoice(tt.input, 3)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseFanoutChoice(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseFanoutChoice(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFormatFanoutTable(t *testing.T) {
+	results := []core.FanoutResult{
+		{
+			FanoutMember: core.FanoutMember{Label: "claude", Extension: "claude"},
+			Name:         "addt-fanout-20261017-120000-1",
+			Isolation:    core.FanoutOverlay,
+			Duration:     90 * time.Second,
+			Stat:         core.DiffStat{Files: 2, Insertions: 10, Deletions: 3},
+		},
+		{
+			FanoutMember: core.FanoutMember{Label: "codex", Extension: "codex"},
+			Name:         "addt-fanout-20261017-120000-2",
+			Isolation:    core.FanoutOverlay,
+			ExitCode:     1,
+			Duration:     5 * time.Second,
+		},
+	}
+	lines := formatFanoutTable(results)
+	if len(lines) != 3 {
+		t.Fatalf("formatFanoutTable() = %d lines, want 3", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "#   AGENT") {
+		t.Errorf("header = %q", lines[0])
+	}
+	if fields := strings.Fields(lines[1]); !reflect.DeepEqual(fields, []string{"1", "claude", "0", "1m30s", "2", "+10/-3", "addt-fanout-20261017-120000-1"}) {
+		t.Errorf("row 1 = %v", fields)
+	}
+	if fields := strings.Fields(lines[2]); fields[2] != "1" || fields[len(fields)-1] != "-" {
+		t.Errorf("row 2 = %v, want exit 1 and no workspace", fields)
+	}
+}
@@ -0,0 +1,73 @@
+package firewall
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jedi4ever/addt/config"
+	"github.com/jedi4ever/addt/config/security"
+)
+
+// reloadTimeout is how long 'addt firewall apply' waits for a session to
+// reload its rules
+var reloadTimeout = 10 * time.Second
+
+// extractApply removes --apply from args and reports whether it was there
+func extractApply(args []string) ([]string, bool) {
+	var rest []string
+	apply := false
+	for _, arg := range args {
+		if arg == "--apply" {
+			apply = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, apply
+}
+
+// applyRules sends the rules in the config files to the running sessions
+// of the project, which reload them without restarting the agent, and
+// prints what changed for each
+func applyRules() {
+	project, _ := os.Getwd()
+	sessions, err := security.FirewallSessions(project)
+	if err != nil {
+		fmt.Printf("Error: failed to list running sessions: %v\n", err)
+		os.Exit(1)
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No running sessions in this project; the rules apply from the next one")
+		return
+	}
+
+	global, projectCfg := config.LoadGlobalConfig(), config.LoadProjectConfig()
+	failed := false
+	for _, s := range sessions {
+		rules := configRules(global, projectCfg, s.Extension)
+		diff := security.DiffFirewallRules(s.Rules, rules)
+		if len(diff) == 0 {
+			fmt.Printf("%s: rules unchanged\n", s.Container)
+			continue
+		}
+		fmt.Printf("%s:\n", s.Container)
+		for _, line := range diff {
+			fmt.Printf("  %s\n", line)
+		}
+		if err := security.RequestFirewallReload(s.Container, rules); err != nil {
+			fmt.Printf("✗ %s: %v\n", s.Container, err)
+			failed = true
+			continue
+		}
+		if err := security.WaitFirewallReload(s.Container, reloadTimeout); err != nil {
+			fmt.Printf("✗ %s: reload failed: %v\n", s.Container, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("✓ Reloaded %s\n", s.Container)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,56 @@
+package firewall
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jedi4ever/addt/config/security"
+)
+
+func TestExtractApply(t *testing.T) {
+	args, apply := extractApply([]string{"project", "allow", "--apply", "api.example.com"})
+	if !apply || !reflect.DeepEqual(args, []string{"project", "allow", "api.example.com"}) {
+		t.Errorf("extractApply() = %v, %v", args, apply)
+	}
+	if _, apply := extractApply([]string{"project", "list"}); apply {
+		t.Error("extractApply() found --apply where there is none")
+	}
+}
+
+func TestHandleCommand_Apply(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Setenv("ADDT_HOME", t.TempDir())
+
+	project, _ := os.Getwd()
+	session := &security.FirewallSession{Container: "addt-test", Project: project, PID: os.Getpid()}
+	if err := security.RegisterFirewallSession(session); err != nil {
+		t.Fatal(err)
+	}
+
+	// Answer the reload as the session would
+	received := make(chan *security.FirewallRules, 1)
+	go func() {
+		for i := 0; i < 100; i++ {
+			if rules, _ := security.TakeFirewallReload("addt-test"); rules != nil {
+				security.ReportFirewallReload("addt-test", nil)
+				received <- rules
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		received <- nil
+	}()
+
+	HandleCommand([]string{"project", "allow", "api.example.com", "--apply"})
+
+	rules := <-received
+	if rules == nil {
+		t.Fatal("no reload was requested")
+	}
+	if !reflect.DeepEqual(rules.Project.Allowed, []string{"api.example.com"}) {
+		t.Errorf("reloaded project rules = %+v, want api.example.com allowed", rules.Project)
+	}
+}
@@ -1,58 +1,237 @@
 package firewall
 
 import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
 	"github.com/jedi4ever/addt/config"
+	"github.com/jedi4ever/addt/config/security"
 )
 
-// CheckResult represents the result of a firewall check
-type CheckResult int
+// Rules returns the layered firewall rules of a config, as the egress
+// proxy enforces them
+func Rules(cfg *config.Config) security.FirewallRules {
+	return security.FirewallRules{
+		Project:   security.FirewallLayer{Allowed: cfg.ProjectFirewallAllowed, Denied: cfg.ProjectFirewallDenied},
+		Global:    security.FirewallLayer{Allowed: cfg.GlobalFirewallAllowed, Denied: cfg.GlobalFirewallDenied},
+		Extension: security.FirewallLayer{Allowed: cfg.ExtensionFirewallAllowed, Denied: cfg.ExtensionFirewallDenied},
+		Defaults:  DefaultAllowedDomains(),
+		// Request rules need the proxy to see inside TLS
+		Intercept:      cfg.FirewallIntercept && cfg.FirewallProxy,
+		DeniedRequests: security.DefaultDeniedRequests(),
+	}
+}
 
-const (
-	// CheckNoMatch means no rule matched at this layer
-	CheckNoMatch CheckResult = iota
-	// CheckAllowed means domain is explicitly allowed
-	CheckAllowed
-	// CheckDenied means domain is explicitly denied
-	CheckDenied
-)
+// Quota returns the egress quota of a config, warning about an invalid
+// firewall.max_egress
+func Quota(cfg *config.Config) security.EgressQuota {
+	quota := security.EgressQuota{MaxRequests: cfg.FirewallMaxRequests}
+	if cfg.FirewallMaxEgress != "" {
+		maxEgress, err := security.ParseByteSize(cfg.FirewallMaxEgress)
+		if err != nil {
+			fmt.Printf("Warning: ignoring firewall.max_egress: %v\n", err)
+		}
+		quota.MaxEgress = maxEgress
+	}
+	return quota
+}
+
+// HostServices returns the host services of a config, warning about an
+// invalid ports.host_services
+func HostServices(cfg *config.Config) []security.HostService {
+	services, err := security.ParseHostServices(cfg.PortsHostServices)
+	if err != nil {
+		fmt.Printf("Warning: ignoring ports.host_services: %v\n", err)
+		return nil
+	}
+	return services
+}
 
 // CheckDomain checks if a domain is allowed based on layered rules.
 // Order: Defaults → Extension → Global → Project (project wins)
 // Returns: allowed (bool), matched layer (string)
 func CheckDomain(domain string, cfg *config.Config, extensionName string) (bool, string) {
-	// Layer 4: Project (most specific, checked first)
-	if result := checkLayer(domain, cfg.ProjectFirewallDenied, cfg.ProjectFirewallAllowed); result != CheckNoMatch {
-		return result == CheckAllowed, "project"
+	rules := Rules(cfg)
+	return rules.Check(domain)
+}
+
+// configRules returns the layered rules as a run of extension would load
+// them from the global and project config files
+func configRules(global, project *config.GlobalConfig, extension string) security.FirewallRules {
+	intercept := firewallSetting(global, project, "ADDT_FIREWALL_INTERCEPT", false,
+		func(f *config.FirewallSettings) *bool { return f.Intercept })
+	proxy := firewallSetting(global, project, "ADDT_FIREWALL_PROXY", true,
+		func(f *config.FirewallSettings) *bool { return f.Proxy })
+	rules := security.FirewallRules{
+		Defaults:       DefaultAllowedDomains(),
+		Intercept:      intercept && proxy,
+		DeniedRequests: security.DefaultDeniedRequests(),
+	}
+	if global.Firewall != nil {
+		rules.Global = security.FirewallLayer{Allowed: global.Firewall.Allowed, Denied: global.Firewall.Denied}
+	}
+	if project.Firewall != nil {
+		rules.Project = security.FirewallLayer{Allowed: project.Firewall.Allowed, Denied: project.Firewall.Denied}
+	}
+	if ext := global.Extensions[extension]; ext != nil {
+		rules.Extension = security.FirewallLayer{Allowed: ext.FirewallAllowed, Denied: ext.FirewallDenied}
+	}
+	return rules
+}
+
+// firewallSetting resolves a firewall bool setting like the config loader:
+// default, then global, then project, then the environment
+func firewallSetting(global, project *config.GlobalConfig, envVar string, value bool, field func(*config.FirewallSettings) *bool) bool {
+	for _, cfg := range []*config.GlobalConfig{global, project} {
+		if cfg.Firewall != nil && field(cfg.Firewall) != nil {
+			value = *field(cfg.Firewall)
+		}
+	}
+	if v := os.Getenv(envVar); v != "" {
+		value = v == "true"
+	}
+	return value
+}
+
+// handleCheck explains how the rules treat a destination, or a request
+// when a method comes first:
+// addt firewall check <host>[:<port>] [extension] [--udp]
+// addt firewall check <METHOD> <host>[/<path>] [extension]
+func handleCheck(args []string) {
+	if len(args) > 0 && security.IsRequestRule(strings.Join(args, " ")) && args[0] != "*" {
+		checkRequest(args)
+		return
+	}
+
+	proto := "tcp"
+	var rest []string
+	for _, arg := range args {
+		if arg == "--udp" {
+			proto = "udp"
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	if len(rest) == 0 || len(rest) > 2 {
+		fmt.Println("Usage: addt firewall check <host>[:<port>] [extension] [--udp]")
+		os.Exit(1)
+	}
+	host, port, err := parseCheckTarget(rest[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if strings.HasSuffix(host, "."+security.HostServiceDomain) {
+		fmt.Println(checkHostService(rest[0], host, port, hostServiceEntries(config.LoadGlobalConfig(), config.LoadProjectConfig())))
+		return
+	}
+	extension := ""
+	if len(rest) == 2 {
+		extension = rest[1]
+	}
+
+	rules := configRules(config.LoadGlobalConfig(), config.LoadProjectConfig(), extension)
+	fmt.Println(describeDecision(rest[0], rules.Decide(host, port, proto)))
+}
+
+// checkRequest explains how the request rules treat a request
+func checkRequest(args []string) {
+	if len(args) < 2 || len(args) > 3 {
+		fmt.Println("Usage: addt firewall check <METHOD> <host>[/<path>] [extension]")
+		os.Exit(1)
+	}
+	rule, err := security.ParseRequestRule(args[0] + " " + args[1])
+	if err != nil || len(rule.Methods) != 1 || rule.Host.Host == "" {
+		fmt.Printf("Error: invalid request %q\n", args[0]+" "+args[1])
+		os.Exit(1)
+	}
+	extension := ""
+	if len(args) == 3 {
+		extension = args[2]
 	}
 
-	// Layer 3: Global
-	if result := checkLayer(domain, cfg.GlobalFirewallDenied, cfg.GlobalFirewallAllowed); result != CheckNoMatch {
-		return result == CheckAllowed, "global"
+	rules := configRules(config.LoadGlobalConfig(), config.LoadProjectConfig(), extension)
+	if !rules.Intercept {
+		fmt.Println("Note: request rules are only enforced with firewall.intercept")
 	}
+	port := rule.Host.Port
+	if port == 0 {
+		port = 443
+	}
+	target := args[0] + " " + args[1]
+	if d := rules.Decide(rule.Host.Host, port, "tcp"); !d.Allowed {
+		fmt.Println(describeDecision(target, d))
+		return
+	}
+	path := rule.Path
+	if path == "" {
+		path = "/"
+	}
+	fmt.Println(describeDecision(target, rules.CheckRequest(rule.Methods[0], rule.Host.Host, port, path)))
+}
 
-	// Layer 2: Extension
-	if result := checkLayer(domain, cfg.ExtensionFirewallDenied, cfg.ExtensionFirewallAllowed); result != CheckNoMatch {
-		return result == CheckAllowed, "extension"
+// hostServiceEntries returns ports.host_services as a run would load it:
+// global, then project, then ADDT_PORTS_HOST_SERVICES
+func hostServiceEntries(globalCfg, projectCfg *config.GlobalConfig) []string {
+	var entries []string
+	if globalCfg != nil && globalCfg.Ports != nil && len(globalCfg.Ports.HostServices) > 0 {
+		entries = globalCfg.Ports.HostServices
+	}
+	if projectCfg != nil && projectCfg.Ports != nil && len(projectCfg.Ports.HostServices) > 0 {
+		entries = projectCfg.Ports.HostServices
+	}
+	if v := os.Getenv("ADDT_PORTS_HOST_SERVICES"); v != "" {
+		entries = strings.Split(v, ",")
 	}
+	return entries
+}
 
-	// Layer 1: Defaults (allow only)
-	if containsString(DefaultAllowedDomains(), domain) {
-		return true, "defaults"
+// checkHostService explains how a <name>.host.addt destination is treated:
+// the host service forwarder, not the firewall rules, decides, and only
+// lets the services in ports.host_services through on their port
+func checkHostService(target, host string, port int, entries []string) string {
+	services, err := security.ParseHostServices(entries)
+	if err != nil {
+		return fmt.Sprintf("%s: denied (invalid ports.host_services: %v)", target, err)
+	}
+	for _, s := range services {
+		if s.Hostname() != host {
+			continue
+		}
+		if port != 0 && port != s.Port {
+			return fmt.Sprintf("%s: denied (host service %s is only forwarded on port %d)", target, s.Name, s.Port)
+		}
+		return fmt.Sprintf("%s: allowed by ports.host_services '%s' (forwarded to the host's localhost:%d)", target, s, s.Port)
 	}
+	return fmt.Sprintf("%s: denied (not in ports.host_services)", target)
+}
 
-	// No match - depends on firewall mode
-	return false, "none"
+// parseCheckTarget splits a check target into host and port; port is 0
+// when only the name is checked
+func parseCheckTarget(target string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		return target, 0, nil
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port in %q", target)
+	}
+	return host, port, nil
 }
 
-// checkLayer checks a single layer's deny and allow lists
-func checkLayer(domain string, denied, allowed []string) CheckResult {
-	// Check deny first
-	if containsString(denied, domain) {
-		return CheckDenied
+// describeDecision says how a destination is treated, e.g.
+// "gitlab.com:22: denied by global rule '!tcp/22'"
+func describeDecision(target string, d security.FirewallDecision) string {
+	verdict := "denied"
+	if d.Allowed {
+		verdict = "allowed"
 	}
-	// Then check allow
-	if containsString(allowed, domain) {
-		return CheckAllowed
+	if d.Layer == "none" {
+		return fmt.Sprintf("%s: %s (no rule matches)", target, verdict)
 	}
-	return CheckNoMatch
+	return fmt.Sprintf("%s: %s by %s rule '%s'", target, verdict, d.Layer, d.Rule)
 }
@@ -109,3 +109,88 @@ func TestCheckDomain_LayeredOverride(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCheckTarget(t *testing.T) {
+	tests := []struct {
+		target   string
+		wantHost string
+		wantPort int
+		wantErr  bool
+	}{
+		{"github.com", "github.com", 0, false},
+		{"registry.internal:5000", "registry.internal", 5000, false},
+		{"[fd00::1]:443", "fd00::1", 443, false},
+		{"fd00::1", "fd00::1", 0, false},
+		{"github.com:ssh", "", 0, true},
+	}
+	for _, tt := range tests {
+		host, port, err := parseCheckTarget(tt.target)
+		if (err != nil) != tt.wantErr || host != tt.wantHost || port != tt.wantPort {
+			t.Errorf("parseCheckTarget(%q) = %q, %d, %v, want %q, %d", tt.target, host, port, err, tt.wantHost, tt.wantPort)
+		}
+	}
+}
+
+func TestDescribeDecision(t *testing.T) {
+	cfg := &config.Config{GlobalFirewallAllowed: []string{"!tcp/22"}}
+	rules := Rules(cfg)
+
+	if got, want := describeDecision("gitlab.com:22", rules.Decide("gitlab.com", 22, "tcp")), "gitlab.com:22: denied by global rule '!tcp/22'"; got != want {
+		t.Errorf("describeDecision() = %q, want %q", got, want)
+	}
+	if got, want := describeDecision("github.com", rules.Decide("github.com", 0, "")), "github.com: allowed by defaults rule 'github.com'"; got != want {
+		t.Errorf("describeDecision() = %q, want %q", got, want)
+	}
+	if got, want := describeDecision("evil.example", rules.Decide("evil.example", 0, "")), "evil.example: denied (no rule matches)"; got != want {
+		t.Errorf("describeDecision() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckHostService(t *testing.T) {
+	entries := []string{"postgres:5432"}
+	tests := []struct {
+		target string
+		port   int
+		want   string
+	}{
+		{"postgres.host.addt:5432", 5432, "postgres.host.addt:5432: allowed by ports.host_services 'postgres:5432' (forwarded to the host's localhost:5432)"},
+		{"postgres.host.addt:22", 22, "postgres.host.addt:22: denied (host service postgres is only forwarded on port 5432)"},
+		{"redis.host.addt", 0, "redis.host.addt: denied (not in ports.host_services)"},
+	}
+	for _, tt := range tests {
+		host, _, _ := parseCheckTarget(tt.target)
+		if got := checkHostService(tt.target, host, tt.port, entries); got != tt.want {
+			t.Errorf("checkHostService(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestHostServiceEntries(t *testing.T) {
+	globalCfg := &config.GlobalConfig{Ports: &config.PortsSettings{HostServices: []string{"postgres:5432"}}}
+	projectCfg := &config.GlobalConfig{Ports: &config.PortsSettings{HostServices: []string{"redis:6379"}}}
+	if got := hostServiceEntries(globalCfg, nil); len(got) != 1 || got[0] != "postgres:5432" {
+		t.Errorf("hostServiceEntries(global) = %v", got)
+	}
+	if got := hostServiceEntries(globalCfg, projectCfg); len(got) != 1 || got[0] != "redis:6379" {
+		t.Errorf("hostServiceEntries(global, project) = %v, want the project's", got)
+	}
+	t.Setenv("ADDT_PORTS_HOST_SERVICES", "api:8080")
+	if got := hostServiceEntries(globalCfg, projectCfg); len(got) != 1 || got[0] != "api:8080" {
+		t.Errorf("hostServiceEntries() with env = %v, want the env's", got)
+	}
+}
+
+func TestRules_Intercept(t *testing.T) {
+	cfg := &config.Config{FirewallIntercept: true, ProjectFirewallAllowed: []string{"GET api.github.com/repos/ourorg/*"}}
+	if Rules(cfg).Intercept {
+		t.Error("Rules() intercepts without the egress proxy")
+	}
+	cfg.FirewallProxy = true
+	rules := Rules(cfg)
+	if !rules.Intercept {
+		t.Fatal("Rules() doesn't intercept with firewall.intercept and the proxy on")
+	}
+	if got, want := describeDecision("POST api.github.com/gists", rules.CheckRequest("POST", "api.github.com", 443, "/gists")), "POST api.github.com/gists: denied by defaults rule 'POST,PATCH api.github.com/gists*'"; got != want {
+		t.Errorf("describeDecision() = %q, want %q", got, want)
+	}
+}
@@ -18,15 +18,7 @@ func handleExtension(args []string) {
 	extName := args[0]
 	cmd := args[1]
 	cfg := config.LoadGlobalConfig()
-
-	// Ensure extensions map exists
-	if cfg.Extensions == nil {
-		cfg.Extensions = make(map[string]*config.ExtensionSettings)
-	}
-	if cfg.Extensions[extName] == nil {
-		cfg.Extensions[extName] = &config.ExtensionSettings{}
-	}
-	ext := cfg.Extensions[extName]
+	ext := ensureExtension(cfg, extName)
 
 	switch cmd {
 	case "allow":
@@ -51,6 +43,7 @@ func extensionAllow(cfg *config.GlobalConfig, ext *config.ExtensionSettings, ext
 		os.Exit(1)
 	}
 	domain := strings.TrimSpace(args[2])
+	validateRule(domain)
 	if containsString(ext.FirewallAllowed, domain) {
 		fmt.Printf("Domain '%s' already in %s allowed list\n", domain, extName)
 		return
@@ -66,6 +59,7 @@ func extensionDeny(cfg *config.GlobalConfig, ext *config.ExtensionSettings, extN
 		os.Exit(1)
 	}
 	domain := strings.TrimSpace(args[2])
+	validateRule(domain)
 	if containsString(ext.FirewallDenied, domain) {
 		fmt.Printf("Domain '%s' already in %s denied list\n", domain, extName)
 		return
@@ -3,31 +3,18 @@ package firewall
 import (
 	"fmt"
 	"os"
+
+	"github.com/jedi4ever/addt/config/security"
 )
 
 // DefaultAllowedDomains returns the default allowed domains for firewall
 func DefaultAllowedDomains() []string {
-	return []string{
-		"api.anthropic.com",
-		"github.com",
-		"api.github.com",
-		"raw.githubusercontent.com",
-		"objects.githubusercontent.com",
-		"registry.npmjs.org",
-		"pypi.org",
-		"files.pythonhosted.org",
-		"proxy.golang.org",
-		"sum.golang.org",
-		"registry-1.docker.io",
-		"auth.docker.io",
-		"production.cloudflare.docker.com",
-		"cdn.jsdelivr.net",
-		"unpkg.com",
-	}
+	return security.DefaultAllowedDomains()
 }
 
 // HandleCommand handles the firewall subcommand
 func HandleCommand(args []string) {
+	args, apply := extractApply(args)
 	if len(args) == 0 {
 		printHelp()
 		return
@@ -42,14 +29,25 @@ func HandleCommand(args []string) {
 		handleProject(args[1:])
 	case "extension":
 		handleExtension(args[1:])
+	case "learn":
+		handleLearn(args[1:])
+	case "check":
+		handleCheck(args[1:])
+	case "apply":
+		applyRules()
+		return
 	case "help", "--help", "-h":
 		printHelp()
 	default:
 		fmt.Printf("Unknown firewall scope: %s\n", scope)
-		fmt.Println("Use: global, project, or extension")
+		fmt.Println("Use: global, project, extension, learn, check, or apply")
 		printHelp()
 		os.Exit(1)
 	}
+
+	if apply {
+		applyRules()
+	}
 }
 
 func printHelp() {
@@ -61,14 +59,27 @@ Scopes:
   global                   Manage global firewall rules (~/.addt/config.yaml)
   project                  Manage project firewall rules (.addt.yaml)
   extension <name>         Manage per-extension firewall rules
+  learn [review|list|clear]
+                           Review destinations recorded by 'addt run --firewall-learn'
+                           and allow them in a layer (default: review)
+  check <host>[:<port>] [extension] [--udp]
+                           Show whether the rules allow a destination, and which
+                           rule decides
+  check <METHOD> <host>[/<path>] [extension]
+                           Show whether the request rules allow a request
+  apply                    Reload the rules in this project's running sessions
 
 Commands:
-  allow <domain>           Add domain to allowed list
-  deny <domain>            Add domain to denied list
-  remove <domain>          Remove domain from any list
+  allow <rule>             Add a rule to the allowed list
+  deny <rule>              Add a rule to the denied list
+  remove <rule>            Remove a rule from any list
   list                     List firewall rules
   reset                    Reset to defaults (global) or clear (project/extension)
 
+Options:
+  --apply                  After changing the rules, reload them in this
+                           project's running sessions and show what changed
+
 Examples:
   addt firewall global list
   addt firewall global allow api.example.com
@@ -77,19 +88,52 @@ Examples:
 
   addt firewall project allow custom-api.com
   addt firewall project list
+  addt firewall project allow api.example.com --apply
 
   addt firewall extension claude allow api.anthropic.com
   addt firewall extension codex allow api.openai.com
   addt firewall extension claude list
 
+  addt firewall project allow '*.githubusercontent.com'
+  addt firewall project allow 10.0.0.0/8:5432
+  addt firewall global allow '!tcp/22'
+  addt firewall check registry.internal:5000
+  addt firewall project allow 'GET api.github.com/repos/ourorg/*'
+  addt firewall check POST api.github.com/gists
+
+  addt run --firewall-learn claude
+  addt firewall learn review
+
+Rules:
+  example.com              The host, on any port
+  *.example.com            Any subdomain of example.com (not example.com itself)
+  10.0.0.0/8, fd00::/8     Addresses in a network; a single address works too
+  host:5000                The host on port 5000 only ([fd00::/8]:443 for IPv6)
+  tcp/22, udp/53, *:443    Any host on a port
+  !<rule>                  Deny, even in an allowed list (e.g. '!tcp/22')
+
+Request rules (need firewall.intercept, which intercepts TLS to the hosts
+they name with an addt CA the container trusts):
+  GET api.github.com/repos/ourorg/*   Method and path; '*' matches anything
+  POST,PUT host/v1/upload             Several methods
+  * uploads.example.com               Any method, any path
+  Once allowed requests name a host, other requests to it are denied.
+  Uploads to paste sites and gist creation are denied by default.
+
 Rule Evaluation (layered override, most specific wins):
   Defaults → Extension → Global → Project
 
-  Each layer checks deny first, then allow. First match wins.
-  Project rules override global, global overrides extension, etc.
+  Each layer checks deny first (including '!' rules), then allow.
+  The first layer with a matching rule decides: project rules override
+  global, global overrides extension, etc.
 
   Example: Defaults allow npm, global denies it, project re-allows it.
 
+Running sessions pick up rule changes from 'addt firewall apply' (or
+--apply) without restarting the agent: the egress proxy and DNS resolver
+check new connections against the new rules, and the container's ruleset
+is rendered again. The mode and other settings apply from the next session.
+
 Firewall Modes (set via 'addt config'):
   strict      - Block all except allowed (default)
   permissive  - Allow all except denied
@@ -42,6 +42,7 @@ func globalAllow(cfg *config.GlobalConfig, args []string) {
 	}
 	fw := ensureFirewall(cfg)
 	domain := strings.TrimSpace(args[1])
+	validateRule(domain)
 	if containsString(fw.Allowed, domain) {
 		fmt.Printf("Domain '%s' already in global allowed list\n", domain)
 		return
@@ -58,6 +59,7 @@ func globalDeny(cfg *config.GlobalConfig, args []string) {
 	}
 	fw := ensureFirewall(cfg)
 	domain := strings.TrimSpace(args[1])
+	validateRule(domain)
 	if containsString(fw.Denied, domain) {
 		fmt.Printf("Domain '%s' already in global denied list\n", domain)
 		return
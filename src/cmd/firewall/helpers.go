@@ -2,8 +2,10 @@ package firewall
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/jedi4ever/addt/config"
+	"github.com/jedi4ever/addt/config/security"
 )
 
 // containsString checks if a slice contains a string
@@ -101,3 +103,23 @@ func removeDomainFromConfig(cfg *config.GlobalConfig, domain string) bool {
 
 	return removed
 }
+
+// ensureExtension returns an extension's settings, creating them if needed
+func ensureExtension(cfg *config.GlobalConfig, name string) *config.ExtensionSettings {
+	if cfg.Extensions == nil {
+		cfg.Extensions = make(map[string]*config.ExtensionSettings)
+	}
+	if cfg.Extensions[name] == nil {
+		cfg.Extensions[name] = &config.ExtensionSettings{}
+	}
+	return cfg.Extensions[name]
+}
+
+// validateRule exits with an error when rule isn't a valid firewall or
+// request rule
+func validateRule(rule string) {
+	if err := security.ValidateFirewallRule(rule); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,198 @@
+package firewall
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jedi4ever/addt/config"
+	"github.com/jedi4ever/addt/config/security"
+)
+
+func handleLearn(args []string) {
+	cmd := "review"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	switch cmd {
+	case "review":
+		learnReview(os.Stdin)
+	case "list", "ls":
+		learnList()
+	case "clear":
+		learnClear()
+	default:
+		fmt.Printf("Unknown command: %s\n", cmd)
+		fmt.Println("Commands: review, list, clear")
+	}
+}
+
+// loadLearnSession loads the current project's learned destinations
+func loadLearnSession() *security.LearnSession {
+	projectDir, _ := os.Getwd()
+	session, err := security.LoadLearnSession(projectDir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return session
+}
+
+// learnReview asks, for each learned destination the rules don't allow
+// yet, which layer to allow it in. Reviewed destinations are dropped from
+// the list; quitting keeps the rest for later.
+func learnReview(in io.Reader) {
+	session := loadLearnSession()
+	if len(session.Destinations) == 0 {
+		fmt.Println("No learned destinations for this project")
+		fmt.Println("Record some with 'addt run --firewall-learn <extension>'")
+		return
+	}
+
+	global := config.LoadGlobalConfig()
+	project := config.LoadProjectConfig()
+	rules := configRules(global, project, session.Extension)
+
+	var pending []security.LearnedDestination
+	var allowed []string
+	for _, d := range session.Destinations {
+		if ok, _ := rules.Check(d.Host); ok {
+			allowed = append(allowed, d.Host)
+		} else {
+			pending = append(pending, d)
+		}
+	}
+	session.Remove(allowed...)
+	if len(allowed) > 0 {
+		fmt.Printf("%d learned destinations are already allowed\n", len(allowed))
+	}
+
+	reader := bufio.NewReader(in)
+	for i, d := range pending {
+		fmt.Printf("\n[%d/%d] %s\n", i+1, len(pending), describeDestination(d))
+		choice, ok := promptLearnChoice(reader, session.Extension)
+		if !ok || choice == "quit" {
+			break
+		}
+		switch choice {
+		case "project":
+			projectAllow(project, []string{"allow", d.Host})
+		case "global":
+			globalAllow(global, []string{"allow", d.Host})
+		case "extension":
+			extensionAllow(global, ensureExtension(global, session.Extension), session.Extension, []string{session.Extension, "allow", d.Host})
+		case "deny":
+			projectDeny(project, []string{"deny", d.Host})
+		}
+		session.Remove(d.Host)
+	}
+
+	if err := session.Save(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if n := len(session.Destinations); n > 0 {
+		fmt.Printf("\n%d destinations left to review\n", n)
+	}
+}
+
+// promptLearnChoice asks where to allow a destination until it gets a
+// valid answer; ok is false at the end of input
+func promptLearnChoice(reader *bufio.Reader, extension string) (string, bool) {
+	prompt := "Allow in [p]roject, [g]lobal"
+	if extension != "" {
+		prompt += fmt.Sprintf(", [e]xtension %s", extension)
+	}
+	prompt += ", [d]eny in project, [s]kip, [q]uit? "
+	for {
+		fmt.Print(prompt)
+		input, err := reader.ReadString('\n')
+		choice, perr := parseLearnChoice(input, extension != "")
+		if perr == nil {
+			return choice, true
+		}
+		if err != nil {
+			fmt.Println()
+			return "", false
+		}
+		fmt.Printf("Error: %v\n", perr)
+	}
+}
+
+// parseLearnChoice parses the answer to the review prompt
+func parseLearnChoice(input string, hasExtension bool) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(input)) {
+	case "p", "project":
+		return "project", nil
+	case "g", "global":
+		return "global", nil
+	case "e", "extension":
+		if hasExtension {
+			return "extension", nil
+		}
+	case "d", "deny":
+		return "deny", nil
+	case "s", "skip":
+		return "skip", nil
+	case "q", "quit":
+		return "quit", nil
+	}
+	return "", fmt.Errorf("invalid choice %q", strings.TrimSpace(input))
+}
+
+// describeDestination summarizes a learned destination on one line, e.g.
+// "registry.yarnpkg.com (12 attempts via dns, connect; port 443; 104.16.1.35)"
+func describeDestination(d security.LearnedDestination) string {
+	parts := []string{fmt.Sprintf("%d attempts via %s", d.Count, strings.Join(d.Via, ", "))}
+	if len(d.Ports) > 0 {
+		ports := make([]string, len(d.Ports))
+		for i, p := range d.Ports {
+			ports[i] = fmt.Sprint(p)
+		}
+		label := "port"
+		if len(ports) > 1 {
+			label = "ports"
+		}
+		parts = append(parts, label+" "+strings.Join(ports, ", "))
+	}
+	if len(d.IPs) > 0 {
+		ips := d.IPs
+		if len(ips) > 3 {
+			ips = append(ips[:3:3], "…")
+		}
+		parts = append(parts, strings.Join(ips, ", "))
+	}
+	return fmt.Sprintf("%s (%s)", d.Host, strings.Join(parts, "; "))
+}
+
+// learnList lists the learned destinations and whether the rules allow them
+func learnList() {
+	session := loadLearnSession()
+	if len(session.Destinations) == 0 {
+		fmt.Println("No learned destinations for this project")
+		return
+	}
+	rules := configRules(config.LoadGlobalConfig(), config.LoadProjectConfig(), session.Extension)
+	fmt.Printf("Learned destinations (%s):\n", session.Project)
+	for _, d := range session.Destinations {
+		status := "not allowed"
+		if ok, layer := rules.Check(d.Host); ok {
+			status = "allowed by " + layer
+		}
+		fmt.Printf("  %s [%s]\n", describeDestination(d), status)
+	}
+}
+
+// learnClear forgets the learned destinations
+func learnClear() {
+	session := loadLearnSession()
+	session.Destinations = nil
+	if err := session.Save(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Cleared learned destinations")
+}
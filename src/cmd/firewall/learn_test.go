@@ -0,0 +1,92 @@
+package firewall
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jedi4ever/addt/config"
+	"github.com/jedi4ever/addt/config/security"
+)
+
+func TestParseLearnChoice(t *testing.T) {
+	tests := []struct {
+		input        string
+		hasExtension bool
+		want         string
+		wantErr      bool
+	}{
+		{"p\n", true, "project", false},
+		{"Global", false, "global", false},
+		{"e", true, "extension", false},
+		{"e", false, "", true},
+		{"d", false, "deny", false},
+		{"s", false, "skip", false},
+		{"q", false, "quit", false},
+		{"\n", false, "", true},
+		{"x", false, "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseLearnChoice(tt.input, tt.hasExtension)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseLearnChoice(%q, %v) = %q, %v, want %q", tt.input, tt.hasExtension, got, err, tt.want)
+		}
+	}
+}
+
+func TestDescribeDestination(t *testing.T) {
+	d := security.LearnedDestination{
+		Host:  "registry.yarnpkg.com",
+		Count: 12,
+		Via:   []string{"dns", "connect"},
+		Ports: []int{80, 443},
+		IPs:   []string{"104.16.1.35", "104.16.2.35", "104.16.3.35", "104.16.4.35"},
+	}
+	want := "registry.yarnpkg.com (12 attempts via dns, connect; ports 80, 443; 104.16.1.35, 104.16.2.35, 104.16.3.35, …)"
+	if got := describeDestination(d); got != want {
+		t.Errorf("describeDestination() = %q, want %q", got, want)
+	}
+}
+
+func TestLearnReview_AllowsChosenLayers(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Setenv("ADDT_HOME", tmpDir)
+	projectDir, _ := os.Getwd()
+
+	session, _ := security.LoadLearnSession(projectDir)
+	session.Extension = "claude"
+	session.Merge([]security.LearnedDestination{
+		{Host: "registry.yarnpkg.com", Count: 5, Via: []string{"connect"}},
+		{Host: "api.example.com", Count: 3, Via: []string{"dns"}},
+		{Host: "telemetry.example.com", Count: 2, Via: []string{"dns"}},
+		{Host: "evil.example.com", Count: 1, Via: []string{"dns"}},
+		{Host: "github.com", Count: 1, Via: []string{"connect"}},
+	})
+	if err := session.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// github.com is allowed by default and isn't asked about
+	learnReview(strings.NewReader("p\ng\ne\nq\n"))
+
+	if fw := config.LoadProjectConfig().Firewall; fw == nil || !containsString(fw.Allowed, "registry.yarnpkg.com") {
+		t.Errorf("project firewall = %+v, want registry.yarnpkg.com allowed", fw)
+	}
+	global := config.LoadGlobalConfig()
+	if global.Firewall == nil || !containsString(global.Firewall.Allowed, "api.example.com") {
+		t.Errorf("global firewall = %+v, want api.example.com allowed", global.Firewall)
+	}
+	if ext := global.Extensions["claude"]; ext == nil || !containsString(ext.FirewallAllowed, "telemetry.example.com") {
+		t.Errorf("claude extension = %+v, want telemetry.example.com allowed", ext)
+	}
+
+	// Quitting keeps what wasn't reviewed
+	session, _ = security.LoadLearnSession(projectDir)
+	if len(session.Destinations) != 1 || session.Destinations[0].Host != "evil.example.com" {
+		t.Errorf("remaining = %+v, want only evil.example.com", session.Destinations)
+	}
+	if _, err := os.Stat(security.LearnFile(projectDir)); err != nil {
+		t.Errorf("learn file: %v", err)
+	}
+}
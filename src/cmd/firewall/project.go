@@ -42,6 +42,7 @@ func projectAllow(cfg *config.GlobalConfig, args []string) {
 	}
 	fw := ensureFirewall(cfg)
 	domain := strings.TrimSpace(args[1])
+	validateRule(domain)
 	if containsString(fw.Allowed, domain) {
 		fmt.Printf("Domain '%s' already in project allowed list\n", domain)
 		return
@@ -58,6 +59,7 @@ func projectDeny(cfg *config.GlobalConfig, args []string) {
 	}
 	fw := ensureFirewall(cfg)
 	domain := strings.TrimSpace(args[1])
+	validateRule(domain)
 	if containsString(fw.Denied, domain) {
 		fmt.Printf("Domain '%s' already in project denied list\n", domain)
 		return
@@ -1,10 +1,11 @@
 package cmd
 
 import (
+	"embed"
 	"fmt"
 	"os"
 
-	"github.com/jedi4ever/addt/provider/docker"
+	"github.com/jedi4ever/addt/provider/ocicli"
 )
 
 // PrintHelp displays usage information for plain addt (no extension)
@@ -15,16 +16,27 @@ Version: %s
 
 Commands:
   addt run <extension> [args...]     Run a specific extension
+  addt run --fanout <N|ext,...> ...  Run several agents at once and compare
+  addt run --firewall-learn <ext>    Run and record destinations for the allowlist
+  addt run --offline <ext>           Run with only the host-side package mirrors reachable
   addt init [-y] [-f]                Initialize project config
   addt update <extension> [version]  Update extension to latest/specific version
   addt build <extension>             Build the container image
   addt shell <extension>             Open bash shell in container
-  addt containers [list|stop|rm]     Manage containers
+  addt containers [list|exec|cp|rm]  Manage containers
   addt firewall [list|add|rm|reset]  Manage firewall
+  addt firewall learn review         Allow destinations recorded by --firewall-learn
+  addt firewall check <host[:port]>  Show which rule allows or denies a destination
+  addt firewall apply                Reload the rules in running sessions
+  addt audit [list|tail|summary|export]  View the security audit log
+  addt diff [--list] [path...]       Show changes in the workspace overlay
+  addt apply [--force] [path...]     Apply workspace overlay changes to the project
+  addt discard [path...]             Discard workspace overlay changes
   addt extensions [list|info|new]    Manage extensions
   addt config [list|set|get|unset|audit] [-g]  Manage configuration
   addt config extension <name> [list|set|get|unset]  Extension config
   addt profile [list|show|apply]     Apply configuration presets
+  addt cache [list|warm|clean]       Manage the offline package cache
   addt completion [bash|zsh|fish]    Generate shell completions
   addt doctor                        Check system health
   addt cli [update|install-podman]   Manage addt CLI
@@ -52,12 +64,17 @@ Container management (via agent):
   <agent> addt update [version]               Update extension to latest/specific version
   <agent> addt build                         Build the container image
   <agent> addt shell                         Open bash shell in container
-  <agent> addt containers [list|stop|rm]     Manage persistent containers
+  <agent> addt containers [list|exec|cp|rm]  Manage persistent containers
   <agent> addt firewall [list|add|rm|reset]  Manage network firewall
+  <agent> addt audit [list|tail|summary|export]  View the security audit log
+  <agent> addt diff [--list] [path...]       Show changes in the workspace overlay
+  <agent> addt apply [--force] [path...]     Apply workspace overlay changes to the project
+  <agent> addt discard [path...]             Discard workspace overlay changes
   <agent> addt extensions [list|info|new]    Manage extensions
   <agent> addt config [list|set|get|unset|audit] [-g]  Manage configuration
   <agent> addt config extension <name> [list|set|get|unset]  Extension config
   <agent> addt profile [list|show|apply]     Apply configuration presets
+  <agent> addt cache [list|warm|clean]       Manage the offline package cache
   <agent> addt cli [update]                  Manage addt CLI
   <agent> addt version                       Show version info
 
@@ -83,6 +100,9 @@ Environment Variables:
     ADDT_PERSISTENT        Persistent container mode (default: false)
     ADDT_WORKDIR           Override working directory (default: .)
     ADDT_WORKDIR_AUTOMOUNT Auto-mount workdir to /workspace (default: true)
+    ADDT_WORKDIR_OVERLAY   Mount a copy-on-write overlay of workdir (default: false)
+    ADDT_WORKDIR_WORKTREE  Mount a git worktree and branch per container (default: false)
+    ADDT_WORKDIR_WORKTREE_NAME  Worktree session name, branch addt/<name>
 
   Docker-in-Docker:
     ADDT_DOCKER_DIND_ENABLE  Enable Docker-in-Docker (default: false)
@@ -91,9 +111,12 @@ Environment Variables:
   Security/Network:
     ADDT_FIREWALL          Enable network firewall (default: false)
     ADDT_FIREWALL_MODE     Firewall mode: strict, permissive, off (default: strict)
+    ADDT_OFFLINE           Reach only host-side npm, PyPI and Go package mirrors (default: false)
+    ADDT_OFFLINE_FETCH     Fetch packages missing from the mirror cache (default: true)
     ADDT_SSH_FORWARD_KEYS  SSH key forwarding: true or false (default: true)
     ADDT_SSH_FORWARD_MODE  SSH forwarding mode: agent, keys, or proxy (default: proxy)
     ADDT_SSH_ALLOWED_KEYS  Comma-separated key filters for proxy mode (e.g., "github,work")
+    ADDT_SSH_ALLOWED_HOSTS Hosts forwarded keys may log in to (e.g., "deploy=github.com")
     ADDT_GIT_DISABLE_HOOKS Neutralize git hooks in container (default: true)
     ADDT_GPG_FORWARD       Enable GPG forwarding (default: false)
 
@@ -103,14 +126,15 @@ Environment Variables:
     ADDT_UV_VERSION        UV Python version (default: latest)
 
   Other:
-    ADDT_PROVIDER          Provider: docker, rancher, podman, orbstack, or daytona (auto-detected)
-    ADDT_PROVIDER_AUTOSELECT  Provider auto-detection order (default: orbstack,rancher,docker,podman)
+    ADDT_PROVIDER          Provider: docker, rancher, podman, orbstack, nerdctl, engine, sandbox, kubernetes, or daytona (auto-detected)
+    ADDT_PROVIDER_AUTOSELECT  Provider auto-detection order (default: orbstack,rancher,docker,podman,nerdctl)
     ADDT_HOME              Addt data directory (default: ~/.addt)
     ADDT_CONFIG_DIR        Global config directory (overrides ADDT_HOME for config only)
     ADDT_GITHUB_FORWARD_TOKEN  Forward GH_TOKEN to container (default: true)
     ADDT_GITHUB_TOKEN_SOURCE   Token source: env or gh_auth (default: gh_auth)
     ADDT_GITHUB_SCOPE_TOKEN    Scope GH_TOKEN to workspace repo (default: true)
     ADDT_GITHUB_SCOPE_REPOS    Additional repos for scoping (comma-separated owner/repo)
+    ADDT_GITHUB_HOLD_TOKEN     Keep GH_TOKEN on the host, serving it to git (default: true)
     ADDT_PORTS_FORWARD     Enable port forwarding (default: true)
     ADDT_PORTS             Comma-separated container ports to expose
     ADDT_PORTS_INJECT_SYSTEM_PROMPT  Inject port mappings into AI system prompt (default: true)
@@ -163,7 +187,7 @@ Examples:
 // printExtensionFlags queries and prints flags for the active extension
 func printExtensionFlags(imageName, command string) {
 	// Create a minimal docker provider to query extension flags
-	p := &docker.DockerProvider{}
+	p := ocicli.New(ocicli.DockerRuntime(""), nil, nil, nil, nil, nil, nil, embed.FS{})
 	flags := p.GetExtensionFlags(imageName, command)
 
 	if len(flags) > 0 {
@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jedi4ever/addt/core"
+)
+
+// overlayArgs are the arguments of addt diff, apply and discard
+type overlayArgs struct {
+	name     string   // --overlay: overlay to use (default: newest of the workdir)
+	paths    []string // files or directories, relative to the project
+	list     bool     // diff --list
+	nameOnly bool     // diff --name-only
+	force    bool     // apply --force
+}
+
+// parseOverlayArgs parses the arguments of an overlay command; flags is the
+// set of boolean flags it accepts besides --overlay
+func parseOverlayArgs(args []string, flags ...string) (overlayArgs, error) {
+	var result overlayArgs
+	accepts := func(flag string) bool {
+		for _, f := range flags {
+			if f == flag {
+				return true
+			}
+		}
+		return false
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			result.paths = append(result.paths, args[i+1:]...)
+			return result, nil
+		case arg == "--overlay" || arg == "-o":
+			if i+1 >= len(args) {
+				return result, fmt.Errorf("%s requires an overlay name", arg)
+			}
+			result.name = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--overlay="):
+			result.name = strings.TrimPrefix(arg, "--overlay=")
+		case accepts(arg):
+			switch arg {
+			case "--list":
+				result.list = true
+			case "--name-only":
+				result.nameOnly = true
+			case "--force", "-f":
+				result.force = true
+			}
+		case strings.HasPrefix(arg, "-"):
+			return result, fmt.Errorf("unknown flag %s", arg)
+		default:
+			result.paths = append(result.paths, arg)
+		}
+	}
+	return result, nil
+}
+
+// loadOverlayChanges finds the overlay a command works on and its changes
+// to the given paths
+func loadOverlayChanges(workdir string, opts overlayArgs) (*core.Overlay, []core.Change) {
+	if workdir == "" {
+		workdir, _ = os.Getwd()
+	}
+	overlay, err := core.FindOverlay(opts.name, workdir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	changes, err := overlay.Changes()
+	if err != nil {
+		fmt.Printf("Error reading overlay %s: %v\n", overlay.Name, err)
+		os.Exit(1)
+	}
+	changes, err = core.FilterChanges(changes, opts.paths)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return overlay, changes
+}
+
+// HandleDiffCommand shows the changes in a workspace overlay:
+// diff [--overlay NAME] [--name-only] [path...], or diff --list
+func HandleDiffCommand(workdir string, args []string) {
+	opts, err := parseOverlayArgs(args, "--list", "--name-only")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Println("Usage: addt diff [--overlay <name>] [--name-only] [path...]")
+		fmt.Println("       addt diff --list")
+		os.Exit(1)
+	}
+	if opts.list {
+		listOverlays()
+		return
+	}
+
+	overlay, changes := loadOverlayChanges(workdir, opts)
+	if len(changes) == 0 {
+		fmt.Printf("No changes in overlay %s\n", overlay.Name)
+		return
+	}
+	for _, c := range changes {
+		marker := ""
+		if c.Conflict {
+			marker = " (also changed in the project)"
+		}
+		if opts.nameOnly {
+			fmt.Printf("%s %s%s\n", c.Kind, c.Path, marker)
+			continue
+		}
+		if c.Conflict {
+			fmt.Printf("# %s also changed in the project since the overlay was created\n", c.Path)
+		}
+		printChangeDiff(overlay, c)
+	}
+}
+
+// printChangeDiff prints a unified diff of a change against the project
+func printChangeDiff(overlay *core.Overlay, c core.Change) {
+	from := filepath.Join(overlay.Source, filepath.FromSlash(c.Path))
+	to := filepath.Join(overlay.Workspace(), filepath.FromSlash(c.Path))
+	if c.Kind == core.ChangeAdded {
+		from = os.DevNull
+	}
+	if c.Kind == core.ChangeDeleted {
+		to = os.DevNull
+	}
+	for _, p := range []string{from, to} {
+		if info, err := os.Lstat(p); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			target, _ := os.Readlink(p)
+			fmt.Printf("%s %s: symlink -> %s\n", c.Kind, c.Path, target)
+			return
+		}
+	}
+
+	cmd := exec.Command("diff", "-u", "--label", "a/"+c.Path, "--label", "b/"+c.Path, from, to)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// diff exits 1 when the files differ
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() > 1 {
+			fmt.Printf("%s %s\n", c.Kind, c.Path)
+		}
+	}
+}
+
+// listOverlays prints all overlays with their number of changes
+func listOverlays() {
+	overlays, err := core.ListOverlays()
+	if err != nil {
+		fmt.Printf("Error listing overlays: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("NAME\t\t\t\tCHANGES\tCREATED\t\t\tSOURCE")
+	for _, o := range overlays {
+		count := "?"
+		if changes, err := o.Changes(); err == nil {
+			count = fmt.Sprint(len(changes))
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\n", o.Name, count, o.CreatedAt.Local().Format("2006-01-02 15:04:05"), o.Source)
+	}
+}
+
+// HandleApplyCommand copies changes from a workspace overlay to the
+// project: apply [--overlay NAME] [--force] [path...]
+func HandleApplyCommand(workdir string, args []string) {
+	opts, err := parseOverlayArgs(args, "--force", "-f")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Println("Usage: addt apply [--overlay <name>] [--force] [path...]")
+		os.Exit(1)
+	}
+	overlay, changes := loadOverlayChanges(workdir, opts)
+	if len(changes) == 0 {
+		fmt.Printf("No changes in overlay %s\n", overlay.Name)
+		finishOverlay(overlay)
+		return
+	}
+
+	if !opts.force {
+		var conflicts []string
+		for _, c := range changes {
+			if c.Conflict {
+				conflicts = append(conflicts, c.Path)
+			}
+		}
+		if len(conflicts) > 0 {
+			fmt.Println("Error: these files also changed in the project since the overlay was created:")
+			for _, path := range conflicts {
+				fmt.Printf("  %s\n", path)
+			}
+			fmt.Println("Apply them anyway with --force, or discard them with 'addt discard <path>'")
+			os.Exit(1)
+		}
+	}
+
+	if err := overlay.Apply(changes); err != nil {
+		fmt.Printf("Error applying overlay %s: %v\n", overlay.Name, err)
+		os.Exit(1)
+	}
+	for _, c := range changes {
+		fmt.Printf("✓ %s %s\n", c.Kind, c.Path)
+	}
+	finishOverlay(overlay)
+}
+
+// HandleDiscardCommand reverts changes in a workspace overlay to the
+// project's version: discard [--overlay NAME] [path...]
+func HandleDiscardCommand(workdir string, args []string) {
+	opts, err := parseOverlayArgs(args)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Println("Usage: addt discard [--overlay <name>] [path...]")
+		os.Exit(1)
+	}
+	overlay, changes := loadOverlayChanges(workdir, opts)
+	if err := overlay.Discard(changes); err != nil {
+		fmt.Printf("Error discarding overlay %s changes: %v\n", overlay.Name, err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Discarded %d change(s)\n", len(changes))
+	finishOverlay(overlay)
+}
+
+// finishOverlay removes an ephemeral environment's overlay once nothing is
+// left in it; a persistent environment's stays mounted
+func finishOverlay(overlay *core.Overlay) {
+	if overlay.Persistent() {
+		return
+	}
+	if changes, err := overlay.Changes(); err != nil || len(changes) > 0 {
+		return
+	}
+	if err := overlay.Remove(); err != nil {
+		fmt.Printf("Error removing overlay %s: %v\n", overlay.Name, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Removed overlay %s\n", overlay.Name)
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOverlayArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		flags   []string
+		want    overlayArgs
+		wantErr bool
+	}{
+		{"paths", []string{"a.txt", "src"}, nil, overlayArgs{paths: []string{"a.txt", "src"}}, false},
+		{"overlay", []string{"--overlay", "addt-x", "a.txt"}, nil, overlayArgs{name: "addt-x", paths: []string{"a.txt"}}, false},
+		{"overlay equals", []string{"--overlay=addt-x"}, nil, overlayArgs{name: "addt-x"}, false},
+		{"force", []string{"-f", "a.txt"}, []string{"--force", "-f"}, overlayArgs{force: true, paths: []string{"a.txt"}}, false},
+		{"list", []string{"--list"}, []string{"--list", "--name-only"}, overlayArgs{list: true}, false},
+		{"dash dash", []string{"--", "--weird"}, nil, overlayArgs{paths: []string{"--weird"}}, false},
+		{"flag not accepted", []string{"--force"}, nil, overlayArgs{}, true},
+		{"missing name", []string{"--overlay"}, nil, overlayArgs{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOverlayArgs(tt.args, tt.flags...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOverlayArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseOverlayArgs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/jedi4ever/addt/assets"
 	"github.com/jedi4ever/addt/config"
@@ -9,15 +10,20 @@ import (
 	"github.com/jedi4ever/addt/provider"
 	"github.com/jedi4ever/addt/provider/daytona"
 	"github.com/jedi4ever/addt/provider/docker"
+	"github.com/jedi4ever/addt/provider/engine"
+	"github.com/jedi4ever/addt/provider/kubernetes"
+	"github.com/jedi4ever/addt/provider/nerdctl"
+	"github.com/jedi4ever/addt/provider/ocicli"
 	"github.com/jedi4ever/addt/provider/orbstack"
 	"github.com/jedi4ever/addt/provider/podman"
+	"github.com/jedi4ever/addt/provider/sandbox"
 )
 
 // NewProvider creates a new provider based on the specified type
 // For podman/default, auto-downloads Podman if not available
 func NewProvider(providerType string, cfg *provider.Config) (provider.Provider, error) {
-	// For container providers (not daytona), ensure runtime is available
-	if providerType != "daytona" {
+	// For container providers (not daytona, sandbox or kubernetes), ensure runtime is available
+	if providerType != "daytona" && providerType != "sandbox" && providerType != "kubernetes" {
 		runtime, err := config.EnsureContainerRuntime()
 		if err != nil {
 			return nil, err
@@ -28,6 +34,17 @@ func NewProvider(providerType string, cfg *provider.Config) (provider.Provider,
 		}
 	}
 
+	return newProvider(providerType, cfg)
+}
+
+// NewExistingProvider creates a provider to manage existing environments.
+// Unlike NewProvider it never installs or starts a container runtime.
+func NewExistingProvider(providerType string, cfg *provider.Config) (provider.Provider, error) {
+	return newProvider(providerType, cfg)
+}
+
+// newProvider creates a provider without checking the runtime is available
+func newProvider(providerType string, cfg *provider.Config) (provider.Provider, error) {
 	switch providerType {
 	case "docker":
 		return docker.NewDockerProvider(cfg, "desktop-linux", assets.DockerDockerfile, assets.DockerDockerfileBase, assets.DockerEntrypoint, assets.DockerInitFirewall, assets.DockerInstallSh, extensions.FS)
@@ -37,9 +54,50 @@ func NewProvider(providerType string, cfg *provider.Config) (provider.Provider,
 		return orbstack.NewOrbStackProvider(cfg, assets.OrbStackDockerfile, assets.OrbStackDockerfileBase, assets.OrbStackEntrypoint, assets.OrbStackInitFirewall, assets.OrbStackInstallSh, extensions.FS)
 	case "podman", "":
 		return podman.NewPodmanProvider(cfg, assets.PodmanDockerfile, assets.PodmanDockerfileBase, assets.PodmanEntrypoint, assets.PodmanInitFirewall, assets.PodmanInstallSh, extensions.FS)
+	case "nerdctl":
+		return nerdctl.NewNerdctlProvider(cfg, assets.DockerDockerfile, assets.DockerDockerfileBase, assets.DockerEntrypoint, assets.DockerInitFirewall, assets.DockerInstallSh, extensions.FS)
+	case "engine":
+		return engine.NewEngineProvider(cfg, provider.EngineSocket(),
+			engine.Assets{Dockerfile: assets.DockerDockerfile, DockerfileBase: assets.DockerDockerfileBase, Entrypoint: assets.DockerEntrypoint, InitFirewall: assets.DockerInitFirewall, InstallSh: assets.DockerInstallSh},
+			engine.Assets{Dockerfile: assets.PodmanDockerfile, DockerfileBase: assets.PodmanDockerfileBase, Entrypoint: assets.PodmanEntrypoint, InitFirewall: assets.PodmanInitFirewall, InstallSh: assets.PodmanInstallSh},
+			extensions.FS)
+	case "sandbox":
+		return sandbox.NewSandboxProvider(cfg, assets.SandboxEntrypoint, assets.DockerInstallSh, extensions.FS)
+	case "kubernetes":
+		return newKubernetesProvider(cfg)
 	case "daytona":
 		return daytona.NewDaytonaProvider(cfg, assets.DaytonaDockerfile, assets.DaytonaEntrypoint)
 	default:
-		return nil, fmt.Errorf("unknown provider type: %s (supported: docker, rancher, podman, orbstack, daytona)", providerType)
+		return nil, fmt.Errorf("unknown provider type: %s (supported: docker, rancher, podman, orbstack, nerdctl, engine, sandbox, kubernetes, daytona)", providerType)
+	}
+}
+
+// newKubernetesProvider creates the kubernetes provider with the local
+// provider that builds its images, chosen by ADDT_KUBERNETES_BUILDER
+// (default: docker, using the current docker context)
+func newKubernetesProvider(cfg *provider.Config) (provider.Provider, error) {
+	builderType := os.Getenv("ADDT_KUBERNETES_BUILDER")
+	var builder provider.Provider
+	var err error
+	switch builderType {
+	case "", "docker":
+		builderType = "docker"
+		builder, err = docker.NewDockerProvider(cfg, "", assets.DockerDockerfile, assets.DockerDockerfileBase, assets.DockerEntrypoint, assets.DockerInitFirewall, assets.DockerInstallSh, extensions.FS)
+	case "podman", "nerdctl", "orbstack", "rancher":
+		builder, err = newProvider(builderType, cfg)
+	default:
+		return nil, fmt.Errorf("unsupported ADDT_KUBERNETES_BUILDER: %s (supported: docker, podman, nerdctl, orbstack, rancher)", builderType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rt := ocicli.DockerRuntime("")
+	switch builderType {
+	case "podman":
+		rt = ocicli.PodmanRuntime()
+	case "nerdctl":
+		rt = ocicli.NerdctlRuntime()
 	}
+	return kubernetes.NewKubernetesProvider(cfg, kubernetes.Builder{Provider: builder, Binary: rt.Binary, EntrypointPath: rt.EntrypointPath})
 }
@@ -6,11 +6,14 @@ import (
 	"path/filepath"
 	"strings"
 
+	auditcmd "github.com/jedi4ever/addt/cmd/audit"
+	cachecmd "github.com/jedi4ever/addt/cmd/cache"
 	configcmd "github.com/jedi4ever/addt/cmd/config"
 	extcmd "github.com/jedi4ever/addt/cmd/extensions"
 	firewallcmd "github.com/jedi4ever/addt/cmd/firewall"
 	profilecmd "github.com/jedi4ever/addt/cmd/profile"
 	"github.com/jedi4ever/addt/config"
+	"github.com/jedi4ever/addt/config/security"
 	"github.com/jedi4ever/addt/core"
 	"github.com/jedi4ever/addt/provider"
 	"github.com/jedi4ever/addt/util"
@@ -61,8 +64,8 @@ func Execute(version, defaultNodeVersion, defaultGoVersion, defaultUvVersion str
 		}
 		// Check if first arg is a known addt command (matches switch cases below)
 		switch args[0] {
-		case "run", "build", "update", "shell", "containers", "firewall",
-			"extensions", "cli", "config", "profile", "version", "completion", "doctor", "init":
+		case "run", "build", "update", "shell", "containers", "firewall", "audit", "diff", "apply", "discard",
+			"extensions", "cli", "config", "profile", "cache", "version", "completion", "doctor", "init":
 			// Known command, continue processing
 		default:
 			// Unknown command, show help
@@ -95,10 +98,18 @@ func Execute(version, defaultNodeVersion, defaultGoVersion, defaultUvVersion str
 		case "profile":
 			profilecmd.HandleCommand(args[1:])
 			return
+		case "cache":
+			cachecmd.HandleCommand(args[1:])
+			return
 		case "extensions":
 			extcmd.HandleCommand(args[1:])
 			return
 		case "run":
+			// addt run --fanout <N|ext1,ext2,...> [args...] - run several at once
+			if len(args) > 1 && isFanoutFlag(args[1]) {
+				HandleFanoutCommand(args[1:], version, defaultNodeVersion, defaultGoVersion, defaultUvVersion, defaultPortRangeStart)
+				return
+			}
 			// addt run <extension> [args...] - run a specific extension
 			remainingArgs := HandleRunCommand(args[1:])
 			if remainingArgs == nil {
@@ -110,7 +121,7 @@ func Execute(version, defaultNodeVersion, defaultGoVersion, defaultUvVersion str
 			HandleUpdateCommand(args[1:], version, defaultNodeVersion, defaultGoVersion, defaultUvVersion, defaultPortRangeStart)
 			return
 
-		case "build", "shell", "containers", "firewall":
+		case "build", "shell", "containers", "firewall", "audit", "diff", "apply", "discard":
 			// Top-level subcommands (work for both plain addt and via "addt" namespace)
 			subCmd := args[0]
 			subArgs := args[1:]
@@ -137,6 +148,8 @@ func Execute(version, defaultNodeVersion, defaultGoVersion, defaultUvVersion str
 				configcmd.HandleCommand(subArgs)
 			case "profile":
 				profilecmd.HandleCommand(subArgs)
+			case "cache":
+				cachecmd.HandleCommand(subArgs)
 			case "version":
 				PrintVersion(version, defaultNodeVersion, defaultGoVersion, defaultUvVersion)
 			default:
@@ -156,11 +169,69 @@ func Execute(version, defaultNodeVersion, defaultGoVersion, defaultUvVersion str
 	util.InitLoggerFull(cfg.LogFile, cfg.LogDir, cfg.LogOutput, cfg.LogEnabled, cfg.LogLevel, cfg.LogModules, cfg.LogRotate, cfg.LogMaxSize, cfg.LogMaxFiles)
 	logger := util.Log("root")
 	logger.Debugf("Initializing logger with file: %s, dir: %s, enabled: %v, level: %s, modules: %s", cfg.LogFile, cfg.LogDir, cfg.LogEnabled, cfg.LogLevel, cfg.LogModules)
+
+	// Open the security audit log (proxy decisions are recorded there)
+	if err := security.InitAuditLog(&cfg.Security); err != nil {
+		fmt.Printf("Warning: failed to open audit log: %v\n", err)
+	}
 	// Note: --yolo and other agent-specific arg transformations are handled
 	// by each extension's args.sh script in the container
 
 	// Convert main config to provider config
-	providerCfg := &provider.Config{
+	providerCfg := newProviderConfig(cfg)
+
+	// Create provider
+	prov, err := NewProvider(cfg.Provider, providerCfg)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Initialize provider (checks prerequisites)
+	if err := prov.Initialize(providerCfg); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Determine image name and build if needed (provider-specific)
+	providerCfg.ImageName = prov.DetermineImageName()
+	if err := prov.BuildIfNeeded(false, false); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Create runner
+	runner := core.NewRunner(prov, providerCfg)
+
+	// Auto-detect GitHub token from gh CLI if configured
+	config.HandleGitHubGhAuth(cfg.GitHubTokenSource)
+
+	// Filter GH_TOKEN from env vars if forwarding is disabled
+	providerCfg.EnvVars = config.HandleGitHubToken(cfg.GitHubForwardToken, providerCfg.EnvVars)
+
+	// Load env file if enabled
+	if cfg.EnvFileLoad {
+		if err := config.LoadEnvFile(cfg.EnvFile); err != nil {
+			fmt.Printf("Error loading env file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Run via runner; a failed run still stops the session's proxies and
+	// saves its usage
+	if err := runner.Run(args); err != nil {
+		prov.Cleanup()
+		os.Exit(1)
+	}
+
+	// Cleanup
+	prov.Cleanup()
+}
+
+// newProviderConfig converts the main config to the provider config a run
+// uses
+func newProviderConfig(cfg *config.Config) *provider.Config {
+	return &provider.Config{
 		AddtVersion:               cfg.AddtVersion,
 		ExtensionVersions:         cfg.ExtensionVersions,
 		ExtensionConfigAutomount:  cfg.ExtensionConfigAutomount,
@@ -177,22 +248,32 @@ func Execute(version, defaultNodeVersion, defaultGoVersion, defaultUvVersion str
 		GoVersion:                 cfg.GoVersion,
 		UvVersion:                 cfg.UvVersion,
 		EnvVars:                   cfg.EnvVars,
+		Secrets:                   cfg.Secrets,
 		GitHubForwardToken:        cfg.GitHubForwardToken,
 		GitHubTokenSource:         cfg.GitHubTokenSource,
 		GitHubScopeToken:          cfg.GitHubScopeToken,
 		GitHubScopeRepos:          cfg.GitHubScopeRepos,
+		GitHubHoldToken:           cfg.GitHubHoldToken,
+		GitHubAppID:               cfg.GitHubAppID,
+		GitHubAppInstallationID:   cfg.GitHubAppInstallationID,
+		GitHubAppKeyFile:          cfg.GitHubAppKeyFile,
+		GitHubAppPermissions:      cfg.GitHubAppPermissions,
 		Ports:                     cfg.Ports,
 		PortRangeStart:            cfg.PortRangeStart,
 		PortsInjectSystemPrompt:   cfg.PortsInjectSystemPrompt,
+		HostServices:              firewallcmd.HostServices(cfg),
 		SSHForwardKeys:            cfg.SSHForwardKeys,
 		SSHForwardMode:            cfg.SSHForwardMode,
 		SSHAllowedKeys:            cfg.SSHAllowedKeys,
+		SSHAllowedHosts:           cfg.SSHAllowedHosts,
+		SSHSignPolicy:             cfg.SSHSignPolicy,
 		SSHDir:                    cfg.SSHDir,
 		GitDisableHooks:           cfg.GitDisableHooks,
 		GitForwardConfig:          cfg.GitForwardConfig,
 		GitConfigPath:             cfg.GitConfigPath,
 		GPGForward:                cfg.GPGForward,
 		GPGAllowedKeyIDs:          cfg.GPGAllowedKeyIDs,
+		GPGSignPolicy:             cfg.GPGSignPolicy,
 		GPGDir:                    cfg.GPGDir,
 		TmuxForward:               cfg.TmuxForward,
 		HistoryPersist:            cfg.HistoryPersist,
@@ -206,67 +287,33 @@ func Execute(version, defaultNodeVersion, defaultGoVersion, defaultUvVersion str
 		Persistent:                cfg.Persistent,
 		WorkdirAutomount:          cfg.WorkdirAutomount,
 		WorkdirReadonly:           cfg.WorkdirReadonly,
+		WorkdirOverlay:            cfg.WorkdirOverlay,
+		WorkdirWorktree:           cfg.WorkdirWorktree,
+		WorkdirWorktreeName:       cfg.WorkdirWorktreeName,
 		WorkdirAutotrust:          cfg.WorkdirAutotrust,
 		Workdir:                   cfg.Workdir,
 		FirewallEnabled:           cfg.FirewallEnabled,
 		FirewallMode:              cfg.FirewallMode,
+		FirewallProxy:             cfg.FirewallProxy,
+		FirewallRules:             firewallcmd.Rules(cfg),
+		FirewallLearn:             cfg.FirewallLearn,
+		FirewallQuota:             firewallcmd.Quota(cfg),
+		FirewallQuotaAction:       cfg.FirewallQuotaAction,
+		OfflineEnabled:            cfg.OfflineEnabled,
+		OfflineFetch:              cfg.OfflineFetch,
 		Mode:                      cfg.Mode,
 		Provider:                  cfg.Provider,
 		Extensions:                cfg.Extensions,
 		Command:                   cfg.Command,
+		ContainerName:             cfg.ContainerName,
 		ContainerCPUs:             cfg.ContainerCPUs,
 		ContainerMemory:           cfg.ContainerMemory,
 		Security:                  cfg.Security,
 		Otel:                      cfg.Otel,
 	}
-
-	// Create provider
-	prov, err := NewProvider(cfg.Provider, providerCfg)
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Initialize provider (checks prerequisites)
-	if err := prov.Initialize(providerCfg); err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Determine image name and build if needed (provider-specific)
-	providerCfg.ImageName = prov.DetermineImageName()
-	if err := prov.BuildIfNeeded(false, false); err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Create runner
-	runner := core.NewRunner(prov, providerCfg)
-
-	// Auto-detect GitHub token from gh CLI if configured
-	config.HandleGitHubGhAuth(cfg.GitHubTokenSource)
-
-	// Filter GH_TOKEN from env vars if forwarding is disabled
-	providerCfg.EnvVars = config.HandleGitHubToken(cfg.GitHubForwardToken, providerCfg.EnvVars)
-
-	// Load env file if enabled
-	if cfg.EnvFileLoad {
-		if err := config.LoadEnvFile(cfg.EnvFile); err != nil {
-			fmt.Printf("Error loading env file: %v\n", err)
-			os.Exit(1)
-		}
-	}
-
-	// Run via runner
-	if err := runner.Run(args); err != nil {
-		os.Exit(1)
-	}
-
-	// Cleanup
-	prov.Cleanup()
 }
 
-// handleSubcommand handles addt subcommands (build, shell, containers, firewall)
+// handleSubcommand handles addt subcommands (build, shell, containers, firewall, audit, diff, apply, discard)
 func handleSubcommand(subCmd string, subArgs []string, version, defaultNodeVersion, defaultGoVersion, defaultUvVersion string, defaultPortRangeStart int) {
 	cfg := config.LoadConfig(version, defaultNodeVersion, defaultGoVersion, defaultUvVersion, defaultPortRangeStart)
 
@@ -351,6 +398,18 @@ func handleSubcommand(subCmd string, subArgs []string, version, defaultNodeVersi
 	case "firewall":
 		firewallcmd.HandleCommand(subArgs)
 
+	case "audit":
+		auditcmd.HandleCommand(subArgs, &cfg.Security)
+
+	case "diff":
+		HandleDiffCommand(cfg.Workdir, subArgs)
+
+	case "apply":
+		HandleApplyCommand(cfg.Workdir, subArgs)
+
+	case "discard":
+		HandleDiscardCommand(cfg.Workdir, subArgs)
+
 	default:
 		fmt.Printf("Unknown command: %s\n", subCmd)
 		os.Exit(1)
@@ -15,6 +15,25 @@ func HandleRunCommand(args []string) []string {
 	runLogger := util.Log("run")
 	runLogger.Debugf("HandleRunCommand called with args: %v", args)
 
+flags:
+	for len(args) > 0 {
+		switch args[0] {
+		case "--firewall-learn":
+			// Enforce nothing, record every destination for review
+			runLogger.Debug("Firewall learn mode enabled")
+			os.Setenv("ADDT_FIREWALL", "true")
+			os.Setenv("ADDT_FIREWALL_MODE", "permissive")
+			os.Setenv("ADDT_FIREWALL_LEARN", "true")
+		case "--offline":
+			// Reach only the host-side package mirrors
+			runLogger.Debug("Offline mode enabled")
+			os.Setenv("ADDT_OFFLINE", "true")
+		default:
+			break flags
+		}
+		args = args[1:]
+	}
+
 	if len(args) < 1 {
 		runLogger.Debug("No extension specified, showing help")
 		printRunHelp()
@@ -63,6 +82,10 @@ func HandleRunCommand(args []string) []string {
 
 func printRunHelp() {
 	fmt.Println("Usage: addt run <extension> [args...]")
+	fmt.Println("       addt run --fanout <count> <extension> [args...]")
+	fmt.Println("       addt run --fanout <ext1,ext2,...> [args...]")
+	fmt.Println("       addt run --firewall-learn <extension> [args...]")
+	fmt.Println("       addt run --offline <extension> [args...]")
 	fmt.Println()
 	fmt.Println("Run a specific extension in a container.")
 	fmt.Println()
@@ -70,10 +93,26 @@ func printRunHelp() {
 	fmt.Println("  <extension>    Name of the extension to run")
 	fmt.Println("  [args...]      Arguments to pass to the extension")
 	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --fanout       Run several agents, or attempts of one, on the same")
+	fmt.Println("                 arguments at once, each in its own workspace overlay")
+	fmt.Println("                 (or worktree), then compare them and pick one to keep")
+	fmt.Println("  --firewall-learn")
+	fmt.Println("                 Run with the firewall in permissive mode and record every")
+	fmt.Println("                 destination the agent tries to reach; review them with")
+	fmt.Println("                 'addt firewall learn review'")
+	fmt.Println("  --offline      Reach only host-side npm, PyPI and Go package mirrors")
+	fmt.Println("                 backed by ~/.addt/cache; pre-seed them with")
+	fmt.Println("                 'addt cache warm <lockfile>'")
+	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  addt run claude \"Fix the bug\"")
 	fmt.Println("  addt run codex --help")
 	fmt.Println("  addt run gemini")
+	fmt.Println("  addt run --fanout claude,codex,gemini \"Fix the bug\"")
+	fmt.Println("  addt run --fanout 3 claude \"Fix the bug\"")
+	fmt.Println("  addt run --firewall-learn claude \"Fix the bug\"")
+	fmt.Println("  addt run --offline claude \"Fix the bug\"")
 	fmt.Println()
 	fmt.Println("To see available extensions:")
 	fmt.Println("  addt extensions list")
@@ -125,3 +125,23 @@ func TestHandleRunCommand_SetsEntrypoint(t *testing.T) {
 
 // Note: Testing invalid extension would cause os.Exit(1), which is hard to test.
 // In production code, you might want to return an error instead of calling os.Exit.
+
+func TestHandleRunCommand_Offline(t *testing.T) {
+	t.Setenv("ADDT_EXTENSIONS", "")
+	t.Setenv("ADDT_COMMAND", "")
+	t.Setenv("ADDT_OFFLINE", "")
+	t.Setenv("ADDT_FIREWALL", "")
+	t.Setenv("ADDT_FIREWALL_MODE", "")
+	t.Setenv("ADDT_FIREWALL_LEARN", "")
+
+	result := HandleRunCommand([]string{"--offline", "--firewall-learn", "claude", "--offline"})
+	if len(result) != 1 || result[0] != "--offline" {
+		t.Errorf("HandleRunCommand() = %v, want the agent's own args", result)
+	}
+	if os.Getenv("ADDT_OFFLINE") != "true" {
+		t.Errorf("ADDT_OFFLINE = %q, want true", os.Getenv("ADDT_OFFLINE"))
+	}
+	if os.Getenv("ADDT_FIREWALL_LEARN") != "true" {
+		t.Errorf("ADDT_FIREWALL_LEARN = %q, want true", os.Getenv("ADDT_FIREWALL_LEARN"))
+	}
+}
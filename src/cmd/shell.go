@@ -6,7 +6,9 @@ import (
 	"strings"
 
 	extcmd "github.com/jedi4ever/addt/cmd/extensions"
+	firewallcmd "github.com/jedi4ever/addt/cmd/firewall"
 	"github.com/jedi4ever/addt/config"
+	"github.com/jedi4ever/addt/config/security"
 	"github.com/jedi4ever/addt/core"
 	"github.com/jedi4ever/addt/provider"
 	"github.com/jedi4ever/addt/util"
@@ -46,6 +48,11 @@ func HandleShellCommand(args []string, version, defaultNodeVersion, defaultGoVer
 		cfg.Command = extcmd.GetEntrypoint(cfg.Extensions)
 	}
 
+	// Open the security audit log (proxy decisions are recorded there)
+	if err := security.InitAuditLog(&cfg.Security); err != nil {
+		fmt.Printf("Warning: failed to open audit log: %v\n", err)
+	}
+
 	// Create provider config
 	providerCfg := &provider.Config{
 		AddtVersion:               cfg.AddtVersion,
@@ -64,17 +71,29 @@ func HandleShellCommand(args []string, version, defaultNodeVersion, defaultGoVer
 		GoVersion:                 cfg.GoVersion,
 		UvVersion:                 cfg.UvVersion,
 		EnvVars:                   cfg.EnvVars,
+		Secrets:                   cfg.Secrets,
 		GitHubForwardToken:        cfg.GitHubForwardToken,
 		GitHubTokenSource:         cfg.GitHubTokenSource,
+		GitHubScopeToken:          cfg.GitHubScopeToken,
+		GitHubScopeRepos:          cfg.GitHubScopeRepos,
+		GitHubHoldToken:           cfg.GitHubHoldToken,
+		GitHubAppID:               cfg.GitHubAppID,
+		GitHubAppInstallationID:   cfg.GitHubAppInstallationID,
+		GitHubAppKeyFile:          cfg.GitHubAppKeyFile,
+		GitHubAppPermissions:      cfg.GitHubAppPermissions,
 		Ports:                     cfg.Ports,
 		PortRangeStart:            cfg.PortRangeStart,
 		PortsInjectSystemPrompt:   cfg.PortsInjectSystemPrompt,
+		HostServices:              firewallcmd.HostServices(cfg),
 		SSHForwardKeys:            cfg.SSHForwardKeys,
 		SSHForwardMode:            cfg.SSHForwardMode,
 		SSHAllowedKeys:            cfg.SSHAllowedKeys,
+		SSHAllowedHosts:           cfg.SSHAllowedHosts,
+		SSHSignPolicy:             cfg.SSHSignPolicy,
 		SSHDir:                    cfg.SSHDir,
 		GPGForward:                cfg.GPGForward,
 		GPGAllowedKeyIDs:          cfg.GPGAllowedKeyIDs,
+		GPGSignPolicy:             cfg.GPGSignPolicy,
 		GPGDir:                    cfg.GPGDir,
 		TmuxForward:               cfg.TmuxForward,
 		HistoryPersist:            cfg.HistoryPersist,
@@ -87,10 +106,20 @@ func HandleShellCommand(args []string, version, defaultNodeVersion, defaultGoVer
 		Persistent:                cfg.Persistent,
 		WorkdirAutomount:          cfg.WorkdirAutomount,
 		WorkdirReadonly:           cfg.WorkdirReadonly,
+		WorkdirOverlay:            cfg.WorkdirOverlay,
+		WorkdirWorktree:           cfg.WorkdirWorktree,
+		WorkdirWorktreeName:       cfg.WorkdirWorktreeName,
 		WorkdirAutotrust:          cfg.WorkdirAutotrust,
 		Workdir:                   cfg.Workdir,
 		FirewallEnabled:           cfg.FirewallEnabled,
 		FirewallMode:              cfg.FirewallMode,
+		FirewallProxy:             cfg.FirewallProxy,
+		FirewallRules:             firewallcmd.Rules(cfg),
+		FirewallLearn:             cfg.FirewallLearn,
+		FirewallQuota:             firewallcmd.Quota(cfg),
+		FirewallQuotaAction:       cfg.FirewallQuotaAction,
+		OfflineEnabled:            cfg.OfflineEnabled,
+		OfflineFetch:              cfg.OfflineFetch,
 		Mode:                      cfg.Mode,
 		Provider:                  cfg.Provider,
 		Extensions:                cfg.Extensions,
@@ -257,6 +257,104 @@ func TestLoadConfig_FirewallModePrecedence(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_OfflineForcesStrictFirewall(t *testing.T) {
+	_, projectDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	t.Setenv("ADDT_OFFLINE", "")
+	t.Setenv("ADDT_OFFLINE_FETCH", "")
+
+	cfg := LoadConfig("0.0.0-test", "20", "1.21", "0.1.0", 30000)
+	if cfg.OfflineEnabled || !cfg.OfflineFetch {
+		t.Errorf("Offline = %v, fetch = %v, want false, true (default)", cfg.OfflineEnabled, cfg.OfflineFetch)
+	}
+
+	enabled, fetch, off := true, false, false
+	writeProjectConfig(t, projectDir, &GlobalConfig{
+		Offline:  &OfflineSettings{Enabled: &enabled, Fetch: &fetch},
+		Firewall: &FirewallSettings{Enabled: &off, Mode: "off"},
+	})
+	cfg = LoadConfig("0.0.0-test", "20", "1.21", "0.1.0", 30000)
+	if !cfg.OfflineEnabled || cfg.OfflineFetch {
+		t.Errorf("Offline = %v, fetch = %v, want true, false (from project)", cfg.OfflineEnabled, cfg.OfflineFetch)
+	}
+	if !cfg.FirewallEnabled || cfg.FirewallMode != "strict" {
+		t.Errorf("Firewall = %v %q, want a strict firewall offline", cfg.FirewallEnabled, cfg.FirewallMode)
+	}
+
+	t.Setenv("ADDT_OFFLINE", "false")
+	cfg = LoadConfig("0.0.0-test", "20", "1.21", "0.1.0", 30000)
+	if cfg.OfflineEnabled || cfg.FirewallEnabled {
+		t.Errorf("Offline = %v, firewall = %v, want both off (from env)", cfg.OfflineEnabled, cfg.FirewallEnabled)
+	}
+}
+
+func TestLoadConfig_SecretsMerge(t *testing.T) {
+	globalDir, projectDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	writeGlobalConfig(t, globalDir, &GlobalConfig{Secrets: map[string]string{
+		"ANTHROPIC_API_KEY": "op://Personal/anthropic/key",
+		"OPENAI_API_KEY":    "pass://ai/openai",
+	}})
+	writeProjectConfig(t, projectDir, &GlobalConfig{Secrets: map[string]string{
+		"ANTHROPIC_API_KEY": "vault://secret/data/ai#anthropic",
+	}})
+
+	cfg := LoadConfig("0.0.0-test", "20", "1.21", "0.1.0", 30000)
+	if cfg.Secrets["ANTHROPIC_API_KEY"] != "vault://secret/data/ai#anthropic" {
+		t.Errorf("ANTHROPIC_API_KEY = %q, want the project's reference", cfg.Secrets["ANTHROPIC_API_KEY"])
+	}
+	if cfg.Secrets["OPENAI_API_KEY"] != "pass://ai/openai" {
+		t.Errorf("OPENAI_API_KEY = %q, want the global reference", cfg.Secrets["OPENAI_API_KEY"])
+	}
+}
+
+func TestLoadConfig_SignPolicy(t *testing.T) {
+	globalDir, projectDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg := LoadConfig("0.0.0-test", "20", "1.21", "0.1.0", 30000)
+	if cfg.SSHSignPolicy != "allow" || cfg.GPGSignPolicy != "allow" {
+		t.Errorf("default sign policies = %q, %q; want allow", cfg.SSHSignPolicy, cfg.GPGSignPolicy)
+	}
+
+	writeGlobalConfig(t, globalDir, &GlobalConfig{SSH: &SSHSettings{SignPolicy: "confirm"}})
+	writeProjectConfig(t, projectDir, &GlobalConfig{GPG: &GPGSettings{SignPolicy: "confirm"}})
+	cfg = LoadConfig("0.0.0-test", "20", "1.21", "0.1.0", 30000)
+	if cfg.SSHSignPolicy != "confirm" || cfg.GPGSignPolicy != "confirm" {
+		t.Errorf("sign policies = %q, %q; want confirm", cfg.SSHSignPolicy, cfg.GPGSignPolicy)
+	}
+
+	t.Setenv("ADDT_SSH_SIGN_POLICY", "allow")
+	cfg = LoadConfig("0.0.0-test", "20", "1.21", "0.1.0", 30000)
+	if cfg.SSHSignPolicy != "allow" {
+		t.Errorf("SSHSignPolicy = %q, want allow from env", cfg.SSHSignPolicy)
+	}
+}
+
+func TestLoadConfig_SSHAllowedHosts(t *testing.T) {
+	globalDir, projectDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	writeGlobalConfig(t, globalDir, &GlobalConfig{SSH: &SSHSettings{AllowedHosts: []string{"github.com"}}})
+	cfg := LoadConfig("0.0.0-test", "20", "1.21", "0.1.0", 30000)
+	if len(cfg.SSHAllowedHosts) != 1 || cfg.SSHAllowedHosts[0] != "github.com" {
+		t.Errorf("SSHAllowedHosts = %v, want [github.com] from global", cfg.SSHAllowedHosts)
+	}
+
+	writeProjectConfig(t, projectDir, &GlobalConfig{SSH: &SSHSettings{AllowedHosts: []string{"deploy=github.com"}}})
+	cfg = LoadConfig("0.0.0-test", "20", "1.21", "0.1.0", 30000)
+	if len(cfg.SSHAllowedHosts) != 1 || cfg.SSHAllowedHosts[0] != "deploy=github.com" {
+		t.Errorf("SSHAllowedHosts = %v, want [deploy=github.com] from project", cfg.SSHAllowedHosts)
+	}
+
+	t.Setenv("ADDT_SSH_ALLOWED_HOSTS", "github.com,gitlab.com")
+	cfg = LoadConfig("0.0.0-test", "20", "1.21", "0.1.0", 30000)
+	if len(cfg.SSHAllowedHosts) != 2 || cfg.SSHAllowedHosts[1] != "gitlab.com" {
+		t.Errorf("SSHAllowedHosts = %v, want two hosts from env", cfg.SSHAllowedHosts)
+	}
+}
+
 func TestLoadConfig_ExtensionVersionPrecedence(t *testing.T) {
 	globalDir, projectDir, cleanup := setupTestEnv(t)
 	defer cleanup()
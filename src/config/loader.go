@@ -115,6 +115,9 @@ func LoadConfig(addtVersion, defaultNodeVersion, defaultGoVersion, defaultUvVers
 		if len(globalCfg.SSH.AllowedKeys) > 0 {
 			cfg.SSHAllowedKeys = globalCfg.SSH.AllowedKeys
 		}
+		if len(globalCfg.SSH.AllowedHosts) > 0 {
+			cfg.SSHAllowedHosts = globalCfg.SSH.AllowedHosts
+		}
 	}
 	if projectCfg.SSH != nil {
 		if projectCfg.SSH.ForwardKeys != nil {
@@ -126,6 +129,9 @@ func LoadConfig(addtVersion, defaultNodeVersion, defaultGoVersion, defaultUvVers
 		if len(projectCfg.SSH.AllowedKeys) > 0 {
 			cfg.SSHAllowedKeys = projectCfg.SSH.AllowedKeys
 		}
+		if len(projectCfg.SSH.AllowedHosts) > 0 {
+			cfg.SSHAllowedHosts = projectCfg.SSH.AllowedHosts
+		}
 	}
 	if v := os.Getenv("ADDT_SSH_FORWARD_KEYS"); v != "" {
 		cfg.SSHForwardKeys = v == "true"
@@ -136,6 +142,9 @@ func LoadConfig(addtVersion, defaultNodeVersion, defaultGoVersion, defaultUvVers
 	if v := os.Getenv("ADDT_SSH_ALLOWED_KEYS"); v != "" {
 		cfg.SSHAllowedKeys = strings.Split(v, ",")
 	}
+	if v := os.Getenv("ADDT_SSH_ALLOWED_HOSTS"); v != "" {
+		cfg.SSHAllowedHosts = strings.Split(v, ",")
+	}
 
 	// SSH dir: default ("") -> global -> project -> env
 	cfg.SSHDir = ""
@@ -149,6 +158,18 @@ func LoadConfig(addtVersion, defaultNodeVersion, defaultGoVersion, defaultUvVers
 		cfg.SSHDir = v
 	}
 
+	// SSH sign policy: default (allow) -> global -> project -> env
+	cfg.SSHSignPolicy = "allow"
+	if globalCfg.SSH != nil && globalCfg.SSH.SignPolicy != "" {
+		cfg.SSHSignPolicy = globalCfg.SSH.SignPolicy
+	}
+	if projectCfg.SSH != nil && projectCfg.SSH.SignPolicy != "" {
+		cfg.SSHSignPolicy = projectCfg.SSH.SignPolicy
+	}
+	if v := os.Getenv("ADDT_SSH_SIGN_POLICY"); v != "" {
+		cfg.SSHSignPolicy = v
+	}
+
 	// Tmux forward: default (false) -> global -> project -> env
 	cfg.TmuxForward = false
 	if globalCfg.TmuxForward != nil {
@@ -215,6 +236,18 @@ func LoadConfig(addtVersion, defaultNodeVersion, defaultGoVersion, defaultUvVers
 		cfg.GPGAllowedKeyIDs = strings.Split(v, ",")
 	}
 
+	// GPG sign policy: default (allow) -> global -> project -> env
+	cfg.GPGSignPolicy = "allow"
+	if globalCfg.GPG != nil && globalCfg.GPG.SignPolicy != "" {
+		cfg.GPGSignPolicy = globalCfg.GPG.SignPolicy
+	}
+	if projectCfg.GPG != nil && projectCfg.GPG.SignPolicy != "" {
+		cfg.GPGSignPolicy = projectCfg.GPG.SignPolicy
+	}
+	if v := os.Getenv("ADDT_GPG_SIGN_POLICY"); v != "" {
+		cfg.GPGSignPolicy = v
+	}
+
 	// GPG dir: default ("") -> global -> project -> env
 	cfg.GPGDir = ""
 	if globalCfg.GPG != nil && globalCfg.GPG.Dir != "" {
@@ -389,6 +422,41 @@ func LoadConfig(addtVersion, defaultNodeVersion, defaultGoVersion, defaultUvVers
 		cfg.WorkdirReadonly = v == "true"
 	}
 
+	// Workdir overlay: default (false) -> global -> project -> env
+	cfg.WorkdirOverlay = false
+	if globalCfg.Workdir != nil && globalCfg.Workdir.Overlay != nil {
+		cfg.WorkdirOverlay = *globalCfg.Workdir.Overlay
+	}
+	if projectCfg.Workdir != nil && projectCfg.Workdir.Overlay != nil {
+		cfg.WorkdirOverlay = *projectCfg.Workdir.Overlay
+	}
+	if v := os.Getenv("ADDT_WORKDIR_OVERLAY"); v != "" {
+		cfg.WorkdirOverlay = v == "true"
+	}
+
+	// Workdir worktree: default (false) -> global -> project -> env
+	cfg.WorkdirWorktree = false
+	if globalCfg.Workdir != nil && globalCfg.Workdir.Worktree != nil {
+		cfg.WorkdirWorktree = *globalCfg.Workdir.Worktree
+	}
+	if projectCfg.Workdir != nil && projectCfg.Workdir.Worktree != nil {
+		cfg.WorkdirWorktree = *projectCfg.Workdir.Worktree
+	}
+	if v := os.Getenv("ADDT_WORKDIR_WORKTREE"); v != "" {
+		cfg.WorkdirWorktree = v == "true"
+	}
+
+	// Workdir worktree name: default (empty = container name) -> global -> project -> env
+	if globalCfg.Workdir != nil {
+		cfg.WorkdirWorktreeName = globalCfg.Workdir.WorktreeName
+	}
+	if projectCfg.Workdir != nil && projectCfg.Workdir.WorktreeName != "" {
+		cfg.WorkdirWorktreeName = projectCfg.Workdir.WorktreeName
+	}
+	if v := os.Getenv("ADDT_WORKDIR_WORKTREE_NAME"); v != "" {
+		cfg.WorkdirWorktreeName = v
+	}
+
 	// Workdir autotrust: default (true) -> global -> project -> env
 	cfg.WorkdirAutotrust = true
 	if globalCfg.Workdir != nil && globalCfg.Workdir.Autotrust != nil {
@@ -425,6 +493,66 @@ func LoadConfig(addtVersion, defaultNodeVersion, defaultGoVersion, defaultUvVers
 		cfg.FirewallMode = v
 	}
 
+	// Firewall proxy: default (true) -> global -> project -> env
+	cfg.FirewallProxy = true
+	if globalCfg.Firewall != nil && globalCfg.Firewall.Proxy != nil {
+		cfg.FirewallProxy = *globalCfg.Firewall.Proxy
+	}
+	if projectCfg.Firewall != nil && projectCfg.Firewall.Proxy != nil {
+		cfg.FirewallProxy = *projectCfg.Firewall.Proxy
+	}
+	if v := os.Getenv("ADDT_FIREWALL_PROXY"); v != "" {
+		cfg.FirewallProxy = v == "true"
+	}
+
+	// Firewall intercept: default (false) -> global -> project -> env
+	if globalCfg.Firewall != nil && globalCfg.Firewall.Intercept != nil {
+		cfg.FirewallIntercept = *globalCfg.Firewall.Intercept
+	}
+	if projectCfg.Firewall != nil && projectCfg.Firewall.Intercept != nil {
+		cfg.FirewallIntercept = *projectCfg.Firewall.Intercept
+	}
+	if v := os.Getenv("ADDT_FIREWALL_INTERCEPT"); v != "" {
+		cfg.FirewallIntercept = v == "true"
+	}
+
+	// Firewall egress quotas: default (none) -> global -> project -> env
+	if globalCfg.Firewall != nil && globalCfg.Firewall.MaxEgress != "" {
+		cfg.FirewallMaxEgress = globalCfg.Firewall.MaxEgress
+	}
+	if projectCfg.Firewall != nil && projectCfg.Firewall.MaxEgress != "" {
+		cfg.FirewallMaxEgress = projectCfg.Firewall.MaxEgress
+	}
+	if v := os.Getenv("ADDT_FIREWALL_MAX_EGRESS"); v != "" {
+		cfg.FirewallMaxEgress = v
+	}
+	if globalCfg.Firewall != nil && globalCfg.Firewall.MaxRequests != nil {
+		cfg.FirewallMaxRequests = *globalCfg.Firewall.MaxRequests
+	}
+	if projectCfg.Firewall != nil && projectCfg.Firewall.MaxRequests != nil {
+		cfg.FirewallMaxRequests = *projectCfg.Firewall.MaxRequests
+	}
+	if v := os.Getenv("ADDT_FIREWALL_MAX_REQUESTS"); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			cfg.FirewallMaxRequests = i
+		}
+	}
+
+	// Firewall quota action: default (pause) -> global -> project -> env
+	cfg.FirewallQuotaAction = "pause"
+	if globalCfg.Firewall != nil && globalCfg.Firewall.QuotaAction != "" {
+		cfg.FirewallQuotaAction = globalCfg.Firewall.QuotaAction
+	}
+	if projectCfg.Firewall != nil && projectCfg.Firewall.QuotaAction != "" {
+		cfg.FirewallQuotaAction = projectCfg.Firewall.QuotaAction
+	}
+	if v := os.Getenv("ADDT_FIREWALL_QUOTA_ACTION"); v != "" {
+		cfg.FirewallQuotaAction = v
+	}
+
+	// Firewall learn: session only (set by 'addt run --firewall-learn')
+	cfg.FirewallLearn = os.Getenv("ADDT_FIREWALL_LEARN") == "true"
+
 	// Firewall rules: keep each layer separate for layered override evaluation
 	// Order: Defaults → Extension → Global → Project (project wins)
 	if globalCfg.Firewall != nil {
@@ -521,6 +649,49 @@ func LoadConfig(addtVersion, defaultNodeVersion, defaultGoVersion, defaultUvVers
 		cfg.GitHubScopeRepos = strings.Split(v, ",")
 	}
 
+	// GitHub hold token: default (true) -> global -> project -> env
+	cfg.GitHubHoldToken = true
+	if globalCfg.GitHub != nil && globalCfg.GitHub.HoldToken != nil {
+		cfg.GitHubHoldToken = *globalCfg.GitHub.HoldToken
+	}
+	if projectCfg.GitHub != nil && projectCfg.GitHub.HoldToken != nil {
+		cfg.GitHubHoldToken = *projectCfg.GitHub.HoldToken
+	}
+	if v := os.Getenv("ADDT_GITHUB_HOLD_TOKEN"); v != "" {
+		cfg.GitHubHoldToken = v == "true"
+	}
+
+	// GitHub App: default (none) -> global -> project -> env
+	for _, gh := range []*GitHubSettings{globalCfg.GitHub, projectCfg.GitHub} {
+		if gh == nil {
+			continue
+		}
+		if gh.AppID != "" {
+			cfg.GitHubAppID = gh.AppID
+		}
+		if gh.AppInstallationID != "" {
+			cfg.GitHubAppInstallationID = gh.AppInstallationID
+		}
+		if gh.AppKeyFile != "" {
+			cfg.GitHubAppKeyFile = gh.AppKeyFile
+		}
+		if len(gh.AppPermissions) > 0 {
+			cfg.GitHubAppPermissions = gh.AppPermissions
+		}
+	}
+	if v := os.Getenv("ADDT_GITHUB_APP_ID"); v != "" {
+		cfg.GitHubAppID = v
+	}
+	if v := os.Getenv("ADDT_GITHUB_APP_INSTALLATION_ID"); v != "" {
+		cfg.GitHubAppInstallationID = v
+	}
+	if v := os.Getenv("ADDT_GITHUB_APP_KEY_FILE"); v != "" {
+		cfg.GitHubAppKeyFile = v
+	}
+	if v := os.Getenv("ADDT_GITHUB_APP_PERMISSIONS"); v != "" {
+		cfg.GitHubAppPermissions = strings.Split(v, ",")
+	}
+
 	// Container CPUs: default (2) -> global -> project -> env
 	cfg.ContainerCPUs = "2" // Secure default: limit CPU usage
 	if globalCfg.Container != nil && globalCfg.Container.CPUs != "" {
@@ -632,6 +803,7 @@ func LoadConfig(addtVersion, defaultNodeVersion, defaultGoVersion, defaultUvVers
 	cfg.Provider = DetectContainerRuntime()
 	cfg.Extensions = os.Getenv("ADDT_EXTENSIONS")
 	cfg.Command = os.Getenv("ADDT_COMMAND")
+	cfg.ContainerName = os.Getenv("ADDT_CONTAINER_NAME")
 
 	// Load per-extension config from config files
 	// Precedence: global config < project config < environment variables
@@ -779,6 +951,30 @@ func LoadConfig(addtVersion, defaultNodeVersion, defaultGoVersion, defaultUvVers
 		}
 	}
 
+	// Ports host services: global -> project -> env
+	if globalCfg.Ports != nil && len(globalCfg.Ports.HostServices) > 0 {
+		cfg.PortsHostServices = globalCfg.Ports.HostServices
+	}
+	if projectCfg.Ports != nil && len(projectCfg.Ports.HostServices) > 0 {
+		cfg.PortsHostServices = projectCfg.Ports.HostServices
+	}
+	if v := os.Getenv("ADDT_PORTS_HOST_SERVICES"); v != "" {
+		cfg.PortsHostServices = strings.Split(v, ",")
+		for i := range cfg.PortsHostServices {
+			cfg.PortsHostServices[i] = strings.TrimSpace(cfg.PortsHostServices[i])
+		}
+	}
+
+	// Secrets: global, then project per env var
+	for _, secrets := range []map[string]string{globalCfg.Secrets, projectCfg.Secrets} {
+		for name, ref := range secrets {
+			if cfg.Secrets == nil {
+				cfg.Secrets = make(map[string]string)
+			}
+			cfg.Secrets[name] = ref
+		}
+	}
+
 	// If ports.forward is false, clear ports so downstream sees no ports
 	if !portsForward {
 		cfg.Ports = nil
@@ -792,6 +988,37 @@ func LoadConfig(addtVersion, defaultNodeVersion, defaultGoVersion, defaultUvVers
 	// Load security configuration using the security package
 	cfg.Security = security.LoadConfig(globalCfg.Security, projectCfg.Security)
 
+	// Offline: default (false) -> global -> project -> env
+	cfg.OfflineEnabled = false
+	if globalCfg.Offline != nil && globalCfg.Offline.Enabled != nil {
+		cfg.OfflineEnabled = *globalCfg.Offline.Enabled
+	}
+	if projectCfg.Offline != nil && projectCfg.Offline.Enabled != nil {
+		cfg.OfflineEnabled = *projectCfg.Offline.Enabled
+	}
+	if v := os.Getenv("ADDT_OFFLINE"); v != "" {
+		cfg.OfflineEnabled = v == "true"
+	}
+
+	// Offline fetch: default (true) -> global -> project -> env
+	cfg.OfflineFetch = true
+	if globalCfg.Offline != nil && globalCfg.Offline.Fetch != nil {
+		cfg.OfflineFetch = *globalCfg.Offline.Fetch
+	}
+	if projectCfg.Offline != nil && projectCfg.Offline.Fetch != nil {
+		cfg.OfflineFetch = *projectCfg.Offline.Fetch
+	}
+	if v := os.Getenv("ADDT_OFFLINE_FETCH"); v != "" {
+		cfg.OfflineFetch = v == "true"
+	}
+
+	// Offline, the container only reaches the package mirror: the strict
+	// firewall enforces that unless the container has no network at all
+	if cfg.OfflineEnabled && cfg.Security.NetworkMode != "none" {
+		cfg.FirewallEnabled = true
+		cfg.FirewallMode = "strict"
+	}
+
 	// Load OTEL configuration using the otel package
 	cfg.Otel = otel.LoadConfig(globalCfg.Otel, projectCfg.Otel)
 
@@ -11,7 +11,7 @@ import (
 )
 
 // defaultAutoselect is the default provider priority order.
-var defaultAutoselect = []string{"orbstack", "rancher", "docker", "podman"}
+var defaultAutoselect = []string{"orbstack", "rancher", "docker", "podman", "nerdctl"}
 
 // getAutoselect returns the provider autoselect order from config or default.
 func getAutoselect() []string {
@@ -69,6 +69,18 @@ func DetectContainerRuntime() string {
 			if isPodmanAvailable() {
 				return "podman"
 			}
+		case "nerdctl":
+			if isNerdctlAvailable() {
+				return "nerdctl"
+			}
+		case "engine":
+			if provider.HasEngineSocket() {
+				return "engine"
+			}
+		case "sandbox":
+			if isBwrapAvailable() {
+				return "sandbox"
+			}
 		}
 	}
 
@@ -98,6 +110,21 @@ func EnsureContainerRuntime() (string, error) {
 			return "", fmt.Errorf("Rancher Desktop is explicitly selected but rancher-desktop context not found")
 		}
 		return "rancher", nil
+	case "nerdctl":
+		if !isNerdctlAvailable() {
+			return "", fmt.Errorf("nerdctl is explicitly selected but not installed or containerd is not running")
+		}
+		return "nerdctl", nil
+	case "engine":
+		if !provider.HasEngineSocket() {
+			return "", fmt.Errorf("Engine API is explicitly selected but no Docker/Podman API socket is reachable")
+		}
+		return "engine", nil
+	case "sandbox":
+		if !isBwrapAvailable() {
+			return "", fmt.Errorf("sandbox is explicitly selected but bwrap is not installed or cannot create user namespaces")
+		}
+		return "sandbox", nil
 	}
 
 	// If explicitly set to something else (e.g. podman), honour it
@@ -152,6 +179,28 @@ func isOrbstackRunning() bool {
 	return strings.TrimSpace(string(output)) == "Running"
 }
 
+// isNerdctlAvailable checks if nerdctl is installed and can reach containerd
+func isNerdctlAvailable() bool {
+	nerdctlPath, err := exec.LookPath("nerdctl")
+	if err != nil {
+		return false
+	}
+	return exec.Command(nerdctlPath, "info").Run() == nil
+}
+
+// isBwrapAvailable checks if bubblewrap is installed and can create an
+// unprivileged user namespace
+func isBwrapAvailable() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	bwrapPath, err := exec.LookPath("bwrap")
+	if err != nil {
+		return false
+	}
+	return exec.Command(bwrapPath, "--unshare-user", "--ro-bind", "/", "/", "true").Run() == nil
+}
+
 // isPodmanAvailable checks if Podman is available and functional
 // Checks both system Podman and bundled Podman
 // On macOS, also verifies that a machine is running
@@ -240,6 +289,13 @@ func GetRuntimeInfo() (rt string, version string, extras []string) {
 		if hasPasta() {
 			extras = append(extras, "pasta")
 		}
+	case "nerdctl":
+		version = getNerdctlVersion()
+	case "engine":
+		version = "unknown"
+		extras = append(extras, provider.EngineSocket())
+	case "sandbox":
+		version = getBwrapVersion()
 	}
 
 	return rt, version, extras
@@ -283,6 +339,28 @@ func getPodmanVersion() string {
 	return strings.TrimPrefix(version, "podman version ")
 }
 
+func getNerdctlVersion() string {
+	cmd := exec.Command("nerdctl", "--version")
+	output, err := cmd.Output()
+	if err != nil {
+		return "unknown"
+	}
+	// Parse "nerdctl version X.Y.Z" -> "X.Y.Z"
+	version := strings.TrimSpace(string(output))
+	return strings.TrimPrefix(version, "nerdctl version ")
+}
+
+func getBwrapVersion() string {
+	cmd := exec.Command("bwrap", "--version")
+	output, err := cmd.Output()
+	if err != nil {
+		return "unknown"
+	}
+	// Parse "bubblewrap X.Y.Z" -> "X.Y.Z"
+	version := strings.TrimSpace(string(output))
+	return strings.TrimPrefix(version, "bubblewrap ")
+}
+
 func hasPasta() bool {
 	_, err := exec.LookPath("pasta")
 	return err == nil
@@ -0,0 +1,179 @@
+package security
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jedi4ever/addt/util"
+)
+
+var apiProxyLogger = util.Log("apiproxy")
+
+// APIProxyContainerPort is the port the API proxy is bridged to on the
+// container's loopback interface; the base URL env vars point there
+const APIProxyContainerPort = 4874
+
+// APIProxyTokenPrefix starts the placeholder keys the container gets
+const APIProxyTokenPrefix = "addt-proxy-"
+
+// APIRoute is an API the proxy forwards to, adding the real credential
+type APIRoute struct {
+	Name     string // path prefix on the proxy, e.g. "anthropic"
+	Upstream string // e.g. "https://api.anthropic.com"
+	Header   string // header the credential goes in, e.g. "x-api-key"
+	Scheme   string // prefix of the header's value, e.g. "Bearer"; "" for none
+	Key      string // the real credential
+}
+
+// headerValue is the header's value for a credential
+func (r APIRoute) headerValue(credential string) string {
+	if r.Scheme == "" {
+		return credential
+	}
+	return r.Scheme + " " + credential
+}
+
+// apiUpstream is a route with its upstream parsed
+type apiUpstream struct {
+	APIRoute
+	url *url.URL
+}
+
+// APIProxy is a host-side reverse proxy that keeps API keys out of the
+// container. The container gets a placeholder key, which the proxy checks
+// on every request and replaces with the real key before forwarding the
+// request upstream, so an agent that reads its environment finds nothing
+// it can use elsewhere. Requests go to /<route name>/<path>.
+type APIProxy struct {
+	container string
+	token     string
+	routes    map[string]*apiUpstream
+	proxy     *httputil.ReverseProxy
+	listener  net.Listener
+	server    *http.Server
+	mu        sync.Mutex
+	running   bool
+}
+
+// NewAPIProxy creates an API proxy for a container that accepts token in
+// place of each route's key
+func NewAPIProxy(container, token string, routes []APIRoute) (*APIProxy, error) {
+	p := &APIProxy{
+		container: container,
+		token:     token,
+		routes:    make(map[string]*apiUpstream, len(routes)),
+	}
+	for _, r := range routes {
+		u, err := url.Parse(r.Upstream)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("invalid upstream %q for API %s", r.Upstream, r.Name)
+		}
+		if r.Name == "" || strings.Contains(r.Name, "/") || r.Header == "" {
+			return nil, fmt.Errorf("API %q needs a name without '/' and a header", r.Name)
+		}
+		p.routes[r.Name] = &apiUpstream{APIRoute: r, url: u}
+	}
+	// Streamed responses (server-sent events) are passed on as they arrive
+	p.proxy = &httputil.ReverseProxy{Rewrite: p.rewrite, FlushInterval: -1}
+	return p, nil
+}
+
+// Start listens on addr, e.g. "127.0.0.1:0"
+func (p *APIProxy) Start(addr string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running {
+		return nil
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	p.listener = l
+	p.server = &http.Server{Handler: p, ReadHeaderTimeout: 30 * time.Second}
+	p.running = true
+	go p.server.Serve(l)
+	return nil
+}
+
+// Stop stops serving
+func (p *APIProxy) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.running {
+		return
+	}
+	p.running = false
+	p.server.Close()
+}
+
+// Port returns the port the proxy listens on (only valid after Start)
+func (p *APIProxy) Port() int {
+	if addr, ok := p.listener.Addr().(*net.TCPAddr); ok {
+		return addr.Port
+	}
+	return 0
+}
+
+// Names returns the names of the proxied APIs, sorted
+func (p *APIProxy) Names() []string {
+	names := make([]string, 0, len(p.routes))
+	for name := range p.routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// route returns the route of a request path and the path upstream
+func (p *APIProxy) route(requestPath string) (*apiUpstream, string) {
+	name, rest, _ := strings.Cut(strings.TrimPrefix(requestPath, "/"), "/")
+	r, ok := p.routes[name]
+	if !ok {
+		return nil, ""
+	}
+	return r, "/" + rest
+}
+
+// ServeHTTP checks a request's placeholder key and forwards it upstream
+func (p *APIProxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r, _ := p.route(req.URL.Path)
+	if r == nil {
+		http.NotFound(w, req)
+		return
+	}
+	got := req.Header.Get(r.Header)
+	if subtle.ConstantTimeCompare([]byte(got), []byte(r.headerValue(p.token))) != 1 {
+		apiProxyLogger.Warningf("%s: refused %s %s without the session's key", p.container, req.Method, req.URL.Path)
+		LogRequest(p.container, req.Method, r.Upstream+req.URL.Path, false, "api proxy: "+r.Name+", wrong key")
+		http.Error(w, "addt API proxy: the request doesn't carry this session's key", http.StatusUnauthorized)
+		return
+	}
+	apiProxyLogger.Debugf("%s: %s %s", p.container, req.Method, req.URL.Path)
+	p.proxy.ServeHTTP(w, req)
+}
+
+// rewrite points a request at its upstream and swaps the placeholder for
+// the real key
+func (p *APIProxy) rewrite(pr *httputil.ProxyRequest) {
+	r, rest := p.route(pr.In.URL.Path)
+	pr.SetURL(r.url)
+	pr.Out.URL.Path = strings.TrimSuffix(r.url.Path, "/") + rest
+	pr.Out.URL.RawPath = ""
+	pr.Out.Header.Set(r.Header, r.headerValue(r.Key))
+}
+
+// APIProxyToken returns the placeholder key for a container's session. A
+// persistent container keeps its token, which it was created with; others
+// get a new one each session.
+func APIProxyToken(container string, persistent bool) (string, error) {
+	return sessionToken(container, "api-token", APIProxyTokenPrefix, persistent)
+}
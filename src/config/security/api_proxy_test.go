@@ -0,0 +1,121 @@
+package security
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// startTestAPIProxy returns a proxy in front of a fake upstream, and the
+// headers and path of the last request the upstream got
+func startTestAPIProxy(t *testing.T, route APIRoute) (*APIProxy, *http.Request) {
+	t.Helper()
+	var last http.Request
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last = *r
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(upstream.Close)
+
+	route.Upstream = upstream.URL + route.Upstream
+	p, err := NewAPIProxy("addt-test", "addt-proxy-token", []APIRoute{route})
+	if err != nil {
+		t.Fatalf("NewAPIProxy() error = %v", err)
+	}
+	if err := p.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(p.Stop)
+	return p, &last
+}
+
+func TestAPIProxy_InjectsKey(t *testing.T) {
+	p, last := startTestAPIProxy(t, APIRoute{Name: "openai", Upstream: "/v1", Header: "Authorization", Scheme: "Bearer", Key: "sk-real"})
+
+	req, _ := http.NewRequest(http.MethodPost, proxyURL(p, "/openai/chat/completions?stream=true"), strings.NewReader("{}"))
+	req.Header.Set("Authorization", "Bearer addt-proxy-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if got := last.Header.Get("Authorization"); got != "Bearer sk-real" {
+		t.Errorf("upstream Authorization = %q, want the real key", got)
+	}
+	if last.URL.Path != "/v1/chat/completions" || last.URL.RawQuery != "stream=true" {
+		t.Errorf("upstream URL = %s, want /v1/chat/completions?stream=true", last.URL)
+	}
+}
+
+func TestAPIProxy_RefusesWithoutToken(t *testing.T) {
+	p, last := startTestAPIProxy(t, APIRoute{Name: "anthropic", Header: "x-api-key", Key: "sk-ant-real"})
+
+	for _, key := range []string{"", "sk-ant-guess"} {
+		req, _ := http.NewRequest(http.MethodPost, proxyURL(p, "/anthropic/v1/messages"), nil)
+		if key != "" {
+			req.Header.Set("x-api-key", key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("key %q: status = %d, want 401", key, resp.StatusCode)
+		}
+	}
+	if last.URL != nil {
+		t.Error("request without the session's key reached the upstream")
+	}
+
+	resp, err := http.Get(proxyURL(p, "/other/v1/messages"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown API: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestNewAPIProxy_InvalidRoutes(t *testing.T) {
+	for _, r := range []APIRoute{
+		{Name: "x", Upstream: "ftp://example.com", Header: "x-api-key"},
+		{Name: "a/b", Upstream: "https://example.com", Header: "x-api-key"},
+		{Name: "x", Upstream: "https://example.com"},
+	} {
+		if _, err := NewAPIProxy("addt-test", "t", []APIRoute{r}); err == nil {
+			t.Errorf("NewAPIProxy(%+v) should fail", r)
+		}
+	}
+}
+
+func TestAPIProxyToken(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+
+	a, _ := APIProxyToken("addt-test", false)
+	b, _ := APIProxyToken("addt-test", false)
+	if a == b || !strings.HasPrefix(a, APIProxyTokenPrefix) {
+		t.Errorf("ephemeral tokens = %q, %q, want different addt-proxy- tokens", a, b)
+	}
+
+	p1, _ := APIProxyToken("addt-persistent", true)
+	p2, _ := APIProxyToken("addt-persistent", true)
+	if p1 != p2 {
+		t.Errorf("persistent tokens = %q, %q, want the same", p1, p2)
+	}
+	RemoveSessionTokens("addt-persistent")
+	if p3, _ := APIProxyToken("addt-persistent", true); p3 == p1 {
+		t.Error("token kept after RemoveSessionTokens()")
+	}
+}
+
+func proxyURL(p *APIProxy, path string) string {
+	return fmt.Sprintf("http://127.0.0.1:%d%s", p.Port(), path)
+}
@@ -1,11 +1,18 @@
 package security
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/jedi4ever/addt/util"
 )
 
 // AuditEventType represents the type of security audit event
@@ -16,22 +23,79 @@ const (
 	AuditSSHSignDenied   AuditEventType = "ssh_sign_denied"
 	AuditSSHKeyListed    AuditEventType = "ssh_key_listed"
 	AuditSSHKeyFiltered  AuditEventType = "ssh_key_filtered"
+	AuditSSHBindAllowed  AuditEventType = "ssh_bind_allowed"
+	AuditSSHBindDenied   AuditEventType = "ssh_bind_denied"
 	AuditGPGSignAllowed  AuditEventType = "gpg_sign_allowed"
 	AuditGPGSignDenied   AuditEventType = "gpg_sign_denied"
 	AuditGPGDecryptAllow AuditEventType = "gpg_decrypt_allowed"
 	AuditGPGDecryptDeny  AuditEventType = "gpg_decrypt_denied"
+	AuditNetworkAllowed  AuditEventType = "network_allowed"
+	AuditNetworkDenied   AuditEventType = "network_denied"
+	AuditDNSAllowed      AuditEventType = "dns_allowed"
+	AuditDNSDenied       AuditEventType = "dns_denied"
+	AuditRequestAllowed  AuditEventType = "request_allowed"
+	AuditRequestDenied   AuditEventType = "request_denied"
+	AuditSecretsInjected AuditEventType = "secrets_injected"
+	AuditMountAllowed    AuditEventType = "mount_allowed"
+	AuditMountDenied     AuditEventType = "mount_denied"
+	AuditYoloEnabled     AuditEventType = "yolo_enabled"
+	AuditContainerStart  AuditEventType = "container_start"
+	AuditContainerStop   AuditEventType = "container_stop"
+	AuditHostServiceOpen AuditEventType = "host_service_exposed"
+	AuditHostServiceConn AuditEventType = "host_service_connect"
+	AuditGitCredIssued   AuditEventType = "git_credential_issued"
+	AuditGitCredDenied   AuditEventType = "git_credential_denied"
 )
 
+// AuditEventTypes returns every event type, in the order they're defined
+func AuditEventTypes() []AuditEventType {
+	return []AuditEventType{
+		AuditSSHSignAllowed, AuditSSHSignDenied, AuditSSHKeyListed, AuditSSHKeyFiltered,
+		AuditSSHBindAllowed, AuditSSHBindDenied,
+		AuditGPGSignAllowed, AuditGPGSignDenied, AuditGPGDecryptAllow, AuditGPGDecryptDeny,
+		AuditNetworkAllowed, AuditNetworkDenied, AuditDNSAllowed, AuditDNSDenied,
+		AuditRequestAllowed, AuditRequestDenied, AuditSecretsInjected,
+		AuditMountAllowed, AuditMountDenied, AuditYoloEnabled,
+		AuditContainerStart, AuditContainerStop,
+		AuditHostServiceOpen, AuditHostServiceConn,
+		AuditGitCredIssued, AuditGitCredDenied,
+	}
+}
+
 // AuditEvent represents a security audit event
 type AuditEvent struct {
 	Timestamp time.Time      `json:"timestamp"`
 	Type      AuditEventType `json:"type"`
 	KeyID     string         `json:"key_id,omitempty"`
 	Comment   string         `json:"comment,omitempty"`
+	Container string         `json:"container,omitempty"`
+	Host      string         `json:"host,omitempty"`
+	Extension string         `json:"extension,omitempty"`
+	Source    string         `json:"source,omitempty"`  // host path of a mount
+	Target    string         `json:"target,omitempty"`  // container path of a mount
+	Secrets   []string       `json:"secrets,omitempty"` // names only, never values
 	Allowed   bool           `json:"allowed"`
 	Reason    string         `json:"reason,omitempty"`
 }
 
+// Subject returns what an event is about: a host, key, mount or secret
+// names, whichever it has
+func (e AuditEvent) Subject() string {
+	switch {
+	case e.Host != "":
+		return e.Host
+	case e.Source != "" || e.Target != "":
+		return e.Source + ":" + e.Target
+	case len(e.Secrets) > 0:
+		return strings.Join(e.Secrets, ",")
+	case e.KeyID != "":
+		return e.KeyID
+	case e.Comment != "":
+		return e.Comment
+	}
+	return e.Extension
+}
+
 // AuditLogger handles security audit logging
 type AuditLogger struct {
 	mu      sync.Mutex
@@ -72,6 +136,35 @@ func EnableAuditLog(path string) error {
 	return nil
 }
 
+// AuditLogPath returns the audit log file of a config, by default
+// <addt_home>/audit.log
+func AuditLogPath(cfg *Config) (string, error) {
+	if cfg.AuditLogFile != "" {
+		return util.ExpandTilde(cfg.AuditLogFile), nil
+	}
+	addtHome := util.GetAddtHome()
+	if addtHome == "" {
+		return "", fmt.Errorf("failed to determine addt home directory")
+	}
+	return filepath.Join(addtHome, "audit.log"), nil
+}
+
+// ReadAuditEvents reads the events of an audit log, skipping lines that
+// aren't events
+func ReadAuditEvents(r io.Reader) ([]AuditEvent, error) {
+	var events []AuditEvent
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || event.Type == "" {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
 // DisableAuditLog disables audit logging
 func DisableAuditLog() {
 	logger := GetAuditLogger()
@@ -133,6 +226,22 @@ func LogSSHKeyAccess(keyComment string, allowed bool) {
 	})
 }
 
+// LogSSHBind logs the SSH proxy accepting, or refusing, ssh binding an
+// agent connection to a destination host under ssh.allowed_hosts
+func LogSSHBind(host string, allowed bool, reason string) {
+	eventType := AuditSSHBindAllowed
+	if !allowed {
+		eventType = AuditSSHBindDenied
+	}
+
+	GetAuditLogger().LogEvent(AuditEvent{
+		Type:    eventType,
+		Host:    host,
+		Allowed: allowed,
+		Reason:  reason,
+	})
+}
+
 // LogGPGSign logs a GPG signing operation
 func LogGPGSign(keyID string, allowed bool, reason string) {
 	eventType := AuditGPGSignAllowed
@@ -162,3 +271,190 @@ func LogGPGDecrypt(keyID string, allowed bool, reason string) {
 		Reason:  reason,
 	})
 }
+
+// LogNetwork logs an egress proxy decision about a host:port
+func LogNetwork(container, host string, allowed bool, reason string) {
+	eventType := AuditNetworkAllowed
+	if !allowed {
+		eventType = AuditNetworkDenied
+	}
+
+	GetAuditLogger().LogEvent(AuditEvent{
+		Type:      eventType,
+		Container: container,
+		Host:      host,
+		Allowed:   allowed,
+		Reason:    reason,
+	})
+}
+
+// LogDNS logs a DNS resolver decision about a query
+func LogDNS(container, name, qtype string, allowed bool, reason string) {
+	eventType := AuditDNSAllowed
+	if !allowed {
+		eventType = AuditDNSDenied
+	}
+
+	GetAuditLogger().LogEvent(AuditEvent{
+		Type:      eventType,
+		Container: container,
+		Host:      name,
+		Allowed:   allowed,
+		Reason:    qtype + ", " + reason,
+	})
+}
+
+// LogRequest logs an intercepting proxy decision about an HTTP request
+func LogRequest(container, method, url string, allowed bool, reason string) {
+	eventType := AuditRequestAllowed
+	if !allowed {
+		eventType = AuditRequestDenied
+	}
+
+	GetAuditLogger().LogEvent(AuditEvent{
+		Type:      eventType,
+		Container: container,
+		Host:      method + " " + url,
+		Allowed:   allowed,
+		Reason:    reason,
+	})
+}
+
+// LogSecretsInjected logs the names of the secrets passed to a container;
+// via is how: "isolated" (a tmpfs file) or "env"
+func LogSecretsInjected(container string, names []string, via string) {
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+	GetAuditLogger().LogEvent(AuditEvent{
+		Type:      AuditSecretsInjected,
+		Container: container,
+		Secrets:   names,
+		Allowed:   true,
+		Reason:    via,
+	})
+}
+
+// LogMount logs a decision to mount, or not mount, a host path
+func LogMount(container, source, target string, allowed bool, reason string) {
+	eventType := AuditMountAllowed
+	if !allowed {
+		eventType = AuditMountDenied
+	}
+
+	GetAuditLogger().LogEvent(AuditEvent{
+		Type:      eventType,
+		Container: container,
+		Source:    source,
+		Target:    target,
+		Allowed:   allowed,
+		Reason:    reason,
+	})
+}
+
+// LogYolo logs a container running with permission checks bypassed; reason
+// says which setting turned it on
+func LogYolo(container, extension, reason string) {
+	GetAuditLogger().LogEvent(AuditEvent{
+		Type:      AuditYoloEnabled,
+		Container: container,
+		Extension: extension,
+		Allowed:   true,
+		Reason:    reason,
+	})
+}
+
+// LogContainerStart logs the start of a container session
+func LogContainerStart(container, extension, reason string) {
+	GetAuditLogger().LogEvent(AuditEvent{
+		Type:      AuditContainerStart,
+		Container: container,
+		Extension: extension,
+		Allowed:   true,
+		Reason:    reason,
+	})
+}
+
+// LogContainerStop logs the end of a container session
+func LogContainerStop(container, extension string, duration time.Duration, err error) {
+	reason := "exited after " + duration.Round(time.Second).String()
+	if err != nil {
+		reason = fmt.Sprintf("failed after %s: %v", duration.Round(time.Second), err)
+	}
+	GetAuditLogger().LogEvent(AuditEvent{
+		Type:      AuditContainerStop,
+		Container: container,
+		Extension: extension,
+		Allowed:   err == nil,
+		Reason:    reason,
+	})
+}
+
+// LogHostServiceExposed logs a host service made reachable in a container
+// under host, e.g. postgres.host.addt; target is where it listens
+func LogHostServiceExposed(container, host, target string) {
+	GetAuditLogger().LogEvent(AuditEvent{
+		Type:      AuditHostServiceOpen,
+		Container: container,
+		Host:      host,
+		Target:    target,
+		Allowed:   true,
+		Reason:    "ports.host_services",
+	})
+}
+
+// LogHostServiceConnect logs a container's connection to a host service;
+// err is why it couldn't be forwarded
+func LogHostServiceConnect(container, host, target string, err error) {
+	reason := "forwarded to " + target
+	if err != nil {
+		reason = fmt.Sprintf("unreachable: %v", err)
+	}
+	GetAuditLogger().LogEvent(AuditEvent{
+		Type:      AuditHostServiceConn,
+		Container: container,
+		Host:      host,
+		Target:    target,
+		Allowed:   err == nil,
+		Reason:    reason,
+	})
+}
+
+// LogGitCredential logs the host-side git credential helper issuing, or
+// refusing, credentials for a repo, e.g. github.com/owner/repo
+func LogGitCredential(container, repo string, issued bool, reason string) {
+	eventType := AuditGitCredIssued
+	if !issued {
+		eventType = AuditGitCredDenied
+	}
+
+	GetAuditLogger().LogEvent(AuditEvent{
+		Type:      eventType,
+		Container: container,
+		Host:      repo,
+		Allowed:   issued,
+		Reason:    reason,
+	})
+}
+
+// LogSignDecision logs a decision on a signature under ssh.sign_policy or
+// gpg.sign_policy confirm, with the container and SSH destination
+func LogSignDecision(req SignRequest, allowed bool, reason string) {
+	event := AuditEvent{
+		Container: req.Container,
+		Host:      req.Host,
+		Allowed:   allowed,
+		Reason:    reason,
+	}
+	if req.Kind == "gpg" {
+		event.Type, event.KeyID = AuditGPGSignAllowed, req.Key
+		if !allowed {
+			event.Type = AuditGPGSignDenied
+		}
+	} else {
+		event.Type, event.Comment = AuditSSHSignAllowed, req.Key
+		if !allowed {
+			event.Type = AuditSSHSignDenied
+		}
+	}
+	GetAuditLogger().LogEvent(event)
+}
@@ -0,0 +1,60 @@
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAuditLog_WriteAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := EnableAuditLog(path); err != nil {
+		t.Fatalf("EnableAuditLog() error = %v", err)
+	}
+	LogNetwork("addt-test", "evil.example:443", false, "not allowed")
+	LogSecretsInjected("addt-test", []string{"OPENAI_API_KEY", "ANTHROPIC_API_KEY"}, "isolated")
+	LogMount("addt-test", "/home/me/project", "/workspace", true, "read-write")
+	LogContainerStop("addt-test", "claude", 90*time.Second, nil)
+	DisableAuditLog()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	events, err := ReadAuditEvents(file)
+	if err != nil {
+		t.Fatalf("ReadAuditEvents() error = %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want 4: %+v", len(events), events)
+	}
+
+	subjects := []string{events[0].Subject(), events[1].Subject(), events[2].Subject()}
+	want := []string{"evil.example:443", "ANTHROPIC_API_KEY,OPENAI_API_KEY", "/home/me/project:/workspace"}
+	if !reflect.DeepEqual(subjects, want) {
+		t.Errorf("subjects = %v, want %v", subjects, want)
+	}
+	if events[0].Type != AuditNetworkDenied || events[0].Allowed {
+		t.Errorf("network event = %+v", events[0])
+	}
+	if stop := events[3]; stop.Type != AuditContainerStop || stop.Reason != "exited after 1m30s" || stop.Extension != "claude" {
+		t.Errorf("stop event = %+v", stop)
+	}
+}
+
+func TestReadAuditEvents_SkipsOtherLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	data := "not json\n{\"timestamp\":\"2026-01-02T03:04:05Z\",\"type\":\"dns_allowed\",\"host\":\"a.example\",\"allowed\":true}\n{}\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	file, _ := os.Open(path)
+	defer file.Close()
+	events, err := ReadAuditEvents(file)
+	if err != nil || len(events) != 1 || events[0].Host != "a.example" {
+		t.Errorf("ReadAuditEvents() = %+v, %v", events, err)
+	}
+}
@@ -0,0 +1,193 @@
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jedi4ever/addt/util"
+)
+
+const (
+	caCertFile     = "addt-ca.pem"
+	caKeyFile      = "addt-ca-key.pem"
+	caValidity     = 10 * 365 * 24 * time.Hour
+	leafValidity   = 7 * 24 * time.Hour
+	leafBackdating = time.Hour
+)
+
+// CertificateAuthority signs the certificates the intercepting proxy
+// presents to containers. It is created once per host and kept in
+// ~/.addt/ca; only its certificate ever enters a container.
+type CertificateAuthority struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+
+	mu      sync.Mutex
+	leafKey *ecdsa.PrivateKey
+	leaves  map[string]*tls.Certificate
+}
+
+// CADir returns where the addt CA is kept
+func CADir() string {
+	return filepath.Join(util.GetAddtHome(), "ca")
+}
+
+// LoadOrCreateCA loads the CA in dir, creating it on first use
+func LoadOrCreateCA(dir string) (*CertificateAuthority, error) {
+	certFile := filepath.Join(dir, caCertFile)
+	keyFile := filepath.Join(dir, caKeyFile)
+
+	certPEM, certErr := os.ReadFile(certFile)
+	keyPEM, keyErr := os.ReadFile(keyFile)
+	if errors.Is(certErr, os.ErrNotExist) && errors.Is(keyErr, os.ErrNotExist) {
+		var err error
+		if certPEM, keyPEM, err = createCA(); err != nil {
+			return nil, fmt.Errorf("failed to create CA: %w", err)
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(certFile, certPEM, 0644); err != nil {
+			return nil, err
+		}
+	} else if certErr != nil {
+		return nil, certErr
+	} else if keyErr != nil {
+		return nil, keyErr
+	}
+
+	ca, err := parseCA(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CA from %s: %w", dir, err)
+	}
+	ca.certFile = certFile
+	return ca, nil
+}
+
+// CertFile returns the path of the CA certificate, for containers to trust
+func (ca *CertificateAuthority) CertFile() string {
+	return ca.certFile
+}
+
+// Certificate returns a certificate for host signed by the CA
+func (ca *CertificateAuthority) Certificate(host string) (*tls.Certificate, error) {
+	host = normalizeHost(host)
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+
+	if leaf, ok := ca.leaves[host]; ok && time.Now().Add(leafBackdating).Before(leaf.Leaf.NotAfter) {
+		return leaf, nil
+	}
+	if ca.leafKey == nil {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		ca.leafKey = key
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    now.Add(-leafBackdating),
+		NotAfter:     now.Add(leafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &ca.leafKey.PublicKey, ca.key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	cert := &tls.Certificate{Certificate: [][]byte{der, ca.cert.Raw}, PrivateKey: ca.leafKey, Leaf: leaf}
+	if ca.leaves == nil {
+		ca.leaves = make(map[string]*tls.Certificate)
+	}
+	ca.leaves[host] = cert
+	return cert, nil
+}
+
+// createCA generates a CA certificate and key, PEM encoded
+func createCA() ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	host, _ := os.Hostname()
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "addt egress CA (" + host + ")", Organization: []string{"addt"}},
+		NotBefore:             now.Add(-leafBackdating),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+// parseCA parses a PEM encoded CA certificate and key
+func parseCA(certPEM, keyPEM []byte) (*CertificateAuthority, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, errors.New("no PEM data")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if !cert.IsCA {
+		return nil, errors.New("certificate is not a CA")
+	}
+	return &CertificateAuthority{cert: cert, key: key}, nil
+}
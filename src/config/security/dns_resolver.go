@@ -0,0 +1,473 @@
+package security
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jedi4ever/addt/util"
+)
+
+var dnsLogger = util.Log("dns")
+
+const (
+	dnsUpstreamTimeout = 5 * time.Second
+	dnsDefaultUpstream = "1.1.1.1:53"
+)
+
+// DNS message constants used by the resolver
+const (
+	dnsHeaderLen   = 12
+	dnsTypeA       = 1
+	dnsTypeAAAA    = 28
+	dnsRcodeNXName = 3
+)
+
+// DNSResolver is a host-side DNS forwarder a container's queries are
+// redirected to. It answers only for names the firewall rules allow and
+// returns NXDOMAIN for the rest, so queries can't carry data to a
+// nameserver of the agent's choosing. Every query is logged.
+type DNSResolver struct {
+	rules      FirewallRules
+	rulesMu    sync.RWMutex
+	permissive bool   // log what would be denied, but answer it
+	container  string // container the resolver serves, for the logs
+	upstream   string // nameserver allowed queries are forwarded to
+	udp        *net.UDPConn
+	tcp        net.Listener
+	port       int
+	mu         sync.Mutex
+	running    bool
+	denied     map[string]int // denied name → queries
+
+	// OnResolve, when set, is called with the IPv4 addresses a name the
+	// rules allow resolved to and the answer's lowest TTL, before the
+	// answer is returned to the container
+	OnResolve func(name string, ips []net.IP, ttl time.Duration)
+
+	// Learner, when set, records every lookup for firewall learn
+	Learner *FirewallLearner
+}
+
+// NewDNSResolver creates a DNS resolver for a container that forwards
+// allowed queries to the host's nameserver. Mode is the firewall mode:
+// permissive answers everything but logs what strict would deny.
+func NewDNSResolver(container string, rules FirewallRules, mode string) *DNSResolver {
+	return &DNSResolver{
+		rules:      rules,
+		permissive: mode == "permissive",
+		container:  container,
+		upstream:   systemNameserver("/etc/resolv.conf"),
+		denied:     make(map[string]int),
+	}
+}
+
+// Start listens for UDP and TCP queries on addr, e.g. "127.0.0.1:0". With
+// port 0 both protocols get the same, free port.
+func (r *DNSResolver) Start(addr string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for attempt := 0; attempt < 3; attempt++ {
+		udp, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
+		udpPort := udp.LocalAddr().(*net.UDPAddr).Port
+		tcp, err := net.Listen("tcp", net.JoinHostPort(host, fmt.Sprint(udpPort)))
+		if err != nil {
+			udp.Close()
+			lastErr = fmt.Errorf("failed to listen on tcp port %d: %w", udpPort, err)
+			if port != "0" {
+				break
+			}
+			continue
+		}
+		r.udp = udp.(*net.UDPConn)
+		r.tcp = tcp
+		r.port = udpPort
+		r.running = true
+		go r.serveUDP()
+		go r.serveTCP()
+		return nil
+	}
+	return lastErr
+}
+
+// Stop stops the resolver
+func (r *DNSResolver) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.running {
+		return nil
+	}
+	r.running = false
+	r.tcp.Close()
+	return r.udp.Close()
+}
+
+// SetRules replaces the rules the resolver checks, for queries answered
+// from now on
+func (r *DNSResolver) SetRules(rules FirewallRules) {
+	r.rulesMu.Lock()
+	r.rules = rules
+	r.rulesMu.Unlock()
+}
+
+func (r *DNSResolver) currentRules() FirewallRules {
+	r.rulesMu.RLock()
+	defer r.rulesMu.RUnlock()
+	return r.rules
+}
+
+// Port returns the port the resolver listens on (only valid after Start)
+func (r *DNSResolver) Port() int {
+	return r.port
+}
+
+// Denied returns the names lookups were refused for, with the number of
+// queries
+func (r *DNSResolver) Denied() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	denied := make(map[string]int, len(r.denied))
+	for name, n := range r.denied {
+		denied[name] = n
+	}
+	return denied
+}
+
+func (r *DNSResolver) isRunning() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running
+}
+
+func (r *DNSResolver) serveUDP() {
+	buf := make([]byte, 65535)
+	for {
+		n, client, err := r.udp.ReadFromUDP(buf)
+		if err != nil {
+			if !r.isRunning() {
+				return
+			}
+			continue
+		}
+		query := append([]byte(nil), buf[:n]...)
+		go func() {
+			if resp := r.resolve(query, "udp"); resp != nil {
+				r.udp.WriteToUDP(resp, client)
+			}
+		}()
+	}
+}
+
+func (r *DNSResolver) serveTCP() {
+	for {
+		conn, err := r.tcp.Accept()
+		if err != nil {
+			if !r.isRunning() {
+				return
+			}
+			continue
+		}
+		go r.handleTCP(conn)
+	}
+}
+
+// handleTCP answers length-prefixed queries until the client is done
+func (r *DNSResolver) handleTCP(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+		query, err := readTCPMessage(reader)
+		if err != nil {
+			return
+		}
+		resp := r.resolve(query, "tcp")
+		if resp == nil {
+			return
+		}
+		if err := writeTCPMessage(conn, resp); err != nil {
+			return
+		}
+	}
+}
+
+// resolve answers a query: NXDOMAIN for names the rules deny, otherwise
+// the upstream's answer. Malformed queries get no answer.
+func (r *DNSResolver) resolve(query []byte, network string) []byte {
+	q, ok := parseDNSQuestion(query)
+	if !ok {
+		return nil
+	}
+	rules := r.currentRules()
+	allowed, layer := rules.Check(q.name)
+	if r.Learner != nil {
+		r.Learner.Observe(q.name, "", "dns")
+	}
+	if !r.allow(q, allowed, "rule: "+layer) {
+		return dnsNXDomain(query, q)
+	}
+
+	resp, err := r.forward(query, network)
+	if err != nil {
+		dnsLogger.Debugf("%s: forwarding %s failed: %v", r.container, q.name, err)
+		return nil
+	}
+	ips, ttl := parseDNSAnswers(resp)
+	if r.Learner != nil {
+		for _, ip := range ips {
+			r.Learner.ObserveIP(q.name, ip.String())
+		}
+	}
+	if allowed && r.OnResolve != nil && len(ips) > 0 {
+		r.OnResolve(q.name, ips, ttl)
+	}
+	return resp
+}
+
+// allow logs a query decision and reports whether to answer it
+func (r *DNSResolver) allow(q dnsQuestion, allowed bool, reason string) bool {
+	if !allowed {
+		r.mu.Lock()
+		r.denied[q.name]++
+		r.mu.Unlock()
+	}
+	if !allowed && r.permissive {
+		dnsLogger.Infof("%s: would refuse %s %s (%s)", r.container, dnsTypeName(q.qtype), q.name, reason)
+		LogDNS(r.container, q.name, dnsTypeName(q.qtype), true, "permissive, would deny: "+reason)
+		return true
+	}
+	if allowed {
+		dnsLogger.Infof("%s: resolve %s %s (%s)", r.container, dnsTypeName(q.qtype), q.name, reason)
+	} else {
+		dnsLogger.Warningf("%s: refuse %s %s (%s)", r.container, dnsTypeName(q.qtype), q.name, reason)
+	}
+	LogDNS(r.container, q.name, dnsTypeName(q.qtype), allowed, reason)
+	return allowed
+}
+
+// forward sends the query to the upstream nameserver over network
+func (r *DNSResolver) forward(query []byte, network string) ([]byte, error) {
+	conn, err := net.DialTimeout(network, r.upstream, dnsUpstreamTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dnsUpstreamTimeout))
+
+	if network == "tcp" {
+		if err := writeTCPMessage(conn, query); err != nil {
+			return nil, err
+		}
+		return readTCPMessage(bufio.NewReader(conn))
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// systemNameserver returns the first nameserver in a resolv.conf, or a
+// public resolver when there is none
+func systemNameserver(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return dnsDefaultUpstream
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			if ip := net.ParseIP(strings.Split(fields[1], "%")[0]); ip != nil {
+				return net.JoinHostPort(fields[1], "53")
+			}
+		}
+	}
+	return dnsDefaultUpstream
+}
+
+func readTCPMessage(r io.Reader) ([]byte, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func writeTCPMessage(w io.Writer, msg []byte) error {
+	buf := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(buf, uint16(len(msg)))
+	copy(buf[2:], msg)
+	_, err := w.Write(buf)
+	return err
+}
+
+// dnsQuestion is the question of a query
+type dnsQuestion struct {
+	name  string // lowercase, without the trailing dot
+	qtype uint16
+	end   int // offset of the end of the question section
+}
+
+// parseDNSQuestion parses a query with exactly one question
+func parseDNSQuestion(msg []byte) (dnsQuestion, bool) {
+	if len(msg) < dnsHeaderLen || msg[2]&0x80 != 0 || binary.BigEndian.Uint16(msg[4:6]) != 1 {
+		return dnsQuestion{}, false
+	}
+	name, off, ok := readDNSName(msg, dnsHeaderLen)
+	if !ok || off+4 > len(msg) {
+		return dnsQuestion{}, false
+	}
+	return dnsQuestion{
+		name:  name,
+		qtype: binary.BigEndian.Uint16(msg[off : off+2]),
+		end:   off + 4,
+	}, true
+}
+
+// parseDNSAnswers returns the A and AAAA records of a response and their
+// lowest TTL
+func parseDNSAnswers(msg []byte) ([]net.IP, time.Duration) {
+	if len(msg) < dnsHeaderLen {
+		return nil, 0
+	}
+	qdcount := int(binary.BigEndian.Uint16(msg[4:6]))
+	ancount := int(binary.BigEndian.Uint16(msg[6:8]))
+	off := dnsHeaderLen
+	for i := 0; i < qdcount; i++ {
+		var ok bool
+		if _, off, ok = readDNSName(msg, off); !ok || off+4 > len(msg) {
+			return nil, 0
+		}
+		off += 4
+	}
+
+	var ips []net.IP
+	var ttl uint32
+	for i := 0; i < ancount; i++ {
+		var ok bool
+		if _, off, ok = readDNSName(msg, off); !ok || off+10 > len(msg) {
+			break
+		}
+		rtype := binary.BigEndian.Uint16(msg[off : off+2])
+		rttl := binary.BigEndian.Uint32(msg[off+4 : off+8])
+		rdlen := int(binary.BigEndian.Uint16(msg[off+8 : off+10]))
+		off += 10
+		if off+rdlen > len(msg) {
+			break
+		}
+		var ip net.IP
+		switch {
+		case rtype == dnsTypeA && rdlen == net.IPv4len:
+			ip = net.IPv4(msg[off], msg[off+1], msg[off+2], msg[off+3])
+		case rtype == dnsTypeAAAA && rdlen == net.IPv6len:
+			ip = append(net.IP(nil), msg[off:off+rdlen]...)
+		}
+		if ip != nil {
+			ips = append(ips, ip)
+			if ttl == 0 || rttl < ttl {
+				ttl = rttl
+			}
+		}
+		off += rdlen
+	}
+	return ips, time.Duration(ttl) * time.Second
+}
+
+// readDNSName reads a possibly compressed name at off and returns it with
+// the offset just past it
+func readDNSName(msg []byte, off int) (string, int, bool) {
+	var labels []string
+	end := -1
+	for jumps := 0; jumps < 16; {
+		if off >= len(msg) {
+			return "", 0, false
+		}
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.ToLower(strings.Join(labels, ".")), end, true
+		case length&0xC0 == 0xC0:
+			if off+1 >= len(msg) {
+				return "", 0, false
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:off+2]) & 0x3FFF)
+			jumps++
+		default:
+			if off+1+length > len(msg) {
+				return "", 0, false
+			}
+			labels = append(labels, string(msg[off+1:off+1+length]))
+			off += 1 + length
+		}
+	}
+	return "", 0, false
+}
+
+// dnsNXDomain builds an NXDOMAIN response to a query
+func dnsNXDomain(query []byte, q dnsQuestion) []byte {
+	resp := make([]byte, q.end)
+	copy(resp, query[:q.end])
+	resp[2] = 0x80 | query[2]&0x79  // QR, keep opcode and RD
+	resp[3] = 0x80 | dnsRcodeNXName // RA
+	binary.BigEndian.PutUint16(resp[6:8], 0)
+	binary.BigEndian.PutUint16(resp[8:10], 0)
+	binary.BigEndian.PutUint16(resp[10:12], 0)
+	return resp
+}
+
+// dnsTypeName names common query types for the logs
+func dnsTypeName(qtype uint16) string {
+	switch qtype {
+	case 1:
+		return "A"
+	case 5:
+		return "CNAME"
+	case 12:
+		return "PTR"
+	case 15:
+		return "MX"
+	case 16:
+		return "TXT"
+	case 28:
+		return "AAAA"
+	case 33:
+		return "SRV"
+	case 65:
+		return "HTTPS"
+	}
+	return fmt.Sprintf("TYPE%d", qtype)
+}
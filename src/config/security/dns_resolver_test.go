@@ -0,0 +1,200 @@
+package security
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// buildDNSQuery builds a query for name with the given type
+func buildDNSQuery(id uint16, name string, qtype uint16) []byte {
+	msg := []byte{byte(id >> 8), byte(id), 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	for _, label := range splitLabels(name) {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0, byte(qtype>>8), byte(qtype), 0, 1)
+	return msg
+}
+
+func splitLabels(name string) []string {
+	var labels []string
+	start := 0
+	for i := 0; i <= len(name); i++ {
+		if i == len(name) || name[i] == '.' {
+			labels = append(labels, name[start:i])
+			start = i + 1
+		}
+	}
+	return labels
+}
+
+// buildDNSAnswer answers query with A records, using a compression pointer
+// to the question name
+func buildDNSAnswer(query []byte, ttl uint32, ips ...string) []byte {
+	resp := append([]byte(nil), query...)
+	resp[2] |= 0x80
+	resp[3] = 0x80
+	binary.BigEndian.PutUint16(resp[6:8], uint16(len(ips)))
+	for _, ip := range ips {
+		addr, rtype := net.ParseIP(ip).To4(), byte(dnsTypeA)
+		if addr == nil {
+			addr, rtype = net.ParseIP(ip), dnsTypeAAAA
+		}
+		rr := []byte{0xC0, dnsHeaderLen, 0, rtype, 0, 1, 0, 0, 0, 0, 0, byte(len(addr))}
+		binary.BigEndian.PutUint32(rr[6:10], ttl)
+		resp = append(resp, rr...)
+		resp = append(resp, addr...)
+	}
+	return resp
+}
+
+func TestParseDNSQuestion(t *testing.T) {
+	q, ok := parseDNSQuestion(buildDNSQuery(7, "API.Anthropic.com", 28))
+	if !ok || q.name != "api.anthropic.com" || q.qtype != 28 {
+		t.Errorf("parseDNSQuestion() = %+v, %v", q, ok)
+	}
+	if _, ok := parseDNSQuestion([]byte{0, 1, 2}); ok {
+		t.Error("parseDNSQuestion(short) should fail")
+	}
+	resp := buildDNSAnswer(buildDNSQuery(7, "github.com", 1), 60, "140.82.112.3")
+	if _, ok := parseDNSQuestion(resp); ok {
+		t.Error("parseDNSQuestion(response) should fail")
+	}
+}
+
+func TestParseDNSAnswers(t *testing.T) {
+	resp := buildDNSAnswer(buildDNSQuery(7, "github.com", 1), 60, "140.82.112.3", "140.82.112.4")
+	ips, ttl := parseDNSAnswers(resp)
+	want := []net.IP{net.ParseIP("140.82.112.3"), net.ParseIP("140.82.112.4")}
+	if len(ips) != 2 || !ips[0].Equal(want[0]) || !ips[1].Equal(want[1]) {
+		t.Errorf("parseDNSAnswers() ips = %v, want %v", ips, want)
+	}
+	if ttl != time.Minute {
+		t.Errorf("parseDNSAnswers() ttl = %v, want 1m", ttl)
+	}
+
+	resp = buildDNSAnswer(buildDNSQuery(8, "github.com", dnsTypeAAAA), 30, "2606:50c0:8000::154")
+	if ips, ttl := parseDNSAnswers(resp); len(ips) != 1 || !ips[0].Equal(net.ParseIP("2606:50c0:8000::154")) || ttl != 30*time.Second {
+		t.Errorf("parseDNSAnswers(AAAA) = %v, %v, want 2606:50c0:8000::154, 30s", ips, ttl)
+	}
+}
+
+func TestDNSNXDomain(t *testing.T) {
+	query := buildDNSQuery(0xBEEF, "evil.example", 16)
+	q, _ := parseDNSQuestion(query)
+	resp := dnsNXDomain(query, q)
+	if resp[0] != 0xBE || resp[1] != 0xEF {
+		t.Errorf("response id = %x%x, want beef", resp[0], resp[1])
+	}
+	if resp[2]&0x80 == 0 || resp[2]&0x01 == 0 || resp[3]&0x0F != dnsRcodeNXName {
+		t.Errorf("response flags = %08b %08b, want QR, RD and NXDOMAIN", resp[2], resp[3])
+	}
+	if rq, _, ok := readDNSName(resp, dnsHeaderLen); !ok || rq != "evil.example" {
+		t.Errorf("response question = %q, %v", rq, ok)
+	}
+}
+
+func TestSystemNameserver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	os.WriteFile(path, []byte("# generated\nsearch lan\nnameserver 192.168.1.1\nnameserver 8.8.8.8\n"), 0644)
+	if got := systemNameserver(path); got != "192.168.1.1:53" {
+		t.Errorf("systemNameserver() = %q, want 192.168.1.1:53", got)
+	}
+	if got := systemNameserver(filepath.Join(t.TempDir(), "missing")); got != dnsDefaultUpstream {
+		t.Errorf("systemNameserver(missing) = %q, want %q", got, dnsDefaultUpstream)
+	}
+}
+
+// startFakeNameserver answers every UDP query with one A record
+func startFakeNameserver(t *testing.T, ip string) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			conn.WriteTo(buildDNSAnswer(buf[:n], 30, ip), addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDNSResolver_AnswersOnlyAllowedNames(t *testing.T) {
+	r := NewDNSResolver("addt-test", FirewallRules{Defaults: []string{"github.com"}}, "strict")
+	r.upstream = startFakeNameserver(t, "140.82.112.3")
+	resolved := make(chan []net.IP, 1)
+	r.OnResolve = func(name string, ips []net.IP, ttl time.Duration) {
+		resolved <- ips
+	}
+	if err := r.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer r.Stop()
+
+	conn, err := net.Dial("udp", net.JoinHostPort("127.0.0.1", strconv.Itoa(r.Port())))
+	if err != nil {
+		t.Fatalf("dial resolver: %v", err)
+	}
+	defer conn.Close()
+	exchange := func(name string) []byte {
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		conn.Write(buildDNSQuery(1, name, 1))
+		buf := make([]byte, 512)
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("query %s: %v", name, err)
+		}
+		return buf[:n]
+	}
+
+	if resp := exchange("github.com"); resp[3]&0x0F != 0 {
+		t.Errorf("github.com rcode = %d, want 0", resp[3]&0x0F)
+	}
+	if ips := <-resolved; len(ips) != 1 || !ips[0].Equal(net.ParseIP("140.82.112.3")) {
+		t.Errorf("OnResolve got %v, want 140.82.112.3", ips)
+	}
+	if resp := exchange("c2.evil.example"); resp[3]&0x0F != dnsRcodeNXName {
+		t.Errorf("c2.evil.example rcode = %d, want NXDOMAIN", resp[3]&0x0F)
+	}
+	if denied := r.Denied(); !reflect.DeepEqual(denied, map[string]int{"c2.evil.example": 1}) {
+		t.Errorf("Denied() = %v", denied)
+	}
+}
+
+func TestDNSResolver_TCP(t *testing.T) {
+	r := NewDNSResolver("addt-test", FirewallRules{}, "strict")
+	if err := r.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer r.Stop()
+
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(r.Port())))
+	if err != nil {
+		t.Fatalf("dial resolver: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := writeTCPMessage(conn, buildDNSQuery(2, "evil.example", 16)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	resp, err := readTCPMessage(conn)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if resp[3]&0x0F != dnsRcodeNXName {
+		t.Errorf("rcode = %d, want NXDOMAIN", resp[3]&0x0F)
+	}
+}
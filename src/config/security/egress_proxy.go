@@ -0,0 +1,816 @@
+package security
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jedi4ever/addt/util"
+)
+
+var egressLogger = util.Log("egress")
+
+// egressProxyUser is the user name in the proxy URL; the password is the
+// session token
+const egressProxyUser = "addt"
+
+const (
+	egressDialTimeout  = 30 * time.Second
+	egressHelloTimeout = 10 * time.Second
+)
+
+// EgressProxy is a host-side HTTP proxy that a container's traffic is
+// forced through. It checks the hostname of every CONNECT tunnel and plain
+// HTTP request against the firewall rules, and the SNI of TLS inside a
+// tunnel too, so allowlisting follows names rather than the addresses they
+// resolved to at startup. Every decision is logged.
+//
+// With a CA set, tunnels to hosts that request rules are about are
+// intercepted: the proxy terminates TLS with a certificate from the CA and
+// checks each request's method and path before forwarding it.
+//
+// The proxy counts the bytes sent and received and the requests made per
+// destination, and can hold all traffic once they exceed a quota.
+type EgressProxy struct {
+	rules       FirewallRules
+	rulesMu     sync.RWMutex
+	permissive  bool   // log what would be denied, but allow it
+	container   string // container the proxy serves, for the logs
+	token       string
+	listener    net.Listener
+	port        int
+	mu          sync.Mutex
+	running     bool
+	denied      map[string]int // denied host or request → attempts
+	usage       map[string]*DestinationUsage
+	started     time.Time
+	quota       EgressQuota // Quota, extended each time the session resumes
+	paused      bool        // over quota, waiting for OnQuotaExceeded
+	blocked     bool        // over quota and not resumed: no more traffic
+	resumed     *sync.Cond
+	record      bool // save usage while running
+	dialer      *net.Dialer
+	transport   *http.Transport // forwards intercepted requests
+	upstreamTLS *tls.Config     // verifies upstream servers; nil for the system roots
+
+	// Learner, when set, records every destination for firewall learn
+	Learner *FirewallLearner
+
+	// CA, when set, signs the certificates for intercepted tunnels
+	CA *CertificateAuthority
+
+	// Quota limits the traffic of the session
+	Quota EgressQuota
+
+	// OnQuotaExceeded decides whether a session over its quota continues
+	// with another quota's worth of room; all traffic waits for it. Without
+	// it, traffic stops at the quota.
+	OnQuotaExceeded func(reason string, total DestinationUsage) bool
+}
+
+// NewEgressProxy creates an egress proxy for a container. Mode is the
+// firewall mode: permissive allows everything but logs what strict would
+// deny.
+func NewEgressProxy(container string, rules FirewallRules, mode string) (*EgressProxy, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate proxy token: %w", err)
+	}
+	p := &EgressProxy{
+		rules:      rules,
+		permissive: mode == "permissive",
+		container:  container,
+		token:      hex.EncodeToString(token),
+		denied:     make(map[string]int),
+		usage:      make(map[string]*DestinationUsage),
+	}
+	p.dialer = &net.Dialer{
+		Timeout: egressDialTimeout,
+		ControlContext: func(ctx context.Context, network, address string, c syscall.RawConn) error {
+			if err := refuseHostAddress(network, address, c); err != nil {
+				return err
+			}
+			return p.checkAddress(ctx, network, address, c)
+		},
+	}
+	p.resumed = sync.NewCond(&p.mu)
+	return p, nil
+}
+
+// Start listens on addr, e.g. "127.0.0.1:0". Containers reach the host
+// through a gateway address, which is where the proxy should listen; the
+// session token keeps others that reach it from using it.
+func (p *EgressProxy) Start(addr string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running {
+		return nil
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	p.listener = l
+	p.port = l.Addr().(*net.TCPAddr).Port
+	p.transport = &http.Transport{
+		DialContext:         p.dialCounted,
+		TLSClientConfig:     p.upstreamTLS,
+		TLSHandshakeTimeout: egressHelloTimeout,
+		DisableCompression:  true,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	p.running = true
+	p.started = time.Now()
+	p.quota = p.Quota
+	go p.acceptLoop()
+	return nil
+}
+
+// RecordUsage saves the session's network usage every interval while the
+// proxy runs, and when it stops
+func (p *EgressProxy) RecordUsage(interval time.Duration) {
+	p.mu.Lock()
+	p.record = true
+	p.mu.Unlock()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			p.mu.Lock()
+			running := p.running
+			p.mu.Unlock()
+			if !running {
+				return
+			}
+			if err := SaveNetworkUsage(p.Usage()); err != nil {
+				egressLogger.Debugf("%s: saving network usage failed: %v", p.container, err)
+			}
+		}
+	}()
+}
+
+// Stop stops the proxy; open tunnels end with the process
+func (p *EgressProxy) Stop() error {
+	p.mu.Lock()
+	if !p.running {
+		p.mu.Unlock()
+		return nil
+	}
+	p.running = false
+	p.resumed.Broadcast()
+	record := p.record
+	p.mu.Unlock()
+
+	// Closing connections writes to them, which takes the lock
+	p.transport.CloseIdleConnections()
+	err := p.listener.Close()
+	if record {
+		if err := SaveNetworkUsage(p.Usage()); err != nil {
+			egressLogger.Debugf("%s: saving network usage failed: %v", p.container, err)
+		}
+	}
+	return err
+}
+
+// SetRules replaces the rules the proxy checks, for connections and
+// requests made from now on
+func (p *EgressProxy) SetRules(rules FirewallRules) {
+	p.rulesMu.Lock()
+	p.rules = rules
+	p.rulesMu.Unlock()
+}
+
+func (p *EgressProxy) currentRules() FirewallRules {
+	p.rulesMu.RLock()
+	defer p.rulesMu.RUnlock()
+	return p.rules
+}
+
+// Port returns the port the proxy listens on (only valid after Start)
+func (p *EgressProxy) Port() int {
+	return p.port
+}
+
+// URL returns the proxy URL, with credentials, for a container that
+// reaches the host as host
+func (p *EgressProxy) URL(host string) string {
+	return fmt.Sprintf("http://%s:%s@%s", egressProxyUser, p.token, net.JoinHostPort(host, strconv.Itoa(p.port)))
+}
+
+// Denied returns the hosts connections were denied to, with the number of
+// attempts
+func (p *EgressProxy) Denied() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	denied := make(map[string]int, len(p.denied))
+	for host, n := range p.denied {
+		denied[host] = n
+	}
+	return denied
+}
+
+// Usage returns the session's traffic so far
+func (p *EgressProxy) Usage() *NetworkUsage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	usage := &NetworkUsage{Container: p.container, Started: p.started, Updated: time.Now()}
+	for _, d := range p.usage {
+		usage.Destinations = append(usage.Destinations, *d)
+	}
+	usage.sortDestinations()
+	return usage
+}
+
+func (p *EgressProxy) acceptLoop() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			p.mu.Lock()
+			running := p.running
+			p.mu.Unlock()
+			if !running {
+				return
+			}
+			continue
+		}
+		go p.handleConnection(conn)
+	}
+}
+
+func (p *EgressProxy) handleConnection(client net.Conn) {
+	defer client.Close()
+
+	reader := bufio.NewReader(client)
+	req, err := http.ReadRequest(reader)
+	if err != nil {
+		return
+	}
+	if !p.authorized(req) {
+		io.WriteString(client, "HTTP/1.1 407 Proxy Authentication Required\r\nProxy-Authenticate: Basic realm=\"addt\"\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+		return
+	}
+
+	if req.Method == http.MethodConnect {
+		p.handleConnect(client, reader, req)
+		return
+	}
+	p.handleHTTP(client, req)
+}
+
+// authorized checks the request's proxy credentials against the token
+func (p *EgressProxy) authorized(req *http.Request) bool {
+	auth := req.Header.Get("Proxy-Authorization")
+	encoded, ok := strings.CutPrefix(auth, "Basic ")
+	if !ok {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+	want := egressProxyUser + ":" + p.token
+	return subtle.ConstantTimeCompare(decoded, []byte(want)) == 1
+}
+
+// handleConnect opens a tunnel to an allowed host. For TLS the tunnel's
+// SNI must be allowed as well, which stops a tunnel to an allowed name
+// being used for another site behind the same CDN.
+func (p *EgressProxy) handleConnect(client net.Conn, reader *bufio.Reader, req *http.Request) {
+	host, port, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		io.WriteString(client, "HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\n\r\n")
+		return
+	}
+	if !p.allow(host, port, "connect") {
+		io.WriteString(client, "HTTP/1.1 403 Forbidden\r\nContent-Length: 0\r\n\r\n")
+		return
+	}
+	// Intercepted requests are counted one by one
+	portNum, _ := strconv.Atoi(port)
+	rules := p.currentRules()
+	intercepts := p.CA != nil && rules.Intercepts(host, portNum)
+	if !intercepts && !p.addRequest(host) {
+		io.WriteString(client, "HTTP/1.1 429 Too Many Requests\r\nContent-Length: 0\r\n\r\n")
+		return
+	}
+	io.WriteString(client, "HTTP/1.1 200 Connection established\r\n\r\n")
+
+	if intercepts {
+		p.intercept(client, reader, host, port)
+		return
+	}
+
+	// Read the TLS ClientHello, if the client starts with one
+	var hello bytes.Buffer
+	client.SetReadDeadline(time.Now().Add(egressHelloTimeout))
+	sni := readServerName(io.TeeReader(reader, &hello))
+	client.SetReadDeadline(time.Time{})
+	if sni != "" && normalizeHost(sni) != normalizeHost(host) && !p.allow(sni, port, "sni") {
+		return
+	}
+
+	upstream, err := p.dialCounted(context.Background(), "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		egressLogger.Debugf("%s: dial %s:%s failed: %v", p.container, host, port, err)
+		return
+	}
+	defer upstream.Close()
+	p.observeIP(host, upstream)
+	if _, err := upstream.Write(hello.Bytes()); err != nil {
+		return
+	}
+	pipe(client, reader, upstream)
+}
+
+// handleHTTP forwards a plain HTTP request to an allowed host. The
+// connection is closed after the response, so every request is checked.
+func (p *EgressProxy) handleHTTP(client net.Conn, req *http.Request) {
+	if req.URL.Scheme != "http" || req.URL.Host == "" {
+		io.WriteString(client, "HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\n\r\n")
+		return
+	}
+	host, port := req.URL.Hostname(), req.URL.Port()
+	if port == "" {
+		port = "80"
+	}
+	if !p.allow(host, port, "http") || !p.allowRequest(req.Method, host, port, req.URL) {
+		io.WriteString(client, "HTTP/1.1 403 Forbidden\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+		return
+	}
+	if !p.addRequest(host) {
+		io.WriteString(client, "HTTP/1.1 429 Too Many Requests\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+		return
+	}
+
+	upstream, err := p.dialCounted(context.Background(), "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		io.WriteString(client, "HTTP/1.1 502 Bad Gateway\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+		return
+	}
+	defer upstream.Close()
+	p.observeIP(host, upstream)
+
+	req.Header.Del("Proxy-Authorization")
+	req.Header.Del("Proxy-Connection")
+	req.Close = true
+	if err := req.Write(upstream); err != nil {
+		return
+	}
+	io.Copy(client, upstream)
+}
+
+// intercept terminates a tunnel's TLS with a certificate from the CA and
+// forwards the requests inside it one by one, each checked against the
+// request rules. Requests go upstream over a new TLS connection to the
+// server name the client asked for, which is checked like an SNI.
+func (p *EgressProxy) intercept(client net.Conn, reader *bufio.Reader, host, port string) {
+	conn := tls.Server(bufferedConn{client, reader}, &tls.Config{
+		NextProtos: []string{"http/1.1"},
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			name := hello.ServerName
+			if name == "" {
+				name = host
+			}
+			if normalizeHost(name) != normalizeHost(host) && !p.allow(name, port, "sni") {
+				return nil, fmt.Errorf("server name %s denied", name)
+			}
+			return p.CA.Certificate(name)
+		},
+	})
+	defer conn.Close()
+	client.SetReadDeadline(time.Now().Add(egressHelloTimeout))
+	if err := conn.Handshake(); err != nil {
+		egressLogger.Debugf("%s: intercepting %s:%s failed: %v", p.container, host, port, err)
+		return
+	}
+	client.SetReadDeadline(time.Time{})
+	serverName := conn.ConnectionState().ServerName
+	if serverName == "" {
+		serverName = host
+	}
+	authority := serverName
+	if port != "443" {
+		authority = net.JoinHostPort(serverName, port)
+	}
+
+	requests := bufio.NewReader(conn)
+	for {
+		req, err := http.ReadRequest(requests)
+		if err != nil {
+			return
+		}
+		if reqHost, _, err := net.SplitHostPort(req.Host); err == nil {
+			req.Host = reqHost
+		}
+		switch {
+		case req.Host != "" && normalizeHost(req.Host) != normalizeHost(serverName):
+			// Another site's requests through this server name's tunnel
+			io.WriteString(conn, "HTTP/1.1 421 Misdirected Request\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+			return
+		case req.Header.Get("Upgrade") != "":
+			io.WriteString(conn, "HTTP/1.1 501 Not Implemented\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+			return
+		}
+		req.URL.Scheme = "https"
+		req.URL.Host = authority
+		req.RequestURI = ""
+		if !p.allowRequest(req.Method, serverName, port, req.URL) {
+			io.WriteString(conn, "HTTP/1.1 403 Forbidden\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+			return
+		}
+		if !p.addRequest(serverName) {
+			io.WriteString(conn, "HTTP/1.1 429 Too Many Requests\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+			return
+		}
+
+		resp, err := p.transport.RoundTrip(req)
+		if err != nil {
+			egressLogger.Debugf("%s: forwarding to %s failed: %v", p.container, authority, err)
+			io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+			return
+		}
+		err = resp.Write(conn)
+		resp.Body.Close()
+		if err != nil || req.Close || resp.Close {
+			return
+		}
+	}
+}
+
+// allowRequest checks an HTTP request against the request rules. Requests
+// no request rule is about were decided with their connection, so only
+// the others are logged.
+func (p *EgressProxy) allowRequest(method, host, port string, u *url.URL) bool {
+	rules := p.currentRules()
+	if !rules.Intercept {
+		return true
+	}
+	portNum, _ := strconv.Atoi(port)
+	urlPath := cleanRequestPath(u.Path)
+	decision := rules.CheckRequest(method, host, portNum, urlPath)
+	if decision.Allowed && decision.Layer == "none" {
+		return true
+	}
+
+	reason := "request rule: " + decision.Layer
+	if decision.Rule != "" {
+		reason += " '" + decision.Rule + "'"
+	}
+	target := u.Scheme + "://" + u.Host + urlPath
+	if !decision.Allowed {
+		p.mu.Lock()
+		p.denied[method+" "+normalizeHost(host)+urlPath]++
+		p.mu.Unlock()
+	}
+	if !decision.Allowed && p.permissive {
+		egressLogger.Infof("%s: would deny %s %s (%s)", p.container, method, target, reason)
+		LogRequest(p.container, method, target, true, "permissive, would deny: "+reason)
+		return true
+	}
+	if decision.Allowed {
+		egressLogger.Infof("%s: allow %s %s (%s)", p.container, method, target, reason)
+	} else {
+		egressLogger.Warningf("%s: deny %s %s (%s)", p.container, method, target, reason)
+	}
+	LogRequest(p.container, method, target, decision.Allowed, reason)
+	return decision.Allowed
+}
+
+// cleanRequestPath resolves "." and ".." in a request path, as servers do,
+// so a rule for a path can't be sidestepped through another
+func cleanRequestPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// allow checks host against the rules and logs the decision; via is where
+// the name came from: "connect", "http", or "sni" for a TLS ClientHello
+func (p *EgressProxy) allow(host, port, via string) bool {
+	portNum, _ := strconv.Atoi(port)
+	rules := p.currentRules()
+	allowed, layer := rules.CheckPort(host, portNum, "tcp")
+	reason := "rule: " + layer
+	if via == "sni" {
+		reason = via + ", " + reason
+	}
+	target := net.JoinHostPort(host, port)
+	if p.Learner != nil {
+		p.Learner.Observe(host, port, via)
+	}
+
+	if !allowed {
+		p.mu.Lock()
+		p.denied[normalizeHost(host)]++
+		p.mu.Unlock()
+	}
+	if !allowed && p.permissive {
+		egressLogger.Infof("%s: would deny %s (%s)", p.container, target, reason)
+		LogNetwork(p.container, target, true, "permissive, would deny: "+reason)
+		return true
+	}
+	if allowed {
+		egressLogger.Infof("%s: allow %s (%s)", p.container, target, reason)
+	} else {
+		egressLogger.Warningf("%s: deny %s (%s)", p.container, target, reason)
+	}
+	LogNetwork(p.container, target, allowed, reason)
+	return allowed
+}
+
+// observeIP records the address a connection to host went to
+func (p *EgressProxy) observeIP(host string, upstream net.Conn) {
+	if p.Learner == nil {
+		return
+	}
+	if addr, ok := upstream.RemoteAddr().(*net.TCPAddr); ok {
+		p.Learner.ObserveIP(host, addr.IP.String())
+	}
+}
+
+// dialHostKey carries the name a dial resolves to its address check
+type dialHostKey struct{}
+
+// dialCounted connects to a destination, counting the traffic to its host
+func (p *EgressProxy) dialCounted(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, _ := net.SplitHostPort(addr)
+	conn, err := p.dialer.DialContext(context.WithValue(ctx, dialHostKey{}, host), network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{Conn: conn, proxy: p, host: normalizeHost(host)}, nil
+}
+
+// addRequest counts a tunnel or HTTP request to host, once traffic may
+// flow; it returns false when the quota stopped the session's traffic
+func (p *EgressProxy) addRequest(host string) bool {
+	if !p.waitQuota() {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.destination(normalizeHost(host)).Requests++
+	p.checkQuota()
+	return true
+}
+
+// addBytes counts data sent to and received from host
+func (p *EgressProxy) addBytes(host string, out, in int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	d := p.destination(host)
+	d.BytesOut += out
+	d.BytesIn += in
+	p.checkQuota()
+}
+
+// destination returns host's usage; the caller holds mu
+func (p *EgressProxy) destination(host string) *DestinationUsage {
+	d := p.usage[host]
+	if d == nil {
+		d = &DestinationUsage{Host: host}
+		p.usage[host] = d
+	}
+	return d
+}
+
+// total sums the usage of all destinations; the caller holds mu
+func (p *EgressProxy) total() DestinationUsage {
+	var total DestinationUsage
+	for _, d := range p.usage {
+		total.BytesOut += d.BytesOut
+		total.BytesIn += d.BytesIn
+		total.Requests += d.Requests
+	}
+	return total
+}
+
+// checkQuota pauses traffic when usage goes over the quota, and asks
+// OnQuotaExceeded whether to continue; the caller holds mu
+func (p *EgressProxy) checkQuota() {
+	if p.paused || p.blocked {
+		return
+	}
+	total := p.total()
+	reason := p.quota.exceeded(total)
+	if reason == "" {
+		return
+	}
+	p.paused = true
+	egressLogger.Warningf("%s: quota exceeded: %s", p.container, reason)
+	LogNetwork(p.container, "*", false, "quota exceeded: "+reason)
+	go p.decideQuota(reason, total)
+}
+
+// decideQuota resumes or stops the traffic of a session over its quota
+func (p *EgressProxy) decideQuota(reason string, total DestinationUsage) {
+	resume := p.OnQuotaExceeded != nil && p.OnQuotaExceeded(reason, total)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused = false
+	if resume {
+		p.quota = p.quota.extend(p.Quota, p.total())
+	} else {
+		p.blocked = true
+	}
+	p.resumed.Broadcast()
+}
+
+// waitQuota waits while traffic is paused; it returns false once the quota
+// stopped it
+func (p *EgressProxy) waitQuota() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.paused && p.running {
+		p.resumed.Wait()
+	}
+	return !p.blocked
+}
+
+// errQuotaExceeded ends tunnels once the quota stopped the session's traffic
+var errQuotaExceeded = errors.New("egress quota exceeded")
+
+// countingConn is a connection to a destination that counts its traffic.
+// Writes wait while the quota pauses traffic.
+type countingConn struct {
+	net.Conn
+	proxy *EgressProxy
+	host  string
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.proxy.addBytes(c.host, 0, int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	if !c.proxy.waitQuota() {
+		return 0, errQuotaExceeded
+	}
+	n, err := c.Conn.Write(b)
+	c.proxy.addBytes(c.host, int64(n), 0)
+	return n, err
+}
+
+// CloseWrite half-closes the connection when it's TCP
+func (c *countingConn) CloseWrite() error {
+	if cw, ok := c.Conn.(closeWriter); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+// closeWriter is a connection that can be half-closed
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// errHelloRead ends the handshake once the ClientHello has been seen
+var errHelloRead = errors.New("client hello read")
+
+// readServerName reads a TLS ClientHello from r and returns its server
+// name, or "" when r doesn't start with one
+func readServerName(r io.Reader) string {
+	var name string
+	conn := tls.Server(helloConn{r}, &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			name = hello.ServerName
+			return nil, errHelloRead
+		},
+	})
+	conn.Handshake()
+	return name
+}
+
+// helloConn is a read-only net.Conn over the start of a tunnel
+type helloConn struct {
+	r io.Reader
+}
+
+func (c helloConn) Read(b []byte) (int, error)         { return c.r.Read(b) }
+func (c helloConn) Write(b []byte) (int, error)        { return 0, io.ErrClosedPipe }
+func (c helloConn) Close() error                       { return nil }
+func (c helloConn) LocalAddr() net.Addr                { return nil }
+func (c helloConn) RemoteAddr() net.Addr               { return nil }
+func (c helloConn) SetDeadline(t time.Time) error      { return nil }
+func (c helloConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c helloConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// bufferedConn is a net.Conn whose reads go through a reader that may
+// already hold some of its bytes
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c bufferedConn) Read(b []byte) (int, error) { return c.r.Read(b) }
+
+// refuseHostAddress keeps the proxy from connecting to the host itself or
+// to link-local addresses such as cloud metadata services, whatever name
+// resolved to them
+func refuseHostAddress(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil
+	}
+	if ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+		return fmt.Errorf("refusing to connect to %s", ip)
+	}
+	return nil
+}
+
+// checkAddress checks the address a dial's name resolved to against the
+// rules, so a network denial covers every name resolving into it. The
+// name was allowed before the dial; the layer matching first, by name or
+// address, decides.
+func (p *EgressProxy) checkAddress(ctx context.Context, network, address string, c syscall.RawConn) error {
+	ipText, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(ipText)
+	if ip == nil {
+		return nil
+	}
+	host, _ := ctx.Value(dialHostKey{}).(string)
+	if host == "" {
+		host = ipText
+	}
+	portNum, _ := strconv.Atoi(port)
+	rules := p.currentRules()
+	d := rules.DecideAddress(host, ip, portNum, "tcp")
+	if d.Allowed {
+		return nil
+	}
+
+	// In permissive mode a name denied by itself was already logged
+	if p.permissive && !rules.Decide(host, portNum, "tcp").Allowed {
+		return nil
+	}
+	target := net.JoinHostPort(ipText, port)
+	reason := fmt.Sprintf("%s resolved to %s, rule: %s", host, ipText, d.Layer)
+	p.mu.Lock()
+	p.denied[normalizeHost(host)]++
+	p.mu.Unlock()
+	if p.permissive {
+		egressLogger.Infof("%s: would deny %s (%s)", p.container, target, reason)
+		LogNetwork(p.container, target, true, "permissive, would deny: "+reason)
+		return nil
+	}
+	egressLogger.Warningf("%s: deny %s (%s)", p.container, target, reason)
+	LogNetwork(p.container, target, false, reason)
+	return fmt.Errorf("refusing to connect to %s: %s", target, reason)
+}
+
+// pipe copies both ways between the client (whose reader may hold
+// buffered bytes) and upstream until either side is done
+func pipe(client net.Conn, reader *bufio.Reader, upstream net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(upstream, reader)
+		if cw, ok := upstream.(closeWriter); ok {
+			cw.CloseWrite()
+		}
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(client, upstream)
+		if cw, ok := client.(closeWriter); ok {
+			cw.CloseWrite()
+		}
+		done <- struct{}{}
+	}()
+	<-done
+	<-done
+}
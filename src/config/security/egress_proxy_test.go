@@ -0,0 +1,312 @@
+package security
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFirewallRules_Check(t *testing.T) {
+	rules := FirewallRules{
+		Project:   FirewallLayer{Allowed: []string{"internal.example"}, Denied: []string{"pypi.org"}},
+		Global:    FirewallLayer{Allowed: []string{"pypi.org", "Corp.Example."}},
+		Extension: FirewallLayer{Denied: []string{"unpkg.com"}},
+		Defaults:  []string{"api.anthropic.com", "unpkg.com"},
+	}
+	tests := []struct {
+		host      string
+		want      bool
+		wantLayer string
+	}{
+		{"internal.example", true, "project"},
+		{"pypi.org", false, "project"},
+		{"corp.example", true, "global"},
+		{"unpkg.com", false, "extension"},
+		{"API.anthropic.com.", true, "defaults"},
+		{"evil.example", false, "none"},
+	}
+	for _, tt := range tests {
+		got, layer := rules.Check(tt.host)
+		if got != tt.want || layer != tt.wantLayer {
+			t.Errorf("Check(%q) = %v, %q, want %v, %q", tt.host, got, layer, tt.want, tt.wantLayer)
+		}
+	}
+}
+
+// startTestEgressProxy starts a proxy allowing only allowed, with a dialer
+// that may connect to loopback test servers
+func startTestEgressProxy(t *testing.T, mode string, allowed ...string) *EgressProxy {
+	t.Helper()
+	p, err := NewEgressProxy("addt-test", FirewallRules{Defaults: allowed}, mode)
+	if err != nil {
+		t.Fatalf("NewEgressProxy() error = %v", err)
+	}
+	p.dialer = &net.Dialer{}
+	if err := p.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { p.Stop() })
+	return p
+}
+
+// connectThrough sends a CONNECT for target to the proxy and returns the
+// connection and the response status code
+func connectThrough(t *testing.T, p *EgressProxy, target string, auth bool) (net.Conn, *bufio.Reader, int) {
+	t.Helper()
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", p.Port()))
+	if err != nil {
+		t.Fatalf("dial proxy: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	req := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", target, target)
+	if auth {
+		creds := base64.StdEncoding.EncodeToString([]byte(egressProxyUser + ":" + p.token))
+		req += "Proxy-Authorization: Basic " + creds + "\r\n"
+	}
+	io.WriteString(conn, req+"\r\n")
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		t.Fatalf("read proxy response: %v", err)
+	}
+	return conn, reader, resp.StatusCode
+}
+
+func TestEgressProxy_RequiresCredentials(t *testing.T) {
+	p := startTestEgressProxy(t, "strict", "localhost")
+	if _, _, code := connectThrough(t, p, "localhost:443", false); code != http.StatusProxyAuthRequired {
+		t.Errorf("CONNECT without credentials = %d, want 407", code)
+	}
+}
+
+func TestEgressProxy_DeniesHost(t *testing.T) {
+	p := startTestEgressProxy(t, "strict", "localhost")
+	if _, _, code := connectThrough(t, p, "evil.example:443", true); code != http.StatusForbidden {
+		t.Errorf("CONNECT evil.example = %d, want 403", code)
+	}
+	if denied := p.Denied(); denied["evil.example"] != 1 {
+		t.Errorf("Denied() = %v, want evil.example once", denied)
+	}
+}
+
+func TestEgressProxy_SetRules(t *testing.T) {
+	p := startTestEgressProxy(t, "strict", "localhost")
+	p.SetRules(FirewallRules{Project: FirewallLayer{Denied: []string{"localhost"}}})
+	if _, _, code := connectThrough(t, p, "localhost:443", true); code != http.StatusForbidden {
+		t.Errorf("CONNECT localhost after SetRules() = %d, want 403", code)
+	}
+}
+
+func TestEgressProxy_PermissiveAllows(t *testing.T) {
+	upstream := startEchoServer(t)
+	p := startTestEgressProxy(t, "permissive")
+	p.Learner = NewFirewallLearner()
+	_, port, _ := net.SplitHostPort(upstream)
+	conn, _, code := connectThrough(t, p, "localhost:"+port, true)
+	if code != http.StatusOK {
+		t.Errorf("CONNECT in permissive mode = %d, want 200", code)
+	}
+	if denied := p.Denied(); denied["localhost"] != 1 {
+		t.Errorf("Denied() = %v, want localhost recorded as would-deny", denied)
+	}
+	// Once the tunnel is up the learner also has the address it went to
+	io.WriteString(conn, "ping\n")
+	bufio.NewReader(conn).ReadString('\n')
+	dests := p.Learner.Destinations()
+	if len(dests) != 1 || dests[0].Host != "localhost" || dests[0].Via[0] != "connect" || len(dests[0].IPs) != 1 {
+		t.Errorf("learned = %+v, want localhost via connect with its IP", dests)
+	}
+}
+
+func TestEgressProxy_TunnelsAllowedHost(t *testing.T) {
+	upstream := startEchoServer(t)
+	p := startTestEgressProxy(t, "strict", "localhost")
+	_, port, _ := net.SplitHostPort(upstream)
+
+	conn, reader, code := connectThrough(t, p, "localhost:"+port, true)
+	if code != http.StatusOK {
+		t.Fatalf("CONNECT localhost = %d, want 200", code)
+	}
+	io.WriteString(conn, "ping\n")
+	line, err := reader.ReadString('\n')
+	if err != nil || line != "ping\n" {
+		t.Errorf("tunnel echo = %q, %v, want \"ping\\n\"", line, err)
+	}
+}
+
+func TestEgressProxy_CountsUsage(t *testing.T) {
+	upstream := startEchoServer(t)
+	p := startTestEgressProxy(t, "strict", "localhost")
+	_, port, _ := net.SplitHostPort(upstream)
+
+	conn, reader, _ := connectThrough(t, p, "localhost:"+port, true)
+	io.WriteString(conn, "ping\n")
+	reader.ReadString('\n')
+	connectThrough(t, p, "evil.example:443", true)
+
+	usage := p.Usage()
+	want := []DestinationUsage{{Host: "localhost", BytesOut: 5, BytesIn: 5, Requests: 1}}
+	if usage.Container != "addt-test" || !reflect.DeepEqual(usage.Destinations, want) {
+		t.Errorf("Usage() = %+v, want %+v", usage, want)
+	}
+}
+
+func TestEgressProxy_QuotaPausesTraffic(t *testing.T) {
+	upstream := startEchoServer(t)
+	_, port, _ := net.SplitHostPort(upstream)
+	target := "localhost:" + port
+
+	for _, resume := range []bool{true, false} {
+		p := startTestEgressProxy(t, "strict", "localhost")
+		p.Quota = EgressQuota{MaxRequests: 1}
+		p.quota = p.Quota
+		asked := make(chan string, 1)
+		answer := make(chan bool)
+		p.OnQuotaExceeded = func(reason string, total DestinationUsage) bool {
+			asked <- reason
+			return <-answer
+		}
+
+		connectThrough(t, p, target, true)
+		connectThrough(t, p, target, true) // goes over the quota
+		if reason := <-asked; !strings.Contains(reason, "2 requests") {
+			t.Errorf("reason = %q", reason)
+		}
+
+		// Traffic waits for the answer
+		codes := make(chan int)
+		go func() {
+			_, _, code := connectThrough(t, p, target, true)
+			codes <- code
+		}()
+		select {
+		case code := <-codes:
+			t.Fatalf("CONNECT while paused = %d, want it to wait", code)
+		case <-time.After(100 * time.Millisecond):
+		}
+		answer <- resume
+
+		want := http.StatusOK
+		if !resume {
+			want = http.StatusTooManyRequests
+		}
+		if code := <-codes; code != want {
+			t.Errorf("resume=%v: CONNECT = %d, want %d", resume, code, want)
+		}
+		if resume && p.quota.MaxRequests != 3 {
+			t.Errorf("extended quota = %+v, want 3 requests", p.quota)
+		}
+	}
+}
+
+func TestEgressProxy_DeniesSNI(t *testing.T) {
+	upstream := startEchoServer(t)
+	p := startTestEgressProxy(t, "strict", "localhost")
+	_, port, _ := net.SplitHostPort(upstream)
+
+	conn, _, code := connectThrough(t, p, "localhost:"+port, true)
+	if code != http.StatusOK {
+		t.Fatalf("CONNECT localhost = %d, want 200", code)
+	}
+	// A TLS handshake for another name through the allowed tunnel
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: "evil.example", InsecureSkipVerify: true})
+	if err := tlsConn.Handshake(); err == nil {
+		t.Error("handshake through tunnel succeeded, want it cut off")
+	}
+	if denied := p.Denied(); denied["evil.example"] != 1 {
+		t.Errorf("Denied() = %v, want evil.example from the SNI", denied)
+	}
+}
+
+func TestReadServerName(t *testing.T) {
+	client, server := net.Pipe()
+	go func() {
+		tls.Client(client, &tls.Config{ServerName: "api.anthropic.com", InsecureSkipVerify: true}).Handshake()
+	}()
+	defer client.Close()
+	defer server.Close()
+
+	if got := readServerName(server); got != "api.anthropic.com" {
+		t.Errorf("readServerName() = %q, want api.anthropic.com", got)
+	}
+	if got := readServerName(strings.NewReader("GET / HTTP/1.1\r\n\r\n")); got != "" {
+		t.Errorf("readServerName(plain HTTP) = %q, want \"\"", got)
+	}
+}
+
+func TestRefuseHostAddress(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:80", "[::1]:443", "169.254.169.254:80", "0.0.0.0:22"} {
+		if err := refuseHostAddress("tcp", addr, nil); err == nil {
+			t.Errorf("refuseHostAddress(%s) = nil, want refused", addr)
+		}
+	}
+	if err := refuseHostAddress("tcp", "140.82.112.3:443", nil); err != nil {
+		t.Errorf("refuseHostAddress(public) = %v, want nil", err)
+	}
+}
+
+func TestEgressProxy_DeniesResolvedAddress(t *testing.T) {
+	upstream := startEchoServer(t)
+	_, port, _ := net.SplitHostPort(upstream)
+	p := startTestEgressProxy(t, "strict")
+	p.dialer = &net.Dialer{ControlContext: p.checkAddress}
+
+	// Every name is allowed, but not those resolving into 127.0.0.0/8
+	p.SetRules(FirewallRules{Project: FirewallLayer{Allowed: []string{"*", "!127.0.0.0/8"}}})
+	conn, reader, code := connectThrough(t, p, "localhost:"+port, true)
+	if code != http.StatusOK {
+		t.Fatalf("CONNECT localhost = %d, want 200 before the dial", code)
+	}
+	io.WriteString(conn, "ping\n")
+	if line, err := reader.ReadString('\n'); err == nil {
+		t.Errorf("tunnel to a denied address echoed %q, want it closed", line)
+	}
+	if denied := p.Denied(); denied["localhost"] != 1 {
+		t.Errorf("Denied() = %v, want localhost once", denied)
+	}
+
+	// A project allow by name wins over a global network denial
+	p.SetRules(FirewallRules{
+		Project: FirewallLayer{Allowed: []string{"localhost"}},
+		Global:  FirewallLayer{Denied: []string{"127.0.0.0/8"}},
+	})
+	conn, reader, _ = connectThrough(t, p, "localhost:"+port, true)
+	io.WriteString(conn, "ping\n")
+	if line, err := reader.ReadString('\n'); err != nil || line != "ping\n" {
+		t.Errorf("tunnel to an allowed name = %q, %v; want the echo", line, err)
+	}
+}
+
+// startEchoServer starts a TCP server echoing lines back and returns its
+// address
+func startEchoServer(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
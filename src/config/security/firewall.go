@@ -0,0 +1,429 @@
+package security
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DefaultAllowedDomains returns the domains the firewall allows unless a
+// layer denies them
+func DefaultAllowedDomains() []string {
+	return []string{
+		"api.anthropic.com",
+		"github.com",
+		"api.github.com",
+		"raw.githubusercontent.com",
+		"objects.githubusercontent.com",
+		"registry.npmjs.org",
+		"pypi.org",
+		"files.pythonhosted.org",
+		"proxy.golang.org",
+		"sum.golang.org",
+		"registry-1.docker.io",
+		"auth.docker.io",
+		"production.cloudflare.docker.com",
+		"cdn.jsdelivr.net",
+		"unpkg.com",
+	}
+}
+
+// FirewallLayer is one layer's allow and deny lists
+type FirewallLayer struct {
+	Allowed []string
+	Denied  []string
+}
+
+// FirewallRules holds the layered firewall rules a session is checked
+// against. Order: Defaults → Extension → Global → Project (project wins).
+// Within a layer, deny rules (and "!" rules in the allow list) are checked
+// before allow rules; the first layer with a matching rule decides.
+type FirewallRules struct {
+	Project   FirewallLayer
+	Global    FirewallLayer
+	Extension FirewallLayer
+	Defaults  []string // allow only
+
+	// Request rules in the layers are enforced only when the proxy
+	// intercepts TLS; an allowed request's host is then allowed too
+	Intercept      bool
+	DeniedRequests []string // default request denials
+}
+
+// FirewallDecision is the outcome of checking a destination
+type FirewallDecision struct {
+	Allowed bool
+	Layer   string // "project", "global", "extension", "defaults" or "none"
+	Rule    string // the rule that decided, as written
+}
+
+// firewallCheck is the outcome of checking one layer
+type firewallCheck int
+
+const (
+	firewallNoMatch firewallCheck = iota
+	firewallAllowed
+	firewallDenied
+)
+
+// Check reports whether host may be looked up and connected to on some
+// port, and the layer that decided it: "project", "global", "extension",
+// "defaults", or "none" when no rule matched. Rules limited to a port
+// allow the name, but only deny it when they cover every port.
+func (r *FirewallRules) Check(host string) (bool, string) {
+	d := r.Decide(host, 0, "")
+	return d.Allowed, d.Layer
+}
+
+// CheckPort is Check for a connection to host on port over proto ("tcp"
+// or "udp")
+func (r *FirewallRules) CheckPort(host string, port int, proto string) (bool, string) {
+	d := r.Decide(host, port, proto)
+	return d.Allowed, d.Layer
+}
+
+// Decide checks a destination against the layers; port 0 checks the name
+// alone
+func (r *FirewallRules) Decide(host string, port int, proto string) FirewallDecision {
+	return r.decide(newDestination(host, port, proto))
+}
+
+// DecideAddress checks a connection to host at the address it resolved
+// to: each layer's rules match the name or the address, so a layer's
+// network denials cover names that resolve into them, and the first layer
+// matching either decides
+func (r *FirewallRules) DecideAddress(host string, ip net.IP, port int, proto string) FirewallDecision {
+	dest := newDestination(host, port, proto)
+	dest.ip = ip
+	return r.decide(dest)
+}
+
+func (r *FirewallRules) decide(dest destination) FirewallDecision {
+	// Layers 4 to 2: Project (most specific, checked first), Global, Extension
+	for _, l := range r.layers() {
+		if result, rule := l.layer.check(dest, r.Intercept); result != firewallNoMatch {
+			return FirewallDecision{Allowed: result == firewallAllowed, Layer: l.name, Rule: rule}
+		}
+	}
+
+	// Layer 1: Defaults (allow only)
+	if rule, ok := firstMatch(r.Defaults, dest, false); ok {
+		return FirewallDecision{Allowed: true, Layer: "defaults", Rule: rule}
+	}
+
+	return FirewallDecision{Allowed: false, Layer: "none"}
+}
+
+// AddressRules returns the rules that apply by address or port rather than
+// by name, in the order they are checked, for the container's packet
+// filter. Each is "<layer> <accept|drop> <proto> <port> <network>", with
+// "-" for any. The script checks each layer's rules, then the addresses
+// that layer's allowed names resolved to, before moving to the next layer,
+// so a layer decides by name or address just as DecideAddress does.
+func (r *FirewallRules) AddressRules() []string {
+	var lines []string
+	add := func(layer, verdict string, rule FirewallRule) {
+		proto, port, network := "-", "-", "-"
+		if rule.Proto != "" {
+			proto = rule.Proto
+		}
+		if rule.Port != 0 {
+			port = strconv.Itoa(rule.Port)
+		}
+		if rule.Net != nil {
+			network = rule.Net.String()
+		}
+		lines = append(lines, strings.Join([]string{layer, verdict, proto, port, network}, " "))
+	}
+	for _, l := range r.layers() {
+		for _, rule := range parseRules(l.layer.Denied) {
+			if rule.IsAddress() {
+				add(l.name, "drop", rule)
+			}
+		}
+		allowed := parseRules(l.layer.Allowed)
+		for _, rule := range allowed {
+			if rule.IsAddress() && rule.Negate {
+				add(l.name, "drop", rule)
+			}
+		}
+		for _, rule := range allowed {
+			if rule.IsAddress() && !rule.Negate {
+				add(l.name, "accept", rule)
+			}
+		}
+	}
+	return lines
+}
+
+// namedLayer is a layer with the name decisions report it by
+type namedLayer struct {
+	name  string
+	layer FirewallLayer
+}
+
+// layers returns the layers with deny rules, most specific first
+func (r *FirewallRules) layers() []namedLayer {
+	return []namedLayer{
+		{"project", r.Project},
+		{"global", r.Global},
+		{"extension", r.Extension},
+	}
+}
+
+// check checks a single layer's denials, then its allow list, including
+// the hosts of allowed requests when requests are checked
+func (l FirewallLayer) check(dest destination, requests bool) (firewallCheck, string) {
+	if rule, ok := firstMatch(l.Denied, dest, true); ok {
+		return firewallDenied, rule
+	}
+	for _, raw := range l.Allowed {
+		if rule, err := ParseFirewallRule(raw); err == nil && rule.Negate && rule.denies(dest) {
+			return firewallDenied, raw
+		}
+	}
+	if rule, ok := firstMatch(l.Allowed, dest, false); ok {
+		return firewallAllowed, rule
+	}
+	if requests {
+		for _, raw := range l.Allowed {
+			if rule, err := ParseRequestRule(raw); err == nil && !rule.Negate && rule.matchesHost(dest) {
+				return firewallAllowed, raw
+			}
+		}
+	}
+	return firewallNoMatch, ""
+}
+
+// firstMatch returns the first of the rules that denies (or allows) dest.
+// Rules that don't parse never match.
+func firstMatch(rules []string, dest destination, deny bool) (string, bool) {
+	for _, raw := range rules {
+		rule, err := ParseFirewallRule(raw)
+		if err != nil {
+			continue
+		}
+		if deny && rule.denies(dest) {
+			return raw, true
+		}
+		if !deny && !rule.Negate && rule.allows(dest) {
+			return raw, true
+		}
+	}
+	return "", false
+}
+
+// parseRules parses rules, skipping those that don't parse
+func parseRules(raw []string) []FirewallRule {
+	var rules []FirewallRule
+	for _, s := range raw {
+		if rule, err := ParseFirewallRule(s); err == nil {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// destination is what a rule is matched against
+type destination struct {
+	host  string
+	ip    net.IP // set when host is an address
+	port  int    // 0 when only the name is checked
+	proto string // "tcp", "udp" or "" for either
+}
+
+func newDestination(host string, port int, proto string) destination {
+	host = normalizeHost(strings.Trim(host, "[]"))
+	return destination{host: host, ip: net.ParseIP(host), port: port, proto: proto}
+}
+
+// FirewallRule is a parsed allow or deny rule. Rules are written as:
+//
+//	example.com          the host, any port
+//	*.example.com        any subdomain of example.com (not example.com itself)
+//	10.0.0.0/8, ::1      addresses in a network, or one address
+//	host:5000            the host on port 5000; [2001:db8::/32]:443 for IPv6
+//	tcp/22, udp/53       any host on a port
+//	*                    any host; *:443 for any host on a port
+//
+// A leading "!" makes a rule deny wherever it is listed, so an allow list
+// can carry its exceptions, e.g. "!tcp/22".
+type FirewallRule struct {
+	Negate   bool
+	Host     string     // exact host
+	Wildcard string     // domain suffix, with its leading dot
+	Net      *net.IPNet // network or single address
+	Proto    string     // "tcp", "udp" or "" for both
+	Port     int        // 0 for any port
+}
+
+// ParseFirewallRule parses a firewall rule
+func ParseFirewallRule(s string) (FirewallRule, error) {
+	var rule FirewallRule
+	text := strings.TrimSpace(s)
+	if strings.HasPrefix(text, "!") {
+		rule.Negate = true
+		text = strings.TrimSpace(text[1:])
+	}
+	if text == "" {
+		return rule, fmt.Errorf("empty firewall rule %q", s)
+	}
+
+	// Protocol and port: tcp/22
+	if proto, port, ok := strings.Cut(text, "/"); ok && (proto == "tcp" || proto == "udp") {
+		n, err := parseRulePort(port)
+		if err != nil {
+			return rule, fmt.Errorf("invalid firewall rule %q: %w", s, err)
+		}
+		rule.Proto, rule.Port = proto, n
+		return rule, nil
+	}
+
+	// Split off a port: host:port or [address]:port. A bare IPv6 address
+	// has no port.
+	host := text
+	if strings.HasPrefix(text, "[") {
+		end := strings.Index(text, "]")
+		if end < 0 {
+			return rule, fmt.Errorf("invalid firewall rule %q: missing ']'", s)
+		}
+		host = text[1:end]
+		if rest := text[end+1:]; rest != "" {
+			port, ok := strings.CutPrefix(rest, ":")
+			if !ok {
+				return rule, fmt.Errorf("invalid firewall rule %q: expected ':port' after ']'", s)
+			}
+			n, err := parseRulePort(port)
+			if err != nil {
+				return rule, fmt.Errorf("invalid firewall rule %q: %w", s, err)
+			}
+			rule.Port = n
+		}
+	} else if strings.Count(text, ":") == 1 {
+		var port string
+		host, port, _ = strings.Cut(text, ":")
+		n, err := parseRulePort(port)
+		if err != nil {
+			return rule, fmt.Errorf("invalid firewall rule %q: %w", s, err)
+		}
+		rule.Port = n
+	}
+
+	switch {
+	case host == "*":
+	case strings.Contains(host, "/"):
+		_, network, err := net.ParseCIDR(host)
+		if err != nil {
+			return rule, fmt.Errorf("invalid firewall rule %q: bad network %q", s, host)
+		}
+		rule.Net = network
+	case net.ParseIP(host) != nil:
+		ip := net.ParseIP(host)
+		bits := 128
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 32
+		}
+		rule.Net = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	case strings.HasPrefix(host, "*."):
+		suffix := normalizeHost(host[2:])
+		if !validRuleHost(suffix) {
+			return rule, fmt.Errorf("invalid firewall rule %q: bad wildcard", s)
+		}
+		rule.Wildcard = "." + suffix
+	default:
+		if !validRuleHost(host) {
+			return rule, fmt.Errorf("invalid firewall rule %q: bad host %q", s, host)
+		}
+		rule.Host = normalizeHost(host)
+	}
+	return rule, nil
+}
+
+// IsAddress reports whether the rule applies by address or port rather
+// than by name
+func (r FirewallRule) IsAddress() bool {
+	return r.Host == "" && r.Wildcard == ""
+}
+
+// Broad reports whether the rule covers any host, a whole top-level
+// domain, or a network of /16 or wider (/32 for IPv6)
+func (r FirewallRule) Broad() bool {
+	switch {
+	case r.Host != "":
+		return false
+	case r.Wildcard != "":
+		return !strings.Contains(r.Wildcard[1:], ".")
+	case r.Net != nil:
+		ones, bits := r.Net.Mask.Size()
+		return bits == 32 && ones <= 16 || bits == 128 && ones <= 32
+	}
+	return true
+}
+
+// allows reports whether the rule matches dest; a rule for one port
+// matches a check of the name alone
+func (r FirewallRule) allows(dest destination) bool {
+	return r.matchesHost(dest) && r.matchesPort(dest, true)
+}
+
+// denies reports whether the rule, as a deny rule, matches dest; a rule
+// for one port doesn't deny the name alone
+func (r FirewallRule) denies(dest destination) bool {
+	return r.matchesHost(dest) && r.matchesPort(dest, false)
+}
+
+func (r FirewallRule) matchesHost(dest destination) bool {
+	switch {
+	case r.Host != "":
+		return dest.host == r.Host
+	case r.Wildcard != "":
+		return strings.HasSuffix(dest.host, r.Wildcard)
+	case r.Net != nil:
+		return dest.ip != nil && r.Net.Contains(dest.ip)
+	}
+	return true
+}
+
+func (r FirewallRule) matchesPort(dest destination, nameOnly bool) bool {
+	if r.Port == 0 {
+		return true
+	}
+	if dest.port == 0 {
+		return nameOnly
+	}
+	if r.Proto != "" && dest.proto != "" && r.Proto != dest.proto {
+		return false
+	}
+	return r.Port == dest.port
+}
+
+// parseRulePort parses a rule's port number
+func parseRulePort(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > 65535 {
+		return 0, fmt.Errorf("bad port %q", s)
+	}
+	return n, nil
+}
+
+// validRuleHost reports whether host is a plausible hostname
+func validRuleHost(host string) bool {
+	if host == "" {
+		return false
+	}
+	for _, c := range host {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '.', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeHost lowercases a hostname and drops a trailing dot
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
@@ -0,0 +1,260 @@
+package security
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/util"
+)
+
+// FirewallSession is a running session whose firewall rules can be
+// reloaded without restarting it
+type FirewallSession struct {
+	Container string        `json:"container"`
+	Project   string        `json:"project"`   // directory the session runs in
+	Extension string        `json:"extension"` // extension whose rules are layered in
+	PID       int           `json:"pid"`       // addt process running the session
+	Started   time.Time     `json:"started"`
+	Rules     FirewallRules `json:"rules"` // the rules in force
+}
+
+// firewallReloadResult is a session's answer to a reload request
+type firewallReloadResult struct {
+	Error string `json:"error,omitempty"`
+}
+
+// SessionDir returns the directory running sessions are registered in.
+// Unlike the firewall directory it is never mounted into containers, so an
+// agent can't change its own rules through it.
+func SessionDir() string {
+	return filepath.Join(util.GetAddtHome(), "sessions")
+}
+
+// sessionTokenKinds are the files session tokens are kept in, by suffix
+var sessionTokenKinds = []string{"api-token", "git-token"}
+
+// sessionToken returns a random token with a prefix for a container's
+// session; kind names the file a persistent container's token is kept in,
+// so it keeps the token it was created with
+func sessionToken(container, kind, prefix string, persistent bool) (string, error) {
+	path := filepath.Join(SessionDir(), container+"."+kind)
+	if persistent {
+		if data, err := os.ReadFile(path); err == nil {
+			if token := strings.TrimSpace(string(data)); strings.HasPrefix(token, prefix) {
+				return token, nil
+			}
+		}
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session token: %w", err)
+	}
+	token := prefix + hex.EncodeToString(b)
+	if persistent {
+		if err := os.MkdirAll(SessionDir(), 0700); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+			return "", err
+		}
+	}
+	return token, nil
+}
+
+// RemoveSessionTokens removes the tokens a persistent container keeps
+func RemoveSessionTokens(container string) {
+	for _, kind := range sessionTokenKinds {
+		os.Remove(filepath.Join(SessionDir(), container+"."+kind))
+	}
+}
+
+func sessionFile(container string) string {
+	return filepath.Join(SessionDir(), container+".json")
+}
+
+func reloadFile(container string) string {
+	return filepath.Join(SessionDir(), container+".reload")
+}
+
+func reloadedFile(container string) string {
+	return filepath.Join(SessionDir(), container+".reloaded")
+}
+
+// writeSessionFile replaces a file in the session directory so readers
+// never see part of it
+func writeSessionFile(path string, v interface{}) error {
+	if err := os.MkdirAll(SessionDir(), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// RegisterFirewallSession records a running session and the rules it
+// enforces, replacing an earlier record for its container
+func RegisterFirewallSession(s *FirewallSession) error {
+	return writeSessionFile(sessionFile(s.Container), s)
+}
+
+// UnregisterFirewallSession removes a session's record and any reload
+// left unanswered
+func UnregisterFirewallSession(container string) {
+	os.Remove(sessionFile(container))
+	os.Remove(reloadFile(container))
+	os.Remove(reloadedFile(container))
+}
+
+// FirewallSessions returns the running sessions in a project directory, or
+// all of them for "". Records of sessions whose process is gone are
+// removed.
+func FirewallSessions(project string) ([]*FirewallSession, error) {
+	entries, err := os.ReadDir(SessionDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sessions []*FirewallSession
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(SessionDir(), entry.Name()))
+		if err != nil {
+			continue
+		}
+		var s FirewallSession
+		if err := json.Unmarshal(data, &s); err != nil || s.Container == "" {
+			continue
+		}
+		if !isProcessRunning(s.PID) {
+			UnregisterFirewallSession(s.Container)
+			continue
+		}
+		if project == "" || s.Project == project {
+			sessions = append(sessions, &s)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Container < sessions[j].Container
+	})
+	return sessions, nil
+}
+
+// RequestFirewallReload asks a container's session to enforce new rules
+func RequestFirewallReload(container string, rules FirewallRules) error {
+	os.Remove(reloadedFile(container))
+	return writeSessionFile(reloadFile(container), rules)
+}
+
+// TakeFirewallReload returns, once, the rules a reload of a container's
+// session asked for since the last call
+func TakeFirewallReload(container string) (*FirewallRules, error) {
+	path := reloadFile(container)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(path)
+	var rules FirewallRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid firewall reload for %s: %w", container, err)
+	}
+	return &rules, nil
+}
+
+// ReportFirewallReload answers a reload request with its outcome
+func ReportFirewallReload(container string, reloadErr error) error {
+	var result firewallReloadResult
+	if reloadErr != nil {
+		result.Error = reloadErr.Error()
+	}
+	return writeSessionFile(reloadedFile(container), result)
+}
+
+// WaitFirewallReload waits for a container's session to answer a reload
+// request and returns the error it reported
+func WaitFirewallReload(container string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		data, err := os.ReadFile(reloadedFile(container))
+		if err == nil {
+			os.Remove(reloadedFile(container))
+			var result firewallReloadResult
+			if err := json.Unmarshal(data, &result); err != nil {
+				return fmt.Errorf("invalid reload result: %w", err)
+			}
+			if result.Error != "" {
+				return fmt.Errorf("%s", result.Error)
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			os.Remove(reloadFile(container))
+			return fmt.Errorf("no answer after %s", timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// DiffFirewallRules lists the rules new adds ("+") and removes ("-")
+// compared to old, per layer, e.g. "+ project allow example.com"
+func DiffFirewallRules(old, new FirewallRules) []string {
+	var diff []string
+	layers := []struct {
+		name     string
+		old, new FirewallLayer
+	}{
+		{"project", old.Project, new.Project},
+		{"global", old.Global, new.Global},
+		{"extension", old.Extension, new.Extension},
+	}
+	for _, l := range layers {
+		diff = append(diff, diffRuleList(l.name+" allow", l.old.Allowed, l.new.Allowed)...)
+		diff = append(diff, diffRuleList(l.name+" deny", l.old.Denied, l.new.Denied)...)
+	}
+	return diff
+}
+
+// diffRuleList returns the rules only in old ("-") and only in new ("+"),
+// in the order they are written
+func diffRuleList(label string, old, new []string) []string {
+	inOld := make(map[string]bool, len(old))
+	for _, r := range old {
+		inOld[r] = true
+	}
+	inNew := make(map[string]bool, len(new))
+	for _, r := range new {
+		inNew[r] = true
+	}
+	var diff []string
+	for _, r := range old {
+		if !inNew[r] {
+			diff = append(diff, fmt.Sprintf("- %s %s", label, r))
+		}
+	}
+	for _, r := range new {
+		if !inOld[r] {
+			diff = append(diff, fmt.Sprintf("+ %s %s", label, r))
+		}
+	}
+	return diff
+}
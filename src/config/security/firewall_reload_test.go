@@ -0,0 +1,91 @@
+package security
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFirewallSessions(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+
+	sessions := []*FirewallSession{
+		{Container: "addt-b", Project: "/work/app", PID: os.Getpid()},
+		{Container: "addt-a", Project: "/work/app", PID: os.Getpid()},
+		{Container: "addt-other", Project: "/work/other", PID: os.Getpid()},
+		{Container: "addt-gone", Project: "/work/app", PID: 999999999},
+	}
+	for _, s := range sessions {
+		if err := RegisterFirewallSession(s); err != nil {
+			t.Fatalf("RegisterFirewallSession() error = %v", err)
+		}
+	}
+
+	got, err := FirewallSessions("/work/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Container != "addt-a" || got[1].Container != "addt-b" {
+		t.Errorf("FirewallSessions(/work/app) = %+v, want addt-a and addt-b", got)
+	}
+	if _, err := os.Stat(sessionFile("addt-gone")); !os.IsNotExist(err) {
+		t.Error("record of a session whose process is gone should be removed")
+	}
+
+	UnregisterFirewallSession("addt-a")
+	if all, _ := FirewallSessions(""); len(all) != 2 {
+		t.Errorf("FirewallSessions(\"\") = %d sessions after unregistering, want 2", len(all))
+	}
+}
+
+func TestFirewallReload(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+
+	if rules, err := TakeFirewallReload("addt-test"); rules != nil || err != nil {
+		t.Errorf("TakeFirewallReload() before a request = %+v, %v", rules, err)
+	}
+	want := FirewallRules{Project: FirewallLayer{Allowed: []string{"example.com"}}}
+	if err := RequestFirewallReload("addt-test", want); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := TakeFirewallReload("addt-test")
+	if err != nil || rules == nil || rules.Project.Allowed[0] != "example.com" {
+		t.Fatalf("TakeFirewallReload() = %+v, %v", rules, err)
+	}
+	if again, _ := TakeFirewallReload("addt-test"); again != nil {
+		t.Error("reload request should be taken exactly once")
+	}
+
+	ReportFirewallReload("addt-test", errors.New("script failed"))
+	if err := WaitFirewallReload("addt-test", time.Second); err == nil || err.Error() != "script failed" {
+		t.Errorf("WaitFirewallReload() = %v, want the reported error", err)
+	}
+	if err := WaitFirewallReload("addt-test", 150*time.Millisecond); err == nil {
+		t.Error("WaitFirewallReload() without an answer should time out")
+	}
+}
+
+func TestDiffFirewallRules(t *testing.T) {
+	old := FirewallRules{
+		Project: FirewallLayer{Allowed: []string{"a.example", "b.example"}},
+		Global:  FirewallLayer{Denied: []string{"evil.example"}},
+	}
+	new := FirewallRules{
+		Project: FirewallLayer{Allowed: []string{"b.example", "c.example"}, Denied: []string{"a.example"}},
+		Global:  FirewallLayer{Denied: []string{"evil.example"}},
+	}
+	got := DiffFirewallRules(old, new)
+	want := []string{
+		"- project allow a.example",
+		"+ project allow c.example",
+		"+ project deny a.example",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("DiffFirewallRules() = %v, want %v", got, want)
+	}
+	if diff := DiffFirewallRules(old, old); len(diff) != 0 {
+		t.Errorf("DiffFirewallRules(same) = %v, want none", diff)
+	}
+}
@@ -0,0 +1,211 @@
+package security
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseFirewallRule(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string // Host/Wildcard/Net, Proto, Port, Negate
+	}{
+		{"GitHub.com.", "github.com  0 false"},
+		{"*.githubusercontent.com", ".githubusercontent.com  0 false"},
+		{"10.0.0.0/8", "10.0.0.0/8  0 false"},
+		{"192.168.1.10", "192.168.1.10/32  0 false"},
+		{"2001:db8::1", "2001:db8::1/128  0 false"},
+		{"registry.internal:5000", "registry.internal  5000 false"},
+		{"[2001:db8::/32]:443", "2001:db8::/32  443 false"},
+		{"!tcp/22", " tcp 22 true"},
+		{"udp/53", " udp 53 false"},
+		{"*:443", "  443 false"},
+	}
+	for _, tt := range tests {
+		rule, err := ParseFirewallRule(tt.rule)
+		if err != nil {
+			t.Errorf("ParseFirewallRule(%q) error = %v", tt.rule, err)
+			continue
+		}
+		target := rule.Host + rule.Wildcard
+		if rule.Net != nil {
+			target = rule.Net.String()
+		}
+		got := fmt.Sprintf("%s %s %d %v", target, rule.Proto, rule.Port, rule.Negate)
+		if got != tt.want {
+			t.Errorf("ParseFirewallRule(%q) = %q, want %q", tt.rule, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "!", "host:http", "host:70000", "10.0.0.0/33", "*.", "ex ample.com", "[::1", "[::1]443", "tcp/x"} {
+		if _, err := ParseFirewallRule(bad); err == nil {
+			t.Errorf("ParseFirewallRule(%q) = nil error, want an error", bad)
+		}
+	}
+}
+
+func TestFirewallRules_Decide(t *testing.T) {
+	rules := FirewallRules{
+		Project: FirewallLayer{
+			Allowed: []string{"*.githubusercontent.com", "registry.internal:5000", "10.0.0.0/8", "github.com:22"},
+			Denied:  []string{"10.0.0.5"},
+		},
+		Global: FirewallLayer{
+			Allowed: []string{"!tcp/22", "fd00::/8:8080", "[fd00::/8]:8443"},
+		},
+		Defaults: []string{"github.com"},
+	}
+	tests := []struct {
+		host      string
+		port      int
+		want      bool
+		wantLayer string
+		wantRule  string
+	}{
+		{"objects.githubusercontent.com", 443, true, "project", "*.githubusercontent.com"},
+		{"githubusercontent.com", 443, false, "none", ""},
+		{"registry.internal", 5000, true, "project", "registry.internal:5000"},
+		{"registry.internal", 443, false, "none", ""},
+		// A rule for one port allows looking the name up
+		{"registry.internal", 0, true, "project", "registry.internal:5000"},
+		{"10.1.2.3", 6379, true, "project", "10.0.0.0/8"},
+		{"10.0.0.5", 6379, false, "project", "10.0.0.5"},
+		// Project allows port 22 to github.com over the global exception
+		{"github.com", 22, true, "project", "github.com:22"},
+		{"github.com", 443, true, "defaults", "github.com"},
+		{"gitlab.com", 22, false, "global", "!tcp/22"},
+		// A port-only deny doesn't deny the name
+		{"gitlab.com", 0, false, "none", ""},
+		{"[fd00::1]", 8443, true, "global", "[fd00::/8]:8443"},
+		{"fd00::1", 8080, false, "none", ""},
+	}
+	for _, tt := range tests {
+		d := rules.Decide(tt.host, tt.port, "tcp")
+		if d.Allowed != tt.want || d.Layer != tt.wantLayer || d.Rule != tt.wantRule {
+			t.Errorf("Decide(%s, %d) = %+v, want %v, %q, %q", tt.host, tt.port, d, tt.want, tt.wantLayer, tt.wantRule)
+		}
+	}
+}
+
+func TestFirewallRules_AddressRules(t *testing.T) {
+	rules := FirewallRules{
+		Project: FirewallLayer{
+			Allowed: []string{"github.com", "10.0.0.0/8:5432", "!udp/53"},
+			Denied:  []string{"10.0.0.5", "*.example.com"},
+		},
+		Global:    FirewallLayer{Allowed: []string{"*:443", "fd00::/8"}},
+		Extension: FirewallLayer{Denied: []string{"tcp/25"}},
+		Defaults:  []string{"api.anthropic.com"},
+	}
+	want := []string{
+		"project drop - - 10.0.0.5/32",
+		"project drop udp 53 -",
+		"project accept - 5432 10.0.0.0/8",
+		"global accept - 443 -",
+		"global accept - - fd00::/8",
+		"extension drop tcp 25 -",
+	}
+	if got := rules.AddressRules(); !reflect.DeepEqual(got, want) {
+		t.Errorf("AddressRules() = %q, want %q", got, want)
+	}
+}
+
+// TestFirewallRules_AddressRulesOrder checks that the packet filter the
+// script builds from AddressRules decides like DecideAddress
+func TestFirewallRules_AddressRulesOrder(t *testing.T) {
+	rules := FirewallRules{
+		Project: FirewallLayer{Allowed: []string{"internal.example.com", "!tcp/22"}},
+		Global: FirewallLayer{
+			Allowed: []string{"github.com", "registry.example.com:5000"},
+			Denied:  []string{"10.0.0.0/8"},
+		},
+		Extension: FirewallLayer{Denied: []string{"tcp/25"}},
+		Defaults:  []string{"api.anthropic.com"},
+	}
+	lines := rules.AddressRules()
+
+	tests := []struct {
+		host string
+		ip   string
+		port int
+	}{
+		{"internal.example.com", "10.1.2.3", 443},
+		{"internal.example.com", "10.1.2.3", 22},
+		{"github.com", "10.0.0.1", 443},
+		{"github.com", "140.82.112.3", 443},
+		{"github.com", "140.82.112.3", 22},
+		{"registry.example.com", "192.0.2.1", 5000},
+		{"registry.example.com", "192.0.2.1", 443},
+		{"api.anthropic.com", "10.0.0.2", 443},
+		{"api.anthropic.com", "160.79.104.10", 25},
+		{"api.anthropic.com", "160.79.104.10", 443},
+	}
+	for _, tt := range tests {
+		ip := net.ParseIP(tt.ip)
+		want := rules.DecideAddress(tt.host, ip, tt.port, "tcp").Allowed
+		if got := scriptVerdict(t, rules, lines, tt.host, ip, tt.port, "tcp"); got != want {
+			t.Errorf("script allows %s (%s) on %d = %v, DecideAddress = %v", tt.host, tt.ip, tt.port, got, want)
+		}
+	}
+}
+
+// scriptVerdict models init-firewall.sh: the DNS resolver adds the
+// addresses an allowed name resolves to under the layer that allowed it,
+// and each layer's address rules are checked before its addresses
+func scriptVerdict(t *testing.T, rules FirewallRules, lines []string, host string, ip net.IP, port int, proto string) bool {
+	t.Helper()
+	name := rules.Decide(host, 0, "")
+	namePort := 0
+	if rule, err := ParseFirewallRule(name.Rule); err == nil {
+		namePort = rule.Port
+	}
+	for _, layer := range []string{"project", "global", "extension", "defaults"} {
+		for _, line := range lines {
+			f := strings.Fields(line)
+			if len(f) != 5 {
+				t.Fatalf("malformed address rule %q", line)
+			}
+			if f[0] != layer || (f[2] != "-" && f[2] != proto) || (f[3] != "-" && f[3] != strconv.Itoa(port)) {
+				continue
+			}
+			if f[4] != "-" {
+				if _, network, err := net.ParseCIDR(f[4]); err != nil || !network.Contains(ip) {
+					continue
+				}
+			}
+			return f[1] == "accept"
+		}
+		if name.Allowed && name.Layer == layer && (namePort == 0 || namePort == port) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFirewallRule_Broad(t *testing.T) {
+	for rule, want := range map[string]bool{
+		"*":               true,
+		"*:443":           true,
+		"tcp/443":         true,
+		"*.com":           true,
+		"*.example.com":   false,
+		"example.com":     false,
+		"0.0.0.0/0":       true,
+		"10.0.0.0/8":      true,
+		"10.1.0.0/24":     false,
+		"2001:db8::/32":   true,
+		"2001:db8:1::/48": false,
+	} {
+		r, err := ParseFirewallRule(rule)
+		if err != nil {
+			t.Fatalf("ParseFirewallRule(%q) error = %v", rule, err)
+		}
+		if got := r.Broad(); got != want {
+			t.Errorf("%q.Broad() = %v, want %v", rule, got, want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package security
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jedi4ever/addt/util"
+)
+
+var gitCredentialLogger = util.Log("gitcred")
+
+// GitCredentialContainerPort is the port the git credential helper is
+// bridged to on the container's loopback interface
+const GitCredentialContainerPort = 4875
+
+// GitCredentialTokenPrefix starts the session tokens the container's git
+// credential helper sends along with its requests
+const GitCredentialTokenPrefix = "addt-git-"
+
+// gitCredentialTimeout bounds reading a request and answering it, which
+// may include minting a GitHub App token
+const gitCredentialTimeout = 30 * time.Second
+
+// GitCredentialHelper is a host-side git credential helper that answers
+// 'git credential fill' for GitHub repos a session may use, so the token
+// never sits in the container's environment. Requests carry the session's
+// token; credentials for other repos are refused with quit=1, which makes
+// git give up rather than prompt. Every answer is audit logged. Without
+// an App the answer is githubToken itself, which works for every repo it's
+// authorized for: the repo check only keeps git from using it elsewhere.
+type GitCredentialHelper struct {
+	container   string
+	token       string
+	repos       []string // allowed owner/repo, or owner/* patterns
+	githubToken string   // served when app is nil
+	app         *GitHubApp
+	listener    net.Listener
+	mu          sync.Mutex
+	running     bool
+}
+
+// NewGitCredentialHelper creates a credential helper serving githubToken,
+// or tokens minted by app when it isn't nil, for repos
+func NewGitCredentialHelper(container, token string, repos []string, githubToken string, app *GitHubApp) *GitCredentialHelper {
+	return &GitCredentialHelper{
+		container:   container,
+		token:       token,
+		repos:       repos,
+		githubToken: githubToken,
+		app:         app,
+	}
+}
+
+// Start listens on addr, e.g. "127.0.0.1:0"
+func (h *GitCredentialHelper) Start(addr string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.running {
+		return nil
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	h.listener = l
+	h.running = true
+	go h.acceptLoop()
+	return nil
+}
+
+// Stop stops serving and revokes the GitHub App token it handed out
+func (h *GitCredentialHelper) Stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.running {
+		return
+	}
+	h.running = false
+	h.listener.Close()
+	if h.app != nil {
+		if err := h.app.Revoke(); err != nil {
+			gitCredentialLogger.Debugf("%s: failed to revoke GitHub App token: %v", h.container, err)
+		}
+	}
+}
+
+// Port returns the port the helper listens on (only valid after Start)
+func (h *GitCredentialHelper) Port() int {
+	if addr, ok := h.listener.Addr().(*net.TCPAddr); ok {
+		return addr.Port
+	}
+	return 0
+}
+
+// Token returns the session token requests must carry
+func (h *GitCredentialHelper) Token() string {
+	return h.token
+}
+
+// Repos returns the repos credentials are served for
+func (h *GitCredentialHelper) Repos() []string {
+	return h.repos
+}
+
+func (h *GitCredentialHelper) acceptLoop() {
+	for {
+		conn, err := h.listener.Accept()
+		if err != nil {
+			h.mu.Lock()
+			running := h.running
+			h.mu.Unlock()
+			if !running {
+				return
+			}
+			continue
+		}
+		go h.handleConnection(conn)
+	}
+}
+
+// handleConnection reads a request in git's credential format, key=value
+// lines up to a blank line or the end, and writes the answer
+func (h *GitCredentialHelper) handleConnection(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(gitCredentialTimeout))
+
+	req := make(map[string]string)
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			req[key] = value
+		}
+	}
+	fmt.Fprint(conn, h.answer(req))
+}
+
+// answer returns the credential for a request, or quit=1
+func (h *GitCredentialHelper) answer(req map[string]string) string {
+	const refuse = "quit=1\n"
+	repo := strings.TrimSuffix(strings.Trim(req["path"], "/"), ".git")
+	subject := req["host"] + "/" + repo
+
+	if subtle.ConstantTimeCompare([]byte(req["token"]), []byte(h.token)) != 1 {
+		gitCredentialLogger.Warningf("%s: refused %s without the session's token", h.container, subject)
+		LogGitCredential(h.container, subject, false, "wrong session token")
+		return refuse
+	}
+	if req["protocol"] != "https" || req["host"] != "github.com" {
+		LogGitCredential(h.container, req["protocol"]+"://"+subject, false, "not a github.com https remote")
+		return refuse
+	}
+	if repo == "" {
+		// Without credential.useHttpPath git doesn't say which repo
+		LogGitCredential(h.container, subject, false, "no repo path")
+		return refuse
+	}
+	if !h.allowed(repo) {
+		gitCredentialLogger.Infof("%s: refused credentials for %s", h.container, subject)
+		LogGitCredential(h.container, subject, false, "not in github.scope_repos or the workspace remote")
+		return refuse
+	}
+
+	password, reason := h.githubToken, "GH_TOKEN, not scoped to the repo"
+	if h.app != nil {
+		token, expires, err := h.app.Token()
+		if err != nil {
+			gitCredentialLogger.Warningf("%s: failed to mint GitHub App token: %v", h.container, err)
+			LogGitCredential(h.container, subject, false, fmt.Sprintf("GitHub App token: %v", err))
+			return refuse
+		}
+		password, reason = token, "GitHub App token, expires "+expires.Local().Format("15:04")
+	}
+	gitCredentialLogger.Debugf("%s: credentials for %s", h.container, subject)
+	LogGitCredential(h.container, subject, true, reason)
+	return fmt.Sprintf("protocol=https\nhost=github.com\nusername=x-access-token\npassword=%s\n", password)
+}
+
+// allowed checks a repo against the allowed repos, ignoring case as
+// GitHub does
+func (h *GitCredentialHelper) allowed(repo string) bool {
+	repo = strings.ToLower(repo)
+	for _, pattern := range h.repos {
+		if ok, _ := path.Match(strings.ToLower(pattern), repo); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// GitCredentialToken returns the token a container's credential helper
+// sends. A persistent container keeps the token it was created with.
+func GitCredentialToken(container string, persistent bool) (string, error) {
+	return sessionToken(container, "git-token", GitCredentialTokenPrefix, persistent)
+}
+
+// GitHubRepoFromURL returns the owner/repo of a github.com remote URL, or
+// "" for other remotes
+func GitHubRepoFromURL(url string) string {
+	url = strings.TrimSpace(url)
+	for _, prefix := range []string{"https://github.com/", "http://github.com/", "ssh://git@github.com/", "git@github.com:"} {
+		if strings.HasPrefix(url, prefix) {
+			repo := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(url, prefix), "/"), ".git")
+			if strings.Count(repo, "/") == 1 {
+				return repo
+			}
+		}
+	}
+	return ""
+}
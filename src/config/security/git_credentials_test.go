@@ -0,0 +1,145 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// askGitCredentials sends a request the way the container's helper does
+// and returns the answer
+func askGitCredentials(t *testing.T, h *GitCredentialHelper, token, path string) string {
+	t.Helper()
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", h.Port()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "token=%s\nprotocol=https\nhost=github.com\npath=%s\n", token, path)
+	conn.(*net.TCPConn).CloseWrite()
+	answer, _ := io.ReadAll(conn)
+	return string(answer)
+}
+
+func TestGitCredentialHelper(t *testing.T) {
+	h := NewGitCredentialHelper("addt-test", "addt-git-token", []string{"ourorg/app", "ourorg/lib-*"}, "ghp_real", nil)
+	if err := h.Start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer h.Stop()
+
+	tests := []struct {
+		token, path string
+		issued      bool
+	}{
+		{"addt-git-token", "ourorg/app.git", true},
+		{"addt-git-token", "OurOrg/App", true},
+		{"addt-git-token", "ourorg/lib-json.git", true},
+		{"addt-git-token", "ourorg/secrets.git", false},
+		{"addt-git-token", "", false},
+		{"addt-git-guess", "ourorg/app.git", false},
+	}
+	for _, tt := range tests {
+		answer := askGitCredentials(t, h, tt.token, tt.path)
+		if issued := strings.Contains(answer, "password=ghp_real\n"); issued != tt.issued {
+			t.Errorf("token %s, path %q: answer %q, want issued = %v", tt.token, tt.path, answer, tt.issued)
+		}
+		if !tt.issued && answer != "quit=1\n" {
+			t.Errorf("token %s, path %q: answer %q, want quit=1", tt.token, tt.path, answer)
+		}
+	}
+}
+
+func TestGitCredentialHelper_GitHubApp(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keyFile := filepath.Join(t.TempDir(), "app.pem")
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600)
+
+	minted, revoked := 0, false
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/app/installations/42/access_tokens":
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ey") {
+				t.Errorf("Authorization = %q, want the App's JWT", r.Header.Get("Authorization"))
+			}
+			var body struct {
+				Repositories []string          `json:"repositories"`
+				Permissions  map[string]string `json:"permissions"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if strings.Join(body.Repositories, ",") != "app" || body.Permissions["contents"] != "write" {
+				t.Errorf("token request = %+v, want repo app with contents:write", body)
+			}
+			minted++
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"token":"ghs_minted","expires_at":%q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+		case r.Method == http.MethodDelete && r.URL.Path == "/installation/token":
+			revoked = r.Header.Get("Authorization") == "Bearer ghs_minted"
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer github.Close()
+
+	app, err := NewGitHubApp("7", "42", keyFile, []string{"ourorg/app"}, nil)
+	if err != nil {
+		t.Fatalf("NewGitHubApp() error = %v", err)
+	}
+	app.APIURL = github.URL
+
+	h := NewGitCredentialHelper("addt-test", "addt-git-token", []string{"ourorg/app"}, "", app)
+	if err := h.Start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if answer := askGitCredentials(t, h, "addt-git-token", "ourorg/app.git"); !strings.Contains(answer, "password=ghs_minted\n") {
+			t.Errorf("answer = %q, want the minted token", answer)
+		}
+	}
+	h.Stop()
+	if minted != 1 {
+		t.Errorf("minted %d tokens, want 1 reused until it expires", minted)
+	}
+	if !revoked {
+		t.Error("token not revoked when the helper stopped")
+	}
+}
+
+func TestNewGitHubApp_Invalid(t *testing.T) {
+	if _, err := NewGitHubApp("7", "", "app.pem", nil, nil); err == nil {
+		t.Error("NewGitHubApp() without an installation should fail")
+	}
+	keyFile := filepath.Join(t.TempDir(), "app.pem")
+	os.WriteFile(keyFile, []byte("not a key"), 0600)
+	if _, err := NewGitHubApp("7", "42", keyFile, nil, nil); err == nil {
+		t.Error("NewGitHubApp() with an invalid key should fail")
+	}
+}
+
+func TestGitHubRepoFromURL(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/ourorg/app.git\n": "ourorg/app",
+		"git@github.com:ourorg/app.git":       "ourorg/app",
+		"ssh://git@github.com/ourorg/app":     "ourorg/app",
+		"https://gitlab.com/ourorg/app.git":   "",
+		"https://github.com/ourorg":           "",
+	}
+	for url, want := range tests {
+		if got := GitHubRepoFromURL(url); got != want {
+			t.Errorf("GitHubRepoFromURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
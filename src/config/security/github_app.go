@@ -0,0 +1,203 @@
+package security
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gitHubAppRefresh is how long before expiry a minted token is replaced
+const gitHubAppRefresh = 5 * time.Minute
+
+// GitHubApp mints installation tokens of a GitHub App, limited to a
+// session's repos and permissions. GitHub expires them after an hour; the
+// git credential helper revokes the last one when the session ends.
+type GitHubApp struct {
+	ID             string
+	InstallationID string
+	Key            *rsa.PrivateKey
+	Repos          []string          // repo names the tokens are limited to; none for all of the installation's
+	Permissions    map[string]string // e.g. {"contents": "write"}
+	APIURL         string            // https://api.github.com unless set
+	client         *http.Client
+	mu             sync.Mutex
+	token          string
+	expires        time.Time
+}
+
+// NewGitHubApp loads a GitHub App's private key and limits its tokens to
+// the given owner/repo entries, which must all belong to the installation's
+// owner, and "<permission>:<access>" permissions (contents:write if none)
+func NewGitHubApp(id, installationID, keyFile string, repos, permissions []string) (*GitHubApp, error) {
+	if id == "" || installationID == "" || keyFile == "" {
+		return nil, fmt.Errorf("a GitHub App needs github.app_id, github.app_installation_id and github.app_key_file")
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GitHub App key: %w", err)
+	}
+	key, err := parseRSAKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub App key %s: %w", keyFile, err)
+	}
+
+	app := &GitHubApp{
+		ID:             id,
+		InstallationID: installationID,
+		Key:            key,
+		Permissions:    make(map[string]string),
+		APIURL:         "https://api.github.com",
+		client:         &http.Client{Timeout: 20 * time.Second},
+	}
+	if len(permissions) == 0 {
+		permissions = []string{"contents:write"}
+	}
+	for _, p := range permissions {
+		name, access, ok := strings.Cut(strings.TrimSpace(p), ":")
+		if !ok || name == "" || (access != "read" && access != "write") {
+			return nil, fmt.Errorf("invalid GitHub App permission %q, want <permission>:read or :write", p)
+		}
+		app.Permissions[name] = access
+	}
+	for _, r := range repos {
+		_, name, _ := strings.Cut(r, "/")
+		if strings.ContainsAny(name, "*?[") {
+			// A pattern can't be listed; the token covers the installation
+			// and the credential helper still checks each repo
+			app.Repos = nil
+			break
+		}
+		app.Repos = append(app.Repos, name)
+	}
+	return app, nil
+}
+
+// parseRSAKey parses a PEM encoded PKCS#1 or PKCS#8 RSA private key
+func parseRSAKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("not an RSA key")
+	}
+	return key, nil
+}
+
+// jwt returns the App's JSON Web Token, valid for ten minutes
+func (a *GitHubApp) jwt() (string, error) {
+	now := time.Now()
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(), // allow for clock drift
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.Key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + enc.EncodeToString(sig), nil
+}
+
+// Token returns an installation token and when it expires, minting a new
+// one when the last is about to expire
+func (a *GitHubApp) Token() (string, time.Time, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Until(a.expires) > gitHubAppRefresh {
+		return a.token, a.expires, nil
+	}
+
+	jwt, err := a.jwt()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	body, err := json.Marshal(struct {
+		Repositories []string          `json:"repositories,omitempty"`
+		Permissions  map[string]string `json:"permissions"`
+	}{a.Repos, a.Permissions})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	url := fmt.Sprintf("%s/app/installations/%s/access_tokens", strings.TrimSuffix(a.APIURL, "/"), a.InstallationID)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		var msg struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&msg)
+		return "", time.Time{}, fmt.Errorf("GitHub returned %s: %s", resp.Status, msg.Message)
+	}
+
+	var result struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || result.Token == "" {
+		return "", time.Time{}, fmt.Errorf("invalid token response from GitHub")
+	}
+	a.token, a.expires = result.Token, result.ExpiresAt
+	return a.token, a.expires, nil
+}
+
+// Revoke revokes the last minted token, if any
+func (a *GitHubApp) Revoke() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token == "" {
+		return nil
+	}
+	req, err := http.NewRequest(http.MethodDelete, strings.TrimSuffix(a.APIURL, "/")+"/installation/token", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	a.token = ""
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("GitHub returned %s", resp.Status)
+	}
+	return nil
+}
@@ -23,8 +23,10 @@ type GPGProxyAgent struct {
 	mu             sync.Mutex
 	running        bool
 	wg             sync.WaitGroup
-	useTCP         bool // listen on TCP instead of Unix socket (macOS + podman)
-	tcpPort        int  // TCP port when useTCP is true
+	useTCP         bool          // listen on TCP instead of Unix socket (macOS + podman)
+	tcpPort        int           // TCP port when useTCP is true
+	tcpHost        string        // address the TCP listener binds
+	approver       *SignApprover // asks before each signature (gpg.sign_policy confirm)
 }
 
 // NewGPGProxyAgent creates a new GPG proxy agent
@@ -77,13 +79,15 @@ func normalizeKeyIDs(keyIDs []string) []string {
 	return normalized
 }
 
-// NewGPGProxyAgentTCP creates a GPG proxy agent that listens on TCP.
-// Used on macOS where podman can't mount Unix sockets from the host.
-func NewGPGProxyAgentTCP(upstreamSocket string, allowedKeyIDs []string) (*GPGProxyAgent, error) {
+// NewGPGProxyAgentTCP creates a GPG proxy agent that listens on TCP on
+// host, the address host.docker.internal leads to. Used on macOS where
+// podman can't mount Unix sockets from the host.
+func NewGPGProxyAgentTCP(upstreamSocket, host string, allowedKeyIDs []string) (*GPGProxyAgent, error) {
 	return &GPGProxyAgent{
 		upstreamSocket: upstreamSocket,
 		allowedKeyIDs:  normalizeKeyIDs(allowedKeyIDs),
 		useTCP:         true,
+		tcpHost:        host,
 	}, nil
 }
 
@@ -103,9 +107,9 @@ func (p *GPGProxyAgent) Start() error {
 
 	var listener net.Listener
 	if p.useTCP {
-		// TCP mode: must bind 0.0.0.0 because containers connect via
-		// host.docker.internal which resolves to a non-loopback IP
-		l, err := net.Listen("tcp", "0.0.0.0:0")
+		// TCP mode: containers connect via host.docker.internal, so
+		// bind only the address it leads to, not every interface
+		l, err := net.Listen("tcp", net.JoinHostPort(p.tcpHost, "0"))
 		if err != nil {
 			return fmt.Errorf("failed to listen on TCP: %w", err)
 		}
@@ -167,6 +171,13 @@ func (p *GPGProxyAgent) SocketPath() string {
 	return p.proxySocket
 }
 
+// SetApprover makes each signature of an allowed key wait for approval
+func (p *GPGProxyAgent) SetApprover(approver *SignApprover) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.approver = approver
+}
+
 // SocketDir returns the directory containing the proxy socket
 func (p *GPGProxyAgent) SocketDir() string {
 	return filepath.Dir(p.proxySocket)
@@ -203,19 +214,23 @@ func (p *GPGProxyAgent) handleConnection(clientConn net.Conn) {
 	}
 	defer upstreamConn.Close()
 
+	p.mu.Lock()
+	approver := p.approver
+	p.mu.Unlock()
+
 	// If no filtering, just proxy everything
-	if len(p.allowedKeyIDs) == 0 {
+	if len(p.allowedKeyIDs) == 0 && approver == nil {
 		go io.Copy(upstreamConn, clientConn)
 		io.Copy(clientConn, upstreamConn)
 		return
 	}
 
 	// With filtering, we need to intercept and check commands
-	p.proxyWithFiltering(clientConn, upstreamConn)
+	p.proxyWithFiltering(clientConn, upstreamConn, approver)
 }
 
 // proxyWithFiltering intercepts Assuan protocol commands
-func (p *GPGProxyAgent) proxyWithFiltering(client, upstream net.Conn) {
+func (p *GPGProxyAgent) proxyWithFiltering(client, upstream net.Conn, approver *SignApprover) {
 	// Read the initial OK from gpg-agent
 	upstreamReader := bufio.NewReader(upstream)
 	clientWriter := bufio.NewWriter(client)
@@ -259,7 +274,20 @@ func (p *GPGProxyAgent) proxyWithFiltering(client, upstream net.Conn) {
 				clientWriter.Flush()
 				continue
 			}
-			LogGPGSign(currentKeyID, true, "")
+			if approver != nil {
+				req := SignRequest{Kind: "gpg", Key: currentKeyID}
+				if req.Key == "" {
+					req.Key = "unknown"
+				}
+				if !approver.Approve(req) {
+					// GPG_ERR_CANCELED from gpg-agent, as when pinentry is dismissed
+					clientWriter.WriteString("ERR 67108963 Signing not confirmed\n")
+					clientWriter.Flush()
+					continue
+				}
+			} else {
+				LogGPGSign(currentKeyID, true, "")
+			}
 		}
 
 		// Check PKDECRYPT operation
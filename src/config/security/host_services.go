@@ -0,0 +1,188 @@
+package security
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jedi4ever/addt/util"
+)
+
+var hostServiceLogger = util.Log("hostservice")
+
+// HostServiceDomain is the domain host services are reachable under in a
+// container, e.g. postgres.host.addt
+const HostServiceDomain = "host.addt"
+
+// hostServiceDialTimeout bounds connecting to a service on the host
+const hostServiceDialTimeout = 5 * time.Second
+
+var hostServiceName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// HostService is a service listening on the host's loopback interface that
+// a container may reach by name
+type HostService struct {
+	Name string // e.g. "postgres", reachable as postgres.host.addt
+	Port int    // port on the host, and under the name in the container
+}
+
+// Hostname returns the name the container reaches the service by
+func (s HostService) Hostname() string {
+	return s.Name + "." + HostServiceDomain
+}
+
+// String formats the service as in ports.host_services, "postgres:5432"
+func (s HostService) String() string {
+	return s.Name + ":" + strconv.Itoa(s.Port)
+}
+
+// ParseHostServices parses ports.host_services entries, "<name>:<port>"
+// such as "postgres:5432", sorted by name. Names are DNS labels.
+func ParseHostServices(entries []string) ([]HostService, error) {
+	var services []HostService
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, portStr, ok := strings.Cut(entry, ":")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || !hostServiceName.MatchString(name) {
+			return nil, fmt.Errorf("invalid host service %q (use <name>:<port>, e.g. postgres:5432)", entry)
+		}
+		port, err := strconv.Atoi(strings.TrimSpace(portStr))
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port in host service %q", entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("host service %q is listed twice", name)
+		}
+		seen[name] = true
+		services = append(services, HostService{Name: name, Port: port})
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services, nil
+}
+
+// HostServiceForwarder forwards a container's connections to services on
+// the host's loopback interface, with a listener per service, so only the
+// ports named in ports.host_services are reachable. Every connection is
+// logged.
+type HostServiceForwarder struct {
+	container string
+	services  []HostService
+	listeners map[string]net.Listener // service name → listener
+	mu        sync.Mutex
+	running   bool
+}
+
+// NewHostServiceForwarder creates a forwarder for a container's host
+// services
+func NewHostServiceForwarder(container string, services []HostService) *HostServiceForwarder {
+	return &HostServiceForwarder{
+		container: container,
+		services:  services,
+		listeners: make(map[string]net.Listener),
+	}
+}
+
+// Start listens for each service on host, on the port ports maps its name
+// to, or a free port when it's missing. Nothing is left listening when a
+// listener can't be created.
+func (f *HostServiceForwarder) Start(host string, ports map[string]int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.running {
+		return nil
+	}
+	for _, s := range f.services {
+		addr := net.JoinHostPort(host, strconv.Itoa(ports[s.Name]))
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, l := range f.listeners {
+				l.Close()
+			}
+			f.listeners = make(map[string]net.Listener)
+			return fmt.Errorf("failed to listen on %s for host service %s: %w", addr, s.Name, err)
+		}
+		f.listeners[s.Name] = l
+	}
+	f.running = true
+	for _, s := range f.services {
+		go f.serve(f.listeners[s.Name], s)
+	}
+	for _, s := range f.services {
+		LogHostServiceExposed(f.container, s.Hostname(), hostServiceTarget(s))
+	}
+	return nil
+}
+
+// Stop closes the listeners; forwarded connections end with the container
+func (f *HostServiceForwarder) Stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.running {
+		return
+	}
+	f.running = false
+	for _, l := range f.listeners {
+		l.Close()
+	}
+}
+
+// Services returns the services the forwarder serves
+func (f *HostServiceForwarder) Services() []HostService {
+	return f.services
+}
+
+// Port returns the port a service is forwarded from (only valid after
+// Start)
+func (f *HostServiceForwarder) Port(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if l, ok := f.listeners[name]; ok {
+		return l.Addr().(*net.TCPAddr).Port
+	}
+	return 0
+}
+
+func (f *HostServiceForwarder) serve(l net.Listener, s HostService) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go f.forward(conn, s)
+	}
+}
+
+// forward connects a container's connection to the service on the host
+func (f *HostServiceForwarder) forward(client net.Conn, s HostService) {
+	defer client.Close()
+
+	target := hostServiceTarget(s)
+	upstream, err := net.DialTimeout("tcp", target, hostServiceDialTimeout)
+	LogHostServiceConnect(f.container, s.Hostname(), target, err)
+	if err != nil {
+		hostServiceLogger.Debugf("%s: %s unreachable: %v", f.container, s.Hostname(), err)
+		return
+	}
+	defer upstream.Close()
+	hostServiceLogger.Debugf("%s: forwarding %s from %s to %s", f.container, s.Hostname(), client.RemoteAddr(), target)
+	pipe(client, bufio.NewReader(client), upstream)
+}
+
+// hostServiceTarget is where a service listens on the host
+func hostServiceTarget(s HostService) string {
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(s.Port))
+}
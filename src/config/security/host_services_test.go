@@ -0,0 +1,104 @@
+package security
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHostServices(t *testing.T) {
+	services, err := ParseHostServices([]string{"redis:6379", " Postgres : 5432 ", ""})
+	if err != nil {
+		t.Fatalf("ParseHostServices() error = %v", err)
+	}
+	want := []HostService{{Name: "postgres", Port: 5432}, {Name: "redis", Port: 6379}}
+	if len(services) != len(want) {
+		t.Fatalf("ParseHostServices() = %v, want %v", services, want)
+	}
+	for i := range want {
+		if services[i] != want[i] {
+			t.Errorf("service %d = %v, want %v", i, services[i], want[i])
+		}
+	}
+	if got := services[0].Hostname(); got != "postgres.host.addt" {
+		t.Errorf("Hostname() = %q, want postgres.host.addt", got)
+	}
+
+	for _, entry := range []string{"postgres", "postgres:0", "postgres:70000", "-db:5432", "my_db:5432", "a.b:80"} {
+		if _, err := ParseHostServices([]string{entry}); err == nil {
+			t.Errorf("ParseHostServices(%q) should fail", entry)
+		}
+	}
+	if _, err := ParseHostServices([]string{"db:5432", "db:5433"}); err == nil {
+		t.Error("ParseHostServices() should reject a name listed twice")
+	}
+}
+
+func TestHostServiceForwarder(t *testing.T) {
+	// An echo service on the host's loopback interface
+	service, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+	go func() {
+		for {
+			conn, err := service.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	port := service.Addr().(*net.TCPAddr).Port
+	forwarder := NewHostServiceForwarder("addt-test", []HostService{{Name: "echo", Port: port}})
+	if err := forwarder.Start("127.0.0.1", nil); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer forwarder.Stop()
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(forwarder.Port("echo"))), time.Second)
+	if err != nil {
+		t.Fatalf("dial forwarder: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte("ping\n")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("read through forwarder: %v", err)
+	}
+	if string(buf) != "ping\n" {
+		t.Errorf("echo through forwarder = %q, want %q", buf, "ping\n")
+	}
+
+	if forwarder.Port("other") != 0 {
+		t.Error("Port() of an unknown service should be 0")
+	}
+}
+
+func TestHostServiceForwarder_StartFailsCleanly(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	forwarder := NewHostServiceForwarder("addt-test", []HostService{{Name: "a", Port: 1}, {Name: "b", Port: 2}})
+	err = forwarder.Start("127.0.0.1", map[string]int{"b": taken.Addr().(*net.TCPAddr).Port})
+	if err == nil || !strings.Contains(err.Error(), "host service b") {
+		t.Fatalf("Start() error = %v, want one naming service b", err)
+	}
+	if forwarder.Port("a") != 0 {
+		t.Error("listener for a left open after Start failed")
+	}
+}
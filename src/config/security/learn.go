@@ -0,0 +1,216 @@
+package security
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jedi4ever/addt/util"
+)
+
+// LearnedDestination is a destination a container tried to reach while
+// the firewall was learning
+type LearnedDestination struct {
+	Host     string    `json:"host"`
+	IPs      []string  `json:"ips,omitempty"`
+	Ports    []int     `json:"ports,omitempty"`
+	Via      []string  `json:"via"`   // dns, connect, sni, http
+	Count    int       `json:"count"` // lookups and connections
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// LearnSession holds what learn sessions in a project observed, waiting
+// for review
+type LearnSession struct {
+	Project      string               `json:"project"`
+	Extension    string               `json:"extension,omitempty"`
+	Destinations []LearnedDestination `json:"destinations"`
+}
+
+// FirewallLearner records the destinations the egress proxy and DNS
+// resolver see
+type FirewallLearner struct {
+	mu    sync.Mutex
+	dests map[string]*LearnedDestination
+}
+
+// NewFirewallLearner creates an empty learner
+func NewFirewallLearner() *FirewallLearner {
+	return &FirewallLearner{dests: make(map[string]*LearnedDestination)}
+}
+
+// Observe records an attempt to reach host; port is "" for DNS lookups
+func (l *FirewallLearner) Observe(host, port, via string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	d := l.destination(host)
+	d.Count++
+	d.LastSeen = time.Now().UTC()
+	d.Via = addUnique(d.Via, via)
+	if n, err := strconv.Atoi(port); err == nil {
+		d.Ports = addUniqueInt(d.Ports, n)
+	}
+}
+
+// ObserveIP records an address host resolved or connected to
+func (l *FirewallLearner) ObserveIP(host, ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	d := l.destination(host)
+	d.IPs = addUnique(d.IPs, ip)
+}
+
+func (l *FirewallLearner) destination(host string) *LearnedDestination {
+	host = normalizeHost(host)
+	d, ok := l.dests[host]
+	if !ok {
+		d = &LearnedDestination{Host: host}
+		l.dests[host] = d
+	}
+	return d
+}
+
+// Destinations returns what was observed, most attempted first
+func (l *FirewallLearner) Destinations() []LearnedDestination {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	dests := make([]LearnedDestination, 0, len(l.dests))
+	for _, d := range l.dests {
+		dests = append(dests, *d)
+	}
+	sortDestinations(dests)
+	return dests
+}
+
+// LearnFile returns where learn sessions for a project directory are kept
+func LearnFile(projectDir string) string {
+	if abs, err := filepath.Abs(projectDir); err == nil {
+		projectDir = abs
+	}
+	hash := md5.Sum([]byte(projectDir))
+	name := fmt.Sprintf("%s-%x.json", filepath.Base(projectDir), hash[:4])
+	return filepath.Join(util.GetAddtHome(), "firewall", "learned", name)
+}
+
+// LoadLearnSession loads a project's learned destinations; a project
+// without any returns an empty session
+func LoadLearnSession(projectDir string) (*LearnSession, error) {
+	session := &LearnSession{Project: projectDir}
+	data, err := os.ReadFile(LearnFile(projectDir))
+	if os.IsNotExist(err) {
+		return session, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, session); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", LearnFile(projectDir), err)
+	}
+	return session, nil
+}
+
+// Merge adds newly observed destinations to the session
+func (s *LearnSession) Merge(dests []LearnedDestination) {
+	index := make(map[string]int, len(s.Destinations))
+	for i, d := range s.Destinations {
+		index[d.Host] = i
+	}
+	for _, d := range dests {
+		i, ok := index[d.Host]
+		if !ok {
+			index[d.Host] = len(s.Destinations)
+			s.Destinations = append(s.Destinations, d)
+			continue
+		}
+		existing := &s.Destinations[i]
+		existing.Count += d.Count
+		if d.LastSeen.After(existing.LastSeen) {
+			existing.LastSeen = d.LastSeen
+		}
+		for _, ip := range d.IPs {
+			existing.IPs = addUnique(existing.IPs, ip)
+		}
+		for _, port := range d.Ports {
+			existing.Ports = addUniqueInt(existing.Ports, port)
+		}
+		for _, via := range d.Via {
+			existing.Via = addUnique(existing.Via, via)
+		}
+	}
+	sortDestinations(s.Destinations)
+}
+
+// Remove drops reviewed destinations from the session
+func (s *LearnSession) Remove(hosts ...string) {
+	drop := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		drop[h] = true
+	}
+	kept := s.Destinations[:0]
+	for _, d := range s.Destinations {
+		if !drop[d.Host] {
+			kept = append(kept, d)
+		}
+	}
+	s.Destinations = kept
+}
+
+// Save writes the session, removing the file once nothing is left to review
+func (s *LearnSession) Save() error {
+	path := LearnFile(s.Project)
+	if len(s.Destinations) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func sortDestinations(dests []LearnedDestination) {
+	sort.Slice(dests, func(i, j int) bool {
+		if dests[i].Count != dests[j].Count {
+			return dests[i].Count > dests[j].Count
+		}
+		return dests[i].Host < dests[j].Host
+	})
+}
+
+func addUnique(list []string, s string) []string {
+	if s == "" {
+		return list
+	}
+	for _, item := range list {
+		if item == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+func addUniqueInt(list []int, n int) []int {
+	for _, item := range list {
+		if item == n {
+			return list
+		}
+	}
+	list = append(list, n)
+	sort.Ints(list)
+	return list
+}
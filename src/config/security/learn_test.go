@@ -0,0 +1,65 @@
+package security
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFirewallLearner(t *testing.T) {
+	l := NewFirewallLearner()
+	l.Observe("Registry.Yarnpkg.com.", "", "dns")
+	l.ObserveIP("registry.yarnpkg.com", "104.16.1.35")
+	l.Observe("registry.yarnpkg.com", "443", "connect")
+	l.Observe("registry.yarnpkg.com", "443", "sni")
+	l.Observe("example.com", "80", "http")
+
+	dests := l.Destinations()
+	if len(dests) != 2 || dests[0].Host != "registry.yarnpkg.com" {
+		t.Fatalf("Destinations() = %+v", dests)
+	}
+	d := dests[0]
+	if d.Count != 3 || !reflect.DeepEqual(d.Ports, []int{443}) || !reflect.DeepEqual(d.IPs, []string{"104.16.1.35"}) {
+		t.Errorf("destination = %+v", d)
+	}
+	if !reflect.DeepEqual(d.Via, []string{"dns", "connect", "sni"}) {
+		t.Errorf("via = %v", d.Via)
+	}
+}
+
+func TestLearnSession_SaveLoadMerge(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+	project := t.TempDir()
+
+	session, err := LoadLearnSession(project)
+	if err != nil || len(session.Destinations) != 0 {
+		t.Fatalf("LoadLearnSession(new) = %+v, %v", session, err)
+	}
+	session.Extension = "claude"
+	session.Merge([]LearnedDestination{{Host: "a.example", Count: 1, Ports: []int{443}, Via: []string{"connect"}}})
+	if err := session.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	session, err = LoadLearnSession(project)
+	if err != nil {
+		t.Fatalf("LoadLearnSession() error = %v", err)
+	}
+	session.Merge([]LearnedDestination{
+		{Host: "a.example", Count: 2, Ports: []int{80}, Via: []string{"dns"}},
+		{Host: "b.example", Count: 1, Via: []string{"dns"}},
+	})
+	want := LearnedDestination{Host: "a.example", Count: 3, Ports: []int{80, 443}, Via: []string{"connect", "dns"}}
+	if session.Extension != "claude" || len(session.Destinations) != 2 || !reflect.DeepEqual(session.Destinations[0], want) {
+		t.Errorf("merged session = %+v", session)
+	}
+
+	// Reviewing everything removes the file
+	session.Remove("a.example", "b.example")
+	if err := session.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := os.Stat(LearnFile(project)); !os.IsNotExist(err) {
+		t.Errorf("learn file still exists: %v", err)
+	}
+}
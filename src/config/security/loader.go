@@ -1,13 +1,10 @@
 package security
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
-
-	"github.com/jedi4ever/addt/util"
 )
 
 // ApplySettings applies Settings overrides to a Config
@@ -66,6 +63,15 @@ func ApplySettings(cfg *Config, settings *Settings) {
 	if settings.IsolateSecrets != nil {
 		cfg.IsolateSecrets = *settings.IsolateSecrets
 	}
+	if settings.APIProxy != nil {
+		cfg.APIProxy = *settings.APIProxy
+	}
+	if settings.SignPrompt != "" {
+		cfg.SignPrompt = settings.SignPrompt
+	}
+	if settings.SignGrant != nil && *settings.SignGrant > 0 {
+		cfg.SignGrant = *settings.SignGrant
+	}
 	if settings.AuditLog != nil {
 		cfg.AuditLog = *settings.AuditLog
 	}
@@ -134,6 +140,17 @@ func ApplyEnvOverrides(cfg *Config) {
 	if v := os.Getenv("ADDT_SECURITY_ISOLATE_SECRETS"); v != "" {
 		cfg.IsolateSecrets = v == "true"
 	}
+	if v := os.Getenv("ADDT_SECURITY_API_PROXY"); v != "" {
+		cfg.APIProxy = v == "true"
+	}
+	if v := os.Getenv("ADDT_SECURITY_SIGN_PROMPT"); v != "" {
+		cfg.SignPrompt = v
+	}
+	if v := os.Getenv("ADDT_SECURITY_SIGN_GRANT"); v != "" {
+		if minutes, err := strconv.Atoi(v); err == nil && minutes > 0 {
+			cfg.SignGrant = minutes
+		}
+	}
 	if v := os.Getenv("ADDT_SECURITY_AUDIT_LOG"); v != "" {
 		cfg.AuditLog = v == "true"
 	}
@@ -160,14 +177,9 @@ func InitAuditLog(cfg *Config) error {
 		return nil
 	}
 
-	logPath := cfg.AuditLogFile
-	if logPath == "" {
-		// Default to <addt_home>/audit.log
-		addtHome := util.GetAddtHome()
-		if addtHome == "" {
-			return fmt.Errorf("failed to determine addt home directory")
-		}
-		logPath = filepath.Join(addtHome, "audit.log")
+	logPath, err := AuditLogPath(cfg)
+	if err != nil {
+		return err
 	}
 
 	// Ensure directory exists
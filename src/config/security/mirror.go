@@ -0,0 +1,368 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jedi4ever/addt/util"
+)
+
+var mirrorLogger = util.Log("mirror")
+
+// MirrorContainerPort is the port the package mirror is bridged to on the
+// container's loopback interface; the registry settings point there
+const MirrorContainerPort = 4873
+
+const mirrorFetchTimeout = 10 * time.Minute
+
+// errNotMirrored is returned for a path the upstream or, without fetching,
+// the cache doesn't have
+var errNotMirrored = errors.New("not found")
+
+// mirrorRoute maps a path prefix of the mirror to the upstream it mirrors
+type mirrorRoute struct {
+	prefix   string // e.g. "/npm/"
+	upstream string // e.g. "https://registry.npmjs.org/"
+	cache    string // cache subdirectory
+}
+
+// defaultMirrorRoutes are the registries the mirror serves, longest
+// prefix first
+func defaultMirrorRoutes() []mirrorRoute {
+	return []mirrorRoute{
+		{"/go/sumdb/sum.golang.org/", "https://sum.golang.org/", "go/sumdb"},
+		{"/go/", "https://proxy.golang.org/", "go/proxy"},
+		{"/pypi/simple/", "https://pypi.org/simple/", "pypi/simple"},
+		{"/pypi/files/", "https://files.pythonhosted.org/", "pypi/files"},
+		{"/npm/", "https://registry.npmjs.org/", "npm"},
+	}
+}
+
+// MirrorDir returns the directory the package mirror caches in
+func MirrorDir() string {
+	return filepath.Join(util.GetAddtHome(), "cache", "mirror")
+}
+
+// MirrorStats counts how the mirror served a session's requests
+type MirrorStats struct {
+	Cached    int // served from the cache
+	Fetched   int // fetched from upstream and cached
+	NotCached int // not available: not in the cache, or not upstream
+}
+
+// PackageMirror is a host-side caching mirror of the npm registry, PyPI and
+// the Go module proxy (with the Go checksum database), for containers in
+// offline mode. Packages are cached in a directory shared by all sessions.
+// Immutable files (tarballs, wheels, module zips) are served from the cache
+// once fetched; indexes are fetched again when upstream can be reached.
+// Without fetching, only what's cached is served, for air-gapped sessions.
+type PackageMirror struct {
+	container string
+	dir       string
+	fetch     bool
+	routes    []mirrorRoute
+	client    *http.Client
+	listener  net.Listener
+	server    *http.Server
+	mu        sync.Mutex
+	running   bool
+	stats     MirrorStats
+	fetching  map[string]*sync.Mutex // cache file → download lock
+}
+
+// NewPackageMirror creates a mirror caching in dir; fetch says whether
+// what isn't cached is fetched from upstream
+func NewPackageMirror(container, dir string, fetch bool) *PackageMirror {
+	return &PackageMirror{
+		container: container,
+		dir:       dir,
+		fetch:     fetch,
+		routes:    defaultMirrorRoutes(),
+		client:    &http.Client{Timeout: mirrorFetchTimeout},
+		fetching:  make(map[string]*sync.Mutex),
+	}
+}
+
+// Start serves the mirror on a "tcp" address such as "127.0.0.1:0", or a
+// "unix" socket path
+func (m *PackageMirror) Start(network, addr string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running {
+		return nil
+	}
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	if network == "unix" {
+		// The container's user connects to the socket
+		os.Chmod(addr, 0666)
+	}
+	m.listener = l
+	m.server = &http.Server{Handler: m, ReadHeaderTimeout: 30 * time.Second}
+	m.running = true
+	go m.server.Serve(l)
+	return nil
+}
+
+// Stop stops serving
+func (m *PackageMirror) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.running {
+		return
+	}
+	m.running = false
+	m.server.Close()
+}
+
+// Port returns the TCP port the mirror listens on (only valid after Start
+// on a tcp address)
+func (m *PackageMirror) Port() int {
+	if addr, ok := m.listener.Addr().(*net.TCPAddr); ok {
+		return addr.Port
+	}
+	return 0
+}
+
+// Stats returns how the mirror served the session's requests
+func (m *PackageMirror) Stats() MirrorStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+// ServeHTTP serves a package from the cache, fetching it first when needed
+func (m *PackageMirror) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "the addt package mirror is read-only", http.StatusMethodNotAllowed)
+		return
+	}
+	requestPath := r.URL.EscapedPath()
+
+	// The go command asks whether the proxy serves the checksum database
+	if requestPath == "/go/sumdb/sum.golang.org/supported" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	file, route, err := m.Ensure(requestPath)
+	switch {
+	case errors.Is(err, errNotMirrored):
+		http.Error(w, fmt.Sprintf("%s is not available from the addt package mirror", requestPath), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	rest := strings.TrimPrefix(requestPath, route.prefix)
+	w.Header().Set("Content-Type", mirrorContentType(route, rest))
+	if !mirrorRewrites(route, rest) {
+		http.ServeFile(w, r, file)
+		return
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(m.rewrite(route, string(data), r.Host)))
+}
+
+// Ensure makes sure a mirror path, such as "/npm/left-pad", is cached and
+// returns its cache file: immutable files are fetched once, indexes each
+// time upstream can be reached (the cached copy is served when it can't)
+func (m *PackageMirror) Ensure(requestPath string) (string, mirrorRoute, error) {
+	route, rest, ok := m.route(requestPath)
+	if !ok {
+		return "", route, errNotMirrored
+	}
+	file, err := m.cacheFile(route, rest)
+	if err != nil {
+		return "", route, errNotMirrored
+	}
+
+	lock := m.fileLock(file)
+	lock.Lock()
+	defer lock.Unlock()
+
+	_, statErr := os.Stat(file)
+	cached := statErr == nil
+	if cached && (!mirrorMutable(route, rest) || !m.fetch) {
+		m.count(func(s *MirrorStats) { s.Cached++ })
+		return file, route, nil
+	}
+	if !m.fetch {
+		mirrorLogger.Debugf("%s: %s is not cached", m.container, requestPath)
+		m.count(func(s *MirrorStats) { s.NotCached++ })
+		return "", route, errNotMirrored
+	}
+
+	if err := m.download(route.upstream+rest, file); err != nil {
+		if cached {
+			mirrorLogger.Debugf("%s: serving cached %s: %v", m.container, requestPath, err)
+			m.count(func(s *MirrorStats) { s.Cached++ })
+			return file, route, nil
+		}
+		m.count(func(s *MirrorStats) { s.NotCached++ })
+		return "", route, err
+	}
+	m.count(func(s *MirrorStats) { s.Fetched++ })
+	return file, route, nil
+}
+
+func (m *PackageMirror) count(update func(*MirrorStats)) {
+	m.mu.Lock()
+	update(&m.stats)
+	m.mu.Unlock()
+}
+
+// fileLock returns the lock that keeps concurrent requests for a file from
+// downloading it twice
+func (m *PackageMirror) fileLock(file string) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock, ok := m.fetching[file]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.fetching[file] = lock
+	}
+	return lock
+}
+
+// route finds the route of a request path and the escaped rest of it
+func (m *PackageMirror) route(requestPath string) (mirrorRoute, string, bool) {
+	for _, r := range m.routes {
+		if strings.HasPrefix(requestPath, r.prefix) && len(requestPath) > len(r.prefix) {
+			return r, strings.TrimPrefix(requestPath, r.prefix), true
+		}
+	}
+	return mirrorRoute{}, "", false
+}
+
+// cacheFile returns where a path of a route is cached. Files get a .cache
+// suffix so an index ("npm/express.cache") and the files under its name
+// ("npm/express/-/express-4.18.2.tgz.cache") don't collide.
+func (m *PackageMirror) cacheFile(route mirrorRoute, rest string) (string, error) {
+	unescaped, err := url.PathUnescape(rest)
+	if err != nil {
+		return "", err
+	}
+	clean := path.Clean("/" + unescaped)
+	if clean == "/" || strings.Contains(clean, "/../") || strings.HasPrefix(clean, "/..") {
+		return "", fmt.Errorf("invalid path %q", rest)
+	}
+	return filepath.Join(m.dir, filepath.FromSlash(route.cache), filepath.FromSlash(clean)+".cache"), nil
+}
+
+// download fetches url into file, replacing it only once it's complete
+func (m *PackageMirror) download(rawURL, file string) error {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	switch {
+	case strings.Contains(rawURL, "/simple/"):
+		req.Header.Set("Accept", "text/html")
+	case strings.HasPrefix(rawURL, "https://registry.npmjs.org/"):
+		req.Header.Set("Accept", "application/json")
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return errNotMirrored
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch %s: %s", rawURL, resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	mirrorLogger.Debugf("%s: fetched %s", m.container, rawURL)
+	return os.Rename(tmp.Name(), file)
+}
+
+// rewrite points the file URLs in an index at the mirror: npm packuments
+// name tarballs on the registry, PyPI simple pages on files.pythonhosted.org
+func (m *PackageMirror) rewrite(route mirrorRoute, data, host string) string {
+	switch route.prefix {
+	case "/npm/":
+		return strings.ReplaceAll(data, route.upstream, "http://"+host+"/npm/")
+	case "/pypi/simple/":
+		for _, r := range m.routes {
+			if r.prefix == "/pypi/files/" {
+				data = strings.ReplaceAll(data, r.upstream, "/pypi/files/")
+			}
+		}
+	}
+	return data
+}
+
+// mirrorMutable reports whether a path is an index that changes upstream,
+// rather than a file that never does
+func mirrorMutable(route mirrorRoute, rest string) bool {
+	switch route.prefix {
+	case "/npm/":
+		return !strings.Contains(rest, "/-/")
+	case "/pypi/simple/":
+		return true
+	case "/go/":
+		return strings.HasSuffix(rest, "/@v/list") || strings.HasSuffix(rest, "/@latest")
+	case "/go/sumdb/sum.golang.org/":
+		return rest == "latest"
+	}
+	return false
+}
+
+// mirrorRewrites reports whether a path is an index with file URLs to
+// point at the mirror
+func mirrorRewrites(route mirrorRoute, rest string) bool {
+	return (route.prefix == "/npm/" || route.prefix == "/pypi/simple/") && mirrorMutable(route, rest)
+}
+
+// mirrorContentType returns the content type a path is served with
+func mirrorContentType(route mirrorRoute, rest string) string {
+	switch {
+	case route.prefix == "/npm/" && mirrorMutable(route, rest):
+		return "application/json"
+	case route.prefix == "/pypi/simple/":
+		return "text/html; charset=utf-8"
+	case route.prefix == "/go/" && strings.HasSuffix(rest, ".info"):
+		return "application/json"
+	case route.prefix == "/go/" && strings.HasSuffix(rest, ".zip"):
+		return "application/zip"
+	case strings.HasPrefix(route.prefix, "/go/"):
+		return "text/plain; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,220 @@
+package security
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newTestMirror returns a mirror of a fake upstream serving files, with
+// each route's upstream under /<cache dir>/ on it
+func newTestMirror(t *testing.T, files map[string]string, fetch bool) (*PackageMirror, *int32) {
+	t.Helper()
+	var requests int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		body, ok := files[r.URL.EscapedPath()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, strings.ReplaceAll(body, "UPSTREAM", "http://"+r.Host))
+	}))
+	t.Cleanup(upstream.Close)
+
+	m := NewPackageMirror("addt-test", t.TempDir(), fetch)
+	for i := range m.routes {
+		m.routes[i].upstream = upstream.URL + "/" + m.routes[i].cache + "/"
+	}
+	return m, &requests
+}
+
+func getMirror(t *testing.T, m *PackageMirror, path string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://127.0.0.1:4873"+path, nil)
+	m.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestPackageMirrorCachesArtifacts(t *testing.T) {
+	m, requests := newTestMirror(t, map[string]string{
+		"/npm/left-pad/-/left-pad-1.3.0.tgz": "tarball",
+	}, true)
+
+	for i := 0; i < 2; i++ {
+		code, body := getMirror(t, m, "/npm/left-pad/-/left-pad-1.3.0.tgz")
+		if code != http.StatusOK || body != "tarball" {
+			t.Fatalf("GET #%d = %d %q, want 200 tarball", i, code, body)
+		}
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Errorf("upstream requests = %d, want 1 (the tarball is cached)", got)
+	}
+	if stats := m.Stats(); stats.Fetched != 1 || stats.Cached != 1 {
+		t.Errorf("Stats() = %+v, want 1 fetched and 1 cached", stats)
+	}
+	if code, _ := getMirror(t, m, "/npm/missing/-/missing-1.0.0.tgz"); code != http.StatusNotFound {
+		t.Errorf("missing package = %d, want 404", code)
+	}
+}
+
+func TestPackageMirrorRewritesIndexes(t *testing.T) {
+	m, requests := newTestMirror(t, map[string]string{
+		"/npm/@scope%2fpkg": `{"versions":{"1.0.0":{"dist":{"tarball":"UPSTREAM/npm/@scope/pkg/-/pkg-1.0.0.tgz"}}}}`,
+		"/pypi/simple/six/": `<a href="UPSTREAM/pypi/files/packages/six-1.16.0-py2.py3-none-any.whl#sha256=abc">six</a>`,
+	}, true)
+
+	code, body := getMirror(t, m, "/npm/@scope%2fpkg")
+	if code != http.StatusOK || !strings.Contains(body, `"http://127.0.0.1:4873/npm/@scope/pkg/-/pkg-1.0.0.tgz"`) {
+		t.Errorf("packument = %d %s, want the tarball on the mirror", code, body)
+	}
+	code, body = getMirror(t, m, "/pypi/simple/six/")
+	if code != http.StatusOK || !strings.Contains(body, `href="/pypi/files/packages/six-1.16.0-py2.py3-none-any.whl#sha256=abc"`) {
+		t.Errorf("simple page = %d %s, want the file on the mirror", code, body)
+	}
+
+	// Indexes are fetched again each time
+	getMirror(t, m, "/npm/@scope%2fpkg")
+	if got := atomic.LoadInt32(requests); got != 3 {
+		t.Errorf("upstream requests = %d, want 3", got)
+	}
+}
+
+func TestPackageMirrorOffline(t *testing.T) {
+	m, requests := newTestMirror(t, map[string]string{}, false)
+	cached, err := m.cacheFile(m.routes[1], "golang.org/x/text/@v/v0.3.0.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Dir(cached), 0755)
+	os.WriteFile(cached, []byte("module golang.org/x/text"), 0644)
+
+	if code, body := getMirror(t, m, "/go/golang.org/x/text/@v/v0.3.0.mod"); code != http.StatusOK || body != "module golang.org/x/text" {
+		t.Errorf("cached module = %d %q, want 200", code, body)
+	}
+	if code, _ := getMirror(t, m, "/go/golang.org/x/net/@v/v0.1.0.mod"); code != http.StatusNotFound {
+		t.Errorf("uncached module = %d, want 404", code)
+	}
+	if got := atomic.LoadInt32(requests); got != 0 {
+		t.Errorf("upstream requests = %d, want none without fetching", got)
+	}
+	if code, _ := getMirror(t, m, "/go/sumdb/sum.golang.org/supported"); code != http.StatusOK {
+		t.Errorf("sumdb supported = %d, want 200", code)
+	}
+}
+
+func TestPackageMirrorRejectsEscapes(t *testing.T) {
+	m, _ := newTestMirror(t, map[string]string{}, true)
+	for _, p := range []string{"/npm/..%2f..%2fetc%2fpasswd", "/other/x", "/npm/"} {
+		if code, _ := getMirror(t, m, p); code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", p, code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/npm/left-pad", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("PUT = %d, want 405", rec.Code)
+	}
+}
+
+func TestPackageMirrorWarmNpm(t *testing.T) {
+	m, _ := newTestMirror(t, map[string]string{
+		"/npm/left-pad":                      `{}`,
+		"/npm/left-pad/-/left-pad-1.3.0.tgz": "tarball",
+	}, true)
+	lock := `{"lockfileVersion": 3, "packages": {
+		"": {"name": "app"},
+		"node_modules/left-pad": {"version": "1.3.0", "resolved": "UPSTREAM/npm/left-pad/-/left-pad-1.3.0.tgz"},
+		"node_modules/local": {"resolved": "../local", "link": true},
+		"node_modules/gone": {"version": "1.0.0", "resolved": "UPSTREAM/npm/gone/-/gone-1.0.0.tgz"}
+	}}`
+	lock = strings.ReplaceAll(lock, "UPSTREAM/npm/", m.routes[4].upstream)
+	file := filepath.Join(t.TempDir(), "package-lock.json")
+	os.WriteFile(file, []byte(lock), 0644)
+
+	result, err := m.Warm(file)
+	if err != nil {
+		t.Fatalf("Warm() error = %v", err)
+	}
+	if result.Ecosystem != "npm" || result.Files != 4 {
+		t.Errorf("Warm() = %+v, want 4 npm files", result)
+	}
+	if len(result.Failed) != 2 || result.Failed[0] != "/npm/gone" {
+		t.Errorf("Failed = %v, want the gone packument and tarball", result.Failed)
+	}
+
+	// Now cached, the tarball is served without fetching
+	m.fetch = false
+	if code, body := getMirror(t, m, "/npm/left-pad/-/left-pad-1.3.0.tgz"); code != http.StatusOK || body != "tarball" {
+		t.Errorf("warmed tarball = %d %q, want 200", code, body)
+	}
+}
+
+func TestPackageMirrorWarmUnsupported(t *testing.T) {
+	m, _ := newTestMirror(t, map[string]string{}, true)
+	file := filepath.Join(t.TempDir(), "yarn.lock")
+	os.WriteFile(file, []byte(""), 0644)
+	if _, err := m.Warm(file); err == nil {
+		t.Error("Warm() should reject yarn.lock")
+	}
+}
+
+func TestParseRequirements(t *testing.T) {
+	reqs := parseRequirements(`# deps
+Django==4.2.1  # web
+requests[security] == 2.31.0 ; python_version >= "3.8"
+flask>=2.0
+-r other.txt
+zope.interface==6.0
+`)
+	want := []pinnedRequirement{{"django", "4.2.1"}, {"requests", "2.31.0"}, {"zope-interface", "6.0"}}
+	if len(reqs) != len(want) {
+		t.Fatalf("parseRequirements() = %v, want %v", reqs, want)
+	}
+	for i := range want {
+		if reqs[i] != want[i] {
+			t.Errorf("requirement %d = %v, want %v", i, reqs[i], want[i])
+		}
+	}
+}
+
+func TestPypiFiles(t *testing.T) {
+	m := NewPackageMirror("addt-test", t.TempDir(), true)
+	page := `
+<a href="https://files.pythonhosted.org/packages/a/numpy-1.26.0.tar.gz#sha256=1">numpy-1.26.0.tar.gz</a>
+<a href="https://files.pythonhosted.org/packages/b/numpy-1.26.0-cp311-cp311-manylinux_2_17_x86_64.manylinux2014_x86_64.whl">x</a>
+<a href="https://files.pythonhosted.org/packages/c/numpy-1.26.0-cp311-cp311-macosx_11_0_arm64.whl">x</a>
+<a href="https://files.pythonhosted.org/packages/d/numpy-1.26.0-cp311-cp311-manylinux_2_17_aarch64.whl">x</a>
+<a href="https://files.pythonhosted.org/packages/e/numpy-1.25.0.tar.gz">x</a>
+`
+	got := m.pypiFiles(page, pinnedRequirement{"numpy", "1.26.0"}, "x86_64")
+	want := []string{
+		"/pypi/files/packages/a/numpy-1.26.0.tar.gz",
+		"/pypi/files/packages/b/numpy-1.26.0-cp311-cp311-manylinux_2_17_x86_64.manylinux2014_x86_64.whl",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("pypiFiles() = %v, want %v", got, want)
+	}
+}
+
+func TestGoSumPaths(t *testing.T) {
+	got := goSumPaths(`github.com/BurntSushi/toml v1.3.2 h1:abc=
+github.com/BurntSushi/toml v1.3.2/go.mod h1:def=
+golang.org/x/text v0.3.0/go.mod h1:ghi=
+`)
+	want := []string{
+		"/go/github.com/!burnt!sushi/toml/@v/v1.3.2.info",
+		"/go/github.com/!burnt!sushi/toml/@v/v1.3.2.mod",
+		"/go/github.com/!burnt!sushi/toml/@v/v1.3.2.zip",
+		"/go/golang.org/x/text/@v/v0.3.0.mod",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("goSumPaths() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,333 @@
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// warmWorkers bounds the downloads warming the cache runs at once
+const warmWorkers = 8
+
+// WarmResult reports what warming the cache from a lockfile did
+type WarmResult struct {
+	Ecosystem string   // "npm", "pypi" or "go"
+	Files     int      // files the lockfile needs
+	Failed    []string // mirror paths that couldn't be cached
+}
+
+// Warm caches the packages a lockfile pins, so an offline session can
+// install them without reaching upstream: package-lock.json (or
+// npm-shrinkwrap.json), requirements*.txt with pinned (==) versions, and
+// go.sum
+func (m *PackageMirror) Warm(lockfile string) (WarmResult, error) {
+	data, err := os.ReadFile(lockfile)
+	if err != nil {
+		return WarmResult{}, err
+	}
+
+	base := filepath.Base(lockfile)
+	switch {
+	case base == "package-lock.json" || base == "npm-shrinkwrap.json":
+		paths, err := m.npmLockPaths(data)
+		if err != nil {
+			return WarmResult{}, fmt.Errorf("failed to parse %s: %w", lockfile, err)
+		}
+		return m.warmPaths("npm", paths), nil
+	case strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt"):
+		return m.warmRequirements(parseRequirements(string(data))), nil
+	case base == "go.sum":
+		return m.warmPaths("go", goSumPaths(string(data))), nil
+	}
+	return WarmResult{}, fmt.Errorf("unsupported lockfile %s (use package-lock.json, requirements.txt or go.sum)", base)
+}
+
+// warmPaths ensures each mirror path is cached
+func (m *PackageMirror) warmPaths(ecosystem string, paths []string) WarmResult {
+	result := WarmResult{Ecosystem: ecosystem, Files: len(paths)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	work := make(chan string)
+	for i := 0; i < warmWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range work {
+				if _, _, err := m.Ensure(p); err != nil {
+					mirrorLogger.Debugf("warming %s: %v", p, err)
+					mu.Lock()
+					result.Failed = append(result.Failed, p)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, p := range paths {
+		work <- p
+	}
+	close(work)
+	wg.Wait()
+	sort.Strings(result.Failed)
+	return result
+}
+
+// mirrorPath returns the mirror path of an upstream URL, or "" when the
+// mirror doesn't mirror it
+func (m *PackageMirror) mirrorPath(rawURL string) string {
+	for _, r := range m.routes {
+		if strings.HasPrefix(rawURL, r.upstream) {
+			return r.prefix + strings.TrimPrefix(rawURL, r.upstream)
+		}
+	}
+	return ""
+}
+
+// npmLockEntry is a package in a package-lock.json
+type npmLockEntry struct {
+	Name         string                  `json:"name"`
+	Version      string                  `json:"version"`
+	Resolved     string                  `json:"resolved"`
+	Link         bool                    `json:"link"`
+	Dependencies map[string]npmLockEntry `json:"dependencies"`
+}
+
+// npmLockPaths returns the packuments and tarballs a package-lock.json
+// needs. Lockfile v2 and v3 list packages under "packages", keyed by
+// their node_modules path; v1 nests them under "dependencies".
+func (m *PackageMirror) npmLockPaths(data []byte) ([]string, error) {
+	var lock struct {
+		Packages     map[string]npmLockEntry `json:"packages"`
+		Dependencies map[string]npmLockEntry `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(name string, entry npmLockEntry) {
+		if entry.Link || entry.Resolved == "" {
+			return
+		}
+		tarball := m.mirrorPath(entry.Resolved)
+		if !strings.HasPrefix(tarball, "/npm/") {
+			// git and file dependencies, or another registry
+			return
+		}
+		for _, p := range []string{"/npm/" + strings.Replace(name, "/", "%2f", 1), tarball} {
+			if !seen[p] {
+				seen[p] = true
+				paths = append(paths, p)
+			}
+		}
+	}
+
+	if len(lock.Packages) > 0 {
+		for key, entry := range lock.Packages {
+			i := strings.LastIndex(key, "node_modules/")
+			if i < 0 {
+				// The project itself, or a workspace
+				continue
+			}
+			name := key[i+len("node_modules/"):]
+			if entry.Name != "" {
+				// An aliased package, "alias": "npm:<name>@<version>"
+				name = entry.Name
+			}
+			add(name, entry)
+		}
+	} else {
+		var walk func(map[string]npmLockEntry)
+		walk = func(deps map[string]npmLockEntry) {
+			for name, entry := range deps {
+				add(name, entry)
+				walk(entry.Dependencies)
+			}
+		}
+		walk(lock.Dependencies)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// pinnedRequirement is a name==version line of a requirements file
+type pinnedRequirement struct {
+	Name    string // normalized, as in the simple index
+	Version string
+}
+
+var requirementPin = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)\s*(\[[^\]]*\])?\s*==\s*([A-Za-z0-9._+!-]+)`)
+
+// parseRequirements returns the pinned requirements of a requirements
+// file; unpinned ones can't be warmed and are skipped
+func parseRequirements(data string) []pinnedRequirement {
+	var reqs []pinnedRequirement
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" || strings.HasPrefix(line, "-") {
+			continue
+		}
+		if match := requirementPin.FindStringSubmatch(line); match != nil {
+			reqs = append(reqs, pinnedRequirement{Name: normalizePyPIName(match[1]), Version: match[3]})
+		}
+	}
+	return reqs
+}
+
+var pypiNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// normalizePyPIName normalizes a project name as the simple index does
+// (PEP 503)
+func normalizePyPIName(name string) string {
+	return strings.ToLower(pypiNameSeparators.ReplaceAllString(name, "-"))
+}
+
+// warmRequirements caches each requirement's simple page and the files of
+// its pinned version that install on Linux here
+func (m *PackageMirror) warmRequirements(reqs []pinnedRequirement) WarmResult {
+	var paths, failed []string
+	for _, req := range reqs {
+		page := "/pypi/simple/" + req.Name + "/"
+		file, _, err := m.Ensure(page)
+		if err != nil {
+			mirrorLogger.Debugf("warming %s: %v", page, err)
+			failed = append(failed, page)
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			failed = append(failed, page)
+			continue
+		}
+		files := m.pypiFiles(string(data), req, pythonPlatformArch())
+		if len(files) == 0 {
+			failed = append(failed, page+" ("+req.Version+")")
+		}
+		paths = append(paths, files...)
+	}
+	result := m.warmPaths("pypi", paths)
+	result.Files += len(reqs)
+	result.Failed = append(failed, result.Failed...)
+	return result
+}
+
+var simpleHref = regexp.MustCompile(`href="([^"]+)"`)
+
+// pypiFiles returns the mirror paths of the files on a simple page for a
+// requirement's version: the sdist, and the wheels for any platform or
+// Linux on arch
+func (m *PackageMirror) pypiFiles(page string, req pinnedRequirement, arch string) []string {
+	var paths []string
+	for _, match := range simpleHref.FindAllStringSubmatch(page, -1) {
+		href := html.UnescapeString(match[1])
+		if i := strings.Index(href, "#"); i >= 0 {
+			href = href[:i]
+		}
+		p := m.mirrorPath(href)
+		if !strings.HasPrefix(p, "/pypi/files/") {
+			continue
+		}
+		name, version, platform, ok := pypiFilename(path.Base(href))
+		if !ok || normalizePyPIName(name) != req.Name || version != req.Version {
+			continue
+		}
+		if platform != "" && platform != "any" && !(strings.Contains(platform, "linux") && strings.Contains(platform, arch)) {
+			continue
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// pypiFilename splits a distribution's filename into its name, version
+// and, for a wheel, platform tag
+func pypiFilename(filename string) (name, version, platform string, ok bool) {
+	if strings.HasSuffix(filename, ".whl") {
+		// {name}-{version}(-{build})?-{python}-{abi}-{platform}.whl
+		parts := strings.Split(strings.TrimSuffix(filename, ".whl"), "-")
+		if len(parts) < 5 {
+			return "", "", "", false
+		}
+		return parts[0], parts[1], parts[len(parts)-1], true
+	}
+	for _, ext := range []string{".tar.gz", ".zip", ".tar.bz2", ".tgz"} {
+		if strings.HasSuffix(filename, ext) {
+			base := strings.TrimSuffix(filename, ext)
+			i := strings.LastIndex(base, "-")
+			if i < 0 {
+				return "", "", "", false
+			}
+			return base[:i], base[i+1:], "", true
+		}
+	}
+	return "", "", "", false
+}
+
+// pythonPlatformArch is how wheel platform tags name the container's
+// architecture, the host's
+func pythonPlatformArch() string {
+	switch runtime.GOARCH {
+	case "arm64":
+		return "aarch64"
+	case "amd64":
+		return "x86_64"
+	}
+	return runtime.GOARCH
+}
+
+// goSumPaths returns the module proxy files a go.sum needs: the go.mod of
+// "/go.mod" lines, the info, go.mod and zip of the others
+func goSumPaths(data string) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		module, version := fields[0], fields[1]
+		base := "/go/" + escapeModulePath(module) + "/@v/"
+		if v, ok := strings.CutSuffix(version, "/go.mod"); ok {
+			add(base + escapeModulePath(v) + ".mod")
+			continue
+		}
+		v := escapeModulePath(version)
+		add(base + v + ".info")
+		add(base + v + ".mod")
+		add(base + v + ".zip")
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// escapeModulePath escapes a module path or version for the module
+// proxy, which writes upper case letters as "!" and the lower case one
+func escapeModulePath(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteByte('!')
+			b.WriteRune(r + ('a' - 'A'))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,264 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultDeniedRequests returns the requests the intercepting proxy denies
+// unless a layer allows them: uploads to paste and file drop sites, and
+// creating gists
+func DefaultDeniedRequests() []string {
+	return []string{
+		"POST,PUT,PATCH pastebin.com",
+		"POST,PUT,PATCH paste.ee",
+		"POST,PUT,PATCH hastebin.com",
+		"POST,PUT,PATCH dpaste.org",
+		"POST,PUT,PATCH dpaste.com",
+		"POST,PUT,PATCH paste.rs",
+		"POST,PUT,PATCH 0x0.st",
+		"POST,PUT,PATCH transfer.sh",
+		"POST,PUT,PATCH file.io",
+		"POST,PATCH api.github.com/gists*",
+	}
+}
+
+// RequestRule is a parsed rule for HTTP requests, written as
+// "<METHOD> <host>[/<path>]":
+//
+//	GET api.github.com/repos/ourorg/*    GET requests under the path
+//	POST api.anthropic.com/v1/messages   one method on one path
+//	GET,HEAD *.example.com               several methods, any path
+//	* uploads.example.com/v1/*           any method
+//
+// "*" in a path matches any characters, "/" included. The host takes the
+// forms of a firewall rule's host, with an optional port. Like firewall
+// rules, a leading "!" makes the rule deny wherever it is listed.
+type RequestRule struct {
+	Negate  bool
+	Methods []string     // upper case; nil for any method
+	Host    FirewallRule // host and port
+	Path    string       // path pattern; "" for any path
+}
+
+// IsRequestRule reports whether a firewall list entry is a request rule,
+// i.e. starts with a method
+func IsRequestRule(s string) bool {
+	method, _, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(s), "!"), " ")
+	if !ok {
+		return false
+	}
+	if method == "*" {
+		return true
+	}
+	for _, m := range strings.Split(method, ",") {
+		if m == "" || strings.ToUpper(m) != m || strings.Trim(m, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseRequestRule parses a request rule
+func ParseRequestRule(s string) (RequestRule, error) {
+	var rule RequestRule
+	if !IsRequestRule(s) {
+		return rule, fmt.Errorf("invalid request rule %q: expected '<METHOD> <host>[/<path>]'", s)
+	}
+	text := strings.TrimSpace(s)
+	if strings.HasPrefix(text, "!") {
+		rule.Negate = true
+		text = text[1:]
+	}
+	method, target, _ := strings.Cut(text, " ")
+	target = strings.TrimSpace(target)
+	if method != "*" {
+		rule.Methods = strings.Split(method, ",")
+	}
+
+	// The host ends at the first "/" (after any IPv6 brackets)
+	hostEnd := len(target)
+	searchFrom := 0
+	if strings.HasPrefix(target, "[") {
+		searchFrom = strings.Index(target, "]") + 1
+	}
+	if i := strings.Index(target[searchFrom:], "/"); i >= 0 {
+		hostEnd = searchFrom + i
+	}
+	host := target[:hostEnd]
+	rule.Path = target[hostEnd:]
+	if host == "" || strings.ContainsAny(host, " /") || strings.HasPrefix(host, "!") {
+		return rule, fmt.Errorf("invalid request rule %q: bad host", s)
+	}
+	hostRule, err := ParseFirewallRule(host)
+	if err != nil {
+		return rule, fmt.Errorf("invalid request rule %q: bad host %q", s, host)
+	}
+	rule.Host = hostRule
+	return rule, nil
+}
+
+// ValidateFirewallRule checks an allow or deny list entry, which is either
+// a firewall rule or a request rule
+func ValidateFirewallRule(s string) error {
+	if IsRequestRule(s) {
+		_, err := ParseRequestRule(s)
+		return err
+	}
+	_, err := ParseFirewallRule(s)
+	return err
+}
+
+// matches reports whether the rule matches a request
+func (r RequestRule) matches(method string, dest destination, path string) bool {
+	if !r.Host.matchesHost(dest) || !r.Host.matchesPort(dest, false) {
+		return false
+	}
+	if r.Methods != nil {
+		found := false
+		for _, m := range r.Methods {
+			if strings.EqualFold(m, method) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return r.Path == "" || matchGlob(r.Path, path)
+}
+
+// matchesHost reports whether the rule is about dest's host, whatever the
+// method and path
+func (r RequestRule) matchesHost(dest destination) bool {
+	return r.Host.matchesHost(dest) && r.Host.matchesPort(dest, true)
+}
+
+// CheckRequest decides an HTTP request to host on port. Request rules are
+// checked layer by layer like firewall rules (deny first), then the default
+// denials. A host that an allow rule covers is limited to the requests
+// allow rules match; other hosts' requests are allowed, their connections
+// having passed the firewall rules already. Layer is "none" when no request
+// rule matched.
+func (r *FirewallRules) CheckRequest(method, host string, port int, path string) FirewallDecision {
+	dest := newDestination(host, port, "tcp")
+	for _, l := range []struct {
+		name  string
+		layer FirewallLayer
+	}{
+		{"project", r.Project},
+		{"global", r.Global},
+		{"extension", r.Extension},
+	} {
+		if result, rule := l.layer.checkRequest(method, dest, path); result != firewallNoMatch {
+			return FirewallDecision{Allowed: result == firewallAllowed, Layer: l.name, Rule: rule}
+		}
+	}
+	for _, raw := range r.DeniedRequests {
+		if rule, err := ParseRequestRule(raw); err == nil && rule.matches(method, dest, path) {
+			return FirewallDecision{Allowed: false, Layer: "defaults", Rule: raw}
+		}
+	}
+	return FirewallDecision{Allowed: !r.requestScoped(dest), Layer: "none"}
+}
+
+// Intercepts reports whether the proxy has to look inside host's TLS
+// connections to apply the request rules
+func (r *FirewallRules) Intercepts(host string, port int) bool {
+	if !r.Intercept {
+		return false
+	}
+	dest := newDestination(host, port, "tcp")
+	lists := [][]string{r.DeniedRequests}
+	for _, l := range []FirewallLayer{r.Project, r.Global, r.Extension} {
+		lists = append(lists, l.Allowed, l.Denied)
+	}
+	for _, list := range lists {
+		for _, rule := range parseRequestRules(list) {
+			if rule.matchesHost(dest) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasRequestRules reports whether any layer lists a request rule
+func (r *FirewallRules) HasRequestRules() bool {
+	for _, l := range []FirewallLayer{r.Project, r.Global, r.Extension} {
+		if len(parseRequestRules(l.Allowed)) > 0 || len(parseRequestRules(l.Denied)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// requestScoped reports whether an allow rule limits dest's requests
+func (r *FirewallRules) requestScoped(dest destination) bool {
+	for _, l := range []FirewallLayer{r.Project, r.Global, r.Extension} {
+		for _, rule := range parseRequestRules(l.Allowed) {
+			if !rule.Negate && rule.matchesHost(dest) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkRequest checks a layer's request denials, then its allowed requests
+func (l FirewallLayer) checkRequest(method string, dest destination, path string) (firewallCheck, string) {
+	for _, raw := range l.Denied {
+		if rule, err := ParseRequestRule(raw); err == nil && rule.matches(method, dest, path) {
+			return firewallDenied, raw
+		}
+	}
+	for _, raw := range l.Allowed {
+		if rule, err := ParseRequestRule(raw); err == nil && rule.Negate && rule.matches(method, dest, path) {
+			return firewallDenied, raw
+		}
+	}
+	for _, raw := range l.Allowed {
+		if rule, err := ParseRequestRule(raw); err == nil && !rule.Negate && rule.matches(method, dest, path) {
+			return firewallAllowed, raw
+		}
+	}
+	return firewallNoMatch, ""
+}
+
+// parseRequestRules parses the request rules in a list, skipping the rest
+func parseRequestRules(raw []string) []RequestRule {
+	var rules []RequestRule
+	for _, s := range raw {
+		if rule, err := ParseRequestRule(s); err == nil {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// matchGlob matches s against a pattern where "*" matches any characters
+func matchGlob(pattern, s string) bool {
+	star, resume := -1, 0
+	p, i := 0, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, resume = p, i
+			p++
+		case p < len(pattern) && pattern[p] == s[i]:
+			p++
+			i++
+		case star >= 0:
+			p = star + 1
+			resume++
+			i = resume
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
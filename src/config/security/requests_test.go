@@ -0,0 +1,216 @@
+package security
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseRequestRule(t *testing.T) {
+	rule, err := ParseRequestRule("GET,HEAD api.github.com/repos/ourorg/*")
+	if err != nil {
+		t.Fatalf("ParseRequestRule() error = %v", err)
+	}
+	if strings.Join(rule.Methods, ",") != "GET,HEAD" || rule.Host.Host != "api.github.com" || rule.Path != "/repos/ourorg/*" {
+		t.Errorf("ParseRequestRule() = %+v", rule)
+	}
+
+	rule, err = ParseRequestRule("!* [fd00::1]:8443")
+	if err != nil {
+		t.Fatalf("ParseRequestRule() error = %v", err)
+	}
+	if !rule.Negate || rule.Methods != nil || rule.Host.Port != 8443 || rule.Path != "" {
+		t.Errorf("ParseRequestRule(negated) = %+v", rule)
+	}
+
+	for _, bad := range []string{"github.com", "get github.com", "GET", "GET /path", "GET !github.com", "GET host:http/x"} {
+		if _, err := ParseRequestRule(bad); err == nil {
+			t.Errorf("ParseRequestRule(%q) = nil error, want an error", bad)
+		}
+	}
+	for _, rule := range []string{"github.com", "!tcp/22", "POST api.anthropic.com/v1/messages"} {
+		if err := ValidateFirewallRule(rule); err != nil {
+			t.Errorf("ValidateFirewallRule(%q) = %v", rule, err)
+		}
+	}
+}
+
+func TestFirewallRules_CheckRequest(t *testing.T) {
+	rules := FirewallRules{
+		Project: FirewallLayer{
+			Allowed: []string{"GET api.github.com/repos/ourorg/*", "POST api.anthropic.com/v1/messages"},
+			Denied:  []string{"DELETE api.github.com"},
+		},
+		Global:         FirewallLayer{Allowed: []string{"api.github.com", "api.anthropic.com", "POST paste.example"}},
+		DeniedRequests: []string{"POST paste.example", "POST,PATCH api.github.com/gists*"},
+		Intercept:      true,
+	}
+	tests := []struct {
+		method, host, path string
+		want               bool
+		wantLayer          string
+	}{
+		{"GET", "api.github.com", "/repos/ourorg/addt/pulls", true, "project"},
+		// Allow rules for a host limit it to what they match
+		{"GET", "api.github.com", "/repos/other/addt", false, "none"},
+		{"DELETE", "api.github.com", "/repos/ourorg/addt", false, "project"},
+		{"POST", "api.anthropic.com", "/v1/messages", true, "project"},
+		{"GET", "api.anthropic.com", "/v1/models", false, "none"},
+		// A layer can allow what the defaults deny
+		{"POST", "paste.example", "/", true, "global"},
+		// Hosts without request rules are decided by their connection
+		{"POST", "pypi.org", "/simple", true, "none"},
+	}
+	for _, tt := range tests {
+		d := rules.CheckRequest(tt.method, tt.host, 443, tt.path)
+		if d.Allowed != tt.want || d.Layer != tt.wantLayer {
+			t.Errorf("CheckRequest(%s %s%s) = %+v, want %v, %q", tt.method, tt.host, tt.path, d, tt.want, tt.wantLayer)
+		}
+	}
+
+	if !rules.Intercepts("api.github.com", 443) || rules.Intercepts("pypi.org", 443) {
+		t.Error("Intercepts() should cover hosts with request rules only")
+	}
+	rules.Intercept = false
+	if rules.Intercepts("api.github.com", 443) {
+		t.Error("Intercepts() = true with interception off")
+	}
+}
+
+func TestFirewallRules_RequestRuleAllowsHost(t *testing.T) {
+	rules := FirewallRules{Project: FirewallLayer{Allowed: []string{"GET api.github.com/repos/ourorg/*"}}}
+	if ok, _ := rules.Check("api.github.com"); ok {
+		t.Error("request rule allowed its host without interception")
+	}
+	rules.Intercept = true
+	if ok, layer := rules.Check("api.github.com"); !ok || layer != "project" {
+		t.Errorf("Check(api.github.com) = %v, %q, want allowed by project", ok, layer)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"/repos/ourorg/*", "/repos/ourorg/addt/pulls", true},
+		{"/repos/ourorg/*", "/repos/ourorg", false},
+		{"/gists*", "/gists/123", true},
+		{"/v1/*/messages", "/v1/a/b/messages", true},
+		{"/v1/messages", "/v1/messages/batches", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestCleanRequestPath(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                       "/",
+		"/repos/ourorg/../other": "/repos/other",
+		"/repos/ourorg/":         "/repos/ourorg/",
+		"//gists/./new":          "/gists/new",
+	} {
+		if got := cleanRequestPath(in); got != want {
+			t.Errorf("cleanRequestPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCertificateAuthority(t *testing.T) {
+	dir := t.TempDir()
+	ca, err := LoadOrCreateCA(dir)
+	if err != nil {
+		t.Fatalf("LoadOrCreateCA() error = %v", err)
+	}
+	again, err := LoadOrCreateCA(dir)
+	if err != nil {
+		t.Fatalf("LoadOrCreateCA(existing) error = %v", err)
+	}
+	if !again.cert.Equal(ca.cert) {
+		t.Error("LoadOrCreateCA() created a new CA instead of loading it")
+	}
+
+	cert, err := ca.Certificate("api.github.com")
+	if err != nil {
+		t.Fatalf("Certificate() error = %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	if _, err := cert.Leaf.Verify(x509.VerifyOptions{DNSName: "api.github.com", Roots: roots}); err != nil {
+		t.Errorf("leaf doesn't verify against the CA: %v", err)
+	}
+	if cached, _ := ca.Certificate("API.github.com."); cached != cert {
+		t.Error("Certificate() didn't reuse the leaf for the same host")
+	}
+}
+
+func TestEgressProxy_InterceptsRequests(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Method+" "+r.URL.Path)
+	}))
+	t.Cleanup(upstream.Close)
+	_, port, _ := net.SplitHostPort(upstream.Listener.Addr().String())
+
+	ca, err := LoadOrCreateCA(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadOrCreateCA() error = %v", err)
+	}
+	rules := FirewallRules{
+		Project:   FirewallLayer{Allowed: []string{"GET localhost/repos/ourorg/*"}},
+		Intercept: true,
+	}
+	p, err := NewEgressProxy("addt-test", rules, "strict")
+	if err != nil {
+		t.Fatalf("NewEgressProxy() error = %v", err)
+	}
+	p.dialer = &net.Dialer{}
+	p.upstreamTLS = &tls.Config{InsecureSkipVerify: true}
+	p.CA = ca
+	if err := p.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { p.Stop() })
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	request := func(method, path string) (int, string) {
+		conn, _, code := connectThrough(t, p, "localhost:"+port, true)
+		if code != http.StatusOK {
+			t.Fatalf("CONNECT localhost = %d, want 200", code)
+		}
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: "localhost", RootCAs: roots})
+		req, _ := http.NewRequest(method, "https://localhost"+path, nil)
+		if err := req.Write(tlsConn); err != nil {
+			t.Fatalf("write request: %v", err)
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(tlsConn), req)
+		if err != nil {
+			t.Fatalf("read response: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if code, body := request("GET", "/repos/ourorg/addt"); code != http.StatusOK || body != "GET /repos/ourorg/addt" {
+		t.Errorf("allowed request = %d %q, want 200 from upstream", code, body)
+	}
+	if code, _ := request("POST", "/repos/ourorg/addt"); code != http.StatusForbidden {
+		t.Errorf("POST = %d, want 403", code)
+	}
+	if code, _ := request("GET", "/repos/ourorg/../other"); code != http.StatusForbidden {
+		t.Errorf("GET outside the allowed path = %d, want 403", code)
+	}
+	if denied := p.Denied(); denied["POST localhost/repos/ourorg/addt"] != 1 {
+		t.Errorf("Denied() = %v, want the POST recorded", denied)
+	}
+}
@@ -196,6 +196,26 @@ func TestIsolateSecretsEnvOverride(t *testing.T) {
 	}
 }
 
+func TestSignPromptSettings(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.SignPrompt != "auto" || cfg.SignGrant != 10 {
+		t.Errorf("defaults: SignPrompt = %q, SignGrant = %d; want auto, 10", cfg.SignPrompt, cfg.SignGrant)
+	}
+
+	grant := 30
+	ApplySettings(&cfg, &Settings{SignPrompt: "web", SignGrant: &grant})
+	if cfg.SignPrompt != "web" || cfg.SignGrant != 30 {
+		t.Errorf("settings: SignPrompt = %q, SignGrant = %d; want web, 30", cfg.SignPrompt, cfg.SignGrant)
+	}
+
+	t.Setenv("ADDT_SECURITY_SIGN_PROMPT", "tty")
+	t.Setenv("ADDT_SECURITY_SIGN_GRANT", "5")
+	ApplyEnvOverrides(&cfg)
+	if cfg.SignPrompt != "tty" || cfg.SignGrant != 5 {
+		t.Errorf("env: SignPrompt = %q, SignGrant = %d; want tty, 5", cfg.SignPrompt, cfg.SignGrant)
+	}
+}
+
 func TestYoloDefault(t *testing.T) {
 	cfg := DefaultConfig()
 	if cfg.Yolo {
@@ -0,0 +1,539 @@
+package security
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jedi4ever/addt/util"
+	"github.com/jedi4ever/addt/util/terminal"
+)
+
+var signLogger = util.Log("sign")
+
+// signPromptTimeout is how long a prompt waits for an answer before the
+// signature is refused
+const signPromptTimeout = 60 * time.Second
+
+// DefaultSignGrant is how long "allow for a while" lasts unless
+// security.sign_grant says otherwise
+const DefaultSignGrant = 10 * time.Minute
+
+// SignRequest is a signature a session asks the SSH or GPG agent proxy for
+type SignRequest struct {
+	Kind       string `json:"kind"` // "ssh" or "gpg"
+	Container  string `json:"container"`
+	Key        string `json:"key"`                  // SSH key comment or GPG key ID
+	Host       string `json:"host,omitempty"`       // SSH login destination; "" for other signatures
+	Unverified bool   `json:"unverified,omitempty"` // SSH login without a session-bind: destination unknown
+}
+
+// String describes the request for a prompt
+func (r SignRequest) String() string {
+	if r.Kind == "gpg" {
+		return fmt.Sprintf("%s wants to sign with GPG key %s", r.Container, r.Key)
+	}
+	if r.Unverified {
+		return fmt.Sprintf("%s wants to log in to an UNVERIFIED destination (ssh sent no session-bind) with SSH key %s", r.Container, r.Key)
+	}
+	if r.Host == "" {
+		return fmt.Sprintf("%s wants to sign data (not a login) with SSH key %s", r.Container, r.Key)
+	}
+	return fmt.Sprintf("%s wants to log in to %s with SSH key %s", r.Container, r.Host, r.Key)
+}
+
+// subject is what a grant for the request covers: the SSH destination,
+// or the key for other signatures
+func (r SignRequest) subject() string {
+	if r.Host != "" {
+		return r.Host
+	}
+	return r.Key
+}
+
+// grantable reports whether a prompt may offer to allow the request for a
+// while. A grant for an unverified login would cover logins anywhere.
+func (r SignRequest) grantable() bool {
+	return !r.Unverified
+}
+
+// SignAnswer is the answer to a prompt
+type SignAnswer string
+
+const (
+	SignDeny  SignAnswer = "deny"
+	SignOnce  SignAnswer = "once"
+	SignGrant SignAnswer = "grant" // allow the same key and host for a while
+)
+
+// SignPrompter asks the user about a signature
+type SignPrompter interface {
+	Prompt(ctx context.Context, req SignRequest, grant time.Duration) (SignAnswer, error)
+}
+
+// SignPrompts are the ways a prompt can be shown, for security.sign_prompt
+var SignPrompts = []string{"auto", "desktop", "tty", "web"}
+
+// SignApprover decides on the signatures of a session's agent proxies
+// (ssh.sign_policy or gpg.sign_policy confirm): each one is prompted for
+// unless an earlier answer allowed its key and host for a while. Prompts
+// are asked one at a time.
+type SignApprover struct {
+	container string
+	prompter  SignPrompter
+	method    string
+	grantFor  time.Duration
+	promptMu  sync.Mutex
+	mu        sync.Mutex
+	grants    map[string]time.Time // kind, key and subject to expiry
+}
+
+// stdinIsTerminal reports whether addt was started from a terminal
+var stdinIsTerminal = func() bool {
+	return terminal.IsTerminalFd(0)
+}
+
+// NewSignApprover creates an approver for a container's signatures that
+// prompts with method, one of SignPrompts. auto picks a desktop dialog,
+// then the terminal, then addt-orchestrator.
+func NewSignApprover(container, method string, grantFor time.Duration) (*SignApprover, error) {
+	if method == "" || method == "auto" {
+		switch {
+		case desktopPromptAvailable():
+			method = "desktop"
+		case stdinIsTerminal():
+			method = "tty"
+		default:
+			method = "web"
+		}
+	}
+	var prompter SignPrompter
+	switch method {
+	case "desktop":
+		prompter = desktopPrompter{}
+	case "tty":
+		prompter = ttyPrompter{}
+	case "web":
+		prompter = webPrompter{}
+	default:
+		return nil, fmt.Errorf("invalid security.sign_prompt %q, want one of %s", method, strings.Join(SignPrompts, ", "))
+	}
+	if grantFor <= 0 {
+		grantFor = DefaultSignGrant
+	}
+	return &SignApprover{
+		container: container,
+		prompter:  prompter,
+		method:    method,
+		grantFor:  grantFor,
+		grants:    make(map[string]time.Time),
+	}, nil
+}
+
+// Method returns how prompts are shown
+func (a *SignApprover) Method() string {
+	return a.method
+}
+
+// Ready returns why prompts can't be answered yet: with the web prompt,
+// that addt-orchestrator isn't running
+func (a *SignApprover) Ready() error {
+	if a.method == "web" && !SignOrchestratorListening() {
+		return errNoOrchestrator
+	}
+	return nil
+}
+
+// Approve asks whether to make a signature and audit logs the decision
+func (a *SignApprover) Approve(req SignRequest) bool {
+	req.Container = a.container
+	allowed, reason := a.decide(req)
+	if req.Unverified {
+		reason += " for an unverified destination"
+	}
+	LogSignDecision(req, allowed, reason)
+	return allowed
+}
+
+// decide returns whether to make a signature and why
+func (a *SignApprover) decide(req SignRequest) (bool, string) {
+	grant := req.Kind + "\x00" + req.Key + "\x00" + req.subject()
+	if until, ok := a.granted(grant); ok && req.grantable() {
+		return true, "allowed until " + until.Format("15:04:05")
+	}
+
+	a.promptMu.Lock()
+	defer a.promptMu.Unlock()
+	// An answer to the prompt this one waited for may cover it
+	if until, ok := a.granted(grant); ok && req.grantable() {
+		return true, "allowed until " + until.Format("15:04:05")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), signPromptTimeout)
+	defer cancel()
+	answer, err := a.prompter.Prompt(ctx, req, a.grantFor)
+	if ctx.Err() == context.DeadlineExceeded {
+		return false, fmt.Sprintf("no answer at the %s prompt within %s", a.method, signPromptTimeout)
+	}
+	if err != nil {
+		signLogger.Warningf("%s: %s prompt failed: %v", a.container, a.method, err)
+		return false, fmt.Sprintf("%s prompt failed: %v", a.method, err)
+	}
+
+	// Prompts don't offer grants they can't give; don't record one anyway
+	if answer == SignGrant && !req.grantable() {
+		answer = SignOnce
+	}
+	switch answer {
+	case SignOnce:
+		return true, "allowed once at the " + a.method + " prompt"
+	case SignGrant:
+		until := time.Now().Add(a.grantFor)
+		a.mu.Lock()
+		a.grants[grant] = until
+		a.mu.Unlock()
+		return true, fmt.Sprintf("allowed %s for %s at the %s prompt", req.subject(), formatGrant(a.grantFor), a.method)
+	}
+	return false, "denied at the " + a.method + " prompt"
+}
+
+// granted returns when a grant expires, if it's still valid
+func (a *SignApprover) granted(grant string) (time.Time, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	until, ok := a.grants[grant]
+	if ok && time.Now().After(until) {
+		delete(a.grants, grant)
+		return time.Time{}, false
+	}
+	return until, ok
+}
+
+// grantLabel is the prompt's "allow for a while" choice, or "" when the
+// request can't be granted
+func grantLabel(req SignRequest, grant time.Duration) string {
+	if !req.grantable() {
+		return ""
+	}
+	return fmt.Sprintf("Allow %s for %s", req.subject(), formatGrant(grant))
+}
+
+// formatGrant formats a grant's duration as "10 minutes"
+func formatGrant(d time.Duration) string {
+	if d%time.Hour == 0 {
+		if d == time.Hour {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	}
+	if d == time.Minute {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", d/time.Minute)
+}
+
+// runPromptCommand runs a desktop dialog and returns what it printed
+var runPromptCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).Output()
+}
+
+// desktopPromptAvailable reports whether desktopPrompter can show a dialog
+func desktopPromptAvailable() bool {
+	if runtime.GOOS == "darwin" {
+		_, err := exec.LookPath("osascript")
+		return err == nil
+	}
+	if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		return false
+	}
+	for _, name := range []string{"zenity", "notify-send"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// desktopPrompter asks with a dialog: osascript on macOS, zenity or a
+// notify-send notification with actions on Linux
+type desktopPrompter struct{}
+
+func (desktopPrompter) Prompt(ctx context.Context, req SignRequest, grant time.Duration) (SignAnswer, error) {
+	label := grantLabel(req, grant)
+	seconds := fmt.Sprint(int(signPromptTimeout.Seconds()))
+
+	if runtime.GOOS == "darwin" {
+		// The text goes in as arguments, so it needs no quoting
+		buttons, args := `{"Deny", "Allow once"}`, []string{req.String()}
+		if label != "" {
+			buttons, args = `{"Deny", "Allow once", (item 2 of argv)}`, append(args, label)
+		}
+		out, err := runPromptCommand(ctx, "osascript", append([]string{
+			"-e", "on run argv",
+			"-e", `display dialog (item 1 of argv) with title "addt" buttons ` + buttons + ` default button "Deny" giving up after ` + seconds,
+			"-e", "end run"}, args...)...)
+		if err != nil {
+			return SignDeny, err
+		}
+		switch {
+		case label != "" && strings.Contains(string(out), "button returned:"+label):
+			return SignGrant, nil
+		case strings.Contains(string(out), "button returned:Allow once"):
+			return SignOnce, nil
+		}
+		return SignDeny, nil
+	}
+
+	if _, err := exec.LookPath("zenity"); err == nil {
+		// Exits 0 for "Allow once"; the extra button prints its label
+		args := []string{"--question", "--title", "addt",
+			"--text", req.String(), "--ok-label", "Allow once", "--cancel-label", "Deny", "--timeout", seconds}
+		if label != "" {
+			args = append(args, "--extra-button", label)
+		}
+		out, err := runPromptCommand(ctx, "zenity", args...)
+		if label != "" && strings.TrimSpace(string(out)) == label {
+			return SignGrant, nil
+		}
+		var exitErr *exec.ExitError
+		if err == nil {
+			return SignOnce, nil
+		} else if errors.As(err, &exitErr) {
+			return SignDeny, nil
+		}
+		return SignDeny, err
+	}
+
+	args := []string{"--app-name", "addt", "--urgency", "critical",
+		"--wait", "--expire-time", seconds + "000", "--action", "once=Allow once"}
+	if label != "" {
+		args = append(args, "--action", "grant="+label)
+	}
+	args = append(args, "--action", "deny=Deny", "addt: signature requested", req.String())
+	out, err := runPromptCommand(ctx, "notify-send", args...)
+	if err != nil {
+		return SignDeny, err
+	}
+	switch strings.TrimSpace(string(out)) {
+	case "once":
+		return SignOnce, nil
+	case "grant":
+		if label != "" {
+			return SignGrant, nil
+		}
+	}
+	return SignDeny, nil
+}
+
+// ttyPrompter asks on the terminal addt was started from. An interactive
+// session reads the same terminal, so it suits sessions that don't.
+type ttyPrompter struct{}
+
+func (ttyPrompter) Prompt(ctx context.Context, req SignRequest, grant time.Duration) (SignAnswer, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return SignDeny, fmt.Errorf("no terminal: %w", err)
+	}
+	defer tty.Close()
+
+	label := grantLabel(req, grant)
+	if label != "" {
+		fmt.Fprintf(tty, "\r\naddt: %s\r\n  [o] allow once, [g] %s, [N] deny? ", req, strings.ToLower(label))
+	} else {
+		fmt.Fprintf(tty, "\r\naddt: %s\r\n  [o] allow once, [N] deny? ", req)
+	}
+	line := make(chan string, 1)
+	go func() {
+		text, _ := bufio.NewReader(tty).ReadString('\n')
+		line <- text
+	}()
+	select {
+	case <-ctx.Done():
+		fmt.Fprint(tty, "\r\n")
+		return SignDeny, ctx.Err()
+	case text := <-line:
+		switch strings.ToLower(strings.TrimSpace(text)) {
+		case "o", "once":
+			return SignOnce, nil
+		case "g", "grant":
+			if label != "" {
+				return SignGrant, nil
+			}
+		}
+		return SignDeny, nil
+	}
+}
+
+// signPollInterval is how often a web prompt looks for its answer
+var signPollInterval = 250 * time.Millisecond
+
+// SignRequestDir holds the prompts addt-orchestrator shows: a
+// <id>.json file per request, answered by an <id>.answer file
+func SignRequestDir() string {
+	return filepath.Join(SessionDir(), "sign-requests")
+}
+
+// PendingSignRequest is a prompt waiting for an answer in addt-orchestrator
+type PendingSignRequest struct {
+	ID string `json:"id"`
+	SignRequest
+	Grant   string    `json:"grant"` // the "allow for a while" choice, e.g. "Allow github.com for 10 minutes"; "" if none
+	Created time.Time `json:"created"`
+}
+
+// errNoOrchestrator refuses web prompts nobody would see
+var errNoOrchestrator = errors.New("signatures are denied: addt-orchestrator isn't running to answer them (start it, or set security.sign_prompt to tty or desktop)")
+
+// signOrchestratorFile records the address addt-orchestrator listens on
+func signOrchestratorFile() string {
+	return filepath.Join(SignRequestDir(), "orchestrator")
+}
+
+// RegisterSignOrchestrator records the address addt-orchestrator listens
+// on, so web prompts can tell whether anyone will see them
+func RegisterSignOrchestrator(addr string) error {
+	if err := os.MkdirAll(SignRequestDir(), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(signOrchestratorFile(), []byte(addr), 0600)
+}
+
+// SignOrchestratorListening reports whether the addt-orchestrator that
+// registered last still accepts connections
+func SignOrchestratorListening() bool {
+	addr, err := os.ReadFile(signOrchestratorFile())
+	if err != nil {
+		return false
+	}
+	conn, err := net.DialTimeout("tcp", strings.TrimSpace(string(addr)), time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// webPrompter leaves the request for addt-orchestrator to show and waits
+// for its answer. Without a running addt-orchestrator it refuses at once
+// rather than wait out the prompt's timeout.
+type webPrompter struct{}
+
+func (webPrompter) Prompt(ctx context.Context, req SignRequest, grant time.Duration) (SignAnswer, error) {
+	if !SignOrchestratorListening() {
+		fmt.Fprintf(os.Stderr, "addt: %s; %v\n", req, errNoOrchestrator)
+		return SignDeny, errNoOrchestrator
+	}
+	dir := SignRequestDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return SignDeny, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return SignDeny, err
+	}
+	pending := PendingSignRequest{
+		ID:          hex.EncodeToString(id),
+		SignRequest: req,
+		Grant:       grantLabel(req, grant),
+		Created:     time.Now(),
+	}
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return SignDeny, err
+	}
+	requestFile := filepath.Join(dir, pending.ID+".json")
+	answerFile := filepath.Join(dir, pending.ID+".answer")
+	if err := os.WriteFile(requestFile, data, 0600); err != nil {
+		return SignDeny, err
+	}
+	defer os.Remove(requestFile)
+	defer os.Remove(answerFile)
+	fmt.Fprintf(os.Stderr, "addt: %s; waiting for an answer in addt-orchestrator\n", req)
+
+	ticker := time.NewTicker(signPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return SignDeny, ctx.Err()
+		case <-ticker.C:
+		}
+		answer, err := os.ReadFile(answerFile)
+		if err != nil {
+			continue
+		}
+		switch SignAnswer(strings.TrimSpace(string(answer))) {
+		case SignOnce:
+			return SignOnce, nil
+		case SignGrant:
+			return SignGrant, nil
+		}
+		return SignDeny, nil
+	}
+}
+
+// PendingSignRequests returns the prompts waiting for an answer, oldest
+// first
+func PendingSignRequests() ([]PendingSignRequest, error) {
+	files, err := filepath.Glob(filepath.Join(SignRequestDir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var pending []PendingSignRequest
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var p PendingSignRequest
+		if err := json.Unmarshal(data, &p); err != nil {
+			continue
+		}
+		// Left behind by a session that was killed while it waited
+		if time.Since(p.Created) > signPromptTimeout {
+			continue
+		}
+		if _, err := os.Stat(strings.TrimSuffix(file, ".json") + ".answer"); err == nil {
+			continue
+		}
+		pending = append(pending, p)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Created.Before(pending[j].Created) })
+	return pending, nil
+}
+
+// AnswerSignRequest answers a pending prompt with "once", "grant" or "deny"
+func AnswerSignRequest(id string, answer SignAnswer) error {
+	if _, err := hex.DecodeString(id); err != nil || id == "" {
+		return fmt.Errorf("invalid sign request id %q", id)
+	}
+	if answer != SignOnce && answer != SignGrant && answer != SignDeny {
+		return fmt.Errorf("invalid answer %q, want once, grant or deny", answer)
+	}
+	dir := SignRequestDir()
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if err != nil {
+		return fmt.Errorf("no pending sign request %s", id)
+	}
+	var pending PendingSignRequest
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return fmt.Errorf("invalid sign request %s: %w", id, err)
+	}
+	if answer == SignGrant && pending.Grant == "" {
+		return fmt.Errorf("sign request %s can only be allowed once or denied", id)
+	}
+	return os.WriteFile(filepath.Join(dir, id+".answer"), []byte(answer), 0600)
+}
@@ -0,0 +1,333 @@
+package security
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakePrompter answers prompts with a fixed answer and records them
+type fakePrompter struct {
+	answer  SignAnswer
+	prompts []SignRequest
+}
+
+func (f *fakePrompter) Prompt(ctx context.Context, req SignRequest, grant time.Duration) (SignAnswer, error) {
+	f.prompts = append(f.prompts, req)
+	return f.answer, nil
+}
+
+func newTestApprover(answer SignAnswer) (*SignApprover, *fakePrompter) {
+	prompter := &fakePrompter{answer: answer}
+	return &SignApprover{
+		container: "addt-test",
+		prompter:  prompter,
+		method:    "test",
+		grantFor:  time.Minute,
+		grants:    make(map[string]time.Time),
+	}, prompter
+}
+
+func TestSignApprover_Grant(t *testing.T) {
+	approver, prompter := newTestApprover(SignGrant)
+	github := SignRequest{Kind: "ssh", Key: "work", Host: "github.com"}
+
+	if !approver.Approve(github) || !approver.Approve(github) {
+		t.Fatal("expected granted signatures to be allowed")
+	}
+	if len(prompter.prompts) != 1 {
+		t.Errorf("prompted %d times, want once for a granted host", len(prompter.prompts))
+	}
+	if prompter.prompts[0].Container != "addt-test" {
+		t.Errorf("prompt container = %q, want addt-test", prompter.prompts[0].Container)
+	}
+
+	// Another host, or the same host with another key, asks again
+	approver.Approve(SignRequest{Kind: "ssh", Key: "work", Host: "gitlab.com"})
+	approver.Approve(SignRequest{Kind: "ssh", Key: "personal", Host: "github.com"})
+	if len(prompter.prompts) != 3 {
+		t.Errorf("prompted %d times, want 3", len(prompter.prompts))
+	}
+
+	// Expired grants ask again
+	for grant := range approver.grants {
+		approver.grants[grant] = time.Now().Add(-time.Second)
+	}
+	approver.Approve(github)
+	if len(prompter.prompts) != 4 {
+		t.Errorf("prompted %d times after the grant expired, want 4", len(prompter.prompts))
+	}
+}
+
+func TestSignApprover_UnverifiedNotGranted(t *testing.T) {
+	approver, prompter := newTestApprover(SignGrant)
+	req := SignRequest{Kind: "ssh", Key: "work", Unverified: true}
+	if grantLabel(req, time.Minute) != "" {
+		t.Error("expected no grant choice for an unverified login")
+	}
+	if !approver.Approve(req) || !approver.Approve(req) {
+		t.Fatal("expected a grant answer to allow the signature once")
+	}
+	if len(prompter.prompts) != 2 || len(approver.grants) != 0 {
+		t.Errorf("prompted %d times with %d grants; want each login asked, none granted", len(prompter.prompts), len(approver.grants))
+	}
+	if !strings.Contains(req.String(), "UNVERIFIED") {
+		t.Errorf("prompt %q should say the destination is unverified", req)
+	}
+
+	// Nor can the web panel grant it
+	t.Setenv("ADDT_HOME", t.TempDir())
+	os.MkdirAll(SignRequestDir(), 0700)
+	data, _ := json.Marshal(PendingSignRequest{ID: "00ff", SignRequest: req, Created: time.Now()})
+	os.WriteFile(filepath.Join(SignRequestDir(), "00ff.json"), data, 0600)
+	if err := AnswerSignRequest("00ff", SignGrant); err == nil {
+		t.Error("expected an error granting an unverified login")
+	}
+	if err := AnswerSignRequest("00ff", SignOnce); err != nil {
+		t.Errorf("AnswerSignRequest(once) error = %v", err)
+	}
+}
+
+func TestSignApprover_OnceAndDeny(t *testing.T) {
+	approver, prompter := newTestApprover(SignOnce)
+	req := SignRequest{Kind: "gpg", Key: "ABCD1234"}
+	if !approver.Approve(req) || !approver.Approve(req) {
+		t.Error("expected signatures allowed once to be allowed")
+	}
+	if len(prompter.prompts) != 2 {
+		t.Errorf("prompted %d times, want each signature", len(prompter.prompts))
+	}
+
+	approver, _ = newTestApprover(SignDeny)
+	if approver.Approve(req) {
+		t.Error("expected a denied signature to be refused")
+	}
+}
+
+func TestSignApprover_Audit(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "audit.log")
+	if err := EnableAuditLog(logFile); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableAuditLog()
+
+	approver, _ := newTestApprover(SignGrant)
+	approver.Approve(SignRequest{Kind: "ssh", Key: "work", Host: "github.com"})
+	approver.Approve(SignRequest{Kind: "ssh", Key: "work", Host: "github.com"})
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d audit events, want 2:\n%s", len(lines), data)
+	}
+	for _, want := range []string{`"type":"ssh_sign_allowed"`, `"container":"addt-test"`, `"host":"github.com"`, `"comment":"work"`, "allowed github.com for 1 minute"} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("first event lacks %s: %s", want, lines[0])
+		}
+	}
+	if !strings.Contains(lines[1], "allowed until") {
+		t.Errorf("second event should cite the grant: %s", lines[1])
+	}
+}
+
+func TestNewSignApprover(t *testing.T) {
+	if _, err := NewSignApprover("c", "carrier-pigeon", 0); err == nil {
+		t.Error("expected an error for an unknown prompt")
+	}
+	approver, err := NewSignApprover("c", "tty", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approver.Method() != "tty" || approver.grantFor != DefaultSignGrant {
+		t.Errorf("got method %s, grant %s", approver.Method(), approver.grantFor)
+	}
+}
+
+func TestNewSignApprover_Auto(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("macOS always has a desktop prompt")
+	}
+	t.Setenv("DISPLAY", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	defer func(orig func() bool) { stdinIsTerminal = orig }(stdinIsTerminal)
+
+	for _, tt := range []struct {
+		terminal bool
+		want     string
+	}{{true, "tty"}, {false, "web"}} {
+		stdinIsTerminal = func() bool { return tt.terminal }
+		approver, err := NewSignApprover("c", "auto", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if approver.Method() != tt.want {
+			t.Errorf("auto with a terminal %v: got %s, want %s", tt.terminal, approver.Method(), tt.want)
+		}
+	}
+}
+
+func TestDesktopPrompter_Zenity(t *testing.T) {
+	if _, err := os.Stat("/usr/bin/osascript"); err == nil {
+		t.Skip("macOS uses osascript")
+	}
+	exit1 := exec.Command("sh", "-c", "exit 1").Run()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "zenity"), []byte("#!/bin/sh\n"), 0755)
+	t.Setenv("PATH", dir)
+
+	req := SignRequest{Kind: "ssh", Container: "c", Key: "work", Host: "github.com"}
+	defer func(orig func(context.Context, string, ...string) ([]byte, error)) { runPromptCommand = orig }(runPromptCommand)
+	tests := []struct {
+		out  string
+		err  error
+		want SignAnswer
+	}{
+		{"", nil, SignOnce},
+		{"Allow github.com for 10 minutes\n", exit1, SignGrant},
+		{"", exit1, SignDeny},
+	}
+	for _, tt := range tests {
+		runPromptCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if name != "zenity" || !strings.Contains(strings.Join(args, " "), "github.com") {
+				t.Errorf("unexpected prompt %s %v", name, args)
+			}
+			return []byte(tt.out), tt.err
+		}
+		got, err := desktopPrompter{}.Prompt(context.Background(), req, 10*time.Minute)
+		if err != nil || got != tt.want {
+			t.Errorf("zenity printing %q: got %s, %v; want %s", tt.out, got, err, tt.want)
+		}
+	}
+}
+
+func TestWebPrompter_NoOrchestrator(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+	approver, err := NewSignApprover("c", "web", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approver.Ready() == nil {
+		t.Error("expected Ready() to report that addt-orchestrator isn't running")
+	}
+
+	start := time.Now()
+	got, err := webPrompter{}.Prompt(context.Background(), SignRequest{Kind: "gpg", Container: "c", Key: "ABCD"}, 0)
+	if got != SignDeny || err == nil || time.Since(start) > 5*time.Second {
+		t.Errorf("got %s, %v after %s; want an immediate denial", got, err, time.Since(start))
+	}
+	if pending, _ := PendingSignRequests(); len(pending) != 0 {
+		t.Errorf("left %d pending requests", len(pending))
+	}
+}
+
+func TestWebPrompter(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+	defer func(orig time.Duration) { signPollInterval = orig }(signPollInterval)
+	signPollInterval = 10 * time.Millisecond
+
+	orchestrator, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer orchestrator.Close()
+	if err := RegisterSignOrchestrator(orchestrator.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+
+	req := SignRequest{Kind: "ssh", Container: "c", Key: "work", Host: "github.com"}
+	answer := make(chan SignAnswer, 1)
+	go func() {
+		got, _ := webPrompter{}.Prompt(context.Background(), req, 10*time.Minute)
+		answer <- got
+	}()
+
+	var pending []PendingSignRequest
+	for i := 0; i < 100 && len(pending) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		pending, _ = PendingSignRequests()
+	}
+	if len(pending) != 1 {
+		t.Fatalf("got %d pending requests, want 1", len(pending))
+	}
+	if pending[0].Host != "github.com" || pending[0].Grant != "Allow github.com for 10 minutes" {
+		t.Errorf("pending request = %+v", pending[0])
+	}
+
+	if err := AnswerSignRequest(pending[0].ID, "maybe"); err == nil {
+		t.Error("expected an error for an invalid answer")
+	}
+	if err := AnswerSignRequest("../../etc", SignOnce); err == nil {
+		t.Error("expected an error for an invalid id")
+	}
+	if err := AnswerSignRequest(pending[0].ID, SignGrant); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-answer:
+		if got != SignGrant {
+			t.Errorf("answer = %s, want grant", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("prompt didn't see the answer")
+	}
+	if pending, _ := PendingSignRequests(); len(pending) != 0 {
+		t.Errorf("answered request still pending: %+v", pending)
+	}
+}
+
+func TestSSHProxyAgent_ConfirmsSignatures(t *testing.T) {
+	sessionID := []byte("session-id")
+	key := newTestHostKeys(t)["ed25519"]
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	os.WriteFile(knownHosts, []byte("github.com ssh-ed25519 "+base64.StdEncoding.EncodeToString(key.blob)+"\n"), 0600)
+
+	approver, prompter := newTestApprover(SignDeny)
+	proxy := &SSHProxyAgent{
+		allowedBlobs: map[string]bool{"userkey": true},
+		blobComments: map[string]string{"userkey": "work"},
+	}
+	proxy.SetApprover(approver, knownHosts)
+
+	client, proxySide := net.Pipe()
+	upstream, upstreamSide := net.Pipe()
+	go proxy.proxyClientToUpstream(proxySide, upstreamSide)
+	defer client.Close()
+	defer upstream.Close()
+
+	// The verified bind goes upstream, which ssh-agent keeps too
+	bind := sessionBindMessage(key.blob, sessionID, key.sign(sessionID), false)
+	go writeAgentMessage(client, bind)
+	if msg, err := readAgentMessage(upstream); err != nil || msg[0] != SSH_AGENTC_EXTENSION {
+		t.Fatalf("upstream got %v, %v; want the session-bind", msg, err)
+	}
+
+	// A denied login is refused without reaching upstream
+	go writeAgentMessage(client, loginSignRequest([]byte("userkey"), sessionID))
+	if msg, err := readAgentMessage(client); err != nil || msg[0] != SSH_AGENT_FAILURE {
+		t.Fatalf("client got %v, %v; want SSH_AGENT_FAILURE", msg, err)
+	}
+	if len(prompter.prompts) != 1 {
+		t.Fatalf("prompted %d times, want 1", len(prompter.prompts))
+	}
+	if got := prompter.prompts[0]; got.Key != "work" || got.Host != "github.com" {
+		t.Errorf("prompt = %+v, want key work and host github.com", got)
+	}
+
+	// A login in a session that wasn't bound doesn't name a host
+	go writeAgentMessage(client, loginSignRequest([]byte("userkey"), []byte("other")))
+	readAgentMessage(client)
+	if got := prompter.prompts[1]; got.Host != "" || !got.Unverified {
+		t.Errorf("unbound session prompt = %+v, want an unverified destination", got)
+	}
+}
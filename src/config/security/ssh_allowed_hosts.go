@@ -0,0 +1,131 @@
+package security
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// SSHHostRule lets keys whose comment contains Key (every key when Key is
+// empty) sign logins to Host: a known_hosts name, host:port, or a SHA256
+// host key fingerprint
+type SSHHostRule struct {
+	Key  string
+	Host string
+}
+
+// ParseSSHHostRules parses ssh.allowed_hosts entries: "github.com" for
+// every key, or "deploy=github.com" for keys matching "deploy"
+func ParseSSHHostRules(entries []string) ([]SSHHostRule, error) {
+	var rules []SSHHostRule
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		var rule SSHHostRule
+		if key, host, ok := strings.Cut(entry, "="); ok {
+			rule.Key, rule.Host = strings.TrimSpace(key), strings.TrimSpace(host)
+			if rule.Key == "" {
+				return nil, fmt.Errorf("invalid ssh.allowed_hosts entry %q: empty key filter", entry)
+			}
+		} else {
+			rule.Host = entry
+		}
+		if rule.Host == "" {
+			return nil, fmt.Errorf("invalid ssh.allowed_hosts entry %q: empty host", entry)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// matchesKey reports whether the rule applies to a key with this comment
+func (r SSHHostRule) matchesKey(comment string) bool {
+	return r.Key == "" || strings.Contains(strings.ToLower(comment), strings.ToLower(r.Key))
+}
+
+// matchesHost reports whether hostKey is the rule's host: its fingerprint,
+// or a key known_hosts lists for the name
+func (r SSHHostRule) matchesHost(knownHostsFile string, hostKey []byte) bool {
+	if strings.HasPrefix(r.Host, "SHA256:") {
+		return r.Host == SSHFingerprint(hostKey)
+	}
+	return KnownHostMatches(knownHostsFile, r.Host, hostKey)
+}
+
+// KnownHostMatches reports whether a known_hosts file lists hostKey for
+// host (host or host:port), including hashed entries. A revoked key never
+// matches.
+func KnownHostMatches(knownHostsFile, host string, hostKey []byte) bool {
+	f, err := os.Open(knownHostsFile)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	// known_hosts writes ports other than 22 as [host]:port
+	name := strings.ToLower(host)
+	if h, port, err := net.SplitHostPort(name); err == nil {
+		name = h
+		if port != "22" {
+			name = "[" + h + "]:" + port
+		}
+	}
+
+	matched := false
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		marker := ""
+		if len(fields) > 0 && strings.HasPrefix(fields[0], "@") {
+			marker, fields = fields[0], fields[1:]
+		}
+		if len(fields) < 3 || strings.HasPrefix(fields[0], "#") || marker == "@cert-authority" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(fields[2])
+		if err != nil || !bytes.Equal(key, hostKey) {
+			continue
+		}
+		if marker == "@revoked" {
+			return false
+		}
+		for _, pattern := range strings.Split(fields[0], ",") {
+			if knownHostPatternMatches(pattern, name) {
+				matched = true
+			}
+		}
+	}
+	return matched
+}
+
+// knownHostPatternMatches matches one known_hosts host field against a
+// name: plain names exactly, hashed names (|1|salt|hash) by their HMAC
+func knownHostPatternMatches(pattern, name string) bool {
+	if !strings.HasPrefix(pattern, "|1|") {
+		return strings.ToLower(pattern) == name
+	}
+	parts := strings.Split(pattern[3:], "|")
+	if len(parts) != 2 {
+		return false
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	want, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(name))
+	return hmac.Equal(mac.Sum(nil), want)
+}
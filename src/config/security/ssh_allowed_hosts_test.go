@@ -0,0 +1,177 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSSHHostRules(t *testing.T) {
+	rules, err := ParseSSHHostRules([]string{"github.com", " deploy = gitlab.com ", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SSHHostRule{{Host: "github.com"}, {Key: "deploy", Host: "gitlab.com"}}
+	if len(rules) != len(want) || rules[0] != want[0] || rules[1] != want[1] {
+		t.Errorf("ParseSSHHostRules() = %+v, want %+v", rules, want)
+	}
+
+	for _, entry := range []string{"=github.com", "deploy="} {
+		if _, err := ParseSSHHostRules([]string{entry}); err == nil {
+			t.Errorf("expected an error for %q", entry)
+		}
+	}
+}
+
+func TestKnownHostMatches(t *testing.T) {
+	keys := newTestHostKeys(t)
+	encode := func(key testHostKey) string {
+		return base64.StdEncoding.EncodeToString(key.blob)
+	}
+	hashed := func(host string) string {
+		salt := []byte("0123456789abcdefghij")
+		mac := hmac.New(sha1.New, salt)
+		mac.Write([]byte(host))
+		return "|1|" + base64.StdEncoding.EncodeToString(salt) + "|" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	file := filepath.Join(t.TempDir(), "known_hosts")
+	content := "github.com,140.82.121.3 ssh-ed25519 " + encode(keys["ed25519"]) + "\n" +
+		"[git.example.com]:2222 ecdsa-sha2-nistp256 " + encode(keys["ecdsa"]) + "\n" +
+		hashed("gitlab.com") + " ssh-rsa " + encode(keys["rsa"]) + "\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host string
+		key  string
+		want bool
+	}{
+		{"github.com", "ed25519", true},
+		{"GitHub.com", "ed25519", true},
+		{"github.com:22", "ed25519", true},
+		{"github.com", "ecdsa", false},
+		{"git.example.com:2222", "ecdsa", true},
+		{"git.example.com", "ecdsa", false},
+		{"gitlab.com", "rsa", true},
+		{"evil.com", "rsa", false},
+	}
+	for _, tt := range tests {
+		if got := KnownHostMatches(file, tt.host, keys[tt.key].blob); got != tt.want {
+			t.Errorf("KnownHostMatches(%s, %s key) = %v, want %v", tt.host, tt.key, got, tt.want)
+		}
+	}
+
+	// A revoked key never matches
+	revoked := content + "@revoked * ssh-ed25519 " + encode(keys["ed25519"]) + "\n"
+	if err := os.WriteFile(file, []byte(revoked), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if KnownHostMatches(file, "github.com", keys["ed25519"].blob) {
+		t.Error("expected a revoked host key not to match")
+	}
+}
+
+func TestSSHProxyAgent_AllowedHosts(t *testing.T) {
+	keys := newTestHostKeys(t)
+	github, gitlab := keys["ed25519"], keys["ecdsa"]
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	os.WriteFile(knownHosts, []byte(
+		"github.com ssh-ed25519 "+base64.StdEncoding.EncodeToString(github.blob)+"\n"+
+			"gitlab.com ecdsa-sha2-nistp256 "+base64.StdEncoding.EncodeToString(gitlab.blob)+"\n"), 0600)
+
+	logFile := filepath.Join(t.TempDir(), "audit.log")
+	if err := EnableAuditLog(logFile); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableAuditLog()
+
+	proxy := &SSHProxyAgent{
+		allowedBlobs: map[string]bool{"deploykey": true, "workkey": true},
+		blobComments: map[string]string{"deploykey": "deploy", "workkey": "work"},
+	}
+	rules, _ := ParseSSHHostRules([]string{"deploy=github.com"})
+	proxy.SetAllowedHosts(rules, knownHosts)
+
+	client, proxySide := net.Pipe()
+	upstream, upstreamSide := net.Pipe()
+	go proxy.proxyClientToUpstream(proxySide, upstreamSide)
+	defer client.Close()
+	defer upstream.Close()
+	upstreamMsgs, clientMsgs := agentMessages(upstream), agentMessages(client)
+
+	// forwarded reports whether msg reached upstream rather than failing
+	forwarded := func(msg []byte) bool {
+		t.Helper()
+		go writeAgentMessage(client, msg)
+		select {
+		case got := <-upstreamMsgs:
+			return got != nil
+		case got := <-clientMsgs:
+			if got == nil || got[0] != SSH_AGENT_FAILURE {
+				t.Fatalf("client got %v, want SSH_AGENT_FAILURE", got)
+			}
+			return false
+		}
+	}
+
+	// A bind to a host no rule allows is refused, and not remembered
+	if forwarded(sessionBindMessage(gitlab.blob, []byte("gitlab-session"), gitlab.sign([]byte("gitlab-session")), false)) {
+		t.Error("expected the gitlab.com bind to be refused")
+	}
+	if !forwarded(sessionBindMessage(github.blob, []byte("github-session"), github.sign([]byte("github-session")), false)) {
+		t.Fatal("expected the github.com bind to be forwarded")
+	}
+
+	if !forwarded(loginSignRequest([]byte("deploykey"), []byte("github-session"))) {
+		t.Error("expected the deploy key to log in to github.com")
+	}
+	if forwarded(loginSignRequest([]byte("deploykey"), []byte("gitlab-session"))) {
+		t.Error("expected the deploy key's login to gitlab.com to be refused")
+	}
+	commit := append([]byte{SSH_AGENTC_SIGN_REQUEST}, sshWire([]byte("deploykey"), []byte("SSHSIG\x00\x00\x00\x03git"))...)
+	if forwarded(append(commit, 0, 0, 0, 0)) {
+		t.Error("expected the deploy key's commit signature to be refused")
+	}
+	// Keys no rule names aren't limited
+	if !forwarded(loginSignRequest([]byte("workkey"), []byte("gitlab-session"))) {
+		t.Error("expected the work key to log in anywhere")
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := string(data)
+	for _, want := range []string{
+		`"type":"ssh_bind_denied","host":"gitlab.com"`,
+		`"type":"ssh_bind_allowed","host":"github.com"`,
+		`"type":"ssh_sign_denied","comment":"deploy","allowed":false,"reason":"login without a session-bind"`,
+		"not a login",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("audit log lacks %s:\n%s", want, log)
+		}
+	}
+}
+
+// agentMessages reads agent messages from conn until it's closed
+func agentMessages(conn net.Conn) <-chan []byte {
+	ch := make(chan []byte)
+	go func() {
+		defer close(ch)
+		for {
+			msg, err := readAgentMessage(conn)
+			if err != nil {
+				return
+			}
+			ch <- msg
+		}
+	}()
+	return ch
+}
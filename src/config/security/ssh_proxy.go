@@ -35,6 +35,10 @@ type SSHProxyAgent struct {
 	blobComments   map[string]string // maps blob to comment for audit logging
 	useTCP         bool              // listen on TCP instead of Unix socket (macOS + podman)
 	tcpPort        int               // TCP port when useTCP is true
+	tcpHost        string            // address the TCP listener binds
+	approver       *SignApprover     // asks before each signature (ssh.sign_policy confirm)
+	knownHosts     string            // names the hosts of session-bind requests
+	hostRules      []SSHHostRule     // destinations keys may log in to (ssh.allowed_hosts)
 }
 
 // NewSSHProxyAgent creates a new SSH proxy agent
@@ -83,9 +87,10 @@ func NewSSHProxyAgent(upstreamSocket string, allowedKeys []string) (*SSHProxyAge
 	}, nil
 }
 
-// NewSSHProxyAgentTCP creates an SSH proxy agent that listens on TCP.
-// Used on macOS where podman can't mount Unix sockets from the host.
-func NewSSHProxyAgentTCP(upstreamSocket string, allowedKeys []string) (*SSHProxyAgent, error) {
+// NewSSHProxyAgentTCP creates an SSH proxy agent that listens on TCP on
+// host, the address host.docker.internal leads to. Used on macOS where
+// podman can't mount Unix sockets from the host.
+func NewSSHProxyAgentTCP(upstreamSocket, host string, allowedKeys []string) (*SSHProxyAgent, error) {
 	if upstreamSocket == "" {
 		return nil, fmt.Errorf("upstream SSH_AUTH_SOCK not set")
 	}
@@ -96,6 +101,7 @@ func NewSSHProxyAgentTCP(upstreamSocket string, allowedKeys []string) (*SSHProxy
 		allowedBlobs:   make(map[string]bool),
 		blobComments:   make(map[string]string),
 		useTCP:         true,
+		tcpHost:        host,
 	}, nil
 }
 
@@ -115,9 +121,9 @@ func (p *SSHProxyAgent) Start() error {
 
 	var listener net.Listener
 	if p.useTCP {
-		// TCP mode: must bind 0.0.0.0 because containers connect via
-		// host.docker.internal which resolves to a non-loopback IP
-		l, err := net.Listen("tcp", "0.0.0.0:0")
+		// TCP mode: containers connect via host.docker.internal, so
+		// bind only the address it leads to, not every interface
+		l, err := net.Listen("tcp", net.JoinHostPort(p.tcpHost, "0"))
 		if err != nil {
 			return fmt.Errorf("failed to listen on TCP: %w", err)
 		}
@@ -177,6 +183,25 @@ func (p *SSHProxyAgent) SocketPath() string {
 	return p.proxySocket
 }
 
+// SetApprover makes each signature of an allowed key wait for approval;
+// knownHostsFile names the login destinations prompts show
+func (p *SSHProxyAgent) SetApprover(approver *SignApprover, knownHostsFile string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.approver = approver
+	p.knownHosts = knownHostsFile
+}
+
+// SetAllowedHosts limits the keys rules match to logging in to the rules'
+// hosts, checked against the host keys ssh binds connections to; those
+// keys make no other signatures. knownHostsFile maps names to host keys.
+func (p *SSHProxyAgent) SetAllowedHosts(rules []SSHHostRule, knownHostsFile string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.hostRules = rules
+	p.knownHosts = knownHostsFile
+}
+
 func (p *SSHProxyAgent) acceptLoop() {
 	for {
 		conn, err := p.listener.Accept()
@@ -223,6 +248,9 @@ func (p *SSHProxyAgent) handleConnection(client net.Conn) {
 }
 
 func (p *SSHProxyAgent) proxyClientToUpstream(client, upstream net.Conn) {
+	// Hosts ssh bound this connection to, for prompts
+	var binds []*SSHSessionBind
+
 	for {
 		msg, err := readAgentMessage(client)
 		if err != nil {
@@ -235,6 +263,23 @@ func (p *SSHProxyAgent) proxyClientToUpstream(client, upstream net.Conn) {
 
 		msgType := msg[0]
 
+		// Remember verified session binds; a forged one fails as it would
+		// with ssh-agent
+		if msgType == SSH_AGENTC_EXTENSION {
+			bind, err := ParseSessionBind(msg)
+			if err != nil || (bind != nil && len(binds) >= maxSessionBinds) {
+				writeAgentMessage(client, []byte{SSH_AGENT_FAILURE})
+				continue
+			}
+			if bind != nil && !p.checkBind(bind) {
+				writeAgentMessage(client, []byte{SSH_AGENT_FAILURE})
+				continue
+			}
+			if bind != nil {
+				binds = append(binds, bind)
+			}
+		}
+
 		// Filter sign requests - only allow for permitted keys
 		if msgType == SSH_AGENTC_SIGN_REQUEST {
 			allowed, keyComment := p.checkSignRequest(msg)
@@ -244,7 +289,24 @@ func (p *SSHProxyAgent) proxyClientToUpstream(client, upstream net.Conn) {
 				writeAgentMessage(client, []byte{SSH_AGENT_FAILURE})
 				continue
 			}
-			LogSSHSign(keyComment, true, "")
+			if host, reason, ok := p.checkDestination(binds, msg, keyComment); !ok {
+				LogSignDecision(SignRequest{Kind: "ssh", Key: keyComment, Host: host}, false, reason)
+				writeAgentMessage(client, []byte{SSH_AGENT_FAILURE})
+				continue
+			}
+			p.mu.Lock()
+			approver := p.approver
+			p.mu.Unlock()
+			if approver != nil {
+				host, unverified := p.signHost(binds, msg)
+				req := SignRequest{Kind: "ssh", Key: keyComment, Host: host, Unverified: unverified}
+				if !approver.Approve(req) {
+					writeAgentMessage(client, []byte{SSH_AGENT_FAILURE})
+					continue
+				}
+			} else {
+				LogSSHSign(keyComment, true, "")
+			}
 		}
 
 		// Forward to upstream
@@ -382,6 +444,85 @@ func (p *SSHProxyAgent) checkSignRequest(msg []byte) (allowed bool, keyComment s
 	return isAllowed, comment
 }
 
+// checkBind reports whether a session-bind's host is one ssh.allowed_hosts
+// lets some key log in to, logging the decision when hosts are limited
+func (p *SSHProxyAgent) checkBind(bind *SSHSessionBind) bool {
+	p.mu.Lock()
+	rules, knownHosts := p.hostRules, p.knownHosts
+	p.mu.Unlock()
+	if len(rules) == 0 {
+		return true
+	}
+	host := KnownHostName(knownHosts, bind.HostKey)
+	for _, rule := range rules {
+		if rule.matchesHost(knownHosts, bind.HostKey) {
+			LogSSHBind(host, true, "")
+			return true
+		}
+	}
+	LogSSHBind(host, false, "host not in ssh.allowed_hosts")
+	return false
+}
+
+// checkDestination checks a signature by a key ssh.allowed_hosts limits:
+// it must be a login in a session bound to one of the key's hosts. It
+// returns the destination, and why the signature is refused.
+func (p *SSHProxyAgent) checkDestination(binds []*SSHSessionBind, msg []byte, keyComment string) (host, reason string, ok bool) {
+	p.mu.Lock()
+	rules, knownHosts := p.hostRules, p.knownHosts
+	p.mu.Unlock()
+
+	var keyRules []SSHHostRule
+	for _, rule := range rules {
+		if rule.matchesKey(keyComment) {
+			keyRules = append(keyRules, rule)
+		}
+	}
+	if len(keyRules) == 0 {
+		return "", "", true
+	}
+
+	sessionID := signRequestSessionID(msg)
+	if sessionID == nil {
+		return "", "not a login; the key may only log in to ssh.allowed_hosts", false
+	}
+	var bind *SSHSessionBind
+	for _, b := range binds {
+		if bytes.Equal(b.SessionID, sessionID) {
+			bind = b
+		}
+	}
+	if bind == nil {
+		// ssh older than 8.9 doesn't bind sessions
+		return "", "login without a session-bind", false
+	}
+	host = KnownHostName(knownHosts, bind.HostKey)
+	for _, rule := range keyRules {
+		if rule.matchesHost(knownHosts, bind.HostKey) {
+			return host, "", true
+		}
+	}
+	return host, "host not in ssh.allowed_hosts for this key", false
+}
+
+// signHost returns the destination of a login signature: the host whose
+// session-bind matches the session it authenticates in. Other signatures,
+// e.g. of git commits, have none. Logins in sessions nothing bound are
+// unverified; the session decides whether ssh binds, so they name no host.
+func (p *SSHProxyAgent) signHost(binds []*SSHSessionBind, msg []byte) (host string, unverified bool) {
+	sessionID := signRequestSessionID(msg)
+	if sessionID == nil {
+		return "", false
+	}
+	for _, bind := range binds {
+		if bytes.Equal(bind.SessionID, sessionID) {
+			return KnownHostName(p.knownHosts, bind.HostKey), false
+		}
+	}
+	// ssh older than 8.9 doesn't bind sessions
+	return "", true
+}
+
 type sshKey struct {
 	blob    []byte
 	comment string
@@ -0,0 +1,262 @@
+package security
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// SSH_AGENTC_EXTENSION carries agent protocol extensions such as
+// session-bind@openssh.com
+const SSH_AGENTC_EXTENSION = 27
+
+// sessionBindExtension is the extension ssh (OpenSSH 8.9+) sends to bind
+// an agent connection to the host it's authenticating to
+const sessionBindExtension = "session-bind@openssh.com"
+
+// maxSessionBinds bounds the binds kept per agent connection, as
+// OpenSSH's agent does
+const maxSessionBinds = 16
+
+// SSHSessionBind is a verified session-bind@openssh.com request: ssh
+// telling the agent which host key signed the session it authenticates in
+type SSHSessionBind struct {
+	HostKey    []byte // the host's public key blob
+	SessionID  []byte // the session's exchange hash
+	Forwarding bool   // the session forwards the agent onwards
+}
+
+// ParseSessionBind parses an SSH_AGENTC_EXTENSION message. It returns nil
+// without an error for other extensions, and an error for a session-bind
+// whose signature the host key didn't make.
+func ParseSessionBind(msg []byte) (*SSHSessionBind, error) {
+	if len(msg) == 0 || msg[0] != SSH_AGENTC_EXTENSION {
+		return nil, nil
+	}
+	name, rest, ok := sshString(msg[1:])
+	if !ok || string(name) != sessionBindExtension {
+		return nil, nil
+	}
+	hostKey, rest, ok := sshString(rest)
+	if !ok {
+		return nil, fmt.Errorf("truncated session-bind")
+	}
+	sessionID, rest, ok := sshString(rest)
+	if !ok {
+		return nil, fmt.Errorf("truncated session-bind")
+	}
+	sig, rest, ok := sshString(rest)
+	if !ok || len(rest) < 1 {
+		return nil, fmt.Errorf("truncated session-bind")
+	}
+	if err := verifySSHSignature(hostKey, sessionID, sig); err != nil {
+		return nil, fmt.Errorf("session-bind signature: %w", err)
+	}
+	return &SSHSessionBind{HostKey: hostKey, SessionID: sessionID, Forwarding: rest[0] != 0}, nil
+}
+
+// signRequestSessionID returns the session ID an SSH_AGENTC_SIGN_REQUEST's
+// data starts with when it's a login (publickey userauth), or nil for
+// other data such as git commit signatures
+func signRequestSessionID(msg []byte) []byte {
+	if len(msg) == 0 || msg[0] != SSH_AGENTC_SIGN_REQUEST {
+		return nil
+	}
+	_, rest, ok := sshString(msg[1:])
+	if !ok {
+		return nil
+	}
+	data, _, ok := sshString(rest)
+	if !ok {
+		return nil
+	}
+	// SSH_MSG_USERAUTH_REQUEST follows the session ID
+	sessionID, rest, ok := sshString(data)
+	if !ok || len(sessionID) == 0 || len(rest) == 0 || rest[0] != 50 {
+		return nil
+	}
+	return sessionID
+}
+
+// sshString splits an SSH wire string (uint32 length, bytes) off data
+func sshString(data []byte) (value, rest []byte, ok bool) {
+	if len(data) < 4 {
+		return nil, nil, false
+	}
+	n := binary.BigEndian.Uint32(data)
+	if uint64(len(data)-4) < uint64(n) {
+		return nil, nil, false
+	}
+	return data[4 : 4+n], data[4+n:], true
+}
+
+// verifySSHSignature checks an SSH signature blob over data by the public
+// key blob: Ed25519, ECDSA (P-256, P-384, P-521) and RSA host keys
+func verifySSHSignature(keyBlob, data, sigBlob []byte) error {
+	keyType, keyRest, ok := sshString(keyBlob)
+	if !ok {
+		return fmt.Errorf("invalid host key")
+	}
+	sigType, sigRest, ok := sshString(sigBlob)
+	if !ok {
+		return fmt.Errorf("invalid signature")
+	}
+	sig, _, ok := sshString(sigRest)
+	if !ok {
+		return fmt.Errorf("invalid signature")
+	}
+
+	switch string(keyType) {
+	case "ssh-ed25519":
+		pub, _, ok := sshString(keyRest)
+		if !ok || len(pub) != ed25519.PublicKeySize || string(sigType) != "ssh-ed25519" {
+			return fmt.Errorf("invalid ed25519 key or signature")
+		}
+		if !ed25519.Verify(pub, data, sig) {
+			return fmt.Errorf("verification failed")
+		}
+		return nil
+
+	case "ecdsa-sha2-nistp256", "ecdsa-sha2-nistp384", "ecdsa-sha2-nistp521":
+		if string(sigType) != string(keyType) {
+			return fmt.Errorf("%s signature for a %s key", sigType, keyType)
+		}
+		_, rest, _ := sshString(keyRest) // curve name
+		point, _, ok := sshString(rest)
+		if !ok {
+			return fmt.Errorf("invalid ecdsa key")
+		}
+		pub, digest, err := ecdsaKey(string(keyType), point, data)
+		if err != nil {
+			return err
+		}
+		r, rest, ok := sshString(sig)
+		if !ok {
+			return fmt.Errorf("invalid ecdsa signature")
+		}
+		s, _, ok := sshString(rest)
+		if !ok {
+			return fmt.Errorf("invalid ecdsa signature")
+		}
+		if !ecdsa.Verify(pub, digest, new(big.Int).SetBytes(r), new(big.Int).SetBytes(s)) {
+			return fmt.Errorf("verification failed")
+		}
+		return nil
+
+	case "ssh-rsa":
+		e, rest, ok := sshString(keyRest)
+		if !ok {
+			return fmt.Errorf("invalid rsa key")
+		}
+		n, _, ok := sshString(rest)
+		if !ok || len(e) > 4 {
+			return fmt.Errorf("invalid rsa key")
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		var hash crypto.Hash
+		var digest []byte
+		switch string(sigType) {
+		case "rsa-sha2-256":
+			sum := sha256.Sum256(data)
+			hash, digest = crypto.SHA256, sum[:]
+		case "rsa-sha2-512":
+			sum := sha512.Sum512(data)
+			hash, digest = crypto.SHA512, sum[:]
+		case "ssh-rsa":
+			sum := sha1.Sum(data)
+			hash, digest = crypto.SHA1, sum[:]
+		default:
+			return fmt.Errorf("%s signature for an rsa key", sigType)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, hash, digest, sig); err != nil {
+			return fmt.Errorf("verification failed")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported host key type %s", keyType)
+}
+
+// ecdsaKey returns an ECDSA public key from its uncompressed point and
+// the digest of data its signatures are made over
+func ecdsaKey(keyType string, point, data []byte) (*ecdsa.PublicKey, []byte, error) {
+	var curve elliptic.Curve
+	var ecdhCurve ecdh.Curve
+	var digest []byte
+	switch keyType {
+	case "ecdsa-sha2-nistp256":
+		sum := sha256.Sum256(data)
+		curve, ecdhCurve, digest = elliptic.P256(), ecdh.P256(), sum[:]
+	case "ecdsa-sha2-nistp384":
+		sum := sha512.Sum384(data)
+		curve, ecdhCurve, digest = elliptic.P384(), ecdh.P384(), sum[:]
+	default:
+		sum := sha512.Sum512(data)
+		curve, ecdhCurve, digest = elliptic.P521(), ecdh.P521(), sum[:]
+	}
+	// ecdh checks the point is on the curve
+	if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+		return nil, nil, fmt.Errorf("invalid ecdsa key: %w", err)
+	}
+	size := (len(point) - 1) / 2
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(point[1 : 1+size]),
+		Y:     new(big.Int).SetBytes(point[1+size:]),
+	}, digest, nil
+}
+
+// SSHFingerprint returns the SHA256 fingerprint of a public key blob, as
+// ssh-keygen -l shows it
+func SSHFingerprint(blob []byte) string {
+	sum := sha256.Sum256(blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// KnownHostName returns the first host name a known_hosts file lists for
+// a host key, or the key's fingerprint when no entry names it; hashed
+// entries can't be read back
+func KnownHostName(knownHostsFile string, hostKey []byte) string {
+	f, err := os.Open(knownHostsFile)
+	if err != nil {
+		return SSHFingerprint(hostKey)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "@") {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(fields[2])
+		if err != nil || !bytes.Equal(key, hostKey) {
+			continue
+		}
+		for _, host := range strings.Split(fields[0], ",") {
+			if strings.HasPrefix(host, "|") || strings.ContainsAny(host, "*?!") {
+				continue
+			}
+			// [host]:port for ports other than 22
+			if strings.HasPrefix(host, "[") {
+				host = strings.Replace(strings.TrimPrefix(host, "["), "]", "", 1)
+			}
+			return host
+		}
+	}
+	return SSHFingerprint(hostKey)
+}
@@ -0,0 +1,180 @@
+package security
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sshWire joins SSH wire strings
+func sshWire(parts ...[]byte) []byte {
+	var buf bytes.Buffer
+	for _, p := range parts {
+		binary.Write(&buf, binary.BigEndian, uint32(len(p)))
+		buf.Write(p)
+	}
+	return buf.Bytes()
+}
+
+// testHostKey is a host key blob and a function signing with it
+type testHostKey struct {
+	blob []byte
+	sign func(data []byte) []byte
+}
+
+func newTestHostKeys(t *testing.T) map[string]testHostKey {
+	t.Helper()
+	keys := make(map[string]testHostKey)
+
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys["ed25519"] = testHostKey{
+		blob: sshWire([]byte("ssh-ed25519"), edPub),
+		sign: func(data []byte) []byte {
+			return sshWire([]byte("ssh-ed25519"), ed25519.Sign(edPriv, data))
+		},
+	}
+
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	point := append([]byte{4}, ecPriv.PublicKey.X.FillBytes(make([]byte, 32))...)
+	point = append(point, ecPriv.PublicKey.Y.FillBytes(make([]byte, 32))...)
+	keys["ecdsa"] = testHostKey{
+		blob: sshWire([]byte("ecdsa-sha2-nistp256"), []byte("nistp256"), point),
+		sign: func(data []byte) []byte {
+			digest := sha256.Sum256(data)
+			r, s, err := ecdsa.Sign(rand.Reader, ecPriv, digest[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return sshWire([]byte("ecdsa-sha2-nistp256"), sshWire(r.Bytes(), s.Bytes()))
+		},
+	}
+
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys["rsa"] = testHostKey{
+		blob: sshWire([]byte("ssh-rsa"), big.NewInt(int64(rsaPriv.E)).Bytes(), append([]byte{0}, rsaPriv.N.Bytes()...)),
+		sign: func(data []byte) []byte {
+			digest := sha256.Sum256(data)
+			sig, err := rsa.SignPKCS1v15(rand.Reader, rsaPriv, crypto.SHA256, digest[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return sshWire([]byte("rsa-sha2-256"), sig)
+		},
+	}
+	return keys
+}
+
+// sessionBindMessage builds a session-bind@openssh.com extension request
+func sessionBindMessage(hostKey, sessionID, sig []byte, forwarding bool) []byte {
+	msg := append([]byte{SSH_AGENTC_EXTENSION}, sshWire([]byte(sessionBindExtension), hostKey, sessionID, sig)...)
+	if forwarding {
+		return append(msg, 1)
+	}
+	return append(msg, 0)
+}
+
+// loginSignRequest builds an SSH_AGENTC_SIGN_REQUEST for a publickey login
+// in a session
+func loginSignRequest(keyBlob, sessionID []byte) []byte {
+	data := append(sshWire(sessionID), 50)
+	data = append(data, sshWire([]byte("git"), []byte("ssh-connection"), []byte("publickey"))...)
+	msg := append([]byte{SSH_AGENTC_SIGN_REQUEST}, sshWire(keyBlob, data)...)
+	return append(msg, 0, 0, 0, 0)
+}
+
+func TestParseSessionBind(t *testing.T) {
+	sessionID := []byte("0123456789abcdef0123456789abcdef")
+	for name, key := range newTestHostKeys(t) {
+		t.Run(name, func(t *testing.T) {
+			bind, err := ParseSessionBind(sessionBindMessage(key.blob, sessionID, key.sign(sessionID), true))
+			if err != nil {
+				t.Fatalf("ParseSessionBind() error = %v", err)
+			}
+			if !bytes.Equal(bind.HostKey, key.blob) || !bytes.Equal(bind.SessionID, sessionID) || !bind.Forwarding {
+				t.Errorf("ParseSessionBind() = %+v", bind)
+			}
+
+			// Signed for another session: a forged bind
+			if _, err := ParseSessionBind(sessionBindMessage(key.blob, sessionID, key.sign([]byte("other")), false)); err == nil {
+				t.Error("expected an error for a signature over another session")
+			}
+		})
+	}
+}
+
+func TestParseSessionBind_OtherMessages(t *testing.T) {
+	other := append([]byte{SSH_AGENTC_EXTENSION}, sshWire([]byte("query"))...)
+	if bind, err := ParseSessionBind(other); bind != nil || err != nil {
+		t.Errorf("other extension: got %v, %v", bind, err)
+	}
+	if bind, err := ParseSessionBind([]byte{SSH_AGENTC_REQUEST_IDENTITIES}); bind != nil || err != nil {
+		t.Errorf("other message: got %v, %v", bind, err)
+	}
+	truncated := append([]byte{SSH_AGENTC_EXTENSION}, sshWire([]byte(sessionBindExtension), []byte("key"))...)
+	if _, err := ParseSessionBind(truncated); err == nil {
+		t.Error("expected an error for a truncated session-bind")
+	}
+}
+
+func TestSignRequestSessionID(t *testing.T) {
+	sessionID := []byte("session")
+	if got := signRequestSessionID(loginSignRequest([]byte("key"), sessionID)); !bytes.Equal(got, sessionID) {
+		t.Errorf("login: got %q, want %q", got, sessionID)
+	}
+
+	// git commit signatures start with the SSHSIG magic
+	commit := append([]byte{SSH_AGENTC_SIGN_REQUEST}, sshWire([]byte("key"), []byte("SSHSIG\x00\x00\x00\x03git"))...)
+	if got := signRequestSessionID(commit); got != nil {
+		t.Errorf("commit signature: got %q, want nil", got)
+	}
+}
+
+func TestKnownHostName(t *testing.T) {
+	keys := newTestHostKeys(t)
+	line := func(hosts string, key testHostKey) string {
+		return hosts + " ssh-ed25519 " + base64.StdEncoding.EncodeToString(key.blob) + "\n"
+	}
+	file := filepath.Join(t.TempDir(), "known_hosts")
+	content := "# comment\n" +
+		line("|1|c2FsdA==|aGFzaA== ", keys["rsa"]) +
+		line("github.com,140.82.121.3", keys["ed25519"]) +
+		line("[git.example.com]:2222", keys["ecdsa"])
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := KnownHostName(file, keys["ed25519"].blob); got != "github.com" {
+		t.Errorf("KnownHostName() = %q, want github.com", got)
+	}
+	if got := KnownHostName(file, keys["ecdsa"].blob); got != "git.example.com:2222" {
+		t.Errorf("KnownHostName() = %q, want git.example.com:2222", got)
+	}
+	// A hashed entry can't be read back
+	if got := KnownHostName(file, keys["rsa"].blob); !strings.HasPrefix(got, "SHA256:") {
+		t.Errorf("KnownHostName() = %q, want a fingerprint", got)
+	}
+	if got := KnownHostName(filepath.Join(t.TempDir(), "missing"), keys["rsa"].blob); got != SSHFingerprint(keys["rsa"].blob) {
+		t.Errorf("KnownHostName() = %q, want the fingerprint", got)
+	}
+}
@@ -19,6 +19,9 @@ type Settings struct {
 	DisableDevices  *bool    `yaml:"disable_devices,omitempty"`   // Drop MKNOD capability (default: false)
 	MemorySwap      string   `yaml:"memory_swap,omitempty"`       // Memory swap limit: "-1" to disable, or size (default: "")
 	IsolateSecrets  *bool    `yaml:"isolate_secrets,omitempty"`   // Isolate secrets from child processes (default: true)
+	APIProxy        *bool    `yaml:"api_proxy,omitempty"`         // Keep API keys on the host behind a proxy (default: false)
+	SignPrompt      string   `yaml:"sign_prompt,omitempty"`       // How sign_policy confirm asks: "auto", "desktop", "tty", "web" (default: "auto")
+	SignGrant       *int     `yaml:"sign_grant,omitempty"`        // Minutes "allow for a while" lasts (default: 10)
 	AuditLog        *bool    `yaml:"audit_log,omitempty"`         // Enable security audit logging (default: false)
 	AuditLogFile    string   `yaml:"audit_log_file,omitempty"`    // Path to audit log file (default: ~/.addt/audit.log)
 	Yolo            *bool    `yaml:"yolo,omitempty"`              // Enable yolo mode globally for all extensions (default: false)
@@ -43,6 +46,9 @@ type Config struct {
 	DisableDevices  bool     // Drop MKNOD capability (default: false)
 	MemorySwap      string   // Memory swap limit: "-1" to disable, or size (default: "")
 	IsolateSecrets  bool     // Isolate secrets from child processes (default: true)
+	APIProxy        bool     // Keep API keys on the host behind a proxy (default: false)
+	SignPrompt      string   // How sign_policy confirm asks: "auto", "desktop", "tty", "web" (default: "auto")
+	SignGrant       int      // Minutes "allow for a while" lasts (default: 10)
 	AuditLog        bool     // Enable security audit logging (default: false)
 	AuditLogFile    string   // Path to audit log file (default: ~/.addt/audit.log)
 	Yolo            bool     // Enable yolo mode globally for all extensions (default: false)
@@ -68,6 +74,9 @@ func DefaultConfig() Config {
 		DisableDevices:  false,
 		MemorySwap:      "",    // Empty = Docker default
 		IsolateSecrets:  true,  // Secure by default: isolate secrets from child processes
+		APIProxy:        false, // Disabled by default
+		SignPrompt:      "auto",
+		SignGrant:       10,
 		AuditLog:        false, // Disabled by default
 		AuditLogFile:    "",    // Empty = use default ~/.addt/audit.log
 		Yolo:            false, // Disabled by default
@@ -0,0 +1,183 @@
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/util"
+)
+
+// DestinationUsage is the traffic to one destination host, or the total of
+// a session when Host is empty. Requests counts tunnels and HTTP requests.
+type DestinationUsage struct {
+	Host     string `json:"host,omitempty"`
+	BytesOut int64  `json:"bytes_out"`
+	BytesIn  int64  `json:"bytes_in"`
+	Requests int    `json:"requests"`
+}
+
+// String formats usage for display, e.g. "↑1.2 MB ↓30.0 MB, 12 requests"
+func (u DestinationUsage) String() string {
+	return fmt.Sprintf("↑%s ↓%s, %d requests", util.FormatBytes(u.BytesOut), util.FormatBytes(u.BytesIn), u.Requests)
+}
+
+// NetworkUsage is the traffic of a container session through its egress
+// proxy. It's saved while the session runs, so other addt commands can
+// show it.
+type NetworkUsage struct {
+	Container    string             `json:"container"`
+	Started      time.Time          `json:"started"`
+	Updated      time.Time          `json:"updated"`
+	Destinations []DestinationUsage `json:"destinations"` // most data first
+}
+
+// Total sums the usage of all destinations
+func (u *NetworkUsage) Total() DestinationUsage {
+	var total DestinationUsage
+	for _, d := range u.Destinations {
+		total.BytesOut += d.BytesOut
+		total.BytesIn += d.BytesIn
+		total.Requests += d.Requests
+	}
+	return total
+}
+
+// sortDestinations orders destinations by data sent and received
+func (u *NetworkUsage) sortDestinations() {
+	sort.Slice(u.Destinations, func(i, j int) bool {
+		a, b := u.Destinations[i], u.Destinations[j]
+		if a.BytesOut+a.BytesIn != b.BytesOut+b.BytesIn {
+			return a.BytesOut+a.BytesIn > b.BytesOut+b.BytesIn
+		}
+		return a.Host < b.Host
+	})
+}
+
+// UsageDir returns the directory network usage is saved in
+func UsageDir() string {
+	return filepath.Join(util.GetAddtHome(), "usage")
+}
+
+func usageFile(container string) string {
+	return filepath.Join(UsageDir(), container+".json")
+}
+
+func resumeFile(container string) string {
+	return filepath.Join(UsageDir(), container+".resume")
+}
+
+// LoadNetworkUsage loads a container's last saved network usage; it returns
+// nil without an error when there is none
+func LoadNetworkUsage(container string) (*NetworkUsage, error) {
+	data, err := os.ReadFile(usageFile(container))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var usage NetworkUsage
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return nil, fmt.Errorf("invalid network usage file for %s: %w", container, err)
+	}
+	return &usage, nil
+}
+
+// SaveNetworkUsage saves a container's network usage, replacing the file so
+// readers never see part of it
+func SaveNetworkUsage(usage *NetworkUsage) error {
+	if err := os.MkdirAll(UsageDir(), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(usage, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := usageFile(usage.Container)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// RemoveNetworkUsage removes a container's saved network usage
+func RemoveNetworkUsage(container string) {
+	os.Remove(usageFile(container))
+	os.Remove(resumeFile(container))
+}
+
+// RequestEgressResume asks the session of a container paused by an egress
+// quota to continue
+func RequestEgressResume(container string) error {
+	if err := os.MkdirAll(UsageDir(), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(resumeFile(container), nil, 0600)
+}
+
+// EgressResumeRequested reports, once, whether resuming a container's
+// session was asked for since the last call
+func EgressResumeRequested(container string) bool {
+	return os.Remove(resumeFile(container)) == nil
+}
+
+// EgressQuota limits a session's traffic through the egress proxy; zero
+// fields don't limit
+type EgressQuota struct {
+	MaxEgress   int64 // bytes sent
+	MaxRequests int   // tunnels and HTTP requests
+}
+
+// exceeded returns why usage is over the quota, or "" when it isn't
+func (q EgressQuota) exceeded(total DestinationUsage) string {
+	if q.MaxEgress > 0 && total.BytesOut > q.MaxEgress {
+		return fmt.Sprintf("sent %s, more than firewall.max_egress %s", util.FormatBytes(total.BytesOut), util.FormatBytes(q.MaxEgress))
+	}
+	if q.MaxRequests > 0 && total.Requests > q.MaxRequests {
+		return fmt.Sprintf("made %d requests, more than firewall.max_requests %d", total.Requests, q.MaxRequests)
+	}
+	return ""
+}
+
+// extend gives usage another quota's worth of room
+func (q EgressQuota) extend(base EgressQuota, total DestinationUsage) EgressQuota {
+	if base.MaxEgress > 0 {
+		q.MaxEgress = total.BytesOut + base.MaxEgress
+	}
+	if base.MaxRequests > 0 {
+		q.MaxRequests = total.Requests + base.MaxRequests
+	}
+	return q
+}
+
+// ParseByteSize parses a size such as "500MB", "2g" or "1048576" (bytes);
+// units are powers of 1024
+func ParseByteSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	units := []struct {
+		suffix string
+		scale  int64
+	}{
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+		{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+	}
+	scale := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(value, u.suffix) {
+			value, scale = strings.TrimSpace(strings.TrimSuffix(value, u.suffix)), u.scale
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 500MB or 2GB)", s)
+	}
+	return int64(n * float64(scale)), nil
+}
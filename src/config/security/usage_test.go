@@ -0,0 +1,88 @@
+package security
+
+import (
+	"testing"
+)
+
+func TestNetworkUsage_SaveLoad(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+
+	if usage, err := LoadNetworkUsage("addt-none"); usage != nil || err != nil {
+		t.Errorf("LoadNetworkUsage(missing) = %+v, %v", usage, err)
+	}
+	usage := &NetworkUsage{Container: "addt-test", Destinations: []DestinationUsage{
+		{Host: "a.example", BytesOut: 100, BytesIn: 2048, Requests: 2},
+		{Host: "b.example", BytesOut: 5, BytesIn: 0, Requests: 1},
+	}}
+	if err := SaveNetworkUsage(usage); err != nil {
+		t.Fatalf("SaveNetworkUsage() error = %v", err)
+	}
+	loaded, err := LoadNetworkUsage("addt-test")
+	if err != nil || loaded == nil || len(loaded.Destinations) != 2 {
+		t.Fatalf("LoadNetworkUsage() = %+v, %v", loaded, err)
+	}
+	if got := loaded.Total().String(); got != "↑105 B ↓2.0 KB, 3 requests" {
+		t.Errorf("Total() = %q", got)
+	}
+
+	RemoveNetworkUsage("addt-test")
+	if usage, _ := LoadNetworkUsage("addt-test"); usage != nil {
+		t.Errorf("usage still there after RemoveNetworkUsage: %+v", usage)
+	}
+}
+
+func TestEgressResumeRequested(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+	if EgressResumeRequested("addt-test") {
+		t.Error("resume requested before asking")
+	}
+	if err := RequestEgressResume("addt-test"); err != nil {
+		t.Fatal(err)
+	}
+	if !EgressResumeRequested("addt-test") || EgressResumeRequested("addt-test") {
+		t.Error("resume request should be seen exactly once")
+	}
+}
+
+func TestEgressQuota(t *testing.T) {
+	q := EgressQuota{MaxEgress: 1000, MaxRequests: 10}
+	if reason := q.exceeded(DestinationUsage{BytesOut: 1000, Requests: 10}); reason != "" {
+		t.Errorf("exceeded at the limit: %q", reason)
+	}
+	if reason := q.exceeded(DestinationUsage{BytesOut: 1001}); reason == "" {
+		t.Error("not exceeded over max egress")
+	}
+	if reason := q.exceeded(DestinationUsage{Requests: 11}); reason == "" {
+		t.Error("not exceeded over max requests")
+	}
+	if reason := (EgressQuota{}).exceeded(DestinationUsage{BytesOut: 1 << 40}); reason != "" {
+		t.Errorf("zero quota exceeded: %q", reason)
+	}
+	extended := q.extend(q, DestinationUsage{BytesOut: 1500, Requests: 12})
+	if extended != (EgressQuota{MaxEgress: 2500, MaxRequests: 22}) {
+		t.Errorf("extend() = %+v", extended)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"1048576", 1 << 20, false},
+		{"500MB", 500 << 20, false},
+		{"2g", 2 << 30, false},
+		{"1.5 KB", 1536, false},
+		{"10b", 10, false},
+		{"lots", 0, true},
+		{"-1MB", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -50,14 +50,23 @@ type PortsSettings struct {
 	Expose             []string `yaml:"expose,omitempty"`
 	RangeStart         *int     `yaml:"range_start,omitempty"`
 	InjectSystemPrompt *bool    `yaml:"inject_system_prompt,omitempty"`
+	HostServices       []string `yaml:"host_services,omitempty"`
 }
 
 // SSHSettings holds SSH forwarding configuration
 type SSHSettings struct {
-	ForwardKeys *bool    `yaml:"forward_keys,omitempty"`
-	ForwardMode string   `yaml:"forward_mode,omitempty"`
-	AllowedKeys []string `yaml:"allowed_keys,omitempty"`
-	Dir         string   `yaml:"dir,omitempty"`
+	ForwardKeys  *bool    `yaml:"forward_keys,omitempty"`
+	ForwardMode  string   `yaml:"forward_mode,omitempty"`
+	AllowedKeys  []string `yaml:"allowed_keys,omitempty"`
+	AllowedHosts []string `yaml:"allowed_hosts,omitempty"` // "host" or "key=host" login destinations
+	Dir          string   `yaml:"dir,omitempty"`
+	SignPolicy   string   `yaml:"sign_policy,omitempty"` // "allow" or "confirm"
+}
+
+// OfflineSettings holds offline mode configuration
+type OfflineSettings struct {
+	Enabled *bool `yaml:"enabled,omitempty"`
+	Fetch   *bool `yaml:"fetch,omitempty"`
 }
 
 // GitHubSettings holds GitHub token forwarding configuration
@@ -66,14 +75,26 @@ type GitHubSettings struct {
 	TokenSource  string   `yaml:"token_source,omitempty"`
 	ScopeToken   *bool    `yaml:"scope_token,omitempty"`
 	ScopeRepos   []string `yaml:"scope_repos,omitempty"`
+	HoldToken    *bool    `yaml:"hold_token,omitempty"` // keep GH_TOKEN on the host
+
+	// GitHub App whose installation tokens the git credential helper mints
+	AppID             string   `yaml:"app_id,omitempty"`
+	AppInstallationID string   `yaml:"app_installation_id,omitempty"`
+	AppKeyFile        string   `yaml:"app_key_file,omitempty"`
+	AppPermissions    []string `yaml:"app_permissions,omitempty"`
 }
 
 // FirewallSettings holds network firewall configuration
 type FirewallSettings struct {
-	Enabled *bool    `yaml:"enabled,omitempty"`
-	Mode    string   `yaml:"mode,omitempty"`
-	Allowed []string `yaml:"allowed,omitempty"`
-	Denied  []string `yaml:"denied,omitempty"`
+	Enabled     *bool    `yaml:"enabled,omitempty"`
+	Mode        string   `yaml:"mode,omitempty"`
+	Proxy       *bool    `yaml:"proxy,omitempty"`
+	Intercept   *bool    `yaml:"intercept,omitempty"`
+	MaxEgress   string   `yaml:"max_egress,omitempty"`
+	MaxRequests *int     `yaml:"max_requests,omitempty"`
+	QuotaAction string   `yaml:"quota_action,omitempty"`
+	Allowed     []string `yaml:"allowed,omitempty"`
+	Denied      []string `yaml:"denied,omitempty"`
 }
 
 // GPGSettings holds GPG forwarding configuration
@@ -81,6 +102,7 @@ type GPGSettings struct {
 	Forward       string   `yaml:"forward,omitempty"`         // "proxy", "agent", "keys", or "off"
 	AllowedKeyIDs []string `yaml:"allowed_key_ids,omitempty"` // GPG key IDs allowed
 	Dir           string   `yaml:"dir,omitempty"`
+	SignPolicy    string   `yaml:"sign_policy,omitempty"` // "allow" or "confirm"
 }
 
 // GitSettings holds git config forwarding configuration
@@ -122,10 +144,13 @@ type TerminalSettings struct {
 
 // WorkdirSettings holds working directory configuration
 type WorkdirSettings struct {
-	Path      string `yaml:"path,omitempty"`      // Override working directory (default: current directory)
-	Automount *bool  `yaml:"automount,omitempty"` // Auto-mount working directory to /workspace
-	Readonly  *bool  `yaml:"readonly,omitempty"`  // Mount working directory as read-only
-	Autotrust *bool  `yaml:"autotrust,omitempty"` // Trust the /workspace directory on first launch (default: true)
+	Path         string `yaml:"path,omitempty"`          // Override working directory (default: current directory)
+	Automount    *bool  `yaml:"automount,omitempty"`     // Auto-mount working directory to /workspace
+	Readonly     *bool  `yaml:"readonly,omitempty"`      // Mount working directory as read-only
+	Overlay      *bool  `yaml:"overlay,omitempty"`       // Mount a copy-on-write overlay, applied with addt apply
+	Worktree     *bool  `yaml:"worktree,omitempty"`      // Mount a git worktree per container, on its own branch
+	WorktreeName string `yaml:"worktree_name,omitempty"` // Session name of the worktree and its addt/<name> branch
+	Autotrust    *bool  `yaml:"autotrust,omitempty"`     // Trust the /workspace directory on first launch (default: true)
 }
 
 // ProviderSettings holds provider selection configuration
@@ -148,6 +173,7 @@ type GlobalConfig struct {
 	GPG            *GPGSettings       `yaml:"gpg,omitempty"`
 	Log            *LogSettings       `yaml:"log,omitempty"`
 	NodeVersion    string             `yaml:"node_version,omitempty"`
+	Offline        *OfflineSettings   `yaml:"offline,omitempty"`
 	Persistent     *bool              `yaml:"persistent,omitempty"`
 	Ports          *PortsSettings     `yaml:"ports,omitempty"`
 	SSH            *SSHSettings       `yaml:"ssh,omitempty"`
@@ -159,6 +185,9 @@ type GlobalConfig struct {
 	Auth           *AuthSettings      `yaml:"auth,omitempty"`
 	Config         *ConfigSettings    `yaml:"config,omitempty"`
 
+	// Env vars fetched from secret backends, e.g. ANTHROPIC_API_KEY: op://Dev/anthropic/key
+	Secrets map[string]string `yaml:"secrets,omitempty"`
+
 	// Per-extension configuration
 	Extensions map[string]*ExtensionSettings `yaml:"extensions,omitempty"`
 
@@ -180,21 +209,31 @@ type Config struct {
 	GitHubTokenSource         string
 	GitHubScopeToken          bool
 	GitHubScopeRepos          []string
+	GitHubHoldToken           bool
+	GitHubAppID               string
+	GitHubAppInstallationID   string
+	GitHubAppKeyFile          string
+	GitHubAppPermissions      []string
 	Ports                     []string
 	PortRangeStart            int
 	PortsInjectSystemPrompt   bool
+	PortsHostServices         []string
+	Secrets                   map[string]string // env var -> secret reference
 	SSHForwardKeys            bool
 	SSHForwardMode            string
 	SSHAllowedKeys            []string
+	SSHAllowedHosts           []string // Login destinations of forwarded keys, "host" or "key=host"
 	TmuxForward               bool
 	HistoryPersist            bool     // Persist shell history between sessions (default: false)
 	SSHDir                    string   // SSH directory path (default: ~/.ssh)
+	SSHSignPolicy             string   // "allow" or "confirm" each signature (default: allow)
 	GitDisableHooks           bool     // Neutralize git hooks inside container (default: true)
 	GitForwardConfig          bool     // Forward .gitconfig to container (default: true)
 	GitConfigPath             string   // Custom .gitconfig file path
 	GPGForward                string   // "proxy", "agent", "keys", or "off"
 	GPGAllowedKeyIDs          []string // GPG key IDs allowed for signing
 	GPGDir                    string   // GPG directory path (default: ~/.gnupg)
+	GPGSignPolicy             string   // "allow" or "confirm" each signature (default: allow)
 	DockerDindMode            string
 	EnvFileLoad               bool
 	EnvFile                   string
@@ -211,10 +250,21 @@ type Config struct {
 	Persistent                bool                       // Enable persistent container mode
 	WorkdirAutomount          bool                       // Auto-mount working directory
 	WorkdirReadonly           bool                       // Mount working directory as read-only
+	WorkdirOverlay            bool                       // Mount a copy-on-write overlay of the working directory
+	WorkdirWorktree           bool                       // Mount a git worktree per container instead of the checkout
+	WorkdirWorktreeName       string                     // Session name of the worktree and its addt/<name> branch
 	WorkdirAutotrust          bool                       // Trust the /workspace directory on first launch (default: true)
 	Workdir                   string                     // Override working directory (default: current directory)
 	FirewallEnabled           bool                       // Enable network firewall
 	FirewallMode              string                     // Firewall mode: strict, permissive, off
+	FirewallProxy             bool                       // Enforce rules by hostname through the host-side egress proxy
+	FirewallIntercept         bool                       // Intercept TLS to enforce method and path request rules
+	FirewallLearn             bool                       // Record attempted destinations for 'addt firewall learn review'
+	FirewallMaxEgress         string                     // Bytes a session may send through the egress proxy, e.g. "500MB" (empty = no limit)
+	FirewallMaxRequests       int                        // Requests a session may make through the egress proxy (0 = no limit)
+	FirewallQuotaAction       string                     // What happens over quota: pause or kill
+	OfflineEnabled            bool                       // Reach only the host-side package mirrors (default: false)
+	OfflineFetch              bool                       // Fetch packages the mirror cache is missing from upstream (default: true)
 	GlobalFirewallAllowed     []string                   // Global allowed domains
 	GlobalFirewallDenied      []string                   // Global denied domains
 	ProjectFirewallAllowed    []string                   // Project allowed domains
@@ -225,6 +275,7 @@ type Config struct {
 	Provider                  string                     // Provider type: docker or daytona
 	Extensions                string                     // Comma-separated list of extensions to install (e.g., "claude,codex")
 	Command                   string                     // Command to run instead of claude (e.g., "gt" for gastown)
+	ContainerName             string                     // Ephemeral container name override (set by fan-out runs)
 	ExtensionVersions         map[string]string          // Per-extension versions (e.g., {"claude": "1.0.5", "codex": "latest"})
 	ExtensionConfigAutomount  map[string]bool            // Per-extension config.automount override
 	ExtensionConfigReadonly   map[string]bool            // Per-extension config.readonly override
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/extensions"
+	"github.com/jedi4ever/addt/provider"
+)
+
+// auditRun records what a new run hands the container in the security
+// audit log: the mounts it gets, the names of the secrets it receives and
+// whether the agent runs in yolo mode. A reused persistent container got
+// its mounts and secrets when it was created.
+func (r *Runner) auditRun(spec *provider.RunSpec, reused bool) {
+	for _, ext := range yoloExtensions(r.config, spec.Args) {
+		security.LogYolo(spec.Name, ext.name, ext.reason)
+	}
+	if reused {
+		return
+	}
+
+	if !r.config.WorkdirAutomount {
+		security.LogMount(spec.Name, spec.WorkDir, "/workspace", false, "workdir automount disabled")
+	}
+	for _, v := range spec.Volumes {
+		mode := "read-write"
+		if v.ReadOnly {
+			mode = "read-only"
+		}
+		security.LogMount(spec.Name, v.Source, v.Target, true, mode)
+	}
+
+	if names := secretNames(r.provider, spec); len(names) > 0 {
+		via := "env"
+		if r.config.Security.IsolateSecrets {
+			via = "isolated"
+		}
+		security.LogSecretsInjected(spec.Name, names, via)
+	}
+}
+
+// secretNames returns the names of the extension secrets that have a value
+// in a run's environment, the ones a provider isolates when asked to
+func secretNames(p provider.Provider, spec *provider.RunSpec) []string {
+	var names []string
+	for _, s := range p.GetExtensionEnvVars(spec.ImageName) {
+		name, _ := parseEnvVarSpec(s)
+		names = append(names, name)
+	}
+	if credVars := spec.Env["ADDT_CREDENTIAL_VARS"]; credVars != "" {
+		for _, v := range strings.Split(credVars, ",") {
+			names = append(names, strings.TrimSpace(v))
+		}
+	}
+
+	var set []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if name == "" || seen[name] || spec.Env[name] == "" {
+			continue
+		}
+		seen[name] = true
+		set = append(set, name)
+	}
+	return set
+}
+
+// yoloExtension is an active extension running with permission checks
+// bypassed, and the setting that turned it on
+type yoloExtension struct {
+	name   string
+	reason string
+}
+
+// yoloExtensions returns the active extensions with yolo mode on, following
+// the precedence of addFlagEnvVars: CLI flag, extension flag setting, then
+// security.yolo
+func yoloExtensions(cfg *provider.Config, args []string) []yoloExtension {
+	allExts, err := extensions.GetExtensions()
+	if err != nil {
+		return nil
+	}
+	extNames := getActiveExtensionNames(cfg)
+
+	var result []yoloExtension
+	for _, ext := range allExts {
+		if !contains(extNames, ext.Name) {
+			continue
+		}
+		for _, flag := range ext.Flags {
+			if flag.EnvVar == "" || strings.TrimPrefix(flag.Flag, "--") != "yolo" {
+				continue
+			}
+			if contains(args, flag.Flag) {
+				result = append(result, yoloExtension{ext.Name, flag.Flag + " flag"})
+				continue
+			}
+			if val, ok := cfg.ExtensionFlagSettings[ext.Name]["yolo"]; ok {
+				if val {
+					result = append(result, yoloExtension{ext.Name, "extensions." + ext.Name + ".flags.yolo"})
+				}
+				continue
+			}
+			if cfg.Security.Yolo {
+				result = append(result, yoloExtension{ext.Name, "security.yolo"})
+			}
+		}
+	}
+	return result
+}
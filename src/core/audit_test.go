@@ -0,0 +1,54 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jedi4ever/addt/provider"
+)
+
+func TestYoloExtensions(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		settings map[string]map[string]bool
+		global   bool
+		want     []yoloExtension
+	}{
+		{"off", nil, nil, false, nil},
+		{"flag", []string{"--yolo"}, map[string]map[string]bool{"claude": {"yolo": false}}, false,
+			[]yoloExtension{{"claude", "--yolo flag"}}},
+		{"extension setting", nil, map[string]map[string]bool{"claude": {"yolo": true}}, false,
+			[]yoloExtension{{"claude", "extensions.claude.flags.yolo"}}},
+		{"extension setting overrides global", nil, map[string]map[string]bool{"claude": {"yolo": false}}, true, nil},
+		{"global", nil, nil, true, []yoloExtension{{"claude", "security.yolo"}}},
+	}
+	for _, tt := range tests {
+		cfg := &provider.Config{Extensions: "claude", ExtensionFlagSettings: tt.settings}
+		cfg.Security.Yolo = tt.global
+		if got := yoloExtensions(cfg, tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: yoloExtensions() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// secretsProvider is a mock provider whose extensions need an API key
+type secretsProvider struct{ mockEnvProvider }
+
+func (p *secretsProvider) GetExtensionEnvVars(imageName string) []string {
+	return []string{"ANTHROPIC_API_KEY", "OPENAI_API_KEY=unset"}
+}
+
+func TestSecretNames(t *testing.T) {
+	spec := &provider.RunSpec{Env: map[string]string{
+		"ANTHROPIC_API_KEY":         "sk-test",
+		"CLAUDE_OAUTH_CREDENTIALS":  "{}",
+		"ADDT_CREDENTIAL_VARS":      "CLAUDE_OAUTH_CREDENTIALS, EMPTY_VAR",
+		"ANTHROPIC_API_KEY_UNKNOWN": "x",
+	}}
+	got := secretNames(&secretsProvider{}, spec)
+	want := []string{"ANTHROPIC_API_KEY", "CLAUDE_OAUTH_CREDENTIALS"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("secretNames() = %v, want %v", got, want)
+	}
+}
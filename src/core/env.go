@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/jedi4ever/addt/config/otel"
+	"github.com/jedi4ever/addt/config/security"
 	"github.com/jedi4ever/addt/extensions"
 	"github.com/jedi4ever/addt/provider"
 	"github.com/jedi4ever/addt/util"
@@ -37,6 +39,9 @@ func BuildEnvironment(p provider.Provider, cfg *provider.Config) map[string]stri
 	// Add firewall configuration
 	addFirewallEnvVars(env, cfg)
 
+	// Point package managers at the package mirror when offline
+	addOfflineEnvVars(env, cfg)
+
 	// Add GitHub scope configuration
 	addGitHubScopeEnvVars(env, cfg)
 
@@ -78,6 +83,9 @@ func addExtensionEnvVars(env map[string]string, p provider.Provider, cfg *provid
 	// Run credential scripts for active extensions
 	// Track credential var names so the entrypoint can unset them after setup
 	addCredentialScriptEnvVars(env, cfg)
+
+	// Fetch secrets referenced in config from their backends
+	addSecretEnvVars(env, cfg)
 }
 
 // parseEnvVarSpec parses an env var specification that can be either:
@@ -136,6 +144,47 @@ func addCredentialScriptEnvVars(env map[string]string, cfg *provider.Config) {
 	}
 }
 
+// addSecretEnvVars fetches the secrets config references, e.g.
+// ANTHROPIC_API_KEY: op://Dev/anthropic/key, on the host. They replace
+// values from the host environment and credential scripts, and are added to
+// ADDT_CREDENTIAL_VARS so they take the same path as credential script vars.
+// A secret that can't be fetched is reported on stderr and left unset.
+func addSecretEnvVars(env map[string]string, cfg *provider.Config) {
+	if len(cfg.Secrets) == 0 {
+		return
+	}
+	dir := cfg.Workdir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	values, errs := extensions.ResolveSecrets(cfg.Secrets, dir)
+	failed := make([]string, 0, len(errs))
+	for name := range errs {
+		failed = append(failed, name)
+	}
+	sort.Strings(failed)
+	for _, name := range failed {
+		envLogger.Warning("secret %s not set: %v", name, errs[name])
+		fmt.Fprintf(os.Stderr, "Warning: secret %s not set: %v\n", name, errs[name])
+	}
+	if len(values) == 0 {
+		return
+	}
+
+	var names []string
+	if existing := env["ADDT_CREDENTIAL_VARS"]; existing != "" {
+		names = strings.Split(existing, ",")
+	}
+	for name, value := range values {
+		env[name] = value
+		if !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	env["ADDT_CREDENTIAL_VARS"] = strings.Join(names, ",")
+}
+
 // getActiveExtensionNames returns the list of active extension names
 func getActiveExtensionNames(cfg *provider.Config) []string {
 	if cfg.Extensions == "" {
@@ -270,6 +319,27 @@ func addFirewallEnvVars(env map[string]string, cfg *provider.Config) {
 	}
 }
 
+// addOfflineEnvVars points npm, pip, uv and go at the host-side package
+// mirror, which the entrypoint bridges to the container's loopback
+// interface. It overrides registries set in the user's env vars, which the
+// container can't reach offline.
+func addOfflineEnvVars(env map[string]string, cfg *provider.Config) {
+	if !cfg.OfflineEnabled {
+		return
+	}
+	mirror := fmt.Sprintf("http://127.0.0.1:%d", security.MirrorContainerPort)
+	env["ADDT_OFFLINE"] = "true"
+	env["npm_config_registry"] = mirror + "/npm/"
+	env["NPM_CONFIG_REGISTRY"] = mirror + "/npm/"
+	// The mirror only serves packages, not the audit and funding endpoints
+	env["npm_config_audit"] = "false"
+	env["npm_config_fund"] = "false"
+	env["PIP_INDEX_URL"] = mirror + "/pypi/simple/"
+	env["UV_INDEX_URL"] = mirror + "/pypi/simple/"
+	env["UV_DEFAULT_INDEX"] = mirror + "/pypi/simple/"
+	env["GOPROXY"] = mirror + "/go"
+}
+
 // addCommandEnvVar adds the command override environment variable
 func addCommandEnvVar(env map[string]string, cfg *provider.Config) {
 	if cfg.Command != "" {
@@ -1,9 +1,13 @@
 package core
 
 import (
+	"context"
+	"io"
+	"os"
 	"strings"
 	"testing"
 
+	"github.com/jedi4ever/addt/extensions"
 	"github.com/jedi4ever/addt/provider"
 )
 
@@ -27,6 +31,19 @@ func (m *mockEnvProvider) GetName() string                                    {
 func (m *mockEnvProvider) GetExtensionEnvVars(imageName string) []string      { return nil }
 func (m *mockEnvProvider) DetermineImageName() string                         { return "test-image" }
 func (m *mockEnvProvider) BuildIfNeeded(rebuild bool, rebuildBase bool) error { return nil }
+func (m *mockEnvProvider) Exec(name string, cmd []string, tty bool, stdio provider.Stdio) error {
+	return nil
+}
+func (m *mockEnvProvider) Logs(name string, follow bool, out io.Writer) error     { return nil }
+func (m *mockEnvProvider) CopyTo(name, hostPath, envPath string) error            { return nil }
+func (m *mockEnvProvider) CopyFrom(name, envPath, hostPath string) error          { return nil }
+func (m *mockEnvProvider) Inspect(name string) (*provider.EnvironmentInfo, error) { return nil, nil }
+func (m *mockEnvProvider) Snapshot(name, tag string, checkpoint bool) (*provider.Snapshot, error) {
+	return nil, nil
+}
+func (m *mockEnvProvider) Restore(name, tag string) error                         { return nil }
+func (m *mockEnvProvider) ListSnapshots(name string) ([]provider.Snapshot, error) { return nil, nil }
+func (m *mockEnvProvider) RemoveSnapshot(name, tag string) error                  { return nil }
 
 func TestBuildEnvironment_Basic(t *testing.T) {
 	cfg := &provider.Config{}
@@ -72,6 +89,39 @@ func TestBuildEnvironment_FirewallDisabled(t *testing.T) {
 	}
 }
 
+func TestBuildEnvironment_Offline(t *testing.T) {
+	cfg := &provider.Config{
+		OfflineEnabled: true,
+		EnvVars:        []string{"NPM_CONFIG_REGISTRY"},
+	}
+	t.Setenv("NPM_CONFIG_REGISTRY", "https://registry.example.com/")
+
+	env := BuildEnvironment(&mockEnvProvider{}, cfg)
+
+	want := map[string]string{
+		"ADDT_OFFLINE":        "true",
+		"NPM_CONFIG_REGISTRY": "http://127.0.0.1:4873/npm/",
+		"PIP_INDEX_URL":       "http://127.0.0.1:4873/pypi/simple/",
+		"UV_DEFAULT_INDEX":    "http://127.0.0.1:4873/pypi/simple/",
+		"GOPROXY":             "http://127.0.0.1:4873/go",
+	}
+	for k, v := range want {
+		if env[k] != v {
+			t.Errorf("%s = %q, want %q", k, env[k], v)
+		}
+	}
+}
+
+func TestBuildEnvironment_NotOffline(t *testing.T) {
+	env := BuildEnvironment(&mockEnvProvider{}, &provider.Config{})
+
+	for _, k := range []string{"ADDT_OFFLINE", "PIP_INDEX_URL", "GOPROXY"} {
+		if _, ok := env[k]; ok {
+			t.Errorf("%s should not be set when not offline", k)
+		}
+	}
+}
+
 func TestBuildEnvironment_Command(t *testing.T) {
 	cfg := &provider.Config{
 		Command: "codex",
@@ -530,3 +580,54 @@ func TestParseEnvVarSpec(t *testing.T) {
 		})
 	}
 }
+
+// envTestSecrets is a secret backend serving secrets from a map
+type envTestSecrets map[string]string
+
+func (s envTestSecrets) Resolve(ctx context.Context, ref *extensions.SecretRef) (string, error) {
+	return s[ref.Path], nil
+}
+
+func TestAddSecretEnvVars(t *testing.T) {
+	extensions.RegisterSecretResolver("envtest", envTestSecrets{"anthropic": "sk-ant-secret"})
+	env := map[string]string{
+		"ANTHROPIC_API_KEY":    "sk-from-host",
+		"ADDT_CREDENTIAL_VARS": "CLAUDE_OAUTH_CREDENTIALS",
+	}
+	cfg := &provider.Config{Secrets: map[string]string{
+		"ANTHROPIC_API_KEY": "envtest://anthropic",
+		"OPENAI_API_KEY":    "envtest://missing",
+	}}
+	addSecretEnvVars(env, cfg)
+
+	if env["ANTHROPIC_API_KEY"] != "sk-ant-secret" {
+		t.Errorf("ANTHROPIC_API_KEY = %q, want the value from the backend", env["ANTHROPIC_API_KEY"])
+	}
+	if _, ok := env["OPENAI_API_KEY"]; ok {
+		t.Error("OPENAI_API_KEY set from an empty secret")
+	}
+	if got := env["ADDT_CREDENTIAL_VARS"]; got != "ANTHROPIC_API_KEY,CLAUDE_OAUTH_CREDENTIALS" {
+		t.Errorf("ADDT_CREDENTIAL_VARS = %q", got)
+	}
+}
+
+func TestAddSecretEnvVars_Failure(t *testing.T) {
+	extensions.RegisterSecretResolver("envtest", envTestSecrets{})
+	env := map[string]string{}
+	cfg := &provider.Config{Secrets: map[string]string{"OPENAI_API_KEY": "envtest://missing"}}
+
+	oldStderr := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+	addSecretEnvVars(env, cfg)
+	w.Close()
+	os.Stderr = oldStderr
+	out, _ := io.ReadAll(r)
+
+	if _, ok := env["OPENAI_API_KEY"]; ok {
+		t.Error("OPENAI_API_KEY set from a failed secret")
+	}
+	if want := "Warning: secret OPENAI_API_KEY not set: envtest://missing is empty"; !strings.Contains(string(out), want) {
+		t.Errorf("stderr = %q, want %q", out, want)
+	}
+}
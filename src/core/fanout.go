@@ -0,0 +1,336 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fan-out: several agents, or several attempts of one, work on the same
+// prompt at once. Each member is a child addt process running an ephemeral,
+// non-interactive container on its own workspace overlay, or its own git
+// worktree with workdir.worktree, so members never see each other's
+// changes. Their output is interleaved line by line behind the member's
+// label; once all have exited their workspaces are compared.
+
+// Isolation of a fan-out member's workspace
+const (
+	FanoutOverlay  = "overlay"
+	FanoutWorktree = "worktree"
+)
+
+// fanoutPortStride separates the host port ranges of members
+const fanoutPortStride = 100
+
+// FanoutMember is one agent run of a fan-out
+type FanoutMember struct {
+	Label     string // shown before each line of its output, e.g. "claude#1"
+	Extension string
+}
+
+// DiffStat summarizes the changes a member made
+type DiffStat struct {
+	Files      int
+	Insertions int
+	Deletions  int
+}
+
+// FanoutResult is how a member's run went
+type FanoutResult struct {
+	FanoutMember
+	Name      string // container name, also the overlay or worktree name
+	Isolation string
+	Repo      string // top level of the checkout (worktree)
+	Branch    string // session branch (worktree)
+	ExitCode  int
+	Duration  time.Duration
+	Stat      DiffStat
+}
+
+// Fanout runs the members concurrently on the same arguments, streaming
+// their prefixed output to out, and returns their results in order
+func (r *Runner) Fanout(members []FanoutMember, args []string, out io.Writer) ([]FanoutResult, error) {
+	if !r.config.WorkdirAutomount || r.config.WorkdirReadonly {
+		return nil, fmt.Errorf("fan-out needs a writable workdir (workdir.automount without workdir.readonly)")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate addt: %w", err)
+	}
+	cwd := r.config.Workdir
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	}
+	isolation := FanoutOverlay
+	var repo, base string
+	if r.config.WorkdirWorktree {
+		isolation = FanoutWorktree
+		if repo, err = git(cwd, "rev-parse", "--show-toplevel"); err != nil {
+			return nil, fmt.Errorf("%s is not in a git repository: %w", cwd, err)
+		}
+		if base, err = git(repo, "rev-parse", "HEAD"); err != nil {
+			return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+		}
+	}
+
+	stamp := time.Now().Format("20060102-150405")
+	results := make([]FanoutResult, len(members))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, m := range members {
+		name := fmt.Sprintf("addt-fanout-%s-%d", stamp, i+1)
+		results[i] = FanoutResult{
+			FanoutMember: m,
+			Name:         name,
+			Isolation:    isolation,
+			Repo:         repo,
+		}
+		if isolation == FanoutWorktree {
+			results[i].Branch = WorktreeBranch(name, "")
+		}
+
+		cmd := exec.Command(exe, append([]string{"run", m.Extension}, args...)...)
+		cmd.Env = append(os.Environ(), fanoutEnv(r.config.PortRangeStart, cwd, name, isolation, i)...)
+		stdout := newPrefixWriter(out, &mu, "["+m.Label+"] ")
+		stderr := newPrefixWriter(out, &mu, "["+m.Label+"] ")
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		wg.Add(1)
+		go func(res *FanoutResult) {
+			defer wg.Done()
+			start := time.Now()
+			err := cmd.Run()
+			res.Duration = time.Since(start)
+			stdout.Flush()
+			stderr.Flush()
+			if err != nil {
+				res.ExitCode = -1
+				if exitErr, ok := err.(*exec.ExitError); ok {
+					res.ExitCode = exitErr.ExitCode()
+				} else {
+					stderr.Write([]byte(err.Error() + "\n"))
+					stderr.Flush()
+				}
+			}
+			runnerLogger.Debugf("Fan-out member %s exited with %d after %s", res.Label, res.ExitCode, res.Duration)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	for i := range results {
+		stat, err := results[i].diffStat(base)
+		if err != nil {
+			runnerLogger.Errorf("Failed to compare %s: %v", results[i].Name, err)
+		}
+		results[i].Stat = stat
+	}
+	return results, nil
+}
+
+// fanoutEnv returns the environment overrides of the index-th member: its
+// container name, an ephemeral container, its own overlay or worktree and
+// its own host port range
+func fanoutEnv(portRangeStart int, workdir, name, isolation string, index int) []string {
+	env := []string{
+		"ADDT_EXTENSIONS=",
+		"ADDT_COMMAND=",
+		"ADDT_CONTAINER_NAME=" + name,
+		"ADDT_PERSISTENT=false",
+		"ADDT_WORKDIR=" + workdir,
+		"ADDT_PORT_RANGE_START=" + strconv.Itoa(portRangeStart+index*fanoutPortStride),
+	}
+	if isolation == FanoutWorktree {
+		return append(env,
+			"ADDT_WORKDIR_WORKTREE=true",
+			"ADDT_WORKDIR_WORKTREE_NAME="+strings.TrimPrefix(name, "addt-"),
+			"ADDT_WORKDIR_OVERLAY=false")
+	}
+	return append(env,
+		"ADDT_WORKDIR_WORKTREE=false",
+		"ADDT_WORKDIR_OVERLAY=true")
+}
+
+// diffStat measures the member's changes: an overlay against the project,
+// a worktree (or only its branch, once the worktree is gone) against the
+// commit the fan-out started from
+func (res *FanoutResult) diffStat(base string) (DiffStat, error) {
+	if res.Isolation == FanoutOverlay {
+		o, err := LoadOverlay(res.Name)
+		if err != nil {
+			return DiffStat{}, nil
+		}
+		return o.DiffStat()
+	}
+
+	if w, err := LoadWorktree(res.Name); err == nil {
+		stat, err := numstat(w.Dir, "diff", "--numstat", base)
+		if err != nil {
+			return stat, err
+		}
+		untracked, err := git(w.Dir, "ls-files", "-z", "--others", "--exclude-standard")
+		if err != nil {
+			return stat, err
+		}
+		for _, path := range strings.Split(untracked, "\x00") {
+			if path == "" {
+				continue
+			}
+			stat.Files++
+			if data, err := os.ReadFile(filepath.Join(w.Dir, path)); err == nil {
+				stat.Insertions += countLines(data)
+			}
+		}
+		return stat, nil
+	}
+	if _, err := git(res.Repo, "rev-parse", "--verify", "--quiet", "refs/heads/"+res.Branch); err != nil {
+		return DiffStat{}, nil
+	}
+	return numstat(res.Repo, "diff", "--numstat", base, res.Branch)
+}
+
+// DiffStat measures the overlay's changes to the project
+func (o *Overlay) DiffStat() (DiffStat, error) {
+	changes, err := o.Changes()
+	if err != nil {
+		return DiffStat{}, err
+	}
+	stat := DiffStat{Files: len(changes)}
+	for _, c := range changes {
+		from := filepath.Join(o.Source, filepath.FromSlash(c.Path))
+		to := filepath.Join(o.Workspace(), filepath.FromSlash(c.Path))
+		if c.Kind == ChangeAdded {
+			from = os.DevNull
+		}
+		if c.Kind == ChangeDeleted {
+			to = os.DevNull
+		}
+		lines, err := numstat(o.Workspace(), "diff", "--no-index", "--numstat", from, to)
+		if err != nil {
+			return stat, err
+		}
+		stat.Insertions += lines.Insertions
+		stat.Deletions += lines.Deletions
+	}
+	return stat, nil
+}
+
+// numstat runs a git diff --numstat in dir and totals its output. diff
+// --no-index exits 1 when the files differ.
+func numstat(dir string, args ...string) (DiffStat, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		err = nil
+	}
+	if err != nil {
+		return DiffStat{}, err
+	}
+	return parseNumstat(string(out)), nil
+}
+
+// parseNumstat totals git diff --numstat output; binary files count as a
+// file without lines
+func parseNumstat(out string) DiffStat {
+	var stat DiffStat
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		stat.Files++
+		added, _ := strconv.Atoi(fields[0])
+		deleted, _ := strconv.Atoi(fields[1])
+		stat.Insertions += added
+		stat.Deletions += deleted
+	}
+	return stat
+}
+
+// countLines counts the lines of a text file
+func countLines(data []byte) int {
+	if len(data) == 0 || bytes.IndexByte(data, 0) >= 0 {
+		return 0
+	}
+	n := bytes.Count(data, []byte("\n"))
+	if data[len(data)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
+// Discard removes a member's workspace: its overlay, or its worktree and
+// branch with whatever they hold
+func (res *FanoutResult) Discard() error {
+	if res.Isolation == FanoutOverlay {
+		if o, err := LoadOverlay(res.Name); err == nil {
+			return o.Remove()
+		}
+		return nil
+	}
+	w, err := LoadWorktree(res.Name)
+	if err != nil {
+		w = &Worktree{Name: res.Name, Repo: res.Repo, Branch: res.Branch, Dir: WorktreeDir(res.Name)}
+	}
+	return w.Discard()
+}
+
+// prefixWriter writes each complete line behind a prefix, holding back a
+// partial line until it ends. Only the text after a line's last carriage
+// return is kept, as a terminal would show it, which drops spinner frames.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func newPrefixWriter(out io.Writer, mu *sync.Mutex, prefix string) *prefixWriter {
+	return &prefixWriter{mu: mu, out: out, prefix: prefix}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.writeLine(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	if i := bytes.LastIndexByte(bytes.TrimRight(w.buf, "\r"), '\r'); i >= 0 {
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes a trailing partial line
+func (w *prefixWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.writeLine(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if i := bytes.LastIndexByte(line, '\r'); i >= 0 {
+		line = line[i+1:]
+	}
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+	fmt.Fprintf(w.out, "%s%s\n", w.prefix, line)
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	w := newPrefixWriter(&out, &mu, "[claude] ")
+
+	w.Write([]byte("first line\nsecond "))
+	w.Write([]byte("line\r\n\n"))
+	// A spinner redraws its line; only the final text is kept
+	w.Write([]byte("\r⠋ Building \r⠙ Building \r      \r"))
+	w.Write([]byte("✓ Built\npartial"))
+	w.Flush()
+
+	want := "[claude] first line\n[claude] second line\n[claude] ✓ Built\n[claude] partial\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestFanoutEnv(t *testing.T) {
+	env := fanoutEnv(30000, "/project", "addt-fanout-20261017-120000-2", FanoutWorktree, 1)
+	for _, want := range []string{
+		"ADDT_CONTAINER_NAME=addt-fanout-20261017-120000-2",
+		"ADDT_PERSISTENT=false",
+		"ADDT_WORKDIR=/project",
+		"ADDT_PORT_RANGE_START=30100",
+		"ADDT_WORKDIR_WORKTREE=true",
+		"ADDT_WORKDIR_WORKTREE_NAME=fanout-20261017-120000-2",
+	} {
+		if !containsString(env, want) {
+			t.Errorf("fanoutEnv() = %v, missing %s", env, want)
+		}
+	}
+
+	env = fanoutEnv(30000, "/project", "addt-fanout-20261017-120000-1", FanoutOverlay, 0)
+	if !containsString(env, "ADDT_WORKDIR_OVERLAY=true") || !containsString(env, "ADDT_WORKDIR_WORKTREE=false") {
+		t.Errorf("fanoutEnv() = %v, want an overlay without a worktree", env)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestParseNumstat(t *testing.T) {
+	out := "3\t1\ta.txt\n0\t2\tdir/b.txt\n-\t-\timage.png\n"
+	want := DiffStat{Files: 3, Insertions: 3, Deletions: 3}
+	if got := parseNumstat(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNumstat() = %+v, want %+v", got, want)
+	}
+	if got := parseNumstat(""); got != (DiffStat{}) {
+		t.Errorf("parseNumstat(\"\") = %+v, want zero", got)
+	}
+}
+
+func TestOverlay_DiffStat(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	o, _ := newTestOverlay(t, "addt-test")
+	writeTestFile(t, o.Workspace(), "a.txt", "a\nb\nc\n")
+	writeTestFile(t, o.Workspace(), "c.txt", "new\n")
+	os.Remove(filepath.Join(o.Workspace(), "dir", "b.txt"))
+
+	stat, err := o.DiffStat()
+	if err != nil {
+		t.Fatalf("DiffStat() error = %v", err)
+	}
+	want := DiffStat{Files: 3, Insertions: 4, Deletions: 2}
+	if stat != want {
+		t.Errorf("DiffStat() = %+v, want %+v", stat, want)
+	}
+}
+
+func TestFanoutResult_WorktreeDiffStatAndDiscard(t *testing.T) {
+	repo := newTestRepo(t)
+	base := runGit(t, repo, "rev-parse", "HEAD")
+	w, err := PrepareWorktree("addt-fanout-test-1", repo, "")
+	if err != nil {
+		t.Fatalf("PrepareWorktree() error = %v", err)
+	}
+	writeTestFile(t, w.Dir, "a.txt", "changed\n")
+	runGit(t, w.Dir, "commit", "-q", "-am", "change a")
+	writeTestFile(t, w.Dir, "new.txt", "one\ntwo\n")
+
+	res := &FanoutResult{Name: w.Name, Isolation: FanoutWorktree, Repo: w.Repo, Branch: w.Branch}
+	stat, err := res.diffStat(base)
+	if err != nil {
+		t.Fatalf("diffStat() error = %v", err)
+	}
+	if want := (DiffStat{Files: 2, Insertions: 3, Deletions: 1}); stat != want {
+		t.Errorf("diffStat() = %+v, want %+v", stat, want)
+	}
+
+	if err := res.Discard(); err != nil {
+		t.Fatalf("Discard() error = %v", err)
+	}
+	if _, err := os.Stat(w.Dir); !os.IsNotExist(err) {
+		t.Errorf("worktree %s still exists: %v", w.Dir, err)
+	}
+	if w.BranchExists() {
+		t.Errorf("branch %s still exists", w.Branch)
+	}
+}
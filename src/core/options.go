@@ -23,6 +23,27 @@ func BuildRunOptions(p provider.Provider, cfg *provider.Config, name string, arg
 	}
 	optionsLogger.Debugf("Working directory: %s", cwd)
 
+	// With a worktree or an overlay, /workspace is the environment's own
+	// copy of the project, prepared by the runner
+	workspace := cwd
+	gitDir := ""
+	switch {
+	case WorktreeEnabled(cfg):
+		if w, err := LoadWorktree(name); err == nil {
+			workspace, gitDir = w.Dir, w.GitDir
+			optionsLogger.Debugf("Workspace worktree: %s on %s", workspace, w.Branch)
+		}
+	case OverlayEnabled(cfg):
+		workspace = OverlayWorkspaceDir(name)
+		optionsLogger.Debugf("Workspace overlay: %s", workspace)
+	}
+	volumes := BuildVolumes(cfg, workspace)
+	if gitDir != "" {
+		// The worktree's .git file points into the repository's git
+		// directory by its host path
+		volumes = append(volumes, provider.VolumeMount{Source: gitDir, Target: gitDir})
+	}
+
 	// Interactive mode: if running in a terminal, allow interactive input
 	// Both shell mode and run mode can be interactive when in a terminal
 	isTerminal := terminal.IsTerminal()
@@ -37,7 +58,7 @@ func BuildRunOptions(p provider.Provider, cfg *provider.Config, name string, arg
 		WorkDir:          cwd,
 		Interactive:      isInteractive,
 		Persistent:       cfg.Persistent,
-		Volumes:          BuildVolumes(cfg, cwd),
+		Volumes:          volumes,
 		Ports:            BuildPorts(cfg),
 		Env:              BuildEnvironment(p, cfg),
 		SSHForwardKeys:   cfg.SSHForwardKeys,
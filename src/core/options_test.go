@@ -1,6 +1,9 @@
 package core
 
 import (
+	"io"
+	"os"
+	"reflect"
 	"testing"
 
 	"github.com/jedi4ever/addt/provider"
@@ -26,6 +29,23 @@ func (m *mockOptionsProvider) GetName() string
 func (m *mockOptionsProvider) GetExtensionEnvVars(imageName string) []string      { return nil }
 func (m *mockOptionsProvider) DetermineImageName() string                         { return "test-image" }
 func (m *mockOptionsProvider) BuildIfNeeded(rebuild bool, rebuildBase bool) error { return nil }
+func (m *mockOptionsProvider) Exec(name string, cmd []string, tty bool, stdio provider.Stdio) error {
+	return nil
+}
+func (m *mockOptionsProvider) Logs(name string, follow bool, out io.Writer) error { return nil }
+func (m *mockOptionsProvider) CopyTo(name, hostPath, envPath string) error        { return nil }
+func (m *mockOptionsProvider) CopyFrom(name, envPath, hostPath string) error      { return nil }
+func (m *mockOptionsProvider) Inspect(name string) (*provider.EnvironmentInfo, error) {
+	return nil, nil
+}
+func (m *mockOptionsProvider) Snapshot(name, tag string, checkpoint bool) (*provider.Snapshot, error) {
+	return nil, nil
+}
+func (m *mockOptionsProvider) Restore(name, tag string) error { return nil }
+func (m *mockOptionsProvider) ListSnapshots(name string) ([]provider.Snapshot, error) {
+	return nil, nil
+}
+func (m *mockOptionsProvider) RemoveSnapshot(name, tag string) error { return nil }
 
 func TestBuildRunOptions_Basic(t *testing.T) {
 	cfg := &provider.Config{
@@ -160,6 +180,60 @@ func TestBuildRunOptions_IncludesVolumes(t *testing.T) {
 	}
 }
 
+func TestBuildRunOptions_OverlayWorkspace(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+	cfg := &provider.Config{
+		ImageName:        "test-image",
+		WorkdirAutomount: true,
+		WorkdirOverlay:   true,
+		Workdir:          "/home/user/project",
+		PortRangeStart:   30000,
+	}
+
+	opts := BuildRunOptions(&mockOptionsProvider{}, cfg, "test-container", []string{}, false)
+
+	if len(opts.Volumes) != 1 || opts.Volumes[0].Source != OverlayWorkspaceDir("test-container") {
+		t.Errorf("Volumes = %v, want the overlay workspace mounted", opts.Volumes)
+	}
+	if opts.WorkDir != "/home/user/project" {
+		t.Errorf("WorkDir = %q, want the project", opts.WorkDir)
+	}
+
+	// A read-only workdir takes precedence
+	cfg.WorkdirReadonly = true
+	opts = BuildRunOptions(&mockOptionsProvider{}, cfg, "test-container", []string{}, false)
+	if opts.Volumes[0].Source != "/home/user/project" || !opts.Volumes[0].ReadOnly {
+		t.Errorf("Volumes = %v, want the project mounted read-only", opts.Volumes)
+	}
+}
+
+func TestBuildRunOptions_WorktreeWorkspace(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+	w := &Worktree{Name: "test-container", Dir: WorktreeDir("test-container"), GitDir: "/home/user/project/.git", Branch: "addt/test"}
+	os.MkdirAll(WorktreesDir(), 0700)
+	if err := w.save(); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &provider.Config{
+		ImageName:        "test-image",
+		WorkdirAutomount: true,
+		WorkdirWorktree:  true,
+		WorkdirOverlay:   true,
+		Workdir:          "/home/user/project",
+		PortRangeStart:   30000,
+	}
+
+	opts := BuildRunOptions(&mockOptionsProvider{}, cfg, "test-container", []string{}, false)
+
+	want := []provider.VolumeMount{
+		{Source: w.Dir, Target: "/workspace"},
+		{Source: "/home/user/project/.git", Target: "/home/user/project/.git"},
+	}
+	if !reflect.DeepEqual(opts.Volumes, want) {
+		t.Errorf("Volumes = %v, want %v", opts.Volumes, want)
+	}
+}
+
 func TestBuildRunOptions_IncludesPorts(t *testing.T) {
 	cfg := &provider.Config{
 		ImageName:        "test-image",
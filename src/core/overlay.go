@@ -0,0 +1,592 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/util"
+)
+
+// Workspace overlays: with workdir.overlay the agent works on a copy of the
+// project instead of the project itself. The copy is cloned with reflinks
+// where the filesystem supports them (APFS, btrfs, XFS), so it shares blocks
+// with the project until written, and is mounted at /workspace. A manifest
+// of the copy as made tells the agent's changes apart from edits made on the
+// host meanwhile; changes reach the project only through addt apply.
+//
+// Overlays live in ~/.addt/overlays/<environment>. A persistent
+// environment keeps its overlay across runs; an ephemeral one leaves it
+// behind only when the agent changed something.
+//
+// The .git directory is copied but not tracked: the agent can use git in
+// the overlay, but its commits and index are never applied.
+
+var overlayLogger = util.Log("overlay")
+
+const overlayManifest = "overlay.json"
+
+// Change kinds, as git status shows them
+const (
+	ChangeAdded    = "A"
+	ChangeModified = "M"
+	ChangeDeleted  = "D"
+)
+
+// Overlay is a copy-on-write workspace of a project
+type Overlay struct {
+	Name      string               `json:"name"`   // environment the overlay is mounted in
+	Source    string               `json:"source"` // host project
+	CreatedAt time.Time            `json:"created_at"`
+	Files     map[string]FileState `json:"files"` // the copy as made, by slash-separated path
+
+	dir string
+}
+
+// FileState is the part of a file's metadata changes are detected by
+type FileState struct {
+	Size    int64       `json:"size"`
+	Mode    fs.FileMode `json:"mode"`
+	ModTime time.Time   `json:"mod_time"`
+	Link    string      `json:"link,omitempty"` // symlink target
+}
+
+// Change is a file the agent added, modified or deleted
+type Change struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	// Conflict is set when the project's file changed since it was copied
+	// too; applying the change overwrites that edit
+	Conflict bool `json:"conflict,omitempty"`
+}
+
+// OverlayEnabled reports whether /workspace is an overlay. A read-only
+// workdir and a worktree take precedence.
+func OverlayEnabled(cfg *provider.Config) bool {
+	return cfg.WorkdirAutomount && cfg.WorkdirOverlay && !cfg.WorkdirReadonly && !cfg.WorkdirWorktree
+}
+
+// OverlaysDir returns the directory overlays are kept in
+func OverlaysDir() string {
+	return filepath.Join(util.GetAddtHome(), "overlays")
+}
+
+// OverlayWorkspaceDir returns the copy of the project mounted in an
+// environment
+func OverlayWorkspaceDir(name string) string {
+	return filepath.Join(OverlaysDir(), name, "workspace")
+}
+
+// PrepareOverlay returns the overlay of an environment, copying source
+// into a new one when it has none yet
+func PrepareOverlay(name, source string) (*Overlay, error) {
+	if o, err := LoadOverlay(name); err == nil {
+		if o.Source != source {
+			return nil, fmt.Errorf("overlay %s belongs to %s, not %s", name, o.Source, source)
+		}
+		overlayLogger.Debugf("Reusing overlay %s of %s", name, source)
+		return o, nil
+	}
+
+	o := &Overlay{
+		Name:      name,
+		Source:    source,
+		CreatedAt: time.Now(),
+		dir:       filepath.Join(OverlaysDir(), name),
+	}
+	os.RemoveAll(o.dir) // leftovers of a failed copy
+	if err := os.MkdirAll(o.Workspace(), 0700); err != nil {
+		return nil, err
+	}
+	err := util.WithSpinner(fmt.Sprintf("Copying %s into a workspace overlay", source), func() error {
+		if err := cloneTree(source, o.Workspace()); err != nil {
+			return err
+		}
+		files, err := scanTree(o.Workspace())
+		if err != nil {
+			return err
+		}
+		o.Files = files
+		return o.save()
+	})
+	if err != nil {
+		os.RemoveAll(o.dir)
+		return nil, fmt.Errorf("failed to create overlay of %s: %w", source, err)
+	}
+	return o, nil
+}
+
+// LoadOverlay reads an environment's overlay
+func LoadOverlay(name string) (*Overlay, error) {
+	dir := filepath.Join(OverlaysDir(), name)
+	data, err := os.ReadFile(filepath.Join(dir, overlayManifest))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("overlay %s not found", name)
+		}
+		return nil, err
+	}
+	o := &Overlay{}
+	if err := json.Unmarshal(data, o); err != nil {
+		return nil, fmt.Errorf("invalid overlay %s: %w", name, err)
+	}
+	if o.Files == nil {
+		o.Files = make(map[string]FileState)
+	}
+	o.dir = dir
+	return o, nil
+}
+
+// ListOverlays returns all overlays, oldest first
+func ListOverlays() ([]*Overlay, error) {
+	entries, err := os.ReadDir(OverlaysDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var overlays []*Overlay
+	for _, entry := range entries {
+		if o, err := LoadOverlay(entry.Name()); err == nil {
+			overlays = append(overlays, o)
+		}
+	}
+	sort.SliceStable(overlays, func(i, j int) bool {
+		return overlays[i].CreatedAt.Before(overlays[j].CreatedAt)
+	})
+	return overlays, nil
+}
+
+// FindOverlay returns the named overlay, or the newest overlay of source
+// when name is empty
+func FindOverlay(name, source string) (*Overlay, error) {
+	if name != "" {
+		return LoadOverlay(name)
+	}
+	overlays, err := ListOverlays()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(overlays) - 1; i >= 0; i-- {
+		if overlays[i].Source == source {
+			return overlays[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no overlay of %s", source)
+}
+
+// Workspace returns the overlay's copy of the project
+func (o *Overlay) Workspace() string {
+	return filepath.Join(o.dir, "workspace")
+}
+
+// Persistent reports whether the overlay belongs to a persistent
+// environment, which keeps it mounted between runs
+func (o *Overlay) Persistent() bool {
+	return strings.HasPrefix(o.Name, "addt-persistent-")
+}
+
+// Remove deletes the overlay
+func (o *Overlay) Remove() error {
+	return os.RemoveAll(o.dir)
+}
+
+// Changes lists the files the agent changed, by path. Files rewritten with
+// the project's content are not changes.
+func (o *Overlay) Changes() ([]Change, error) {
+	current, err := scanTree(o.Workspace())
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for path, state := range current {
+		old, tracked := o.Files[path]
+		switch {
+		case !tracked:
+			changes = append(changes, Change{Path: path, Kind: ChangeAdded})
+		case !state.matches(old, 0) && !o.sameAsSource(path):
+			changes = append(changes, Change{Path: path, Kind: ChangeModified})
+		}
+	}
+	for path := range o.Files {
+		if _, ok := current[path]; !ok {
+			changes = append(changes, Change{Path: path, Kind: ChangeDeleted})
+		}
+	}
+	for i := range changes {
+		changes[i].Conflict = o.sourceChanged(changes[i].Path)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// Apply copies changes to the project
+func (o *Overlay) Apply(changes []Change) error {
+	for _, c := range changes {
+		if err := syncPath(o.Workspace(), o.Source, c.Path); err != nil {
+			return fmt.Errorf("failed to apply %s: %w", c.Path, err)
+		}
+		if err := o.track(c.Path); err != nil {
+			return err
+		}
+	}
+	return o.save()
+}
+
+// Discard reverts changes to the project's current version of each file
+func (o *Overlay) Discard(changes []Change) error {
+	for _, c := range changes {
+		if err := syncPath(o.Source, o.Workspace(), c.Path); err != nil {
+			return fmt.Errorf("failed to discard %s: %w", c.Path, err)
+		}
+		if err := o.track(c.Path); err != nil {
+			return err
+		}
+	}
+	return o.save()
+}
+
+// track records the overlay's current version of a file as unchanged
+func (o *Overlay) track(path string) error {
+	state, ok, err := statPath(filepath.Join(o.Workspace(), filepath.FromSlash(path)))
+	if err != nil {
+		return err
+	}
+	if ok {
+		o.Files[path] = state
+	} else {
+		delete(o.Files, path)
+	}
+	return nil
+}
+
+// sourceChanged reports whether the project's file differs from the copy
+// that was made of it. Modification times are compared to the second, as
+// not every filesystem keeps more.
+func (o *Overlay) sourceChanged(path string) bool {
+	state, exists, err := statPath(filepath.Join(o.Source, filepath.FromSlash(path)))
+	if err != nil {
+		return true
+	}
+	old, tracked := o.Files[path]
+	if exists != tracked {
+		return true
+	}
+	return exists && !state.matches(old, time.Second)
+}
+
+// sameAsSource reports whether the overlay's file has the project's
+// content, as when the agent rewrote a file without changing it
+func (o *Overlay) sameAsSource(path string) bool {
+	a := filepath.Join(o.Workspace(), filepath.FromSlash(path))
+	b := filepath.Join(o.Source, filepath.FromSlash(path))
+	sa, okA, errA := statPath(a)
+	sb, okB, errB := statPath(b)
+	if errA != nil || errB != nil || !okA || !okB || sa.Mode != sb.Mode || sa.Size != sb.Size || sa.Link != sb.Link {
+		return false
+	}
+	if sa.Mode&fs.ModeSymlink != 0 {
+		return true
+	}
+	equal, err := filesEqual(a, b)
+	return err == nil && equal
+}
+
+// save writes the manifest
+func (o *Overlay) save() error {
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(o.dir, overlayManifest)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// FilterChanges returns the changes to the given paths, or below them for
+// directories; all changes when no paths are given
+func FilterChanges(changes []Change, paths []string) ([]Change, error) {
+	if len(paths) == 0 {
+		return changes, nil
+	}
+	var result []Change
+	for _, p := range paths {
+		p = strings.TrimSuffix(filepath.ToSlash(filepath.Clean(p)), "/")
+		matched := false
+		for _, c := range changes {
+			if p == "." || c.Path == p || strings.HasPrefix(c.Path, p+"/") {
+				result = append(result, c)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no changes to %s", p)
+		}
+	}
+	return result, nil
+}
+
+// matches compares two states, with modification times truncated to
+// precision. Symlinks compare by target only.
+func (s FileState) matches(other FileState, precision time.Duration) bool {
+	if s.Mode != other.Mode {
+		return false
+	}
+	if s.Mode&fs.ModeSymlink != 0 {
+		return s.Link == other.Link
+	}
+	return s.Size == other.Size && s.ModTime.Truncate(precision).Equal(other.ModTime.Truncate(precision))
+}
+
+// statPath returns the state of a file or symlink, and whether it exists
+func statPath(path string) (FileState, bool, error) {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return FileState{}, false, nil
+	}
+	if err != nil {
+		return FileState{}, false, err
+	}
+	return fileState(path, info)
+}
+
+// fileState converts file info into a state; directories and special
+// files have none
+func fileState(path string, info fs.FileInfo) (FileState, bool, error) {
+	state := FileState{Size: info.Size(), Mode: info.Mode(), ModTime: info.ModTime()}
+	switch {
+	case info.Mode().IsRegular():
+		return state, true, nil
+	case info.Mode()&fs.ModeSymlink != 0:
+		link, err := os.Readlink(path)
+		if err != nil {
+			return FileState{}, false, err
+		}
+		state.Link = link
+		state.Size = 0
+		state.ModTime = time.Time{}
+		return state, true, nil
+	}
+	return FileState{}, false, nil
+}
+
+// scanTree returns the state of every file and symlink below root, skipping
+// the top-level .git directory
+func scanTree(root string) (map[string]FileState, error) {
+	files := make(map[string]FileState)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		state, ok, err := fileState(path, info)
+		if err != nil {
+			return err
+		}
+		if ok {
+			files[rel] = state
+		}
+		return nil
+	})
+	return files, err
+}
+
+// syncPath makes toRoot's file at path what fromRoot's is, removing it when
+// fromRoot has none. Directories on the way are created but never written
+// through a symlink, so a file can't land outside toRoot.
+func syncPath(fromRoot, toRoot, path string) error {
+	rel := filepath.FromSlash(path)
+	if !filepath.IsLocal(rel) {
+		return fmt.Errorf("invalid path %s", path)
+	}
+	from := filepath.Join(fromRoot, rel)
+	to := filepath.Join(toRoot, rel)
+	if err := checkParents(toRoot, rel); err != nil {
+		return err
+	}
+
+	state, exists, err := statPath(from)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Lstat(to); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory", to)
+		}
+		if err := os.Remove(to); err != nil {
+			return err
+		}
+	}
+	if !exists {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	return copyEntry(from, to, state)
+}
+
+// checkParents fails when a directory between root and rel is a symlink
+func checkParents(root, rel string) error {
+	dir := root
+	parts := strings.Split(filepath.Dir(rel), string(filepath.Separator))
+	for _, part := range parts {
+		if part == "." {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symlink", dir)
+		}
+	}
+	return nil
+}
+
+// copyEntry copies a file or symlink, keeping its mode and modification time
+func copyEntry(from, to string, state FileState) error {
+	if state.Mode&fs.ModeSymlink != 0 {
+		return os.Symlink(state.Link, to)
+	}
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, state.Mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	// Permissions are set again past the umask
+	if err := os.Chmod(to, state.Mode.Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(to, state.ModTime, state.ModTime)
+}
+
+// filesEqual compares the contents of two files
+func filesEqual(a, b string) (bool, error) {
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	bufA := make([]byte, 64*1024)
+	bufB := make([]byte, 64*1024)
+	for {
+		na, errA := io.ReadFull(fa, bufA)
+		nb, errB := io.ReadFull(fb, bufB)
+		if na != nb || !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false, nil
+		}
+		if errA == io.EOF || errA == io.ErrUnexpectedEOF {
+			return errB == io.EOF || errB == io.ErrUnexpectedEOF, nil
+		}
+		if errA != nil {
+			return false, errA
+		}
+		if errB != nil {
+			return false, errB
+		}
+	}
+}
+
+// cloneTree copies src's contents into dst, with cp's reflink support
+// where available and a plain copy otherwise
+func cloneTree(src, dst string) error {
+	var args []string
+	switch runtime.GOOS {
+	case "darwin":
+		args = []string{"-Rpc", src + "/.", dst}
+	case "linux":
+		args = []string{"-a", "--reflink=auto", src + "/.", dst}
+	}
+	if args != nil {
+		output, err := exec.Command("cp", args...).CombinedOutput()
+		if err == nil {
+			return nil
+		}
+		overlayLogger.Debugf("cp %v failed, copying without clones: %v: %s", args, err, strings.TrimSpace(string(output)))
+		if err := os.RemoveAll(dst); err != nil {
+			return err
+		}
+	}
+	return copyTree(src, dst)
+}
+
+// copyTree copies src's contents into dst file by file
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+		state, ok, err := fileState(path, info)
+		if err != nil || !ok {
+			return err
+		}
+		return copyEntry(path, target, state)
+	})
+}
@@ -0,0 +1,266 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestOverlay creates a project and an overlay of it under a temporary
+// ADDT_HOME
+func newTestOverlay(t *testing.T, name string) (*Overlay, string) {
+	t.Helper()
+	t.Setenv("ADDT_HOME", t.TempDir())
+	project := t.TempDir()
+	writeTestFile(t, project, "a.txt", "a")
+	writeTestFile(t, project, "dir/b.txt", "b")
+	writeTestFile(t, project, ".git/HEAD", "ref: refs/heads/main")
+
+	o, err := PrepareOverlay(name, project)
+	if err != nil {
+		t.Fatalf("PrepareOverlay() error = %v", err)
+	}
+	return o, project
+}
+
+func writeTestFile(t *testing.T, root, path, content string) {
+	t.Helper()
+	full := filepath.Join(root, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Later writes must be told apart by modification time alone
+	future := time.Now().Add(time.Duration(len(content)) * time.Minute)
+	if err := os.Chtimes(full, future, future); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, root, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(path)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func changePaths(changes []Change) []string {
+	var paths []string
+	for _, c := range changes {
+		paths = append(paths, c.Kind+" "+c.Path)
+	}
+	return paths
+}
+
+func TestOverlay_Changes(t *testing.T) {
+	o, _ := newTestOverlay(t, "addt-test")
+	if o.Workspace() != OverlayWorkspaceDir("addt-test") {
+		t.Errorf("Workspace() = %q, want %q", o.Workspace(), OverlayWorkspaceDir("addt-test"))
+	}
+	if got := readTestFile(t, o.Workspace(), "dir/b.txt"); got != "b" {
+		t.Errorf("copied dir/b.txt = %q, want %q", got, "b")
+	}
+
+	writeTestFile(t, o.Workspace(), "a.txt", "changed")
+	writeTestFile(t, o.Workspace(), "c.txt", "new")
+	writeTestFile(t, o.Workspace(), ".git/HEAD", "ref: refs/heads/agent")
+	os.Remove(filepath.Join(o.Workspace(), "dir", "b.txt"))
+
+	changes, err := o.Changes()
+	if err != nil {
+		t.Fatalf("Changes() error = %v", err)
+	}
+	want := []string{"M a.txt", "A c.txt", "D dir/b.txt"}
+	if got := changePaths(changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("Changes() = %v, want %v", got, want)
+	}
+	for _, c := range changes {
+		if c.Conflict {
+			t.Errorf("%s conflicts, want no conflict", c.Path)
+		}
+	}
+}
+
+func TestOverlay_RewriteIsNoChange(t *testing.T) {
+	o, _ := newTestOverlay(t, "addt-test")
+	writeTestFile(t, o.Workspace(), "a.txt", "a")
+	// Only the modification time differs from the copy
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(o.Workspace(), "a.txt"), later, later)
+
+	changes, err := o.Changes()
+	if err != nil {
+		t.Fatalf("Changes() error = %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Changes() = %v, want none", changePaths(changes))
+	}
+}
+
+func TestOverlay_Conflict(t *testing.T) {
+	o, project := newTestOverlay(t, "addt-test")
+	writeTestFile(t, o.Workspace(), "a.txt", "agent")
+	writeTestFile(t, project, "a.txt", "host edit")
+
+	changes, err := o.Changes()
+	if err != nil {
+		t.Fatalf("Changes() error = %v", err)
+	}
+	if len(changes) != 1 || !changes[0].Conflict {
+		t.Errorf("Changes() = %+v, want a conflicting a.txt", changes)
+	}
+}
+
+func TestOverlay_ApplyAndDiscard(t *testing.T) {
+	o, project := newTestOverlay(t, "addt-test")
+	writeTestFile(t, o.Workspace(), "a.txt", "changed")
+	writeTestFile(t, o.Workspace(), "new/c.txt", "new")
+	os.Remove(filepath.Join(o.Workspace(), "dir", "b.txt"))
+
+	changes, _ := o.Changes()
+	apply, err := FilterChanges(changes, []string{"a.txt", "new"})
+	if err != nil {
+		t.Fatalf("FilterChanges() error = %v", err)
+	}
+	if err := o.Apply(apply); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if got := readTestFile(t, project, "a.txt"); got != "changed" {
+		t.Errorf("project a.txt = %q, want %q", got, "changed")
+	}
+	if got := readTestFile(t, project, "new/c.txt"); got != "new" {
+		t.Errorf("project new/c.txt = %q, want %q", got, "new")
+	}
+
+	// The manifest survives a reload
+	o, err = LoadOverlay("addt-test")
+	if err != nil {
+		t.Fatalf("LoadOverlay() error = %v", err)
+	}
+	changes, _ = o.Changes()
+	if got, want := changePaths(changes), []string{"D dir/b.txt"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Changes() after apply = %v, want %v", got, want)
+	}
+
+	if err := o.Discard(changes); err != nil {
+		t.Fatalf("Discard() error = %v", err)
+	}
+	if got := readTestFile(t, o.Workspace(), "dir/b.txt"); got != "b" {
+		t.Errorf("restored dir/b.txt = %q, want %q", got, "b")
+	}
+	if _, err := os.Stat(filepath.Join(project, "dir", "b.txt")); err != nil {
+		t.Errorf("project dir/b.txt was removed: %v", err)
+	}
+	if changes, _ := o.Changes(); len(changes) != 0 {
+		t.Errorf("Changes() after discard = %v, want none", changePaths(changes))
+	}
+}
+
+func TestOverlay_ApplyDeletion(t *testing.T) {
+	o, project := newTestOverlay(t, "addt-test")
+	os.Remove(filepath.Join(o.Workspace(), "a.txt"))
+
+	changes, _ := o.Changes()
+	if err := o.Apply(changes); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(project, "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("project a.txt still exists: %v", err)
+	}
+}
+
+func TestPrepareOverlay_Reuse(t *testing.T) {
+	o, project := newTestOverlay(t, "addt-persistent-test-1234")
+	if !o.Persistent() {
+		t.Error("Persistent() = false, want true")
+	}
+	writeTestFile(t, o.Workspace(), "a.txt", "kept")
+
+	again, err := PrepareOverlay("addt-persistent-test-1234", project)
+	if err != nil {
+		t.Fatalf("PrepareOverlay() error = %v", err)
+	}
+	if got := readTestFile(t, again.Workspace(), "a.txt"); got != "kept" {
+		t.Errorf("reused a.txt = %q, want %q", got, "kept")
+	}
+	if _, err := PrepareOverlay("addt-persistent-test-1234", t.TempDir()); err == nil {
+		t.Error("PrepareOverlay() of another project succeeded, want error")
+	}
+
+	found, err := FindOverlay("", project)
+	if err != nil || found.Name != "addt-persistent-test-1234" {
+		t.Errorf("FindOverlay() = %v, %v, want the overlay of the project", found, err)
+	}
+}
+
+func TestSyncPath_RefusesSymlinkedParent(t *testing.T) {
+	from := t.TempDir()
+	to := t.TempDir()
+	outside := t.TempDir()
+	writeTestFile(t, from, "link/x", "x")
+	if err := os.Symlink(outside, filepath.Join(to, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := syncPath(from, to, "link/x"); err == nil {
+		t.Error("syncPath() through a symlink succeeded, want error")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "x")); !os.IsNotExist(err) {
+		t.Errorf("file written outside the destination: %v", err)
+	}
+	if err := syncPath(from, to, "../x"); err == nil {
+		t.Error("syncPath() of ../x succeeded, want error")
+	}
+}
+
+func TestFilterChanges(t *testing.T) {
+	changes := []Change{
+		{Path: "a.txt", Kind: ChangeModified},
+		{Path: "src/b.go", Kind: ChangeAdded},
+		{Path: "src/c.go", Kind: ChangeDeleted},
+		{Path: "srcx", Kind: ChangeAdded},
+	}
+
+	got, err := FilterChanges(changes, []string{"src/"})
+	if err != nil {
+		t.Fatalf("FilterChanges() error = %v", err)
+	}
+	if want := []string{"A src/b.go", "D src/c.go"}; !reflect.DeepEqual(changePaths(got), want) {
+		t.Errorf("FilterChanges(src/) = %v, want %v", changePaths(got), want)
+	}
+	if got, _ := FilterChanges(changes, nil); len(got) != len(changes) {
+		t.Errorf("FilterChanges(nil) = %d changes, want all", len(got))
+	}
+	if _, err := FilterChanges(changes, []string{"missing"}); err == nil {
+		t.Error("FilterChanges(missing) succeeded, want error")
+	}
+}
+
+func TestCopyTree(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	writeTestFile(t, src, "dir/a.txt", "a")
+	if err := os.Symlink("dir/a.txt", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := copyTree(src, dst); err != nil {
+		t.Fatalf("copyTree() error = %v", err)
+	}
+	want, _ := scanTree(src)
+	got, _ := scanTree(dst)
+	for path, state := range want {
+		if !got[path].matches(state, 0) {
+			t.Errorf("%s = %+v, want %+v", path, got[path], state)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("copied %d files, want %d", len(got), len(want))
+	}
+}
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"strings"
+
 	"github.com/jedi4ever/addt/provider"
 )
 
@@ -19,6 +21,22 @@ func PortsInjectPrompt(env map[string]string, cfg *provider.Config) {
 	if portMap != "" {
 		env["ADDT_PORT_MAP"] = portMap
 	}
+
+	// Add host services, which the entrypoint lists in ADDT_SYSTEM_PROMPT
+	// by the names the container reaches them under
+	if hostServiceMap := BuildHostServiceMapString(cfg); hostServiceMap != "" {
+		env["ADDT_HOST_SERVICE_MAP"] = hostServiceMap
+	}
+}
+
+// BuildHostServiceMapString creates a comma-separated host service list
+// Format: "name:port,name:port", e.g. "postgres:5432,redis:6379"
+func BuildHostServiceMapString(cfg *provider.Config) string {
+	var services []string
+	for _, s := range cfg.HostServices {
+		services = append(services, s.String())
+	}
+	return strings.Join(services, ",")
 }
 
 // BuildSystemPromptPortSection generates the port mapping section of the system prompt
@@ -100,3 +118,26 @@ func parsePortMapping(mapping string) (containerPort, hostPort string) {
 	}
 	return mapping[:colonIdx], mapping[colonIdx+1:]
 }
+
+// BuildSystemPromptHostServiceSection generates the host services section of
+// the system prompt, as the entrypoint script does from ADDT_HOST_SERVICE_MAP
+func BuildSystemPromptHostServiceSection(hostServiceMap string) string {
+	if hostServiceMap == "" {
+		return ""
+	}
+
+	result := `# Host Services
+
+These services on the user's machine are reachable from this container:
+`
+	for _, service := range splitPortMap(hostServiceMap) {
+		name, port := parsePortMapping(service)
+		if name != "" && port != "" {
+			result += "- " + name + ": " + name + ".host.addt:" + port + "\n"
+		}
+	}
+	return result + `
+IMPORTANT:
+- Connect to these services by the hostnames above, not localhost
+- Other services on the user's machine are not reachable from this container`
+}
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/jedi4ever/addt/config/security"
 	"github.com/jedi4ever/addt/provider"
 )
 
@@ -52,6 +53,48 @@ func TestPortsInjectPrompt_InjectSystemPromptDisabled(t *testing.T) {
 	}
 }
 
+func TestPortsInjectPrompt_HostServices(t *testing.T) {
+	cfg := &provider.Config{
+		HostServices: []security.HostService{
+			{Name: "postgres", Port: 5432},
+			{Name: "redis", Port: 6379},
+		},
+		PortsInjectSystemPrompt: true,
+	}
+
+	env := make(map[string]string)
+	PortsInjectPrompt(env, cfg)
+
+	if env["ADDT_HOST_SERVICE_MAP"] != "postgres:5432,redis:6379" {
+		t.Errorf("ADDT_HOST_SERVICE_MAP = %q, want postgres:5432,redis:6379", env["ADDT_HOST_SERVICE_MAP"])
+	}
+
+	cfg.PortsInjectSystemPrompt = false
+	env = make(map[string]string)
+	PortsInjectPrompt(env, cfg)
+	if _, ok := env["ADDT_HOST_SERVICE_MAP"]; ok {
+		t.Error("ADDT_HOST_SERVICE_MAP should not be set when inject_system_prompt is false")
+	}
+}
+
+func TestBuildSystemPromptHostServiceSection(t *testing.T) {
+	if prompt := BuildSystemPromptHostServiceSection(""); prompt != "" {
+		t.Errorf("Expected empty prompt, got %q", prompt)
+	}
+
+	prompt := BuildSystemPromptHostServiceSection("postgres:5432,redis:6379")
+	for _, phrase := range []string{
+		"Host Services",
+		"- postgres: postgres.host.addt:5432",
+		"- redis: redis.host.addt:6379",
+		"not localhost",
+	} {
+		if !strings.Contains(prompt, phrase) {
+			t.Errorf("Prompt missing %q\nGot: %s", phrase, prompt)
+		}
+	}
+}
+
 func TestBuildSystemPromptPortSection_Empty(t *testing.T) {
 	prompt := BuildSystemPromptPortSection("")
 
@@ -2,13 +2,21 @@ package core
 
 import (
 	"fmt"
+	"os"
+	"regexp"
+	"time"
 
+	"github.com/jedi4ever/addt/config/security"
 	"github.com/jedi4ever/addt/provider"
 	"github.com/jedi4ever/addt/util"
 )
 
 var runnerLogger = util.Log("runner")
 
+// containerNamePattern is what a given container name must look like; it
+// also names the environment's overlay or worktree directory
+var containerNamePattern = regexp.MustCompile(`^addt-[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
 // Runner coordinates container execution
 type Runner struct {
 	provider provider.Provider
@@ -45,6 +53,16 @@ func (r *Runner) execute(args []string, openShell bool) error {
 	name := r.generateName()
 	runnerLogger.Debugf("Generated container name: %s", name)
 
+	// Create the worktree or workspace overlay the run options mount
+	worktree, err := r.prepareWorktree(name)
+	if err != nil {
+		return err
+	}
+	overlay, err := r.prepareOverlay(name)
+	if err != nil {
+		return err
+	}
+
 	// Build run options
 	runnerLogger.Debug("Building run options")
 	opts := BuildRunOptions(r.provider, r.config, name, args, openShell)
@@ -55,32 +73,133 @@ func (r *Runner) execute(args []string, openShell bool) error {
 	runnerLogger.Debug("Displaying status")
 	DisplayStatus(r.provider, r.config, name)
 
+	// Record the session in the security audit log
+	mode := "run"
+	if openShell {
+		mode = "shell"
+	}
+	reused := opts.Persistent && r.provider.Exists(name)
+	if reused {
+		mode += ", existing container"
+	}
+	security.LogContainerStart(name, r.GetExtensionName(), mode)
+	r.auditRun(opts, reused)
+	started := time.Now()
+
 	// Execute via provider
 	if openShell {
 		runnerLogger.Debug("Calling provider.Shell")
-		err := r.provider.Shell(opts)
+		err = r.provider.Shell(opts)
 		if err != nil {
 			runnerLogger.Errorf("Provider.Shell failed: %v", err)
 		} else {
 			runnerLogger.Debug("Provider.Shell completed successfully")
 		}
-		return err
-	}
-	runnerLogger.Debug("Calling provider.Run")
-	err := r.provider.Run(opts)
-	if err != nil {
-		runnerLogger.Errorf("Provider.Run failed: %v", err)
 	} else {
-		runnerLogger.Debug("Provider.Run completed successfully")
+		runnerLogger.Debug("Calling provider.Run")
+		err = r.provider.Run(opts)
+		if err != nil {
+			runnerLogger.Errorf("Provider.Run failed: %v", err)
+		} else {
+			runnerLogger.Debug("Provider.Run completed successfully")
+		}
+	}
+	security.LogContainerStop(name, r.GetExtensionName(), time.Since(started), err)
+	if worktree != nil {
+		reportWorktree(worktree)
+	}
+	if overlay != nil {
+		reportOverlay(overlay)
 	}
 	return err
 }
 
-// generateName generates the container name based on persistence mode
+// prepareWorktree creates or reuses the environment's git worktree when the
+// workdir is one. A persistent container created without a worktree keeps
+// mounting the checkout.
+func (r *Runner) prepareWorktree(name string) (*Worktree, error) {
+	if !WorktreeEnabled(r.config) {
+		return nil, nil
+	}
+	if _, err := LoadWorktree(name); err != nil && r.config.Persistent && r.provider.Exists(name) {
+		fmt.Printf("⚠ %s was created without a worktree; remove it with 'addt containers rm %s' to use one\n", name, name)
+		return nil, nil
+	}
+	cwd := r.config.Workdir
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	}
+	return PrepareWorktree(name, cwd, r.config.WorkdirWorktreeName)
+}
+
+// reportWorktree shows the worktree's branch for review. An ephemeral
+// environment's worktree is removed unless it has uncommitted changes; the
+// branch stays while it holds commits.
+func reportWorktree(w *Worktree) {
+	fmt.Printf("Worktree branch %s\n", w.Summary())
+	if ahead, err := w.Ahead(); err == nil && ahead > 0 {
+		fmt.Printf("Review with 'git log -p HEAD..%s', merge with 'git merge %s'\n", w.Branch, w.Branch)
+	}
+	if w.Persistent() {
+		return
+	}
+	if dirty, err := w.Dirty(); err != nil || dirty {
+		fmt.Printf("Keeping worktree %s; remove it with 'git worktree remove --force %s'\n", w.Dir, w.Dir)
+		return
+	}
+	if err := w.Remove(); err != nil {
+		runnerLogger.Errorf("Failed to remove worktree: %v", err)
+	}
+}
+
+// prepareOverlay creates or reuses the environment's workspace overlay when
+// the workdir is one. A persistent container created without an overlay
+// keeps mounting the project itself.
+func (r *Runner) prepareOverlay(name string) (*Overlay, error) {
+	if !OverlayEnabled(r.config) {
+		return nil, nil
+	}
+	if _, err := LoadOverlay(name); err != nil && r.config.Persistent && r.provider.Exists(name) {
+		fmt.Printf("⚠ %s was created without a workspace overlay; remove it with 'addt containers rm %s' to use one\n", name, name)
+		return nil, nil
+	}
+	cwd := r.config.Workdir
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	}
+	return PrepareOverlay(name, cwd)
+}
+
+// reportOverlay tells what the agent changed in the overlay, dropping an
+// ephemeral environment's overlay when it changed nothing
+func reportOverlay(overlay *Overlay) {
+	changes, err := overlay.Changes()
+	if err != nil {
+		runnerLogger.Errorf("Failed to list overlay changes: %v", err)
+		return
+	}
+	if len(changes) == 0 {
+		if !overlay.Persistent() {
+			overlay.Remove()
+		}
+		return
+	}
+	fmt.Printf("%d file(s) changed in the workspace overlay; review with 'addt diff', then 'addt apply' or 'addt discard'\n", len(changes))
+}
+
+// generateName generates the container name based on persistence mode. An
+// ephemeral container takes the name it was given, if any, as a fan-out
+// member does.
 func (r *Runner) generateName() string {
 	if r.config.Persistent {
 		return r.provider.GeneratePersistentName()
 	}
+	if name := r.config.ContainerName; name != "" {
+		if containerNamePattern.MatchString(name) {
+			return name
+		}
+		runnerLogger.Errorf("Ignoring invalid container name %q", name)
+	}
 	return r.provider.GenerateEphemeralName()
 }
 
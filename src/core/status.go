@@ -6,6 +6,7 @@ import (
 
 	"github.com/muesli/termenv"
 
+	"github.com/jedi4ever/addt/config/security"
 	"github.com/jedi4ever/addt/provider"
 )
 
@@ -52,6 +53,12 @@ func SecurityPostureLine(cfg *provider.Config) (string, bool) {
 		parts = append(parts, "workdir:none")
 	} else if cfg.WorkdirReadonly {
 		parts = append(parts, "workdir:ro")
+	} else if cfg.WorkdirWorktree {
+		// Writes land on a branch, through the repository's git directory
+		parts = append(parts, "workdir:worktree")
+		allLocked = false
+	} else if cfg.WorkdirOverlay {
+		parts = append(parts, "workdir:overlay")
 	} else {
 		parts = append(parts, "workdir:rw")
 		allLocked = false
@@ -140,5 +147,21 @@ func FormatStatus(p provider.Provider, cfg *provider.Config, envName string) str
 		status += fmt.Sprintf(" | Ports:%s", portDisplay)
 	}
 
+	// Add the network usage of the environment's last session
+	if usage := NetworkUsageSummary(envName); usage != "" {
+		status += " | Net: " + usage
+	}
+
 	return status
 }
+
+// NetworkUsageSummary summarizes the network usage recorded for an
+// environment's last session, e.g. "↑1.2 MB ↓30.0 MB, 12 requests"; it's
+// empty when none was recorded
+func NetworkUsageSummary(envName string) string {
+	usage, err := security.LoadNetworkUsage(envName)
+	if err != nil || usage == nil {
+		return ""
+	}
+	return usage.Total().String()
+}
@@ -148,6 +148,20 @@ func TestSecurityPostureLine_WorkdirNotMounted(t *testing.T) {
 	}
 }
 
+func TestSecurityPostureLine_WorkdirOverlay(t *testing.T) {
+	cfg := &provider.Config{
+		WorkdirAutomount: true,
+		WorkdirOverlay:   true,
+		Security:         security.DefaultConfig(),
+	}
+
+	posture, _ := SecurityPostureLine(cfg)
+
+	if !strings.Contains(posture, "workdir:overlay") {
+		t.Errorf("Expected workdir:overlay, got %q", posture)
+	}
+}
+
 func TestSecurityPostureLine_SecretsExposed(t *testing.T) {
 	sec := security.DefaultConfig()
 	sec.IsolateSecrets = false
@@ -199,3 +213,27 @@ func TestSecurityPostureLine_FirewallPermissive(t *testing.T) {
 		t.Errorf("Expected firewall:permissive, got %q", posture)
 	}
 }
+
+func TestNetworkUsageSummary(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+
+	if got := NetworkUsageSummary("addt-test"); got != "" {
+		t.Errorf("Expected no summary without recorded usage, got %q", got)
+	}
+
+	usage := &security.NetworkUsage{
+		Container: "addt-test",
+		Destinations: []security.DestinationUsage{
+			{Host: "api.anthropic.com", BytesOut: 2048, BytesIn: 4096, Requests: 3},
+			{Host: "github.com", BytesOut: 1024, Requests: 1},
+		},
+	}
+	if err := security.SaveNetworkUsage(usage); err != nil {
+		t.Fatal(err)
+	}
+
+	got := NetworkUsageSummary("addt-test")
+	if !strings.Contains(got, "↑3.0 KB") || !strings.Contains(got, "4 requests") {
+		t.Errorf("Expected totals of both destinations, got %q", got)
+	}
+}
@@ -0,0 +1,255 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/util"
+)
+
+// Worktree isolation: with workdir.worktree each environment gets its own
+// git worktree of the project, on its own branch, in
+// ~/.addt/worktrees/<environment>, mounted at /workspace in place of the
+// checkout. The repository's git directory is mounted at its host path too,
+// which is where the worktree's .git file points, so the agent can commit.
+//
+// A persistent environment keeps its worktree until the container is
+// removed; an ephemeral one's is removed after the run unless it has
+// uncommitted changes. Branches are kept while they hold commits, for
+// review and merge.
+
+var worktreeLogger = util.Log("worktree")
+
+// worktreeBranchPrefix is the namespace of session branches
+const worktreeBranchPrefix = "addt/"
+
+// Worktree is an environment's git worktree
+type Worktree struct {
+	Name      string    `json:"name"`    // environment the worktree is mounted in
+	Repo      string    `json:"repo"`    // top level of the host checkout
+	GitDir    string    `json:"git_dir"` // the repository's common git directory
+	Branch    string    `json:"branch"`
+	Dir       string    `json:"dir"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WorktreeEnabled reports whether /workspace is a git worktree. A
+// read-only workdir takes precedence.
+func WorktreeEnabled(cfg *provider.Config) bool {
+	return cfg.WorkdirAutomount && cfg.WorkdirWorktree && !cfg.WorkdirReadonly
+}
+
+// WorktreesDir returns the directory worktrees are kept in
+func WorktreesDir() string {
+	return filepath.Join(util.GetAddtHome(), "worktrees")
+}
+
+// WorktreeDir returns the worktree mounted in an environment
+func WorktreeDir(name string) string {
+	return filepath.Join(WorktreesDir(), name)
+}
+
+// worktreeMetadata returns the file describing an environment's worktree
+func worktreeMetadata(name string) string {
+	return filepath.Join(WorktreesDir(), name+".json")
+}
+
+// WorktreeBranch returns the branch of an environment's worktree: addt/
+// followed by the session name, or by the environment's name without its
+// addt- prefix
+func WorktreeBranch(name, session string) string {
+	if session == "" {
+		session = strings.TrimPrefix(name, "addt-")
+	}
+	return worktreeBranchPrefix + session
+}
+
+// PrepareWorktree returns the worktree of an environment, adding one of the
+// repository source is in when it has none yet. An existing session branch
+// is checked out, otherwise a new one starts at the checkout's HEAD.
+func PrepareWorktree(name, source, session string) (*Worktree, error) {
+	if w, err := LoadWorktree(name); err == nil {
+		worktreeLogger.Debugf("Reusing worktree %s on %s", w.Dir, w.Branch)
+		return w, nil
+	}
+
+	repo, err := git(source, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository: %w", source, err)
+	}
+	branch := WorktreeBranch(name, session)
+	if _, err := git(repo, "check-ref-format", "--branch", branch); err != nil {
+		return nil, fmt.Errorf("invalid worktree branch %s", branch)
+	}
+
+	w := &Worktree{
+		Name:      name,
+		Repo:      repo,
+		Branch:    branch,
+		Dir:       WorktreeDir(name),
+		CreatedAt: time.Now(),
+	}
+	if err := os.MkdirAll(WorktreesDir(), 0700); err != nil {
+		return nil, err
+	}
+	args := []string{"worktree", "add", "-b", branch, w.Dir, "HEAD"}
+	if w.BranchExists() {
+		args = []string{"worktree", "add", w.Dir, branch}
+	}
+	if _, err := git(repo, args...); err != nil {
+		return nil, fmt.Errorf("failed to create worktree: %w", err)
+	}
+	// The git directory as the worktree's .git file names it, which is
+	// where it has to be mounted
+	gitDir, err := git(w.Dir, "rev-parse", "--git-common-dir")
+	if err == nil && !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(w.Dir, gitDir)
+	}
+	w.GitDir = gitDir
+	if err == nil {
+		err = w.save()
+	}
+	if err != nil {
+		git(repo, "worktree", "remove", "--force", w.Dir)
+		return nil, err
+	}
+	return w, nil
+}
+
+// LoadWorktree reads the worktree of an environment
+func LoadWorktree(name string) (*Worktree, error) {
+	data, err := os.ReadFile(worktreeMetadata(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("worktree %s not found", name)
+		}
+		return nil, err
+	}
+	w := &Worktree{}
+	if err := json.Unmarshal(data, w); err != nil {
+		return nil, fmt.Errorf("invalid worktree %s: %w", name, err)
+	}
+	return w, nil
+}
+
+// Persistent reports whether the worktree belongs to a persistent
+// environment
+func (w *Worktree) Persistent() bool {
+	return strings.HasPrefix(w.Name, "addt-persistent-")
+}
+
+// Ahead returns the number of commits on the branch that the checkout's
+// HEAD doesn't have
+func (w *Worktree) Ahead() (int, error) {
+	out, err := git(w.Repo, "rev-list", "--count", "HEAD.."+w.Branch)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(out)
+}
+
+// BranchExists reports whether the worktree's branch exists
+func (w *Worktree) BranchExists() bool {
+	_, err := git(w.Repo, "rev-parse", "--verify", "--quiet", "refs/heads/"+w.Branch)
+	return err == nil
+}
+
+// Dirty reports whether the worktree has uncommitted changes
+func (w *Worktree) Dirty() (bool, error) {
+	out, err := git(w.Dir, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// Remove removes the worktree, refusing when it has uncommitted changes.
+// The branch is deleted too when the checkout's HEAD has all its commits.
+func (w *Worktree) Remove() error {
+	if _, err := os.Stat(w.Dir); err == nil {
+		if _, err := git(w.Repo, "worktree", "remove", w.Dir); err != nil {
+			return fmt.Errorf("failed to remove worktree %s: %w", w.Dir, err)
+		}
+	} else {
+		git(w.Repo, "worktree", "prune")
+	}
+	if _, err := git(w.Repo, "branch", "-d", w.Branch); err != nil {
+		worktreeLogger.Debugf("Keeping branch %s: %v", w.Branch, err)
+	}
+	return os.Remove(worktreeMetadata(w.Name))
+}
+
+// Discard removes the worktree with its uncommitted changes and deletes
+// its branch with its commits
+func (w *Worktree) Discard() error {
+	if _, err := os.Stat(w.Dir); err == nil {
+		if _, err := git(w.Repo, "worktree", "remove", "--force", w.Dir); err != nil {
+			return fmt.Errorf("failed to remove worktree %s: %w", w.Dir, err)
+		}
+	} else {
+		git(w.Repo, "worktree", "prune")
+	}
+	if w.BranchExists() {
+		if _, err := git(w.Repo, "branch", "-D", w.Branch); err != nil {
+			return fmt.Errorf("failed to delete branch %s: %w", w.Branch, err)
+		}
+	}
+	if err := os.Remove(worktreeMetadata(w.Name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Summary describes the branch for review, e.g. "addt/x: 2 commit(s) ahead
+// of HEAD, uncommitted changes in ..."
+func (w *Worktree) Summary() string {
+	summary := w.Branch
+	if ahead, err := w.Ahead(); err == nil {
+		summary += fmt.Sprintf(": %d commit(s) ahead of HEAD", ahead)
+	}
+	if dirty, err := w.Dirty(); err == nil && dirty {
+		summary += fmt.Sprintf(", uncommitted changes in %s", w.Dir)
+	}
+	return summary
+}
+
+// RemoveWorktree removes an environment's worktree, if it has one
+func RemoveWorktree(name string) (*Worktree, error) {
+	w, err := LoadWorktree(name)
+	if err != nil {
+		return nil, nil
+	}
+	return w, w.Remove()
+}
+
+// save writes the worktree's metadata
+func (w *Worktree) save() error {
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(worktreeMetadata(w.Name), data, 0600)
+}
+
+// git runs git in dir and returns its trimmed output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// newTestRepo creates a git repository with one commit under a temporary
+// ADDT_HOME
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("ADDT_HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q", "-b", "main")
+	writeTestFile(t, repo, "a.txt", "a")
+	runGit(t, repo, "add", "a.txt")
+	runGit(t, repo, "commit", "-q", "-m", "initial")
+	return repo
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := git(dir, args...)
+	if err != nil {
+		t.Fatalf("git %v: %v", args, err)
+	}
+	return out
+}
+
+func TestWorktreeBranch(t *testing.T) {
+	if got := WorktreeBranch("addt-20261017-120000-42", ""); got != "addt/20261017-120000-42" {
+		t.Errorf("WorktreeBranch() = %q, want addt/20261017-120000-42", got)
+	}
+	if got := WorktreeBranch("addt-persistent-repo-1234abcd", "fix-login"); got != "addt/fix-login" {
+		t.Errorf("WorktreeBranch() = %q, want addt/fix-login", got)
+	}
+}
+
+func TestPrepareWorktree(t *testing.T) {
+	repo := newTestRepo(t)
+	os.MkdirAll(filepath.Join(repo, "sub"), 0755)
+
+	w, err := PrepareWorktree("addt-test-1", filepath.Join(repo, "sub"), "")
+	if err != nil {
+		t.Fatalf("PrepareWorktree() error = %v", err)
+	}
+	if w.Dir != WorktreeDir("addt-test-1") || w.Branch != "addt/test-1" {
+		t.Errorf("worktree = %s on %s, want %s on addt/test-1", w.Dir, w.Branch, WorktreeDir("addt-test-1"))
+	}
+	if got := readTestFile(t, w.Dir, "a.txt"); got != "a" {
+		t.Errorf("worktree a.txt = %q, want %q", got, "a")
+	}
+	// The git directory is mounted where the worktree's .git file points
+	wantGitDir, _ := filepath.EvalSymlinks(filepath.Join(repo, ".git"))
+	if gotGitDir, _ := filepath.EvalSymlinks(w.GitDir); gotGitDir != wantGitDir {
+		t.Errorf("GitDir = %q, want %q", w.GitDir, wantGitDir)
+	}
+
+	// The agent commits on its branch
+	writeTestFile(t, w.Dir, "b.txt", "b")
+	runGit(t, w.Dir, "add", "b.txt")
+	runGit(t, w.Dir, "commit", "-q", "-m", "agent")
+	if ahead, err := w.Ahead(); err != nil || ahead != 1 {
+		t.Errorf("Ahead() = %d, %v, want 1", ahead, err)
+	}
+	if dirty, _ := w.Dirty(); dirty {
+		t.Error("Dirty() = true after commit, want false")
+	}
+
+	// Reused while it exists
+	again, err := PrepareWorktree("addt-test-1", repo, "")
+	if err != nil || again.Dir != w.Dir {
+		t.Errorf("PrepareWorktree() again = %v, %v, want the same worktree", again, err)
+	}
+}
+
+func TestWorktree_Remove(t *testing.T) {
+	repo := newTestRepo(t)
+	w, err := PrepareWorktree("addt-test-2", repo, "session")
+	if err != nil {
+		t.Fatalf("PrepareWorktree() error = %v", err)
+	}
+
+	// Uncommitted changes keep the worktree
+	writeTestFile(t, w.Dir, "b.txt", "b")
+	if _, err := RemoveWorktree("addt-test-2"); err == nil {
+		t.Fatal("RemoveWorktree() with uncommitted changes succeeded, want error")
+	}
+	if _, err := os.Stat(w.Dir); err != nil {
+		t.Fatalf("worktree removed despite uncommitted changes: %v", err)
+	}
+
+	// A branch with commits is kept for merging
+	runGit(t, w.Dir, "add", "b.txt")
+	runGit(t, w.Dir, "commit", "-q", "-m", "agent")
+	if _, err := RemoveWorktree("addt-test-2"); err != nil {
+		t.Fatalf("RemoveWorktree() error = %v", err)
+	}
+	if _, err := os.Stat(w.Dir); !os.IsNotExist(err) {
+		t.Errorf("worktree still exists: %v", err)
+	}
+	if !w.BranchExists() {
+		t.Error("branch with commits was deleted")
+	}
+	if _, err := LoadWorktree("addt-test-2"); err == nil {
+		t.Error("LoadWorktree() after removal succeeded, want error")
+	}
+
+	// A new session on the same name checks the branch out again
+	w, err = PrepareWorktree("addt-test-3", repo, "session")
+	if err != nil {
+		t.Fatalf("PrepareWorktree() on existing branch error = %v", err)
+	}
+	if got := readTestFile(t, w.Dir, "b.txt"); got != "b" {
+		t.Errorf("b.txt = %q, want the branch's commit", got)
+	}
+
+	// A branch without commits of its own goes with the worktree
+	runGit(t, repo, "merge", "-q", "--ff-only", "addt/session")
+	if _, err := RemoveWorktree("addt-test-3"); err != nil {
+		t.Fatalf("RemoveWorktree() error = %v", err)
+	}
+	if w.BranchExists() {
+		t.Error("merged branch was kept")
+	}
+}
+
+func TestPrepareWorktree_NotARepository(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+	if _, err := PrepareWorktree("addt-test", t.TempDir(), ""); err == nil {
+		t.Error("PrepareWorktree() outside a repository succeeded, want error")
+	}
+}
@@ -0,0 +1,247 @@
+package extensions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// secretTimeout bounds a secret lookup; longer than credential scripts'
+// 5 seconds since a backend may wait for the user to unlock it
+const secretTimeout = 30 * time.Second
+
+// SecretRef is a parsed secret reference, e.g. "vault://secret/data/ai#key"
+type SecretRef struct {
+	Scheme string // backend: "op", "pass", "sops" or "vault"
+	Path   string // what the backend looks up, e.g. "secret/data/ai"
+	Field  string // part of the secret to return, e.g. "key"; "" for all of it
+	Raw    string // the reference as written
+}
+
+// ParseSecretRef parses a "<scheme>://<path>[#field]" secret reference
+func ParseSecretRef(ref string) (*SecretRef, error) {
+	scheme, rest, ok := strings.Cut(ref, "://")
+	if !ok || scheme == "" || rest == "" {
+		return nil, fmt.Errorf("invalid secret reference %q, want <backend>://<path>[#field]", ref)
+	}
+	path, field, _ := strings.Cut(rest, "#")
+	if path == "" {
+		return nil, fmt.Errorf("invalid secret reference %q: no path", ref)
+	}
+	return &SecretRef{Scheme: scheme, Path: path, Field: field, Raw: ref}, nil
+}
+
+// SecretResolver fetches secrets from one backend
+type SecretResolver interface {
+	Resolve(ctx context.Context, ref *SecretRef) (string, error)
+}
+
+// secretResolvers are the backends by reference scheme
+var secretResolvers = map[string]SecretResolver{
+	"op":    onePasswordResolver{},
+	"pass":  passResolver{},
+	"sops":  sopsResolver{},
+	"vault": vaultResolver{},
+}
+
+// RegisterSecretResolver adds or replaces the backend of a reference scheme
+func RegisterSecretResolver(scheme string, r SecretResolver) {
+	secretResolvers[scheme] = r
+}
+
+// SecretBackends returns the schemes secrets can be referenced with, sorted
+func SecretBackends() []string {
+	schemes := make([]string, 0, len(secretResolvers))
+	for scheme := range secretResolvers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// ResolveSecret fetches the value of a secret reference. Relative sops file
+// paths are relative to dir.
+func ResolveSecret(ref, dir string) (string, error) {
+	r, err := ParseSecretRef(ref)
+	if err != nil {
+		return "", err
+	}
+	resolver, ok := secretResolvers[r.Scheme]
+	if !ok {
+		return "", fmt.Errorf("unknown secret backend %q in %s (known: %s)", r.Scheme, ref, strings.Join(SecretBackends(), ", "))
+	}
+	if r.Scheme == "sops" && !filepath.IsAbs(r.Path) && dir != "" {
+		r.Path = filepath.Join(dir, r.Path)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+	value, err := resolver.Resolve(ctx, r)
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("%s timed out after %s", ref, secretTimeout)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", ref, err)
+	}
+	if value == "" {
+		return "", fmt.Errorf("%s is empty", ref)
+	}
+	return value, nil
+}
+
+// ResolveSecrets fetches the values of secret references by env var name.
+// Failed lookups are returned as errors by name and left out of the values.
+func ResolveSecrets(refs map[string]string, dir string) (map[string]string, map[string]error) {
+	values := make(map[string]string, len(refs))
+	errs := make(map[string]error)
+	for name, ref := range refs {
+		if !isValidEnvVarName(name) {
+			errs[name] = fmt.Errorf("%q is not an environment variable name", name)
+			continue
+		}
+		value, err := ResolveSecret(ref, dir)
+		if err != nil {
+			errs[name] = err
+			continue
+		}
+		values[name] = value
+	}
+	return values, errs
+}
+
+// runSecretCommand runs a backend's CLI and returns its output. Like
+// credential scripts it gets no stdin, so it can't hang on a prompt, and
+// its errors go to the user.
+var runSecretCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, fmt.Errorf("%s is not installed", name)
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = nil
+	return cmd.Output()
+}
+
+// onePasswordResolver reads op://<vault>/<item>/<field> with the 1Password CLI
+type onePasswordResolver struct{}
+
+func (onePasswordResolver) Resolve(ctx context.Context, ref *SecretRef) (string, error) {
+	out, err := runSecretCommand(ctx, "op", "read", "--no-newline", "op://"+ref.Path)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// passResolver reads pass://<path> with pass: the first line, or the
+// "<field>: value" line for pass://<path>#<field>
+type passResolver struct{}
+
+func (passResolver) Resolve(ctx context.Context, ref *SecretRef) (string, error) {
+	out, err := runSecretCommand(ctx, "pass", "show", ref.Path)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	if ref.Field == "" {
+		return lines[0], nil
+	}
+	for _, line := range lines[1:] {
+		if key, value, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(key) == ref.Field {
+			return strings.TrimSpace(value), nil
+		}
+	}
+	return "", fmt.Errorf("no %q field", ref.Field)
+}
+
+// sopsResolver decrypts a key of a sops file, sops://<file>#<key>; nested
+// keys are separated by dots, e.g. #openai.key
+type sopsResolver struct{}
+
+func (sopsResolver) Resolve(ctx context.Context, ref *SecretRef) (string, error) {
+	if ref.Field == "" {
+		return "", fmt.Errorf("needs a key, e.g. sops://secrets.enc.yaml#openai")
+	}
+	var extract strings.Builder
+	for _, key := range strings.Split(ref.Field, ".") {
+		fmt.Fprintf(&extract, "[%q]", key)
+	}
+	out, err := runSecretCommand(ctx, "sops", "--decrypt", "--extract", extract.String(), ref.Path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// vaultResolver reads a field of vault://<path>#<field> from Vault's HTTP
+// API at VAULT_ADDR, with VAULT_TOKEN or the token 'vault login' saved in
+// ~/.vault-token. KV version 2 paths include data/, e.g.
+// vault://secret/data/ai#key.
+type vaultResolver struct{}
+
+func (vaultResolver) Resolve(ctx context.Context, ref *SecretRef) (string, error) {
+	if ref.Field == "" {
+		return "", fmt.Errorf("needs a field, e.g. vault://secret/data/ai#key")
+	}
+	addr := strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/")
+	if addr == "" {
+		return "", fmt.Errorf("VAULT_ADDR is not set")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			data, _ := os.ReadFile(filepath.Join(home, ".vault-token"))
+			token = string(bytes.TrimSpace(data))
+		}
+	}
+	if token == "" {
+		return "", fmt.Errorf("no Vault token: set VAULT_TOKEN or run 'vault login'")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr+"/v1/"+strings.TrimPrefix(ref.Path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s", resp.Status)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid vault response: %w", err)
+	}
+	data := body.Data
+	// KV version 2 nests the secret under data.data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+	value, ok := data[ref.Field]
+	if !ok {
+		return "", fmt.Errorf("no %q field", ref.Field)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
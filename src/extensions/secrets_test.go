@@ -0,0 +1,137 @@
+package extensions
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeSecrets is a backend serving secrets from a map
+type fakeSecrets map[string]string
+
+func (f fakeSecrets) Resolve(ctx context.Context, ref *SecretRef) (string, error) {
+	value, ok := f[ref.Path]
+	if !ok {
+		return "", fmt.Errorf("not found")
+	}
+	return value, nil
+}
+
+// stubSecretCommand replaces the backends' CLIs, recording their args
+func stubSecretCommand(t *testing.T, output string) *[]string {
+	t.Helper()
+	var got []string
+	orig := runSecretCommand
+	runSecretCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		got = append([]string{name}, args...)
+		return []byte(output), nil
+	}
+	t.Cleanup(func() { runSecretCommand = orig })
+	return &got
+}
+
+func TestParseSecretRef(t *testing.T) {
+	r, err := ParseSecretRef("vault://secret/data/ai#key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Scheme != "vault" || r.Path != "secret/data/ai" || r.Field != "key" {
+		t.Errorf("ParseSecretRef() = %+v", r)
+	}
+	for _, bad := range []string{"sk-plain-value", "op://", "://path", "pass://#field"} {
+		if _, err := ParseSecretRef(bad); err == nil {
+			t.Errorf("ParseSecretRef(%q) should fail", bad)
+		}
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	RegisterSecretResolver("fake", fakeSecrets{"dev/anthropic": "sk-ant-123"})
+	defer delete(secretResolvers, "fake")
+
+	values, errs := ResolveSecrets(map[string]string{
+		"ANTHROPIC_API_KEY": "fake://dev/anthropic",
+		"OPENAI_API_KEY":    "fake://dev/openai",
+		"GEMINI_API_KEY":    "nope://x",
+		"not-a-var":         "fake://dev/anthropic",
+	}, "")
+	if len(values) != 1 || values["ANTHROPIC_API_KEY"] != "sk-ant-123" {
+		t.Errorf("values = %v", values)
+	}
+	for _, name := range []string{"OPENAI_API_KEY", "GEMINI_API_KEY", "not-a-var"} {
+		if errs[name] == nil {
+			t.Errorf("no error for %s", name)
+		}
+	}
+	if !strings.Contains(errs["GEMINI_API_KEY"].Error(), "unknown secret backend") {
+		t.Errorf("GEMINI_API_KEY error = %v", errs["GEMINI_API_KEY"])
+	}
+}
+
+func TestPassResolver(t *testing.T) {
+	stubSecretCommand(t, "s3cret\nuser: me\napi_key: sk-456\n")
+	if v, err := ResolveSecret("pass://dev/openai", ""); err != nil || v != "s3cret" {
+		t.Errorf("ResolveSecret(pass) = %q, %v, want the first line", v, err)
+	}
+	if v, err := ResolveSecret("pass://dev/openai#api_key", ""); err != nil || v != "sk-456" {
+		t.Errorf("ResolveSecret(pass#api_key) = %q, %v", v, err)
+	}
+}
+
+func TestSopsResolver(t *testing.T) {
+	got := stubSecretCommand(t, "sk-789\n")
+	v, err := ResolveSecret("sops://secrets.enc.yaml#ai.openai", "/project")
+	if err != nil || v != "sk-789" {
+		t.Fatalf("ResolveSecret(sops) = %q, %v", v, err)
+	}
+	want := `sops --decrypt --extract ["ai"]["openai"] /project/secrets.enc.yaml`
+	if strings.Join(*got, " ") != want {
+		t.Errorf("ran %v, want %s", *got, want)
+	}
+	if _, err := ResolveSecret("sops://secrets.enc.yaml", "/project"); err == nil {
+		t.Error("sops reference without a key should fail")
+	}
+}
+
+func TestVaultResolver(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "dev-token" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/ai":
+			fmt.Fprint(w, `{"data":{"data":{"key":"sk-kv2"},"metadata":{"version":3}}}`)
+		case "/v1/kv/ai":
+			fmt.Fprint(w, `{"data":{"key":"sk-kv1"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer vault.Close()
+	t.Setenv("VAULT_ADDR", vault.URL)
+	t.Setenv("VAULT_TOKEN", "dev-token")
+
+	tests := map[string]string{
+		"vault://secret/data/ai#key": "sk-kv2",
+		"vault://kv/ai#key":          "sk-kv1",
+	}
+	for ref, want := range tests {
+		if v, err := ResolveSecret(ref, ""); err != nil || v != want {
+			t.Errorf("ResolveSecret(%s) = %q, %v, want %q", ref, v, err, want)
+		}
+	}
+	for _, ref := range []string{"vault://secret/data/ai#other", "vault://secret/data/none#key", "vault://secret/data/ai"} {
+		if _, err := ResolveSecret(ref, ""); err == nil {
+			t.Errorf("ResolveSecret(%s) should fail", ref)
+		}
+	}
+
+	t.Setenv("VAULT_TOKEN", "wrong")
+	if _, err := ResolveSecret("vault://secret/data/ai#key", ""); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("ResolveSecret() with a wrong token = %v, want 403", err)
+	}
+}
@@ -87,6 +87,21 @@ type ExtensionAuthConfig struct {
 	Method    string `yaml:"method" json:"method"`       // How to authenticate: native, env, auto
 }
 
+// ExtensionAPI declares an API a tool calls with a key, for the host-side
+// API proxy (security.api_proxy): the container gets a placeholder in
+// KeyEnv and BaseURLEnv pointed at the proxy, which puts the real key in
+// Header before forwarding to Upstream. UnproxiedEnv names credentials the
+// tool can use instead, which the proxy can't stand in for.
+type ExtensionAPI struct {
+	Name         string   `yaml:"name" json:"name"`                                       // Route on the proxy, e.g. anthropic
+	Upstream     string   `yaml:"upstream" json:"upstream"`                               // e.g. https://api.anthropic.com
+	BaseURLEnv   string   `yaml:"base_url_env" json:"base_url_env"`                       // Env var the tool reads its base URL from
+	KeyEnv       string   `yaml:"key_env" json:"key_env"`                                 // Env var holding the key
+	Header       string   `yaml:"header" json:"header"`                                   // Header the key is sent in, e.g. x-api-key
+	Scheme       string   `yaml:"scheme,omitempty" json:"scheme,omitempty"`               // Prefix of the header value, e.g. Bearer
+	UnproxiedEnv []string `yaml:"unproxied_env,omitempty" json:"unproxied_env,omitempty"` // Other credentials, passed into the container as they are
+}
+
 // ExtensionCfgSection holds the config: section in extension config.yaml
 type ExtensionCfgSection struct {
 	Automount bool             `yaml:"automount" json:"automount"` // Auto-mount extension config directories
@@ -108,6 +123,7 @@ type ExtensionConfig struct {
 	OtelVars         []string            `yaml:"otel_vars" json:"otel_vars,omitempty"` // OpenTelemetry env vars; supports "VAR" or "VAR=default"
 	Flags            []ExtensionFlag     `yaml:"flags" json:"flags,omitempty"`
 	CredentialScript string              `yaml:"credential_script,omitempty" json:"credential_script,omitempty"` // Script to run on host for credentials
+	APIs             []ExtensionAPI      `yaml:"apis,omitempty" json:"apis,omitempty"`                           // APIs whose keys the API proxy keeps on the host
 	IsLocal          bool                `yaml:"-" json:"-"`                                                     // Runtime flag, not serialized
 }
 
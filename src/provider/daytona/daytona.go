@@ -499,12 +499,9 @@ func (p *DaytonaProvider) loadEnvFile(envFilePath string) []string {
 	return args
 }
 
-// runInteractiveSSH uses the Daytona API to get an SSH token and connects via native ssh
-func (p *DaytonaProvider) runInteractiveSSH(sandboxName string, args []string) error {
-	ctx := context.Background()
-
-	fmt.Println("Creating Daytona API client...")
-	// Create API client configuration with proper settings
+// newAPIClient creates a Daytona API client from DAYTONA_API_URL,
+// DAYTONA_API_KEY and DAYTONA_ORGANIZATION_ID
+func newAPIClient() (*apiclient.APIClient, error) {
 	cfg := apiclient.NewConfiguration()
 
 	// Configure API URL (default to app.daytona.io/api)
@@ -513,42 +510,71 @@ func (p *DaytonaProvider) runInteractiveSSH(sandboxName string, args []string) e
 		apiURL = "https://app.daytona.io/api"
 	}
 	cfg.Servers[0].URL = apiURL
-	fmt.Printf("Using API URL: %s\n", apiURL)
 
 	// Add API key authentication
 	apiKey := os.Getenv("DAYTONA_API_KEY")
 	if apiKey == "" {
-		return fmt.Errorf("DAYTONA_API_KEY environment variable not set")
+		return nil, fmt.Errorf("DAYTONA_API_KEY environment variable not set")
 	}
 	cfg.AddDefaultHeader("Authorization", "Bearer "+apiKey)
 
 	// Add organization ID if set
-	orgID := os.Getenv("DAYTONA_ORGANIZATION_ID")
-	if orgID != "" {
+	if orgID := os.Getenv("DAYTONA_ORGANIZATION_ID"); orgID != "" {
 		cfg.AddDefaultHeader("X-Daytona-Organization-ID", orgID)
-		fmt.Printf("Using Organization ID: %s\n", orgID)
 	}
 
-	apiClient := apiclient.NewAPIClient(cfg)
+	return apiclient.NewAPIClient(cfg), nil
+}
 
-	fmt.Printf("Requesting SSH token for sandbox: %s\n", sandboxName)
-	// Generate SSH token (30 minutes expiry)
+// sshAccessToken requests a 30 minute SSH token for a sandbox
+func sshAccessToken(ctx context.Context, apiClient *apiclient.APIClient, sandboxName string) (string, error) {
 	expiresIn := float32(30)
 	req := apiClient.SandboxAPI.CreateSshAccess(ctx, sandboxName).ExpiresInMinutes(expiresIn)
 	sshAccess, resp, err := req.Execute()
 	if err != nil {
-		fmt.Printf("API Error: %v\n", err)
 		if resp != nil {
-			fmt.Printf("Response Status: %s\n", resp.Status)
+			return "", fmt.Errorf("failed to create SSH access (%s): %w", resp.Status, err)
 		}
-		return fmt.Errorf("failed to create SSH access: %w", err)
+		return "", fmt.Errorf("failed to create SSH access: %w", err)
 	}
-
 	if sshAccess.Token == "" {
-		return fmt.Errorf("SSH token is empty")
+		return "", fmt.Errorf("SSH token is empty")
 	}
+	return sshAccess.Token, nil
+}
 
-	fmt.Printf("Got SSH token: %s...\n", sshAccess.Token[:10])
+// sshCommand returns an ssh command running remoteCmd in the sandbox the
+// token grants access to. tty requests a remote terminal.
+func sshCommand(token string, tty bool, remoteCmd string) *exec.Cmd {
+	args := []string{
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+		"-o", "LogLevel=ERROR",
+	}
+	if tty {
+		args = append([]string{"-t"}, args...)
+	}
+	args = append(args, fmt.Sprintf("%s@ssh.app.daytona.io", token), remoteCmd)
+	return exec.Command("ssh", args...)
+}
+
+// runInteractiveSSH uses the Daytona API to get an SSH token and connects via native ssh
+func (p *DaytonaProvider) runInteractiveSSH(sandboxName string, args []string) error {
+	ctx := context.Background()
+
+	fmt.Println("Creating Daytona API client...")
+	apiClient, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Requesting SSH token for sandbox: %s\n", sandboxName)
+	token, err := sshAccessToken(ctx, apiClient, sandboxName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Got SSH token: %s...\n", token[:min(10, len(token))])
 
 	// Build command to run - use entrypoint script which handles port mapping and setup
 	entrypointCmd := "/usr/local/bin/daytona-entrypoint.sh"
@@ -561,13 +587,7 @@ func (p *DaytonaProvider) runInteractiveSSH(sandboxName string, args []string) e
 
 	// Use native SSH with -t flag for TTY allocation
 	fmt.Println("Connecting via SSH with proper TTY...")
-	sshCmd := exec.Command("ssh",
-		"-t",
-		"-o", "StrictHostKeyChecking=no",
-		"-o", "UserKnownHostsFile=/dev/null",
-		"-o", "LogLevel=ERROR",
-		fmt.Sprintf("%s@ssh.app.daytona.io", sshAccess.Token),
-		cmdToRun)
+	sshCmd := sshCommand(token, true, cmdToRun)
 	sshCmd.Stdin = os.Stdin
 	sshCmd.Stdout = os.Stdout
 	sshCmd.Stderr = os.Stderr
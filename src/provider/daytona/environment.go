@@ -0,0 +1,148 @@
+package daytona
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/util"
+)
+
+// Access to existing sandboxes over SSH: exec runs the command directly
+// (bypassing the entrypoint), copies stream a tar archive through a remote
+// tar.
+
+// ssh runs remoteCmd in a sandbox with the given stdio
+func (p *DaytonaProvider) ssh(name string, remoteCmd string, tty bool, stdio provider.Stdio) error {
+	apiClient, err := newAPIClient()
+	if err != nil {
+		return err
+	}
+	token, err := sshAccessToken(context.Background(), apiClient, name)
+	if err != nil {
+		return err
+	}
+	cmd := sshCommand(token, tty, remoteCmd)
+	cmd.Stdin = stdio.Stdin
+	cmd.Stdout = stdio.Stdout
+	cmd.Stderr = stdio.Stderr
+	return cmd.Run()
+}
+
+// Exec runs cmd in a sandbox
+func (p *DaytonaProvider) Exec(name string, cmd []string, tty bool, stdio provider.Stdio) error {
+	return p.ssh(name, shellJoin(cmd), tty, stdio.WithDefaults())
+}
+
+// Logs is not supported: Daytona keeps no output of SSH sessions
+func (p *DaytonaProvider) Logs(name string, follow bool, out io.Writer) error {
+	return fmt.Errorf("logs of %s: %w", name, provider.ErrNotSupported)
+}
+
+// CopyTo copies a host file or directory into a sandbox
+func (p *DaytonaProvider) CopyTo(name, hostPath, envPath string) error {
+	if _, err := os.Stat(hostPath); err != nil {
+		return err
+	}
+	// Into an existing directory the source keeps its name, otherwise it
+	// is renamed to the destination
+	dir, base := envPath, filepath.Base(hostPath)
+	if p.ssh(name, shellJoin([]string{"test", "-d", envPath}), false, provider.Stdio{Stdout: io.Discard, Stderr: io.Discard}) != nil {
+		dir, base = path.Dir(envPath), path.Base(envPath)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(util.TarPath(hostPath, base, pw))
+	}()
+	var stderr bytes.Buffer
+	err := p.ssh(name, shellJoin([]string{"tar", "-xf", "-", "-C", dir}), false, provider.Stdio{Stdin: pr, Stdout: io.Discard, Stderr: &stderr})
+	pr.Close()
+	if err != nil {
+		return fmt.Errorf("copy to %s:%s: %w: %s", name, envPath, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// CopyFrom copies a file or directory from a sandbox to the host
+func (p *DaytonaProvider) CopyFrom(name, envPath, hostPath string) error {
+	pr, pw := io.Pipe()
+	var stderr bytes.Buffer
+	go func() {
+		remoteCmd := shellJoin([]string{"tar", "-cf", "-", "-C", path.Dir(envPath), path.Base(envPath)})
+		pw.CloseWithError(p.ssh(name, remoteCmd, false, provider.Stdio{Stdout: pw, Stderr: &stderr}))
+	}()
+
+	err := util.UntarPath(pr, hostPath)
+	io.Copy(io.Discard, pr)
+	if err != nil {
+		return fmt.Errorf("copy from %s:%s: %w: %s", name, envPath, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Inspect describes a sandbox
+func (p *DaytonaProvider) Inspect(name string) (*provider.EnvironmentInfo, error) {
+	apiClient, err := newAPIClient()
+	if err != nil {
+		return nil, err
+	}
+	sandbox, _, err := apiClient.SandboxAPI.GetSandbox(context.Background(), name).Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sandbox %s: %w", name, err)
+	}
+
+	state := string(sandbox.GetState())
+	info := &provider.EnvironmentInfo{
+		Name:       sandbox.GetName(),
+		ID:         sandbox.GetId(),
+		Image:      sandbox.GetSnapshot(),
+		Status:     state,
+		Running:    state == "started",
+		Persistent: strings.HasPrefix(name, "addt-persistent-"),
+		Labels:     sandbox.GetLabels(),
+	}
+	if t, err := time.Parse(time.RFC3339, sandbox.GetCreatedAt()); err == nil {
+		info.CreatedAt = t
+	}
+	return info, nil
+}
+
+// shellJoin quotes args for the remote shell ssh hands the command to
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Snapshots are not supported: Daytona snapshots are images sandboxes are
+// created from, not saved sandbox state
+
+// Snapshot is not supported
+func (p *DaytonaProvider) Snapshot(name, tag string, checkpoint bool) (*provider.Snapshot, error) {
+	return nil, fmt.Errorf("daytona snapshots: %w", provider.ErrNotSupported)
+}
+
+// Restore is not supported
+func (p *DaytonaProvider) Restore(name, tag string) error {
+	return fmt.Errorf("daytona snapshots: %w", provider.ErrNotSupported)
+}
+
+// ListSnapshots returns no snapshots
+func (p *DaytonaProvider) ListSnapshots(name string) ([]provider.Snapshot, error) {
+	return nil, nil
+}
+
+// RemoveSnapshot is not supported
+func (p *DaytonaProvider) RemoveSnapshot(name, tag string) error {
+	return fmt.Errorf("daytona snapshots: %w", provider.ErrNotSupported)
+}
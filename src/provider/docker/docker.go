@@ -3,116 +3,39 @@ package docker
 import (
 	"embed"
 	"fmt"
-	"os"
 	"os/exec"
 
-	"github.com/jedi4ever/addt/config/security"
 	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/provider/ocicli"
 )
 
-// DockerProvider implements the Provider interface for Docker
-type DockerProvider struct {
-	dockerContext          string // Docker context name (e.g. "desktop-linux", "rancher-desktop")
-	config                 *provider.Config
-	tempDirs               []string
-	sshProxy               *security.SSHProxyAgent
-	gpgProxy               *security.GPGProxyAgent
-	tmuxProxy              *tmuxProxy
-	embeddedDockerfile     []byte
-	embeddedDockerfileBase []byte
-	embeddedEntrypoint     []byte
-	embeddedInitFirewall   []byte
-	embeddedInstallSh      []byte
-	embeddedExtensions     embed.FS
-}
-
 // NewDockerProvider creates a new Docker provider.
 // dockerContext is the Docker context name (e.g. "desktop-linux", "rancher-desktop").
 func NewDockerProvider(cfg *provider.Config, dockerContext string, dockerfile, dockerfileBase, entrypoint, initFirewall, installSh []byte, extensions embed.FS) (provider.Provider, error) {
-	return &DockerProvider{
-		dockerContext:          dockerContext,
-		config:                 cfg,
-		tempDirs:               []string{},
-		embeddedDockerfile:     dockerfile,
-		embeddedDockerfileBase: dockerfileBase,
-		embeddedEntrypoint:     entrypoint,
-		embeddedInitFirewall:   initFirewall,
-		embeddedInstallSh:      installSh,
-		embeddedExtensions:     extensions,
-	}, nil
-}
-
-// Initialize initializes the Docker provider
-func (p *DockerProvider) Initialize(cfg *provider.Config) error {
-	p.config = cfg
-
-	// Clean up stale temp directories from previous runs
-	security.CleanupAll()
-
-	return p.CheckPrerequisites()
+	return ocicli.New(Runtime(dockerContext), cfg, dockerfile, dockerfileBase, entrypoint, initFirewall, installSh, extensions), nil
 }
 
-// GetName returns the provider name
-func (p *DockerProvider) GetName() string {
-	if p.dockerContext == "rancher-desktop" {
-		return "rancher"
+// Runtime returns the Docker runtime descriptor for the given context
+func Runtime(dockerContext string) ocicli.Runtime {
+	rt := ocicli.DockerRuntime(dockerContext)
+	rt.Prerequisites = func() error {
+		return checkPrerequisites(dockerContext)
 	}
-	return "docker"
+	return rt
 }
 
-// CheckPrerequisites verifies Docker is installed and running
-func (p *DockerProvider) CheckPrerequisites() error {
+// checkPrerequisites verifies Docker is installed and running
+func checkPrerequisites(dockerContext string) error {
 	// Check Docker is installed
 	if _, err := exec.LookPath("docker"); err != nil {
 		return fmt.Errorf("Docker is not installed. Please install Docker from: https://docs.docker.com/get-docker/")
 	}
 
 	// Check Docker daemon is running
-	cmd := p.dockerCmd("info")
+	cmd := provider.DockerCmd(dockerContext, "info")
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("Docker daemon is not running. Please start Docker and try again")
 	}
 
 	return nil
 }
-
-// Container lifecycle methods (Exists, IsRunning, Start, Stop, Remove, List)
-// and name generation (GenerateContainerName, GenerateEphemeralName, GeneratePersistentName)
-// are defined in persistent.go
-
-// dockerCmd creates an exec.Cmd for docker targeting the provider's context.
-func (p *DockerProvider) dockerCmd(args ...string) *exec.Cmd {
-	return provider.DockerCmd(p.dockerContext, args...)
-}
-
-// dockerEnv returns the environment slice for Docker commands in this context.
-func (p *DockerProvider) dockerEnv() []string {
-	return append(os.Environ(), "DOCKER_CONTEXT="+p.dockerContext)
-}
-
-// Cleanup removes temporary directories and stops proxies
-func (p *DockerProvider) Cleanup() error {
-	// Stop SSH proxy if running
-	if p.sshProxy != nil {
-		p.sshProxy.Stop()
-		p.sshProxy = nil
-	}
-
-	// Stop GPG proxy if running
-	if p.gpgProxy != nil {
-		p.gpgProxy.Stop()
-		p.gpgProxy = nil
-	}
-
-	// Stop tmux proxy if running
-	if p.tmuxProxy != nil {
-		p.tmuxProxy.Stop()
-		p.tmuxProxy = nil
-	}
-
-	for _, dir := range p.tempDirs {
-		os.RemoveAll(dir)
-	}
-	p.tempDirs = []string{}
-	return nil
-}
@@ -0,0 +1,360 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The shared provider describes containers as docker-compatible `run` and
+// `exec` argument vectors. These parsers translate the subset of flags it
+// emits into Engine API request bodies; any other flag is rejected so a new
+// flag can't be silently dropped.
+
+// runRequest is a parsed `run` argument vector
+type runRequest struct {
+	name        string
+	remove      bool // --rm
+	detach      bool // -d
+	interactive bool // -i
+	config      ContainerConfig
+}
+
+// execRequest is a parsed `exec` argument vector
+type execRequest struct {
+	container string
+	config    ExecConfig
+}
+
+// valueFlags lists run/exec flags that take a value
+var valueFlags = map[string]bool{
+	"--name": true, "-v": true, "--volume": true, "-e": true, "--env": true,
+	"-p": true, "--publish": true, "--tmpfs": true, "--network": true,
+	"--cap-add": true, "--cap-drop": true, "--security-opt": true,
+	"--pids-limit": true, "--ulimit": true, "--ipc": true, "--memory": true,
+	"--memory-swap": true, "--cpus": true, "--add-host": true,
+	"--group-add": true, "--userns": true, "--device": true, "-u": true,
+	"--user": true, "--entrypoint": true, "-w": true, "--workdir": true,
+	"-l": true, "--label": true,
+}
+
+// splitFlag splits args[i] into flag and value, consuming the next argument
+// for value flags given as "--flag value". It returns the index of the last
+// consumed argument.
+func splitFlag(args []string, i int) (flag, value string, next int, err error) {
+	arg := args[i]
+	if strings.HasPrefix(arg, "--") {
+		if eq := strings.Index(arg, "="); eq > 0 {
+			return arg[:eq], arg[eq+1:], i, nil
+		}
+	}
+	if !valueFlags[arg] {
+		return arg, "", i, nil
+	}
+	if i+1 >= len(args) {
+		return "", "", i, fmt.Errorf("flag %s needs a value", arg)
+	}
+	return arg, args[i+1], i + 1, nil
+}
+
+// parseRunArgs parses a `run` argument vector (starting with "run")
+func parseRunArgs(args []string) (*runRequest, error) {
+	if len(args) == 0 || args[0] != "run" {
+		return nil, fmt.Errorf("not a run command: %v", args)
+	}
+
+	req := &runRequest{}
+	cfg := &req.config
+	host := &cfg.HostConfig
+
+	i := 1
+	for ; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") {
+			break // image
+		}
+		flag, value, next, err := splitFlag(args, i)
+		if err != nil {
+			return nil, err
+		}
+		i = next
+
+		switch flag {
+		case "--rm":
+			req.remove = true
+		case "-d", "--detach":
+			req.detach = true
+		case "-i", "--interactive":
+			req.interactive = true
+		case "-t", "--tty":
+			cfg.Tty = true
+		case "-it", "-ti":
+			req.interactive = true
+			cfg.Tty = true
+		case "--init":
+			init := true
+			host.Init = &init
+		case "--privileged":
+			host.Privileged = true
+		case "--read-only":
+			host.ReadonlyRootfs = true
+		case "--name":
+			req.name = value
+		case "-v", "--volume":
+			host.Binds = append(host.Binds, value)
+		case "-e", "--env":
+			if env, ok := envValue(value); ok {
+				cfg.Env = append(cfg.Env, env)
+			}
+		case "-p", "--publish":
+			if err := addPortBinding(cfg, value); err != nil {
+				return nil, err
+			}
+		case "--tmpfs":
+			if host.Tmpfs == nil {
+				host.Tmpfs = map[string]string{}
+			}
+			path, opts, _ := strings.Cut(value, ":")
+			host.Tmpfs[path] = opts
+		case "--network":
+			host.NetworkMode = value
+		case "--cap-add":
+			host.CapAdd = append(host.CapAdd, value)
+		case "--cap-drop":
+			host.CapDrop = append(host.CapDrop, value)
+		case "--security-opt":
+			host.SecurityOpt = append(host.SecurityOpt, value)
+		case "--pids-limit":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --pids-limit %q: %w", value, err)
+			}
+			host.PidsLimit = &n
+		case "--ulimit":
+			ulimit, err := parseUlimit(value)
+			if err != nil {
+				return nil, err
+			}
+			host.Ulimits = append(host.Ulimits, ulimit)
+		case "--ipc":
+			host.IpcMode = value
+		case "--memory":
+			n, err := parseBytes(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --memory %q: %w", value, err)
+			}
+			host.Memory = n
+		case "--memory-swap":
+			n, err := parseBytes(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --memory-swap %q: %w", value, err)
+			}
+			host.MemorySwap = n
+		case "--cpus":
+			cpus, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --cpus %q: %w", value, err)
+			}
+			host.NanoCPUs = int64(cpus * 1e9)
+		case "--add-host":
+			host.ExtraHosts = append(host.ExtraHosts, value)
+		case "--group-add":
+			host.GroupAdd = append(host.GroupAdd, value)
+		case "--userns":
+			host.UsernsMode = value
+		case "--device":
+			host.Devices = append(host.Devices, parseDevice(value))
+		case "-u", "--user":
+			cfg.User = value
+		case "--entrypoint":
+			cfg.Entrypoint = []string{value}
+		case "-w", "--workdir":
+			cfg.WorkingDir = value
+		case "-l", "--label":
+			if cfg.Labels == nil {
+				cfg.Labels = map[string]string{}
+			}
+			k, v, _ := strings.Cut(value, "=")
+			cfg.Labels[k] = v
+		default:
+			return nil, fmt.Errorf("unsupported run flag %q", flag)
+		}
+	}
+
+	if i >= len(args) {
+		return nil, fmt.Errorf("run command has no image: %v", args)
+	}
+	cfg.Image = args[i]
+	cfg.Cmd = args[i+1:]
+
+	cfg.AttachStdout = !req.detach
+	cfg.AttachStderr = !req.detach
+	if req.interactive {
+		cfg.OpenStdin = true
+		cfg.AttachStdin = !req.detach
+		cfg.StdinOnce = !req.detach
+	}
+	return req, nil
+}
+
+// parseExecArgs parses an `exec` argument vector (starting with "exec")
+func parseExecArgs(args []string) (*execRequest, error) {
+	if len(args) == 0 || args[0] != "exec" {
+		return nil, fmt.Errorf("not an exec command: %v", args)
+	}
+
+	req := &execRequest{}
+	cfg := &req.config
+
+	i := 1
+	for ; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") {
+			break // container
+		}
+		flag, value, next, err := splitFlag(args, i)
+		if err != nil {
+			return nil, err
+		}
+		i = next
+
+		switch flag {
+		case "-i", "--interactive":
+			cfg.AttachStdin = true
+		case "-t", "--tty":
+			cfg.Tty = true
+		case "-it", "-ti":
+			cfg.AttachStdin = true
+			cfg.Tty = true
+		case "-u", "--user":
+			cfg.User = value
+		case "-e", "--env":
+			if env, ok := envValue(value); ok {
+				cfg.Env = append(cfg.Env, env)
+			}
+		case "-w", "--workdir":
+			cfg.WorkingDir = value
+		default:
+			return nil, fmt.Errorf("unsupported exec flag %q", flag)
+		}
+	}
+
+	if i+1 >= len(args) {
+		return nil, fmt.Errorf("exec command needs a container and a command: %v", args)
+	}
+	req.container = args[i]
+	cfg.Cmd = args[i+1:]
+	cfg.AttachStdout = true
+	cfg.AttachStderr = true
+	return req, nil
+}
+
+// envValue resolves an -e value. A bare name takes its value from the host
+// environment and is skipped when unset, as with the CLI.
+func envValue(value string) (string, bool) {
+	if strings.Contains(value, "=") {
+		return value, true
+	}
+	if v, ok := os.LookupEnv(value); ok {
+		return value + "=" + v, true
+	}
+	return "", false
+}
+
+// addPortBinding parses -p [ip:][hostPort:]containerPort[/proto]
+func addPortBinding(cfg *ContainerConfig, value string) error {
+	spec, proto, _ := strings.Cut(value, "/")
+	if proto == "" {
+		proto = "tcp"
+	}
+
+	parts := strings.Split(spec, ":")
+	var binding PortBinding
+	var containerPort string
+	switch len(parts) {
+	case 1:
+		containerPort = parts[0]
+	case 2:
+		binding.HostPort, containerPort = parts[0], parts[1]
+	case 3:
+		binding.HostIP, binding.HostPort, containerPort = parts[0], parts[1], parts[2]
+	default:
+		return fmt.Errorf("invalid port mapping %q", value)
+	}
+	if _, err := strconv.Atoi(containerPort); err != nil {
+		return fmt.Errorf("invalid port mapping %q", value)
+	}
+
+	key := containerPort + "/" + proto
+	if cfg.ExposedPorts == nil {
+		cfg.ExposedPorts = map[string]struct{}{}
+	}
+	cfg.ExposedPorts[key] = struct{}{}
+	if cfg.HostConfig.PortBindings == nil {
+		cfg.HostConfig.PortBindings = map[string][]PortBinding{}
+	}
+	cfg.HostConfig.PortBindings[key] = append(cfg.HostConfig.PortBindings[key], binding)
+	return nil
+}
+
+// parseUlimit parses name=soft[:hard]
+func parseUlimit(value string) (Ulimit, error) {
+	name, limits, ok := strings.Cut(value, "=")
+	if !ok {
+		return Ulimit{}, fmt.Errorf("invalid --ulimit %q", value)
+	}
+	softStr, hardStr, hasHard := strings.Cut(limits, ":")
+	soft, err := strconv.ParseInt(softStr, 10, 64)
+	if err != nil {
+		return Ulimit{}, fmt.Errorf("invalid --ulimit %q: %w", value, err)
+	}
+	hard := soft
+	if hasHard {
+		if hard, err = strconv.ParseInt(hardStr, 10, 64); err != nil {
+			return Ulimit{}, fmt.Errorf("invalid --ulimit %q: %w", value, err)
+		}
+	}
+	return Ulimit{Name: name, Soft: soft, Hard: hard}, nil
+}
+
+// parseDevice parses host[:container[:permissions]]
+func parseDevice(value string) DeviceMapping {
+	parts := strings.SplitN(value, ":", 3)
+	dev := DeviceMapping{PathOnHost: parts[0], PathInContainer: parts[0], CgroupPermissions: "rwm"}
+	if len(parts) > 1 && parts[1] != "" {
+		dev.PathInContainer = parts[1]
+	}
+	if len(parts) > 2 && parts[2] != "" {
+		dev.CgroupPermissions = parts[2]
+	}
+	return dev
+}
+
+// parseBytes parses a CLI memory size ("512m", "2g", "4gb", "1024", "-1")
+func parseBytes(value string) (int64, error) {
+	if value == "-1" {
+		return -1, nil
+	}
+	s := strings.ToLower(strings.TrimSpace(value))
+	s = strings.TrimSuffix(s, "b")
+	multiplier := int64(1)
+	if n := len(s); n > 0 {
+		switch s[n-1] {
+		case 'k':
+			multiplier = 1 << 10
+		case 'm':
+			multiplier = 1 << 20
+		case 'g':
+			multiplier = 1 << 30
+		case 't':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			s = s[:n-1]
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(f * float64(multiplier)), nil
+}
@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRunArgs_Interactive(t *testing.T) {
+	args := []string{"run", "--rm", "--name", "addt-test", "-it", "--init",
+		"-v", "/src:/workspace", "-v", "/home/u/.gitconfig:/home/addt/.gitconfig.host:ro",
+		"-e", "FOO=bar", "-p", "127.0.0.1:30000:3000",
+		"--network=pasta", "--add-host=host.docker.internal:host-gateway",
+		"addt:claude", "--model", "opus"}
+
+	req, err := parseRunArgs(args)
+	if err != nil {
+		t.Fatalf("parseRunArgs() error = %v", err)
+	}
+
+	if req.name != "addt-test" || !req.remove || req.detach || !req.interactive {
+		t.Errorf("unexpected flags: name=%q remove=%v detach=%v interactive=%v", req.name, req.remove, req.detach, req.interactive)
+	}
+	cfg := req.config
+	if !cfg.Tty || !cfg.OpenStdin || !cfg.AttachStdin || !cfg.StdinOnce {
+		t.Errorf("interactive TTY not configured: %+v", cfg)
+	}
+	if cfg.HostConfig.Init == nil || !*cfg.HostConfig.Init {
+		t.Error("--init not translated")
+	}
+	if cfg.Image != "addt:claude" {
+		t.Errorf("Image = %q, want addt:claude", cfg.Image)
+	}
+	// Flags after the image belong to the command
+	if !reflect.DeepEqual(cfg.Cmd, []string{"--model", "opus"}) {
+		t.Errorf("Cmd = %v", cfg.Cmd)
+	}
+	if len(cfg.HostConfig.Binds) != 2 || cfg.HostConfig.Binds[1] != "/home/u/.gitconfig:/home/addt/.gitconfig.host:ro" {
+		t.Errorf("Binds = %v", cfg.HostConfig.Binds)
+	}
+	if !reflect.DeepEqual(cfg.Env, []string{"FOO=bar"}) {
+		t.Errorf("Env = %v", cfg.Env)
+	}
+	if cfg.HostConfig.NetworkMode != "pasta" {
+		t.Errorf("NetworkMode = %q, want pasta", cfg.HostConfig.NetworkMode)
+	}
+	if !reflect.DeepEqual(cfg.HostConfig.ExtraHosts, []string{"host.docker.internal:host-gateway"}) {
+		t.Errorf("ExtraHosts = %v", cfg.HostConfig.ExtraHosts)
+	}
+	want := []PortBinding{{HostIP: "127.0.0.1", HostPort: "30000"}}
+	if !reflect.DeepEqual(cfg.HostConfig.PortBindings["3000/tcp"], want) {
+		t.Errorf("PortBindings = %v", cfg.HostConfig.PortBindings)
+	}
+	if _, ok := cfg.ExposedPorts["3000/tcp"]; !ok {
+		t.Errorf("ExposedPorts = %v", cfg.ExposedPorts)
+	}
+}
+
+func TestParseRunArgs_DetachedKeepAlive(t *testing.T) {
+	args := []string{"run", "--name", "addt-persistent-x", "--user", "root",
+		"-d", "--entrypoint", "sleep", "addt:claude", "infinity"}
+
+	req, err := parseRunArgs(args)
+	if err != nil {
+		t.Fatalf("parseRunArgs() error = %v", err)
+	}
+	if !req.detach || req.remove {
+		t.Errorf("detach=%v remove=%v, want detached persistent container", req.detach, req.remove)
+	}
+	if req.config.AttachStdout || req.config.AttachStdin {
+		t.Error("detached container should not attach stdio")
+	}
+	if req.config.User != "root" {
+		t.Errorf("User = %q, want root", req.config.User)
+	}
+	if !reflect.DeepEqual(req.config.Entrypoint, []string{"sleep"}) || !reflect.DeepEqual(req.config.Cmd, []string{"infinity"}) {
+		t.Errorf("Entrypoint=%v Cmd=%v", req.config.Entrypoint, req.config.Cmd)
+	}
+}
+
+func TestParseRunArgs_SecuritySettings(t *testing.T) {
+	args := []string{"run", "--rm", "--name", "c", "-i",
+		"--pids-limit", "200", "--ulimit", "nofile=4096:8192", "--ulimit", "nproc=256",
+		"--security-opt", "no-new-privileges", "--cap-drop", "ALL", "--cap-add", "NET_ADMIN",
+		"--read-only", "--tmpfs", "/tmp:rw,noexec,nosuid,size=256m", "--tmpfs", "/run/secrets:size=1m,mode=0777",
+		"--ipc", "none", "--userns", "keep-id", "--memory", "2g", "--memory-swap", "-1", "--cpus", "1.5",
+		"--privileged", "--group-add", "999", "--device", "/dev/fuse",
+		"img"}
+
+	req, err := parseRunArgs(args)
+	if err != nil {
+		t.Fatalf("parseRunArgs() error = %v", err)
+	}
+	h := req.config.HostConfig
+
+	if h.PidsLimit == nil || *h.PidsLimit != 200 {
+		t.Errorf("PidsLimit = %v", h.PidsLimit)
+	}
+	wantUlimits := []Ulimit{{Name: "nofile", Soft: 4096, Hard: 8192}, {Name: "nproc", Soft: 256, Hard: 256}}
+	if !reflect.DeepEqual(h.Ulimits, wantUlimits) {
+		t.Errorf("Ulimits = %v", h.Ulimits)
+	}
+	if !reflect.DeepEqual(h.SecurityOpt, []string{"no-new-privileges"}) || !reflect.DeepEqual(h.CapDrop, []string{"ALL"}) || !reflect.DeepEqual(h.CapAdd, []string{"NET_ADMIN"}) {
+		t.Errorf("SecurityOpt=%v CapDrop=%v CapAdd=%v", h.SecurityOpt, h.CapDrop, h.CapAdd)
+	}
+	if !h.ReadonlyRootfs || !h.Privileged {
+		t.Error("--read-only/--privileged not translated")
+	}
+	if h.Tmpfs["/tmp"] != "rw,noexec,nosuid,size=256m" || h.Tmpfs["/run/secrets"] != "size=1m,mode=0777" {
+		t.Errorf("Tmpfs = %v", h.Tmpfs)
+	}
+	if h.IpcMode != "none" || h.UsernsMode != "keep-id" {
+		t.Errorf("IpcMode=%q UsernsMode=%q", h.IpcMode, h.UsernsMode)
+	}
+	if h.Memory != 2<<30 || h.MemorySwap != -1 || h.NanoCPUs != 1500000000 {
+		t.Errorf("Memory=%d MemorySwap=%d NanoCPUs=%d", h.Memory, h.MemorySwap, h.NanoCPUs)
+	}
+	if !reflect.DeepEqual(h.GroupAdd, []string{"999"}) {
+		t.Errorf("GroupAdd = %v", h.GroupAdd)
+	}
+	wantDev := DeviceMapping{PathOnHost: "/dev/fuse", PathInContainer: "/dev/fuse", CgroupPermissions: "rwm"}
+	if len(h.Devices) != 1 || h.Devices[0] != wantDev {
+		t.Errorf("Devices = %v", h.Devices)
+	}
+	if !req.interactive || req.config.Tty {
+		t.Error("-i should open stdin without a TTY")
+	}
+}
+
+func TestParseRunArgs_Errors(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"not run", []string{"exec", "c", "ls"}, "not a run command"},
+		{"no image", []string{"run", "--rm"}, "no image"},
+		{"missing value", []string{"run", "--name"}, "needs a value"},
+		{"unknown flag", []string{"run", "--gpus", "all", "img"}, "unsupported run flag"},
+		{"bad memory", []string{"run", "--memory", "lots", "img"}, "invalid --memory"},
+		{"bad port", []string{"run", "-p", "a:b:c:d", "img"}, "invalid port mapping"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseRunArgs(tc.args)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("parseRunArgs(%v) error = %v, want %q", tc.args, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseExecArgs(t *testing.T) {
+	args := []string{"exec", "--user", "root", "-it", "-e", "ADDT_COMMAND=/bin/bash",
+		"addt-persistent-x", "/usr/local/bin/docker-entrypoint.sh", "--continue"}
+
+	req, err := parseExecArgs(args)
+	if err != nil {
+		t.Fatalf("parseExecArgs() error = %v", err)
+	}
+	if req.container != "addt-persistent-x" {
+		t.Errorf("container = %q", req.container)
+	}
+	cfg := req.config
+	if cfg.User != "root" || !cfg.Tty || !cfg.AttachStdin || !cfg.AttachStdout {
+		t.Errorf("unexpected exec config: %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.Env, []string{"ADDT_COMMAND=/bin/bash"}) {
+		t.Errorf("Env = %v", cfg.Env)
+	}
+	if !reflect.DeepEqual(cfg.Cmd, []string{"/usr/local/bin/docker-entrypoint.sh", "--continue"}) {
+		t.Errorf("Cmd = %v", cfg.Cmd)
+	}
+
+	if _, err := parseExecArgs([]string{"exec", "-it", "container-only"}); err == nil {
+		t.Error("exec without a command should fail")
+	}
+}
+
+func TestEnvValue_FromHost(t *testing.T) {
+	t.Setenv("ADDT_ENGINE_TEST_VAR", "hello")
+
+	if got, ok := envValue("ADDT_ENGINE_TEST_VAR"); !ok || got != "ADDT_ENGINE_TEST_VAR=hello" {
+		t.Errorf("envValue() = %q, %v", got, ok)
+	}
+	if _, ok := envValue("ADDT_ENGINE_TEST_UNSET_VAR"); ok {
+		t.Error("unset bare variable should be skipped")
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	testCases := map[string]int64{
+		"1024": 1024,
+		"512m": 512 << 20,
+		"2g":   2 << 30,
+		"4gb":  4 << 30,
+		"1.5g": 3 << 29,
+		"-1":   -1,
+	}
+	for in, want := range testCases {
+		got, err := parseBytes(in)
+		if err != nil || got != want {
+			t.Errorf("parseBytes(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,522 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/provider/ocicli"
+	"github.com/jedi4ever/addt/util"
+)
+
+// stopTimeout is the grace period (seconds) before a stopped container is killed
+const stopTimeout = 10
+
+// apiBackend implements ocicli.Backend on top of the Engine API
+type apiBackend struct {
+	client *Client
+	logger *util.ModuleLogger
+}
+
+// ExitError reports a non-zero exit status of a container or exec process
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the process exit status, like *exec.ExitError
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+func newAPIBackend(client *Client) *apiBackend {
+	return &apiBackend{client: client, logger: util.Log("engine")}
+}
+
+func (b *apiBackend) ImageExists(image string) bool {
+	_, err := b.client.ImageInspect(context.Background(), image)
+	return err == nil
+}
+
+func (b *apiBackend) ImageLabel(image, label string) string {
+	img, err := b.client.ImageInspect(context.Background(), image)
+	if err != nil {
+		return ""
+	}
+	return img.Config.Labels[label]
+}
+
+func (b *apiBackend) FindImageByLabel(label, value string) string {
+	images, err := b.client.ImageList(context.Background(), label+"="+value)
+	if err != nil {
+		return ""
+	}
+	for _, img := range images {
+		for _, tag := range img.RepoTags {
+			if tag != "" && !strings.Contains(tag, "<none>") {
+				return tag
+			}
+		}
+	}
+	return ""
+}
+
+func (b *apiBackend) ListImages(prefix string) ([]ocicli.ImageSummary, error) {
+	images, err := b.client.ImageList(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	var matches []ocicli.ImageSummary
+	for _, img := range images {
+		for _, ref := range img.RepoTags {
+			ref = strings.TrimPrefix(ref, "localhost/") // Podman's local images
+			if strings.HasPrefix(ref, prefix) {
+				matches = append(matches, ocicli.ImageSummary{Ref: ref, Created: time.Unix(img.Created, 0), Labels: img.Labels})
+			}
+		}
+	}
+	return matches, nil
+}
+
+func (b *apiBackend) RemoveImage(image string) error {
+	return b.client.ImageRemove(context.Background(), image)
+}
+
+func (b *apiBackend) TagImage(source, target string) error {
+	return b.client.ImageTag(context.Background(), source, target)
+}
+
+func (b *apiBackend) BuildImage(req ocicli.BuildRequest) error {
+	dockerfile, err := buildContextDockerfile(req.ContextDir, req.Dockerfile)
+	if err != nil {
+		return err
+	}
+	opts := BuildOptions{
+		Tag:        req.Tag,
+		Dockerfile: dockerfile,
+		BuildArgs:  map[string]string{},
+		NoCache:    req.NoCache,
+	}
+	for _, arg := range req.BuildArgs {
+		k, v, _ := strings.Cut(arg, "=")
+		opts.BuildArgs[k] = v
+	}
+
+	build := func(out io.Writer) error {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(tarDirectory(req.ContextDir, pw))
+		}()
+		defer pr.Close()
+		return b.client.ImageBuild(context.Background(), pr, opts, out)
+	}
+
+	if req.Quiet {
+		return build(io.Discard)
+	}
+	return util.RunBuildFunc(build)
+}
+
+func (b *apiBackend) RunOutput(image, entrypoint string, args ...string) ([]byte, error) {
+	ctx := context.Background()
+	id, err := b.client.ContainerCreate(ctx, "", &ContainerConfig{
+		Image:        image,
+		Entrypoint:   []string{entrypoint},
+		Cmd:          args,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer b.client.ContainerRemove(ctx, id, true)
+
+	if err := b.client.ContainerStart(ctx, id); err != nil {
+		return nil, err
+	}
+	code, err := b.client.ContainerWait(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only stdout is returned, matching `run` + cmd.Output()
+	var stdout bytes.Buffer
+	if err := b.client.ContainerLogs(ctx, id, false, false, &stdout, io.Discard); err != nil {
+		return nil, err
+	}
+	if code != 0 {
+		return stdout.Bytes(), &ExitError{Code: code}
+	}
+	return stdout.Bytes(), nil
+}
+
+func (b *apiBackend) ContainerExists(name string) bool {
+	_, err := b.client.ContainerInspect(context.Background(), name)
+	return err == nil
+}
+
+func (b *apiBackend) ContainerRunning(name string) bool {
+	info, err := b.client.ContainerInspect(context.Background(), name)
+	return err == nil && info.State.Running
+}
+
+func (b *apiBackend) StartContainer(name string) error {
+	return b.client.ContainerStart(context.Background(), name)
+}
+
+func (b *apiBackend) StopContainer(name string) error {
+	return b.client.ContainerStop(context.Background(), name, stopTimeout)
+}
+
+func (b *apiBackend) RemoveContainer(name string) error {
+	err := b.client.ContainerRemove(context.Background(), name, true)
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (b *apiBackend) ListContainers(prefix string) ([]provider.Environment, error) {
+	containers, err := b.client.ContainerList(context.Background(), true, "^/?"+prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var envs []provider.Environment
+	for _, c := range containers {
+		if len(c.Names) == 0 {
+			continue
+		}
+		name := strings.TrimPrefix(c.Names[0], "/")
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		envs = append(envs, provider.Environment{
+			Name:      name,
+			Status:    c.Status,
+			CreatedAt: time.Unix(c.Created, 0).Format("2006-01-02 15:04:05 -0700 MST"),
+		})
+	}
+	return envs, nil
+}
+
+func (b *apiBackend) ContainerLogs(name string) ([]byte, error) {
+	ctx := context.Background()
+	info, err := b.client.ContainerInspect(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	var output bytes.Buffer
+	err = b.client.ContainerLogs(ctx, name, info.Config.Tty, false, &output, &output)
+	return output.Bytes(), err
+}
+
+func (b *apiBackend) StreamLogs(name string, follow bool, out io.Writer) error {
+	ctx := context.Background()
+	info, err := b.client.ContainerInspect(ctx, name)
+	if err != nil {
+		return err
+	}
+	return b.client.ContainerLogs(ctx, name, info.Config.Tty, follow, out, out)
+}
+
+func (b *apiBackend) InspectContainer(name string) (*provider.EnvironmentInfo, error) {
+	info, err := b.client.ContainerInspect(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+	return info.environmentInfo(), nil
+}
+
+// environmentInfo converts the inspect response into an EnvironmentInfo
+func (c *ContainerInspect) environmentInfo() *provider.EnvironmentInfo {
+	name := strings.TrimPrefix(c.Name, "/")
+	info := &provider.EnvironmentInfo{
+		Name:       name,
+		ID:         c.ID,
+		Image:      c.Config.Image,
+		Status:     c.State.Status,
+		Running:    c.State.Running,
+		ExitCode:   c.State.ExitCode,
+		Persistent: ocicli.IsPersistentContainer(name),
+		CreatedAt:  apiTime(c.Created),
+		StartedAt:  apiTime(c.State.StartedAt),
+		Labels:     c.Config.Labels,
+	}
+	for _, m := range c.Mounts {
+		info.Mounts = append(info.Mounts, provider.VolumeMount{Source: m.Source, Target: m.Destination, ReadOnly: !m.RW})
+	}
+	for spec, bindings := range c.NetworkSettings.Ports {
+		port, _, _ := strings.Cut(spec, "/")
+		containerPort, err := strconv.Atoi(port)
+		if err != nil || len(bindings) == 0 {
+			continue
+		}
+		if hostPort, err := strconv.Atoi(bindings[0].HostPort); err == nil {
+			info.Ports = append(info.Ports, provider.PortMapping{Container: containerPort, Host: hostPort})
+		}
+	}
+	sort.Slice(info.Ports, func(i, j int) bool { return info.Ports[i].Container < info.Ports[j].Container })
+	return info
+}
+
+// apiTime parses an API timestamp; never-set times ("0001-01-01T00:00:00Z")
+// become the zero time
+func apiTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil || t.Year() <= 1 {
+		return time.Time{}
+	}
+	return t
+}
+
+func (b *apiBackend) ExecInput(name string, input []byte, cmd ...string) error {
+	ctx := context.Background()
+	execID, err := b.client.ExecCreate(ctx, name, &ExecConfig{
+		Cmd:          cmd,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	conn, reader, err := b.client.ExecStart(ctx, execID, false)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write(input); err != nil {
+		return fmt.Errorf("exec write: %w", err)
+	}
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		cw.CloseWrite()
+	}
+
+	var output bytes.Buffer
+	demux(reader, &output, &output)
+
+	info, err := b.client.ExecInspect(ctx, execID)
+	if err != nil {
+		return err
+	}
+	if info.ExitCode != 0 {
+		return fmt.Errorf("exec %v: %w\n%s", cmd, &ExitError{Code: info.ExitCode}, output.String())
+	}
+	return nil
+}
+
+func (b *apiBackend) CopyToContainer(name, dest string, data []byte) error {
+	archive := tarFile(path.Base(dest), data, 0644)
+	return b.client.PutArchive(context.Background(), name, path.Dir(dest), archive)
+}
+
+func (b *apiBackend) CopyIn(name, hostPath, containerPath string) error {
+	ctx := context.Background()
+	// Like docker cp: into an existing directory, otherwise as the path itself
+	dir, base := path.Dir(containerPath), path.Base(containerPath)
+	if stat, err := b.client.ContainerStatPath(ctx, name, containerPath); err == nil && stat.IsDir() {
+		dir, base = containerPath, filepath.Base(hostPath)
+	} else if err != nil && !IsNotFound(err) {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(util.TarPath(hostPath, base, pw))
+	}()
+	defer pr.Close()
+	return b.client.PutArchive(ctx, name, dir, pr)
+}
+
+func (b *apiBackend) CopyOut(name, containerPath, hostPath string) error {
+	archive, err := b.client.GetArchive(context.Background(), name, containerPath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	return util.UntarPath(archive, hostPath)
+}
+
+func (b *apiBackend) CommitContainer(name, image string, labels map[string]string) error {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var changes []string
+	for _, key := range keys {
+		changes = append(changes, fmt.Sprintf("LABEL %s=%q", key, labels[key]))
+	}
+	_, err := b.client.ContainerCommit(context.Background(), name, image, changes)
+	return err
+}
+
+// CheckpointContainer is not supported: the Engine API has no checkpoint
+// images (and Podman's compatible API no checkpoints at all)
+func (b *apiBackend) CheckpointContainer(name, image string) error {
+	return fmt.Errorf("engine checkpoint: %w", provider.ErrNotSupported)
+}
+
+func (b *apiBackend) RestoreCheckpoint(name, image string) error {
+	return fmt.Errorf("engine restore: %w", provider.ErrNotSupported)
+}
+
+func (b *apiBackend) RunDetached(args []string) error {
+	req, err := parseRunArgs(args)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	id, err := b.client.ContainerCreate(ctx, req.name, &req.config)
+	if err != nil {
+		return err
+	}
+	if err := b.client.ContainerStart(ctx, id); err != nil {
+		b.client.ContainerRemove(ctx, id, true)
+		return err
+	}
+	return nil
+}
+
+func (b *apiBackend) Execute(args []string) error {
+	b.logger.Debugf("Executing via Engine API: %v", args)
+	if len(args) == 0 {
+		return fmt.Errorf("empty command")
+	}
+	switch args[0] {
+	case "run":
+		return b.run(args)
+	case "exec":
+		return b.exec(args)
+	default:
+		return fmt.Errorf("unsupported command %q", args[0])
+	}
+}
+
+// run creates a container, attaches the terminal, starts it and waits for
+// it to exit, removing it afterwards for --rm
+func (b *apiBackend) run(args []string) error {
+	req, err := parseRunArgs(args)
+	if err != nil {
+		return err
+	}
+	if req.detach {
+		return b.RunDetached(args)
+	}
+
+	ctx := context.Background()
+	id, err := b.client.ContainerCreate(ctx, req.name, &req.config)
+	if err != nil {
+		return err
+	}
+	if req.remove {
+		defer b.client.ContainerRemove(ctx, id, true)
+	}
+
+	// Attach before starting so no early output is lost
+	conn, reader, err := b.client.ContainerAttach(ctx, id, req.interactive)
+	if err != nil {
+		return err
+	}
+	session := terminalSession(conn, reader, req.config.Tty, req.interactive,
+		func(w, h int) { b.client.ContainerResize(ctx, id, w, h) })
+
+	if err := b.client.ContainerStart(ctx, id); err != nil {
+		conn.Close()
+		return err
+	}
+	if err := session.run(); err != nil {
+		b.logger.Debugf("Attach stream ended with error: %v", err)
+	}
+
+	code, err := b.client.ContainerWait(ctx, id)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
+}
+
+// exec runs a command in a running container attached to the terminal
+func (b *apiBackend) exec(args []string) error {
+	req, err := parseExecArgs(args)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	execID, err := b.client.ExecCreate(ctx, req.container, &req.config)
+	if err != nil {
+		return err
+	}
+	conn, reader, err := b.client.ExecStart(ctx, execID, req.config.Tty)
+	if err != nil {
+		return err
+	}
+	session := terminalSession(conn, reader, req.config.Tty, req.config.AttachStdin,
+		func(w, h int) { b.client.ExecResize(ctx, execID, w, h) })
+	return b.runExec(ctx, execID, session)
+}
+
+// runExec relays an exec session and returns its exit status
+func (b *apiBackend) runExec(ctx context.Context, execID string, session *streamSession) error {
+	if err := session.run(); err != nil {
+		b.logger.Debugf("Exec stream ended with error: %v", err)
+	}
+
+	info, err := b.client.ExecInspect(ctx, execID)
+	if err != nil {
+		return err
+	}
+	if info.ExitCode != 0 {
+		return &ExitError{Code: info.ExitCode}
+	}
+	return nil
+}
+
+func (b *apiBackend) Exec(name string, cmd []string, tty bool, stdio provider.Stdio) error {
+	ctx := context.Background()
+	execID, err := b.client.ExecCreate(ctx, name, &ExecConfig{
+		Cmd:          cmd,
+		Tty:          tty,
+		AttachStdin:  stdio.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return err
+	}
+	conn, reader, err := b.client.ExecStart(ctx, execID, tty)
+	if err != nil {
+		return err
+	}
+	session := &streamSession{
+		conn:   conn,
+		reader: reader,
+		tty:    tty,
+		stdin:  stdio.Stdin,
+		stdout: stdio.Stdout,
+		stderr: stdio.Stderr,
+		resize: func(w, h int) { b.client.ExecResize(ctx, execID, w, h) },
+	}
+	return b.runExec(ctx, execID, session)
+}
+
+// compile-time check
+var _ ocicli.Backend = (*apiBackend)(nil)
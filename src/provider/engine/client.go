@@ -0,0 +1,220 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// apiVersion is the Engine API version requested. 1.41 (Docker 20.10) is
+// also served by Podman's docker-compatible socket.
+const apiVersion = "v1.41"
+
+// Client talks to a Docker Engine API endpoint over a unix socket
+type Client struct {
+	socket string
+	http   *http.Client
+}
+
+// NewClient creates a client for the Engine API listening on socket
+func NewClient(socket string) *Client {
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", socket)
+	}
+	return &Client{
+		socket: socket,
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext:     dial,
+				IdleConnTimeout: 30 * time.Second,
+			},
+		},
+	}
+}
+
+// Socket returns the unix socket path the client connects to
+func (c *Client) Socket() string {
+	return c.socket
+}
+
+// APIError is a non-2xx response from the Engine API
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("engine API %s %s: %d %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is an Engine API 404
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsConflict reports whether err is an Engine API 409 (e.g. name in use)
+func IsConflict(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict
+}
+
+// request describes one Engine API call
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	body        io.Reader
+	contentType string
+	headers     map[string]string
+}
+
+// url returns the request URL. The host part is ignored by the unix dialer.
+func (r *request) url() string {
+	u := "http://engine/" + apiVersion + r.path
+	if len(r.query) > 0 {
+		u += "?" + r.query.Encode()
+	}
+	return u
+}
+
+// do performs req and returns the response, converting non-2xx statuses
+// into *APIError. The caller must close the response body.
+func (c *Client) do(ctx context.Context, req *request) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, req.url(), req.body)
+	if err != nil {
+		return nil, err
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	for k, v := range req.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("engine API %s %s: %w", req.method, req.path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, responseError(req, resp)
+	}
+	return resp, nil
+}
+
+// responseError builds an *APIError from an error response body
+// ({"message": "..."} on both Docker and Podman)
+func responseError(req *request, resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var msg struct {
+		Message string `json:"message"`
+	}
+	message := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &msg) == nil && msg.Message != "" {
+		message = msg.Message
+	}
+	return &APIError{
+		Method:     req.method,
+		Path:       req.path,
+		StatusCode: resp.StatusCode,
+		Message:    message,
+	}
+}
+
+// doJSON performs req with an optional JSON body and decodes the JSON
+// response into out (if non-nil)
+func (c *Client) doJSON(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	req := &request{method: method, path: path, query: query}
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		req.body = bytes.NewReader(data)
+		req.contentType = "application/json"
+	}
+
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// hijack performs req on a dedicated connection and, once the daemon
+// accepts it, hands the raw connection to the caller for bidirectional
+// streaming (attach and exec start). Returned reader must be used for
+// reads since it may hold buffered bytes.
+func (c *Client) hijack(ctx context.Context, req *request) (net.Conn, *bufio.Reader, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", c.socket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("engine API %s %s: %w", req.method, req.path, err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, req.url(), req.body)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	httpReq.Header.Set("Connection", "Upgrade")
+	httpReq.Header.Set("Upgrade", "tcp")
+
+	if err := httpReq.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("engine API %s %s: %w", req.method, req.path, err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, httpReq)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("engine API %s %s: %w", req.method, req.path, err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols && resp.StatusCode != http.StatusOK {
+		defer conn.Close()
+		return nil, nil, responseError(req, resp)
+	}
+	return conn, br, nil
+}
+
+// Ping checks that the daemon is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := c.do(ctx, &request{method: http.MethodGet, path: "/_ping"})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Version returns the daemon's version information
+func (c *Client) Version(ctx context.Context) (*Version, error) {
+	var v Version
+	if err := c.doJSON(ctx, http.MethodGet, "/version", nil, nil, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
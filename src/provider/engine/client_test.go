@@ -0,0 +1,319 @@
+package engine
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeDaemon serves handler on a unix socket and returns a client for it
+func fakeDaemon(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	// unix socket paths are limited to ~104 bytes, so avoid t.TempDir()
+	dir, err := os.MkdirTemp("", "addt-engine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "engine.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: handler}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	return NewClient(socket)
+}
+
+// frame encodes payload as one multiplexed stream frame
+func frame(stream byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+func TestClient_NotFoundIsStructured(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.41/images/missing:latest/json", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"No such image: missing:latest"}`)
+	})
+	client := fakeDaemon(t, mux)
+
+	_, err := client.ImageInspect(context.Background(), "missing:latest")
+	if !IsNotFound(err) {
+		t.Fatalf("ImageInspect() error = %v, want 404", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "No such image: missing:latest" {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+	if IsConflict(err) {
+		t.Error("404 reported as conflict")
+	}
+}
+
+func TestAPIBackend_ImageLabel(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.41/images/addt:claude/json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Id":"sha256:abc","RepoTags":["addt:claude"],"Config":{"Labels":{"tools.node.version":"22.1.0"}}}`)
+	})
+	b := newAPIBackend(fakeDaemon(t, mux))
+
+	if !b.ImageExists("addt:claude") {
+		t.Error("ImageExists() = false")
+	}
+	if got := b.ImageLabel("addt:claude", "tools.node.version"); got != "22.1.0" {
+		t.Errorf("ImageLabel() = %q, want 22.1.0", got)
+	}
+	if b.ImageExists("addt:other") {
+		t.Error("ImageExists() = true for unknown image")
+	}
+}
+
+func TestAPIBackend_FindImageByLabel(t *testing.T) {
+	var filters string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.41/images/json", func(w http.ResponseWriter, r *http.Request) {
+		filters = r.URL.Query().Get("filters")
+		fmt.Fprint(w, `[{"Id":"sha256:1","RepoTags":["<none>:<none>"]},{"Id":"sha256:2","RepoTags":["addt:claude-2.0"]}]`)
+	})
+	b := newAPIBackend(fakeDaemon(t, mux))
+
+	if got := b.FindImageByLabel("tools.claude.version", "2.0"); got != "addt:claude-2.0" {
+		t.Errorf("FindImageByLabel() = %q", got)
+	}
+	if filters != `{"label":["tools.claude.version=2.0"]}` {
+		t.Errorf("filters = %s", filters)
+	}
+}
+
+func TestClient_ImageTagSplitsRepository(t *testing.T) {
+	var repo, tag string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.41/images/addt:base/tag", func(w http.ResponseWriter, r *http.Request) {
+		repo, tag = r.URL.Query().Get("repo"), r.URL.Query().Get("tag")
+		w.WriteHeader(http.StatusCreated)
+	})
+	client := fakeDaemon(t, mux)
+
+	if err := client.ImageTag(context.Background(), "addt:base", "localhost:5000/addt:claude"); err != nil {
+		t.Fatal(err)
+	}
+	if repo != "localhost:5000/addt" || tag != "claude" {
+		t.Errorf("repo=%q tag=%q", repo, tag)
+	}
+}
+
+func TestClient_ImageBuildReportsStreamError(t *testing.T) {
+	var query string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.41/build", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		io.Copy(io.Discard, r.Body)
+		fmt.Fprintln(w, `{"stream":"Step 1/2 : FROM debian\n"}`)
+		fmt.Fprintln(w, `{"errorDetail":{"message":"RUN exited with 1"},"error":"RUN exited with 1"}`)
+	})
+	client := fakeDaemon(t, mux)
+
+	var out bytes.Buffer
+	err := client.ImageBuild(context.Background(), strings.NewReader(""), BuildOptions{Tag: "addt:test", NoCache: true}, &out)
+
+	var buildErr *BuildError
+	if !errors.As(err, &buildErr) || buildErr.Message != "RUN exited with 1" {
+		t.Fatalf("ImageBuild() error = %v, want BuildError", err)
+	}
+	if out.String() != "Step 1/2 : FROM debian\n" {
+		t.Errorf("build output = %q", out.String())
+	}
+	if !strings.Contains(query, "t=addt%3Atest") || !strings.Contains(query, "nocache=1") {
+		t.Errorf("build query = %s", query)
+	}
+}
+
+func TestAPIBackend_ListContainers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.41/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("all") != "true" {
+			t.Errorf("expected all=true, got %s", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `[
+			{"Id":"1","Names":["/addt-persistent-a"],"Status":"Up 2 minutes","Created":1700000000},
+			{"Id":"2","Names":["/other-addt-persistent-b"],"Status":"Exited (0)","Created":1700000000}
+		]`)
+	})
+	b := newAPIBackend(fakeDaemon(t, mux))
+
+	envs, err := b.ListContainers("addt-persistent-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(envs) != 1 || envs[0].Name != "addt-persistent-a" || envs[0].Status != "Up 2 minutes" {
+		t.Errorf("ListContainers() = %+v", envs)
+	}
+}
+
+func TestAPIBackend_ExecInputStreamsStdin(t *testing.T) {
+	var received string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.41/containers/c1/exec", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"Id":"exec1"}`)
+	})
+	mux.HandleFunc("/v1.41/exec/exec1/start", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body) // start options, not stdin
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(buf, "HTTP/1.1 101 UPGRADED\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		buf.Flush()
+
+		data, _ := io.ReadAll(buf)
+		received = string(data)
+		conn.Write(frame(2, "permission denied\n"))
+	})
+	mux.HandleFunc("/v1.41/exec/exec1/json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Running":false,"ExitCode":1}`)
+	})
+	b := newAPIBackend(fakeDaemon(t, mux))
+
+	err := b.ExecInput("c1", []byte(`{"KEY":"value"}`), "sh", "-c", "cat > /run/secrets/.secrets")
+
+	if received != `{"KEY":"value"}` {
+		t.Errorf("daemon received %q", received)
+	}
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Fatalf("ExecInput() error = %v, want exit status 1", err)
+	}
+	if !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("error should include exec output: %v", err)
+	}
+}
+
+func TestAPIBackend_RemoveMissingContainer(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.41/containers/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"No such container: gone"}`)
+	})
+	b := newAPIBackend(fakeDaemon(t, mux))
+
+	if err := b.RemoveContainer("gone"); err != nil {
+		t.Errorf("RemoveContainer() error = %v, want nil for missing container", err)
+	}
+}
+
+func TestDemux(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(frame(1, "hello "))
+	stream.Write(frame(2, "oops\n"))
+	stream.Write(frame(1, "world\n"))
+
+	var stdout, stderr bytes.Buffer
+	if err := demux(&stream, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "hello world\n" || stderr.String() != "oops\n" {
+		t.Errorf("stdout=%q stderr=%q", stdout.String(), stderr.String())
+	}
+
+	truncated := bytes.NewReader(frame(1, "cut off")[:10])
+	if err := demux(truncated, io.Discard, io.Discard); err == nil {
+		t.Error("demux() should fail on a truncated frame")
+	}
+}
+
+func TestBuildContextDockerfile(t *testing.T) {
+	if got, err := buildContextDockerfile("/tmp/ctx", "/tmp/ctx/sub/Dockerfile"); err != nil || got != "sub/Dockerfile" {
+		t.Errorf("buildContextDockerfile() = %q, %v", got, err)
+	}
+	if _, err := buildContextDockerfile("/tmp/ctx", "/tmp/Dockerfile"); err == nil {
+		t.Error("Dockerfile outside the context should be rejected")
+	}
+}
+
+func TestAPIBackend_CopyInFollowsDockerCp(t *testing.T) {
+	var puts []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.41/containers/addt-x/archive", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodHead:
+			if r.URL.Query().Get("path") != "/workspace" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			stat := fmt.Sprintf(`{"name":"workspace","size":4096,"mode":%d}`, uint32(os.ModeDir|0755))
+			w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString([]byte(stat)))
+		case http.MethodPut:
+			tr := tar.NewReader(r.Body)
+			hdr, err := tr.Next()
+			if err != nil {
+				t.Errorf("PUT archive: %v", err)
+				return
+			}
+			puts = append(puts, r.URL.Query().Get("path")+" "+hdr.Name)
+		}
+	})
+	b := newAPIBackend(fakeDaemon(t, mux))
+
+	src := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(src, []byte("hello"), 0644)
+	if err := b.CopyIn("addt-x", src, "/workspace"); err != nil {
+		t.Fatalf("CopyIn(dir) error = %v", err)
+	}
+	if err := b.CopyIn("addt-x", src, "/workspace/renamed.txt"); err != nil {
+		t.Fatalf("CopyIn(new path) error = %v", err)
+	}
+	want := []string{"/workspace notes.txt", "/workspace renamed.txt"}
+	if strings.Join(puts, ",") != strings.Join(want, ",") {
+		t.Errorf("archives = %v, want %v", puts, want)
+	}
+}
+
+func TestAPIBackend_CommitAndListSnapshots(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.41/commit", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("container") != "addt-x" || q.Get("repo") != "addt-snapshot-addt-x" || q.Get("tag") != "t1" {
+			t.Errorf("commit query = %v", q)
+		}
+		if got := strings.Join(q["changes"], ","); got != `LABEL a="1",LABEL b="2"` {
+			t.Errorf("changes = %s", got)
+		}
+		fmt.Fprint(w, `{"Id":"sha256:abc"}`)
+	})
+	mux.HandleFunc("/v1.41/images/json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"Id":"sha256:abc","RepoTags":["localhost/addt-snapshot-addt-x:t1","addt:claude"],"Created":1767348000,"Labels":{"a":"1"}}]`)
+	})
+	b := newAPIBackend(fakeDaemon(t, mux))
+
+	if err := b.CommitContainer("addt-x", "addt-snapshot-addt-x:t1", map[string]string{"b": "2", "a": "1"}); err != nil {
+		t.Fatalf("CommitContainer() error = %v", err)
+	}
+	images, err := b.ListImages("addt-snapshot-")
+	if err != nil {
+		t.Fatalf("ListImages() error = %v", err)
+	}
+	if len(images) != 1 || images[0].Ref != "addt-snapshot-addt-x:t1" || images[0].Created.Unix() != 1767348000 {
+		t.Errorf("images = %+v", images)
+	}
+}
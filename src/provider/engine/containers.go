@@ -0,0 +1,237 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ContainerCreate creates a container named name and returns its ID
+func (c *Client) ContainerCreate(ctx context.Context, name string, cfg *ContainerConfig) (string, error) {
+	var query url.Values
+	if name != "" {
+		query = url.Values{"name": {name}}
+	}
+	var resp idResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/containers/create", query, cfg, &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// ContainerStart starts a created or stopped container
+func (c *Client) ContainerStart(ctx context.Context, id string) error {
+	err := c.doJSON(ctx, http.MethodPost, "/containers/"+id+"/start", nil, nil, nil)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotModified {
+		return nil // already running
+	}
+	return err
+}
+
+// ContainerStop stops a running container, killing it after timeout seconds
+func (c *Client) ContainerStop(ctx context.Context, id string, timeout int) error {
+	query := url.Values{"t": {strconv.Itoa(timeout)}}
+	err := c.doJSON(ctx, http.MethodPost, "/containers/"+id+"/stop", query, nil, nil)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotModified {
+		return nil // already stopped
+	}
+	return err
+}
+
+// ContainerRemove removes a container, killing it first if force is set
+func (c *Client) ContainerRemove(ctx context.Context, id string, force bool) error {
+	query := url.Values{"force": {strconv.FormatBool(force)}}
+	return c.doJSON(ctx, http.MethodDelete, "/containers/"+id, query, nil, nil)
+}
+
+// ContainerInspect returns low-level information about a container
+func (c *Client) ContainerInspect(ctx context.Context, id string) (*ContainerInspect, error) {
+	var info ContainerInspect
+	if err := c.doJSON(ctx, http.MethodGet, "/containers/"+id+"/json", nil, nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// ContainerList lists containers whose name matches the regular expression
+// nameFilter (all containers when empty). Stopped containers are included
+// when all is set.
+func (c *Client) ContainerList(ctx context.Context, all bool, nameFilter string) ([]ContainerSummary, error) {
+	query := url.Values{"all": {strconv.FormatBool(all)}}
+	if nameFilter != "" {
+		filters, _ := json.Marshal(map[string][]string{"name": {nameFilter}})
+		query.Set("filters", string(filters))
+	}
+	var containers []ContainerSummary
+	if err := c.doJSON(ctx, http.MethodGet, "/containers/json", query, nil, &containers); err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
+
+// ContainerLogs copies the container's output to stdout and stderr,
+// waiting for new output until the container stops when follow is set. tty
+// must match the container's Tty setting, which decides whether the stream
+// is multiplexed; TTY output all goes to stdout.
+func (c *Client) ContainerLogs(ctx context.Context, id string, tty, follow bool, stdout, stderr io.Writer) error {
+	query := url.Values{"stdout": {"1"}, "stderr": {"1"}, "follow": {strconv.FormatBool(follow)}}
+	resp, err := c.do(ctx, &request{method: http.MethodGet, path: "/containers/" + id + "/logs", query: query})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if tty {
+		_, err = io.Copy(stdout, resp.Body)
+		return err
+	}
+	return demux(resp.Body, stdout, stderr)
+}
+
+// ContainerWait blocks until the container stops and returns its exit code
+func (c *Client) ContainerWait(ctx context.Context, id string) (int, error) {
+	var resp waitResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/containers/"+id+"/wait", nil, nil, &resp); err != nil {
+		return -1, err
+	}
+	if resp.Error != nil && resp.Error.Message != "" {
+		return resp.StatusCode, fmt.Errorf("wait %s: %s", id, resp.Error.Message)
+	}
+	return resp.StatusCode, nil
+}
+
+// ContainerAttach attaches to the container's stdio. The returned
+// connection carries stdin writes and (multiplexed unless tty) output.
+func (c *Client) ContainerAttach(ctx context.Context, id string, stdin bool) (net.Conn, *bufio.Reader, error) {
+	query := url.Values{
+		"stream": {"1"},
+		"stdin":  {strconv.FormatBool(stdin)},
+		"stdout": {"1"},
+		"stderr": {"1"},
+	}
+	return c.hijack(ctx, &request{method: http.MethodPost, path: "/containers/" + id + "/attach", query: query})
+}
+
+// ContainerResize resizes the container's TTY
+func (c *Client) ContainerResize(ctx context.Context, id string, width, height int) error {
+	return c.doJSON(ctx, http.MethodPost, "/containers/"+id+"/resize", resizeQuery(width, height), nil, nil)
+}
+
+// PutArchive extracts a tar archive into dir inside the container
+func (c *Client) PutArchive(ctx context.Context, id, dir string, archive io.Reader) error {
+	resp, err := c.do(ctx, &request{
+		method:      http.MethodPut,
+		path:        "/containers/" + id + "/archive",
+		query:       url.Values{"path": {dir}},
+		body:        archive,
+		contentType: "application/x-tar",
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetArchive returns a tar archive of path inside the container, with the
+// path's base name as its top-level entry
+func (c *Client) GetArchive(ctx context.Context, id, path string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, &request{
+		method: http.MethodGet,
+		path:   "/containers/" + id + "/archive",
+		query:  url.Values{"path": {path}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ContainerStatPath describes a path inside the container. A missing path
+// is reported as a not-found APIError.
+func (c *Client) ContainerStatPath(ctx context.Context, id, path string) (*PathStat, error) {
+	resp, err := c.do(ctx, &request{
+		method: http.MethodHead,
+		path:   "/containers/" + id + "/archive",
+		query:  url.Values{"path": {path}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	data, err := base64.StdEncoding.DecodeString(resp.Header.Get("X-Docker-Container-Path-Stat"))
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: invalid path stat header: %w", path, err)
+	}
+	var stat PathStat
+	if err := json.Unmarshal(data, &stat); err != nil {
+		return nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	return &stat, nil
+}
+
+// ContainerCommit saves a container's filesystem as image ("repo:tag"),
+// applying Dockerfile instructions such as LABEL
+func (c *Client) ContainerCommit(ctx context.Context, id, image string, changes []string) (string, error) {
+	repo, tag := splitImageRef(image)
+	query := url.Values{"container": {id}, "repo": {repo}, "tag": {tag}}
+	for _, change := range changes {
+		query.Add("changes", change)
+	}
+	var resp idResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/commit", query, nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// ExecCreate prepares a command to run in a running container
+func (c *Client) ExecCreate(ctx context.Context, id string, cfg *ExecConfig) (string, error) {
+	var resp idResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/containers/"+id+"/exec", nil, cfg, &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// ExecStart starts an exec instance and returns the hijacked connection
+// carrying its stdio
+func (c *Client) ExecStart(ctx context.Context, execID string, tty bool) (net.Conn, *bufio.Reader, error) {
+	body, _ := json.Marshal(map[string]bool{"Detach": false, "Tty": tty})
+	return c.hijack(ctx, &request{
+		method:      http.MethodPost,
+		path:        "/exec/" + execID + "/start",
+		body:        bytes.NewReader(body),
+		contentType: "application/json",
+	})
+}
+
+// ExecInspect returns the state of an exec instance
+func (c *Client) ExecInspect(ctx context.Context, execID string) (*ExecInspect, error) {
+	var info ExecInspect
+	if err := c.doJSON(ctx, http.MethodGet, "/exec/"+execID+"/json", nil, nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// ExecResize resizes an exec instance's TTY
+func (c *Client) ExecResize(ctx context.Context, execID string, width, height int) error {
+	return c.doJSON(ctx, http.MethodPost, "/exec/"+execID+"/resize", resizeQuery(width, height), nil, nil)
+}
+
+func resizeQuery(width, height int) url.Values {
+	return url.Values{"w": {strconv.Itoa(width)}, "h": {strconv.Itoa(height)}}
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"time"
+
+	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/provider/ocicli"
+)
+
+// Assets holds the embedded build files for one image flavour
+type Assets struct {
+	Dockerfile     []byte
+	DockerfileBase []byte
+	Entrypoint     []byte
+	InitFirewall   []byte
+	InstallSh      []byte
+}
+
+// NewEngineProvider creates a provider that talks to the Docker Engine API
+// on socket instead of shelling out to a CLI. Podman's docker-compatible
+// socket is detected from the daemon version and gets Podman's runtime
+// quirks and image assets; any other daemon is treated as Docker.
+func NewEngineProvider(cfg *provider.Config, socket string, dockerAssets, podmanAssets Assets, extensions embed.FS) (provider.Provider, error) {
+	if socket == "" {
+		return nil, fmt.Errorf("no Engine API socket found (set DOCKER_HOST=unix:///path/to/docker.sock)")
+	}
+	client := NewClient(socket)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	version, err := client.Version(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Engine API not reachable at %s: %w", socket, err)
+	}
+
+	rt, assets := Runtime(client, false), dockerAssets
+	if version.IsPodman() {
+		rt, assets = Runtime(client, true), podmanAssets
+	}
+
+	return ocicli.NewWithBackend(rt, newAPIBackend(client), cfg,
+		assets.Dockerfile, assets.DockerfileBase, assets.Entrypoint,
+		assets.InitFirewall, assets.InstallSh, extensions), nil
+}
+
+// Runtime returns the runtime descriptor for the Engine API provider. The
+// container-side behaviour follows the daemon behind the socket (Docker or
+// Podman); only the name and prerequisite check differ.
+func Runtime(client *Client, podman bool) ocicli.Runtime {
+	rt := ocicli.DockerRuntime("")
+	if podman {
+		rt = ocicli.PodmanRuntime()
+	}
+	rt.Name = "engine"
+	rt.Context = ""
+	rt.Prerequisites = func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := client.Ping(ctx); err != nil {
+			return fmt.Errorf("Engine API not reachable at %s: %w", client.Socket(), err)
+		}
+		return nil
+	}
+	return rt
+}
@@ -0,0 +1,223 @@
+package engine
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImageInspect returns low-level information about an image
+func (c *Client) ImageInspect(ctx context.Context, name string) (*ImageInspect, error) {
+	var img ImageInspect
+	if err := c.doJSON(ctx, http.MethodGet, "/images/"+name+"/json", nil, nil, &img); err != nil {
+		return nil, err
+	}
+	return &img, nil
+}
+
+// ImageList lists images matching the given label filters ("key=value")
+func (c *Client) ImageList(ctx context.Context, labels ...string) ([]ImageSummary, error) {
+	query := url.Values{}
+	if len(labels) > 0 {
+		filters, _ := json.Marshal(map[string][]string{"label": labels})
+		query.Set("filters", string(filters))
+	}
+	var images []ImageSummary
+	if err := c.doJSON(ctx, http.MethodGet, "/images/json", query, nil, &images); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// ImageRemove removes an image
+func (c *Client) ImageRemove(ctx context.Context, name string) error {
+	return c.doJSON(ctx, http.MethodDelete, "/images/"+name, nil, nil, nil)
+}
+
+// ImageTag tags source as target ("repo:tag")
+func (c *Client) ImageTag(ctx context.Context, source, target string) error {
+	repo, tag := splitImageRef(target)
+	query := url.Values{"repo": {repo}, "tag": {tag}}
+	return c.doJSON(ctx, http.MethodPost, "/images/"+source+"/tag", query, nil, nil)
+}
+
+// splitImageRef splits "repo:tag" (tag defaulting to latest); a colon
+// before the last "/" belongs to a registry port
+func splitImageRef(ref string) (repo, tag string) {
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, "latest"
+}
+
+// BuildOptions configures ImageBuild
+type BuildOptions struct {
+	Tag        string
+	Dockerfile string // path relative to the build context
+	BuildArgs  map[string]string
+	NoCache    bool
+}
+
+// BuildError is a build failure reported in the build progress stream
+type BuildError struct {
+	Message string
+}
+
+func (e *BuildError) Error() string {
+	return "build failed: " + e.Message
+}
+
+// ImageBuild builds an image from a tar build context, writing the build's
+// text output to out
+func (c *Client) ImageBuild(ctx context.Context, buildContext io.Reader, opts BuildOptions, out io.Writer) error {
+	query := url.Values{"rm": {"1"}}
+	if opts.Tag != "" {
+		query.Set("t", opts.Tag)
+	}
+	if opts.Dockerfile != "" {
+		query.Set("dockerfile", opts.Dockerfile)
+	}
+	if len(opts.BuildArgs) > 0 {
+		buildArgs, _ := json.Marshal(opts.BuildArgs)
+		query.Set("buildargs", string(buildArgs))
+	}
+	if opts.NoCache {
+		query.Set("nocache", "1")
+	}
+
+	resp, err := c.do(ctx, &request{
+		method:      http.MethodPost,
+		path:        "/build",
+		query:       query,
+		body:        buildContext,
+		contentType: "application/x-tar",
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return readBuildStream(resp.Body, out)
+}
+
+// readBuildStream relays the JSON progress stream of a build to out and
+// returns the first error message the daemon reports
+func readBuildStream(r io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var msg buildMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			continue
+		}
+		if msg.Error != "" {
+			return &BuildError{Message: msg.Error}
+		}
+		if msg.ErrorDetail != nil && msg.ErrorDetail.Message != "" {
+			return &BuildError{Message: msg.ErrorDetail.Message}
+		}
+		if msg.Stream != "" {
+			io.WriteString(out, msg.Stream)
+		} else if msg.Status != "" {
+			io.WriteString(out, msg.Status+"\n")
+		}
+	}
+	return scanner.Err()
+}
+
+// tarDirectory writes dir as a tar archive to w. Paths are relative to dir.
+func tarDirectory(dir string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// tarFile returns a tar archive holding a single file
+func tarFile(name string, data []byte, mode int64) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     mode,
+			Size:     int64(len(data)),
+			Typeflag: tar.TypeReg,
+		})
+		if err == nil {
+			_, err = tw.Write(data)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// buildContextDockerfile returns the Dockerfile path relative to the build
+// context, or an error if it lies outside the context
+func buildContextDockerfile(contextDir, dockerfile string) (string, error) {
+	rel, err := filepath.Rel(contextDir, dockerfile)
+	if err != nil {
+		return "", err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("dockerfile %s is outside the build context %s", dockerfile, contextDir)
+	}
+	return filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+
+	"github.com/jedi4ever/addt/util/terminal"
+)
+
+// demux splits a multiplexed attach/logs stream (used when the container
+// has no TTY) into stdout and stderr. Each frame is an 8-byte header —
+// stream type in byte 0, big-endian payload size in bytes 4-7 — followed by
+// the payload.
+func demux(r io.Reader, stdout, stderr io.Writer) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		w := stdout
+		if header[0] == 2 {
+			w = stderr
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(w, r, size); err != nil {
+			return err
+		}
+	}
+}
+
+// streamSession wires local stdio to a hijacked attach or exec connection
+type streamSession struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	tty    bool
+	stdin  io.Reader // nil when stdin is not attached
+	stdout io.Writer
+	stderr io.Writer
+	// resize propagates the local terminal size to the remote TTY
+	resize func(width, height int)
+}
+
+// terminalSession returns a session attached to the process's own stdio
+func terminalSession(conn net.Conn, reader *bufio.Reader, tty, stdin bool, resize func(width, height int)) *streamSession {
+	s := &streamSession{conn: conn, reader: reader, tty: tty, stdout: os.Stdout, stderr: os.Stderr, resize: resize}
+	if stdin {
+		s.stdin = os.Stdin
+	}
+	return s
+}
+
+// run relays stdio until the remote side closes its output. With a TTY a
+// local terminal on stdin is switched to raw mode; resizes are forwarded
+// when that terminal is the process's own.
+func (s *streamSession) run() error {
+	defer s.conn.Close()
+
+	if f, ok := s.stdin.(*os.File); ok && s.tty && isRawCandidate(f) {
+		if restore, err := terminal.MakeRaw(int(f.Fd())); err == nil {
+			defer restore()
+		}
+		if s.resize != nil && f == os.Stdin {
+			s.resize(terminal.GetTerminalSize())
+			sigs := make(chan os.Signal, 1)
+			terminal.NotifyResize(sigs)
+			defer func() {
+				signal.Stop(sigs)
+				close(sigs)
+			}()
+			go func() {
+				for range sigs {
+					s.resize(terminal.GetTerminalSize())
+				}
+			}()
+		}
+	}
+
+	if s.stdin != nil {
+		go func() {
+			io.Copy(s.conn, s.stdin)
+			// Signal EOF to the container while keeping its output flowing
+			if cw, ok := s.conn.(interface{ CloseWrite() error }); ok {
+				cw.CloseWrite()
+			}
+		}()
+	}
+
+	var err error
+	if s.tty {
+		_, err = io.Copy(s.stdout, s.reader)
+	} else {
+		err = demux(s.reader, s.stdout, s.stderr)
+	}
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// isRawCandidate reports whether f is a terminal to put in raw mode. The
+// process's own stdin only qualifies when stdout is a terminal as well.
+func isRawCandidate(f *os.File) bool {
+	if f == os.Stdin {
+		return terminal.IsTerminal()
+	}
+	return terminal.IsTerminalFd(int(f.Fd()))
+}
@@ -0,0 +1,202 @@
+package engine
+
+import (
+	"os"
+	"strings"
+)
+
+// Engine API request and response bodies. Only the fields addt uses are
+// modelled; unknown fields are ignored on decode and omitted on encode.
+
+// Version is the response of GET /version
+type Version struct {
+	Version    string      `json:"Version"`
+	APIVersion string      `json:"ApiVersion"`
+	Components []Component `json:"Components"`
+}
+
+// Component is one entry of Version.Components
+type Component struct {
+	Name    string `json:"Name"`
+	Version string `json:"Version"`
+}
+
+// IsPodman reports whether the daemon is Podman's docker-compatible service
+func (v *Version) IsPodman() bool {
+	for _, c := range v.Components {
+		if strings.Contains(strings.ToLower(c.Name), "podman") {
+			return true
+		}
+	}
+	return false
+}
+
+// ImageInspect is the response of GET /images/{name}/json
+type ImageInspect struct {
+	ID       string   `json:"Id"`
+	RepoTags []string `json:"RepoTags"`
+	Config   struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+// ImageSummary is one entry of GET /images/json
+type ImageSummary struct {
+	ID       string            `json:"Id"`
+	RepoTags []string          `json:"RepoTags"`
+	Created  int64             `json:"Created"` // Unix time
+	Labels   map[string]string `json:"Labels"`
+}
+
+// ContainerConfig is the body of POST /containers/create
+type ContainerConfig struct {
+	Image        string              `json:"Image"`
+	Cmd          []string            `json:"Cmd,omitempty"`
+	Entrypoint   []string            `json:"Entrypoint,omitempty"`
+	Env          []string            `json:"Env,omitempty"`
+	WorkingDir   string              `json:"WorkingDir,omitempty"`
+	User         string              `json:"User,omitempty"`
+	Labels       map[string]string   `json:"Labels,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	Tty          bool                `json:"Tty"`
+	OpenStdin    bool                `json:"OpenStdin"`
+	StdinOnce    bool                `json:"StdinOnce"`
+	AttachStdin  bool                `json:"AttachStdin"`
+	AttachStdout bool                `json:"AttachStdout"`
+	AttachStderr bool                `json:"AttachStderr"`
+	HostConfig   HostConfig          `json:"HostConfig"`
+}
+
+// HostConfig is the host-side part of ContainerConfig
+type HostConfig struct {
+	Binds          []string                 `json:"Binds,omitempty"`
+	PortBindings   map[string][]PortBinding `json:"PortBindings,omitempty"`
+	NetworkMode    string                   `json:"NetworkMode,omitempty"`
+	CapAdd         []string                 `json:"CapAdd,omitempty"`
+	CapDrop        []string                 `json:"CapDrop,omitempty"`
+	SecurityOpt    []string                 `json:"SecurityOpt,omitempty"`
+	Privileged     bool                     `json:"Privileged,omitempty"`
+	ReadonlyRootfs bool                     `json:"ReadonlyRootfs,omitempty"`
+	Tmpfs          map[string]string        `json:"Tmpfs,omitempty"`
+	IpcMode        string                   `json:"IpcMode,omitempty"`
+	UsernsMode     string                   `json:"UsernsMode,omitempty"`
+	GroupAdd       []string                 `json:"GroupAdd,omitempty"`
+	ExtraHosts     []string                 `json:"ExtraHosts,omitempty"`
+	Init           *bool                    `json:"Init,omitempty"`
+	PidsLimit      *int64                   `json:"PidsLimit,omitempty"`
+	Ulimits        []Ulimit                 `json:"Ulimits,omitempty"`
+	Memory         int64                    `json:"Memory,omitempty"`
+	MemorySwap     int64                    `json:"MemorySwap,omitempty"`
+	NanoCPUs       int64                    `json:"NanoCpus,omitempty"`
+	Devices        []DeviceMapping          `json:"Devices,omitempty"`
+}
+
+// PortBinding maps a container port to a host address
+type PortBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+// Ulimit is a resource limit (e.g. nofile=1024:2048)
+type Ulimit struct {
+	Name string `json:"Name"`
+	Soft int64  `json:"Soft"`
+	Hard int64  `json:"Hard"`
+}
+
+// DeviceMapping exposes a host device inside the container
+type DeviceMapping struct {
+	PathOnHost        string `json:"PathOnHost"`
+	PathInContainer   string `json:"PathInContainer"`
+	CgroupPermissions string `json:"CgroupPermissions"`
+}
+
+// ContainerInspect is the response of GET /containers/{id}/json
+type ContainerInspect struct {
+	ID      string `json:"Id"`
+	Name    string `json:"Name"`
+	Created string `json:"Created"`
+	State   struct {
+		Status    string `json:"Status"`
+		Running   bool   `json:"Running"`
+		ExitCode  int    `json:"ExitCode"`
+		StartedAt string `json:"StartedAt"`
+	} `json:"State"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+		Tty    bool              `json:"Tty"`
+	} `json:"Config"`
+	Mounts []struct {
+		Source      string `json:"Source"`
+		Destination string `json:"Destination"`
+		RW          bool   `json:"RW"`
+	} `json:"Mounts"`
+	NetworkSettings struct {
+		Ports map[string][]PortBinding `json:"Ports"`
+	} `json:"NetworkSettings"`
+}
+
+// PathStat is the X-Docker-Container-Path-Stat header of HEAD
+// /containers/{id}/archive
+type PathStat struct {
+	Name string      `json:"name"`
+	Size int64       `json:"size"`
+	Mode os.FileMode `json:"mode"`
+}
+
+// IsDir reports whether the path is a directory
+func (s *PathStat) IsDir() bool {
+	return s.Mode.IsDir()
+}
+
+// ContainerSummary is one entry of GET /containers/json
+type ContainerSummary struct {
+	ID      string   `json:"Id"`
+	Names   []string `json:"Names"`
+	Image   string   `json:"Image"`
+	State   string   `json:"State"`
+	Status  string   `json:"Status"`
+	Created int64    `json:"Created"`
+}
+
+// ExecConfig is the body of POST /containers/{id}/exec
+type ExecConfig struct {
+	User         string   `json:"User,omitempty"`
+	Env          []string `json:"Env,omitempty"`
+	Cmd          []string `json:"Cmd"`
+	WorkingDir   string   `json:"WorkingDir,omitempty"`
+	Tty          bool     `json:"Tty"`
+	AttachStdin  bool     `json:"AttachStdin"`
+	AttachStdout bool     `json:"AttachStdout"`
+	AttachStderr bool     `json:"AttachStderr"`
+}
+
+// ExecInspect is the response of GET /exec/{id}/json
+type ExecInspect struct {
+	Running  bool `json:"Running"`
+	ExitCode int  `json:"ExitCode"`
+}
+
+// idResponse is the {"Id": "..."} body returned by create endpoints
+type idResponse struct {
+	ID string `json:"Id"`
+}
+
+// waitResponse is the response of POST /containers/{id}/wait
+type waitResponse struct {
+	StatusCode int `json:"StatusCode"`
+	Error      *struct {
+		Message string `json:"Message"`
+	} `json:"Error"`
+}
+
+// buildMessage is one JSON line of the POST /build progress stream
+type buildMessage struct {
+	Stream      string `json:"stream"`
+	Status      string `json:"status"`
+	Error       string `json:"error"`
+	ErrorDetail *struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+}
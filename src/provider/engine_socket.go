@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EngineSocket returns the unix socket path of a Docker Engine API endpoint.
+// DOCKER_HOST (unix:// only) wins; otherwise the first existing socket among
+// the rootful Docker socket, Docker Desktop, OrbStack and rootless Podman's
+// docker-compatible socket is used. Returns "" when none is found.
+func EngineSocket() string {
+	if host := os.Getenv("DOCKER_HOST"); strings.HasPrefix(host, "unix://") {
+		return strings.TrimPrefix(host, "unix://")
+	}
+	for _, path := range engineSocketCandidates() {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// engineSocketCandidates lists well-known Engine API socket locations in
+// preference order
+func engineSocketCandidates() []string {
+	candidates := []string{"/var/run/docker.sock"}
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates,
+			filepath.Join(home, ".docker", "run", "docker.sock"),
+			filepath.Join(home, ".orbstack", "run", "docker.sock"),
+		)
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = filepath.Join("/run/user", strconv.Itoa(os.Getuid()))
+	}
+	return append(candidates, filepath.Join(runtimeDir, "podman", "podman.sock"))
+}
+
+// HasEngineSocket checks if an Engine API socket exists and accepts connections.
+func HasEngineSocket() bool {
+	socket := EngineSocket()
+	if socket == "" {
+		return false
+	}
+	conn, err := net.DialTimeout("unix", socket, 2*time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
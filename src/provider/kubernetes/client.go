@@ -0,0 +1,346 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Client talks to the Kubernetes API server of one kubeconfig context
+type Client struct {
+	config *RestConfig
+	http   *http.Client
+}
+
+// NewClient creates a client for the cluster described by cfg
+func NewClient(cfg *RestConfig) *Client {
+	return &Client{
+		config: cfg,
+		http: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: cfg.TLS,
+				IdleConnTimeout: 30 * time.Second,
+			},
+		},
+	}
+}
+
+// Namespace returns the namespace the client's objects live in
+func (c *Client) Namespace() string {
+	return c.config.Namespace
+}
+
+// APIError is a non-2xx response from the API server
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Reason     string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("kubernetes API %s %s: %d %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is an API 404
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsConflict reports whether err is an API 409 (e.g. name already exists)
+func IsConflict(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict
+}
+
+// authorize adds the context's credentials to a request's headers
+func (c *Client) authorize(h http.Header) {
+	if c.config.Token != "" {
+		h.Set("Authorization", "Bearer "+c.config.Token)
+	} else if c.config.Username != "" {
+		creds := c.config.Username + ":" + c.config.Password
+		h.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(creds)))
+	}
+}
+
+// doJSON performs a request with an optional JSON body and decodes the
+// JSON response into out (if non-nil)
+func (c *Client) doJSON(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	u := c.config.Server + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.authorize(req.Header)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("kubernetes API %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return responseError(method, path, resp)
+	}
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// responseError builds an *APIError from a Status response body
+func responseError(method, path string, resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var status Status
+	message := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &status) == nil && status.Message != "" {
+		message = status.Message
+	}
+	return &APIError{
+		Method:     method,
+		Path:       path,
+		StatusCode: resp.StatusCode,
+		Reason:     status.Reason,
+		Message:    message,
+	}
+}
+
+func (c *Client) podsPath(name string) string {
+	p := "/api/v1/namespaces/" + c.config.Namespace + "/pods"
+	if name != "" {
+		p += "/" + name
+	}
+	return p
+}
+
+// ServerVersion returns the API server's git version (e.g. v1.31.0)
+func (c *Client) ServerVersion(ctx context.Context) (string, error) {
+	var v struct {
+		GitVersion string `json:"gitVersion"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/version", nil, nil, &v); err != nil {
+		return "", err
+	}
+	return v.GitVersion, nil
+}
+
+// GetPod returns the named pod
+func (c *Client) GetPod(ctx context.Context, name string) (*Pod, error) {
+	var pod Pod
+	if err := c.doJSON(ctx, http.MethodGet, c.podsPath(name), nil, nil, &pod); err != nil {
+		return nil, err
+	}
+	return &pod, nil
+}
+
+// CreatePod creates a pod and returns it as stored by the server
+func (c *Client) CreatePod(ctx context.Context, pod *Pod) (*Pod, error) {
+	var created Pod
+	if err := c.doJSON(ctx, http.MethodPost, c.podsPath(""), nil, pod, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// DeletePod deletes the named pod without waiting for it to terminate.
+// Deleting a pod that does not exist is not an error.
+func (c *Client) DeletePod(ctx context.Context, name string, gracePeriodSeconds int64) error {
+	opts := map[string]interface{}{
+		"gracePeriodSeconds": gracePeriodSeconds,
+		// Remove owned secrets and policies along with the pod
+		"propagationPolicy": "Background",
+	}
+	err := c.doJSON(ctx, http.MethodDelete, c.podsPath(name), nil, opts, nil)
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// ListPods returns the pods matching a label selector
+func (c *Client) ListPods(ctx context.Context, labelSelector string) ([]Pod, error) {
+	var list PodList
+	query := url.Values{"labelSelector": {labelSelector}}
+	if err := c.doJSON(ctx, http.MethodGet, c.podsPath(""), query, nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// PodLogs returns the last lines of a container's log
+func (c *Client) PodLogs(ctx context.Context, name, container string, tailLines int) ([]byte, error) {
+	var buf bytes.Buffer
+	err := c.StreamPodLogs(ctx, name, container, false, tailLines, &buf)
+	return buf.Bytes(), err
+}
+
+// StreamPodLogs copies a container's log to out (the last tailLines lines,
+// or all when 0), waiting for new output when follow is set
+func (c *Client) StreamPodLogs(ctx context.Context, name, container string, follow bool, tailLines int, out io.Writer) error {
+	query := url.Values{"container": {container}}
+	if follow {
+		query.Set("follow", "true")
+	}
+	if tailLines > 0 {
+		query.Set("tailLines", strconv.Itoa(tailLines))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.config.Server+c.podsPath(name)+"/log?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	c.authorize(req.Header)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(http.MethodGet, c.podsPath(name)+"/log", resp)
+	}
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
+
+// PodWarnings returns the messages of warning events recorded for a pod,
+// which explain pods stuck in ContainerCreating (missing host paths, failed
+// secret mounts, unschedulable nodes)
+func (c *Client) PodWarnings(ctx context.Context, name string) ([]string, error) {
+	var list EventList
+	query := url.Values{"fieldSelector": {"involvedObject.kind=Pod,involvedObject.name=" + name}}
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/namespaces/"+c.config.Namespace+"/events", query, nil, &list); err != nil {
+		return nil, err
+	}
+	var warnings []string
+	for _, e := range list.Items {
+		if e.Type == "Warning" {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", e.Reason, e.Message))
+		}
+	}
+	return warnings, nil
+}
+
+// CreateSecret creates a secret
+func (c *Client) CreateSecret(ctx context.Context, secret *Secret) error {
+	return c.doJSON(ctx, http.MethodPost, "/api/v1/namespaces/"+c.config.Namespace+"/secrets", nil, secret, nil)
+}
+
+// DeleteSecret deletes the named secret. A missing secret is not an error.
+func (c *Client) DeleteSecret(ctx context.Context, name string) error {
+	err := c.doJSON(ctx, http.MethodDelete, "/api/v1/namespaces/"+c.config.Namespace+"/secrets/"+name, nil, nil, nil)
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// CreateNetworkPolicy creates a network policy
+func (c *Client) CreateNetworkPolicy(ctx context.Context, policy *NetworkPolicy) error {
+	return c.doJSON(ctx, http.MethodPost, "/apis/networking.k8s.io/v1/namespaces/"+c.config.Namespace+"/networkpolicies", nil, policy, nil)
+}
+
+// ListNodes returns the cluster's nodes
+func (c *Client) ListNodes(ctx context.Context) (*NodeList, error) {
+	var list NodeList
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/nodes", nil, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Stream subprotocols for exec, newest first. v5 adds a close signal for
+// stdin, so EOF reaches the remote process.
+const (
+	protocolV5 = "v5.channel.k8s.io"
+	protocolV4 = "v4.channel.k8s.io"
+)
+
+// ExecOptions selects the streams of an exec session
+type ExecOptions struct {
+	Container string
+	Command   []string
+	Stdin     bool
+	TTY       bool
+}
+
+// Exec starts a command in a running pod and returns the websocket
+// carrying its multiplexed streams
+func (c *Client) Exec(ctx context.Context, pod string, opts ExecOptions) (*websocket.Conn, error) {
+	query := url.Values{
+		"container": {opts.Container},
+		"stdout":    {"true"},
+	}
+	for _, arg := range opts.Command {
+		query.Add("command", arg)
+	}
+	if opts.Stdin {
+		query.Set("stdin", "true")
+	}
+	// The API server rejects stderr together with a TTY: both share the
+	// terminal
+	if opts.TTY {
+		query.Set("tty", "true")
+	} else {
+		query.Set("stderr", "true")
+	}
+
+	u, err := url.Parse(c.config.Server + c.podsPath(pod) + "/exec?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	}
+
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		TLSClientConfig:  c.config.TLS,
+		Subprotocols:     []string{protocolV5, protocolV4},
+		HandshakeTimeout: 30 * time.Second,
+	}
+	header := http.Header{}
+	c.authorize(header)
+
+	conn, resp, err := dialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		if resp != nil {
+			defer resp.Body.Close()
+			return nil, responseError(http.MethodGet, c.podsPath(pod)+"/exec", resp)
+		}
+		return nil, fmt.Errorf("kubernetes API exec %s: %w", pod, err)
+	}
+	return conn, nil
+}
@@ -0,0 +1,208 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestClient returns a client for a fake API server
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewClient(&RestConfig{Server: srv.URL, Namespace: "agents", Token: "secret-token"})
+}
+
+func TestClient_Pods(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/namespaces/agents/pods", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			var pod Pod
+			json.NewDecoder(r.Body).Decode(&pod)
+			pod.Metadata.UID = "uid-1"
+			json.NewEncoder(w).Encode(pod)
+		case http.MethodGet:
+			if got := r.URL.Query().Get("labelSelector"); got != "addt/persistent=true" {
+				t.Errorf("labelSelector = %q", got)
+			}
+			fmt.Fprint(w, `{"items":[{"metadata":{"name":"addt-persistent-a"},"status":{"phase":"Running"}}]}`)
+		}
+	})
+	mux.HandleFunc("/api/v1/namespaces/agents/pods/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"kind":"Status","status":"Failure","message":"pods \"missing\" not found","reason":"NotFound","code":404}`)
+	})
+	client := newTestClient(t, mux)
+	ctx := context.Background()
+
+	created, err := client.CreatePod(ctx, &Pod{Metadata: ObjectMeta{Name: "addt-x"}})
+	if err != nil || created.Metadata.UID != "uid-1" {
+		t.Fatalf("CreatePod() = %+v, %v", created, err)
+	}
+
+	pods, err := client.ListPods(ctx, "addt/persistent=true")
+	if err != nil || len(pods) != 1 || pods[0].Status.Phase != "Running" {
+		t.Errorf("ListPods() = %+v, %v", pods, err)
+	}
+
+	_, err = client.GetPod(ctx, "missing")
+	if !IsNotFound(err) || !strings.Contains(err.Error(), `pods "missing" not found`) {
+		t.Errorf("GetPod(missing) error = %v", err)
+	}
+	if err := client.DeletePod(ctx, "missing", 0); err != nil {
+		t.Errorf("deleting a missing pod should succeed, got %v", err)
+	}
+}
+
+// fakeExecServer upgrades exec requests and runs fn with the connection
+func fakeExecServer(t *testing.T, fn func(r *http.Request, conn *websocket.Conn)) *Client {
+	upgrader := websocket.Upgrader{Subprotocols: []string{protocolV5, protocolV4}}
+	return newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/agents/pods/addt-x/exec" {
+			http.NotFound(w, r)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		fn(r, conn)
+	}))
+}
+
+func TestExec_StreamsAndExitCode(t *testing.T) {
+	client := fakeExecServer(t, func(r *http.Request, conn *websocket.Conn) {
+		q := r.URL.Query()
+		if !reflect.DeepEqual(q["command"], []string{"/entrypoint.sh", "--help"}) || q.Get("stderr") != "true" || q.Get("tty") != "" {
+			t.Errorf("query = %v", q)
+		}
+
+		// Echo stdin to stdout until stdin is closed
+		var input []byte
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				t.Errorf("read: %v", err)
+				return
+			}
+			if msg[0] == channelClose && msg[1] == channelStdin {
+				break
+			}
+			if msg[0] == channelStdin {
+				input = append(input, msg[1:]...)
+			}
+		}
+		conn.WriteMessage(websocket.BinaryMessage, append([]byte{channelStdout}, input...))
+		conn.WriteMessage(websocket.BinaryMessage, append([]byte{channelStderr}, "warning\n"...))
+		conn.WriteMessage(websocket.BinaryMessage, append([]byte{channelError},
+			`{"status":"Failure","reason":"NonZeroExitCode","details":{"causes":[{"reason":"ExitCode","message":"3"}]}}`...))
+	})
+
+	conn, err := client.Exec(context.Background(), "addt-x", ExecOptions{
+		Container: containerName,
+		Command:   []string{"/entrypoint.sh", "--help"},
+		Stdin:     true,
+	})
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if conn.Subprotocol() != protocolV5 {
+		t.Errorf("subprotocol = %q", conn.Subprotocol())
+	}
+
+	var stdout, stderr bytes.Buffer
+	session := &streamSession{conn: conn, stdin: strings.NewReader("hello"), stdout: &stdout, stderr: &stderr}
+	err = session.run()
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Errorf("run() error = %v, want exit status 3", err)
+	}
+	if stdout.String() != "hello" || stderr.String() != "warning\n" {
+		t.Errorf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestExec_TTYDisablesStderr(t *testing.T) {
+	client := fakeExecServer(t, func(r *http.Request, conn *websocket.Conn) {
+		if q := r.URL.Query(); q.Get("tty") != "true" || q.Get("stderr") != "" {
+			t.Errorf("query = %v", q)
+		}
+		conn.WriteMessage(websocket.BinaryMessage, append([]byte{channelError}, `{"status":"Success"}`...))
+	})
+
+	conn, err := client.Exec(context.Background(), "addt-x", ExecOptions{Container: containerName, Command: []string{"true"}, TTY: true})
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	var out bytes.Buffer
+	if err := (&streamSession{conn: conn, stdout: &out, stderr: &out}).run(); err != nil {
+		t.Errorf("run() error = %v, want success", err)
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	if err := exitStatus([]byte(`{"status":"Success"}`)); err != nil {
+		t.Errorf("success = %v", err)
+	}
+	err := exitStatus([]byte(`{"status":"Failure","message":"executable file not found in $PATH"}`))
+	if err == nil || !strings.Contains(err.Error(), "executable file not found") {
+		t.Errorf("start failure = %v", err)
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		t.Error("a start failure is not an exit status")
+	}
+}
+
+func TestImageOnAllNodes(t *testing.T) {
+	nodes := `{"items":[
+		{"metadata":{"name":"cp"},"status":{"images":[{"names":["docker.io/library/addt:v1_claude-stable"]}]}},
+		{"metadata":{"name":"worker"},"status":{"images":[{"names":["registry.k8s.io/pause:3.9"]}]}}
+	]}`
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, nodes)
+	}))
+	p := &KubernetesProvider{client: client}
+
+	if p.imageOnAllNodes("addt:v1_claude-stable") {
+		t.Error("worker node lacks the image")
+	}
+	nodes = strings.Replace(nodes, "registry.k8s.io/pause:3.9", "docker.io/library/addt:v1_claude-stable", 1)
+	if !p.imageOnAllNodes("addt:v1_claude-stable") {
+		t.Error("image present on every node")
+	}
+}
+
+func TestImageLoadMode(t *testing.T) {
+	t.Setenv("ADDT_KUBERNETES_IMAGE_LOAD", "")
+	for context, want := range map[string]string{"kind-addt": "kind", "k3d-dev": "k3d", "prod-eu": "none"} {
+		if got := imageLoadMode(context); got != want {
+			t.Errorf("imageLoadMode(%q) = %q, want %q", context, got, want)
+		}
+	}
+	if got := clusterName("k3d-dev"); got != "dev" {
+		t.Errorf("clusterName() = %q", got)
+	}
+	t.Setenv("ADDT_KUBERNETES_IMAGE_LOAD", "none")
+	if got := imageLoadMode("kind-addt"); got != "none" {
+		t.Errorf("override ignored: %q", got)
+	}
+}
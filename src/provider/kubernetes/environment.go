@@ -0,0 +1,170 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/util"
+)
+
+// Access to existing pods: exec, logs, copy and inspect. Copies stream a
+// tar archive through an exec'd tar in the agent container, like kubectl cp.
+
+// exec runs command in the agent container, relaying stdio
+func (p *KubernetesProvider) exec(ctx context.Context, name string, command []string, tty bool, stdio provider.Stdio) error {
+	conn, err := p.client.Exec(ctx, name, ExecOptions{
+		Container: containerName,
+		Command:   command,
+		Stdin:     stdio.Stdin != nil,
+		TTY:       tty,
+	})
+	if err != nil {
+		return err
+	}
+	session := &streamSession{conn: conn, stdin: stdio.Stdin, stdout: stdio.Stdout, stderr: stdio.Stderr, tty: tty}
+	return session.run()
+}
+
+// Exec runs cmd in a running pod
+func (p *KubernetesProvider) Exec(name string, cmd []string, tty bool, stdio provider.Stdio) error {
+	if !p.IsRunning(name) {
+		return fmt.Errorf("pod %s is not running", name)
+	}
+	return p.exec(context.Background(), name, cmd, tty, stdio.WithDefaults())
+}
+
+// Logs writes the agent container's output to out
+func (p *KubernetesProvider) Logs(name string, follow bool, out io.Writer) error {
+	return p.client.StreamPodLogs(context.Background(), name, containerName, follow, 0, out)
+}
+
+// CopyTo copies a host file or directory into the pod
+func (p *KubernetesProvider) CopyTo(name, hostPath, envPath string) error {
+	if _, err := os.Stat(hostPath); err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	// Into an existing directory the source keeps its name, otherwise it
+	// is renamed to the destination
+	dir, base := envPath, path.Base(hostPath)
+	if p.exec(ctx, name, []string{"test", "-d", envPath}, false, provider.Stdio{Stdout: io.Discard, Stderr: io.Discard}) != nil {
+		dir, base = path.Dir(envPath), path.Base(envPath)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(util.TarPath(hostPath, base, pw))
+	}()
+	var stderr bytes.Buffer
+	err := p.exec(ctx, name, []string{"tar", "-xf", "-", "-C", dir}, false, provider.Stdio{Stdin: pr, Stdout: io.Discard, Stderr: &stderr})
+	pr.Close()
+	if err != nil {
+		return fmt.Errorf("copy to %s:%s: %w: %s", name, envPath, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// CopyFrom copies a file or directory from the pod to the host
+func (p *KubernetesProvider) CopyFrom(name, envPath, hostPath string) error {
+	pr, pw := io.Pipe()
+	var stderr bytes.Buffer
+	go func() {
+		command := []string{"tar", "-cf", "-", "-C", path.Dir(envPath), path.Base(envPath)}
+		pw.CloseWithError(p.exec(context.Background(), name, command, false, provider.Stdio{Stdout: pw, Stderr: &stderr}))
+	}()
+
+	err := util.UntarPath(pr, hostPath)
+	io.Copy(io.Discard, pr)
+	if err != nil {
+		return fmt.Errorf("copy from %s:%s: %w: %s", name, envPath, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Inspect describes a pod
+func (p *KubernetesProvider) Inspect(name string) (*provider.EnvironmentInfo, error) {
+	pod, err := p.client.GetPod(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+	return podInfo(pod), nil
+}
+
+// podInfo converts a pod into an EnvironmentInfo, describing the agent
+// container
+func podInfo(pod *Pod) *provider.EnvironmentInfo {
+	info := &provider.EnvironmentInfo{
+		Name:       pod.Metadata.Name,
+		ID:         pod.Metadata.UID,
+		Status:     podStatus(pod),
+		Running:    pod.Status.Phase == "Running",
+		Persistent: pod.Metadata.Labels[labelPersistent] == "true",
+		Labels:     pod.Metadata.Labels,
+	}
+	if t, err := time.Parse(time.RFC3339, pod.Metadata.CreationTimestamp); err == nil {
+		info.CreatedAt = t
+	}
+
+	hostPaths := make(map[string]string)
+	for _, vol := range pod.Spec.Volumes {
+		if vol.HostPath != nil {
+			hostPaths[vol.Name] = vol.HostPath.Path
+		}
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name != containerName {
+			continue
+		}
+		info.Image = c.Image
+		for _, m := range c.VolumeMounts {
+			if source, ok := hostPaths[m.Name]; ok {
+				info.Mounts = append(info.Mounts, provider.VolumeMount{Source: source, Target: m.MountPath, ReadOnly: m.ReadOnly})
+			}
+		}
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name != containerName {
+			continue
+		}
+		if cs.State.Running != nil {
+			if t, err := time.Parse(time.RFC3339, cs.State.Running.StartedAt); err == nil {
+				info.StartedAt = t
+			}
+		}
+		if cs.State.Terminated != nil {
+			info.ExitCode = cs.State.Terminated.ExitCode
+		}
+	}
+	return info
+}
+
+// Snapshots are not supported: a pod's filesystem can't be saved as an
+// image through the API server
+
+// Snapshot is not supported
+func (p *KubernetesProvider) Snapshot(name, tag string, checkpoint bool) (*provider.Snapshot, error) {
+	return nil, fmt.Errorf("kubernetes snapshots: %w", provider.ErrNotSupported)
+}
+
+// Restore is not supported
+func (p *KubernetesProvider) Restore(name, tag string) error {
+	return fmt.Errorf("kubernetes snapshots: %w", provider.ErrNotSupported)
+}
+
+// ListSnapshots returns no snapshots
+func (p *KubernetesProvider) ListSnapshots(name string) ([]provider.Snapshot, error) {
+	return nil, nil
+}
+
+// RemoveSnapshot is not supported
+func (p *KubernetesProvider) RemoveSnapshot(name, tag string) error {
+	return fmt.Errorf("kubernetes snapshots: %w", provider.ErrNotSupported)
+}
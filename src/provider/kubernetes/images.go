@@ -0,0 +1,125 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BuildIfNeeded builds the image with the local builder and makes it
+// available to the cluster's nodes
+func (p *KubernetesProvider) BuildIfNeeded(rebuild bool, rebuildBase bool) error {
+	if err := p.builder.Provider.BuildIfNeeded(rebuild, rebuildBase); err != nil {
+		return err
+	}
+	return p.loadImage(p.config.ImageName, rebuild || rebuildBase)
+}
+
+// imageLoadMode returns how images reach the cluster: "kind" or "k3d" load
+// them into the nodes, "none" leaves pulling to the cluster (e.g. from a
+// registry). ADDT_KUBERNETES_IMAGE_LOAD overrides the mode guessed from the
+// context name.
+func imageLoadMode(contextName string) string {
+	if mode := os.Getenv("ADDT_KUBERNETES_IMAGE_LOAD"); mode != "" {
+		return mode
+	}
+	switch {
+	case strings.HasPrefix(contextName, "kind-"):
+		return "kind"
+	case strings.HasPrefix(contextName, "k3d-"):
+		return "k3d"
+	}
+	return "none"
+}
+
+// clusterName derives the kind/k3d cluster name from its kubeconfig context
+func clusterName(contextName string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(contextName, "kind-"), "k3d-")
+}
+
+// loadImage copies an image into the nodes of a kind or k3d cluster unless
+// every node already has it
+func (p *KubernetesProvider) loadImage(imageName string, force bool) error {
+	mode := imageLoadMode(p.rest.Context)
+	if mode == "none" {
+		return nil
+	}
+	if mode != "kind" && mode != "k3d" {
+		return fmt.Errorf("unknown ADDT_KUBERNETES_IMAGE_LOAD %q (supported: kind, k3d, none)", mode)
+	}
+
+	if !force && p.imageOnAllNodes(imageName) {
+		p.logger.Debugf("Image %s already present on all nodes", imageName)
+		return nil
+	}
+	if _, err := exec.LookPath(mode); err != nil {
+		return fmt.Errorf("%s is not installed, cannot load %s into the cluster (or set ADDT_KUBERNETES_IMAGE_LOAD=none)", mode, imageName)
+	}
+
+	cluster := clusterName(p.rest.Context)
+	fmt.Printf("Loading image %s into %s cluster %s...\n", imageName, mode, cluster)
+	startTime := time.Now()
+
+	// kind and k3d read docker's image store directly; other builders
+	// export an archive first
+	source := imageName
+	if p.builder.Binary != "docker" {
+		tmpDir, err := os.MkdirTemp("", "addt-image-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmpDir)
+		source = filepath.Join(tmpDir, "image.tar")
+		if output, err := exec.Command(p.builder.Binary, "save", "-o", source, imageName).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to export image %s: %w\n%s", imageName, err, string(output))
+		}
+	}
+
+	cmd := loadCommand(mode, cluster, source, source != imageName)
+	p.logger.Debugf("Loading image: %v", cmd.Args)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to load image into %s cluster %s: %w\n%s", mode, cluster, err, string(output))
+	}
+	p.logger.Debugf("Image loaded in %s", time.Since(startTime).Round(time.Second))
+	return nil
+}
+
+// loadCommand returns the kind/k3d command importing an image or archive
+func loadCommand(mode, cluster, source string, archive bool) *exec.Cmd {
+	if mode == "k3d" {
+		return exec.Command("k3d", "image", "import", source, "-c", cluster)
+	}
+	if archive {
+		return exec.Command("kind", "load", "image-archive", source, "--name", cluster)
+	}
+	return exec.Command("kind", "load", "docker-image", source, "--name", cluster)
+}
+
+// imageOnAllNodes reports whether every node lists the image
+func (p *KubernetesProvider) imageOnAllNodes(imageName string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	nodes, err := p.client.ListNodes(ctx)
+	if err != nil || len(nodes.Items) == 0 {
+		return false
+	}
+	for _, node := range nodes.Items {
+		found := false
+		for _, image := range node.Status.Images {
+			for _, name := range image.Names {
+				// Nodes report fully qualified names (docker.io/library/addt:...)
+				if name == imageName || strings.HasSuffix(name, "/"+imageName) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,247 @@
+package kubernetes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kubeconfigFile is the subset of a kubeconfig file addt understands
+type kubeconfigFile struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+			TLSServerName            string `yaml:"tls-server-name"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string    `yaml:"token"`
+			TokenFile             string    `yaml:"tokenFile"`
+			ClientCertificate     string    `yaml:"client-certificate"`
+			ClientCertificateData string    `yaml:"client-certificate-data"`
+			ClientKey             string    `yaml:"client-key"`
+			ClientKeyData         string    `yaml:"client-key-data"`
+			Username              string    `yaml:"username"`
+			Password              string    `yaml:"password"`
+			Exec                  yaml.Node `yaml:"exec"`
+			AuthProvider          yaml.Node `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// RestConfig holds what is needed to talk to one cluster
+type RestConfig struct {
+	Context   string
+	Server    string
+	Namespace string
+	Token     string
+	Username  string
+	Password  string
+	TLS       *tls.Config
+}
+
+// kubeconfigPaths returns the kubeconfig files to read, honouring a
+// colon-separated KUBECONFIG like kubectl
+func kubeconfigPaths() []string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		var paths []string
+		for _, p := range filepath.SplitList(env) {
+			if p != "" {
+				paths = append(paths, p)
+			}
+		}
+		return paths
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(home, ".kube", "config")}
+}
+
+// LoadRestConfig reads the kubeconfig files and resolves contextName (the
+// current context when empty). A non-empty namespace overrides the
+// context's namespace.
+func LoadRestConfig(contextName, namespace string) (*RestConfig, error) {
+	paths := kubeconfigPaths()
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no kubeconfig found (set KUBECONFIG)")
+	}
+
+	var files []loadedKubeconfig
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kubeconfig %s: %w", path, err)
+		}
+		var kc kubeconfigFile
+		if err := yaml.Unmarshal(data, &kc); err != nil {
+			return nil, fmt.Errorf("invalid kubeconfig %s: %w", path, err)
+		}
+		files = append(files, loadedKubeconfig{dir: filepath.Dir(path), kc: &kc})
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no kubeconfig found at %s", strings.Join(paths, ", "))
+	}
+	return resolveContext(files, contextName, namespace)
+}
+
+// loadedKubeconfig is a parsed file and the directory relative paths in it
+// resolve against
+type loadedKubeconfig struct {
+	dir string
+	kc  *kubeconfigFile
+}
+
+// resolveContext builds a RestConfig from merged kubeconfig files. As with
+// kubectl, the first file to define a name or the current context wins.
+func resolveContext(files []loadedKubeconfig, contextName, namespace string) (*RestConfig, error) {
+	if contextName == "" {
+		for _, f := range files {
+			if f.kc.CurrentContext != "" {
+				contextName = f.kc.CurrentContext
+				break
+			}
+		}
+	}
+	if contextName == "" {
+		return nil, fmt.Errorf("no current kubeconfig context (set ADDT_KUBERNETES_CONTEXT)")
+	}
+
+	cfg := &RestConfig{Context: contextName}
+	var clusterName, userName string
+	found := false
+	for _, f := range files {
+		for _, c := range f.kc.Contexts {
+			if c.Name == contextName && !found {
+				clusterName, userName, cfg.Namespace = c.Context.Cluster, c.Context.User, c.Context.Namespace
+				found = true
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("kubeconfig context %q not found", contextName)
+	}
+	if namespace != "" {
+		cfg.Namespace = namespace
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = "default"
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	cfg.TLS = tlsConfig
+
+	clusterFound := false
+	for _, f := range files {
+		for _, c := range f.kc.Clusters {
+			if c.Name != clusterName || clusterFound {
+				continue
+			}
+			clusterFound = true
+			cfg.Server = strings.TrimSuffix(c.Cluster.Server, "/")
+			tlsConfig.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+			tlsConfig.ServerName = c.Cluster.TLSServerName
+			ca, err := readData(c.Cluster.CertificateAuthorityData, c.Cluster.CertificateAuthority, f.dir)
+			if err != nil {
+				return nil, fmt.Errorf("cluster %s: certificate authority: %w", clusterName, err)
+			}
+			if ca != nil {
+				pool := x509.NewCertPool()
+				if !pool.AppendCertsFromPEM(ca) {
+					return nil, fmt.Errorf("cluster %s: no certificates in certificate authority", clusterName)
+				}
+				tlsConfig.RootCAs = pool
+			}
+		}
+	}
+	if !clusterFound || cfg.Server == "" {
+		return nil, fmt.Errorf("kubeconfig cluster %q not found or has no server", clusterName)
+	}
+
+	userFound := false
+	for _, f := range files {
+		for _, u := range f.kc.Users {
+			if u.Name != userName || userFound {
+				continue
+			}
+			userFound = true
+			if !u.User.Exec.IsZero() || !u.User.AuthProvider.IsZero() {
+				return nil, fmt.Errorf("user %s: exec and auth-provider credential plugins are not supported; use a token or client certificate", userName)
+			}
+			cfg.Username, cfg.Password = u.User.Username, u.User.Password
+			cfg.Token = u.User.Token
+			if cfg.Token == "" && u.User.TokenFile != "" {
+				token, err := os.ReadFile(resolvePath(u.User.TokenFile, f.dir))
+				if err != nil {
+					return nil, fmt.Errorf("user %s: %w", userName, err)
+				}
+				cfg.Token = strings.TrimSpace(string(token))
+			}
+			cert, err := readData(u.User.ClientCertificateData, u.User.ClientCertificate, f.dir)
+			if err != nil {
+				return nil, fmt.Errorf("user %s: client certificate: %w", userName, err)
+			}
+			key, err := readData(u.User.ClientKeyData, u.User.ClientKey, f.dir)
+			if err != nil {
+				return nil, fmt.Errorf("user %s: client key: %w", userName, err)
+			}
+			if cert != nil && key != nil {
+				pair, err := tls.X509KeyPair(cert, key)
+				if err != nil {
+					return nil, fmt.Errorf("user %s: %w", userName, err)
+				}
+				tlsConfig.Certificates = []tls.Certificate{pair}
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// readData returns inline base64 data, or the contents of path, or nil
+func readData(data, path, dir string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if path != "" {
+		return os.ReadFile(resolvePath(path, dir))
+	}
+	return nil, nil
+}
+
+// resolvePath resolves a kubeconfig path relative to the file it came from
+func resolvePath(path, dir string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
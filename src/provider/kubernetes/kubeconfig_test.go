@@ -0,0 +1,156 @@
+package kubernetes
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCertPair returns a self-signed certificate and key in PEM
+func testCertPair(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "addt-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeKubeconfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRestConfig_KindStyle(t *testing.T) {
+	cert, key := testCertPair(t)
+	b64 := base64.StdEncoding.EncodeToString
+	dir := t.TempDir()
+	path := writeKubeconfig(t, dir, "config", `
+apiVersion: v1
+kind: Config
+current-context: kind-addt
+clusters:
+- name: kind-addt
+  cluster:
+    server: https://127.0.0.1:6443/
+    certificate-authority-data: `+b64(cert)+`
+contexts:
+- name: kind-addt
+  context:
+    cluster: kind-addt
+    user: kind-addt
+users:
+- name: kind-addt
+  user:
+    client-certificate-data: `+b64(cert)+`
+    client-key-data: `+b64(key)+`
+`)
+	t.Setenv("KUBECONFIG", path)
+
+	cfg, err := LoadRestConfig("", "")
+	if err != nil {
+		t.Fatalf("LoadRestConfig() error = %v", err)
+	}
+	if cfg.Context != "kind-addt" || cfg.Server != "https://127.0.0.1:6443" || cfg.Namespace != "default" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if cfg.TLS.RootCAs == nil || len(cfg.TLS.Certificates) != 1 {
+		t.Error("CA and client certificate should be loaded")
+	}
+
+	if cfg, err := LoadRestConfig("", "agents"); err != nil || cfg.Namespace != "agents" {
+		t.Errorf("namespace override = %v, %v", cfg, err)
+	}
+	if _, err := LoadRestConfig("missing", ""); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("unknown context error = %v", err)
+	}
+}
+
+func TestLoadRestConfig_MergedFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("file-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	first := writeKubeconfig(t, dir, "first", `
+current-context: remote
+contexts:
+- name: remote
+  context: {cluster: remote, user: remote, namespace: agents}
+`)
+	second := writeKubeconfig(t, dir, "second", `
+current-context: ignored
+clusters:
+- name: remote
+  cluster: {server: "https://k8s.example.com", insecure-skip-tls-verify: true}
+users:
+- name: remote
+  user: {tokenFile: token}
+`)
+	t.Setenv("KUBECONFIG", first+string(os.PathListSeparator)+second)
+
+	cfg, err := LoadRestConfig("", "")
+	if err != nil {
+		t.Fatalf("LoadRestConfig() error = %v", err)
+	}
+	if cfg.Context != "remote" || cfg.Namespace != "agents" {
+		t.Errorf("first file should win: %+v", cfg)
+	}
+	if cfg.Token != "file-token" {
+		t.Errorf("tokenFile should resolve relative to its kubeconfig, got %q", cfg.Token)
+	}
+	if !cfg.TLS.InsecureSkipVerify {
+		t.Error("insecure-skip-tls-verify not applied")
+	}
+}
+
+func TestLoadRestConfig_ExecPluginRejected(t *testing.T) {
+	path := writeKubeconfig(t, t.TempDir(), "config", `
+current-context: eks
+clusters:
+- name: eks
+  cluster: {server: "https://eks.example.com"}
+contexts:
+- name: eks
+  context: {cluster: eks, user: eks}
+users:
+- name: eks
+  user:
+    exec:
+      command: aws
+`)
+	t.Setenv("KUBECONFIG", path)
+
+	if _, err := LoadRestConfig("", ""); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("exec plugin error = %v", err)
+	}
+}
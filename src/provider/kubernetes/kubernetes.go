@@ -0,0 +1,470 @@
+// Package kubernetes runs extensions as pods on a Kubernetes cluster (kind,
+// k3d/k3s or a remote cluster), so long agent sessions don't tie up the
+// local machine. Images are built locally with a container provider and
+// loaded into the cluster.
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/util"
+)
+
+// podStartTimeout bounds the wait for a pod to run, including image pulls
+const podStartTimeout = 5 * time.Minute
+
+// Builder is the local container provider that builds the pod images
+type Builder struct {
+	Provider provider.Provider
+	// Binary is the builder's CLI, used to export images for the cluster
+	Binary string
+	// EntrypointPath is where the builder's images install the entrypoint
+	EntrypointPath string
+}
+
+// KubernetesProvider implements the Provider interface with pods
+type KubernetesProvider struct {
+	config  *provider.Config
+	builder Builder
+	rest    *RestConfig
+	client  *Client
+	logger  *util.ModuleLogger
+	uid     int64
+	gid     int64
+}
+
+// NewKubernetesProvider creates a provider for the kubeconfig context named
+// by ADDT_KUBERNETES_CONTEXT (default: the current context) and namespace
+// ADDT_KUBERNETES_NAMESPACE (default: the context's namespace)
+func NewKubernetesProvider(cfg *provider.Config, builder Builder) (provider.Provider, error) {
+	rest, err := LoadRestConfig(os.Getenv("ADDT_KUBERNETES_CONTEXT"), os.Getenv("ADDT_KUBERNETES_NAMESPACE"))
+	if err != nil {
+		return nil, err
+	}
+	return &KubernetesProvider{
+		config:  cfg,
+		builder: builder,
+		rest:    rest,
+		client:  NewClient(rest),
+		logger:  util.Log("kubernetes"),
+		uid:     int64(os.Getuid()),
+		gid:     int64(os.Getgid()),
+	}, nil
+}
+
+// Initialize initializes the image builder and checks the cluster is reachable
+func (p *KubernetesProvider) Initialize(cfg *provider.Config) error {
+	p.config = cfg
+	if err := p.builder.Provider.Initialize(cfg); err != nil {
+		return err
+	}
+	return p.CheckPrerequisites()
+}
+
+// GetName returns the provider name
+func (p *KubernetesProvider) GetName() string {
+	return "kubernetes"
+}
+
+// CheckPrerequisites verifies the API server answers with the configured
+// credentials
+func (p *KubernetesProvider) CheckPrerequisites() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	version, err := p.client.ServerVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("Kubernetes cluster not reachable (context %s): %w", p.rest.Context, err)
+	}
+	p.logger.Debugf("Connected to %s (Kubernetes %s), namespace %s", p.rest.Context, version, p.rest.Namespace)
+	return nil
+}
+
+// Cleanup cleans up the image builder
+func (p *KubernetesProvider) Cleanup() error {
+	return p.builder.Provider.Cleanup()
+}
+
+// Exists checks if a pod exists
+func (p *KubernetesProvider) Exists(name string) bool {
+	_, err := p.client.GetPod(context.Background(), name)
+	return err == nil
+}
+
+// IsRunning checks if a pod is running
+func (p *KubernetesProvider) IsRunning(name string) bool {
+	pod, err := p.client.GetPod(context.Background(), name)
+	return err == nil && pod.Status.Phase == "Running"
+}
+
+// Start checks that a pod is running. Pods are never restarted; an exited
+// pod is recreated by the next run.
+func (p *KubernetesProvider) Start(name string) error {
+	pod, err := p.client.GetPod(context.Background(), name)
+	if err != nil {
+		return err
+	}
+	if pod.Status.Phase != "Running" {
+		return fmt.Errorf("pod %s is %s and cannot be restarted; it is recreated on the next run", name, strings.ToLower(pod.Status.Phase))
+	}
+	return nil
+}
+
+// Stop deletes a pod: a stopped pod cannot be started again
+func (p *KubernetesProvider) Stop(name string) error {
+	return p.client.DeletePod(context.Background(), name, 5)
+}
+
+// Remove deletes a pod immediately, with its secret and network policy
+func (p *KubernetesProvider) Remove(name string) error {
+	return p.client.DeletePod(context.Background(), name, 0)
+}
+
+// List lists all persistent pods in the namespace
+func (p *KubernetesProvider) List() ([]provider.Environment, error) {
+	pods, err := p.client.ListPods(context.Background(), labelManagedBy+"=addt,"+labelPersistent+"=true")
+	if err != nil {
+		return nil, err
+	}
+
+	var envs []provider.Environment
+	for _, pod := range pods {
+		createdAt := pod.Metadata.CreationTimestamp
+		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+			createdAt = t.Local().Format("2006-01-02 15:04:05")
+		}
+		envs = append(envs, provider.Environment{
+			Name:      pod.Metadata.Name,
+			Status:    podStatus(&pod),
+			CreatedAt: createdAt,
+		})
+	}
+	return envs, nil
+}
+
+// podStatus maps the pod phase onto the provider's status words
+func podStatus(pod *Pod) string {
+	if pod.Status.Phase == "Succeeded" || pod.Status.Phase == "Failed" {
+		return "exited"
+	}
+	return strings.ToLower(pod.Status.Phase)
+}
+
+// GeneratePersistentName uses the builder's naming, which yields valid pod names
+func (p *KubernetesProvider) GeneratePersistentName() string {
+	return p.builder.Provider.GeneratePersistentName()
+}
+
+// GenerateEphemeralName generates a unique ephemeral pod name
+func (p *KubernetesProvider) GenerateEphemeralName() string {
+	return fmt.Sprintf("addt-%s-%d", time.Now().Format("20060102-150405"), os.Getpid())
+}
+
+// DetermineImageName returns the image name of the local builder
+func (p *KubernetesProvider) DetermineImageName() string {
+	return p.builder.Provider.DetermineImageName()
+}
+
+// GetExtensionEnvVars reads extension metadata from the locally built image
+func (p *KubernetesProvider) GetExtensionEnvVars(imageName string) []string {
+	return p.builder.Provider.GetExtensionEnvVars(imageName)
+}
+
+// GetStatus returns a status string for display
+func (p *KubernetesProvider) GetStatus(cfg *provider.Config, envName string) string {
+	parts := []string{fmt.Sprintf("%s %s/%s", p.GetName(), p.rest.Context, p.rest.Namespace)}
+
+	var resources []string
+	if cfg.ContainerCPUs != "" {
+		resources = append(resources, fmt.Sprintf("cpu:%s", cfg.ContainerCPUs))
+	}
+	if cfg.ContainerMemory != "" {
+		resources = append(resources, fmt.Sprintf("mem:%s", cfg.ContainerMemory))
+	}
+	if len(resources) > 0 {
+		parts = append(parts, strings.Join(resources, " "))
+	}
+
+	// The workdir is a hostPath on the node, not on this machine
+	workdir := cfg.Workdir
+	if workdir == "" {
+		workdir, _ = os.Getwd()
+	}
+	if cfg.WorkdirAutomount {
+		if cfg.WorkdirReadonly {
+			parts = append(parts, fmt.Sprintf("node:%s [RO]", workdir))
+		} else if cfg.WorkdirWorktree {
+			parts = append(parts, fmt.Sprintf("node:%s [worktree]", workdir))
+		} else if cfg.WorkdirOverlay {
+			parts = append(parts, fmt.Sprintf("node:%s [overlay]", workdir))
+		} else {
+			parts = append(parts, fmt.Sprintf("node:%s [RW]", workdir))
+		}
+	} else {
+		parts = append(parts, "[not mounted]")
+	}
+
+	if os.Getenv("GH_TOKEN") != "" {
+		parts = append(parts, "GH")
+	}
+	if cfg.Security.NetworkMode == "none" {
+		parts = append(parts, "net:none")
+	}
+	if cfg.Persistent {
+		parts = append(parts, "Persistent")
+	}
+
+	return strings.Join(parts, " | ")
+}
+
+// Run runs the extension entrypoint in a pod
+func (p *KubernetesProvider) Run(spec *provider.RunSpec) error {
+	return p.run(spec, append([]string{p.builder.EntrypointPath}, spec.Args...))
+}
+
+// Shell opens a bash shell in a pod
+func (p *KubernetesProvider) Shell(spec *provider.RunSpec) error {
+	fmt.Println("Opening bash shell in pod...")
+	// Run through the entrypoint so secrets and extension setup still work.
+	// Exec cannot set env vars, hence env(1).
+	return p.run(spec, append([]string{"env", "ADDT_COMMAND=/bin/bash", p.builder.EntrypointPath}, spec.Args...))
+}
+
+// run makes sure a pod for spec is running, then execs command in it
+// attached to the terminal. Ephemeral pods are deleted afterwards.
+func (p *KubernetesProvider) run(spec *provider.RunSpec, command []string) error {
+	ctx := context.Background()
+
+	// iptables rules would need NET_ADMIN in the pod; refuse rather than
+	// run unfiltered, unless all traffic is blocked by a NetworkPolicy
+	if p.config.FirewallEnabled && p.config.Security.NetworkMode != "none" {
+		return fmt.Errorf("the kubernetes provider cannot enforce firewall rules; set security.network_mode: none or use a container provider")
+	}
+
+	existing := false
+	if spec.Persistent {
+		pod, err := p.client.GetPod(ctx, spec.Name)
+		switch {
+		case err == nil && pod.Status.Phase == "Running":
+			fmt.Printf("Found existing persistent pod: %s\n", spec.Name)
+			existing = true
+		case err == nil:
+			fmt.Printf("Persistent pod %s is %s, recreating...\n", spec.Name, strings.ToLower(pod.Status.Phase))
+			if err := p.removeAndWait(ctx, spec.Name); err != nil {
+				return err
+			}
+		case IsNotFound(err):
+			fmt.Printf("Creating new persistent pod: %s\n", spec.Name)
+		default:
+			return err
+		}
+	}
+
+	if !existing {
+		if err := p.createPod(ctx, spec); err != nil {
+			return err
+		}
+	}
+	if !spec.Persistent {
+		defer func() {
+			p.logger.Debugf("Removing ephemeral pod %s", spec.Name)
+			p.client.DeletePod(context.Background(), spec.Name, 0)
+		}()
+	}
+
+	execErr := p.exec(ctx, spec.Name, command, spec.Interactive, provider.Stdio{}.WithDefaults())
+
+	if _, ok := execErr.(*ExitError); execErr != nil && !ok {
+		if logs, err := p.client.PodLogs(ctx, spec.Name, containerName, 50); err == nil && len(logs) > 0 {
+			p.logger.Debugf("Pod logs:\n%s", string(logs))
+		}
+	}
+	return execErr
+}
+
+// createPod creates the pod for spec with its secret and network policy,
+// and waits until it runs
+func (p *KubernetesProvider) createPod(ctx context.Context, spec *provider.RunSpec) error {
+	sec := p.config.Security
+
+	env := make(map[string]string, len(spec.Env))
+	for k, v := range spec.Env {
+		env[k] = v
+	}
+
+	var secretsJSON []byte
+	secretName := ""
+	if sec.IsolateSecrets {
+		var err error
+		if secretsJSON, err = p.prepareSecretsJSON(spec.ImageName, env); err != nil {
+			return err
+		}
+		if secretsJSON != nil {
+			secretName = spec.Name + "-secrets"
+		}
+	}
+	if sec.TimeLimit > 0 {
+		env["ADDT_TIME_LIMIT_SECONDS"] = fmt.Sprint(sec.TimeLimit * 60)
+	}
+	p.warnUnsupported(spec)
+
+	created, err := p.client.CreatePod(ctx, p.buildPod(spec, env, secretName))
+	if err != nil {
+		if IsConflict(err) {
+			return fmt.Errorf("pod %s already exists in namespace %s", spec.Name, p.rest.Namespace)
+		}
+		return fmt.Errorf("failed to create pod: %w", err)
+	}
+	fail := func(err error) error {
+		p.client.DeletePod(context.Background(), spec.Name, 0)
+		return err
+	}
+
+	// The Secret is created after the pod so it can be owned by it; the
+	// kubelet waits for it before running the init container
+	if secretName != "" {
+		if err := p.client.CreateSecret(ctx, buildSecret(secretName, created, secretsJSON)); err != nil {
+			return fail(fmt.Errorf("failed to create secret: %w", err))
+		}
+	}
+	if sec.NetworkMode == "none" {
+		if err := p.client.CreateNetworkPolicy(ctx, buildDenyAllPolicy(created)); err != nil {
+			return fail(fmt.Errorf("failed to create network policy: %w", err))
+		}
+		p.logger.Debug("network_mode none: deny-all NetworkPolicy created (needs a CNI that enforces policies)")
+	}
+
+	if err := p.waitForPod(ctx, spec.Name); err != nil {
+		return fail(err)
+	}
+
+	// The secrets now live only in the pod's tmpfs
+	if secretName != "" {
+		if err := p.client.DeleteSecret(ctx, secretName); err != nil {
+			p.logger.Debugf("Failed to delete secret %s: %v", secretName, err)
+		}
+	}
+	return nil
+}
+
+// waitForPod polls until the pod runs, failing fast on errors that will
+// not resolve themselves (bad image, failed init container)
+func (p *KubernetesProvider) waitForPod(ctx context.Context, name string) error {
+	fmt.Printf("Waiting for pod %s...\n", name)
+	deadline := time.Now().Add(podStartTimeout)
+	for time.Now().Before(deadline) {
+		pod, err := p.client.GetPod(ctx, name)
+		if err != nil {
+			return err
+		}
+		done, err := podReady(pod)
+		if done || err != nil {
+			return err
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	msg := fmt.Sprintf("pod %s did not start within %s", name, podStartTimeout)
+	if warnings, err := p.client.PodWarnings(ctx, name); err == nil && len(warnings) > 0 {
+		msg += ": " + warnings[len(warnings)-1]
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// podReady reports whether the agent container runs, or why it never will
+func podReady(pod *Pod) (bool, error) {
+	switch pod.Status.Phase {
+	case "Running":
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Name == containerName && cs.State.Running != nil {
+				return true, nil
+			}
+		}
+	case "Succeeded", "Failed":
+		return false, fmt.Errorf("pod %s %s: %s %s", pod.Metadata.Name, strings.ToLower(pod.Status.Phase), pod.Status.Reason, pod.Status.Message)
+	}
+
+	statuses := append(append([]ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		if w := cs.State.Waiting; w != nil {
+			switch w.Reason {
+			case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "ErrImageNeverPull", "CreateContainerConfigError", "CreateContainerError":
+				return false, fmt.Errorf("pod %s: %s: %s", pod.Metadata.Name, w.Reason, w.Message)
+			}
+		}
+		if t := cs.State.Terminated; t != nil && t.ExitCode != 0 {
+			return false, fmt.Errorf("pod %s: container %s exited with %d: %s", pod.Metadata.Name, cs.Name, t.ExitCode, t.Message)
+		}
+	}
+	return false, nil
+}
+
+// removeAndWait deletes a pod and waits until its name can be reused
+func (p *KubernetesProvider) removeAndWait(ctx context.Context, name string) error {
+	if err := p.client.DeletePod(ctx, name, 0); err != nil {
+		return err
+	}
+	for i := 0; i < 120; i++ {
+		if _, err := p.client.GetPod(ctx, name); IsNotFound(err) {
+			return nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return fmt.Errorf("pod %s is still terminating", name)
+}
+
+// prepareSecretsJSON moves extension secrets out of env into a JSON
+// document for the entrypoint. Returns nil when there is nothing to pass.
+func (p *KubernetesProvider) prepareSecretsJSON(imageName string, env map[string]string) ([]byte, error) {
+	names := p.GetExtensionEnvVars(imageName)
+	if credVars := env["ADDT_CREDENTIAL_VARS"]; credVars != "" {
+		for _, v := range strings.Split(credVars, ",") {
+			names = append(names, strings.TrimSpace(v))
+		}
+	}
+
+	secrets := make(map[string]string)
+	for _, name := range names {
+		if value := env[name]; value != "" {
+			secrets[name] = value
+			delete(env, name)
+		}
+	}
+	if len(secrets) == 0 {
+		return nil, nil
+	}
+	// ADDT_CREDENTIAL_VARS is no longer needed — secrets are in the file
+	delete(env, "ADDT_CREDENTIAL_VARS")
+
+	data, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+	return data, nil
+}
+
+// warnUnsupported reports requested features that depend on the local
+// machine and cannot reach a pod
+func (p *KubernetesProvider) warnUnsupported(spec *provider.RunSpec) {
+	if len(spec.Ports) > 0 {
+		fmt.Printf("Warning: ports are not published by the kubernetes provider; use kubectl port-forward pod/%s <port>\n", spec.Name)
+	}
+	if spec.SSHForwardKeys {
+		fmt.Println("Warning: SSH forwarding is not available in the kubernetes provider")
+	}
+	if spec.GPGForward != "" && spec.GPGForward != "off" && spec.GPGForward != "false" {
+		fmt.Println("Warning: GPG forwarding is not available in the kubernetes provider")
+	}
+	switch spec.DockerDindMode {
+	case "isolated", "true", "host":
+		fmt.Println("Warning: Docker-in-Docker is not available in the kubernetes provider")
+	}
+	if spec.TmuxForward || spec.HistoryPersist {
+		p.logger.Debug("Tmux forwarding and history persistence are not available in the kubernetes provider")
+	}
+}
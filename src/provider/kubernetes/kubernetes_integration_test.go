@@ -0,0 +1,131 @@
+//go:build integration
+
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/provider"
+)
+
+// stubBuilder stands in for the local image builder; only the extension
+// metadata is consulted once the image exists
+type stubBuilder struct {
+	provider.Provider
+}
+
+func (stubBuilder) GetExtensionEnvVars(string) []string {
+	return []string{"ADDT_TEST_SECRET"}
+}
+
+// requireCluster returns a provider for the current kubeconfig context
+// (e.g. a kind or k3d cluster), or skips the test
+func requireCluster(t *testing.T) *KubernetesProvider {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping cluster test in short mode")
+	}
+	prov, err := NewKubernetesProvider(&provider.Config{Security: security.DefaultConfig()}, Builder{Provider: stubBuilder{}})
+	if err != nil {
+		t.Skipf("no kubeconfig: %v", err)
+	}
+	p := prov.(*KubernetesProvider)
+	if err := p.CheckPrerequisites(); err != nil {
+		t.Skipf("cluster not reachable: %v", err)
+	}
+	return p
+}
+
+// testImage is any image with a shell and coreutils sleep (for "infinity")
+func testImage() string {
+	if image := os.Getenv("ADDT_KUBERNETES_TEST_IMAGE"); image != "" {
+		return image
+	}
+	return "debian:bookworm-slim"
+}
+
+// execOutput runs a command in the pod and returns its stdout
+func execOutput(t *testing.T, p *KubernetesProvider, pod string, command ...string) (string, error) {
+	t.Helper()
+	conn, err := p.client.Exec(context.Background(), pod, ExecOptions{Container: containerName, Command: command})
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	var stdout, stderr bytes.Buffer
+	err = (&streamSession{conn: conn, stdout: &stdout, stderr: &stderr}).run()
+	return stdout.String(), err
+}
+
+func TestKubernetesPod_Integration(t *testing.T) {
+	p := requireCluster(t)
+	p.config.Security.IsolateSecrets = true
+	name := fmt.Sprintf("addt-test-%d", time.Now().UnixNano()%1000000)
+	spec := &provider.RunSpec{
+		Name:      name,
+		ImageName: testImage(),
+		Env:       map[string]string{"ADDT_TEST_SECRET": "s3cret", "ADDT_TEST_PLAIN": "visible"},
+	}
+	defer p.Remove(name)
+
+	if err := p.createPod(context.Background(), spec); err != nil {
+		t.Fatalf("createPod() error = %v", err)
+	}
+	if !p.IsRunning(name) {
+		t.Fatal("pod should be running")
+	}
+
+	// The secret reached the tmpfs, and only through it
+	out, err := execOutput(t, p, name, "cat", "/run/secrets/.secrets")
+	if err != nil || !strings.Contains(out, "s3cret") {
+		t.Errorf("secrets file = %q, %v", out, err)
+	}
+	out, _ = execOutput(t, p, name, "env")
+	if strings.Contains(out, "s3cret") || !strings.Contains(out, "ADDT_TEST_PLAIN=visible") {
+		t.Errorf("pod env = %q", out)
+	}
+	if err := p.client.DeleteSecret(context.Background(), name+"-secrets"); err != nil {
+		t.Errorf("DeleteSecret() error = %v", err)
+	}
+
+	_, err = execOutput(t, p, name, "sh", "-c", "exit 3")
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Errorf("exit status = %v, want 3", err)
+	}
+
+	if err := p.Remove(name); err != nil {
+		t.Errorf("Remove() error = %v", err)
+	}
+}
+
+func TestKubernetesPersistentList_Integration(t *testing.T) {
+	p := requireCluster(t)
+	name := fmt.Sprintf("addt-persistent-test-%d", time.Now().UnixNano()%1000000)
+	defer p.Remove(name)
+
+	if err := p.createPod(context.Background(), &provider.RunSpec{Name: name, ImageName: testImage(), Persistent: true}); err != nil {
+		t.Fatalf("createPod() error = %v", err)
+	}
+
+	envs, err := p.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	found := false
+	for _, env := range envs {
+		if env.Name == name && env.Status == "running" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("List() = %+v, want running %s", envs, name)
+	}
+}
@@ -0,0 +1,252 @@
+package kubernetes
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jedi4ever/addt/provider"
+)
+
+const (
+	// containerName is the name of the agent container in every pod
+	containerName = "addt"
+
+	labelManagedBy  = "app.kubernetes.io/managed-by"
+	labelName       = "addt/name"
+	labelPersistent = "addt/persistent"
+
+	// secretMountPath is where the init container reads the Secret from
+	secretMountPath = "/run/addt-secret"
+)
+
+// podLabels returns the labels identifying an addt pod and its companions
+func podLabels(name string, persistent bool) map[string]string {
+	return map[string]string{
+		labelManagedBy:  "addt",
+		labelName:       name,
+		labelPersistent: fmt.Sprint(persistent),
+	}
+}
+
+// buildPod turns a RunSpec into a pod. Like the persistent containers of
+// the CLI providers, the agent container only keeps the pod alive; the
+// entrypoint is started through exec, so the terminal is attached from the
+// first byte of output. secretName, when set, names the Secret holding the
+// secrets JSON, which an init container copies into the /run/secrets tmpfs.
+func (p *KubernetesProvider) buildPod(spec *provider.RunSpec, env map[string]string, secretName string) *Pod {
+	sec := p.config.Security
+	pod := &Pod{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Metadata: ObjectMeta{
+			Name:   spec.Name,
+			Labels: podLabels(spec.Name, spec.Persistent),
+		},
+		Spec: PodSpec{
+			RestartPolicy:                 "Never",
+			TerminationGracePeriodSeconds: int64Ptr(5),
+			// The agent has no business talking to the cluster it runs on
+			AutomountServiceAccountToken: boolPtr(false),
+			EnableServiceLinks:           boolPtr(false),
+			Hostname:                     hostname(spec.Name),
+			SecurityContext: &PodSecurityContext{
+				// The image's addt user has the host UID/GID it was built with
+				RunAsUser:      int64Ptr(p.uid),
+				RunAsGroup:     int64Ptr(p.gid),
+				RunAsNonRoot:   boolPtr(p.uid != 0),
+				FSGroup:        int64Ptr(p.gid),
+				SeccompProfile: seccompProfile(sec.SeccompProfile),
+			},
+		},
+	}
+
+	// Persistent pods outlive a single session, so only ephemeral pods get
+	// the time limit as a hard deadline (the entrypoint enforces it too)
+	if sec.TimeLimit > 0 && !spec.Persistent {
+		pod.Spec.ActiveDeadlineSeconds = int64Ptr(int64(sec.TimeLimit) * 60)
+	}
+
+	container := Container{
+		Name:            containerName,
+		Image:           spec.ImageName,
+		ImagePullPolicy: "IfNotPresent",
+		Command:         []string{"sleep", "infinity"},
+		Env:             envVars(env),
+		SecurityContext: &SecurityContext{
+			Capabilities: &Capabilities{Drop: sec.CapDrop, Add: sec.CapAdd},
+		},
+	}
+	if sec.NoNewPrivileges {
+		container.SecurityContext.AllowPrivilegeEscalation = boolPtr(false)
+	}
+
+	limits := map[string]string{}
+	if spec.ContainerCPUs != "" {
+		limits["cpu"] = spec.ContainerCPUs
+	}
+	if spec.ContainerMemory != "" {
+		limits["memory"] = memoryQuantity(spec.ContainerMemory)
+	}
+	if len(limits) > 0 {
+		container.Resources = &ResourceRequirements{Limits: limits}
+	}
+
+	for i, vol := range spec.Volumes {
+		name := fmt.Sprintf("volume-%d", i)
+		pod.Spec.Volumes = append(pod.Spec.Volumes, Volume{
+			Name:     name,
+			HostPath: &HostPathVolumeSource{Path: vol.Source, Type: hostPathType(vol.Source)},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, VolumeMount{Name: name, MountPath: vol.Target, ReadOnly: vol.ReadOnly})
+	}
+
+	if sec.ReadOnlyRootfs {
+		container.SecurityContext.ReadOnlyRootFilesystem = boolPtr(true)
+		for _, scratch := range []struct{ name, path, size string }{
+			{"tmp", "/tmp", sec.TmpfsTmpSize},
+			{"var-tmp", "/var/tmp", "128m"},
+			{"home", "/home/addt", sec.TmpfsHomeSize},
+		} {
+			emptyDir := &EmptyDirVolumeSource{Medium: "Memory"}
+			if scratch.size != "" {
+				emptyDir.SizeLimit = memoryQuantity(scratch.size)
+			}
+			pod.Spec.Volumes = append(pod.Spec.Volumes, Volume{Name: scratch.name, EmptyDir: emptyDir})
+			container.VolumeMounts = append(container.VolumeMounts, VolumeMount{Name: scratch.name, MountPath: scratch.path})
+		}
+	}
+
+	if secretName != "" {
+		// Secret volumes are read-only, but the entrypoint scrubs and deletes
+		// the file after loading it, so it is copied into a writable tmpfs
+		pod.Spec.Volumes = append(pod.Spec.Volumes,
+			Volume{Name: "secrets", EmptyDir: &EmptyDirVolumeSource{Medium: "Memory", SizeLimit: "1Mi"}},
+			Volume{Name: "secret-source", Secret: &SecretVolumeSource{SecretName: secretName, DefaultMode: int32Ptr(0440)}},
+		)
+		container.VolumeMounts = append(container.VolumeMounts, VolumeMount{Name: "secrets", MountPath: "/run/secrets"})
+		pod.Spec.InitContainers = []Container{{
+			Name:            "secrets",
+			Image:           spec.ImageName,
+			ImagePullPolicy: "IfNotPresent",
+			Command:         []string{"sh", "-c", "cat " + secretMountPath + "/.secrets > /run/secrets/.secrets && chmod 644 /run/secrets/.secrets"},
+			VolumeMounts: []VolumeMount{
+				{Name: "secrets", MountPath: "/run/secrets"},
+				{Name: "secret-source", MountPath: secretMountPath, ReadOnly: true},
+			},
+			SecurityContext: &SecurityContext{
+				AllowPrivilegeEscalation: boolPtr(false),
+				Capabilities:             &Capabilities{Drop: []string{"ALL"}},
+			},
+		}}
+	}
+
+	pod.Spec.Containers = []Container{container}
+	return pod
+}
+
+// buildSecret returns the Secret carrying the secrets JSON for a pod. It is
+// owned by the pod, so it is garbage collected with it even if addt dies
+// before deleting it.
+func buildSecret(name string, owner *Pod, secretsJSON []byte) *Secret {
+	return &Secret{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata: ObjectMeta{
+			Name:            name,
+			Labels:          map[string]string{labelManagedBy: "addt", labelName: owner.Metadata.Name},
+			OwnerReferences: []OwnerReference{podOwner(owner)},
+		},
+		Type:       "Opaque",
+		StringData: map[string]string{".secrets": string(secretsJSON)},
+	}
+}
+
+// buildDenyAllPolicy returns a NetworkPolicy that blocks all ingress and
+// egress of a pod, the cluster equivalent of network_mode: none
+func buildDenyAllPolicy(owner *Pod) *NetworkPolicy {
+	return &NetworkPolicy{
+		APIVersion: "networking.k8s.io/v1",
+		Kind:       "NetworkPolicy",
+		Metadata: ObjectMeta{
+			Name:            owner.Metadata.Name,
+			Labels:          map[string]string{labelManagedBy: "addt", labelName: owner.Metadata.Name},
+			OwnerReferences: []OwnerReference{podOwner(owner)},
+		},
+		Spec: NetworkPolicySpec{
+			PodSelector: LabelSelector{MatchLabels: map[string]string{labelName: owner.Metadata.Name}},
+			PolicyTypes: []string{"Ingress", "Egress"},
+		},
+	}
+}
+
+func podOwner(pod *Pod) OwnerReference {
+	return OwnerReference{APIVersion: "v1", Kind: "Pod", Name: pod.Metadata.Name, UID: pod.Metadata.UID}
+}
+
+// envVars converts env to pod env vars in key order
+func envVars(env map[string]string) []EnvVar {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	vars := make([]EnvVar, 0, len(keys))
+	for _, k := range keys {
+		vars = append(vars, EnvVar{Name: k, Value: env[k]})
+	}
+	return vars
+}
+
+// seccompProfile maps security.seccomp_profile to a pod seccomp profile.
+// Profiles cannot be uploaded through the API: "restrictive" falls back to
+// the runtime default, and any other value names a Localhost profile,
+// relative to the kubelet's seccomp directory on the nodes.
+func seccompProfile(profile string) *SeccompProfile {
+	switch profile {
+	case "", "default", "restrictive":
+		return &SeccompProfile{Type: "RuntimeDefault"}
+	case "unconfined":
+		return &SeccompProfile{Type: "Unconfined"}
+	default:
+		return &SeccompProfile{Type: "Localhost", LocalhostProfile: profile}
+	}
+}
+
+// hostPathType picks the hostPath type from the local path, so a workdir
+// missing on the node fails the mount instead of creating an empty one
+func hostPathType(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	if info.IsDir() {
+		return "Directory"
+	}
+	return "File"
+}
+
+// memoryQuantity converts a Docker size (512m, 2g, 4gb) to a Kubernetes
+// quantity (512Mi, 2Gi, 4Gi)
+func memoryQuantity(size string) string {
+	s := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(size)), "b")
+	for suffix, unit := range map[string]string{"k": "Ki", "m": "Mi", "g": "Gi", "t": "Ti"} {
+		if strings.HasSuffix(s, suffix) {
+			return strings.TrimSuffix(s, suffix) + unit
+		}
+	}
+	return s
+}
+
+// hostname returns a pod hostname (a DNS label of at most 63 characters)
+func hostname(name string) string {
+	if len(name) > 63 {
+		name = strings.TrimRight(name[:63], "-")
+	}
+	return name
+}
+
+func boolPtr(b bool) *bool    { return &b }
+func int32Ptr(i int32) *int32 { return &i }
+func int64Ptr(i int64) *int64 { return &i }
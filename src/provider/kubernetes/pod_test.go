@@ -0,0 +1,207 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/provider"
+)
+
+func newTestProvider(sec security.Config) *KubernetesProvider {
+	return &KubernetesProvider{
+		config: &provider.Config{Security: sec},
+		uid:    1000,
+		gid:    1000,
+	}
+}
+
+func TestBuildPod_SecurityContext(t *testing.T) {
+	p := newTestProvider(security.DefaultConfig())
+	dir := t.TempDir()
+	pod := p.buildPod(&provider.RunSpec{
+		Name:            "addt-20260101-120000-42",
+		ImageName:       "addt:v1_claude-stable",
+		Volumes:         []provider.VolumeMount{{Source: dir, Target: "/workspace", ReadOnly: true}},
+		Env:             map[string]string{"B": "2", "A": "1"},
+		ContainerCPUs:   "1.5",
+		ContainerMemory: "4gb",
+	}, map[string]string{"B": "2", "A": "1"}, "")
+
+	if pod.Spec.RestartPolicy != "Never" || *pod.Spec.AutomountServiceAccountToken {
+		t.Error("pods should never restart and never mount a service account token")
+	}
+	psc := pod.Spec.SecurityContext
+	if *psc.RunAsUser != 1000 || !*psc.RunAsNonRoot || psc.SeccompProfile.Type != "RuntimeDefault" {
+		t.Errorf("pod security context = %+v", psc)
+	}
+
+	c := pod.Spec.Containers[0]
+	if !reflect.DeepEqual(c.Command, []string{"sleep", "infinity"}) {
+		t.Errorf("command = %v, want keep-alive", c.Command)
+	}
+	if *c.SecurityContext.AllowPrivilegeEscalation {
+		t.Error("no_new_privileges should disallow privilege escalation")
+	}
+	if !reflect.DeepEqual(c.SecurityContext.Capabilities.Drop, []string{"ALL"}) {
+		t.Errorf("capabilities = %+v", c.SecurityContext.Capabilities)
+	}
+	if c.Resources.Limits["cpu"] != "1.5" || c.Resources.Limits["memory"] != "4Gi" {
+		t.Errorf("limits = %v", c.Resources.Limits)
+	}
+	if !reflect.DeepEqual(c.Env, []EnvVar{{"A", "1"}, {"B", "2"}}) {
+		t.Errorf("env = %v", c.Env)
+	}
+	if len(pod.Spec.Volumes) != 1 || pod.Spec.Volumes[0].HostPath.Path != dir || pod.Spec.Volumes[0].HostPath.Type != "Directory" {
+		t.Errorf("volumes = %+v", pod.Spec.Volumes)
+	}
+	if len(c.VolumeMounts) != 1 || !c.VolumeMounts[0].ReadOnly {
+		t.Errorf("mounts = %+v", c.VolumeMounts)
+	}
+	if pod.Spec.InitContainers != nil || pod.Spec.ActiveDeadlineSeconds != nil {
+		t.Error("no secrets or time limit: no init container or deadline expected")
+	}
+}
+
+func TestBuildPod_SecretsAndReadOnly(t *testing.T) {
+	sec := security.DefaultConfig()
+	sec.ReadOnlyRootfs = true
+	sec.TmpfsTmpSize = "256m"
+	sec.TmpfsHomeSize = "512m"
+	sec.TimeLimit = 30
+	p := newTestProvider(sec)
+
+	pod := p.buildPod(&provider.RunSpec{Name: "addt-x", ImageName: "addt:v1"}, nil, "addt-x-secrets")
+
+	if pod.Spec.ActiveDeadlineSeconds == nil || *pod.Spec.ActiveDeadlineSeconds != 1800 {
+		t.Errorf("deadline = %v, want 1800", pod.Spec.ActiveDeadlineSeconds)
+	}
+	c := pod.Spec.Containers[0]
+	if !*c.SecurityContext.ReadOnlyRootFilesystem {
+		t.Error("read-only rootfs not applied")
+	}
+
+	mounts := map[string]string{}
+	for _, m := range c.VolumeMounts {
+		mounts[m.MountPath] = m.Name
+	}
+	volumes := map[string]Volume{}
+	for _, v := range pod.Spec.Volumes {
+		volumes[v.Name] = v
+	}
+	for path, size := range map[string]string{"/tmp": "256Mi", "/home/addt": "512Mi", "/run/secrets": "1Mi"} {
+		v, ok := volumes[mounts[path]]
+		if !ok || v.EmptyDir == nil || v.EmptyDir.Medium != "Memory" || v.EmptyDir.SizeLimit != size {
+			t.Errorf("%s should be a %s memory emptyDir, got %+v", path, size, v)
+		}
+	}
+	if volumes["secret-source"].Secret == nil || volumes["secret-source"].Secret.SecretName != "addt-x-secrets" {
+		t.Errorf("secret volume = %+v", volumes["secret-source"])
+	}
+	if len(pod.Spec.InitContainers) != 1 || !strings.Contains(pod.Spec.InitContainers[0].Command[2], "/run/secrets/.secrets") {
+		t.Errorf("init container = %+v", pod.Spec.InitContainers)
+	}
+
+	// Persistent pods outlive one session: no deadline
+	pod = p.buildPod(&provider.RunSpec{Name: "addt-persistent-x", Persistent: true}, nil, "")
+	if pod.Spec.ActiveDeadlineSeconds != nil {
+		t.Error("persistent pods should not get a deadline")
+	}
+	if pod.Metadata.Labels[labelPersistent] != "true" {
+		t.Errorf("labels = %v", pod.Metadata.Labels)
+	}
+}
+
+func TestBuildSecretAndPolicy(t *testing.T) {
+	owner := &Pod{Metadata: ObjectMeta{Name: "addt-x", UID: "uid-1"}}
+
+	secret := buildSecret("addt-x-secrets", owner, []byte(`{"K":"v"}`))
+	if secret.StringData[".secrets"] != `{"K":"v"}` || secret.Metadata.OwnerReferences[0].UID != "uid-1" {
+		t.Errorf("secret = %+v", secret)
+	}
+
+	policy := buildDenyAllPolicy(owner)
+	data, _ := json.Marshal(policy.Spec)
+	if string(data) != `{"podSelector":{"matchLabels":{"addt/name":"addt-x"}},"policyTypes":["Ingress","Egress"]}` {
+		t.Errorf("policy spec = %s", data)
+	}
+}
+
+func TestSeccompProfile(t *testing.T) {
+	testCases := map[string]SeccompProfile{
+		"":                 {Type: "RuntimeDefault"},
+		"restrictive":      {Type: "RuntimeDefault"},
+		"unconfined":       {Type: "Unconfined"},
+		"profiles/ai.json": {Type: "Localhost", LocalhostProfile: "profiles/ai.json"},
+	}
+	for profile, want := range testCases {
+		if got := seccompProfile(profile); *got != want {
+			t.Errorf("seccompProfile(%q) = %+v, want %+v", profile, got, want)
+		}
+	}
+}
+
+func TestMemoryQuantity(t *testing.T) {
+	for in, want := range map[string]string{"512m": "512Mi", "2g": "2Gi", "4gb": "4Gi", "1G": "1Gi", "1048576": "1048576"} {
+		if got := memoryQuantity(in); got != want {
+			t.Errorf("memoryQuantity(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPodReady(t *testing.T) {
+	var running ContainerState
+	if err := json.Unmarshal([]byte(`{"running":{}}`), &running); err != nil {
+		t.Fatal(err)
+	}
+	var pulling ContainerState
+	if err := json.Unmarshal([]byte(`{"waiting":{"reason":"ImagePullBackOff","message":"not found"}}`), &pulling); err != nil {
+		t.Fatal(err)
+	}
+
+	pod := &Pod{Status: PodStatus{Phase: "Running", ContainerStatuses: []ContainerStatus{{Name: containerName, State: running}}}}
+	if done, err := podReady(pod); !done || err != nil {
+		t.Errorf("running pod: done=%v err=%v", done, err)
+	}
+
+	pod = &Pod{Status: PodStatus{Phase: "Pending"}}
+	if done, err := podReady(pod); done || err != nil {
+		t.Errorf("pending pod: done=%v err=%v", done, err)
+	}
+
+	pod = &Pod{Status: PodStatus{Phase: "Pending", ContainerStatuses: []ContainerStatus{{Name: containerName, State: pulling}}}}
+	if _, err := podReady(pod); err == nil || !strings.Contains(err.Error(), "ImagePullBackOff") {
+		t.Errorf("image pull failure should fail fast, got %v", err)
+	}
+}
+
+func TestPodInfo(t *testing.T) {
+	p := newTestProvider(security.DefaultConfig())
+	dir := t.TempDir()
+	pod := p.buildPod(&provider.RunSpec{
+		Name:       "addt-persistent-myproject-1a2b3c4d",
+		ImageName:  "addt:v1_claude-stable",
+		Persistent: true,
+		Volumes:    []provider.VolumeMount{{Source: dir, Target: "/workspace", ReadOnly: true}},
+	}, nil, "")
+	pod.Metadata.UID = "0b7e"
+	pod.Metadata.CreationTimestamp = "2026-01-02T10:00:00Z"
+	if err := json.Unmarshal([]byte(`{"phase":"Running","containerStatuses":[
+		{"name":"addt","state":{"running":{"startedAt":"2026-01-02T10:00:05Z"}}}]}`), &pod.Status); err != nil {
+		t.Fatal(err)
+	}
+
+	info := podInfo(pod)
+	if info.ID != "0b7e" || info.Image != "addt:v1_claude-stable" || !info.Persistent || !info.Running || info.Status != "running" {
+		t.Errorf("info = %+v", info)
+	}
+	if info.CreatedAt.IsZero() || info.StartedAt.Second() != 5 {
+		t.Errorf("times = %v, %v", info.CreatedAt, info.StartedAt)
+	}
+	want := []provider.VolumeMount{{Source: dir, Target: "/workspace", ReadOnly: true}}
+	if !reflect.DeepEqual(info.Mounts, want) {
+		t.Errorf("mounts = %+v, want only the host path volumes %+v", info.Mounts, want)
+	}
+}
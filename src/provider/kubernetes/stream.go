@@ -0,0 +1,171 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/jedi4ever/addt/util/terminal"
+)
+
+// Channel numbers of the exec stream protocol. Every websocket message
+// starts with the channel byte, followed by the payload.
+const (
+	channelStdin  = 0
+	channelStdout = 1
+	channelStderr = 2
+	channelError  = 3
+	channelResize = 4
+	// channelClose (v5 only) closes the channel named in the payload
+	channelClose = 255
+)
+
+// ExitError reports a non-zero exit status of an exec'd process
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the process exit status, like *exec.ExitError
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// streamSession relays an exec session between local stdio and the pod
+type streamSession struct {
+	conn   *websocket.Conn
+	stdin  io.Reader // nil when stdin is not attached
+	stdout io.Writer
+	stderr io.Writer
+	tty    bool
+
+	writeMu sync.Mutex
+}
+
+// write sends one message on a channel. Writes come from both the stdin
+// and the resize goroutines, so they are serialized.
+func (s *streamSession) write(channel byte, data []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.conn.WriteMessage(websocket.BinaryMessage, append([]byte{channel}, data...))
+}
+
+// resize sends the local terminal size to the remote TTY
+func (s *streamSession) resize(width, height int) {
+	data, _ := json.Marshal(map[string]int{"Width": width, "Height": height})
+	s.write(channelResize, data)
+}
+
+// run relays stdio until the server reports the process status, and
+// returns nil or an *ExitError. With a TTY a terminal stdin is switched to
+// raw mode; resizes are forwarded when that terminal is our own.
+func (s *streamSession) run() error {
+	defer s.conn.Close()
+
+	if f, ok := s.stdin.(*os.File); ok && s.tty && isRawCandidate(f) {
+		if restore, err := terminal.MakeRaw(int(f.Fd())); err == nil {
+			defer restore()
+		}
+		if f == os.Stdin {
+			s.resize(terminal.GetTerminalSize())
+			sigs := make(chan os.Signal, 1)
+			terminal.NotifyResize(sigs)
+			defer func() {
+				signal.Stop(sigs)
+				close(sigs)
+			}()
+			go func() {
+				for range sigs {
+					s.resize(terminal.GetTerminalSize())
+				}
+			}()
+		}
+	}
+
+	if s.stdin != nil {
+		go s.copyStdin()
+	}
+
+	for {
+		_, msg, err := s.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) || errors.Is(err, io.EOF) {
+				// Closed without a status: the process is gone, outcome unknown
+				return nil
+			}
+			return fmt.Errorf("exec stream: %w", err)
+		}
+		if len(msg) == 0 {
+			continue
+		}
+		switch msg[0] {
+		case channelStdout:
+			s.stdout.Write(msg[1:])
+		case channelStderr:
+			s.stderr.Write(msg[1:])
+		case channelError:
+			return exitStatus(msg[1:])
+		}
+	}
+}
+
+// isRawCandidate reports whether f is a terminal to put in raw mode. The
+// process's own stdin only qualifies when stdout is a terminal as well.
+func isRawCandidate(f *os.File) bool {
+	if f == os.Stdin {
+		return terminal.IsTerminal()
+	}
+	return terminal.IsTerminalFd(int(f.Fd()))
+}
+
+// copyStdin forwards local stdin. On EOF the v5 protocol can close the
+// remote stdin; v4 has no such signal, so the remote side keeps waiting.
+func (s *streamSession) copyStdin() {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := s.stdin.Read(buf)
+		if n > 0 {
+			if s.write(channelStdin, buf[:n]) != nil {
+				return
+			}
+		}
+		if err != nil {
+			if s.conn.Subprotocol() == protocolV5 {
+				s.write(channelClose, []byte{channelStdin})
+			}
+			return
+		}
+	}
+}
+
+// exitStatus converts the Status sent on the error channel into nil (exit
+// code 0), an *ExitError, or a plain error for failures to start
+func exitStatus(data []byte) error {
+	var status Status
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("exec stream: invalid status: %s", string(data))
+	}
+	if status.Status == "Success" {
+		return nil
+	}
+	if status.Reason == "NonZeroExitCode" && status.Details != nil {
+		for _, cause := range status.Details.Causes {
+			if cause.Reason == "ExitCode" {
+				if code, err := strconv.Atoi(cause.Message); err == nil {
+					return &ExitError{Code: code}
+				}
+			}
+		}
+	}
+	return errors.New(status.Message)
+}
@@ -0,0 +1,229 @@
+package kubernetes
+
+// Minimal Kubernetes API object types: only the fields addt sets or reads.
+
+// ObjectMeta is the metadata common to all objects
+type ObjectMeta struct {
+	Name              string            `json:"name,omitempty"`
+	Namespace         string            `json:"namespace,omitempty"`
+	UID               string            `json:"uid,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	Annotations       map[string]string `json:"annotations,omitempty"`
+	OwnerReferences   []OwnerReference  `json:"ownerReferences,omitempty"`
+	CreationTimestamp string            `json:"creationTimestamp,omitempty"`
+}
+
+// OwnerReference ties an object's lifetime to its owner (garbage collection)
+type OwnerReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	UID        string `json:"uid"`
+}
+
+// Pod is a v1 Pod
+type Pod struct {
+	APIVersion string     `json:"apiVersion,omitempty"`
+	Kind       string     `json:"kind,omitempty"`
+	Metadata   ObjectMeta `json:"metadata"`
+	Spec       PodSpec    `json:"spec"`
+	Status     PodStatus  `json:"status,omitempty"`
+}
+
+// PodSpec is the desired state of a pod
+type PodSpec struct {
+	InitContainers                []Container         `json:"initContainers,omitempty"`
+	Containers                    []Container         `json:"containers"`
+	Volumes                       []Volume            `json:"volumes,omitempty"`
+	RestartPolicy                 string              `json:"restartPolicy,omitempty"`
+	TerminationGracePeriodSeconds *int64              `json:"terminationGracePeriodSeconds,omitempty"`
+	ActiveDeadlineSeconds         *int64              `json:"activeDeadlineSeconds,omitempty"`
+	AutomountServiceAccountToken  *bool               `json:"automountServiceAccountToken,omitempty"`
+	EnableServiceLinks            *bool               `json:"enableServiceLinks,omitempty"`
+	Hostname                      string              `json:"hostname,omitempty"`
+	SecurityContext               *PodSecurityContext `json:"securityContext,omitempty"`
+}
+
+// Container is one container of a pod
+type Container struct {
+	Name            string                `json:"name"`
+	Image           string                `json:"image"`
+	ImagePullPolicy string                `json:"imagePullPolicy,omitempty"`
+	Command         []string              `json:"command,omitempty"`
+	Env             []EnvVar              `json:"env,omitempty"`
+	VolumeMounts    []VolumeMount         `json:"volumeMounts,omitempty"`
+	Resources       *ResourceRequirements `json:"resources,omitempty"`
+	SecurityContext *SecurityContext      `json:"securityContext,omitempty"`
+}
+
+// EnvVar is a literal environment variable
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// VolumeMount mounts a pod volume into a container
+type VolumeMount struct {
+	Name      string `json:"name"`
+	MountPath string `json:"mountPath"`
+	ReadOnly  bool   `json:"readOnly,omitempty"`
+}
+
+// Volume is a pod volume. Exactly one source is set.
+type Volume struct {
+	Name     string                `json:"name"`
+	HostPath *HostPathVolumeSource `json:"hostPath,omitempty"`
+	EmptyDir *EmptyDirVolumeSource `json:"emptyDir,omitempty"`
+	Secret   *SecretVolumeSource   `json:"secret,omitempty"`
+}
+
+// HostPathVolumeSource mounts a path from the node
+type HostPathVolumeSource struct {
+	Path string `json:"path"`
+	Type string `json:"type,omitempty"`
+}
+
+// EmptyDirVolumeSource is scratch space; Medium "Memory" makes it a tmpfs
+type EmptyDirVolumeSource struct {
+	Medium    string `json:"medium,omitempty"`
+	SizeLimit string `json:"sizeLimit,omitempty"`
+}
+
+// SecretVolumeSource mounts the keys of a Secret as files
+type SecretVolumeSource struct {
+	SecretName  string `json:"secretName"`
+	DefaultMode *int32 `json:"defaultMode,omitempty"`
+}
+
+// ResourceRequirements holds resource limits as quantity strings
+type ResourceRequirements struct {
+	Limits map[string]string `json:"limits,omitempty"`
+}
+
+// PodSecurityContext holds pod-level security settings
+type PodSecurityContext struct {
+	RunAsUser      *int64          `json:"runAsUser,omitempty"`
+	RunAsGroup     *int64          `json:"runAsGroup,omitempty"`
+	RunAsNonRoot   *bool           `json:"runAsNonRoot,omitempty"`
+	FSGroup        *int64          `json:"fsGroup,omitempty"`
+	SeccompProfile *SeccompProfile `json:"seccompProfile,omitempty"`
+}
+
+// SecurityContext holds container-level security settings
+type SecurityContext struct {
+	AllowPrivilegeEscalation *bool         `json:"allowPrivilegeEscalation,omitempty"`
+	ReadOnlyRootFilesystem   *bool         `json:"readOnlyRootFilesystem,omitempty"`
+	Capabilities             *Capabilities `json:"capabilities,omitempty"`
+}
+
+// Capabilities lists Linux capabilities to add and drop
+type Capabilities struct {
+	Add  []string `json:"add,omitempty"`
+	Drop []string `json:"drop,omitempty"`
+}
+
+// SeccompProfile selects RuntimeDefault, Unconfined or a Localhost profile
+type SeccompProfile struct {
+	Type             string `json:"type"`
+	LocalhostProfile string `json:"localhostProfile,omitempty"`
+}
+
+// PodStatus is the observed state of a pod
+type PodStatus struct {
+	Phase                 string            `json:"phase,omitempty"`
+	Message               string            `json:"message,omitempty"`
+	Reason                string            `json:"reason,omitempty"`
+	InitContainerStatuses []ContainerStatus `json:"initContainerStatuses,omitempty"`
+	ContainerStatuses     []ContainerStatus `json:"containerStatuses,omitempty"`
+}
+
+// ContainerStatus is the state of one container
+type ContainerStatus struct {
+	Name  string         `json:"name"`
+	State ContainerState `json:"state"`
+}
+
+// ContainerState holds at most one of waiting, running or terminated
+type ContainerState struct {
+	Waiting *struct {
+		Reason  string `json:"reason,omitempty"`
+		Message string `json:"message,omitempty"`
+	} `json:"waiting,omitempty"`
+	Running *struct {
+		StartedAt string `json:"startedAt,omitempty"`
+	} `json:"running,omitempty"`
+	Terminated *struct {
+		ExitCode int    `json:"exitCode"`
+		Reason   string `json:"reason,omitempty"`
+		Message  string `json:"message,omitempty"`
+	} `json:"terminated,omitempty"`
+}
+
+// PodList is the response of a pod list call
+type PodList struct {
+	Items []Pod `json:"items"`
+}
+
+// Secret is a v1 Secret. StringData is write-only and merged into Data.
+type Secret struct {
+	APIVersion string            `json:"apiVersion,omitempty"`
+	Kind       string            `json:"kind,omitempty"`
+	Metadata   ObjectMeta        `json:"metadata"`
+	Type       string            `json:"type,omitempty"`
+	StringData map[string]string `json:"stringData,omitempty"`
+}
+
+// NetworkPolicy is a networking.k8s.io/v1 NetworkPolicy. addt only creates
+// deny-all policies, so no rules are modelled.
+type NetworkPolicy struct {
+	APIVersion string            `json:"apiVersion,omitempty"`
+	Kind       string            `json:"kind,omitempty"`
+	Metadata   ObjectMeta        `json:"metadata"`
+	Spec       NetworkPolicySpec `json:"spec"`
+}
+
+// NetworkPolicySpec selects pods and the traffic directions it restricts
+type NetworkPolicySpec struct {
+	PodSelector LabelSelector `json:"podSelector"`
+	PolicyTypes []string      `json:"policyTypes"`
+}
+
+// LabelSelector matches objects by labels
+type LabelSelector struct {
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+}
+
+// NodeList is the response of a node list call
+type NodeList struct {
+	Items []struct {
+		Metadata ObjectMeta `json:"metadata"`
+		Status   struct {
+			Images []struct {
+				Names []string `json:"names"`
+			} `json:"images"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// EventList is the response of an event list call
+type EventList struct {
+	Items []struct {
+		Type    string `json:"type"`
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	} `json:"items"`
+}
+
+// Status is the API's error and exec result object
+type Status struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+	Code    int    `json:"code"`
+	Details *struct {
+		Causes []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"causes"`
+	} `json:"details,omitempty"`
+}
@@ -0,0 +1,44 @@
+package nerdctl
+
+import (
+	"embed"
+	"fmt"
+	"os/exec"
+
+	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/provider/ocicli"
+)
+
+// NewNerdctlProvider creates a new containerd/nerdctl provider
+func NewNerdctlProvider(cfg *provider.Config, dockerfile, dockerfileBase, entrypoint, initFirewall, installSh []byte, extensions embed.FS) (provider.Provider, error) {
+	return ocicli.New(Runtime(), cfg, dockerfile, dockerfileBase, entrypoint, initFirewall, installSh, extensions), nil
+}
+
+// Runtime returns the nerdctl runtime descriptor
+func Runtime() ocicli.Runtime {
+	rt := ocicli.NerdctlRuntime()
+	rt.Prerequisites = checkPrerequisites
+	return rt
+}
+
+// checkPrerequisites verifies nerdctl is installed, containerd is reachable
+// and BuildKit is available for image builds
+func checkPrerequisites() error {
+	// Check nerdctl is installed
+	if _, err := exec.LookPath("nerdctl"); err != nil {
+		return fmt.Errorf("nerdctl is not installed. Please install nerdctl from: https://github.com/containerd/nerdctl/releases")
+	}
+
+	// Check containerd is running (rootless: containerd-rootless-setuptool.sh install)
+	cmd := exec.Command("nerdctl", "info")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("containerd is not running. Start it with: systemctl --user start containerd (rootless) or sudo systemctl start containerd")
+	}
+
+	// nerdctl build needs a BuildKit daemon
+	if _, err := exec.LookPath("buildctl"); err != nil {
+		return fmt.Errorf("BuildKit is not installed (nerdctl needs it to build images). Install it with: containerd-rootless-setuptool.sh install-buildkit")
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package ocicli
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/extensions"
+	"github.com/jedi4ever/addt/provider"
+)
+
+// startAPIProxy keeps the API keys the active extensions declare in apis:
+// on the host (security.api_proxy). The proxy gets the real keys from the
+// session's environment, which then carries a placeholder for each and
+// the tool's base URL pointed at the entrypoint's bridge to the proxy.
+// Like the egress proxy, a persistent container keeps its port, and its
+// placeholder, which it was created with. Credentials the proxy can't stand
+// in for, such as OAuth logins, still reach the container, with a warning.
+func (p *Provider) startAPIProxy(spec *provider.RunSpec) error {
+	if !p.config.Security.APIProxy || p.apiProxy != nil {
+		return nil
+	}
+	if p.config.Security.NetworkMode == "none" {
+		fmt.Println("Warning: the API proxy is unreachable with security.network_mode none")
+		return nil
+	}
+	if unproxied := p.unproxiedCredentials(spec.Env); len(unproxied) > 0 {
+		fmt.Printf("Warning: the API proxy doesn't cover %s, which is passed into the container\n", strings.Join(unproxied, ", "))
+	}
+
+	var routes []security.APIRoute
+	var apis []extensions.ExtensionAPI
+	for _, api := range p.extensionAPIs() {
+		key := spec.Env[api.KeyEnv]
+		if key == "" || strings.HasPrefix(key, security.APIProxyTokenPrefix) {
+			continue
+		}
+		routes = append(routes, security.APIRoute{
+			Name:     api.Name,
+			Upstream: api.Upstream,
+			Header:   api.Header,
+			Scheme:   api.Scheme,
+			Key:      key,
+		})
+		apis = append(apis, api)
+	}
+	if len(routes) == 0 {
+		return nil
+	}
+
+	token, err := security.APIProxyToken(spec.Name, spec.Persistent)
+	if err != nil {
+		return err
+	}
+	proxy, err := security.NewAPIProxy(spec.Name, token, routes)
+	if err != nil {
+		return err
+	}
+	port := 0
+	if spec.Persistent {
+		port = apiProxyPort(spec.Name)
+	}
+	host := p.helperListenIP()
+	if err := proxy.Start(net.JoinHostPort(host, strconv.Itoa(port))); err != nil {
+		if port == 0 {
+			return fmt.Errorf("failed to start API proxy: %w", err)
+		}
+		fmt.Printf("Warning: API proxy port %d is taken, %s will need to be recreated to reach it\n", port, spec.Name)
+		if err := proxy.Start(net.JoinHostPort(host, "0")); err != nil {
+			return fmt.Errorf("failed to start API proxy: %w", err)
+		}
+	}
+	p.apiProxy = proxy
+
+	for _, api := range apis {
+		spec.Env[api.KeyEnv] = token
+		spec.Env[api.BaseURLEnv] = fmt.Sprintf("http://127.0.0.1:%d/%s", security.APIProxyContainerPort, api.Name)
+	}
+	fmt.Printf("API proxy: %s keys stay on the host\n", strings.Join(proxy.Names(), ", "))
+	return nil
+}
+
+// unproxiedCredentials returns the credentials in env that the active
+// extensions' APIs can use but the proxy can't stand in for
+func (p *Provider) unproxiedCredentials(env map[string]string) []string {
+	var names []string
+	for _, api := range p.extensionAPIs() {
+		for _, name := range api.UnproxiedEnv {
+			if env[name] != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// extensionAPIs returns the APIs the active extensions declare
+func (p *Provider) extensionAPIs() []extensions.ExtensionAPI {
+	exts, err := extensions.GetExtensions()
+	if err != nil {
+		p.logger.Debugf("Failed to load extensions for the API proxy: %v", err)
+		return nil
+	}
+	active := strings.Split(p.config.Extensions, ",")
+	if p.config.Extensions == "" {
+		active = []string{"claude"}
+	}
+	var apis []extensions.ExtensionAPI
+	for _, ext := range exts {
+		for _, name := range active {
+			if strings.TrimSpace(name) == ext.Name {
+				apis = append(apis, ext.APIs...)
+			}
+		}
+	}
+	return apis
+}
+
+// apiProxyPort derives a persistent container's API proxy port, between
+// the host service forwarder's and the egress proxy's ranges
+func apiProxyPort(name string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return 30000 + int(h.Sum32()%10000)
+}
+
+// apiProxyEnvArgs tells the entrypoint where to bridge the container's
+// 127.0.0.1:4874 to, and the firewall script to allow it
+func (p *Provider) apiProxyEnvArgs() []string {
+	if p.apiProxy == nil {
+		return nil
+	}
+	return []string{"-e", fmt.Sprintf("ADDT_API_PROXY=%s:%d", egressProxyHost, p.apiProxy.Port())}
+}
+
+// stopAPIProxy stops the API proxy
+func (p *Provider) stopAPIProxy() {
+	if p.apiProxy == nil {
+		return
+	}
+	p.apiProxy.Stop()
+	p.apiProxy = nil
+}
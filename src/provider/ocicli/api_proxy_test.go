@@ -0,0 +1,82 @@
+package ocicli
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/provider"
+)
+
+func TestStartAPIProxy(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+	cfg := &provider.Config{Extensions: "claude", Security: security.Config{APIProxy: true}}
+	p := newTestProvider(DockerRuntime("desktop-linux"), cfg)
+	spec := &provider.RunSpec{Name: "addt-test", Env: map[string]string{"ANTHROPIC_API_KEY": "sk-ant-real"}}
+	if err := p.startAPIProxy(spec); err != nil {
+		t.Fatalf("startAPIProxy() error = %v", err)
+	}
+	defer p.stopAPIProxy()
+
+	if key := spec.Env["ANTHROPIC_API_KEY"]; !strings.HasPrefix(key, security.APIProxyTokenPrefix) {
+		t.Errorf("ANTHROPIC_API_KEY = %q, want a placeholder", key)
+	}
+	if got, want := spec.Env["ANTHROPIC_BASE_URL"], fmt.Sprintf("http://127.0.0.1:%d/anthropic", security.APIProxyContainerPort); got != want {
+		t.Errorf("ANTHROPIC_BASE_URL = %q, want %q", got, want)
+	}
+	env := strings.Join(p.apiProxyEnvArgs(), " ")
+	if env != fmt.Sprintf("-e ADDT_API_PROXY=host.docker.internal:%d", p.apiProxy.Port()) {
+		t.Errorf("apiProxyEnvArgs() = %s", env)
+	}
+}
+
+func TestUnproxiedCredentials(t *testing.T) {
+	p := newTestProvider(DockerRuntime("desktop-linux"), &provider.Config{Extensions: "claude"})
+	env := map[string]string{"ANTHROPIC_API_KEY": "sk-ant-real", "CLAUDE_OAUTH_CREDENTIALS": "eyJ..."}
+	if got := p.unproxiedCredentials(env); len(got) != 1 || got[0] != "CLAUDE_OAUTH_CREDENTIALS" {
+		t.Errorf("unproxiedCredentials() = %v, want CLAUDE_OAUTH_CREDENTIALS", got)
+	}
+	delete(env, "CLAUDE_OAUTH_CREDENTIALS")
+	if got := p.unproxiedCredentials(env); len(got) != 0 {
+		t.Errorf("unproxiedCredentials() = %v, want none", got)
+	}
+}
+
+func TestStartAPIProxy_Skipped(t *testing.T) {
+	tests := []struct {
+		name string
+		sec  security.Config
+		env  map[string]string
+	}{
+		{"disabled", security.Config{}, map[string]string{"ANTHROPIC_API_KEY": "sk-ant-real"}},
+		{"no network", security.Config{APIProxy: true, NetworkMode: "none"}, map[string]string{"ANTHROPIC_API_KEY": "sk-ant-real"}},
+		{"no key", security.Config{APIProxy: true}, map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProvider(DockerRuntime("desktop-linux"), &provider.Config{Extensions: "claude", Security: tt.sec})
+			spec := &provider.RunSpec{Name: "addt-test", Env: tt.env}
+			if err := p.startAPIProxy(spec); err != nil {
+				t.Fatalf("startAPIProxy() error = %v", err)
+			}
+			if p.apiProxy != nil {
+				p.stopAPIProxy()
+				t.Fatal("API proxy started")
+			}
+			if key, ok := spec.Env["ANTHROPIC_API_KEY"]; ok && key != "sk-ant-real" {
+				t.Errorf("ANTHROPIC_API_KEY = %q, want it unchanged", key)
+			}
+		})
+	}
+}
+
+func TestAPIProxyPort(t *testing.T) {
+	port := apiProxyPort("addt-persistent-myproject-abc123")
+	if port < 30000 || port >= 40000 {
+		t.Errorf("apiProxyPort() = %d, want 30000-39999", port)
+	}
+	if apiProxyPort("addt-persistent-myproject-abc123") != port {
+		t.Error("apiProxyPort() is not stable")
+	}
+}
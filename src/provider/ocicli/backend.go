@@ -0,0 +1,97 @@
+package ocicli
+
+import (
+	"io"
+	"time"
+
+	"github.com/jedi4ever/addt/provider"
+)
+
+// Backend carries out the runtime operations the Provider issues.
+//
+// The Provider still describes containers as docker-compatible `run`/`exec`
+// argument vectors (the forwarding helpers all emit CLI flags), so Execute and
+// RunDetached take those vectors. The CLI backend hands them to the runtime
+// binary; provider/engine translates them into Engine API requests.
+type Backend interface {
+	// ImageExists reports whether the image is present locally
+	ImageExists(image string) bool
+	// ImageLabel returns a label value from the image, or "" if unset
+	ImageLabel(image, label string) string
+	// FindImageByLabel returns the first "repo:tag" carrying label=value
+	FindImageByLabel(label, value string) string
+	// RemoveImage removes an image
+	RemoveImage(image string) error
+	// TagImage adds target as a tag of source
+	TagImage(source, target string) error
+	// BuildImage builds an image, showing progress unless req.Quiet is set
+	BuildImage(req BuildRequest) error
+	// ListImages lists images whose repository starts with prefix
+	ListImages(prefix string) ([]ImageSummary, error)
+	// RunOutput runs a throwaway container with the given entrypoint and
+	// returns its stdout
+	RunOutput(image, entrypoint string, args ...string) ([]byte, error)
+
+	// ContainerExists reports whether a container exists (running or stopped)
+	ContainerExists(name string) bool
+	// ContainerRunning reports whether a container is running
+	ContainerRunning(name string) bool
+	// StartContainer starts a stopped container
+	StartContainer(name string) error
+	// StopContainer stops a running container
+	StopContainer(name string) error
+	// RemoveContainer force-removes a container
+	RemoveContainer(name string) error
+	// ListContainers lists containers whose name starts with prefix
+	ListContainers(prefix string) ([]provider.Environment, error)
+	// ContainerLogs returns the combined output of a container
+	ContainerLogs(name string) ([]byte, error)
+	// ExecInput runs cmd in a running container with input on stdin
+	ExecInput(name string, input []byte, cmd ...string) error
+	// CopyToContainer writes data to path inside the container with mode 0644
+	CopyToContainer(name, path string, data []byte) error
+	// InspectContainer describes a container
+	InspectContainer(name string) (*provider.EnvironmentInfo, error)
+	// StreamLogs copies the container's output to out, following new
+	// output when follow is set
+	StreamLogs(name string, follow bool, out io.Writer) error
+	// Exec runs cmd in a running container connected to stdio, allocating
+	// a TTY when tty is set
+	Exec(name string, cmd []string, tty bool, stdio provider.Stdio) error
+	// CopyIn copies a host file or directory into the container
+	CopyIn(name, hostPath, containerPath string) error
+	// CopyOut copies a container file or directory to the host
+	CopyOut(name, containerPath, hostPath string) error
+	// CommitContainer saves a container's filesystem as image with labels
+	CommitContainer(name, image string, labels map[string]string) error
+	// CheckpointContainer saves a running container, processes included,
+	// as a checkpoint image and leaves it running
+	CheckpointContainer(name, image string) error
+	// RestoreCheckpoint creates container name from a checkpoint image
+	RestoreCheckpoint(name, image string) error
+
+	// RunDetached creates and starts a container from `run` arguments
+	// (the argument vector includes -d)
+	RunDetached(args []string) error
+	// Execute runs a `run` or `exec` argument vector attached to the terminal
+	Execute(args []string) error
+}
+
+// ImageSummary describes a local image. Ref is "repo:tag" without the
+// registry prefix the runtime may add to local images.
+type ImageSummary struct {
+	Ref        string
+	Created    time.Time
+	Labels     map[string]string
+	Checkpoint bool // a CRIU checkpoint image
+}
+
+// BuildRequest describes an image build
+type BuildRequest struct {
+	ContextDir string
+	Dockerfile string // path to the Dockerfile on the host
+	Tag        string
+	BuildArgs  []string // KEY=VALUE pairs
+	NoCache    bool
+	Quiet      bool // no progress output (used for the label layer)
+}
@@ -0,0 +1,419 @@
+package ocicli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/util"
+)
+
+// cliBackend implements Backend by shelling out to the runtime CLI
+type cliBackend struct {
+	rt     Runtime
+	logger *util.ModuleLogger
+}
+
+// newCLIBackend creates the default backend for rt
+func newCLIBackend(rt Runtime) *cliBackend {
+	return &cliBackend{rt: rt, logger: util.Log(rt.Name)}
+}
+
+// cmd creates an exec.Cmd for the runtime CLI, targeting the runtime's
+// Docker context when it has one.
+func (b *cliBackend) cmd(args ...string) *exec.Cmd {
+	cmd := exec.Command(b.rt.Binary, args...)
+	if b.rt.Context != "" {
+		cmd.Env = b.env()
+	}
+	return cmd
+}
+
+// env returns the environment slice for runtime CLI commands.
+func (b *cliBackend) env() []string {
+	if b.rt.Context == "" {
+		return os.Environ()
+	}
+	return append(os.Environ(), "DOCKER_CONTEXT="+b.rt.Context)
+}
+
+func (b *cliBackend) ImageExists(image string) bool {
+	return b.cmd("image", "inspect", image).Run() == nil
+}
+
+func (b *cliBackend) ImageLabel(image, label string) string {
+	cmd := b.cmd("inspect",
+		"--format", fmt.Sprintf("{{index .Config.Labels %q}}", label),
+		image)
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func (b *cliBackend) FindImageByLabel(label, value string) string {
+	cmd := b.cmd("images",
+		"--filter", fmt.Sprintf("label=%s=%s", label, value),
+		"--format", "{{.Repository}}:{{.Tag}}")
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	for _, line := range lines {
+		if line != "" && !strings.Contains(line, "<none>") {
+			return line
+		}
+	}
+	return ""
+}
+
+func (b *cliBackend) ListImages(prefix string) ([]ImageSummary, error) {
+	output, err := b.cmd("images", "--format", "{{.Repository}}:{{.Tag}}").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	var images []ImageSummary
+	for _, ref := range matchImageRefs(string(output), prefix) {
+		data, err := b.cmd("image", "inspect", ref).Output()
+		if err != nil {
+			continue // removed in the meantime
+		}
+		image, err := parseImageInspect(ref, data)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, *image)
+	}
+	return images, nil
+}
+
+func (b *cliBackend) RemoveImage(image string) error {
+	return b.cmd("rmi", image).Run()
+}
+
+func (b *cliBackend) TagImage(source, target string) error {
+	return b.cmd("tag", source, target).Run()
+}
+
+func (b *cliBackend) BuildImage(req BuildRequest) error {
+	args := []string{"build"}
+	if req.NoCache {
+		args = append(args, "--no-cache")
+	}
+	for _, arg := range req.BuildArgs {
+		args = append(args, "--build-arg", arg)
+	}
+	args = append(args, "-t", req.Tag, "-f", req.Dockerfile, req.ContextDir)
+
+	if req.Quiet {
+		return b.cmd(args...).Run()
+	}
+	// Run build with progress indication (using the runtime's context, if any)
+	return util.RunBuildCommandWithEnv(b.rt.Binary, args, b.env())
+}
+
+func (b *cliBackend) RunOutput(image, entrypoint string, args ...string) ([]byte, error) {
+	runArgs := append([]string{"run", "--rm", "--entrypoint", entrypoint, image}, args...)
+	return b.cmd(runArgs...).Output()
+}
+
+func (b *cliBackend) ContainerExists(name string) bool {
+	cmd := b.cmd("ps", "-a", "--filter", fmt.Sprintf("name=^%s$", name), "--format", "{{.Names}}")
+	output, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+	return hasLine(output, name)
+}
+
+func (b *cliBackend) ContainerRunning(name string) bool {
+	cmd := b.cmd("ps", "--filter", fmt.Sprintf("name=^%s$", name), "--format", "{{.Names}}")
+	output, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+	return hasLine(output, name)
+}
+
+// hasLine reports whether output contains name as a whole line. Not every
+// CLI treats the name filter as an anchored regex, so the filter only
+// narrows the listing.
+func hasLine(output []byte, name string) bool {
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.TrimSpace(line) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *cliBackend) StartContainer(name string) error {
+	return b.quiet(b.cmd("start", name))
+}
+
+func (b *cliBackend) StopContainer(name string) error {
+	return b.quiet(b.cmd("stop", name))
+}
+
+func (b *cliBackend) RemoveContainer(name string) error {
+	return b.quiet(b.cmd("rm", "-f", name))
+}
+
+// quiet runs cmd, showing its output only in verbose mode
+func (b *cliBackend) quiet(cmd *exec.Cmd) error {
+	if os.Getenv("ADDT_VERBOSE") == "true" {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+	return cmd.Run()
+}
+
+func (b *cliBackend) ListContainers(prefix string) ([]provider.Environment, error) {
+	cmd := b.cmd("ps", "-a", "--filter", "name=^"+prefix,
+		"--format", "{{.Names}}\t{{.Status}}\t{{.CreatedAt}}")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var envs []provider.Environment
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		parts := strings.Split(line, "\t")
+		if len(parts) >= 3 && strings.HasPrefix(parts[0], prefix) {
+			envs = append(envs, provider.Environment{
+				Name:      parts[0],
+				Status:    parts[1],
+				CreatedAt: parts[2],
+			})
+		}
+	}
+	return envs, nil
+}
+
+func (b *cliBackend) ContainerLogs(name string) ([]byte, error) {
+	return b.cmd("logs", name).CombinedOutput()
+}
+
+func (b *cliBackend) ExecInput(name string, input []byte, cmd ...string) error {
+	args := append([]string{"exec", "-i", name}, cmd...)
+	c := b.cmd(args...)
+	c.Stdin = strings.NewReader(string(input))
+	if output, err := c.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s exec failed: %w\n%s", b.rt.Binary, err, string(output))
+	}
+	return nil
+}
+
+func (b *cliBackend) CopyToContainer(name, path string, data []byte) error {
+	// Write data to a temp file
+	tmpFile, err := os.CreateTemp("", "addt-cp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer util.ScrubAndRemove(tmpPath)
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	tmpFile.Close()
+
+	// cp preserves permissions inside the container
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+
+	cmd := b.cmd("cp", tmpPath, name+":"+path)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s cp failed: %w\n%s", b.rt.Binary, err, string(output))
+	}
+	return nil
+}
+
+func (b *cliBackend) InspectContainer(name string) (*provider.EnvironmentInfo, error) {
+	output, err := b.cmd("container", "inspect", name).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s container inspect %s failed: %w", b.rt.Binary, name, err)
+	}
+	return parseInspect(output)
+}
+
+func (b *cliBackend) StreamLogs(name string, follow bool, out io.Writer) error {
+	args := []string{"logs"}
+	if follow {
+		args = append(args, "--follow")
+	}
+	cmd := b.cmd(append(args, name)...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	return cmd.Run()
+}
+
+func (b *cliBackend) Exec(name string, command []string, tty bool, stdio provider.Stdio) error {
+	cmd := b.cmd(execArgs(name, command, tty)...)
+	cmd.Stdin = stdio.Stdin
+	cmd.Stdout = stdio.Stdout
+	cmd.Stderr = stdio.Stderr
+	b.logger.Debugf("Executing: %s %v", b.rt.Binary, cmd.Args[1:])
+	return cmd.Run()
+}
+
+// execArgs returns the `exec` argument vector for running command in a
+// container with stdin attached
+func execArgs(name string, command []string, tty bool) []string {
+	args := []string{"exec", "-i"}
+	if tty {
+		args = append(args, "-t")
+	}
+	return append(append(args, name), command...)
+}
+
+func (b *cliBackend) CopyIn(name, hostPath, containerPath string) error {
+	if output, err := b.cmd("cp", hostPath, name+":"+containerPath).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s cp failed: %w\n%s", b.rt.Binary, err, string(output))
+	}
+	return nil
+}
+
+func (b *cliBackend) CopyOut(name, containerPath, hostPath string) error {
+	// Stream the archive rather than letting the CLI write to the host, so
+	// the extraction is guarded against hostile paths and symlinks
+	cmd := b.cmd("cp", name+":"+containerPath, "-")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	extractErr := util.UntarPath(stdout, hostPath)
+	io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("%s cp failed: %w\n%s", b.rt.Binary, err, stderr.String())
+	}
+	return extractErr
+}
+
+func (b *cliBackend) CommitContainer(name, image string, labels map[string]string) error {
+	if b.rt.CommitWithoutLabels {
+		labels = nil
+	}
+	output, err := b.cmd(commitArgs(name, image, labels)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s commit failed: %w\n%s", b.rt.Binary, err, string(output))
+	}
+	return nil
+}
+
+// commitArgs returns the arguments committing container name as image,
+// with labels added through LABEL changes
+func commitArgs(name, image string, labels map[string]string) []string {
+	args := []string{"commit"}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--change", fmt.Sprintf("LABEL %s=%q", key, labels[key]))
+	}
+	return append(args, name, image)
+}
+
+func (b *cliBackend) CheckpointContainer(name, image string) error {
+	if !b.rt.Checkpoint {
+		return fmt.Errorf("%s checkpoint: %w", b.rt.Name, provider.ErrNotSupported)
+	}
+	output, err := b.cmd("container", "checkpoint", "--leave-running", "--create-image", image, name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s checkpoint failed: %w\n%s", b.rt.Binary, err, string(output))
+	}
+	return nil
+}
+
+func (b *cliBackend) RestoreCheckpoint(name, image string) error {
+	if !b.rt.Checkpoint {
+		return fmt.Errorf("%s restore: %w", b.rt.Name, provider.ErrNotSupported)
+	}
+	output, err := b.cmd("container", "restore", "--name", name, image).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s restore failed: %w\n%s", b.rt.Binary, err, string(output))
+	}
+	return nil
+}
+
+func (b *cliBackend) RunDetached(args []string) error {
+	output, err := b.cmd(args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, string(output))
+	}
+	return nil
+}
+
+func (b *cliBackend) Execute(cliArgs []string) error {
+	b.logger.Debugf("Executing: %s %v", b.rt.Binary, cliArgs)
+	cmd := b.cmd(cliArgs...)
+
+	// Check if -it flag is present (fully interactive mode)
+	hasItFlag := false
+	hasIFlag := false
+	isAttach := false
+	for _, arg := range cliArgs {
+		if arg == "-it" {
+			hasItFlag = true
+			break
+		}
+		if arg == "-i" {
+			hasIFlag = true
+		}
+		if arg == "attach" {
+			isAttach = true
+			b.logger.Debug("Detected attach command")
+		}
+	}
+	b.logger.Debugf("Flag check: hasItFlag=%v, hasIFlag=%v, isAttach=%v", hasItFlag, hasIFlag, isAttach)
+
+	if hasItFlag {
+		// Fully interactive: connect to terminal stdin
+		cmd.Stdin = os.Stdin
+		b.logger.Debug("Connecting stdin to terminal (interactive mode with -it)")
+	} else if hasIFlag {
+		// Has -i but not -it: still connect to terminal stdin for interactive commands
+		// This allows commands like "addt run claude" to receive input
+		cmd.Stdin = os.Stdin
+		b.logger.Debug("Connecting stdin to terminal (interactive mode with -i)")
+	} else if isAttach {
+		// Attach command: connect stdin (container was started with -i, so attach inherits it)
+		cmd.Stdin = os.Stdin
+		b.logger.Debug("Connecting stdin to terminal (attach command)")
+	} else {
+		// No -i flag: don't connect stdin
+		cmd.Stdin = nil
+		b.logger.Debug("Not connecting stdin (no -i flag)")
+	}
+
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	b.logger.Debug("Starting command execution")
+	err := cmd.Run()
+	if err != nil {
+		b.logger.Debugf("Command failed: %v", err)
+	} else {
+		b.logger.Debug("Command completed successfully")
+	}
+	return err
+}
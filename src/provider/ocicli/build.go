@@ -1,4 +1,4 @@
-package docker
+package ocicli
 
 import (
 	"fmt"
@@ -9,22 +9,21 @@ import (
 	"github.com/jedi4ever/addt/util"
 )
 
-// BuildIfNeeded ensures the Docker image is ready
-func (p *DockerProvider) BuildIfNeeded(rebuild bool, rebuildBase bool) error {
+// BuildIfNeeded ensures the image is ready
+func (p *Provider) BuildIfNeeded(rebuild bool, rebuildBase bool) error {
 	// Handle --addt-rebuild-base flag - rebuild base image first
 	if rebuildBase {
 		baseImageName := p.GetBaseImageName()
 		fmt.Printf("Rebuilding base image %s...\n", baseImageName)
 		if p.ImageExists(baseImageName) {
-			cmd := p.dockerCmd("rmi", baseImageName)
-			cmd.Run()
+			p.backend.RemoveImage(baseImageName)
 		}
 		if err := p.BuildBaseImage(); err != nil {
 			return err
 		}
 	}
 
-	logger := util.Log("docker-build")
+	logger := util.Log(p.rt.Name + "-build")
 	logger.Debugf("Checking image: %s", p.config.ImageName)
 	imageExists := p.ImageExists(p.config.ImageName)
 	logger.Debugf("Image exists: %v", imageExists)
@@ -34,8 +33,7 @@ func (p *DockerProvider) BuildIfNeeded(rebuild bool, rebuildBase bool) error {
 		if imageExists {
 			fmt.Printf("Rebuilding %s...\n", p.config.ImageName)
 			fmt.Println("Removing existing image...")
-			cmd := p.dockerCmd("rmi", p.config.ImageName)
-			cmd.Run()
+			p.backend.RemoveImage(p.config.ImageName)
 		}
 		return p.BuildImage(p.embeddedDockerfile, p.embeddedEntrypoint)
 	}
@@ -49,8 +47,8 @@ func (p *DockerProvider) BuildIfNeeded(rebuild bool, rebuildBase bool) error {
 	return nil
 }
 
-// DetermineImageName determines the appropriate Docker image name based on installed extensions
-func (p *DockerProvider) DetermineImageName() string {
+// DetermineImageName determines the appropriate image name based on installed extensions
+func (p *Provider) DetermineImageName() string {
 	// Parse extensions list (comma-separated)
 	extensions := strings.Split(p.config.Extensions, ",")
 	for i := range extensions {
@@ -103,13 +101,13 @@ func (p *DockerProvider) DetermineImageName() string {
 	baseHash := p.assetsHash()
 	extHash := p.extAssetsHash()
 	imageName := fmt.Sprintf("addt:v%s_%s-%s-%s", p.config.AddtVersion, tag, baseHash, extHash)
-	logger := util.Log("docker-build")
+	logger := util.Log(p.rt.Name + "-build")
 	logger.Debugf("assetsHash=%s extAssetsHash=%s imageName=%s", baseHash, extHash, imageName)
 	return imageName
 }
 
 // resolveExtensionVersion resolves the version for an extension, handling dist-tags
-func (p *DockerProvider) resolveExtensionVersion(extName string) string {
+func (p *Provider) resolveExtensionVersion(extName string) string {
 	version := p.getExtensionVersion(extName)
 
 	// For claude extension, handle npm dist-tags (latest, stable, next)
@@ -134,7 +132,7 @@ func (p *DockerProvider) resolveExtensionVersion(extName string) string {
 }
 
 // getExtensionVersion returns the version for an extension, defaulting to "stable" for claude
-func (p *DockerProvider) getExtensionVersion(extName string) string {
+func (p *Provider) getExtensionVersion(extName string) string {
 	if p.config.ExtensionVersions == nil {
 		if extName == "claude" {
 			return "stable"
@@ -151,7 +149,7 @@ func (p *DockerProvider) getExtensionVersion(extName string) string {
 }
 
 // setExtensionVersion sets the version for an extension
-func (p *DockerProvider) setExtensionVersion(extName, version string) {
+func (p *Provider) setExtensionVersion(extName, version string) {
 	if p.config.ExtensionVersions == nil {
 		p.config.ExtensionVersions = make(map[string]string)
 	}
@@ -1,9 +1,10 @@
-package docker
+package ocicli
 
 import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -14,7 +15,7 @@ import (
 //   - "host": Mount host's Docker socket (shares Docker daemon with host)
 //   - "isolated" or "true": Run isolated Docker daemon inside container (requires --privileged)
 //   - "" or other: No Docker forwarding
-func (p *DockerProvider) HandleDockerForwarding(dindMode, containerName string) []string {
+func (p *Provider) HandleDockerForwarding(dindMode, containerName string) []string {
 	switch dindMode {
 	case "host":
 		return p.handleHostDockerForwarding()
@@ -26,7 +27,7 @@ func (p *DockerProvider) HandleDockerForwarding(dindMode, containerName string)
 }
 
 // handleHostDockerForwarding mounts the host's Docker socket into the container
-func (p *DockerProvider) handleHostDockerForwarding() []string {
+func (p *Provider) handleHostDockerForwarding() []string {
 	var args []string
 
 	socketPath := "/var/run/docker.sock"
@@ -45,7 +46,7 @@ func (p *DockerProvider) handleHostDockerForwarding() []string {
 }
 
 // handleIsolatedDockerForwarding configures an isolated Docker daemon inside the container
-func (p *DockerProvider) handleIsolatedDockerForwarding(containerName string) []string {
+func (p *Provider) handleIsolatedDockerForwarding(containerName string) []string {
 	var args []string
 
 	// Isolated mode requires privileged access
@@ -111,3 +112,56 @@ func getDockerSocketGID(socketPath string) int {
 
 	return 0
 }
+
+// HandlePodmanForwarding configures Podman-in-Podman (nested containers) support.
+// Modes:
+//   - "host": Share host's Podman socket (dangerous but useful for some workflows)
+//   - "isolated" or "true": Run isolated nested Podman inside container (requires --privileged)
+//   - "" or other: No Podman forwarding
+func (p *Provider) HandlePodmanForwarding(mode string, containerName string) []string {
+	switch mode {
+	case "isolated", "true":
+		return p.handleIsolatedPodmanForwarding(containerName)
+	case "host":
+		return p.handleHostPodmanForwarding()
+	default:
+		return nil
+	}
+}
+
+// handleIsolatedPodmanForwarding configures an isolated nested Podman inside the container
+func (p *Provider) handleIsolatedPodmanForwarding(containerName string) []string {
+	var args []string
+
+	// Isolated mode requires privileged access for nested user namespaces
+	args = append(args, "--privileged")
+
+	// Use a named volume for Podman container storage persistence
+	volumeName := fmt.Sprintf("addt-podman-%s", containerName)
+	args = append(args, "-v", fmt.Sprintf("%s:/home/addt/.local/share/containers", volumeName))
+
+	// Signal to entrypoint that it should set up nested Podman
+	args = append(args, "-e", "ADDT_DOCKER_DIND_ENABLE=true")
+
+	return args
+}
+
+// handleHostPodmanForwarding shares the host's Podman socket with the container
+func (p *Provider) handleHostPodmanForwarding() []string {
+	var args []string
+
+	podmanSocket := os.Getenv("XDG_RUNTIME_DIR")
+	if podmanSocket == "" {
+		podmanSocket = fmt.Sprintf("/run/user/%d", os.Getuid())
+	}
+	socketPath := filepath.Join(podmanSocket, "podman", "podman.sock")
+
+	if _, err := os.Stat(socketPath); err == nil {
+		args = append(args,
+			"-v", fmt.Sprintf("%s:/run/podman/podman.sock", socketPath),
+			"-e", "DOCKER_HOST=unix:///run/podman/podman.sock",
+		)
+	}
+
+	return args
+}
@@ -0,0 +1,132 @@
+package ocicli
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/jedi4ever/addt/config/security"
+)
+
+// firewallScriptPath is where the image installs init-firewall.sh
+const firewallScriptPath = "/usr/local/bin/init-firewall.sh"
+
+// dnsMinAllowTTL is the shortest time a resolved IP stays allowed, so
+// short-TTL answers don't cost an exec per connection
+const dnsMinAllowTTL = 5 * time.Minute
+
+// startDNSResolver starts the DNS resolver the container's port 53 traffic
+// is redirected to. Without the egress proxy, the addresses allowed names
+// resolve to are added to the container's allowed IP set as they are
+// looked up. Like the proxy, a persistent container keeps its port.
+func (p *Provider) startDNSResolver(name string, persistent bool) error {
+	if !p.firewallEnforced() || p.config.OfflineEnabled || p.dnsResolver != nil {
+		return nil
+	}
+	resolver := security.NewDNSResolver(name, p.config.FirewallRules, p.config.FirewallMode)
+	resolver.Learner = p.firewallLearner()
+	if !p.egressEnabled() {
+		resolver.OnResolve = func(host string, ips []net.IP, ttl time.Duration) {
+			port, layer := p.resolvedRule(host)
+			p.allowResolvedIPs(name, ips, port, layer, ttl)
+		}
+	}
+	port := 0
+	if persistent {
+		port = dnsResolverPort(name)
+	}
+	host := p.helperListenIP()
+	if err := resolver.Start(net.JoinHostPort(host, strconv.Itoa(port))); err != nil {
+		if port == 0 {
+			return fmt.Errorf("failed to start DNS resolver: %w", err)
+		}
+		fmt.Printf("Warning: DNS resolver port %d is taken, %s will need to be recreated to reach it\n", port, name)
+		if err := resolver.Start(net.JoinHostPort(host, "0")); err != nil {
+			return fmt.Errorf("failed to start DNS resolver: %w", err)
+		}
+	}
+	p.dnsResolver = resolver
+	p.dnsAllowed = make(map[string]time.Time)
+	p.logger.Debugf("DNS resolver for %s listening on port %d", name, resolver.Port())
+	return nil
+}
+
+// dnsResolverPort derives a persistent container's resolver port from its
+// name, clear of the egress proxy's range
+func dnsResolverPort(name string) int {
+	return egressProxyPort(name) + 10000
+}
+
+// dnsResolverEnvArgs tells the firewall script where to redirect DNS
+func (p *Provider) dnsResolverEnvArgs() []string {
+	if p.dnsResolver == nil {
+		return nil
+	}
+	return []string{"-e", fmt.Sprintf("ADDT_DNS_RESOLVER=%s:%d", egressProxyHost, p.dnsResolver.Port())}
+}
+
+// resolvedRule returns the only port a resolved name is allowed on, or 0
+// when the rule that allows it covers every port, and the layer of that
+// rule
+func (p *Provider) resolvedRule(host string) (int, string) {
+	rules := p.firewallRules()
+	decision := rules.Decide(host, 0, "")
+	rule, err := security.ParseFirewallRule(decision.Rule)
+	if err != nil {
+		return 0, decision.Layer
+	}
+	return rule.Port, decision.Layer
+}
+
+// allowResolvedIPs adds freshly resolved IPs to the container's allowed IP
+// sets, skipping those still allowed from an earlier lookup. With a port,
+// the IPs are only allowed on that port. The IPs go in the sets of the
+// layer that allowed the name, so the address rules of the layers before
+// it still apply to them.
+func (p *Provider) allowResolvedIPs(container string, ips []net.IP, port int, layer string, ttl time.Duration) {
+	if ttl < dnsMinAllowTTL {
+		ttl = dnsMinAllowTTL
+	}
+	now := time.Now()
+
+	p.dnsMu.Lock()
+	var fresh []string
+	for _, ip := range ips {
+		key := ip.String()
+		if port != 0 {
+			key += "," + strconv.Itoa(port)
+		}
+		key += "@" + layer
+		if expiry, ok := p.dnsAllowed[key]; ok && now.Before(expiry) {
+			continue
+		}
+		p.dnsAllowed[key] = now.Add(ttl)
+		fresh = append(fresh, key)
+	}
+	p.dnsMu.Unlock()
+	if len(fresh) == 0 {
+		return
+	}
+
+	cmd := append([]string{firewallScriptPath, "--allow", strconv.Itoa(int(ttl.Seconds()))}, fresh...)
+	if err := p.backend.ExecInput(container, nil, cmd...); err != nil {
+		p.logger.Debugf("Failed to allow %v in %s: %v", fresh, container, err)
+	}
+}
+
+// stopDNSResolver stops the DNS resolver and reports the lookups it refused
+func (p *Provider) stopDNSResolver() {
+	if p.dnsResolver == nil {
+		return
+	}
+	p.dnsResolver.Stop()
+	if denied := p.dnsResolver.Denied(); len(denied) > 0 {
+		verb := "refused"
+		if p.config.FirewallMode == "permissive" {
+			verb = "would have refused"
+		}
+		fmt.Printf("Firewall %s DNS lookups: %s\n", verb, formatDenied(denied))
+	}
+	p.dnsResolver = nil
+}
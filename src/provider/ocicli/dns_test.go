@@ -0,0 +1,90 @@
+package ocicli
+
+import (
+	"embed"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/provider"
+)
+
+// execRecordingBackend records ExecInput commands; other Backend methods
+// are not used
+type execRecordingBackend struct {
+	Backend
+	execs  [][]string
+	inputs []string
+}
+
+func (b *execRecordingBackend) ExecInput(name string, input []byte, cmd ...string) error {
+	b.execs = append(b.execs, append([]string{name}, cmd...))
+	b.inputs = append(b.inputs, string(input))
+	return nil
+}
+
+func TestAllowResolvedIPs(t *testing.T) {
+	backend := &execRecordingBackend{}
+	p := NewWithBackend(DockerRuntime(""), backend, &provider.Config{}, nil, nil, nil, nil, nil, embed.FS{})
+	p.dnsAllowed = make(map[string]time.Time)
+
+	ips := []net.IP{net.ParseIP("140.82.112.3"), net.ParseIP("2606:50c0:8000::154")}
+	p.allowResolvedIPs("addt-test", ips, 0, "defaults", 30*time.Second)
+	// Already allowed: no second exec
+	p.allowResolvedIPs("addt-test", ips[:1], 0, "defaults", time.Hour)
+	// Allowed on one port only
+	p.allowResolvedIPs("addt-test", ips[:1], 5000, "defaults", time.Hour)
+	// Allowed by another layer's rule
+	p.allowResolvedIPs("addt-test", ips[:1], 0, "project", time.Hour)
+
+	want := [][]string{
+		{"addt-test", firewallScriptPath, "--allow", "300", "140.82.112.3@defaults", "2606:50c0:8000::154@defaults"},
+		{"addt-test", firewallScriptPath, "--allow", "3600", "140.82.112.3,5000@defaults"},
+		{"addt-test", firewallScriptPath, "--allow", "3600", "140.82.112.3@project"},
+	}
+	if !reflect.DeepEqual(backend.execs, want) {
+		t.Errorf("execs = %v, want %v", backend.execs, want)
+	}
+}
+
+func TestResolvedRule(t *testing.T) {
+	cfg := &provider.Config{FirewallRules: security.FirewallRules{
+		Project:  security.FirewallLayer{Allowed: []string{"registry.internal:5000", "*.example.com"}},
+		Defaults: []string{"github.com"},
+	}}
+	p := newTestProvider(DockerRuntime("desktop-linux"), cfg)
+	tests := []struct {
+		host      string
+		wantPort  int
+		wantLayer string
+	}{
+		{"registry.internal", 5000, "project"},
+		{"api.example.com", 0, "project"},
+		{"github.com", 0, "defaults"},
+	}
+	for _, tt := range tests {
+		if port, layer := p.resolvedRule(tt.host); port != tt.wantPort || layer != tt.wantLayer {
+			t.Errorf("resolvedRule(%s) = %d, %s, want %d, %s", tt.host, port, layer, tt.wantPort, tt.wantLayer)
+		}
+	}
+}
+
+func TestDNSResolverEnvArgs(t *testing.T) {
+	cfg := &provider.Config{FirewallEnabled: true, FirewallMode: "strict"}
+	p := newTestProvider(DockerRuntime("desktop-linux"), cfg)
+	if err := p.startDNSResolver("addt-test", false); err != nil {
+		t.Fatalf("startDNSResolver() error = %v", err)
+	}
+	defer p.stopDNSResolver()
+
+	args := p.dnsResolverEnvArgs()
+	if len(args) != 2 || args[0] != "-e" || !strings.HasPrefix(args[1], "ADDT_DNS_RESOLVER=host.docker.internal:") {
+		t.Errorf("dnsResolverEnvArgs() = %v", args)
+	}
+	if port := dnsResolverPort("addt-persistent-x"); port < 50000 || port >= 60000 {
+		t.Errorf("dnsResolverPort() = %d, want 50000-59999", port)
+	}
+}
@@ -0,0 +1,207 @@
+package ocicli
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/config/security"
+)
+
+// egressProxyHost is the name containers reach the host's egress proxy by
+const egressProxyHost = "host.docker.internal"
+
+// egressCAPath is where the egress proxy's CA certificate is mounted
+const egressCAPath = "/usr/local/share/addt/addt-ca.crt"
+
+// firewallEnforced reports whether the firewall is on for a container that
+// has a network
+func (p *Provider) firewallEnforced() bool {
+	cfg := p.config
+	if !cfg.FirewallEnabled || cfg.Security.NetworkMode == "none" {
+		return false
+	}
+	return cfg.FirewallMode != "off" && cfg.FirewallMode != "disabled"
+}
+
+// firewallRulesEnvArgs passes the rules that apply by address or port to
+// the firewall script, which enforces them in the container
+func (p *Provider) firewallRulesEnvArgs() []string {
+	if !p.firewallEnforced() || p.config.OfflineEnabled {
+		return nil
+	}
+	current := p.firewallRules()
+	rules := current.AddressRules()
+	if len(rules) == 0 {
+		return nil
+	}
+	return []string{"-e", "ADDT_FIREWALL_RULES=" + strings.Join(rules, ",")}
+}
+
+// egressEnabled reports whether the container's traffic goes through the
+// host-side egress proxy; offline, it only reaches the package mirror
+func (p *Provider) egressEnabled() bool {
+	return p.firewallEnforced() && p.config.FirewallProxy && !p.config.OfflineEnabled
+}
+
+// startEgressProxy starts the egress proxy for a container. A persistent
+// container gets the same port on every run, since the firewall rules
+// created with it allow only that port; the credentials are renewed.
+func (p *Provider) startEgressProxy(name string, persistent bool) error {
+	if !p.egressEnabled() || p.egressProxy != nil {
+		return nil
+	}
+	proxy, err := security.NewEgressProxy(name, p.config.FirewallRules, p.config.FirewallMode)
+	if err != nil {
+		return err
+	}
+	proxy.Learner = p.firewallLearner()
+	proxy.Quota = p.config.FirewallQuota
+	proxy.OnQuotaExceeded = p.egressQuotaExceeded(name)
+	if p.config.FirewallRules.Intercept {
+		ca, err := security.LoadOrCreateCA(security.CADir())
+		if err != nil {
+			return fmt.Errorf("failed to load egress proxy CA: %w", err)
+		}
+		proxy.CA = ca
+	} else if p.config.FirewallRules.HasRequestRules() {
+		fmt.Println("Warning: firewall request rules are ignored without firewall.intercept")
+	}
+	port := 0
+	if persistent {
+		port = egressProxyPort(name)
+	}
+	host := p.helperListenIP()
+	if err := proxy.Start(net.JoinHostPort(host, strconv.Itoa(port))); err != nil {
+		if port == 0 {
+			return fmt.Errorf("failed to start egress proxy: %w", err)
+		}
+		fmt.Printf("Warning: egress proxy port %d is taken, %s will need to be recreated to reach it\n", port, name)
+		if err := proxy.Start(net.JoinHostPort(host, "0")); err != nil {
+			return fmt.Errorf("failed to start egress proxy: %w", err)
+		}
+	}
+	proxy.RecordUsage(egressUsageInterval)
+	p.egressProxy = proxy
+	p.logger.Debugf("Egress proxy for %s listening on port %d", name, proxy.Port())
+	return nil
+}
+
+// egressUsageInterval is how often a session's network usage is saved for
+// 'addt containers list'
+const egressUsageInterval = 5 * time.Second
+
+// egressQuotaExceeded returns what happens when a container's session goes
+// over its egress quota: with the kill action the container is stopped,
+// otherwise its traffic stays paused until 'addt containers resume' asks
+// for another quota's worth, or the container stops
+func (p *Provider) egressQuotaExceeded(name string) func(string, security.DestinationUsage) bool {
+	return func(reason string, total security.DestinationUsage) bool {
+		// The terminal may be in raw mode for the agent
+		if p.config.FirewallQuotaAction == "kill" {
+			fmt.Fprintf(os.Stderr, "\r\n⚠ %s %s; stopping it\r\n", name, reason)
+			if err := p.backend.StopContainer(name); err != nil {
+				fmt.Fprintf(os.Stderr, "Error stopping %s: %v\r\n", name, err)
+			}
+			return false
+		}
+
+		fmt.Fprintf(os.Stderr, "\r\n⚠ %s %s; its network is paused\r\n", name, reason)
+		fmt.Fprintf(os.Stderr, "  Continue with 'addt containers resume %s', or end the session with 'addt containers stop %s'\r\n", name, name)
+		for {
+			if security.EgressResumeRequested(name) {
+				fmt.Fprintf(os.Stderr, "\r\n✓ %s resumed\r\n", name)
+				return true
+			}
+			if !p.backend.ContainerRunning(name) {
+				return false
+			}
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+// egressProxyPort derives a persistent container's proxy port from its name
+func egressProxyPort(name string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return 40000 + int(h.Sum32()%10000)
+}
+
+// egressProxyEnvArgs points the container's HTTP clients at the egress
+// proxy and tells the firewall script to allow nothing else
+func (p *Provider) egressProxyEnvArgs() []string {
+	if p.egressProxy == nil {
+		return nil
+	}
+	url := p.egressProxy.URL(egressProxyHost)
+	var args []string
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY", "http_proxy", "https_proxy", "all_proxy"} {
+		args = append(args, "-e", name+"="+url)
+	}
+	// Host services are reached directly, through their bridges
+	noProxy := "localhost,127.0.0.1,::1,." + security.HostServiceDomain
+	args = append(args, "-e", "NO_PROXY="+noProxy, "-e", "no_proxy="+noProxy)
+	args = append(args, "-e", fmt.Sprintf("ADDT_EGRESS_PROXY=%s:%d", egressProxyHost, p.egressProxy.Port()))
+	return args
+}
+
+// egressCAArgs mounts the CA of an intercepting egress proxy and points
+// the container's TLS clients at it; the entrypoint adds it to the system
+// trust store, which most other clients use
+func (p *Provider) egressCAArgs() []string {
+	if p.egressProxy == nil || p.egressProxy.CA == nil {
+		return nil
+	}
+	return []string{
+		"-v", p.egressProxy.CA.CertFile() + ":" + egressCAPath + ":ro",
+		"-e", "ADDT_CA_CERT=" + egressCAPath,
+		"-e", "NODE_EXTRA_CA_CERTS=" + egressCAPath,
+		"-e", "REQUESTS_CA_BUNDLE=/etc/ssl/certs/ca-certificates.crt",
+		"-e", "PIP_CERT=/etc/ssl/certs/ca-certificates.crt",
+	}
+}
+
+// stopEgressProxy stops the egress proxy and reports the session's traffic
+// and what it denied. An ephemeral container's usage goes with it.
+func (p *Provider) stopEgressProxy() {
+	if p.egressProxy == nil {
+		return
+	}
+	p.egressProxy.Stop()
+	usage := p.egressProxy.Usage()
+	if len(usage.Destinations) > 0 {
+		fmt.Printf("Network: %s to %d hosts\n", usage.Total(), len(usage.Destinations))
+	}
+	if !p.config.Persistent {
+		security.RemoveNetworkUsage(usage.Container)
+	}
+	if denied := p.egressProxy.Denied(); len(denied) > 0 {
+		verb := "denied"
+		if p.config.FirewallMode == "permissive" {
+			verb = "would have denied"
+		}
+		fmt.Printf("Firewall %s: %s\n", verb, formatDenied(denied))
+	}
+	p.egressProxy = nil
+}
+
+// formatDenied lists denied hosts with their attempts, e.g.
+// "evil.example (3), paste.example (1)"
+func formatDenied(denied map[string]int) string {
+	hosts := make([]string, 0, len(denied))
+	for host := range denied {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	parts := make([]string, len(hosts))
+	for i, host := range hosts {
+		parts[i] = fmt.Sprintf("%s (%d)", host, denied[host])
+	}
+	return strings.Join(parts, ", ")
+}
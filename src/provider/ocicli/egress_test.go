@@ -0,0 +1,109 @@
+package ocicli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/provider"
+)
+
+func TestEgressEnabled(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  provider.Config
+		want bool
+	}{
+		{"firewall off", provider.Config{FirewallProxy: true}, false},
+		{"strict", provider.Config{FirewallEnabled: true, FirewallProxy: true, FirewallMode: "strict"}, true},
+		{"proxy disabled", provider.Config{FirewallEnabled: true, FirewallMode: "strict"}, false},
+		{"mode off", provider.Config{FirewallEnabled: true, FirewallProxy: true, FirewallMode: "off"}, false},
+		{"no network", provider.Config{FirewallEnabled: true, FirewallProxy: true, Security: security.Config{NetworkMode: "none"}}, false},
+	}
+	for _, tt := range tests {
+		cfg := tt.cfg
+		p := newTestProvider(DockerRuntime("desktop-linux"), &cfg)
+		if got := p.egressEnabled(); got != tt.want {
+			t.Errorf("%s: egressEnabled() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEgressProxyEnvArgs(t *testing.T) {
+	cfg := &provider.Config{FirewallEnabled: true, FirewallProxy: true, FirewallMode: "strict"}
+	p := newTestProvider(DockerRuntime("desktop-linux"), cfg)
+	if args := p.egressProxyEnvArgs(); args != nil {
+		t.Errorf("egressProxyEnvArgs() before start = %v, want nil", args)
+	}
+
+	if err := p.startEgressProxy("addt-test", false); err != nil {
+		t.Fatalf("startEgressProxy() error = %v", err)
+	}
+	defer p.stopEgressProxy()
+
+	joined := strings.Join(p.egressProxyEnvArgs(), " ")
+	for _, want := range []string{"HTTPS_PROXY=http://addt:", "https_proxy=http://addt:", "@host.docker.internal:", "NO_PROXY=localhost", "ADDT_EGRESS_PROXY=host.docker.internal:"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("egressProxyEnvArgs() = %s, missing %s", joined, want)
+		}
+	}
+}
+
+func TestEgressCAArgs(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+	cfg := &provider.Config{FirewallEnabled: true, FirewallProxy: true, FirewallMode: "strict"}
+	p := newTestProvider(DockerRuntime("desktop-linux"), cfg)
+	if err := p.startEgressProxy("addt-test", false); err != nil {
+		t.Fatalf("startEgressProxy() error = %v", err)
+	}
+	if args := p.egressCAArgs(); args != nil {
+		t.Errorf("egressCAArgs() without interception = %v, want nil", args)
+	}
+	p.stopEgressProxy()
+
+	cfg.FirewallRules.Intercept = true
+	if err := p.startEgressProxy("addt-test", false); err != nil {
+		t.Fatalf("startEgressProxy() error = %v", err)
+	}
+	defer p.stopEgressProxy()
+	joined := strings.Join(p.egressCAArgs(), " ")
+	for _, want := range []string{"addt-ca.pem:" + egressCAPath + ":ro", "ADDT_CA_CERT=" + egressCAPath, "NODE_EXTRA_CA_CERTS=" + egressCAPath} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("egressCAArgs() = %s, missing %s", joined, want)
+		}
+	}
+}
+
+func TestFirewallRulesEnvArgs(t *testing.T) {
+	cfg := &provider.Config{FirewallEnabled: true, FirewallMode: "strict", FirewallRules: security.FirewallRules{
+		Project: security.FirewallLayer{Allowed: []string{"github.com", "10.0.0.0/8:5432"}},
+		Global:  security.FirewallLayer{Allowed: []string{"!tcp/22"}},
+	}}
+	p := newTestProvider(DockerRuntime("desktop-linux"), cfg)
+	want := []string{"-e", "ADDT_FIREWALL_RULES=project accept - 5432 10.0.0.0/8,global drop tcp 22 -"}
+	if got := p.firewallRulesEnvArgs(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("firewallRulesEnvArgs() = %v, want %v", got, want)
+	}
+
+	cfg.FirewallMode = "off"
+	if got := p.firewallRulesEnvArgs(); got != nil {
+		t.Errorf("firewallRulesEnvArgs() with the firewall off = %v, want nil", got)
+	}
+}
+
+func TestEgressProxyPort(t *testing.T) {
+	port := egressProxyPort("addt-persistent-myproject-1a2b3c4d")
+	if port < 40000 || port >= 50000 {
+		t.Errorf("egressProxyPort() = %d, want 40000-49999", port)
+	}
+	if port != egressProxyPort("addt-persistent-myproject-1a2b3c4d") {
+		t.Error("egressProxyPort() is not stable for the same name")
+	}
+}
+
+func TestFormatDenied(t *testing.T) {
+	got := formatDenied(map[string]int{"paste.example": 1, "evil.example": 3})
+	if want := "evil.example (3), paste.example (1)"; got != want {
+		t.Errorf("formatDenied() = %q, want %q", got, want)
+	}
+}
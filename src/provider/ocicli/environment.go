@@ -0,0 +1,42 @@
+package ocicli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jedi4ever/addt/provider"
+)
+
+// Access to existing containers: exec, logs, copy and inspect
+
+// Exec runs cmd in a running container
+func (p *Provider) Exec(name string, cmd []string, tty bool, stdio provider.Stdio) error {
+	if !p.backend.ContainerRunning(name) {
+		return fmt.Errorf("container %s is not running", name)
+	}
+	return p.backend.Exec(name, cmd, tty, stdio.WithDefaults())
+}
+
+// Logs writes the container's output to out
+func (p *Provider) Logs(name string, follow bool, out io.Writer) error {
+	return p.backend.StreamLogs(name, follow, out)
+}
+
+// CopyTo copies a host file or directory into the container
+func (p *Provider) CopyTo(name, hostPath, envPath string) error {
+	if _, err := os.Stat(hostPath); err != nil {
+		return err
+	}
+	return p.backend.CopyIn(name, hostPath, envPath)
+}
+
+// CopyFrom copies a file or directory from the container to the host
+func (p *Provider) CopyFrom(name, envPath, hostPath string) error {
+	return p.backend.CopyOut(name, envPath, hostPath)
+}
+
+// Inspect describes a container
+func (p *Provider) Inspect(name string) (*provider.EnvironmentInfo, error) {
+	return p.backend.InspectContainer(name)
+}
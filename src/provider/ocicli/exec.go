@@ -0,0 +1,710 @@
+package ocicli
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/jedi4ever/addt/assets"
+	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/util"
+)
+
+// containerContext holds common container setup information
+type containerContext struct {
+	homeDir              string
+	username             string
+	useExistingContainer bool
+}
+
+// setupContainerContext prepares common container context and checks for existing containers
+func (p *Provider) setupContainerContext(spec *provider.RunSpec) (*containerContext, error) {
+	currentUser, err := user.Current()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current user: %w", err)
+	}
+
+	ctx := &containerContext{
+		homeDir:              currentUser.HomeDir,
+		username:             "addt", // Always use "addt" in container, but with host UID/GID
+		useExistingContainer: false,
+	}
+
+	// Check if we should use existing container
+	if spec.Persistent && p.Exists(spec.Name) {
+		fmt.Printf("Found existing persistent container: %s\n", spec.Name)
+		if p.IsRunning(spec.Name) {
+			fmt.Println("Container is running, connecting...")
+			ctx.useExistingContainer = true
+		} else {
+			fmt.Println("Container is stopped, starting...")
+			if err := p.Start(spec.Name); err != nil {
+				fmt.Println("Failed to start container, removing and recreating...")
+				p.Remove(spec.Name)
+				// Fall through to create a new container
+			} else {
+				// Give container a moment to stabilize (entrypoint may exit immediately)
+				time.Sleep(500 * time.Millisecond)
+				if !p.IsRunning(spec.Name) {
+					// Container started but exited immediately (entrypoint finished)
+					fmt.Println("Container exited after start, removing and recreating...")
+					p.Remove(spec.Name)
+					// Fall through to create a new container
+				} else {
+					ctx.useExistingContainer = true
+				}
+			}
+		}
+	} else if spec.Persistent {
+		fmt.Printf("Creating new persistent container: %s\n", spec.Name)
+	}
+
+	return ctx, nil
+}
+
+// buildBaseArgs creates the base runtime CLI arguments for run/exec commands
+func (p *Provider) buildBaseArgs(spec *provider.RunSpec, ctx *containerContext) []string {
+	var cliArgs []string
+
+	if ctx.useExistingContainer {
+		cliArgs = []string{"exec"}
+	} else {
+		if spec.Persistent {
+			cliArgs = []string{"run", "--name", spec.Name}
+		} else {
+			cliArgs = []string{"run", "--rm", "--name", spec.Name}
+		}
+	}
+
+	// Interactive mode
+	if spec.Interactive {
+		cliArgs = append(cliArgs, "-it")
+		if !ctx.useExistingContainer && p.rt.initAvailable() {
+			cliArgs = append(cliArgs, "--init")
+		}
+	} else {
+		cliArgs = append(cliArgs, "-i")
+	}
+
+	return cliArgs
+}
+
+// addContainerVolumesAndEnv adds volumes, mounts, and environment variables for new containers
+func (p *Provider) addContainerVolumesAndEnv(cliArgs []string, spec *provider.RunSpec, ctx *containerContext) ([]string, func()) {
+	// Cleanup function for secrets directory (caller should defer this)
+	cleanup := func() {}
+	// Add volumes
+	for _, vol := range spec.Volumes {
+		mount := fmt.Sprintf("%s:%s", vol.Source, vol.Target)
+		if vol.ReadOnly {
+			mount += ":ro"
+		}
+		cliArgs = append(cliArgs, "-v", mount)
+	}
+
+	// Add extension mounts
+	cliArgs = p.AddExtensionMounts(cliArgs, spec.ImageName, ctx.homeDir)
+
+	// Mount .gitconfig (if forwarding enabled)
+	if p.config.GitForwardConfig {
+		gitconfigPath := p.config.GitConfigPath
+		if gitconfigPath == "" {
+			gitconfigPath = filepath.Join(ctx.homeDir, ".gitconfig")
+		} else {
+			gitconfigPath = util.ExpandTilde(gitconfigPath)
+		}
+		if _, err := os.Stat(gitconfigPath); err == nil {
+			cliArgs = append(cliArgs, "-v", fmt.Sprintf("%s:/home/%s/.gitconfig.host:ro", gitconfigPath, ctx.username))
+		}
+	}
+
+	// Note: Claude config mounts (~/.claude, ~/.claude.json) are now handled
+	// by the claude extension via AddExtensionMounts above.
+	// Use ADDT_MOUNT_CLAUDE_CONFIG=false to disable them.
+
+	// Env file vars are loaded into spec.Env by BuildRunOptions (see core/options.go)
+	// so they go through the same -e mechanism as other env vars.
+
+	// SSH forwarding
+	sshDir := p.config.SSHDir
+	if sshDir == "" {
+		sshDir = filepath.Join(ctx.homeDir, ".ssh")
+	} else {
+		sshDir = util.ExpandTilde(sshDir)
+	}
+	cliArgs = append(cliArgs, p.HandleSSHForwarding(spec.SSHForwardKeys, spec.SSHForwardMode, sshDir, ctx.username, spec.SSHAllowedKeys)...)
+
+	// GPG forwarding
+	gpgDir := p.config.GPGDir
+	if gpgDir == "" {
+		gpgDir = filepath.Join(ctx.homeDir, ".gnupg")
+	} else {
+		gpgDir = util.ExpandTilde(gpgDir)
+	}
+	cliArgs = append(cliArgs, p.HandleGPGForwarding(spec.GPGForward, gpgDir, ctx.username, spec.GPGAllowedKeyIDs)...)
+
+	// Tmux forwarding
+	cliArgs = append(cliArgs, p.HandleTmuxForwarding(spec.TmuxForward)...)
+
+	// History persistence
+	cliArgs = append(cliArgs, p.HandleHistoryPersist(spec.HistoryPersist, spec.WorkDir, ctx.username)...)
+
+	// Firewall configuration
+	hostGateway := false
+	if p.config.FirewallEnabled {
+		// Start as root so entrypoint can apply iptables rules without sudo,
+		// then drop to addt via gosu (compatible with no-new-privileges)
+		cliArgs = append(cliArgs, "--user", "root")
+
+		// Use pasta network backend for better firewall support in rootless mode
+		// pasta handles network namespaces efficiently and supports filtering
+		if p.rt.pastaAvailable() {
+			cliArgs = append(cliArgs, "--network=pasta")
+		}
+
+		// Capabilities for the root phase (dropped after gosu switches to addt):
+		// NET_ADMIN: iptables/nftables rules
+		// DAC_OVERRIDE: create dirs/files in addt's home
+		// CHOWN: fix file ownership
+		// SETUID/SETGID: gosu needs these to switch from root to addt
+		cliArgs = append(cliArgs, "--cap-add", "NET_ADMIN")
+		cliArgs = append(cliArgs, "--cap-add", "DAC_OVERRIDE")
+		cliArgs = append(cliArgs, "--cap-add", "CHOWN")
+		cliArgs = append(cliArgs, "--cap-add", "SETUID")
+		cliArgs = append(cliArgs, "--cap-add", "SETGID")
+
+		// Mount firewall config directory
+		addtHome := util.GetAddtHome()
+		if addtHome != "" {
+			firewallConfigDir := filepath.Join(addtHome, "firewall")
+			if _, err := os.Stat(firewallConfigDir); err == nil {
+				cliArgs = append(cliArgs, "-v", fmt.Sprintf("%s:/home/%s/.addt/firewall", firewallConfigDir, ctx.username))
+			}
+		}
+
+		// Route traffic through the host-side egress proxy and DNS through
+		// the host-side resolver, which the firewall script then allows as
+		// the only destinations
+		if p.egressProxy != nil || p.dnsResolver != nil {
+			cliArgs = append(cliArgs, p.hostGatewayArgs()...)
+			hostGateway = true
+			cliArgs = append(cliArgs, p.egressProxyEnvArgs()...)
+			cliArgs = append(cliArgs, p.egressCAArgs()...)
+			cliArgs = append(cliArgs, p.dnsResolverEnvArgs()...)
+		}
+		cliArgs = append(cliArgs, p.firewallRulesEnvArgs()...)
+	}
+
+	// Nested container support (DinD or Podman-in-Podman)
+	if p.rt.NestedRuntime == "podman" {
+		cliArgs = append(cliArgs, p.HandlePodmanForwarding(spec.DockerDindMode, spec.Name)...)
+	} else {
+		cliArgs = append(cliArgs, p.HandleDockerForwarding(spec.DockerDindMode, spec.Name)...)
+	}
+
+	// Start as root for DinD so entrypoint can start the nested runtime without sudo
+	if spec.DockerDindMode == "isolated" || spec.DockerDindMode == "true" {
+		cliArgs = append(cliArgs, "--user", "root")
+	}
+
+	// Add ports (bind to localhost only to avoid exposing dev ports to the network)
+	for _, port := range spec.Ports {
+		cliArgs = append(cliArgs, "-p", fmt.Sprintf("127.0.0.1:%d:%d", port.Host, port.Container))
+	}
+
+	// Handle isolate_secrets: add tmpfs mount for secrets
+	// Secrets are written into the tmpfs after the container starts (see runWithSecrets)
+	if p.config.Security.IsolateSecrets {
+		cliArgs = p.addTmpfsSecretsMount(cliArgs)
+	}
+
+	// Handle OTEL, host services, the package mirror, the API proxy and the
+	// git credential helper: add host alias so container can reach host's
+	// OTEL collector, the host service forwarder, the mirror (unless its
+	// socket is mounted), the API proxy and the credential helper
+	mirrorOverTCP := p.packageMirror != nil && p.mirrorSocketDir == ""
+	hostSide := p.hostServices != nil || mirrorOverTCP || p.apiProxy != nil || p.gitCredentials != nil
+	if (p.config.Otel.Enabled || hostSide) && !hostGateway {
+		cliArgs = append(cliArgs, p.hostGatewayArgs()...)
+	}
+	cliArgs = append(cliArgs, p.hostServiceHostArgs()...)
+	cliArgs = append(cliArgs, p.hostServiceEnvArgs()...)
+	cliArgs = append(cliArgs, p.packageMirrorMountArgs()...)
+	cliArgs = append(cliArgs, p.packageMirrorEnvArgs()...)
+	cliArgs = append(cliArgs, p.apiProxyEnvArgs()...)
+	cliArgs = append(cliArgs, p.gitCredentialEnvArgs()...)
+
+	// Add environment variables
+	for k, v := range spec.Env {
+		cliArgs = append(cliArgs, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	// Add resource limits
+	if spec.ContainerCPUs != "" {
+		cliArgs = append(cliArgs, "--cpus", spec.ContainerCPUs)
+	}
+	if spec.ContainerMemory != "" {
+		cliArgs = append(cliArgs, "--memory", spec.ContainerMemory)
+	}
+
+	// Add security settings
+	cliArgs = p.addSecuritySettings(cliArgs)
+
+	return cliArgs, cleanup
+}
+
+// executeCommand runs a run/exec argument vector attached to the terminal
+func (p *Provider) executeCommand(cliArgs []string) error {
+	return p.backend.Execute(cliArgs)
+}
+
+// Run runs a new container
+func (p *Provider) Run(spec *provider.RunSpec) error {
+	ctx, err := p.setupContainerContext(spec)
+	if err != nil {
+		return err
+	}
+	if err := p.startEgressProxy(spec.Name, spec.Persistent); err != nil {
+		return err
+	}
+	if err := p.startDNSResolver(spec.Name, spec.Persistent); err != nil {
+		return err
+	}
+	if err := p.startHostServices(spec.Name, spec.Persistent); err != nil {
+		return err
+	}
+	if err := p.startPackageMirror(spec.Name, spec.Persistent); err != nil {
+		return err
+	}
+	if err := p.startAPIProxy(spec); err != nil {
+		return err
+	}
+	if err := p.startGitCredentials(spec); err != nil {
+		return err
+	}
+	if err := p.startSignApproval(spec.Name); err != nil {
+		return err
+	}
+	p.watchFirewallReload(spec.Name)
+
+	// Prepare secrets if enabled (before building args so we can filter env)
+	var secretsJSON string
+	if p.config.Security.IsolateSecrets && !ctx.useExistingContainer {
+		json, secretVarNames, err := p.prepareSecretsJSON(spec.ImageName, spec.Env)
+		if err == nil && json != "" {
+			secretsJSON = json
+			p.filterSecretEnvVars(spec.Env, secretVarNames)
+			// ADDT_CREDENTIAL_VARS is no longer needed — secrets are in the file
+			delete(spec.Env, "ADDT_CREDENTIAL_VARS")
+		}
+	}
+
+	cliArgs := p.buildBaseArgs(spec, ctx)
+
+	// Only add volumes and environment when creating a new container
+	cleanup := func() {}
+	if !ctx.useExistingContainer {
+		cliArgs, cleanup = p.addContainerVolumesAndEnv(cliArgs, spec, ctx)
+	}
+	defer cleanup()
+
+	// Handle existing container (with fresh egress proxy and resolver settings)
+	if ctx.useExistingContainer {
+		cliArgs = append(cliArgs, p.egressProxyEnvArgs()...)
+		cliArgs = append(cliArgs, p.dnsResolverEnvArgs()...)
+		cliArgs = append(cliArgs, p.hostServiceEnvArgs()...)
+		cliArgs = append(cliArgs, p.packageMirrorEnvArgs()...)
+		cliArgs = append(cliArgs, p.apiProxyEnvArgs()...)
+		cliArgs = append(cliArgs, p.gitCredentialEnvArgs()...)
+		cliArgs = append(cliArgs, spec.Name)
+		cliArgs = append(cliArgs, p.rt.EntrypointPath)
+		cliArgs = append(cliArgs, spec.Args...)
+		return p.executeCommand(cliArgs)
+	}
+
+	// New persistent container: detached keep-alive + exec entrypoint
+	if spec.Persistent {
+		return p.runPersistent(cliArgs, spec, secretsJSON)
+	}
+
+	// New container with secrets: use two-step process
+	// 1. Start container detached with wait script
+	// 2. Copy secrets into the tmpfs
+	// 3. Signal container to continue and attach
+	if secretsJSON != "" {
+		return p.runWithSecrets(cliArgs, spec, secretsJSON)
+	}
+
+	// Normal run without secrets
+	cliArgs = append(cliArgs, spec.ImageName)
+	cliArgs = append(cliArgs, spec.Args...)
+	return p.executeCommand(cliArgs)
+}
+
+// runPersistent creates a persistent container with sleep infinity as PID 1,
+// then execs the entrypoint. This ensures the container stays alive after
+// the agent exits, so subsequent runs can reuse it via exec.
+func (p *Provider) runPersistent(baseArgs []string, spec *provider.RunSpec, secretsJSON string) error {
+	// Strip interactive/init flags — not needed for detached sleep process
+	var runArgs []string
+	needsTTY := false
+	needsStdin := false
+	for _, arg := range baseArgs {
+		switch arg {
+		case "-it":
+			needsTTY = true
+			needsStdin = true
+		case "-i":
+			needsStdin = true
+		case "-t", "--init":
+			// not needed for detached sleep process
+		default:
+			runArgs = append(runArgs, arg)
+		}
+	}
+
+	// Start container detached with sleep as keep-alive PID 1
+	image := p.persistentImage(spec)
+	runArgs = append(runArgs, "-d", "--entrypoint", "sleep", image, "infinity")
+	p.logger.Debugf("Starting persistent container: %s %v", p.rt.Binary, runArgs)
+
+	if err := p.backend.RunDetached(runArgs); err != nil {
+		return fmt.Errorf("failed to start persistent container: %w", err)
+	}
+	p.clearRestore(spec, image)
+
+	// Copy secrets if needed
+	if secretsJSON != "" {
+		p.logger.Debug("Copying secrets to persistent container")
+		if err := p.copySecretsToContainer(spec.Name, secretsJSON); err != nil {
+			p.logger.Debugf("Failed to copy secrets, cleaning up container %s", spec.Name)
+			p.backend.RemoveContainer(spec.Name)
+			return fmt.Errorf("failed to copy secrets: %w", err)
+		}
+	}
+
+	execArgs := p.entrypointExecArgs()
+	if needsTTY {
+		execArgs = append(execArgs, "-it")
+	} else if needsStdin {
+		execArgs = append(execArgs, "-i")
+	}
+	execArgs = append(execArgs, spec.Name, p.rt.EntrypointPath)
+	execArgs = append(execArgs, spec.Args...)
+
+	p.logger.Debugf("Executing entrypoint in persistent container: %s %v", p.rt.Binary, execArgs)
+	return p.executeCommand(execArgs)
+}
+
+// runWithSecrets starts a container, copies secrets, then execs the entrypoint.
+// Uses a simple approach: start with sleep, copy secrets, exec entrypoint.
+// Entrypoint output goes directly to terminal via exec (no attach needed).
+func (p *Provider) runWithSecrets(baseArgs []string, spec *provider.RunSpec, secretsJSON string) error {
+	// Strip interactive flags from run args — they'll be added to exec instead.
+	// The detached sleep process doesn't need stdin or TTY.
+	var runArgs []string
+	needsTTY := false
+	needsStdin := false
+	for _, arg := range baseArgs {
+		switch arg {
+		case "-it":
+			needsTTY = true
+			needsStdin = true
+		case "-i":
+			needsStdin = true
+		case "-t", "--init":
+			// not needed for detached sleep process
+		default:
+			runArgs = append(runArgs, arg)
+		}
+	}
+
+	// Start container detached with sleep as keep-alive
+	runArgs = append(runArgs, "-d", "--entrypoint", "sleep", spec.ImageName, "infinity")
+	p.logger.Debugf("Starting detached container: %s %v", p.rt.Binary, runArgs)
+
+	if err := p.backend.RunDetached(runArgs); err != nil {
+		return fmt.Errorf("failed to start container: %w", err)
+	}
+
+	// Copy secrets to container tmpfs
+	p.logger.Debug("Copying secrets to container")
+	if err := p.copySecretsToContainer(spec.Name, secretsJSON); err != nil {
+		p.logger.Debugf("Failed to copy secrets, cleaning up container %s", spec.Name)
+		p.backend.RemoveContainer(spec.Name)
+		return fmt.Errorf("failed to copy secrets: %w", err)
+	}
+
+	execArgs := p.entrypointExecArgs()
+	if needsTTY {
+		execArgs = append(execArgs, "-it")
+	} else if needsStdin {
+		execArgs = append(execArgs, "-i")
+	}
+	execArgs = append(execArgs, spec.Name, p.rt.EntrypointPath)
+	execArgs = append(execArgs, spec.Args...)
+
+	p.logger.Debugf("Executing entrypoint: %s %v", p.rt.Binary, execArgs)
+	execErr := p.executeCommand(execArgs)
+
+	// On failure, dump container logs for debugging
+	if execErr != nil {
+		p.logger.Debugf("Entrypoint failed, fetching container logs for %s", spec.Name)
+		if logsOutput, err := p.backend.ContainerLogs(spec.Name); err == nil && len(logsOutput) > 0 {
+			p.logger.Debugf("Container logs:\n%s", string(logsOutput))
+		}
+	}
+
+	// Clean up non-persistent containers (stop sleep, triggers --rm if set)
+	if !spec.Persistent {
+		p.logger.Debugf("Removing non-persistent container %s", spec.Name)
+		p.backend.RemoveContainer(spec.Name)
+	}
+
+	return execErr
+}
+
+// Shell opens a shell in a container
+func (p *Provider) Shell(spec *provider.RunSpec) error {
+	ctx, err := p.setupContainerContext(spec)
+	if err != nil {
+		return err
+	}
+	if err := p.startEgressProxy(spec.Name, spec.Persistent); err != nil {
+		return err
+	}
+	if err := p.startDNSResolver(spec.Name, spec.Persistent); err != nil {
+		return err
+	}
+	if err := p.startHostServices(spec.Name, spec.Persistent); err != nil {
+		return err
+	}
+	if err := p.startPackageMirror(spec.Name, spec.Persistent); err != nil {
+		return err
+	}
+	if err := p.startAPIProxy(spec); err != nil {
+		return err
+	}
+	if err := p.startGitCredentials(spec); err != nil {
+		return err
+	}
+	if err := p.startSignApproval(spec.Name); err != nil {
+		return err
+	}
+	p.watchFirewallReload(spec.Name)
+
+	cliArgs := p.buildBaseArgs(spec, ctx)
+
+	// Only add volumes and environment when creating a new container
+	cleanup := func() {}
+	if !ctx.useExistingContainer {
+		cliArgs, cleanup = p.addContainerVolumesAndEnv(cliArgs, spec, ctx)
+	}
+	defer cleanup()
+
+	// Open shell
+	fmt.Println("Opening bash shell in container...")
+	if ctx.useExistingContainer {
+		// Run through entrypoint so init (socat, firewall, DinD) works
+		cliArgs = append(cliArgs, "-e", "ADDT_COMMAND=/bin/bash")
+		cliArgs = append(cliArgs, p.egressProxyEnvArgs()...)
+		cliArgs = append(cliArgs, p.dnsResolverEnvArgs()...)
+		cliArgs = append(cliArgs, p.hostServiceEnvArgs()...)
+		cliArgs = append(cliArgs, p.packageMirrorEnvArgs()...)
+		cliArgs = append(cliArgs, p.apiProxyEnvArgs()...)
+		cliArgs = append(cliArgs, p.gitCredentialEnvArgs()...)
+		cliArgs = append(cliArgs, spec.Name, p.rt.EntrypointPath)
+		cliArgs = append(cliArgs, spec.Args...)
+	} else if spec.Persistent {
+		return p.shellPersistent(cliArgs, spec, ctx)
+	} else {
+		// Use default entrypoint with ADDT_COMMAND override to bash
+		// The entrypoint handles all initialization: socat bridges, secrets,
+		// firewall, DinD, extensions, and debug logging.
+		cliArgs = append(cliArgs, "-e", "ADDT_COMMAND=/bin/bash")
+		cliArgs = append(cliArgs, spec.ImageName)
+		cliArgs = append(cliArgs, spec.Args...)
+	}
+
+	return p.executeCommand(cliArgs)
+}
+
+// shellPersistent creates a persistent container with sleep infinity as PID 1,
+// then execs the entrypoint with ADDT_COMMAND=/bin/bash for shell access.
+func (p *Provider) shellPersistent(baseArgs []string, spec *provider.RunSpec, ctx *containerContext) error {
+	// Strip interactive/init flags — not needed for detached sleep process
+	var runArgs []string
+	needsTTY := false
+	needsStdin := false
+	for _, arg := range baseArgs {
+		switch arg {
+		case "-it":
+			needsTTY = true
+			needsStdin = true
+		case "-i":
+			needsStdin = true
+		case "-t", "--init":
+			// not needed for detached sleep process
+		default:
+			runArgs = append(runArgs, arg)
+		}
+	}
+
+	// Start container detached with sleep as keep-alive PID 1
+	image := p.persistentImage(spec)
+	runArgs = append(runArgs, "-d", "--entrypoint", "sleep", image, "infinity")
+	p.logger.Debugf("Starting persistent container for shell: %s %v", p.rt.Binary, runArgs)
+
+	if err := p.backend.RunDetached(runArgs); err != nil {
+		return fmt.Errorf("failed to start persistent container: %w", err)
+	}
+	p.clearRestore(spec, image)
+
+	execArgs := p.entrypointExecArgs()
+	if needsTTY {
+		execArgs = append(execArgs, "-it")
+	} else if needsStdin {
+		execArgs = append(execArgs, "-i")
+	}
+	execArgs = append(execArgs, "-e", "ADDT_COMMAND=/bin/bash")
+	execArgs = append(execArgs, spec.Name, p.rt.EntrypointPath)
+	execArgs = append(execArgs, spec.Args...)
+
+	p.logger.Debugf("Executing shell in persistent container: %s %v", p.rt.Binary, execArgs)
+	return p.executeCommand(execArgs)
+}
+
+// entrypointExecArgs returns the exec prefix used to start the entrypoint in a
+// detached keep-alive container. Rootful runtimes exec as root so the root
+// phase (chown secrets, firewall, DinD) runs before dropping to addt via gosu.
+func (p *Provider) entrypointExecArgs() []string {
+	if p.rt.Rootless {
+		return []string{"exec"}
+	}
+	return []string{"exec", "--user", "root"}
+}
+
+// hostGatewayArgs returns the --add-host flag that lets the container reach
+// the host as host.docker.internal
+func (p *Provider) hostGatewayArgs() []string {
+	if !p.rt.DetectHostGateway {
+		return []string{"--add-host=host.docker.internal:host-gateway"}
+	}
+	// host-gateway can fail on macOS (e.g. Podman machine without
+	// host_containers_internal_ip); use the detected host IP instead
+	hostIP, err := getHostGatewayIP()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not detect host IP for host.docker.internal: %v\n", err)
+		return nil
+	}
+	return []string{fmt.Sprintf("--add-host=host.docker.internal:%s", hostIP)}
+}
+
+// addSecuritySettings adds container security hardening options
+func (p *Provider) addSecuritySettings(cliArgs []string) []string {
+	sec := p.config.Security
+
+	// Process limits
+	if sec.PidsLimit > 0 {
+		cliArgs = append(cliArgs, "--pids-limit", fmt.Sprintf("%d", sec.PidsLimit))
+	}
+
+	// Ulimits
+	if sec.UlimitNofile != "" {
+		cliArgs = append(cliArgs, "--ulimit", "nofile="+sec.UlimitNofile)
+	}
+	if sec.UlimitNproc != "" {
+		cliArgs = append(cliArgs, "--ulimit", "nproc="+sec.UlimitNproc)
+	}
+
+	// Privilege escalation prevention
+	if sec.NoNewPrivileges {
+		cliArgs = append(cliArgs, "--security-opt", "no-new-privileges")
+	}
+
+	// Drop capabilities
+	for _, cap := range sec.CapDrop {
+		cliArgs = append(cliArgs, "--cap-drop", cap)
+	}
+
+	// Add capabilities back
+	for _, cap := range sec.CapAdd {
+		cliArgs = append(cliArgs, "--cap-add", cap)
+	}
+
+	// Read-only root filesystem
+	if sec.ReadOnlyRootfs {
+		cliArgs = append(cliArgs, "--read-only")
+		// Add tmpfs mounts for writable directories when using read-only rootfs
+		cliArgs = append(cliArgs, "--tmpfs", fmt.Sprintf("/tmp:rw,noexec,nosuid,size=%s", sec.TmpfsTmpSize))
+		cliArgs = append(cliArgs, "--tmpfs", "/var/tmp:rw,noexec,nosuid,size=128m")
+		// Home dir needs exec (npm installs executables there) and uid/gid
+		// so the non-root container user owns the tmpfs. Rootless runtimes
+		// don't support uid/gid tmpfs params, so use mode=1777 instead.
+		homeOpts := fmt.Sprintf("/home/addt:rw,exec,nosuid,size=%s", sec.TmpfsHomeSize)
+		if p.rt.Rootless {
+			homeOpts = fmt.Sprintf("/home/addt:rw,exec,nosuid,mode=1777,size=%s", sec.TmpfsHomeSize)
+		} else if u, err := user.Current(); err == nil {
+			homeOpts = fmt.Sprintf("/home/addt:rw,exec,nosuid,uid=%s,gid=%s,size=%s", u.Uid, u.Gid, sec.TmpfsHomeSize)
+		}
+		cliArgs = append(cliArgs, "--tmpfs", homeOpts)
+	}
+
+	// Seccomp profile
+	if sec.SeccompProfile != "" {
+		switch sec.SeccompProfile {
+		case "unconfined":
+			cliArgs = append(cliArgs, "--security-opt", "seccomp=unconfined")
+		case "restrictive":
+			// Write embedded restrictive profile to temp file with restrictive permissions
+			profilePath := filepath.Join(os.TempDir(), "addt-seccomp-restrictive.json")
+			if err := os.WriteFile(profilePath, assets.SeccompRestrictive, 0600); err == nil {
+				cliArgs = append(cliArgs, "--security-opt", "seccomp="+profilePath)
+			}
+		case "default":
+			// Use the runtime's default profile, no flag needed
+		default:
+			// Custom profile path
+			cliArgs = append(cliArgs, "--security-opt", "seccomp="+sec.SeccompProfile)
+		}
+	}
+
+	// Network mode (none = completely isolated, no network access)
+	// Skipped when the firewall runs on pasta, which owns the network setting
+	if sec.NetworkMode != "" && !(p.rt.Pasta && p.config.FirewallEnabled) {
+		cliArgs = append(cliArgs, "--network", sec.NetworkMode)
+	}
+
+	// IPC namespace isolation
+	if sec.DisableIPC {
+		ipcMode := "none"
+		if p.rt.PrivateIPC {
+			ipcMode = "private"
+		}
+		cliArgs = append(cliArgs, "--ipc", ipcMode)
+	}
+
+	// Time limit - pass as env var for entrypoint to enforce with timeout command
+	if sec.TimeLimit > 0 {
+		cliArgs = append(cliArgs, "-e", fmt.Sprintf("ADDT_TIME_LIMIT_SECONDS=%d", sec.TimeLimit*60))
+	}
+
+	// User namespace remapping (Docker requires daemon config for "host")
+	if sec.UserNamespace != "" {
+		cliArgs = append(cliArgs, "--userns", sec.UserNamespace)
+	}
+
+	// Block mknod capability (prevents creating device files)
+	if sec.DisableDevices {
+		cliArgs = append(cliArgs, "--cap-drop", "MKNOD")
+	}
+
+	// Memory swap limit (-1 = disable swap entirely)
+	if sec.MemorySwap != "" {
+		cliArgs = append(cliArgs, "--memory-swap", sec.MemorySwap)
+	}
+
+	return cliArgs
+}
@@ -1,4 +1,4 @@
-package docker
+package ocicli
 
 import (
 	"testing"
@@ -7,71 +7,73 @@ import (
 )
 
 func TestBuildBaseDockerArgs_NewPersistent(t *testing.T) {
-	p := &DockerProvider{
-		config: &provider.Config{},
-	}
-	spec := &provider.RunSpec{
-		Name:        "test-container",
-		Persistent:  true,
-		Interactive: true,
-	}
-	ctx := &containerContext{
-		useExistingContainer: false,
-	}
+	forEachRuntime(t, func(t *testing.T, rt Runtime) {
+		p := newTestProvider(rt, &provider.Config{})
+		spec := &provider.RunSpec{
+			Name:        "test-container",
+			Persistent:  true,
+			Interactive: true,
+		}
+		ctx := &containerContext{
+			useExistingContainer: false,
+		}
 
-	args := p.buildBaseDockerArgs(spec, ctx)
+		args := p.buildBaseArgs(spec, ctx)
 
-	// Should have: run --name test-container -it --init
-	assertContains(t, args, "run")
-	assertContains(t, args, "--name")
-	assertContains(t, args, "test-container")
-	assertContains(t, args, "-it")
-	assertContains(t, args, "--init")
-	assertNotContains(t, args, "--rm")
+		// Should have: run --name test-container -it --init
+		assertContains(t, args, "run")
+		assertContains(t, args, "--name")
+		assertContains(t, args, "test-container")
+		assertContains(t, args, "-it")
+		if rt.initAvailable() {
+			assertContains(t, args, "--init")
+		}
+		assertNotContains(t, args, "--rm")
+	})
 }
 
 func TestBuildBaseDockerArgs_ExistingPersistent(t *testing.T) {
-	p := &DockerProvider{
-		config: &provider.Config{},
-	}
-	spec := &provider.RunSpec{
-		Name:        "test-container",
-		Persistent:  true,
-		Interactive: true,
-	}
-	ctx := &containerContext{
-		useExistingContainer: true,
-	}
+	forEachRuntime(t, func(t *testing.T, rt Runtime) {
+		p := newTestProvider(rt, &provider.Config{})
+		spec := &provider.RunSpec{
+			Name:        "test-container",
+			Persistent:  true,
+			Interactive: true,
+		}
+		ctx := &containerContext{
+			useExistingContainer: true,
+		}
 
-	args := p.buildBaseDockerArgs(spec, ctx)
+		args := p.buildBaseArgs(spec, ctx)
 
-	// Should have: exec -it
-	assertContains(t, args, "exec")
-	assertContains(t, args, "-it")
-	assertNotContains(t, args, "run")
-	assertNotContains(t, args, "--rm")
+		// Should have: exec -it
+		assertContains(t, args, "exec")
+		assertContains(t, args, "-it")
+		assertNotContains(t, args, "run")
+		assertNotContains(t, args, "--rm")
+	})
 }
 
 func TestBuildBaseDockerArgs_Ephemeral(t *testing.T) {
-	p := &DockerProvider{
-		config: &provider.Config{},
-	}
-	spec := &provider.RunSpec{
-		Name:        "test-container",
-		Persistent:  false,
-		Interactive: false,
-	}
-	ctx := &containerContext{
-		useExistingContainer: false,
-	}
+	forEachRuntime(t, func(t *testing.T, rt Runtime) {
+		p := newTestProvider(rt, &provider.Config{})
+		spec := &provider.RunSpec{
+			Name:        "test-container",
+			Persistent:  false,
+			Interactive: false,
+		}
+		ctx := &containerContext{
+			useExistingContainer: false,
+		}
 
-	args := p.buildBaseDockerArgs(spec, ctx)
+		args := p.buildBaseArgs(spec, ctx)
 
-	// Should have: run --rm --name test-container -i
-	assertContains(t, args, "run")
-	assertContains(t, args, "--rm")
-	assertContains(t, args, "-i")
-	assertNotContains(t, args, "-it")
+		// Should have: run --rm --name test-container -i
+		assertContains(t, args, "run")
+		assertContains(t, args, "--rm")
+		assertContains(t, args, "-i")
+		assertNotContains(t, args, "-it")
+	})
 }
 
 func TestStripInteractiveFlags(t *testing.T) {
@@ -134,29 +136,29 @@ func TestStripInteractiveFlags(t *testing.T) {
 }
 
 func TestShellExistingContainer_UsesEntrypoint(t *testing.T) {
-	// Verify that Shell() for existing containers now uses the entrypoint
-	// with ADDT_COMMAND=/bin/bash instead of direct /bin/bash
-	p := &DockerProvider{
-		config: &provider.Config{},
-	}
-	spec := &provider.RunSpec{
-		Name:        "test-container",
-		Persistent:  true,
-		Interactive: true,
-	}
-	ctx := &containerContext{
-		useExistingContainer: true,
-	}
+	forEachRuntime(t, func(t *testing.T, rt Runtime) {
+		// Verify that Shell() for existing containers now uses the entrypoint
+		// with ADDT_COMMAND=/bin/bash instead of direct /bin/bash
+		p := newTestProvider(rt, &provider.Config{})
+		spec := &provider.RunSpec{
+			Name:        "test-container",
+			Persistent:  true,
+			Interactive: true,
+		}
+		ctx := &containerContext{
+			useExistingContainer: true,
+		}
 
-	args := p.buildBaseDockerArgs(spec, ctx)
-	// Simulate what Shell() does for existing containers
-	args = append(args, "-e", "ADDT_COMMAND=/bin/bash")
-	args = append(args, spec.Name, "/usr/local/bin/docker-entrypoint.sh")
+		args := p.buildBaseArgs(spec, ctx)
+		// Simulate what Shell() does for existing containers
+		args = append(args, "-e", "ADDT_COMMAND=/bin/bash")
+		args = append(args, spec.Name, rt.EntrypointPath)
 
-	assertContains(t, args, "exec")
-	assertContains(t, args, "-e")
-	assertContains(t, args, "ADDT_COMMAND=/bin/bash")
-	assertContains(t, args, "/usr/local/bin/docker-entrypoint.sh")
+		assertContains(t, args, "exec")
+		assertContains(t, args, "-e")
+		assertContains(t, args, "ADDT_COMMAND=/bin/bash")
+		assertContains(t, args, rt.EntrypointPath)
+	})
 }
 
 // Helper functions
@@ -1,4 +1,4 @@
-package docker
+package ocicli
 
 import (
 	"encoding/json"
@@ -10,7 +10,7 @@ import (
 )
 
 // GetExtensionMounts reads extension metadata from image and returns all mounts
-func (p *DockerProvider) GetExtensionMounts(imageName string) []extensions.ExtensionMount {
+func (p *Provider) GetExtensionMounts(imageName string) []extensions.ExtensionMount {
 	mountsWithNames := p.GetExtensionMountsWithNames(imageName)
 	var mounts []extensions.ExtensionMount
 	for _, m := range mountsWithNames {
@@ -23,13 +23,11 @@ func (p *DockerProvider) GetExtensionMounts(imageName string) []extensions.Exten
 }
 
 // GetExtensionMountsWithNames reads extension metadata and returns mounts with extension names
-func (p *DockerProvider) GetExtensionMountsWithNames(imageName string) []extensions.ExtensionMountWithName {
+func (p *Provider) GetExtensionMountsWithNames(imageName string) []extensions.ExtensionMountWithName {
 	var mounts []extensions.ExtensionMountWithName
 
 	// Read extensions.json from the image
-	cmd := p.dockerCmd("run", "--rm", "--entrypoint", "cat", imageName,
-		"/home/addt/.addt/extensions.json")
-	output, err := cmd.Output()
+	output, err := p.backend.RunOutput(imageName, "cat", "/home/addt/.addt/extensions.json")
 	if err != nil {
 		// Extension metadata not available - this is normal for images without extensions
 		// or when the extensions.json file doesn't exist yet. Not an error condition.
@@ -66,8 +64,8 @@ func (p *DockerProvider) GetExtensionMountsWithNames(imageName string) []extensi
 	return mounts
 }
 
-// AddExtensionMounts adds extension mount volumes to docker args
-func (p *DockerProvider) AddExtensionMounts(dockerArgs []string, imageName, homeDir string) []string {
+// AddExtensionMounts adds extension mount volumes to runtime CLI args
+func (p *Provider) AddExtensionMounts(cliArgs []string, imageName, homeDir string) []string {
 	extMounts := p.GetExtensionMountsWithNames(imageName)
 	for _, extMount := range extMounts {
 		// Determine if mount should be enabled based on mounts.automount and explicit config
@@ -120,29 +118,27 @@ func (p *DockerProvider) AddExtensionMounts(dockerArgs []string, imageName, home
 		// Check if source exists, create if it's a directory path
 		if info, err := os.Stat(source); err == nil {
 			// Source exists (file or directory)
-			dockerArgs = append(dockerArgs, "-v", source+":"+extMount.Target+mountSuffix)
+			cliArgs = append(cliArgs, "-v", source+":"+extMount.Target+mountSuffix)
 		} else if os.IsNotExist(err) {
 			// Source doesn't exist - create directory if path doesn't look like a file
 			if !strings.Contains(filepath.Base(source), ".") {
 				// Looks like a directory (no extension)
 				if err := os.MkdirAll(source, 0755); err == nil {
-					dockerArgs = append(dockerArgs, "-v", source+":"+extMount.Target+mountSuffix)
+					cliArgs = append(cliArgs, "-v", source+":"+extMount.Target+mountSuffix)
 				}
 			}
 			// Skip files that don't exist (e.g., ~/.claude.json on fresh install)
 		} else if info != nil {
-			dockerArgs = append(dockerArgs, "-v", source+":"+extMount.Target+mountSuffix)
+			cliArgs = append(cliArgs, "-v", source+":"+extMount.Target+mountSuffix)
 		}
 	}
-	return dockerArgs
+	return cliArgs
 }
 
 // GetExtensionMetadata reads all extension metadata from the image
-func (p *DockerProvider) GetExtensionMetadata(imageName string) map[string]extensions.ExtensionMetadata {
+func (p *Provider) GetExtensionMetadata(imageName string) map[string]extensions.ExtensionMetadata {
 	// Read extensions.json from the image
-	cmd := p.dockerCmd("run", "--rm", "--entrypoint", "cat", imageName,
-		"/home/addt/.addt/extensions.json")
-	output, err := cmd.Output()
+	output, err := p.backend.RunOutput(imageName, "cat", "/home/addt/.addt/extensions.json")
 	if err != nil {
 		// Extension metadata not available - this is normal for images without extensions
 		// or when the extensions.json file doesn't exist yet. Not an error condition.
@@ -158,7 +154,7 @@ func (p *DockerProvider) GetExtensionMetadata(imageName string) map[string]exten
 }
 
 // GetExtensionFlags returns flags for a specific extension by entrypoint command
-func (p *DockerProvider) GetExtensionFlags(imageName, command string) []extensions.ExtensionFlag {
+func (p *Provider) GetExtensionFlags(imageName, command string) []extensions.ExtensionFlag {
 	metadata := p.GetExtensionMetadata(imageName)
 	if metadata == nil {
 		return nil
@@ -177,7 +173,7 @@ func (p *DockerProvider) GetExtensionFlags(imageName, command string) []extensio
 // GetExtensionEnvVars returns all unique environment variables needed by installed extensions
 // This includes both regular env_vars and otel_vars from extension configs.
 // Entries can be either "VAR_NAME" (pass-through from host) or "VAR_NAME=default" (with default value).
-func (p *DockerProvider) GetExtensionEnvVars(imageName string) []string {
+func (p *Provider) GetExtensionEnvVars(imageName string) []string {
 	metadata := p.GetExtensionMetadata(imageName)
 	if metadata == nil {
 		return nil
@@ -0,0 +1,153 @@
+package ocicli
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/config/security"
+)
+
+// firewallReloadInterval is how often a session looks for rules sent by
+// 'addt firewall apply'
+const firewallReloadInterval = time.Second
+
+// firewallReloadVars are the firewall script's settings a reload passes
+// again; those the session doesn't set are cleared, so values the
+// container was created with don't come back
+var firewallReloadVars = []string{"ADDT_FIREWALL_MODE", "ADDT_FIREWALL_RULES", "ADDT_EGRESS_PROXY", "ADDT_DNS_RESOLVER", "ADDT_HOST_SERVICES", "ADDT_HOST_SERVICES_HOST", "ADDT_API_PROXY", "ADDT_GIT_CREDENTIALS"}
+
+// watchFirewallReload registers the session for 'addt firewall apply' and,
+// until Cleanup, applies the rules it sends without restarting the agent
+func (p *Provider) watchFirewallReload(name string) {
+	if !p.firewallEnforced() || p.config.OfflineEnabled || p.reloadStop != nil {
+		return
+	}
+	project, _ := os.Getwd()
+	extension, _, _ := strings.Cut(p.config.Extensions, ",")
+	session := &security.FirewallSession{
+		Container: name,
+		Project:   project,
+		Extension: extension,
+		PID:       os.Getpid(),
+		Started:   time.Now(),
+		Rules:     p.firewallRules(),
+	}
+	if err := security.RegisterFirewallSession(session); err != nil {
+		p.logger.Debugf("Failed to register %s for firewall reloads: %v", name, err)
+		return
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	p.reloadStop, p.reloadDone, p.reloadContainer = stop, done, name
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(firewallReloadInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			rules, err := security.TakeFirewallReload(name)
+			if rules == nil && err == nil {
+				continue
+			}
+			if err == nil {
+				err = p.reloadFirewall(name, *rules)
+			}
+			if err == nil {
+				session.Rules = p.firewallRules()
+				err = security.RegisterFirewallSession(session)
+			}
+			if err != nil {
+				p.logger.Infof("Firewall reload of %s failed: %v", name, err)
+			} else {
+				p.logger.Infof("Firewall rules of %s reloaded", name)
+			}
+			security.ReportFirewallReload(name, err)
+		}
+	}()
+}
+
+// stopFirewallReload stops applying reloads and unregisters the session
+func (p *Provider) stopFirewallReload() {
+	if p.reloadStop == nil {
+		return
+	}
+	close(p.reloadStop)
+	<-p.reloadDone
+	p.reloadStop, p.reloadDone = nil, nil
+	security.UnregisterFirewallSession(p.reloadContainer)
+	p.reloadContainer = ""
+}
+
+// reloadFirewall replaces the session's project, global and extension
+// rules: the egress proxy and DNS resolver check new lookups and
+// connections against them, and the firewall script renders the
+// container's ruleset again. Addresses allowed from earlier lookups are
+// dropped and allowed again as names resolve under the new rules.
+func (p *Provider) reloadFirewall(name string, rules security.FirewallRules) error {
+	p.rulesMu.Lock()
+	current := p.config.FirewallRules
+	current.Project = rules.Project
+	current.Global = rules.Global
+	current.Extension = rules.Extension
+	p.config.FirewallRules = current
+	p.rulesMu.Unlock()
+
+	if p.egressProxy != nil {
+		p.egressProxy.SetRules(current)
+	}
+	if p.dnsResolver != nil {
+		p.dnsResolver.SetRules(current)
+	}
+	p.dnsMu.Lock()
+	p.dnsAllowed = make(map[string]time.Time)
+	p.dnsMu.Unlock()
+
+	// Settings go through stdin, so they don't show in the host's process list
+	input := strings.Join(p.firewallReloadEnv(), "\n") + "\n"
+	return p.backend.ExecInput(name, []byte(input), firewallScriptPath, "--reload")
+}
+
+// firewallReloadEnv returns the firewall script's settings for a reload,
+// as KEY=VALUE lines
+func (p *Provider) firewallReloadEnv() []string {
+	env := make(map[string]string, len(firewallReloadVars))
+	for _, key := range firewallReloadVars {
+		env[key] = ""
+	}
+	env["ADDT_FIREWALL_MODE"] = p.config.FirewallMode
+
+	var args []string
+	args = append(args, p.egressProxyEnvArgs()...)
+	args = append(args, p.dnsResolverEnvArgs()...)
+	args = append(args, p.hostServiceEnvArgs()...)
+	args = append(args, p.firewallRulesEnvArgs()...)
+	args = append(args, p.apiProxyEnvArgs()...)
+	args = append(args, p.gitCredentialEnvArgs()...)
+	for i := 1; i < len(args); i += 2 {
+		key, value, _ := strings.Cut(args[i], "=")
+		if _, ok := env[key]; ok {
+			env[key] = value
+		}
+	}
+
+	lines := make([]string, 0, len(env))
+	for key, value := range env {
+		lines = append(lines, key+"="+value)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// firewallRules returns the session's rules, which a reload can replace
+func (p *Provider) firewallRules() security.FirewallRules {
+	p.rulesMu.Lock()
+	defer p.rulesMu.Unlock()
+	return p.config.FirewallRules
+}
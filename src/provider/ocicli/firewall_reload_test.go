@@ -0,0 +1,105 @@
+package ocicli
+
+import (
+	"embed"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/provider"
+)
+
+func TestReloadFirewall(t *testing.T) {
+	backend := &execRecordingBackend{}
+	cfg := &provider.Config{
+		FirewallEnabled: true,
+		FirewallMode:    "strict",
+		FirewallRules: security.FirewallRules{
+			Project:   security.FirewallLayer{Allowed: []string{"old.example"}},
+			Defaults:  []string{"github.com"},
+			Intercept: true,
+		},
+	}
+	p := NewWithBackend(DockerRuntime(""), backend, cfg, nil, nil, nil, nil, nil, embed.FS{})
+	if err := p.startDNSResolver("addt-test", false); err != nil {
+		t.Fatalf("startDNSResolver() error = %v", err)
+	}
+	defer p.stopDNSResolver()
+	p.dnsAllowed["140.82.112.3"] = time.Now().Add(time.Hour)
+
+	rules := security.FirewallRules{Project: security.FirewallLayer{
+		Allowed: []string{"new.example"},
+		Denied:  []string{"10.0.0.0/8"},
+	}}
+	if err := p.reloadFirewall("addt-test", rules); err != nil {
+		t.Fatalf("reloadFirewall() error = %v", err)
+	}
+
+	got := p.firewallRules()
+	if !reflect.DeepEqual(got.Project, rules.Project) {
+		t.Errorf("project rules = %+v, want %+v", got.Project, rules.Project)
+	}
+	if !got.Intercept || len(got.Defaults) != 1 {
+		t.Errorf("reload should keep the defaults and intercept setting: %+v", got)
+	}
+	if len(p.dnsAllowed) != 0 {
+		t.Errorf("dnsAllowed = %v, want it cleared", p.dnsAllowed)
+	}
+
+	want := []string{"addt-test", firewallScriptPath, "--reload"}
+	if len(backend.execs) != 1 || !reflect.DeepEqual(backend.execs[0], want) {
+		t.Fatalf("execs = %v, want %v", backend.execs, want)
+	}
+	input := backend.inputs[0]
+	for _, line := range []string{
+		"ADDT_FIREWALL_MODE=strict\n",
+		"ADDT_FIREWALL_RULES=project drop - - 10.0.0.0/8\n",
+		"ADDT_EGRESS_PROXY=\n",
+		"ADDT_DNS_RESOLVER=host.docker.internal:",
+	} {
+		if !strings.Contains(input, line) {
+			t.Errorf("reload input missing %q:\n%s", line, input)
+		}
+	}
+}
+
+func TestWatchFirewallReload(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+	backend := &execRecordingBackend{}
+	cfg := &provider.Config{FirewallEnabled: true, FirewallMode: "strict", Extensions: "claude"}
+	p := NewWithBackend(DockerRuntime(""), backend, cfg, nil, nil, nil, nil, nil, embed.FS{})
+
+	p.watchFirewallReload("addt-test")
+	sessions, _ := security.FirewallSessions("")
+	if len(sessions) != 1 || sessions[0].Container != "addt-test" || sessions[0].Extension != "claude" {
+		t.Fatalf("FirewallSessions() = %+v, want the addt-test session", sessions)
+	}
+
+	rules := security.FirewallRules{Project: security.FirewallLayer{Allowed: []string{"new.example"}}}
+	if err := security.RequestFirewallReload("addt-test", rules); err != nil {
+		t.Fatal(err)
+	}
+	if err := security.WaitFirewallReload("addt-test", 5*time.Second); err != nil {
+		t.Fatalf("WaitFirewallReload() error = %v", err)
+	}
+	sessions, _ = security.FirewallSessions("")
+	if len(sessions) != 1 || !reflect.DeepEqual(sessions[0].Rules.Project, rules.Project) {
+		t.Errorf("registered rules = %+v, want the reloaded ones", sessions)
+	}
+
+	p.stopFirewallReload()
+	if sessions, _ := security.FirewallSessions(""); len(sessions) != 0 {
+		t.Errorf("FirewallSessions() after stopping = %+v, want none", sessions)
+	}
+}
+
+func TestWatchFirewallReload_NotEnforced(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+	p := newTestProvider(DockerRuntime("desktop-linux"), &provider.Config{})
+	p.watchFirewallReload("addt-test")
+	if p.reloadStop != nil {
+		t.Error("reloads watched without the firewall")
+	}
+}
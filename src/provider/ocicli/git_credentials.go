@@ -0,0 +1,144 @@
+package ocicli
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/provider"
+)
+
+// startGitCredentials serves GitHub credentials for git from the host
+// when GH_TOKEN is held there (github.hold_token) or a GitHub App mints
+// them (github.app_id): the container's git asks the host-side helper,
+// which answers only for the workspace's GitHub remote and
+// github.scope_repos, with GH_TOKEN or App tokens limited to those repos.
+// GH_TOKEN is taken out of the container's environment, so gh isn't
+// logged in. Otherwise the entrypoint scopes GH_TOKEN with git's
+// credential cache. Like the API proxy, a persistent container keeps its
+// port and token.
+func (p *Provider) startGitCredentials(spec *provider.RunSpec) error {
+	if !p.config.GitHubScopeToken || p.gitCredentials != nil {
+		return nil
+	}
+	if !p.config.GitHubHoldToken && p.config.GitHubAppID == "" {
+		return nil
+	}
+	githubToken := spec.Env["GH_TOKEN"]
+	if githubToken == "" && p.config.GitHubAppID == "" {
+		return nil
+	}
+	if p.config.Security.NetworkMode == "none" {
+		// Nothing reaches GitHub, but a held token still stays out
+		if p.config.GitHubHoldToken {
+			delete(spec.Env, "GH_TOKEN")
+		}
+		return nil
+	}
+
+	repos := p.gitCredentialRepos()
+	var app *security.GitHubApp
+	if p.config.GitHubAppID != "" {
+		var err error
+		app, err = security.NewGitHubApp(p.config.GitHubAppID, p.config.GitHubAppInstallationID,
+			p.config.GitHubAppKeyFile, repos, p.config.GitHubAppPermissions)
+		if err != nil {
+			return err
+		}
+	}
+
+	token, err := security.GitCredentialToken(spec.Name, spec.Persistent)
+	if err != nil {
+		return err
+	}
+	helper := security.NewGitCredentialHelper(spec.Name, token, repos, githubToken, app)
+	port := 0
+	if spec.Persistent {
+		port = gitCredentialPort(spec.Name)
+	}
+	host := p.helperListenIP()
+	if err := helper.Start(net.JoinHostPort(host, strconv.Itoa(port))); err != nil {
+		if port == 0 {
+			return fmt.Errorf("failed to start git credential helper: %w", err)
+		}
+		fmt.Printf("Warning: git credential helper port %d is taken, %s will need to be recreated to reach it\n", port, spec.Name)
+		if err := helper.Start(net.JoinHostPort(host, "0")); err != nil {
+			return fmt.Errorf("failed to start git credential helper: %w", err)
+		}
+	}
+	p.gitCredentials = helper
+	delete(spec.Env, "GH_TOKEN")
+
+	source := "GH_TOKEN"
+	if app != nil {
+		source = "GitHub App tokens"
+	}
+	if len(repos) == 0 {
+		fmt.Printf("Git credentials: no GitHub repos to serve %s for (set github.scope_repos)\n", source)
+	} else {
+		fmt.Printf("Git credentials: %s for %s, served from the host\n", source, strings.Join(repos, ", "))
+	}
+	if app == nil {
+		fmt.Println("Git credentials: GH_TOKEN isn't scoped; git can pass it to any repo it's authorized for (set github.app_id for repo-scoped tokens)")
+	}
+	if githubToken != "" {
+		fmt.Println("Git credentials: GH_TOKEN stays on the host, gh in the container isn't logged in")
+	}
+	return nil
+}
+
+// gitCredentialRepos returns the repos credentials are served for: the
+// workspace's GitHub remote and github.scope_repos
+func (p *Provider) gitCredentialRepos() []string {
+	var repos []string
+	dir := p.config.Workdir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	if out, err := exec.Command("git", "-C", dir, "remote", "get-url", "origin").Output(); err == nil {
+		if repo := security.GitHubRepoFromURL(string(out)); repo != "" {
+			repos = append(repos, repo)
+		}
+	}
+	for _, r := range p.config.GitHubScopeRepos {
+		if r = strings.TrimSpace(r); r != "" {
+			repos = append(repos, r)
+		}
+	}
+	return repos
+}
+
+// gitCredentialPort derives a persistent container's git credential helper
+// port, above the DNS resolver's range
+func gitCredentialPort(name string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return 60000 + int(h.Sum32()%5000)
+}
+
+// gitCredentialEnvArgs tells the entrypoint where to bridge the container's
+// 127.0.0.1:4875 to and which token git's requests carry, and the firewall
+// script to allow it
+func (p *Provider) gitCredentialEnvArgs() []string {
+	if p.gitCredentials == nil {
+		return nil
+	}
+	return []string{
+		"-e", fmt.Sprintf("ADDT_GIT_CREDENTIALS=%s:%d", egressProxyHost, p.gitCredentials.Port()),
+		"-e", "ADDT_GIT_CREDENTIAL_TOKEN=" + p.gitCredentials.Token(),
+	}
+}
+
+// stopGitCredentials stops the git credential helper
+func (p *Provider) stopGitCredentials() {
+	if p.gitCredentials == nil {
+		return
+	}
+	p.gitCredentials.Stop()
+	p.gitCredentials = nil
+}
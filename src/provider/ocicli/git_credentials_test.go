@@ -0,0 +1,84 @@
+package ocicli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jedi4ever/addt/provider"
+)
+
+func TestStartGitCredentials(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+	cfg := &provider.Config{GitHubScopeToken: true, GitHubHoldToken: true, GitHubScopeRepos: []string{"ourorg/app"}, Workdir: t.TempDir()}
+	p := newTestProvider(DockerRuntime("desktop-linux"), cfg)
+	spec := &provider.RunSpec{Name: "addt-test", Env: map[string]string{"GH_TOKEN": "ghp_real"}}
+	if err := p.startGitCredentials(spec); err != nil {
+		t.Fatalf("startGitCredentials() error = %v", err)
+	}
+	defer p.stopGitCredentials()
+
+	if _, ok := spec.Env["GH_TOKEN"]; ok {
+		t.Error("GH_TOKEN still in the container's environment")
+	}
+	if repos := p.gitCredentials.Repos(); len(repos) != 1 || repos[0] != "ourorg/app" {
+		t.Errorf("repos = %v, want ourorg/app", repos)
+	}
+	env := strings.Join(p.gitCredentialEnvArgs(), " ")
+	if !strings.Contains(env, "ADDT_GIT_CREDENTIALS=host.docker.internal:") || !strings.Contains(env, "ADDT_GIT_CREDENTIAL_TOKEN=addt-git-") {
+		t.Errorf("gitCredentialEnvArgs() = %s", env)
+	}
+}
+
+func TestStartGitCredentials_Skipped(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope bool
+		hold  bool
+		env   map[string]string
+	}{
+		{"not scoped", false, true, map[string]string{"GH_TOKEN": "ghp_real"}},
+		{"not held", true, false, map[string]string{"GH_TOKEN": "ghp_real"}},
+		{"no token", true, true, map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &provider.Config{GitHubScopeToken: tt.scope, GitHubHoldToken: tt.hold}
+			p := newTestProvider(DockerRuntime("desktop-linux"), cfg)
+			spec := &provider.RunSpec{Name: "addt-test", Env: tt.env}
+			if err := p.startGitCredentials(spec); err != nil {
+				t.Fatalf("startGitCredentials() error = %v", err)
+			}
+			if p.gitCredentials != nil {
+				p.stopGitCredentials()
+				t.Fatal("git credential helper started")
+			}
+			if len(tt.env) > 0 && spec.Env["GH_TOKEN"] != "ghp_real" {
+				t.Error("GH_TOKEN removed")
+			}
+		})
+	}
+}
+
+func TestStartGitCredentials_NetworkNone(t *testing.T) {
+	cfg := &provider.Config{GitHubScopeToken: true, GitHubHoldToken: true}
+	cfg.Security.NetworkMode = "none"
+	p := newTestProvider(DockerRuntime("desktop-linux"), cfg)
+	spec := &provider.RunSpec{Name: "addt-test", Env: map[string]string{"GH_TOKEN": "ghp_real"}}
+	if err := p.startGitCredentials(spec); err != nil {
+		t.Fatalf("startGitCredentials() error = %v", err)
+	}
+	if p.gitCredentials != nil {
+		p.stopGitCredentials()
+		t.Fatal("git credential helper started")
+	}
+	if _, ok := spec.Env["GH_TOKEN"]; ok {
+		t.Error("held GH_TOKEN reached the container")
+	}
+}
+
+func TestGitCredentialPort(t *testing.T) {
+	port := gitCredentialPort("addt-persistent-myproject-abc123")
+	if port < 60000 || port >= 65000 {
+		t.Errorf("gitCredentialPort() = %d, want 60000-64999", port)
+	}
+}
@@ -1,4 +1,4 @@
-package orbstack
+package ocicli
 
 import (
 	"fmt"
@@ -19,8 +19,9 @@ import (
 //   - "keys" or "true": Mount ~/.gnupg read-only (legacy, less secure)
 //   - "" or "off" or "false": No GPG forwarding
 //
-// If allowedKeyIDs is set, proxy mode is automatically enabled
-func (p *OrbStackProvider) HandleGPGForwarding(gpgForward, gpgDir, username string, allowedKeyIDs []string) []string {
+// If allowedKeyIDs is set or gpg.sign_policy is confirm, proxy mode is
+// automatically enabled
+func (p *Provider) HandleGPGForwarding(gpgForward, gpgDir, username string, allowedKeyIDs []string) []string {
 	var args []string
 
 	// Normalize boolean-like values
@@ -31,10 +32,14 @@ func (p *OrbStackProvider) HandleGPGForwarding(gpgForward, gpgDir, username stri
 		return args
 	}
 
-	// If allowed key IDs are specified, use proxy mode
-	if len(allowedKeyIDs) > 0 && (gpgForward == "agent" || gpgForward == "true" || gpgForward == "proxy") {
+	// If allowed key IDs are specified or signatures are confirmed, use proxy mode
+	confirm := p.config != nil && p.config.GPGSignPolicy == "confirm"
+	if (len(allowedKeyIDs) > 0 || confirm) && (gpgForward == "agent" || gpgForward == "true" || gpgForward == "proxy") {
 		return p.handleGPGProxyForwarding(gpgDir, username, allowedKeyIDs)
 	}
+	if confirm && gpgForward == "keys" {
+		fmt.Println("Warning: gpg.sign_policy confirm needs gpg.forward proxy; keys mode mounts the keys and signs without asking")
+	}
 
 	switch gpgForward {
 	case "proxy":
@@ -50,7 +55,7 @@ func (p *OrbStackProvider) HandleGPGForwarding(gpgForward, gpgDir, username stri
 }
 
 // handleGPGProxyForwarding creates a filtering GPG agent proxy
-func (p *OrbStackProvider) handleGPGProxyForwarding(gpgDir, username string, allowedKeyIDs []string) []string {
+func (p *Provider) handleGPGProxyForwarding(gpgDir, username string, allowedKeyIDs []string) []string {
 	var args []string
 
 	agentSocket := getGPGAgentSocket(gpgDir)
@@ -59,7 +64,7 @@ func (p *OrbStackProvider) handleGPGProxyForwarding(gpgDir, username string, all
 		return args
 	}
 
-	// On macOS, Docker Desktop runs containers in a VM and can't mount Unix sockets.
+	// On macOS, containers run in a VM and the proxy socket can't be mounted.
 	// Use TCP mode: proxy listens on TCP, container connects via socat.
 	if runtime.GOOS == "darwin" {
 		return p.handleGPGProxyForwardingTCP(agentSocket, gpgDir, username, allowedKeyIDs)
@@ -78,6 +83,7 @@ func (p *OrbStackProvider) handleGPGProxyForwarding(gpgDir, username string, all
 	}
 
 	p.gpgProxy = proxy
+	p.approveGPGSigns(proxy)
 
 	proxyDir := proxy.SocketDir()
 	args = append(args, "-v", fmt.Sprintf("%s:/home/%s/.gnupg/S.gpg-agent", proxy.SocketPath(), username))
@@ -95,10 +101,10 @@ func (p *OrbStackProvider) handleGPGProxyForwarding(gpgDir, username string, all
 
 // handleGPGProxyForwardingTCP creates a TCP-based GPG agent proxy for macOS.
 // The proxy listens on a TCP port on the host; the container connects via socat.
-func (p *OrbStackProvider) handleGPGProxyForwardingTCP(agentSocket, gpgDir, username string, allowedKeyIDs []string) []string {
+func (p *Provider) handleGPGProxyForwardingTCP(agentSocket, gpgDir, username string, allowedKeyIDs []string) []string {
 	var args []string
 
-	proxy, err := security.NewGPGProxyAgentTCP(agentSocket, allowedKeyIDs)
+	proxy, err := security.NewGPGProxyAgentTCP(agentSocket, p.helperListenIP(), allowedKeyIDs)
 	if err != nil {
 		fmt.Printf("Warning: failed to create GPG TCP proxy: %v\n", err)
 		return args
@@ -110,16 +116,10 @@ func (p *OrbStackProvider) handleGPGProxyForwardingTCP(agentSocket, gpgDir, user
 	}
 
 	p.gpgProxy = proxy
-
-	hostIP, err := getHostGatewayIP()
-	if err != nil {
-		fmt.Printf("Warning: could not detect host IP for GPG proxy: %v\n", err)
-		proxy.Stop()
-		return args
-	}
+	p.approveGPGSigns(proxy)
 
 	// Pass TCP connection info — entrypoint uses socat to bridge TCP→Unix socket
-	args = append(args, "-e", fmt.Sprintf("ADDT_GPG_PROXY_HOST=%s", hostIP))
+	args = append(args, "-e", fmt.Sprintf("ADDT_GPG_PROXY_HOST=%s", egressProxyHost))
 	args = append(args, "-e", fmt.Sprintf("ADDT_GPG_PROXY_PORT=%d", proxy.TCPPort()))
 
 	// Mount safe GPG files writable (socat needs to create S.gpg-agent socket inside)
@@ -137,7 +137,7 @@ func (p *OrbStackProvider) handleGPGProxyForwardingTCP(agentSocket, gpgDir, user
 }
 
 // handleGPGAgentForwarding forwards the gpg-agent socket directly
-func (p *OrbStackProvider) handleGPGAgentForwarding(gpgDir, username string) []string {
+func (p *Provider) handleGPGAgentForwarding(gpgDir, username string) []string {
 	var args []string
 
 	agentSocket := getGPGAgentSocket(gpgDir)
@@ -146,10 +146,16 @@ func (p *OrbStackProvider) handleGPGAgentForwarding(gpgDir, username string) []s
 		return args
 	}
 
+	// Some runtimes (podman machine) can't mount Unix sockets on macOS
+	if runtime.GOOS == "darwin" && !p.rt.SocketMountsOnDarwin {
+		fmt.Println("Warning: GPG agent forwarding not supported on macOS (use ADDT_GPG_FORWARD=proxy)")
+		return args
+	}
+
 	// Mount safe GPG files writable first (socket needs writable parent dir)
 	args = append(args, p.mountSafeGPGFilesWritable(gpgDir, username)...)
 
-	// Mount the agent socket on top (Docker/OrbStack support Unix socket forwarding on macOS)
+	// Mount the agent socket on top
 	args = append(args, "-v", fmt.Sprintf("%s:/home/%s/.gnupg/S.gpg-agent", agentSocket, username))
 
 	// Set GPG_TTY
@@ -161,7 +167,7 @@ func (p *OrbStackProvider) handleGPGAgentForwarding(gpgDir, username string) []s
 }
 
 // handleGPGKeysForwarding mounts the GPG directory read-only (legacy mode)
-func (p *OrbStackProvider) handleGPGKeysForwarding(gpgDir, username string) []string {
+func (p *Provider) handleGPGKeysForwarding(gpgDir, username string) []string {
 	var args []string
 
 	if _, err := os.Stat(gpgDir); err != nil {
@@ -179,7 +185,7 @@ func (p *OrbStackProvider) handleGPGKeysForwarding(gpgDir, username string) []st
 
 // mountSafeGPGFiles creates a temp directory with only safe GPG files
 // and returns mount arguments
-func (p *OrbStackProvider) mountSafeGPGFiles(gpgDir, username string) []string {
+func (p *Provider) mountSafeGPGFiles(gpgDir, username string) []string {
 	var args []string
 
 	if _, err := os.Stat(gpgDir); err != nil {
@@ -241,7 +247,7 @@ func (p *OrbStackProvider) mountSafeGPGFiles(gpgDir, username string) []string {
 
 // mountSafeGPGFilesWritable creates a temp directory with only safe GPG files
 // and returns writable mount arguments (needed for TCP mode where socat creates S.gpg-agent)
-func (p *OrbStackProvider) mountSafeGPGFilesWritable(gpgDir, username string) []string {
+func (p *Provider) mountSafeGPGFilesWritable(gpgDir, username string) []string {
 	var args []string
 
 	if _, err := os.Stat(gpgDir); err != nil {
@@ -1,4 +1,4 @@
-package podman
+package ocicli
 
 import (
 	"crypto/sha256"
@@ -12,7 +12,7 @@ import (
 
 // HandleHistoryPersist configures shell history persistence.
 // When enabled, mounts per-project history files from ~/.addt/history/<project-hash>/
-func (p *PodmanProvider) HandleHistoryPersist(enabled bool, projectDir, username string) []string {
+func (p *Provider) HandleHistoryPersist(enabled bool, projectDir, username string) []string {
 	if !enabled {
 		return nil
 	}
@@ -0,0 +1,128 @@
+package ocicli
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/jedi4ever/addt/config/security"
+)
+
+// startHostServices starts the forwarder for the host services the
+// container may reach. Each service listens on the host on its own port;
+// in the container, <name>.host.addt resolves to a loopback address where
+// the entrypoint bridges the service's port to it. Like the egress proxy, a
+// persistent container keeps its ports, so bridges left running from an
+// earlier session still reach them.
+func (p *Provider) startHostServices(name string, persistent bool) error {
+	services := p.config.HostServices
+	if len(services) == 0 || p.hostServices != nil {
+		return nil
+	}
+	if p.config.Security.NetworkMode == "none" {
+		fmt.Println("Warning: ports.host_services are unreachable with security.network_mode none")
+		return nil
+	}
+	if p.config.OfflineEnabled {
+		fmt.Println("Warning: ports.host_services are unreachable offline")
+		return nil
+	}
+
+	forwarder := security.NewHostServiceForwarder(name, services)
+	var ports map[string]int
+	if persistent {
+		ports = make(map[string]int)
+		for _, s := range services {
+			ports[s.Name] = hostServicePort(name, s.Name)
+		}
+	}
+	host := p.helperListenIP()
+	if err := forwarder.Start(host, ports); err != nil {
+		if ports == nil {
+			return fmt.Errorf("failed to start host service forwarder: %w", err)
+		}
+		fmt.Printf("Warning: %v; %s will need to be recreated to reach its host services\n", err, name)
+		if err := forwarder.Start(host, nil); err != nil {
+			return fmt.Errorf("failed to start host service forwarder: %w", err)
+		}
+	}
+	p.hostServices = forwarder
+
+	names := make([]string, len(services))
+	for i, s := range services {
+		names[i] = fmt.Sprintf("%s:%d", s.Hostname(), s.Port)
+	}
+	fmt.Printf("Host services: %s\n", strings.Join(names, ", "))
+	return nil
+}
+
+// hostServicePort derives the port a persistent container's host service
+// is forwarded from, below the egress proxy's range
+func hostServicePort(container, service string) int {
+	h := fnv.New32a()
+	h.Write([]byte(container + "/" + service))
+	return 20000 + int(h.Sum32()%10000)
+}
+
+// hostServiceAddresses gives each service a loopback address in
+// 127.1.0.0/16, derived from its name so it stays the same when services
+// are added or removed
+func hostServiceAddresses(services []security.HostService) map[string]string {
+	addrs := make(map[string]string, len(services))
+	used := make(map[uint32]bool)
+	for _, s := range services {
+		h := fnv.New32a()
+		h.Write([]byte(s.Name))
+		n := h.Sum32() % 0xfffe
+		for used[n] {
+			n = (n + 1) % 0xfffe
+		}
+		used[n] = true
+		n++ // skip 127.1.0.0
+		addrs[s.Name] = fmt.Sprintf("127.1.%d.%d", n>>8, n&0xff)
+	}
+	return addrs
+}
+
+// hostServiceHostArgs maps each host service's name to its loopback
+// address in a new container's /etc/hosts
+func (p *Provider) hostServiceHostArgs() []string {
+	if p.hostServices == nil {
+		return nil
+	}
+	services := p.hostServices.Services()
+	addrs := hostServiceAddresses(services)
+	var args []string
+	for _, s := range services {
+		args = append(args, fmt.Sprintf("--add-host=%s:%s", s.Hostname(), addrs[s.Name]))
+	}
+	return args
+}
+
+// hostServiceEnvArgs tells the entrypoint which bridges to start and the
+// firewall script which forwarder ports to allow, as
+// ADDT_HOST_SERVICES=<name>=<address>:<port>:<forwarder port>,...
+func (p *Provider) hostServiceEnvArgs() []string {
+	if p.hostServices == nil {
+		return nil
+	}
+	services := p.hostServices.Services()
+	addrs := hostServiceAddresses(services)
+	entries := make([]string, len(services))
+	for i, s := range services {
+		entries[i] = fmt.Sprintf("%s=%s:%d:%d", s.Name, addrs[s.Name], s.Port, p.hostServices.Port(s.Name))
+	}
+	return []string{
+		"-e", "ADDT_HOST_SERVICES=" + strings.Join(entries, ","),
+		"-e", "ADDT_HOST_SERVICES_HOST=" + egressProxyHost,
+	}
+}
+
+// stopHostServices stops the host service forwarder
+func (p *Provider) stopHostServices() {
+	if p.hostServices == nil {
+		return
+	}
+	p.hostServices.Stop()
+	p.hostServices = nil
+}
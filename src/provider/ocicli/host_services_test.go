@@ -0,0 +1,66 @@
+package ocicli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/provider"
+)
+
+func TestHostServiceAddresses(t *testing.T) {
+	services := []security.HostService{{Name: "api", Port: 8080}, {Name: "postgres", Port: 5432}}
+	addrs := hostServiceAddresses(services)
+	if addrs["api"] == addrs["postgres"] {
+		t.Errorf("services share address %s", addrs["api"])
+	}
+	for name, addr := range addrs {
+		if !strings.HasPrefix(addr, "127.1.") {
+			t.Errorf("%s address = %s, want 127.1.x.y", name, addr)
+		}
+	}
+
+	// Adding a service doesn't move the others
+	more := hostServiceAddresses(append(services, security.HostService{Name: "redis", Port: 6379}))
+	if more["postgres"] != addrs["postgres"] || more["api"] != addrs["api"] {
+		t.Errorf("addresses changed when adding a service: %v, then %v", addrs, more)
+	}
+}
+
+func TestHostServiceArgs(t *testing.T) {
+	cfg := &provider.Config{HostServices: []security.HostService{{Name: "postgres", Port: 5432}}}
+	p := newTestProvider(DockerRuntime("desktop-linux"), cfg)
+	if args := p.hostServiceEnvArgs(); args != nil {
+		t.Errorf("hostServiceEnvArgs() before start = %v, want nil", args)
+	}
+
+	if err := p.startHostServices("addt-test", false); err != nil {
+		t.Fatalf("startHostServices() error = %v", err)
+	}
+	defer p.stopHostServices()
+
+	addr := hostServiceAddresses(cfg.HostServices)["postgres"]
+	hosts := strings.Join(p.hostServiceHostArgs(), " ")
+	if hosts != "--add-host=postgres.host.addt:"+addr {
+		t.Errorf("hostServiceHostArgs() = %s", hosts)
+	}
+	env := strings.Join(p.hostServiceEnvArgs(), " ")
+	want := "ADDT_HOST_SERVICES=postgres=" + addr + ":5432:"
+	if !strings.Contains(env, want) || !strings.Contains(env, "ADDT_HOST_SERVICES_HOST=host.docker.internal") {
+		t.Errorf("hostServiceEnvArgs() = %s, missing %s", env, want)
+	}
+}
+
+func TestStartHostServices_NoNetwork(t *testing.T) {
+	cfg := &provider.Config{
+		HostServices: []security.HostService{{Name: "postgres", Port: 5432}},
+		Security:     security.Config{NetworkMode: "none"},
+	}
+	p := newTestProvider(DockerRuntime("desktop-linux"), cfg)
+	if err := p.startHostServices("addt-test", false); err != nil {
+		t.Fatalf("startHostServices() error = %v", err)
+	}
+	if p.hostServices != nil {
+		t.Error("host services started without a network")
+	}
+}
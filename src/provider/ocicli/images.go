@@ -1,4 +1,4 @@
-package docker
+package ocicli
 
 import (
 	"crypto/sha256"
@@ -8,53 +8,30 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
-	"strings"
 
 	profilecmd "github.com/jedi4ever/addt/cmd/profile"
 	"github.com/jedi4ever/addt/extensions"
 	"github.com/jedi4ever/addt/util"
 )
 
-// ImageExists checks if a Docker image exists
-func (p *DockerProvider) ImageExists(imageName string) bool {
-	cmd := p.dockerCmd("image", "inspect", imageName)
-	return cmd.Run() == nil
+// ImageExists checks if an image exists
+func (p *Provider) ImageExists(imageName string) bool {
+	return p.backend.ImageExists(imageName)
 }
 
 // FindImageByLabel finds an image by a specific label value
-func (p *DockerProvider) FindImageByLabel(label, value string) string {
-	cmd := p.dockerCmd("images",
-		"--filter", fmt.Sprintf("label=%s=%s", label, value),
-		"--format", "{{.Repository}}:{{.Tag}}")
-	output, err := cmd.Output()
-	if err != nil {
-		return ""
-	}
-
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	for _, line := range lines {
-		if line != "" && !strings.Contains(line, "<none>") {
-			return line
-		}
-	}
-	return ""
+func (p *Provider) FindImageByLabel(label, value string) string {
+	return p.backend.FindImageByLabel(label, value)
 }
 
 // GetImageLabel retrieves a specific label value from an image
-func (p *DockerProvider) GetImageLabel(imageName, label string) string {
-	cmd := p.dockerCmd("inspect",
-		"--format", fmt.Sprintf("{{index .Config.Labels %q}}", label),
-		imageName)
-	output, err := cmd.Output()
-	if err != nil {
-		return ""
-	}
-	return strings.TrimSpace(string(output))
+func (p *Provider) GetImageLabel(imageName, label string) string {
+	return p.backend.ImageLabel(imageName, label)
 }
 
 // assetsHash returns a short hash of the base image assets (Dockerfile.base, entrypoint, firewall)
 // Used in base image tags so that changes to these files trigger a base rebuild
-func (p *DockerProvider) assetsHash() string {
+func (p *Provider) assetsHash() string {
 	h := sha256.New()
 	h.Write(p.embeddedDockerfileBase)
 	h.Write(p.embeddedEntrypoint)
@@ -88,8 +65,8 @@ func hashDir(h hash.Hash, dir string, logger *util.ModuleLogger, fileCount *int,
 
 // extAssetsHash returns a short hash of the extension layer assets
 // (Dockerfile, install.sh, extensions/) so changes trigger an extension image rebuild
-func (p *DockerProvider) extAssetsHash() string {
-	logger := util.Log("docker-hash")
+func (p *Provider) extAssetsHash() string {
+	logger := util.Log(p.rt.Name + "-hash")
 	h := sha256.New()
 	h.Write(p.embeddedDockerfile)
 	h.Write(p.embeddedInstallSh)
@@ -141,7 +118,7 @@ func (p *DockerProvider) extAssetsHash() string {
 }
 
 // GetBaseImageName returns the base image name for the current config
-func (p *DockerProvider) GetBaseImageName() string {
+func (p *Provider) GetBaseImageName() string {
 	currentUser, err := user.Current()
 	if err != nil {
 		return "addt-base:latest"
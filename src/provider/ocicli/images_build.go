@@ -1,4 +1,4 @@
-package orbstack
+package ocicli
 
 import (
 	"fmt"
@@ -15,8 +15,8 @@ import (
 	"github.com/jedi4ever/addt/util"
 )
 
-// BuildBaseImage builds the base Docker image (contains Node, Go, UV, system packages)
-func (p *OrbStackProvider) BuildBaseImage() error {
+// BuildBaseImage builds the base image (contains Node, Go, UV, system packages)
+func (p *Provider) BuildBaseImage() error {
 	baseImageName := p.GetBaseImageName()
 	startTime := time.Now()
 
@@ -37,9 +37,9 @@ func (p *OrbStackProvider) BuildBaseImage() error {
 	}
 
 	// Write embedded entrypoint script
-	entrypointPath := filepath.Join(buildDir, "orbstack-entrypoint.sh")
+	entrypointPath := filepath.Join(buildDir, p.rt.EntrypointFile)
 	if err := os.WriteFile(entrypointPath, p.embeddedEntrypoint, 0755); err != nil {
-		return fmt.Errorf("failed to write orbstack-entrypoint.sh: %w", err)
+		return fmt.Errorf("failed to write %s: %w", p.rt.EntrypointFile, err)
 	}
 
 	// Write embedded firewall init script
@@ -56,24 +56,23 @@ func (p *OrbStackProvider) BuildBaseImage() error {
 	uid := currentUser.Uid
 	gid := currentUser.Gid
 
-	// Build docker command for base image
-	args := []string{
-		"build",
-		"--build-arg", fmt.Sprintf("NODE_VERSION=%s", p.config.NodeVersion),
-		"--build-arg", fmt.Sprintf("GO_VERSION=%s", p.config.GoVersion),
-		"--build-arg", fmt.Sprintf("UV_VERSION=%s", p.config.UvVersion),
-		"--build-arg", fmt.Sprintf("USER_ID=%s", uid),
-		"--build-arg", fmt.Sprintf("GROUP_ID=%s", gid),
-		"--build-arg", "USERNAME=addt",
-		"-t", baseImageName,
-		"-f", dockerfilePath,
-		buildDir,
-	}
-
-	// Run build with progress indication
-	if err := util.RunBuildCommandWithEnv("docker", args, p.dockerEnv()); err != nil {
+	// Build base image with progress indication
+	req := BuildRequest{
+		ContextDir: buildDir,
+		Dockerfile: dockerfilePath,
+		Tag:        baseImageName,
+		BuildArgs: []string{
+			fmt.Sprintf("NODE_VERSION=%s", p.config.NodeVersion),
+			fmt.Sprintf("GO_VERSION=%s", p.config.GoVersion),
+			fmt.Sprintf("UV_VERSION=%s", p.config.UvVersion),
+			fmt.Sprintf("USER_ID=%s", uid),
+			fmt.Sprintf("GROUP_ID=%s", gid),
+			"USERNAME=addt",
+		},
+	}
+	if err := p.backend.BuildImage(req); err != nil {
 		util.PrintError(fmt.Sprintf("Failed to build base image: %v", err))
-		return fmt.Errorf("failed to build base Docker image: %w", err)
+		return fmt.Errorf("failed to build base %s image: %w", p.rt.Name, err)
 	}
 
 	elapsed := time.Since(startTime)
@@ -83,7 +82,7 @@ func (p *OrbStackProvider) BuildBaseImage() error {
 }
 
 // EnsureBaseImage checks if base image exists and builds it if needed
-func (p *OrbStackProvider) EnsureBaseImage(forceRebuild bool) error {
+func (p *Provider) EnsureBaseImage(forceRebuild bool) error {
 	baseImageName := p.GetBaseImageName()
 
 	if forceRebuild || !p.ImageExists(baseImageName) {
@@ -94,8 +93,8 @@ func (p *OrbStackProvider) EnsureBaseImage(forceRebuild bool) error {
 	return nil
 }
 
-// BuildImage builds the Docker image (extension layer on top of base)
-func (p *OrbStackProvider) BuildImage(embeddedDockerfile, embeddedEntrypoint []byte) error {
+// BuildImage builds the image (extension layer on top of base)
+func (p *Provider) BuildImage(embeddedDockerfile, embeddedEntrypoint []byte) error {
 	// First ensure base image exists
 	if err := p.EnsureBaseImage(false); err != nil {
 		return fmt.Errorf("failed to ensure base image: %w", err)
@@ -121,9 +120,9 @@ func (p *OrbStackProvider) BuildImage(embeddedDockerfile, embeddedEntrypoint []b
 	}
 
 	// Write embedded entrypoint script
-	entrypointPath := filepath.Join(buildDir, "docker-entrypoint.sh")
+	entrypointPath := filepath.Join(buildDir, p.rt.EntrypointFile)
 	if err := os.WriteFile(entrypointPath, embeddedEntrypoint, 0755); err != nil {
-		return fmt.Errorf("failed to write docker-entrypoint.sh: %w", err)
+		return fmt.Errorf("failed to write %s: %w", p.rt.EntrypointFile, err)
 	}
 
 	// Write embedded firewall init script
@@ -196,27 +195,21 @@ func (p *OrbStackProvider) BuildImage(embeddedDockerfile, embeddedEntrypoint []b
 	}
 	extensionVersions := strings.Join(versionPairs, ",")
 
-	// Build docker command - use base image and only pass extension args
-	args := []string{"build"}
-
-	// Add --no-cache if requested
-	if p.config.NoCache {
-		args = append(args, "--no-cache")
-	}
-
-	args = append(args,
-		"--build-arg", fmt.Sprintf("BASE_IMAGE=%s", baseImageName),
-		"--build-arg", fmt.Sprintf("ADDT_EXTENSIONS=%s", p.config.Extensions),
-		"--build-arg", fmt.Sprintf("EXTENSION_VERSIONS=%s", extensionVersions),
-		"-t", p.config.ImageName,
-		"-f", dockerfilePath,
-		scriptDir,
-	)
-
-	// Run build with progress indication
-	if err := util.RunBuildCommandWithEnv("docker", args, p.dockerEnv()); err != nil {
+	// Build with progress indication - use base image and only pass extension args
+	req := BuildRequest{
+		ContextDir: scriptDir,
+		Dockerfile: dockerfilePath,
+		Tag:        p.config.ImageName,
+		NoCache:    p.config.NoCache,
+		BuildArgs: []string{
+			fmt.Sprintf("BASE_IMAGE=%s", baseImageName),
+			fmt.Sprintf("ADDT_EXTENSIONS=%s", p.config.Extensions),
+			fmt.Sprintf("EXTENSION_VERSIONS=%s", extensionVersions),
+		},
+	}
+	if err := p.backend.BuildImage(req); err != nil {
 		util.PrintError(fmt.Sprintf("Failed to build image: %v", err))
-		return fmt.Errorf("failed to build Docker image: %w", err)
+		return fmt.Errorf("failed to build %s image: %w", p.rt.Name, err)
 	}
 
 	elapsed := time.Since(startTime)
@@ -253,7 +246,7 @@ func (p *OrbStackProvider) BuildImage(embeddedDockerfile, embeddedEntrypoint []b
 	return nil
 }
 
-func (p *OrbStackProvider) detectToolVersions(imageName string) map[string]string {
+func (p *Provider) detectToolVersions(imageName string) map[string]string {
 	versions := make(map[string]string)
 	versionRegex := regexp.MustCompile(`[0-9]+\.[0-9]+\.[0-9]+`)
 
@@ -270,9 +263,7 @@ func (p *OrbStackProvider) detectToolVersions(imageName string) map[string]strin
 
 	for name, cmdArgs := range tools {
 		spinner.UpdateMessage(fmt.Sprintf("Detecting %s version...", name))
-		args := append([]string{"run", "--rm", "--entrypoint", cmdArgs[0], imageName}, cmdArgs[1:]...)
-		cmd := p.dockerCmd(args...)
-		output, err := cmd.Output()
+		output, err := p.backend.RunOutput(imageName, cmdArgs[0], cmdArgs[1:]...)
 		if err == nil {
 			if match := versionRegex.FindString(string(output)); match != "" {
 				versions[name] = match
@@ -284,19 +275,19 @@ func (p *OrbStackProvider) detectToolVersions(imageName string) map[string]strin
 	return versions
 }
 
-func (p *OrbStackProvider) addVersionLabels(cfg interface{}, versions map[string]string) {
+func (p *Provider) addVersionLabels(cfg interface{}, versions map[string]string) {
 	// Get ImageName from config (using p.config directly)
 	imageName := p.config.ImageName
 	if imageName == "" {
 		return
 	}
 
-	// Create temporary Dockerfile
-	tmpFile, err := os.CreateTemp("", "Dockerfile-labels-*")
+	// Create a build context holding only the label Dockerfile
+	buildDir, err := os.MkdirTemp("", "addt-labels-*")
 	if err != nil {
 		return
 	}
-	defer os.Remove(tmpFile.Name())
+	defer os.RemoveAll(buildDir)
 
 	content := fmt.Sprintf("FROM %s\n", imageName)
 	for tool, version := range versions {
@@ -304,33 +295,35 @@ func (p *OrbStackProvider) addVersionLabels(cfg interface{}, versions map[string
 			content += fmt.Sprintf("LABEL tools.%s.version=\"%s\"\n", tool, version)
 		}
 	}
-	tmpFile.WriteString(content)
-	tmpFile.Close()
+	dockerfilePath := filepath.Join(buildDir, "Dockerfile")
+	if err := os.WriteFile(dockerfilePath, []byte(content), 0644); err != nil {
+		return
+	}
 
 	// Build with labels
-	cmd := p.dockerCmd("build", "-f", tmpFile.Name(), "-t", imageName, ".")
-	if err := cmd.Run(); err != nil {
+	req := BuildRequest{ContextDir: buildDir, Dockerfile: dockerfilePath, Tag: imageName, Quiet: true}
+	if err := p.backend.BuildImage(req); err != nil {
 		fmt.Printf("Warning: failed to add version labels: %v\n", err)
 	}
 
 	// Tag as addt:latest if this is latest
 	claudeVersion := p.getExtensionVersion("claude")
 	if claudeVersion == "latest" {
-		if err := p.dockerCmd("tag", imageName, "addt:latest").Run(); err != nil {
+		if err := p.backend.TagImage(imageName, "addt:latest"); err != nil {
 			fmt.Printf("Warning: failed to tag as addt:latest: %v\n", err)
 		}
 	}
 
 	// Tag with claude version
 	if v, ok := versions["claude"]; ok && v != "" {
-		if err := p.dockerCmd("tag", imageName, fmt.Sprintf("addt:claude-%s", v)).Run(); err != nil {
+		if err := p.backend.TagImage(imageName, fmt.Sprintf("addt:claude-%s", v)); err != nil {
 			fmt.Printf("Warning: failed to tag with claude version: %v\n", err)
 		}
 	}
 }
 
 // copyLocalExtensions copies local extensions to the build directory, overwriting embedded ones
-func (p *OrbStackProvider) copyLocalExtensions(srcDir, destDir string) error {
+func (p *Provider) copyLocalExtensions(srcDir, destDir string) error {
 	entries, err := os.ReadDir(srcDir)
 	if err != nil {
 		return err
@@ -0,0 +1,165 @@
+package ocicli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/provider"
+)
+
+// containerInspect is the subset of `container inspect` output addt reads.
+// Docker, Podman and nerdctl (dockercompat mode) share this layout; Podman
+// additionally reports ImageName, nerdctl the image name in Image.
+type containerInspect struct {
+	ID        string `json:"Id"`
+	Name      string `json:"Name"`
+	Image     string `json:"Image"`
+	ImageName string `json:"ImageName"`
+	Created   string `json:"Created"`
+	State     struct {
+		Status    string `json:"Status"`
+		Running   bool   `json:"Running"`
+		ExitCode  int    `json:"ExitCode"`
+		StartedAt string `json:"StartedAt"`
+	} `json:"State"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	Mounts []struct {
+		Source      string `json:"Source"`
+		Destination string `json:"Destination"`
+		RW          bool   `json:"RW"`
+	} `json:"Mounts"`
+	NetworkSettings struct {
+		Ports map[string][]struct {
+			HostPort string `json:"HostPort"`
+		} `json:"Ports"`
+	} `json:"NetworkSettings"`
+}
+
+// parseInspect converts `container inspect` output (a JSON array with one
+// element) into an EnvironmentInfo
+func parseInspect(data []byte) (*provider.EnvironmentInfo, error) {
+	var containers []containerInspect
+	if err := json.Unmarshal(data, &containers); err != nil {
+		return nil, fmt.Errorf("failed to parse inspect output: %w", err)
+	}
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("inspect returned no containers")
+	}
+	c := containers[0]
+
+	image := c.Config.Image
+	if image == "" {
+		image = c.ImageName
+	}
+	if image == "" {
+		image = c.Image
+	}
+	name := strings.TrimPrefix(c.Name, "/")
+	info := &provider.EnvironmentInfo{
+		Name:       name,
+		ID:         c.ID,
+		Image:      image,
+		Status:     c.State.Status,
+		Running:    c.State.Running,
+		ExitCode:   c.State.ExitCode,
+		Persistent: IsPersistentContainer(name),
+		CreatedAt:  parseTime(c.Created),
+		StartedAt:  parseTime(c.State.StartedAt),
+		Labels:     c.Config.Labels,
+	}
+	for _, m := range c.Mounts {
+		info.Mounts = append(info.Mounts, provider.VolumeMount{Source: m.Source, Target: m.Destination, ReadOnly: !m.RW})
+	}
+	info.Ports = parsePorts(c.NetworkSettings.Ports)
+	return info, nil
+}
+
+// parsePorts converts {"3000/tcp": [{"HostPort": "30000"}]} bindings into
+// port mappings, sorted by container port
+func parsePorts(ports map[string][]struct {
+	HostPort string `json:"HostPort"`
+}) []provider.PortMapping {
+	var mappings []provider.PortMapping
+	for spec, bindings := range ports {
+		port, _, _ := strings.Cut(spec, "/")
+		containerPort, err := strconv.Atoi(port)
+		if err != nil {
+			continue
+		}
+		for _, binding := range bindings {
+			if hostPort, err := strconv.Atoi(binding.HostPort); err == nil {
+				mappings = append(mappings, provider.PortMapping{Container: containerPort, Host: hostPort})
+				break // IPv4 and IPv6 bindings repeat the same port
+			}
+		}
+	}
+	sort.Slice(mappings, func(i, j int) bool { return mappings[i].Container < mappings[j].Container })
+	return mappings
+}
+
+// parseTime parses an RFC 3339 timestamp; the zero time stands for unknown
+// (Docker reports "0001-01-01T00:00:00Z" for containers never started)
+func parseTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil || t.Year() <= 1 {
+		return time.Time{}
+	}
+	return t
+}
+
+// checkpointAnnotation is set on Podman's CRIU checkpoint images
+const checkpointAnnotation = "io.podman.annotations.checkpoint.name"
+
+// imageInspect is the subset of `image inspect` output addt reads
+type imageInspect struct {
+	Created string `json:"Created"`
+	Config  struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	Annotations map[string]string `json:"Annotations"`
+}
+
+// parseImageInspect converts `image inspect` output for ref into an
+// ImageSummary
+func parseImageInspect(ref string, data []byte) (*ImageSummary, error) {
+	var images []imageInspect
+	if err := json.Unmarshal(data, &images); err != nil {
+		return nil, fmt.Errorf("failed to parse image inspect output: %w", err)
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("image inspect returned no images")
+	}
+	img := images[0]
+	return &ImageSummary{
+		Ref:        ref,
+		Created:    parseTime(img.Created),
+		Labels:     img.Config.Labels,
+		Checkpoint: img.Annotations[checkpointAnnotation] != "",
+	}, nil
+}
+
+// matchImageRefs picks the "repo:tag" lines of `images` output whose
+// repository starts with prefix, dropping the registry prefixes runtimes
+// put on local images (Podman's localhost/, nerdctl's docker.io/library/)
+func matchImageRefs(output, prefix string) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		ref := strings.TrimSpace(line)
+		ref = strings.TrimPrefix(ref, "localhost/")
+		ref = strings.TrimPrefix(ref, "docker.io/library/")
+		if !strings.HasPrefix(ref, prefix) || strings.Contains(ref, "<none>") || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
+	}
+	return refs
+}
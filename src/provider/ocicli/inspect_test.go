@@ -0,0 +1,117 @@
+package ocicli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jedi4ever/addt/provider"
+)
+
+func TestParseInspect_Docker(t *testing.T) {
+	output := `[{
+		"Id": "4f2c",
+		"Name": "/addt-persistent-myproject-1a2b3c4d",
+		"Image": "sha256:9e1f",
+		"Created": "2026-01-02T10:00:00.123456789Z",
+		"State": {"Status": "running", "Running": true, "ExitCode": 0, "StartedAt": "2026-01-02T10:00:01Z"},
+		"Config": {"Image": "addt:claude", "Labels": {"tools.node.version": "22"}},
+		"Mounts": [{"Source": "/home/u/myproject", "Destination": "/workspace", "RW": true},
+		           {"Source": "/home/u/.gitconfig", "Destination": "/home/addt/.gitconfig", "RW": false}],
+		"NetworkSettings": {"Ports": {
+			"8080/tcp": [{"HostIp": "0.0.0.0", "HostPort": "30001"}, {"HostIp": "::", "HostPort": "30001"}],
+			"3000/tcp": [{"HostIp": "0.0.0.0", "HostPort": "30000"}],
+			"9229/tcp": null}}
+	}]`
+
+	info, err := parseInspect([]byte(output))
+	if err != nil {
+		t.Fatalf("parseInspect() error = %v", err)
+	}
+	if info.Name != "addt-persistent-myproject-1a2b3c4d" || !info.Persistent || !info.Running {
+		t.Errorf("info = %+v", info)
+	}
+	if info.Image != "addt:claude" || info.Labels["tools.node.version"] != "22" {
+		t.Errorf("image = %q, labels = %v", info.Image, info.Labels)
+	}
+	if info.CreatedAt.IsZero() || info.StartedAt.Second() != 1 {
+		t.Errorf("times = %v, %v", info.CreatedAt, info.StartedAt)
+	}
+	wantMounts := []provider.VolumeMount{
+		{Source: "/home/u/myproject", Target: "/workspace"},
+		{Source: "/home/u/.gitconfig", Target: "/home/addt/.gitconfig", ReadOnly: true},
+	}
+	if !reflect.DeepEqual(info.Mounts, wantMounts) {
+		t.Errorf("mounts = %+v", info.Mounts)
+	}
+	wantPorts := []provider.PortMapping{{Container: 3000, Host: 30000}, {Container: 8080, Host: 30001}}
+	if !reflect.DeepEqual(info.Ports, wantPorts) {
+		t.Errorf("ports = %+v", info.Ports)
+	}
+}
+
+func TestParseInspect_PodmanStopped(t *testing.T) {
+	output := `[{
+		"Id": "77aa",
+		"Name": "addt-20260102-100000-42",
+		"ImageName": "localhost/addt:claude",
+		"Created": "2026-01-02T11:00:00.5+01:00",
+		"State": {"Status": "exited", "Running": false, "ExitCode": 137, "StartedAt": "0001-01-01T00:00:00Z"},
+		"Config": {}
+	}]`
+
+	info, err := parseInspect([]byte(output))
+	if err != nil {
+		t.Fatalf("parseInspect() error = %v", err)
+	}
+	if info.Image != "localhost/addt:claude" || info.Persistent || info.ExitCode != 137 || info.Status != "exited" {
+		t.Errorf("info = %+v", info)
+	}
+	if !info.StartedAt.IsZero() {
+		t.Errorf("never-started timestamp should be zero, got %v", info.StartedAt)
+	}
+	if _, err := parseInspect([]byte(`[]`)); err == nil {
+		t.Error("empty inspect output should be an error")
+	}
+}
+
+func TestExecArgs(t *testing.T) {
+	got := execArgs("addt-x", []string{"ls", "-la"}, false)
+	if !reflect.DeepEqual(got, []string{"exec", "-i", "addt-x", "ls", "-la"}) {
+		t.Errorf("execArgs() = %v", got)
+	}
+	got = execArgs("addt-x", []string{"/bin/bash"}, true)
+	if !reflect.DeepEqual(got, []string{"exec", "-i", "-t", "addt-x", "/bin/bash"}) {
+		t.Errorf("execArgs(tty) = %v", got)
+	}
+}
+
+func TestCommitArgs(t *testing.T) {
+	got := commitArgs("addt-x", "addt-snapshot-addt-x:t1", map[string]string{"b": "2", "a": "one two"})
+	want := []string{"commit", "--change", `LABEL a="one two"`, "--change", `LABEL b="2"`, "addt-x", "addt-snapshot-addt-x:t1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("commitArgs() = %q", got)
+	}
+	if got := commitArgs("addt-x", "img:t", nil); !reflect.DeepEqual(got, []string{"commit", "addt-x", "img:t"}) {
+		t.Errorf("commitArgs(no labels) = %q", got)
+	}
+}
+
+func TestMatchImageRefs(t *testing.T) {
+	output := "addt:claude\nlocalhost/addt-snapshot-x:t1\ndocker.io/library/addt-snapshot-y:t2\naddt-snapshot-x:t1\n<none>:<none>\n"
+	got := matchImageRefs(output, "addt-snapshot-")
+	if !reflect.DeepEqual(got, []string{"addt-snapshot-x:t1", "addt-snapshot-y:t2"}) {
+		t.Errorf("matchImageRefs() = %v", got)
+	}
+}
+
+func TestParseImageInspect_Checkpoint(t *testing.T) {
+	output := `[{"Created": "2026-01-02T10:00:00Z", "Config": {"Labels": null},
+		"Annotations": {"io.podman.annotations.checkpoint.name": "addt-x"}}]`
+	img, err := parseImageInspect("addt-snapshot-x:t1", []byte(output))
+	if err != nil {
+		t.Fatalf("parseImageInspect() error = %v", err)
+	}
+	if !img.Checkpoint || img.Created.IsZero() || img.Ref != "addt-snapshot-x:t1" {
+		t.Errorf("image = %+v", img)
+	}
+}
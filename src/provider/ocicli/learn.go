@@ -0,0 +1,60 @@
+package ocicli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jedi4ever/addt/config/security"
+)
+
+// firewallLearner returns the session's learner when the firewall is
+// learning, creating it on first use
+func (p *Provider) firewallLearner() *security.FirewallLearner {
+	if !p.config.FirewallLearn {
+		return nil
+	}
+	if p.learner == nil {
+		p.learner = security.NewFirewallLearner()
+	}
+	return p.learner
+}
+
+// saveFirewallLearn adds what the session observed to the project's learn
+// file for 'addt firewall learn review'
+func (p *Provider) saveFirewallLearn() {
+	if p.learner == nil {
+		return
+	}
+	dests := p.learner.Destinations()
+	p.learner = nil
+	if len(dests) == 0 {
+		fmt.Println("Firewall learn: no destinations observed")
+		return
+	}
+
+	projectDir := p.config.Workdir
+	if projectDir == "" {
+		projectDir, _ = os.Getwd()
+	}
+	session, err := security.LoadLearnSession(projectDir)
+	if err != nil {
+		fmt.Printf("Warning: failed to load learned destinations: %v\n", err)
+		return
+	}
+	session.Extension, _, _ = strings.Cut(p.config.Extensions, ",")
+	session.Merge(dests)
+	if err := session.Save(); err != nil {
+		fmt.Printf("Warning: failed to save learned destinations: %v\n", err)
+		return
+	}
+
+	blocked := 0
+	for _, d := range dests {
+		if allowed, _ := p.config.FirewallRules.Check(d.Host); !allowed {
+			blocked++
+		}
+	}
+	fmt.Printf("Firewall learn: %d destinations observed, %d not allowed by the current rules\n", len(dests), blocked)
+	fmt.Println("Review them with 'addt firewall learn review'")
+}
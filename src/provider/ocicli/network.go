@@ -0,0 +1,66 @@
+package ocicli
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// getHostGatewayIP detects the host's IP address that is reachable from containers.
+// On macOS, containers run inside a Linux VM (Docker Desktop, podman machine), and
+// "host-gateway" resolution isn't always configured. This function detects the
+// host's outbound IP via the routing table for TCP-based proxy connections.
+func getHostGatewayIP() (string, error) {
+	// Use a UDP dial to determine the outbound IP without sending any traffic.
+	// The OS routing table resolves which local IP would be used to reach 8.8.8.8.
+	conn, err := net.Dial("udp", "8.8.8.8:80")
+	if err != nil {
+		return "", fmt.Errorf("failed to detect host IP: %w", err)
+	}
+	defer conn.Close()
+
+	localAddr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return "", fmt.Errorf("unexpected address type from UDP dial")
+	}
+
+	return localAddr.IP.String(), nil
+}
+
+// helperListenIP returns the address host-side helpers (egress proxy, API
+// proxy, ...) listen on: the one host.docker.internal leads to, rather
+// than every interface, so hosts on the network can't reach them. That is
+// the detected host IP where the runtime is given it, the docker0 bridge
+// with a native Linux engine, and loopback where a VM (Docker Desktop,
+// OrbStack, Rancher Desktop) forwards host.docker.internal to the host.
+func (p *Provider) helperListenIP() string {
+	if p.rt.DetectHostGateway {
+		if ip, err := getHostGatewayIP(); err == nil {
+			return ip
+		}
+	}
+	if runtime.GOOS == "linux" && p.rt.Context == "" {
+		if ip := interfaceIPv4("docker0"); ip != "" {
+			return ip
+		}
+	}
+	return "127.0.0.1"
+}
+
+// interfaceIPv4 returns a network interface's first IPv4 address, or ""
+func interfaceIPv4(name string) string {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return ""
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return ""
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package ocicli
+
+import (
+	"net"
+	"testing"
+)
+
+func TestGetHostGatewayIP(t *testing.T) {
+	ip, err := getHostGatewayIP()
+	if err != nil {
+		t.Skipf("Skipping: no network available to detect host IP: %v", err)
+	}
+
+	if ip == "" {
+		t.Fatal("expected non-empty IP address")
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		t.Fatalf("returned value %q is not a valid IP address", ip)
+	}
+
+	if parsed.IsLoopback() {
+		t.Fatalf("expected non-loopback IP, got %s", ip)
+	}
+}
+
+func TestHelperListenIP(t *testing.T) {
+	// A VM-backed runtime forwards host.docker.internal to the host's loopback
+	p := newTestProvider(DockerRuntime("desktop-linux"), nil)
+	if got := p.helperListenIP(); got != "127.0.0.1" {
+		t.Errorf("helperListenIP() = %s, want 127.0.0.1", got)
+	}
+
+	// Runtimes given the host IP listen there, never on every interface
+	p = newTestProvider(PodmanRuntime(), nil)
+	ip := net.ParseIP(p.helperListenIP())
+	if ip == nil || ip.IsUnspecified() {
+		t.Errorf("helperListenIP() = %v, want a specific address", ip)
+	}
+}
@@ -0,0 +1,127 @@
+package ocicli
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/util"
+)
+
+// mirrorSocketDir is where a container without a network finds the
+// package mirror's socket
+const mirrorSocketDir = "/run/addt-mirror"
+
+// startPackageMirror starts the package mirror for an offline container.
+// A container with a network reaches it on the host over TCP, which the
+// strict firewall allows as its only destination; a container without one
+// (security.network_mode none) gets its socket mounted instead. Like the
+// egress proxy, a persistent container keeps its port and socket.
+func (p *Provider) startPackageMirror(name string, persistent bool) error {
+	if !p.config.OfflineEnabled || p.packageMirror != nil {
+		return nil
+	}
+	mirror := security.NewPackageMirror(name, security.MirrorDir(), p.config.OfflineFetch)
+
+	if p.config.Security.NetworkMode == "none" {
+		if runtime.GOOS == "darwin" && !p.rt.SocketMountsOnDarwin {
+			fmt.Println("Warning: the package mirror is unreachable with security.network_mode none on this runtime on macOS")
+			return nil
+		}
+		dir := mirrorSocketHostDir(name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create package mirror socket directory: %w", err)
+		}
+		sock := filepath.Join(dir, "sock")
+		os.Remove(sock)
+		if err := mirror.Start("unix", sock); err != nil {
+			return fmt.Errorf("failed to start package mirror: %w", err)
+		}
+		if !persistent {
+			p.tempDirs = append(p.tempDirs, dir)
+		}
+		p.mirrorSocketDir = dir
+	} else {
+		port := 0
+		if persistent {
+			port = packageMirrorPort(name)
+		}
+		host := p.helperListenIP()
+		if err := mirror.Start("tcp", net.JoinHostPort(host, strconv.Itoa(port))); err != nil {
+			if port == 0 {
+				return fmt.Errorf("failed to start package mirror: %w", err)
+			}
+			fmt.Printf("Warning: package mirror port %d is taken, %s will need to be recreated to reach it\n", port, name)
+			if err := mirror.Start("tcp", net.JoinHostPort(host, "0")); err != nil {
+				return fmt.Errorf("failed to start package mirror: %w", err)
+			}
+		}
+	}
+	p.packageMirror = mirror
+
+	if p.config.OfflineFetch {
+		fmt.Println("Offline: packages come from the addt package mirror (fetching what isn't cached)")
+	} else {
+		fmt.Println("Offline: packages come from the addt package mirror (cached packages only)")
+	}
+	return nil
+}
+
+// mirrorSocketHostDir is the host directory holding a container's package
+// mirror socket, mounted at mirrorSocketDir
+func mirrorSocketHostDir(name string) string {
+	return filepath.Join(util.GetAddtHome(), "run", "mirror", name)
+}
+
+// packageMirrorPort derives a persistent container's package mirror port,
+// below the host service forwarder's range
+func packageMirrorPort(name string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return 10000 + int(h.Sum32()%10000)
+}
+
+// packageMirrorMountArgs mounts the package mirror's socket directory into
+// a new container without a network
+func (p *Provider) packageMirrorMountArgs() []string {
+	if p.packageMirror == nil || p.mirrorSocketDir == "" {
+		return nil
+	}
+	return []string{"-v", fmt.Sprintf("%s:%s", p.mirrorSocketDir, mirrorSocketDir)}
+}
+
+// packageMirrorEnvArgs tells the entrypoint where to bridge the container's
+// 127.0.0.1:4873 to, and the firewall script the only destination to allow:
+// ADDT_OFFLINE_MIRROR=<host>:<port>, or unix:<socket>
+func (p *Provider) packageMirrorEnvArgs() []string {
+	if p.packageMirror == nil {
+		return nil
+	}
+	if p.mirrorSocketDir != "" {
+		return []string{"-e", "ADDT_OFFLINE_MIRROR=unix:" + mirrorSocketDir + "/sock"}
+	}
+	return []string{"-e", fmt.Sprintf("ADDT_OFFLINE_MIRROR=%s:%d", egressProxyHost, p.packageMirror.Port())}
+}
+
+// stopPackageMirror stops the package mirror and says how it served the
+// session
+func (p *Provider) stopPackageMirror() {
+	if p.packageMirror == nil {
+		return
+	}
+	p.packageMirror.Stop()
+	stats := p.packageMirror.Stats()
+	if stats.NotCached > 0 {
+		fmt.Printf("Package mirror: %d cached, %d fetched, %d unavailable (warm the cache with 'addt cache warm <lockfile>')\n",
+			stats.Cached, stats.Fetched, stats.NotCached)
+	} else if stats.Cached+stats.Fetched > 0 {
+		p.logger.Debugf("Package mirror: %d cached, %d fetched", stats.Cached, stats.Fetched)
+	}
+	p.packageMirror = nil
+	p.mirrorSocketDir = ""
+}
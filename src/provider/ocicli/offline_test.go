@@ -0,0 +1,89 @@
+package ocicli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/provider"
+)
+
+func TestPackageMirrorArgs_TCP(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+	cfg := &provider.Config{
+		OfflineEnabled:  true,
+		FirewallEnabled: true,
+		FirewallMode:    "strict",
+		FirewallProxy:   true,
+	}
+	p := newTestProvider(DockerRuntime("desktop-linux"), cfg)
+	if err := p.startPackageMirror("addt-test", false); err != nil {
+		t.Fatalf("startPackageMirror() error = %v", err)
+	}
+	defer p.stopPackageMirror()
+
+	if p.egressEnabled() {
+		t.Error("egressEnabled() = true offline, want false")
+	}
+	if args := p.packageMirrorMountArgs(); args != nil {
+		t.Errorf("packageMirrorMountArgs() = %v, want nil over TCP", args)
+	}
+	env := strings.Join(p.packageMirrorEnvArgs(), " ")
+	if !strings.HasPrefix(env, "-e ADDT_OFFLINE_MIRROR=host.docker.internal:") {
+		t.Errorf("packageMirrorEnvArgs() = %s", env)
+	}
+}
+
+func TestPackageMirrorArgs_NoNetwork(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("ADDT_HOME", home)
+	cfg := &provider.Config{
+		OfflineEnabled: true,
+		Security:       security.Config{NetworkMode: "none"},
+	}
+	p := newTestProvider(DockerRuntime("desktop-linux"), cfg)
+	if err := p.startPackageMirror("addt-test", false); err != nil {
+		t.Fatalf("startPackageMirror() error = %v", err)
+	}
+
+	dir := filepath.Join(home, "run", "mirror", "addt-test")
+	if _, err := os.Stat(filepath.Join(dir, "sock")); err != nil {
+		t.Errorf("mirror socket missing: %v", err)
+	}
+	mount := strings.Join(p.packageMirrorMountArgs(), " ")
+	if mount != "-v "+dir+":/run/addt-mirror" {
+		t.Errorf("packageMirrorMountArgs() = %s", mount)
+	}
+	env := strings.Join(p.packageMirrorEnvArgs(), " ")
+	if env != "-e ADDT_OFFLINE_MIRROR=unix:/run/addt-mirror/sock" {
+		t.Errorf("packageMirrorEnvArgs() = %s", env)
+	}
+
+	// An ephemeral container's socket directory goes with it
+	p.Cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("socket directory left behind after Cleanup()")
+	}
+}
+
+func TestPackageMirror_NotOffline(t *testing.T) {
+	p := newTestProvider(DockerRuntime("desktop-linux"), &provider.Config{})
+	if err := p.startPackageMirror("addt-test", false); err != nil {
+		t.Fatalf("startPackageMirror() error = %v", err)
+	}
+	if p.packageMirror != nil || p.packageMirrorEnvArgs() != nil {
+		t.Error("package mirror started without offline mode")
+	}
+}
+
+func TestPackageMirrorPort(t *testing.T) {
+	port := packageMirrorPort("addt-persistent-app")
+	if port < 10000 || port >= 20000 {
+		t.Errorf("packageMirrorPort() = %d, want 10000-19999", port)
+	}
+	if packageMirrorPort("addt-persistent-app") != port {
+		t.Error("packageMirrorPort() is not stable")
+	}
+}
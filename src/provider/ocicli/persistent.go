@@ -1,4 +1,4 @@
-package docker
+package ocicli
 
 import (
 	"crypto/md5"
@@ -16,68 +16,39 @@ import (
 // Container lifecycle management for persistent and ephemeral containers
 
 // Exists checks if a container exists (running or stopped)
-func (p *DockerProvider) Exists(name string) bool {
-	cmd := p.dockerCmd("ps", "-a", "--filter", fmt.Sprintf("name=^%s$", name), "--format", "{{.Names}}")
-	output, err := cmd.Output()
-	if err != nil {
-		return false
-	}
-	return strings.TrimSpace(string(output)) == name
+func (p *Provider) Exists(name string) bool {
+	return p.backend.ContainerExists(name)
 }
 
 // IsRunning checks if a container is currently running
-func (p *DockerProvider) IsRunning(name string) bool {
-	cmd := p.dockerCmd("ps", "--filter", fmt.Sprintf("name=^%s$", name), "--format", "{{.Names}}")
-	output, err := cmd.Output()
-	if err != nil {
-		return false
-	}
-	return strings.TrimSpace(string(output)) == name
+func (p *Provider) IsRunning(name string) bool {
+	return p.backend.ContainerRunning(name)
 }
 
 // Start starts a stopped container
-func (p *DockerProvider) Start(name string) error {
-	cmd := p.dockerCmd("start", name)
-	return util.SimpleSpinnerRun(fmt.Sprintf("Starting container %s", name), cmd)
+func (p *Provider) Start(name string) error {
+	return util.WithSpinner(fmt.Sprintf("Starting container %s", name), func() error {
+		return p.backend.StartContainer(name)
+	})
 }
 
 // Stop stops a running container
-func (p *DockerProvider) Stop(name string) error {
-	cmd := p.dockerCmd("stop", name)
-	return util.SimpleSpinnerRun(fmt.Sprintf("Stopping container %s", name), cmd)
+func (p *Provider) Stop(name string) error {
+	return util.WithSpinner(fmt.Sprintf("Stopping container %s", name), func() error {
+		return p.backend.StopContainer(name)
+	})
 }
 
 // Remove removes a container
-func (p *DockerProvider) Remove(name string) error {
-	cmd := p.dockerCmd("rm", "-f", name)
-	return util.SimpleSpinnerRun(fmt.Sprintf("Removing container %s", name), cmd)
+func (p *Provider) Remove(name string) error {
+	return util.WithSpinner(fmt.Sprintf("Removing container %s", name), func() error {
+		return p.backend.RemoveContainer(name)
+	})
 }
 
 // List lists all persistent addt containers
-func (p *DockerProvider) List() ([]provider.Environment, error) {
-	cmd := p.dockerCmd("ps", "-a", "--filter", "name=^addt-persistent-",
-		"--format", "{{.Names}}\t{{.Status}}\t{{.CreatedAt}}")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, err
-	}
-
-	var envs []provider.Environment
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		parts := strings.Split(line, "\t")
-		if len(parts) >= 3 {
-			envs = append(envs, provider.Environment{
-				Name:      parts[0],
-				Status:    parts[1],
-				CreatedAt: parts[2],
-			})
-		}
-	}
-	return envs, nil
+func (p *Provider) List() ([]provider.Environment, error) {
+	return p.backend.ListContainers("addt-persistent-")
 }
 
 // GenerateContainerName generates a persistent container name based on working directory and extensions
@@ -85,7 +56,7 @@ func (p *DockerProvider) List() ([]provider.Environment, error) {
 // The hash is based on workdir + extensions to ensure:
 // - Same workdir + same extensions = same container
 // - Same workdir + different extensions = different container
-func (p *DockerProvider) GenerateContainerName() string {
+func (p *Provider) GenerateContainerName() string {
 	// Use configured workdir or fall back to current directory
 	workdir := p.config.Workdir
 	if workdir == "" {
@@ -128,7 +99,11 @@ func (p *DockerProvider) GenerateContainerName() string {
 	// Create hash of workdir + extensions for uniqueness
 	// Same workdir + same extensions = same container
 	// Same workdir + different extensions = different container
+	// Named worktree sessions each get their own container
 	hashInput := workdir + "|" + extStr
+	if p.config.WorkdirWorktree && p.config.WorkdirWorktreeName != "" {
+		hashInput += "|worktree:" + p.config.WorkdirWorktreeName
+	}
 	hash := md5.Sum([]byte(hashInput))
 	hashStr := fmt.Sprintf("%x", hash)[:8]
 
@@ -137,12 +112,12 @@ func (p *DockerProvider) GenerateContainerName() string {
 
 // GenerateEphemeralName generates a unique ephemeral container name
 // The name format is: addt-<timestamp>-<pid>
-func (p *DockerProvider) GenerateEphemeralName() string {
+func (p *Provider) GenerateEphemeralName() string {
 	return fmt.Sprintf("addt-%s-%d", time.Now().Format("20060102-150405"), os.Getpid())
 }
 
 // GeneratePersistentName is an alias for GenerateContainerName to implement Provider interface
-func (p *DockerProvider) GeneratePersistentName() string {
+func (p *Provider) GeneratePersistentName() string {
 	return p.GenerateContainerName()
 }
 
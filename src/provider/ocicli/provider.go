@@ -0,0 +1,143 @@
+package ocicli
+
+import (
+	"embed"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/util"
+)
+
+// Provider implements the Provider interface for any OCI runtime driven
+// through a docker-compatible CLI (Docker, Rancher Desktop, OrbStack, Podman).
+// Runtime-specific behaviour is described by the Runtime passed to New.
+type Provider struct {
+	rt                     Runtime
+	backend                Backend
+	logger                 *util.ModuleLogger
+	config                 *provider.Config
+	tempDirs               []string
+	sshProxy               *security.SSHProxyAgent
+	gpgProxy               *security.GPGProxyAgent
+	signApprover           *security.SignApprover // sign_policy confirm prompts of the agent proxies
+	egressProxy            *security.EgressProxy
+	dnsResolver            *security.DNSResolver
+	hostServices           *security.HostServiceForwarder
+	packageMirror          *security.PackageMirror
+	apiProxy               *security.APIProxy
+	gitCredentials         *security.GitCredentialHelper
+	mirrorSocketDir        string               // host directory of the package mirror socket
+	dnsAllowed             map[string]time.Time // IPs fed to the firewall → expiry
+	dnsMu                  sync.Mutex
+	rulesMu                sync.Mutex // guards config.FirewallRules, which reloads replace
+	reloadStop             chan struct{}
+	reloadDone             chan struct{}
+	reloadContainer        string
+	learner                *security.FirewallLearner
+	tmuxProxy              *tmuxProxy
+	embeddedDockerfile     []byte
+	embeddedDockerfileBase []byte
+	embeddedEntrypoint     []byte
+	embeddedInitFirewall   []byte
+	embeddedInstallSh      []byte
+	embeddedExtensions     embed.FS
+}
+
+// New creates a provider for the given runtime descriptor that drives the
+// runtime through its CLI.
+func New(rt Runtime, cfg *provider.Config, dockerfile, dockerfileBase, entrypoint, initFirewall, installSh []byte, extensions embed.FS) *Provider {
+	return NewWithBackend(rt, newCLIBackend(rt), cfg, dockerfile, dockerfileBase, entrypoint, initFirewall, installSh, extensions)
+}
+
+// NewWithBackend creates a provider for the given runtime descriptor that
+// performs runtime operations through backend.
+func NewWithBackend(rt Runtime, backend Backend, cfg *provider.Config, dockerfile, dockerfileBase, entrypoint, initFirewall, installSh []byte, extensions embed.FS) *Provider {
+	return &Provider{
+		rt:                     rt,
+		backend:                backend,
+		logger:                 util.Log(rt.Name),
+		config:                 cfg,
+		tempDirs:               []string{},
+		embeddedDockerfile:     dockerfile,
+		embeddedDockerfileBase: dockerfileBase,
+		embeddedEntrypoint:     entrypoint,
+		embeddedInitFirewall:   initFirewall,
+		embeddedInstallSh:      installSh,
+		embeddedExtensions:     extensions,
+	}
+}
+
+// Runtime returns the runtime descriptor this provider was created with
+func (p *Provider) Runtime() Runtime {
+	return p.rt
+}
+
+// Initialize initializes the provider
+func (p *Provider) Initialize(cfg *provider.Config) error {
+	p.config = cfg
+
+	// Clean up stale temp directories from previous runs
+	security.CleanupAll()
+
+	return p.CheckPrerequisites()
+}
+
+// GetName returns the provider name
+func (p *Provider) GetName() string {
+	return p.rt.Name
+}
+
+// CheckPrerequisites verifies the runtime is installed and usable
+func (p *Provider) CheckPrerequisites() error {
+	if p.rt.Prerequisites == nil {
+		return nil
+	}
+	return p.rt.Prerequisites()
+}
+
+// Container lifecycle methods (Exists, IsRunning, Start, Stop, Remove, List)
+// and name generation (GenerateContainerName, GenerateEphemeralName, GeneratePersistentName)
+// are defined in persistent.go
+
+// Cleanup removes temporary directories and stops proxies
+func (p *Provider) Cleanup() error {
+	// Stop SSH proxy if running
+	if p.sshProxy != nil {
+		p.sshProxy.Stop()
+		p.sshProxy = nil
+	}
+
+	// Stop GPG proxy if running
+	if p.gpgProxy != nil {
+		p.gpgProxy.Stop()
+		p.gpgProxy = nil
+	}
+	p.signApprover = nil
+
+	// Stop firewall reloads, egress proxy, DNS resolver, host service
+	// forwarder, package mirror, API proxy and git credential helper if
+	// running
+	p.stopFirewallReload()
+	p.stopEgressProxy()
+	p.stopDNSResolver()
+	p.stopHostServices()
+	p.stopPackageMirror()
+	p.stopAPIProxy()
+	p.stopGitCredentials()
+	p.saveFirewallLearn()
+
+	// Stop tmux proxy if running
+	if p.tmuxProxy != nil {
+		p.tmuxProxy.Stop()
+		p.tmuxProxy = nil
+	}
+
+	for _, dir := range p.tempDirs {
+		os.RemoveAll(dir)
+	}
+	p.tempDirs = []string{}
+	return nil
+}
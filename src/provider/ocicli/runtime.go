@@ -0,0 +1,167 @@
+package ocicli
+
+import (
+	"os/exec"
+)
+
+// Runtime describes the capabilities and quirks of one OCI runtime CLI.
+// The shared Provider consults it wherever docker, podman and orbstack
+// used to diverge, so per-runtime packages only need to supply a descriptor.
+type Runtime struct {
+	// Name is the provider name reported by GetName and shown in status output
+	Name string
+
+	// Binary is the CLI executable ("docker" or "podman")
+	Binary string
+
+	// Context is the Docker context to target (e.g. "desktop-linux", "orbstack").
+	// Empty means no DOCKER_CONTEXT override.
+	Context string
+
+	// EntrypointFile is the entrypoint file name expected in the build context
+	EntrypointFile string
+
+	// EntrypointPath is where the image installs the entrypoint script
+	EntrypointPath string
+
+	// Rootless marks daemonless, rootless runtimes (Podman):
+	//   - tmpfs mounts can't take uid/gid options, mode=1777 is used instead
+	//   - the entrypoint is exec'd as the image user instead of --user root
+	Rootless bool
+
+	// PrivateIPC isolates IPC with "private" for runtimes that don't accept
+	// --ipc none (Podman, nerdctl)
+	PrivateIPC bool
+
+	// InitBinary is the host binary the CLI injects for --init (nerdctl
+	// uses tini from the host PATH). When set and not installed, --init is
+	// skipped. Empty means the runtime ships its own init.
+	InitBinary string
+
+	// SocketMountsOnDarwin reports whether host Unix sockets can be
+	// bind-mounted into containers on macOS (Docker Desktop, OrbStack)
+	SocketMountsOnDarwin bool
+
+	// DetectHostGateway resolves host.docker.internal to the host's outbound
+	// IP instead of relying on the runtime's "host-gateway" alias
+	DetectHostGateway bool
+
+	// Pasta uses the pasta network backend (when installed) for firewalled
+	// containers. The firewall then owns the network setting, so
+	// security.network_mode is not applied on top of it.
+	Pasta bool
+
+	// NestedRuntime is the runtime started inside the container for
+	// dind.mode=isolated/host: "docker" (DinD) or "podman" (PinP)
+	NestedRuntime string
+
+	// SecretsViaCopy delivers the secrets file with `<binary> cp` instead of
+	// writing it through `exec` (docker cp can't see tmpfs mounts)
+	SecretsViaCopy bool
+
+	// Checkpoint marks runtimes that can checkpoint running containers to
+	// an image with CRIU (Podman; needs root and CRIU installed)
+	Checkpoint bool
+
+	// CommitWithoutLabels marks runtimes whose commit only accepts CMD and
+	// ENTRYPOINT changes (nerdctl), so snapshots are tracked by name only
+	CommitWithoutLabels bool
+
+	// Prerequisites verifies the runtime is installed and running.
+	// Nil means no check.
+	Prerequisites func() error
+}
+
+// nestedLabel returns the status label for nested container support
+func (rt Runtime) nestedLabel() string {
+	if rt.NestedRuntime == "podman" {
+		return "PinP" // Podman-in-Podman
+	}
+	return "DinD"
+}
+
+// pastaAvailable reports whether the runtime uses pasta and it is installed
+func (rt Runtime) pastaAvailable() bool {
+	if !rt.Pasta {
+		return false
+	}
+	_, err := exec.LookPath("pasta")
+	return err == nil
+}
+
+// initAvailable reports whether --init can be used with this runtime
+func (rt Runtime) initAvailable() bool {
+	if rt.InitBinary == "" {
+		return true
+	}
+	_, err := exec.LookPath(rt.InitBinary)
+	return err == nil
+}
+
+// DockerRuntime returns the descriptor for Docker Desktop and other
+// docker-CLI daemons reached through the given Docker context.
+// The "rancher-desktop" context is reported as the "rancher" provider.
+func DockerRuntime(context string) Runtime {
+	name := "docker"
+	if context == "rancher-desktop" {
+		name = "rancher"
+	}
+	return Runtime{
+		Name:                 name,
+		Binary:               "docker",
+		Context:              context,
+		EntrypointFile:       "docker-entrypoint.sh",
+		EntrypointPath:       "/usr/local/bin/docker-entrypoint.sh",
+		SocketMountsOnDarwin: true,
+		NestedRuntime:        "docker",
+	}
+}
+
+// OrbStackRuntime returns the descriptor for OrbStack's Docker engine
+func OrbStackRuntime() Runtime {
+	return Runtime{
+		Name:                 "orbstack",
+		Binary:               "docker",
+		Context:              "orbstack",
+		EntrypointFile:       "orbstack-entrypoint.sh",
+		EntrypointPath:       "/usr/local/bin/docker-entrypoint.sh",
+		SocketMountsOnDarwin: true,
+		NestedRuntime:        "docker",
+	}
+}
+
+// PodmanRuntime returns the descriptor for rootless Podman
+func PodmanRuntime() Runtime {
+	return Runtime{
+		Name:              "podman",
+		Binary:            "podman",
+		EntrypointFile:    "podman-entrypoint.sh",
+		EntrypointPath:    "/usr/local/bin/podman-entrypoint.sh",
+		Rootless:          true,
+		PrivateIPC:        true,
+		DetectHostGateway: true,
+		Pasta:             true,
+		NestedRuntime:     "podman",
+		SecretsViaCopy:    true,
+		Checkpoint:        true,
+	}
+}
+
+// NerdctlRuntime returns the descriptor for containerd driven by nerdctl
+// (typically rootless containerd via RootlessKit). Containers start as root
+// inside their user namespace, so the entrypoint's root phase works as with
+// Docker. Host-gateway is resolved from the host's outbound IP since older
+// nerdctl releases don't know the "host-gateway" alias.
+func NerdctlRuntime() Runtime {
+	return Runtime{
+		Name:                "nerdctl",
+		Binary:              "nerdctl",
+		EntrypointFile:      "docker-entrypoint.sh",
+		EntrypointPath:      "/usr/local/bin/docker-entrypoint.sh",
+		PrivateIPC:          true,
+		InitBinary:          "tini",
+		DetectHostGateway:   true,
+		NestedRuntime:       "docker",
+		CommitWithoutLabels: true,
+	}
+}
@@ -0,0 +1,205 @@
+package ocicli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/provider"
+)
+
+func TestDockerRuntime_Name(t *testing.T) {
+	testCases := []struct {
+		context string
+		want    string
+	}{
+		{"", "docker"},
+		{"desktop-linux", "docker"},
+		{"rancher-desktop", "rancher"},
+	}
+
+	for _, tc := range testCases {
+		p := newTestProvider(DockerRuntime(tc.context), nil)
+		if got := p.GetName(); got != tc.want {
+			t.Errorf("DockerRuntime(%q) name = %q, want %q", tc.context, got, tc.want)
+		}
+	}
+}
+
+func TestRuntime_NestedLabel(t *testing.T) {
+	if got := DockerRuntime("").nestedLabel(); got != "DinD" {
+		t.Errorf("docker nestedLabel() = %q, want DinD", got)
+	}
+	if got := OrbStackRuntime().nestedLabel(); got != "DinD" {
+		t.Errorf("orbstack nestedLabel() = %q, want DinD", got)
+	}
+	if got := PodmanRuntime().nestedLabel(); got != "PinP" {
+		t.Errorf("podman nestedLabel() = %q, want PinP", got)
+	}
+}
+
+func TestRuntime_PastaOnlyForPodman(t *testing.T) {
+	if DockerRuntime("").pastaAvailable() {
+		t.Error("docker runtime should never use pasta")
+	}
+	if OrbStackRuntime().pastaAvailable() {
+		t.Error("orbstack runtime should never use pasta")
+	}
+}
+
+func TestCLIBackend_CmdSetsDockerContext(t *testing.T) {
+	cmd := newCLIBackend(OrbStackRuntime()).cmd("ps")
+	found := false
+	for _, e := range cmd.Env {
+		if e == "DOCKER_CONTEXT=orbstack" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("cmd() should set DOCKER_CONTEXT=orbstack")
+	}
+
+	cmd = newCLIBackend(PodmanRuntime()).cmd("ps")
+	if cmd.Env != nil {
+		t.Errorf("cmd() without context should inherit env, got %d entries", len(cmd.Env))
+	}
+	if cmd.Args[0] != "podman" {
+		t.Errorf("cmd() should run podman, got %v", cmd.Args)
+	}
+}
+
+func TestProvider_EntrypointExecArgs(t *testing.T) {
+	got := newTestProvider(DockerRuntime(""), nil).entrypointExecArgs()
+	if strings.Join(got, " ") != "exec --user root" {
+		t.Errorf("docker entrypointExecArgs() = %v, want [exec --user root]", got)
+	}
+
+	got = newTestProvider(PodmanRuntime(), nil).entrypointExecArgs()
+	if strings.Join(got, " ") != "exec" {
+		t.Errorf("podman entrypointExecArgs() = %v, want [exec]", got)
+	}
+}
+
+func TestProvider_HostGatewayArgs_Docker(t *testing.T) {
+	got := newTestProvider(DockerRuntime(""), nil).hostGatewayArgs()
+	want := "--add-host=host.docker.internal:host-gateway"
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("hostGatewayArgs() = %v, want [%s]", got, want)
+	}
+}
+
+func TestAddSecuritySettings_RuntimeDifferences(t *testing.T) {
+	cfg := &provider.Config{
+		Security: security.Config{
+			ReadOnlyRootfs: true,
+			TmpfsTmpSize:   "256m",
+			TmpfsHomeSize:  "512m",
+			DisableIPC:     true,
+		},
+	}
+
+	dockerArgs := newTestProvider(DockerRuntime(""), cfg).addSecuritySettings(nil)
+	podmanArgs := newTestProvider(PodmanRuntime(), cfg).addSecuritySettings(nil)
+
+	if !containsFlagValue(dockerArgs, "--ipc", "none") {
+		t.Errorf("docker should use --ipc none, got %v", dockerArgs)
+	}
+	if !containsFlagValue(podmanArgs, "--ipc", "private") {
+		t.Errorf("podman should use --ipc private, got %v", podmanArgs)
+	}
+	if !containsFlagValue(podmanArgs, "--tmpfs", "/home/addt:rw,exec,nosuid,mode=1777,size=512m") {
+		t.Errorf("podman home tmpfs should use mode=1777, got %v", podmanArgs)
+	}
+	for i, arg := range dockerArgs {
+		if arg == "--tmpfs" && i+1 < len(dockerArgs) && strings.HasPrefix(dockerArgs[i+1], "/home/addt:") {
+			if strings.Contains(dockerArgs[i+1], "mode=1777") {
+				t.Errorf("docker home tmpfs should not use mode=1777, got %q", dockerArgs[i+1])
+			}
+		}
+	}
+}
+
+func TestAddSecuritySettings_PastaFirewallSkipsNetworkMode(t *testing.T) {
+	cfg := &provider.Config{
+		FirewallEnabled: true,
+		Security:        security.Config{NetworkMode: "none"},
+	}
+
+	if !containsFlagValue(newTestProvider(DockerRuntime(""), cfg).addSecuritySettings(nil), "--network", "none") {
+		t.Error("docker should keep --network none with the firewall enabled")
+	}
+	if containsFlagValue(newTestProvider(PodmanRuntime(), cfg).addSecuritySettings(nil), "--network", "none") {
+		t.Error("podman should leave the network to pasta when the firewall is enabled")
+	}
+}
+
+func TestNerdctlRuntime_Settings(t *testing.T) {
+	cfg := &provider.Config{
+		Security: security.Config{
+			ReadOnlyRootfs: true,
+			TmpfsTmpSize:   "256m",
+			TmpfsHomeSize:  "512m",
+			DisableIPC:     true,
+		},
+	}
+	p := newTestProvider(NerdctlRuntime(), cfg)
+
+	if got := p.GetName(); got != "nerdctl" {
+		t.Errorf("GetName() = %q, want nerdctl", got)
+	}
+	args := p.addSecuritySettings(nil)
+	if !containsFlagValue(args, "--ipc", "private") {
+		t.Errorf("nerdctl should use --ipc private, got %v", args)
+	}
+	for i, arg := range args {
+		if arg == "--tmpfs" && i+1 < len(args) && strings.HasPrefix(args[i+1], "/home/addt:") {
+			if strings.Contains(args[i+1], "mode=1777") {
+				t.Errorf("nerdctl home tmpfs should be owned via uid/gid, got %q", args[i+1])
+			}
+		}
+	}
+	// Containers start as root in their user namespace, like Docker
+	if got := p.entrypointExecArgs(); strings.Join(got, " ") != "exec --user root" {
+		t.Errorf("nerdctl entrypointExecArgs() = %v, want [exec --user root]", got)
+	}
+}
+
+func TestRuntime_InitAvailable(t *testing.T) {
+	if !DockerRuntime("").initAvailable() {
+		t.Error("docker ships its own init, --init should always be available")
+	}
+
+	rt := NerdctlRuntime()
+	rt.InitBinary = "addt-test-missing-init-binary"
+	if rt.initAvailable() {
+		t.Error("initAvailable() should be false when the init binary is missing")
+	}
+
+	spec := &provider.RunSpec{Name: "test", Interactive: true}
+	args := newTestProvider(rt, nil).buildBaseArgs(spec, &containerContext{})
+	for _, arg := range args {
+		if arg == "--init" {
+			t.Errorf("--init should be skipped without %s, got %v", rt.InitBinary, args)
+		}
+	}
+}
+
+func TestHasLine(t *testing.T) {
+	output := []byte("addt-persistent-foo-1\naddt-persistent-foo\n")
+	if !hasLine(output, "addt-persistent-foo") {
+		t.Error("hasLine() should match an exact line")
+	}
+	if hasLine([]byte("addt-persistent-foo-1\n"), "addt-persistent-foo") {
+		t.Error("hasLine() should not match a name prefix")
+	}
+}
+
+// containsFlagValue checks if flag is immediately followed by value in args
+func containsFlagValue(args []string, flag, value string) bool {
+	for i, arg := range args {
+		if arg == flag && i+1 < len(args) && args[i+1] == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,4 +1,4 @@
-package docker
+package ocicli
 
 import (
 	"encoding/json"
@@ -8,7 +8,7 @@ import (
 
 // prepareSecretsJSON collects secret environment variables and returns them as JSON
 // Returns the JSON string and the list of secret variable names
-func (p *DockerProvider) prepareSecretsJSON(imageName string, env map[string]string) (string, []string, error) {
+func (p *Provider) prepareSecretsJSON(imageName string, env map[string]string) (string, []string, error) {
 	// Get extension env vars (these are the "secrets")
 	secretVarNames := p.GetExtensionEnvVars(imageName)
 
@@ -49,29 +49,30 @@ func (p *DockerProvider) prepareSecretsJSON(imageName string, env map[string]str
 }
 
 // copySecretsToContainer writes secrets JSON directly into the container's tmpfs.
-// Uses docker exec instead of docker cp because docker cp writes to the overlay
-// layer beneath tmpfs mounts, making the file invisible inside the container.
-func (p *DockerProvider) copySecretsToContainer(containerName, secretsJSON string) error {
-	cmd := p.dockerCmd("exec", "-i", containerName,
-		"sh", "-c", "cat > /run/secrets/.secrets && chmod 644 /run/secrets/.secrets")
-	cmd.Stdin = strings.NewReader(secretsJSON)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("docker exec write secrets failed: %w\n%s", err, string(output))
+// Docker-engine runtimes use exec instead of cp because docker cp writes to the
+// overlay layer beneath tmpfs mounts, making the file invisible inside the container.
+func (p *Provider) copySecretsToContainer(containerName, secretsJSON string) error {
+	if p.rt.SecretsViaCopy {
+		// cp preserves the 0644 mode — the entrypoint runs as addt (not root)
+		// so it needs to read the file. The file lives in a tmpfs and is
+		// deleted immediately after parsing.
+		return p.backend.CopyToContainer(containerName, "/run/secrets/.secrets", []byte(secretsJSON))
 	}
-	return nil
+	return p.backend.ExecInput(containerName, []byte(secretsJSON),
+		"sh", "-c", "cat > /run/secrets/.secrets && chmod 644 /run/secrets/.secrets")
 }
 
 // addTmpfsSecretsMount adds a tmpfs mount for secrets at /run/secrets
 // World-writable so the entrypoint (running as addt) can read and delete
 // the secrets file. The tmpfs is ephemeral and secrets are deleted immediately
 // after parsing, so the broad permissions are acceptable.
-func (p *DockerProvider) addTmpfsSecretsMount(dockerArgs []string) []string {
-	return append(dockerArgs, "--tmpfs", "/run/secrets:size=1m,mode=0777")
+func (p *Provider) addTmpfsSecretsMount(cliArgs []string) []string {
+	return append(cliArgs, "--tmpfs", "/run/secrets:size=1m,mode=0777")
 }
 
 // filterSecretEnvVars removes secret env vars from the env map
 // This prevents secrets from being passed as -e flags
-func (p *DockerProvider) filterSecretEnvVars(env map[string]string, secretVarNames []string) {
+func (p *Provider) filterSecretEnvVars(env map[string]string, secretVarNames []string) {
 	for _, varName := range secretVarNames {
 		delete(env, varName)
 	}
@@ -0,0 +1,54 @@
+package ocicli
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/jedi4ever/addt/config/security"
+)
+
+// startSignApproval prepares the prompts of ssh.sign_policy and
+// gpg.sign_policy confirm, which the agent proxies ask before each
+// signature. Invalid settings fail the run rather than signing unasked.
+func (p *Provider) startSignApproval(name string) error {
+	if p.signApprover != nil {
+		return nil
+	}
+	for key, policy := range map[string]string{"ssh.sign_policy": p.config.SSHSignPolicy, "gpg.sign_policy": p.config.GPGSignPolicy} {
+		if policy != "" && policy != "allow" && policy != "confirm" {
+			return fmt.Errorf("invalid %s %q, want allow or confirm", key, policy)
+		}
+	}
+	if p.config.SSHSignPolicy != "confirm" && p.config.GPGSignPolicy != "confirm" {
+		return nil
+	}
+	approver, err := security.NewSignApprover(name, p.config.Security.SignPrompt, time.Duration(p.config.Security.SignGrant)*time.Minute)
+	if err != nil {
+		return err
+	}
+	if err := approver.Ready(); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	p.signApprover = approver
+	return nil
+}
+
+// approveSSHSigns makes the SSH proxy ask before each signature; prompts
+// name login destinations from the host's known_hosts
+func (p *Provider) approveSSHSigns(proxy *security.SSHProxyAgent, sshDir string) {
+	if p.signApprover == nil || p.config.SSHSignPolicy != "confirm" {
+		return
+	}
+	proxy.SetApprover(p.signApprover, filepath.Join(sshDir, "known_hosts"))
+	fmt.Printf("SSH proxy: each signature is confirmed at a %s prompt\n", p.signApprover.Method())
+}
+
+// approveGPGSigns makes the GPG proxy ask before each signature
+func (p *Provider) approveGPGSigns(proxy *security.GPGProxyAgent) {
+	if p.signApprover == nil || p.config.GPGSignPolicy != "confirm" {
+		return
+	}
+	proxy.SetApprover(p.signApprover)
+	fmt.Printf("GPG proxy: each signature is confirmed at a %s prompt\n", p.signApprover.Method())
+}
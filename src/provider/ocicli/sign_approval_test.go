@@ -0,0 +1,42 @@
+package ocicli
+
+import (
+	"testing"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/provider"
+)
+
+func TestStartSignApproval(t *testing.T) {
+	cfg := &provider.Config{SSHSignPolicy: "confirm", GPGSignPolicy: "allow", Security: security.Config{SignPrompt: "web", SignGrant: 5}}
+	p := newTestProvider(DockerRuntime("desktop-linux"), cfg)
+	if err := p.startSignApproval("addt-test"); err != nil {
+		t.Fatalf("startSignApproval() error = %v", err)
+	}
+	if p.signApprover == nil || p.signApprover.Method() != "web" {
+		t.Fatalf("signApprover = %v, want a web approver", p.signApprover)
+	}
+}
+
+func TestStartSignApproval_Skipped(t *testing.T) {
+	p := newTestProvider(DockerRuntime("desktop-linux"), &provider.Config{SSHSignPolicy: "allow"})
+	if err := p.startSignApproval("addt-test"); err != nil {
+		t.Fatalf("startSignApproval() error = %v", err)
+	}
+	if p.signApprover != nil {
+		t.Error("approver created without a confirm policy")
+	}
+}
+
+func TestStartSignApproval_Invalid(t *testing.T) {
+	tests := []*provider.Config{
+		{SSHSignPolicy: "ask"},
+		{GPGSignPolicy: "confirm", Security: security.Config{SignPrompt: "carrier-pigeon"}},
+	}
+	for _, cfg := range tests {
+		p := newTestProvider(DockerRuntime("desktop-linux"), cfg)
+		if err := p.startSignApproval("addt-test"); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
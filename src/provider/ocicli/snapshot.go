@@ -0,0 +1,182 @@
+package ocicli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/util"
+)
+
+// Snapshots of persistent containers are images named
+// addt-snapshot-<container>:<tag>, labelled with what they were taken of.
+// Committed snapshots hold the container's filesystem (not its mounts, so
+// not the workspace); restoring one recreates the container from it on the
+// next run, with the run's current settings. Checkpoint snapshots also hold
+// the running processes and the container's settings, and are restored
+// right away.
+
+const (
+	snapshotRepoPrefix = "addt-snapshot-"
+	// restoreRepoPrefix tags the snapshot the next persistent container
+	// is created from
+	restoreRepoPrefix = "addt-restore-"
+
+	labelSnapshotOf     = "addt.snapshot.of"
+	labelSnapshotTag    = "addt.snapshot.tag"
+	labelSnapshotMethod = "addt.snapshot.method"
+)
+
+// snapshotImage returns the image holding a snapshot
+func snapshotImage(name, tag string) string {
+	return snapshotRepoPrefix + name + ":" + tag
+}
+
+// restoreImage returns the tag marking a pending restore of name
+func restoreImage(name string) string {
+	return restoreRepoPrefix + name + ":latest"
+}
+
+// Snapshot saves a container's state as an image. With checkpoint set the
+// running processes are saved too, where the runtime supports CRIU.
+func (p *Provider) Snapshot(name, tag string, checkpoint bool) (*provider.Snapshot, error) {
+	if err := provider.ValidateSnapshotTag(tag); err != nil {
+		return nil, err
+	}
+	if !p.backend.ContainerExists(name) {
+		return nil, fmt.Errorf("container %s not found", name)
+	}
+	image := snapshotImage(name, tag)
+	if p.backend.ImageExists(image) {
+		return nil, fmt.Errorf("snapshot %s of %s already exists", tag, name)
+	}
+
+	method := provider.SnapshotCommit
+	if checkpoint {
+		method = provider.SnapshotCheckpoint
+	}
+	err := util.WithSpinner(fmt.Sprintf("Saving snapshot %s of %s", tag, name), func() error {
+		if checkpoint {
+			return p.backend.CheckpointContainer(name, image)
+		}
+		return p.backend.CommitContainer(name, image, map[string]string{
+			labelSnapshotOf:     name,
+			labelSnapshotTag:    tag,
+			labelSnapshotMethod: method,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &provider.Snapshot{Environment: name, Tag: tag, Method: method, Image: image, CreatedAt: time.Now()}, nil
+}
+
+// Restore replaces a persistent container with a snapshot of it. The
+// container is removed; a checkpoint is restored immediately, a committed
+// snapshot becomes the image of the container the next run creates.
+func (p *Provider) Restore(name, tag string) error {
+	if !IsPersistentContainer(name) {
+		return fmt.Errorf("%s is not a persistent container; only persistent containers can be restored", name)
+	}
+	snapshot, err := p.findSnapshot(name, tag)
+	if err != nil {
+		return err
+	}
+
+	if p.backend.ContainerExists(name) {
+		if err := p.Remove(name); err != nil {
+			return fmt.Errorf("failed to remove container %s: %w", name, err)
+		}
+	}
+	if snapshot.Method == provider.SnapshotCheckpoint {
+		return util.WithSpinner(fmt.Sprintf("Restoring %s from checkpoint %s", name, tag), func() error {
+			return p.backend.RestoreCheckpoint(name, snapshot.Image)
+		})
+	}
+	return p.backend.TagImage(snapshot.Image, restoreImage(name))
+}
+
+// ListSnapshots lists the snapshots of a container, or of all containers
+// when name is empty, oldest first
+func (p *Provider) ListSnapshots(name string) ([]provider.Snapshot, error) {
+	images, err := p.backend.ListImages(snapshotRepoPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []provider.Snapshot
+	for _, img := range images {
+		repo, tag, ok := strings.Cut(img.Ref, ":")
+		if !ok {
+			continue
+		}
+		env := strings.TrimPrefix(repo, snapshotRepoPrefix)
+		if name != "" && env != name {
+			continue
+		}
+		method := img.Labels[labelSnapshotMethod]
+		if img.Checkpoint {
+			method = provider.SnapshotCheckpoint
+		} else if method == "" {
+			method = provider.SnapshotCommit // runtimes that can't label commits
+		}
+		snapshots = append(snapshots, provider.Snapshot{
+			Environment: env,
+			Tag:         tag,
+			Method:      method,
+			Image:       img.Ref,
+			CreatedAt:   img.Created,
+		})
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		if snapshots[i].Environment != snapshots[j].Environment {
+			return snapshots[i].Environment < snapshots[j].Environment
+		}
+		return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// RemoveSnapshot removes a snapshot image
+func (p *Provider) RemoveSnapshot(name, tag string) error {
+	snapshot, err := p.findSnapshot(name, tag)
+	if err != nil {
+		return err
+	}
+	return p.backend.RemoveImage(snapshot.Image)
+}
+
+// findSnapshot returns a container's snapshot by tag
+func (p *Provider) findSnapshot(name, tag string) (*provider.Snapshot, error) {
+	snapshots, err := p.ListSnapshots(name)
+	if err != nil {
+		return nil, err
+	}
+	for i := range snapshots {
+		if snapshots[i].Tag == tag {
+			return &snapshots[i], nil
+		}
+	}
+	return nil, fmt.Errorf("snapshot %s of %s not found", tag, name)
+}
+
+// persistentImage returns the image to create a persistent container
+// from: the snapshot pending restore, if any, else the spec's image
+func (p *Provider) persistentImage(spec *provider.RunSpec) string {
+	if image := restoreImage(spec.Name); p.backend.ImageExists(image) {
+		fmt.Printf("Restoring %s from snapshot\n", spec.Name)
+		return image
+	}
+	return spec.ImageName
+}
+
+// clearRestore drops the pending restore once the container has been
+// created from it. Only the restore tag goes; the snapshot keeps its own.
+func (p *Provider) clearRestore(spec *provider.RunSpec, image string) {
+	if image != spec.ImageName {
+		if err := p.backend.RemoveImage(image); err != nil {
+			p.logger.Debugf("Failed to remove restore tag %s: %v", image, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package ocicli
+
+import (
+	"embed"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jedi4ever/addt/provider"
+)
+
+// imageListBackend serves ListImages from a fixed list; other Backend
+// methods are not implemented
+type imageListBackend struct {
+	Backend
+	images []ImageSummary
+}
+
+func (b *imageListBackend) ListImages(prefix string) ([]ImageSummary, error) {
+	return b.images, nil
+}
+
+func TestListSnapshots(t *testing.T) {
+	older := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	backend := &imageListBackend{images: []ImageSummary{
+		{Ref: "addt-snapshot-addt-persistent-b-2222:ckpt", Created: older, Checkpoint: true},
+		{Ref: "addt-snapshot-addt-persistent-a-1111:second", Created: newer,
+			Labels: map[string]string{labelSnapshotMethod: provider.SnapshotCommit}},
+		{Ref: "addt-snapshot-addt-persistent-a-1111:first", Created: older}, // unlabelled (nerdctl)
+	}}
+	p := NewWithBackend(DockerRuntime(""), backend, &provider.Config{}, nil, nil, nil, nil, nil, embed.FS{})
+
+	all, err := p.ListSnapshots("")
+	if err != nil {
+		t.Fatalf("ListSnapshots() error = %v", err)
+	}
+	want := []provider.Snapshot{
+		{Environment: "addt-persistent-a-1111", Tag: "first", Method: provider.SnapshotCommit, Image: "addt-snapshot-addt-persistent-a-1111:first", CreatedAt: older},
+		{Environment: "addt-persistent-a-1111", Tag: "second", Method: provider.SnapshotCommit, Image: "addt-snapshot-addt-persistent-a-1111:second", CreatedAt: newer},
+		{Environment: "addt-persistent-b-2222", Tag: "ckpt", Method: provider.SnapshotCheckpoint, Image: "addt-snapshot-addt-persistent-b-2222:ckpt", CreatedAt: older},
+	}
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("ListSnapshots() = %+v\nwant %+v", all, want)
+	}
+
+	one, _ := p.ListSnapshots("addt-persistent-b-2222")
+	if len(one) != 1 || one[0].Tag != "ckpt" {
+		t.Errorf("ListSnapshots(b) = %+v", one)
+	}
+	if _, err := p.findSnapshot("addt-persistent-a-1111", "missing"); err == nil {
+		t.Error("findSnapshot() should fail for an unknown tag")
+	}
+}
+
+func TestRestore_OnlyPersistent(t *testing.T) {
+	p := NewWithBackend(DockerRuntime(""), &imageListBackend{}, &provider.Config{}, nil, nil, nil, nil, nil, embed.FS{})
+	if err := p.Restore("addt-20260102-100000-42", "x"); err == nil {
+		t.Error("restoring an ephemeral container should fail")
+	}
+}
@@ -1,4 +1,4 @@
-package orbstack
+package ocicli
 
 import (
 	"fmt"
@@ -15,16 +15,26 @@ import (
 //   - "agent": Forward SSH agent socket (not supported on macOS)
 //   - "keys": Mount ~/.ssh directory read-only
 //
-// If allowedKeys is set, proxy mode is automatically enabled for agent forwarding
-func (p *OrbStackProvider) HandleSSHForwarding(forwardKeys bool, forwardMode, sshDir, username string, allowedKeys []string) []string {
+// If allowedKeys or ssh.allowed_hosts is set, or ssh.sign_policy is
+// confirm, proxy mode is automatically enabled for agent forwarding
+func (p *Provider) HandleSSHForwarding(forwardKeys bool, forwardMode, sshDir, username string, allowedKeys []string) []string {
 	if !forwardKeys {
 		return nil
 	}
 
-	// If allowed keys are specified, use proxy mode regardless of forwardMode setting
-	if len(allowedKeys) > 0 && (forwardMode == "agent" || forwardMode == "proxy") {
+	// If allowed keys or hosts are specified or signatures are confirmed,
+	// use proxy mode regardless of forwardMode setting
+	confirm := p.config != nil && p.config.SSHSignPolicy == "confirm"
+	limitHosts := p.config != nil && len(p.config.SSHAllowedHosts) > 0
+	if (len(allowedKeys) > 0 || confirm || limitHosts) && (forwardMode == "agent" || forwardMode == "proxy") {
 		return p.handleSSHProxyForwarding(sshDir, username, allowedKeys)
 	}
+	if confirm && forwardMode == "keys" {
+		fmt.Println("Warning: ssh.sign_policy confirm needs ssh.forward_mode proxy; keys mode mounts the private keys and signs without asking")
+	}
+	if limitHosts && forwardMode == "keys" {
+		fmt.Println("Warning: ssh.allowed_hosts needs ssh.forward_mode proxy; keys mode mounts the private keys, which can log in anywhere")
+	}
 
 	if forwardMode == "proxy" {
 		// Proxy mode without filters - just forward all keys through proxy
@@ -40,7 +50,7 @@ func (p *OrbStackProvider) HandleSSHForwarding(forwardKeys bool, forwardMode, ss
 
 // mountSafeSSHFiles creates a temp directory with only safe SSH files
 // (config, known_hosts, public keys) and returns mount arguments
-func (p *OrbStackProvider) mountSafeSSHFiles(sshDir, username string) []string {
+func (p *Provider) mountSafeSSHFiles(sshDir, username string) []string {
 	var args []string
 
 	if _, err := os.Stat(sshDir); err != nil {
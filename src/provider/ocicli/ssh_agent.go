@@ -1,4 +1,4 @@
-package podman
+package ocicli
 
 import (
 	"fmt"
@@ -7,7 +7,7 @@ import (
 )
 
 // handleSSHAgentForwarding forwards the SSH agent socket into the container
-func (p *PodmanProvider) handleSSHAgentForwarding(sshDir, username string) []string {
+func (p *Provider) handleSSHAgentForwarding(sshDir, username string) []string {
 	var args []string
 
 	sshAuthSock := os.Getenv("SSH_AUTH_SOCK")
@@ -20,7 +20,7 @@ func (p *PodmanProvider) handleSSHAgentForwarding(sshDir, username string) []str
 		return args
 	}
 
-	// Check for macOS launchd sockets (can't be mounted into podman containers)
+	// Check for macOS launchd sockets (can't be mounted into containers)
 	if strings.Contains(sshAuthSock, "com.apple.launchd") || strings.Contains(sshAuthSock, "/var/folders/") {
 		fmt.Println("Warning: SSH agent forwarding not supported on macOS (use ADDT_SSH_FORWARD_MODE=proxy)")
 		return args
@@ -0,0 +1,31 @@
+//go:build integration
+
+package ocicli
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSSHForwarding_Integration_AgentModeNoSocket(t *testing.T) {
+	forEachRuntime(t, func(t *testing.T, rt Runtime) {
+		requireRuntime(t, rt)
+
+		// Unset SSH_AUTH_SOCK temporarily
+		origSock := os.Getenv("SSH_AUTH_SOCK")
+		os.Unsetenv("SSH_AUTH_SOCK")
+		defer func() {
+			if origSock != "" {
+				os.Setenv("SSH_AUTH_SOCK", origSock)
+			}
+		}()
+
+		prov := createTestProvider(t, rt)
+		args := prov.HandleSSHForwarding(true, "agent", "/home/test/.ssh", "testuser", nil)
+
+		// Should return empty args when no socket
+		if len(args) > 0 {
+			t.Errorf("Expected empty args when SSH_AUTH_SOCK not set, got: %v", args)
+		}
+	})
+}
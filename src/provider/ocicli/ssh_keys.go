@@ -1,4 +1,4 @@
-package docker
+package ocicli
 
 import (
 	"fmt"
@@ -6,7 +6,7 @@ import (
 )
 
 // handleSSHKeysForwarding mounts the entire SSH directory read-only
-func (p *DockerProvider) handleSSHKeysForwarding(sshDir, username string) []string {
+func (p *Provider) handleSSHKeysForwarding(sshDir, username string) []string {
 	var args []string
 
 	if _, err := os.Stat(sshDir); err == nil {
@@ -1,15 +1,17 @@
-package podman
+package ocicli
 
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/jedi4ever/addt/config/security"
 )
 
 // handleSSHProxyForwarding creates a filtered SSH agent proxy
-func (p *PodmanProvider) handleSSHProxyForwarding(sshDir, username string, allowedKeys []string) []string {
+func (p *Provider) handleSSHProxyForwarding(sshDir, username string, allowedKeys []string) []string {
 	var args []string
 
 	sshAuthSock := os.Getenv("SSH_AUTH_SOCK")
@@ -18,10 +20,21 @@ func (p *PodmanProvider) handleSSHProxyForwarding(sshDir, username string, allow
 		return args
 	}
 
-	// On macOS, podman runs in a VM and can't mount Unix sockets via virtiofs.
+	// Forwarding keys that could log in anywhere isn't what was asked for
+	var hostRules []security.SSHHostRule
+	if p.config != nil {
+		rules, err := security.ParseSSHHostRules(p.config.SSHAllowedHosts)
+		if err != nil {
+			fmt.Printf("Warning: %v, not forwarding SSH agent\n", err)
+			return args
+		}
+		hostRules = rules
+	}
+
+	// On macOS, containers run in a VM and the proxy socket can't be mounted.
 	// Use TCP mode: proxy listens on TCP, container connects via socat.
 	if runtime.GOOS == "darwin" {
-		return p.handleSSHProxyForwardingTCP(sshAuthSock, sshDir, username, allowedKeys)
+		return p.handleSSHProxyForwardingTCP(sshAuthSock, sshDir, username, allowedKeys, hostRules)
 	}
 
 	// Linux: use Unix socket (can be mounted directly)
@@ -37,6 +50,8 @@ func (p *PodmanProvider) handleSSHProxyForwarding(sshDir, username string, allow
 	}
 
 	p.sshProxy = proxy
+	p.limitSSHHosts(proxy, hostRules, sshDir)
+	p.approveSSHSigns(proxy, sshDir)
 
 	proxySocket := proxy.SocketPath()
 	args = append(args, "-v", fmt.Sprintf("%s:/ssh-agent", proxySocket))
@@ -54,10 +69,10 @@ func (p *PodmanProvider) handleSSHProxyForwarding(sshDir, username string, allow
 
 // handleSSHProxyForwardingTCP creates a TCP-based SSH agent proxy for macOS.
 // The proxy listens on a TCP port on the host; the container connects via socat.
-func (p *PodmanProvider) handleSSHProxyForwardingTCP(sshAuthSock, sshDir, username string, allowedKeys []string) []string {
+func (p *Provider) handleSSHProxyForwardingTCP(sshAuthSock, sshDir, username string, allowedKeys []string, hostRules []security.SSHHostRule) []string {
 	var args []string
 
-	proxy, err := security.NewSSHProxyAgentTCP(sshAuthSock, allowedKeys)
+	proxy, err := security.NewSSHProxyAgentTCP(sshAuthSock, p.helperListenIP(), allowedKeys)
 	if err != nil {
 		fmt.Printf("Warning: failed to create SSH TCP proxy: %v\n", err)
 		return args
@@ -69,17 +84,11 @@ func (p *PodmanProvider) handleSSHProxyForwardingTCP(sshAuthSock, sshDir, userna
 	}
 
 	p.sshProxy = proxy
-
-	// Get host IP reachable from the container
-	hostIP, err := getHostGatewayIP()
-	if err != nil {
-		fmt.Printf("Warning: could not detect host IP for SSH proxy: %v\n", err)
-		proxy.Stop()
-		return args
-	}
+	p.limitSSHHosts(proxy, hostRules, sshDir)
+	p.approveSSHSigns(proxy, sshDir)
 
 	// Pass connection info as env vars — entrypoint uses socat to bridge TCP→Unix socket
-	args = append(args, "-e", fmt.Sprintf("ADDT_SSH_PROXY_HOST=%s", hostIP))
+	args = append(args, "-e", fmt.Sprintf("ADDT_SSH_PROXY_HOST=%s", egressProxyHost))
 	args = append(args, "-e", fmt.Sprintf("ADDT_SSH_PROXY_PORT=%d", proxy.TCPPort()))
 
 	// Mount safe SSH files only (config, known_hosts, public keys)
@@ -93,3 +102,21 @@ func (p *PodmanProvider) handleSSHProxyForwardingTCP(sshAuthSock, sshDir, userna
 
 	return args
 }
+
+// limitSSHHosts makes the SSH proxy refuse logins to hosts outside
+// ssh.allowed_hosts, whose names are looked up in the host's known_hosts
+func (p *Provider) limitSSHHosts(proxy *security.SSHProxyAgent, rules []security.SSHHostRule, sshDir string) {
+	if len(rules) == 0 {
+		return
+	}
+	proxy.SetAllowedHosts(rules, filepath.Join(sshDir, "known_hosts"))
+	hosts := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Key != "" {
+			hosts = append(hosts, rule.Key+"="+rule.Host)
+		} else {
+			hosts = append(hosts, rule.Host)
+		}
+	}
+	fmt.Printf("SSH proxy: logins limited to %s\n", strings.Join(hosts, ", "))
+}
@@ -1,20 +1,19 @@
-package podman
+package ocicli
 
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
 
 	"github.com/jedi4ever/addt/provider"
 )
 
 // GetStatus returns a status string for display
-func (p *PodmanProvider) GetStatus(cfg *provider.Config, envName string) string {
+func (p *Provider) GetStatus(cfg *provider.Config, envName string) string {
 	var parts []string
 
 	// Provider name
-	parts = append(parts, "podman")
+	parts = append(parts, p.GetName())
 
 	// Resource limits
 	resources := buildResourceString(cfg)
@@ -23,11 +22,8 @@ func (p *PodmanProvider) GetStatus(cfg *provider.Config, envName string) string
 	}
 
 	// Get Node version from image labels
-	cmd := exec.Command("podman", "inspect", cfg.ImageName, "--format", "{{index .Config.Labels \"tools.node.version\"}}")
-	if output, err := cmd.Output(); err == nil {
-		if nodeVersion := strings.TrimSpace(string(output)); nodeVersion != "" {
-			parts = append(parts, fmt.Sprintf("Node %s", nodeVersion))
-		}
+	if nodeVersion := p.backend.ImageLabel(cfg.ImageName, "tools.node.version"); nodeVersion != "" {
+		parts = append(parts, fmt.Sprintf("Node %s", nodeVersion))
 	}
 
 	// Show mounted workdir with RW/RO/none indicator (key security boundary)
@@ -38,6 +34,10 @@ func (p *PodmanProvider) GetStatus(cfg *provider.Config, envName string) string
 	if cfg.WorkdirAutomount {
 		if cfg.WorkdirReadonly {
 			parts = append(parts, fmt.Sprintf("%s [RO]", workdir))
+		} else if cfg.WorkdirWorktree {
+			parts = append(parts, fmt.Sprintf("%s [worktree]", workdir))
+		} else if cfg.WorkdirOverlay {
+			parts = append(parts, fmt.Sprintf("%s [overlay]", workdir))
 		} else {
 			parts = append(parts, fmt.Sprintf("%s [RW]", workdir))
 		}
@@ -60,14 +60,14 @@ func (p *PodmanProvider) GetStatus(cfg *provider.Config, envName string) string
 
 	switch cfg.DockerDindMode {
 	case "isolated", "true":
-		parts = append(parts, "PinP:isolated") // Podman-in-Podman
+		parts = append(parts, p.rt.nestedLabel()+":isolated")
 	case "host":
-		parts = append(parts, "PinP:host")
+		parts = append(parts, p.rt.nestedLabel()+":host")
 	}
 
 	if cfg.FirewallEnabled {
 		mode := cfg.FirewallMode
-		if p.CheckPastaAvailable() {
+		if p.rt.pastaAvailable() {
 			mode += "+pasta"
 		}
 		parts = append(parts, fmt.Sprintf("Firewall:%s", mode))
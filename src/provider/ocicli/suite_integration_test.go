@@ -0,0 +1,35 @@
+//go:build integration
+
+package ocicli
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/jedi4ever/addt/provider"
+)
+
+// requireRuntime skips the test unless rt is installed and running
+func requireRuntime(t *testing.T, rt Runtime) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping container test in short mode")
+	}
+	if rt.Context != "" {
+		if !provider.HasDockerContext(rt.Context) {
+			t.Skipf("%s not installed (no %s context)", rt.Name, rt.Context)
+		}
+		return
+	}
+	if _, err := exec.LookPath(rt.Binary); err != nil {
+		t.Skipf("%s not found, skipping integration test", rt.Binary)
+	}
+	if err := exec.Command(rt.Binary, "info").Run(); err != nil {
+		t.Skipf("%s not running, skipping integration test", rt.Name)
+	}
+}
+
+// runtimeCmd creates a CLI command for rt, targeting its context if any
+func runtimeCmd(rt Runtime, args ...string) *exec.Cmd {
+	return newCLIBackend(rt).cmd(args...)
+}
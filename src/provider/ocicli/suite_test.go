@@ -0,0 +1,34 @@
+package ocicli
+
+import (
+	"embed"
+	"testing"
+
+	"github.com/jedi4ever/addt/provider"
+)
+
+// testRuntimes lists the runtime descriptors the shared suite runs against
+func testRuntimes() []Runtime {
+	return []Runtime{
+		DockerRuntime("desktop-linux"),
+		DockerRuntime("rancher-desktop"),
+		OrbStackRuntime(),
+		PodmanRuntime(),
+		NerdctlRuntime(),
+	}
+}
+
+// forEachRuntime runs fn as a subtest for every runtime descriptor
+func forEachRuntime(t *testing.T, fn func(t *testing.T, rt Runtime)) {
+	t.Helper()
+	for _, rt := range testRuntimes() {
+		t.Run(rt.Name, func(t *testing.T) {
+			fn(t, rt)
+		})
+	}
+}
+
+// newTestProvider creates a Provider for rt without embedded assets
+func newTestProvider(rt Runtime, cfg *provider.Config) *Provider {
+	return New(rt, cfg, nil, nil, nil, nil, nil, embed.FS{})
+}
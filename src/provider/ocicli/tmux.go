@@ -1,4 +1,4 @@
-package docker
+package ocicli
 
 import (
 	"fmt"
@@ -29,7 +29,7 @@ type tmuxProxy struct {
 // HandleTmuxForwarding handles tmux socket forwarding to the container.
 // When enabled, it detects the active tmux socket and mounts it into the container,
 // allowing the container to interact with the host's tmux session.
-func (p *DockerProvider) HandleTmuxForwarding(enabled bool) []string {
+func (p *Provider) HandleTmuxForwarding(enabled bool) []string {
 	if !enabled {
 		return nil
 	}
@@ -58,7 +58,7 @@ func (p *DockerProvider) HandleTmuxForwarding(enabled bool) []string {
 		return nil
 	}
 
-	// On macOS, Docker Desktop can't mount Unix sockets via virtiofs — use TCP bridge
+	// On macOS, sockets can't be mounted via virtiofs — use TCP bridge
 	if runtime.GOOS == "darwin" {
 		return p.handleTmuxForwardingTCP(socketPath, parts)
 	}
@@ -86,23 +86,16 @@ func (p *DockerProvider) HandleTmuxForwarding(enabled bool) []string {
 	return args
 }
 
-// handleTmuxForwardingTCP uses TCP bridge for macOS + Docker Desktop.
-func (p *DockerProvider) handleTmuxForwardingTCP(socketPath string, tmuxParts []string) []string {
+// handleTmuxForwardingTCP uses TCP bridge for macOS.
+func (p *Provider) handleTmuxForwardingTCP(socketPath string, tmuxParts []string) []string {
 	proxy, err := p.createTmuxProxyTCP(socketPath)
 	if err != nil {
 		fmt.Printf("Warning: failed to create tmux TCP proxy: %v\n", err)
 		return nil
 	}
 
-	hostIP, err := getHostGatewayIP()
-	if err != nil {
-		fmt.Printf("Warning: could not detect host IP for tmux proxy: %v\n", err)
-		proxy.Stop()
-		return nil
-	}
-
 	var args []string
-	args = append(args, "-e", fmt.Sprintf("ADDT_TMUX_PROXY_HOST=%s", hostIP))
+	args = append(args, "-e", fmt.Sprintf("ADDT_TMUX_PROXY_HOST=%s", egressProxyHost))
 	args = append(args, "-e", fmt.Sprintf("ADDT_TMUX_PROXY_PORT=%d", proxy.tcpPort))
 
 	// Pass the remaining TMUX parts (pid, window) for reconstruction in the entrypoint
@@ -119,10 +112,10 @@ func (p *DockerProvider) handleTmuxForwardingTCP(socketPath string, tmuxParts []
 }
 
 // createTmuxProxyTCP creates a TCP-based tmux proxy for macOS.
-func (p *DockerProvider) createTmuxProxyTCP(upstreamSocket string) (*tmuxProxy, error) {
-	// Must bind 0.0.0.0 because containers connect via host.docker.internal
-	// which resolves to a non-loopback IP (VM gateway)
-	listener, err := net.Listen("tcp", "0.0.0.0:0")
+func (p *Provider) createTmuxProxyTCP(upstreamSocket string) (*tmuxProxy, error) {
+	// Containers connect via host.docker.internal; bind only the address
+	// it leads to, not every interface
+	listener, err := net.Listen("tcp", net.JoinHostPort(p.helperListenIP(), "0"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to listen on TCP: %w", err)
 	}
@@ -142,7 +135,7 @@ func (p *DockerProvider) createTmuxProxyTCP(upstreamSocket string) (*tmuxProxy,
 }
 
 // createTmuxProxy creates a Unix socket proxy from addt sockets dir to the real tmux socket
-func (p *DockerProvider) createTmuxProxy(upstreamSocket string) (string, string, error) {
+func (p *Provider) createTmuxProxy(upstreamSocket string) (string, string, error) {
 	addtHome := util.GetAddtHome()
 	if addtHome == "" {
 		return "", "", fmt.Errorf("failed to determine addt home directory")
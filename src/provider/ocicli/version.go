@@ -1,4 +1,4 @@
-package podman
+package ocicli
 
 import (
 	"encoding/json"
@@ -10,7 +10,7 @@ import (
 const npmRegistryURL = "https://registry.npmjs.org/@anthropic-ai/claude-code"
 
 // getNpmVersionByTag fetches the version for a specific dist-tag (latest, stable, etc.)
-func (p *PodmanProvider) getNpmVersionByTag(tag string) string {
+func (p *Provider) getNpmVersionByTag(tag string) string {
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Get(npmRegistryURL)
 	if err != nil {
@@ -38,12 +38,12 @@ func (p *PodmanProvider) getNpmVersionByTag(tag string) string {
 }
 
 // getNpmLatestVersion fetches the latest stable version from npm registry (kept for compatibility)
-func (p *PodmanProvider) getNpmLatestVersion() string {
+func (p *Provider) getNpmLatestVersion() string {
 	return p.getNpmVersionByTag("latest")
 }
 
 // validateNpmVersion checks if a specific version exists in npm registry
-func (p *PodmanProvider) validateNpmVersion(version string) bool {
+func (p *Provider) validateNpmVersion(version string) bool {
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Get(npmRegistryURL)
 	if err != nil {
@@ -3,60 +3,27 @@ package orbstack
 import (
 	"embed"
 	"fmt"
-	"os"
 	"os/exec"
 	"runtime"
 
-	"github.com/jedi4ever/addt/config/security"
 	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/provider/ocicli"
 )
 
-// OrbStackProvider implements the Provider interface for OrbStack
-type OrbStackProvider struct {
-	config                 *provider.Config
-	tempDirs               []string
-	sshProxy               *security.SSHProxyAgent
-	gpgProxy               *security.GPGProxyAgent
-	tmuxProxy              *tmuxProxy
-	embeddedDockerfile     []byte
-	embeddedDockerfileBase []byte
-	embeddedEntrypoint     []byte
-	embeddedInitFirewall   []byte
-	embeddedInstallSh      []byte
-	embeddedExtensions     embed.FS
-}
-
 // NewOrbStackProvider creates a new OrbStack provider
 func NewOrbStackProvider(cfg *provider.Config, dockerfile, dockerfileBase, entrypoint, initFirewall, installSh []byte, extensions embed.FS) (provider.Provider, error) {
-	return &OrbStackProvider{
-		config:                 cfg,
-		tempDirs:               []string{},
-		embeddedDockerfile:     dockerfile,
-		embeddedDockerfileBase: dockerfileBase,
-		embeddedEntrypoint:     entrypoint,
-		embeddedInitFirewall:   initFirewall,
-		embeddedInstallSh:      installSh,
-		embeddedExtensions:     extensions,
-	}, nil
-}
-
-// Initialize initializes the OrbStack provider
-func (p *OrbStackProvider) Initialize(cfg *provider.Config) error {
-	p.config = cfg
-
-	// Clean up stale temp directories from previous runs
-	security.CleanupAll()
-
-	return p.CheckPrerequisites()
+	return ocicli.New(Runtime(), cfg, dockerfile, dockerfileBase, entrypoint, initFirewall, installSh, extensions), nil
 }
 
-// GetName returns the provider name
-func (p *OrbStackProvider) GetName() string {
-	return "orbstack"
+// Runtime returns the OrbStack runtime descriptor
+func Runtime() ocicli.Runtime {
+	rt := ocicli.OrbStackRuntime()
+	rt.Prerequisites = checkPrerequisites
+	return rt
 }
 
-// CheckPrerequisites verifies OrbStack and Docker CLI are installed and OrbStack is running
-func (p *OrbStackProvider) CheckPrerequisites() error {
+// checkPrerequisites verifies OrbStack and Docker CLI are installed and OrbStack is running
+func checkPrerequisites() error {
 	// OrbStack is macOS-only
 	if runtime.GOOS != "darwin" {
 		return fmt.Errorf("OrbStack is only available on macOS")
@@ -84,44 +51,3 @@ func (p *OrbStackProvider) CheckPrerequisites() error {
 
 	return nil
 }
-
-// Container lifecycle methods (Exists, IsRunning, Start, Stop, Remove, List)
-// and name generation (GenerateContainerName, GenerateEphemeralName, GeneratePersistentName)
-// are defined in persistent.go
-
-// dockerCmd creates an exec.Cmd for docker targeting the "orbstack" context.
-func (p *OrbStackProvider) dockerCmd(args ...string) *exec.Cmd {
-	return provider.DockerCmd("orbstack", args...)
-}
-
-// dockerEnv returns the environment slice for Docker commands in the orbstack context.
-func (p *OrbStackProvider) dockerEnv() []string {
-	return append(os.Environ(), "DOCKER_CONTEXT=orbstack")
-}
-
-// Cleanup removes temporary directories and stops proxies
-func (p *OrbStackProvider) Cleanup() error {
-	// Stop SSH proxy if running
-	if p.sshProxy != nil {
-		p.sshProxy.Stop()
-		p.sshProxy = nil
-	}
-
-	// Stop GPG proxy if running
-	if p.gpgProxy != nil {
-		p.gpgProxy.Stop()
-		p.gpgProxy = nil
-	}
-
-	// Stop tmux proxy if running
-	if p.tmuxProxy != nil {
-		p.tmuxProxy.Stop()
-		p.tmuxProxy = nil
-	}
-
-	for _, dir := range p.tempDirs {
-		os.RemoveAll(dir)
-	}
-	p.tempDirs = []string{}
-	return nil
-}
@@ -1,353 +0,0 @@
-//go:build integration
-
-package podman
-
-import (
-	"os"
-	"os/exec"
-	"strings"
-	"testing"
-	"time"
-
-	"github.com/jedi4ever/addt/config"
-	"github.com/jedi4ever/addt/provider"
-)
-
-// checkPodmanForPersistent verifies Podman is available
-func checkPodmanForPersistent(t *testing.T) {
-	t.Helper()
-	podmanPath := config.GetPodmanPath()
-	if podmanPath == "" {
-		t.Skip("Podman not found, skipping integration test")
-	}
-	cmd := exec.Command(podmanPath, "info")
-	if err := cmd.Run(); err != nil {
-		t.Skip("Podman not running, skipping integration test")
-	}
-}
-
-// createPersistentIntegrationProvider creates a PodmanProvider for persistent integration tests
-func createPersistentIntegrationProvider(workdir, extensions string) *PodmanProvider {
-	return &PodmanProvider{
-		config: &provider.Config{
-			Workdir:    workdir,
-			Extensions: extensions,
-		},
-		tempDirs: []string{},
-	}
-}
-
-// cleanupPodmanContainer removes a container if it exists
-func cleanupPodmanContainer(name string) {
-	podmanPath := config.GetPodmanPath()
-	if podmanPath != "" {
-		exec.Command(podmanPath, "rm", "-f", name).Run()
-	}
-}
-
-func TestPersistent_Integration_GenerateContainerName(t *testing.T) {
-	checkPodmanForPersistent(t)
-
-	testCases := []struct {
-		name       string
-		workdir    string
-		extensions string
-		wantPrefix string
-	}{
-		{
-			name:       "simple workdir",
-			workdir:    "/home/user/myproject",
-			extensions: "claude",
-			wantPrefix: "addt-persistent-myproject-",
-		},
-		{
-			name:       "workdir with special chars",
-			workdir:    "/home/user/My Project!",
-			extensions: "claude",
-			wantPrefix: "addt-persistent-my-project-",
-		},
-		{
-			name:       "long workdir name",
-			workdir:    "/home/user/this-is-a-very-long-directory-name-that-should-be-truncated",
-			extensions: "claude",
-			wantPrefix: "addt-persistent-this-is-a-very-long-",
-		},
-		{
-			name:       "multiple extensions",
-			workdir:    "/home/user/project",
-			extensions: "claude,codex",
-			wantPrefix: "addt-persistent-project-",
-		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			prov := createPersistentIntegrationProvider(tc.workdir, tc.extensions)
-			name := prov.GenerateContainerName()
-
-			if !strings.HasPrefix(name, tc.wantPrefix) {
-				t.Errorf("GenerateContainerName() = %q, want prefix %q", name, tc.wantPrefix)
-			}
-
-			// Name should be consistent
-			name2 := prov.GenerateContainerName()
-			if name != name2 {
-				t.Errorf("GenerateContainerName() not consistent: %q != %q", name, name2)
-			}
-		})
-	}
-}
-
-func TestPersistent_Integration_DifferentExtensionsDifferentNames(t *testing.T) {
-	checkPodmanForPersistent(t)
-
-	workdir := "/home/user/testproject"
-
-	prov1 := createPersistentIntegrationProvider(workdir, "claude")
-	prov2 := createPersistentIntegrationProvider(workdir, "codex")
-	prov3 := createPersistentIntegrationProvider(workdir, "claude,codex")
-
-	name1 := prov1.GenerateContainerName()
-	name2 := prov2.GenerateContainerName()
-	name3 := prov3.GenerateContainerName()
-
-	if name1 == name2 {
-		t.Errorf("Same name for different extensions: %q", name1)
-	}
-	if name1 == name3 {
-		t.Errorf("Same name for different extensions: %q", name1)
-	}
-	if name2 == name3 {
-		t.Errorf("Same name for different extensions: %q", name2)
-	}
-}
-
-func TestPersistent_Integration_ExtensionOrderDoesNotMatter(t *testing.T) {
-	checkPodmanForPersistent(t)
-
-	workdir := "/home/user/testproject"
-
-	prov1 := createPersistentIntegrationProvider(workdir, "claude,codex")
-	prov2 := createPersistentIntegrationProvider(workdir, "codex,claude")
-
-	name1 := prov1.GenerateContainerName()
-	name2 := prov2.GenerateContainerName()
-
-	if name1 != name2 {
-		t.Errorf("Extension order matters: %q != %q", name1, name2)
-	}
-}
-
-func TestPersistent_Integration_GenerateEphemeralName(t *testing.T) {
-	checkPodmanForPersistent(t)
-
-	prov := createPersistentIntegrationProvider("/tmp", "claude")
-
-	name1 := prov.GenerateEphemeralName()
-	time.Sleep(time.Second) // Ensure different timestamp
-	name2 := prov.GenerateEphemeralName()
-
-	if !strings.HasPrefix(name1, "addt-") {
-		t.Errorf("Ephemeral name should start with 'addt-': %q", name1)
-	}
-
-	if strings.HasPrefix(name1, "addt-persistent-") {
-		t.Errorf("Ephemeral name should not be persistent: %q", name1)
-	}
-
-	if name1 == name2 {
-		t.Errorf("Ephemeral names should be unique: %q == %q", name1, name2)
-	}
-}
-
-func TestPersistent_Integration_ContainerLifecycle(t *testing.T) {
-	checkPodmanForPersistent(t)
-
-	containerName := "addt-persistent-test-lifecycle"
-	cleanupPodmanContainer(containerName)
-	defer cleanupPodmanContainer(containerName)
-
-	prov := &PodmanProvider{}
-	podmanPath := config.GetPodmanPath()
-
-	// Initially should not exist
-	if prov.Exists(containerName) {
-		t.Fatal("Container should not exist initially")
-	}
-
-	if prov.IsRunning(containerName) {
-		t.Fatal("Container should not be running initially")
-	}
-
-	// Create a container
-	cmd := exec.Command(podmanPath, "run", "-d", "--name", containerName, "alpine:latest", "sleep", "60")
-	if err := cmd.Run(); err != nil {
-		t.Fatalf("Failed to create container: %v", err)
-	}
-
-	// Should exist and be running
-	if !prov.Exists(containerName) {
-		t.Error("Container should exist after creation")
-	}
-
-	if !prov.IsRunning(containerName) {
-		t.Error("Container should be running after creation")
-	}
-
-	// Stop the container
-	if err := prov.Stop(containerName); err != nil {
-		t.Fatalf("Failed to stop container: %v", err)
-	}
-
-	// Should exist but not be running
-	if !prov.Exists(containerName) {
-		t.Error("Container should still exist after stop")
-	}
-
-	if prov.IsRunning(containerName) {
-		t.Error("Container should not be running after stop")
-	}
-
-	// Start the container
-	if err := prov.Start(containerName); err != nil {
-		t.Fatalf("Failed to start container: %v", err)
-	}
-
-	// Should be running again
-	if !prov.IsRunning(containerName) {
-		t.Error("Container should be running after start")
-	}
-
-	// Remove the container
-	if err := prov.Remove(containerName); err != nil {
-		t.Fatalf("Failed to remove container: %v", err)
-	}
-
-	// Should not exist
-	if prov.Exists(containerName) {
-		t.Error("Container should not exist after removal")
-	}
-}
-
-func TestPersistent_Integration_ListContainers(t *testing.T) {
-	checkPodmanForPersistent(t)
-
-	podmanPath := config.GetPodmanPath()
-	testContainers := []string{
-		"addt-persistent-test-list-1",
-		"addt-persistent-test-list-2",
-	}
-
-	for _, name := range testContainers {
-		cleanupPodmanContainer(name)
-	}
-	defer func() {
-		for _, name := range testContainers {
-			cleanupPodmanContainer(name)
-		}
-	}()
-
-	// Create containers
-	for _, name := range testContainers {
-		cmd := exec.Command(podmanPath, "run", "-d", "--name", name, "alpine:latest", "sleep", "60")
-		if err := cmd.Run(); err != nil {
-			t.Fatalf("Failed to create container %s: %v", name, err)
-		}
-	}
-
-	prov := &PodmanProvider{}
-	envs, err := prov.List()
-	if err != nil {
-		t.Fatalf("List() failed: %v", err)
-	}
-
-	// Check our test containers are in the list
-	foundContainers := make(map[string]bool)
-	for _, env := range envs {
-		foundContainers[env.Name] = true
-	}
-
-	for _, name := range testContainers {
-		if !foundContainers[name] {
-			t.Errorf("Container %s not found in list", name)
-		}
-	}
-}
-
-func TestPersistent_Integration_ReuseExistingContainer(t *testing.T) {
-	checkPodmanForPersistent(t)
-
-	podmanPath := config.GetPodmanPath()
-	containerName := "addt-persistent-test-reuse"
-	cleanupPodmanContainer(containerName)
-	defer cleanupPodmanContainer(containerName)
-
-	// Create a container with a marker file
-	cmd := exec.Command(podmanPath, "run", "-d", "--name", containerName,
-		"alpine:latest", "sh", "-c", "echo 'marker' > /tmp/marker && sleep 60")
-	if err := cmd.Run(); err != nil {
-		t.Fatalf("Failed to create container: %v", err)
-	}
-
-	// Verify marker file exists
-	checkCmd := exec.Command(podmanPath, "exec", containerName, "cat", "/tmp/marker")
-	output, err := checkCmd.Output()
-	if err != nil {
-		t.Fatalf("Failed to check marker file: %v", err)
-	}
-
-	if !strings.Contains(string(output), "marker") {
-		t.Error("Marker file not found in container")
-	}
-
-	// Stop the container
-	prov := &PodmanProvider{}
-	if err := prov.Stop(containerName); err != nil {
-		t.Fatalf("Failed to stop container: %v", err)
-	}
-
-	// Start it again
-	if err := prov.Start(containerName); err != nil {
-		t.Fatalf("Failed to start container: %v", err)
-	}
-
-	// Marker file should still exist (persistent state)
-	checkCmd = exec.Command(podmanPath, "exec", containerName, "cat", "/tmp/marker")
-	output, err = checkCmd.Output()
-	if err != nil {
-		t.Fatalf("Failed to check marker file after restart: %v", err)
-	}
-
-	if !strings.Contains(string(output), "marker") {
-		t.Error("Marker file lost after container restart - state not persisted")
-	}
-}
-
-func TestPersistent_Integration_WorkdirFromEnv(t *testing.T) {
-	checkPodmanForPersistent(t)
-
-	// Test with empty workdir (should use current directory)
-	prov := createPersistentIntegrationProvider("", "claude")
-
-	name := prov.GenerateContainerName()
-
-	if !strings.HasPrefix(name, "addt-persistent-") {
-		t.Errorf("Name should start with 'addt-persistent-': %q", name)
-	}
-
-	cwd, _ := os.Getwd()
-	t.Logf("Generated name: %s (from cwd: %s)", name, cwd)
-}
-
-func TestPersistent_Integration_GeneratePersistentNameAlias(t *testing.T) {
-	checkPodmanForPersistent(t)
-
-	prov := createPersistentIntegrationProvider("/tmp/test", "claude")
-
-	name1 := prov.GenerateContainerName()
-	name2 := prov.GeneratePersistentName()
-
-	if name1 != name2 {
-		t.Errorf("GeneratePersistentName() should equal GenerateContainerName(): %q != %q", name1, name2)
-	}
-}
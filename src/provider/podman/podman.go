@@ -3,59 +3,26 @@ package podman
 import (
 	"embed"
 	"fmt"
-	"os"
 	"os/exec"
 
-	"github.com/jedi4ever/addt/config/security"
 	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/provider/ocicli"
 )
 
-// PodmanProvider implements the Provider interface for Podman
-type PodmanProvider struct {
-	config                 *provider.Config
-	tempDirs               []string
-	sshProxy               *security.SSHProxyAgent
-	gpgProxy               *security.GPGProxyAgent
-	tmuxProxy              *tmuxProxy
-	embeddedDockerfile     []byte
-	embeddedDockerfileBase []byte
-	embeddedEntrypoint     []byte
-	embeddedInitFirewall   []byte
-	embeddedInstallSh      []byte
-	embeddedExtensions     embed.FS
-}
-
 // NewPodmanProvider creates a new Podman provider
 func NewPodmanProvider(cfg *provider.Config, dockerfile, dockerfileBase, entrypoint, initFirewall, installSh []byte, extensions embed.FS) (provider.Provider, error) {
-	return &PodmanProvider{
-		config:                 cfg,
-		tempDirs:               []string{},
-		embeddedDockerfile:     dockerfile,
-		embeddedDockerfileBase: dockerfileBase,
-		embeddedEntrypoint:     entrypoint,
-		embeddedInitFirewall:   initFirewall,
-		embeddedInstallSh:      installSh,
-		embeddedExtensions:     extensions,
-	}, nil
-}
-
-// Initialize initializes the Podman provider
-func (p *PodmanProvider) Initialize(cfg *provider.Config) error {
-	p.config = cfg
-
-	// Clean up stale temp directories from previous runs
-	security.CleanupAll()
-
-	return p.CheckPrerequisites()
+	return ocicli.New(Runtime(), cfg, dockerfile, dockerfileBase, entrypoint, initFirewall, installSh, extensions), nil
 }
 
-// GetName returns the provider name
-func (p *PodmanProvider) GetName() string {
-	return "podman"
+// Runtime returns the Podman runtime descriptor
+func Runtime() ocicli.Runtime {
+	rt := ocicli.PodmanRuntime()
+	rt.Prerequisites = checkPrerequisites
+	return rt
 }
 
-// CheckPrerequisites verifies Podman is installed
-func (p *PodmanProvider) CheckPrerequisites() error {
+// checkPrerequisites verifies Podman is installed
+func checkPrerequisites() error {
 	// Check Podman is installed
 	if _, err := exec.LookPath("podman"); err != nil {
 		return fmt.Errorf("Podman is not installed. Please install Podman from: https://podman.io/getting-started/installation")
@@ -69,40 +36,3 @@ func (p *PodmanProvider) CheckPrerequisites() error {
 
 	return nil
 }
-
-// CheckPastaAvailable checks if pasta is available for network namespaces
-func (p *PodmanProvider) CheckPastaAvailable() bool {
-	_, err := exec.LookPath("pasta")
-	return err == nil
-}
-
-// Container lifecycle methods (Exists, IsRunning, Start, Stop, Remove, List)
-// and name generation (GenerateContainerName, GenerateEphemeralName, GeneratePersistentName)
-// are defined in persistent.go
-
-// Cleanup removes temporary directories and stops proxies
-func (p *PodmanProvider) Cleanup() error {
-	// Stop SSH proxy if running
-	if p.sshProxy != nil {
-		p.sshProxy.Stop()
-		p.sshProxy = nil
-	}
-
-	// Stop GPG proxy if running
-	if p.gpgProxy != nil {
-		p.gpgProxy.Stop()
-		p.gpgProxy = nil
-	}
-
-	// Stop tmux proxy if running
-	if p.tmuxProxy != nil {
-		p.tmuxProxy.Stop()
-		p.tmuxProxy = nil
-	}
-
-	for _, dir := range p.tempDirs {
-		os.RemoveAll(dir)
-	}
-	p.tempDirs = []string{}
-	return nil
-}
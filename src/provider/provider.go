@@ -1,6 +1,11 @@
 package provider
 
 import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
 	"github.com/jedi4ever/addt/config/otel"
 	"github.com/jedi4ever/addt/config/security"
 )
@@ -21,6 +26,22 @@ type Provider interface {
 	Remove(name string) error
 	List() ([]Environment, error)
 
+	// Access to existing environments. CopyTo and CopyFrom follow `docker cp`:
+	// a destination that is an existing directory receives the source inside
+	// it, otherwise the copy is created at the destination path.
+	Exec(name string, cmd []string, tty bool, stdio Stdio) error
+	Logs(name string, follow bool, out io.Writer) error
+	CopyTo(name, hostPath, envPath string) error
+	CopyFrom(name, envPath, hostPath string) error
+	Inspect(name string) (*EnvironmentInfo, error)
+
+	// Snapshots of persistent environments. ListSnapshots lists all
+	// environments' snapshots when name is empty.
+	Snapshot(name, tag string, checkpoint bool) (*Snapshot, error)
+	Restore(name, tag string) error
+	ListSnapshots(name string) ([]Snapshot, error)
+	RemoveSnapshot(name, tag string) error
+
 	// Environment naming
 	GeneratePersistentName() string
 	GenerateEphemeralName() string
@@ -37,6 +58,10 @@ type Provider interface {
 	GetExtensionEnvVars(imageName string) []string
 }
 
+// ErrNotSupported is returned (wrapped) by providers that cannot perform an
+// operation, e.g. fetching logs of a sandbox that only runs attached
+var ErrNotSupported = errors.New("not supported by this provider")
+
 // Config holds provider configuration
 type Config struct {
 	AddtVersion               string
@@ -44,17 +69,26 @@ type Config struct {
 	GoVersion                 string
 	UvVersion                 string
 	EnvVars                   []string
+	Secrets                   map[string]string // env var -> secret reference, resolved on the host
 	GitHubForwardToken        bool
 	GitHubTokenSource         string
 	GitHubScopeToken          bool
 	GitHubScopeRepos          []string
+	GitHubHoldToken           bool
+	GitHubAppID               string // GitHub App minting repo-scoped tokens for git
+	GitHubAppInstallationID   string
+	GitHubAppKeyFile          string
+	GitHubAppPermissions      []string // e.g. contents:write
 	Ports                     []string
 	PortRangeStart            int
 	PortsInjectSystemPrompt   bool
+	HostServices              []security.HostService // ports.host_services, reachable as <name>.host.addt
 	SSHForwardKeys            bool
 	SSHForwardMode            string
 	SSHAllowedKeys            []string
+	SSHAllowedHosts           []string // hosts forwarded keys may log in to, "host" or "key=host"
 	SSHDir                    string
+	SSHSignPolicy             string // "allow" or "confirm" each signature through the SSH proxy
 	TmuxForward               bool
 	HistoryPersist            bool
 	GitDisableHooks           bool     // Neutralize git hooks inside container (default: true)
@@ -63,7 +97,8 @@ type Config struct {
 	GPGForward                string   // "proxy", "agent", "keys", or "off"
 	GPGAllowedKeyIDs          []string // GPG key IDs (fingerprints) that are allowed
 	GPGDir                    string
-	TerminalOSC               bool // Forward terminal identification for OSC support (default: false)
+	GPGSignPolicy             string // "allow" or "confirm" each signature through the GPG proxy
+	TerminalOSC               bool   // Forward terminal identification for OSC support (default: false)
 	DockerDindMode            string
 	EnvFileLoad               bool
 	EnvFile                   string
@@ -73,14 +108,25 @@ type Config struct {
 	Persistent                bool
 	WorkdirAutomount          bool
 	WorkdirReadonly           bool
+	WorkdirOverlay            bool
+	WorkdirWorktree           bool
+	WorkdirWorktreeName       string
 	WorkdirAutotrust          bool
 	Workdir                   string
 	FirewallEnabled           bool
 	FirewallMode              string
+	FirewallProxy             bool
+	FirewallRules             security.FirewallRules
+	FirewallLearn             bool
+	FirewallQuota             security.EgressQuota
+	FirewallQuotaAction       string // "pause" or "kill"
+	OfflineEnabled            bool   // Reach only the host-side package mirrors
+	OfflineFetch              bool   // Fetch packages missing from the mirror cache
 	Mode                      string
 	Provider                  string
 	Extensions                string
 	Command                   string
+	ContainerName             string
 	ExtensionVersions         map[string]string          // Per-extension versions (e.g., {"claude": "1.0.5", "codex": "latest"})
 	ExtensionConfigAutomount  map[string]bool            // Per-extension automount control (e.g., {"claude": true, "codex": false})
 	ExtensionConfigReadonly   map[string]bool            // Per-extension readonly control for config mounts
@@ -133,15 +179,53 @@ type Environment struct {
 	CreatedAt string
 }
 
+// EnvironmentInfo is the structured description returned by Inspect
+type EnvironmentInfo struct {
+	Name       string            `json:"name"`
+	ID         string            `json:"id,omitempty"`
+	Image      string            `json:"image,omitempty"`
+	Status     string            `json:"status"` // "running", "stopped", "exited", ...
+	Running    bool              `json:"running"`
+	ExitCode   int               `json:"exit_code"`
+	Persistent bool              `json:"persistent"`
+	CreatedAt  time.Time         `json:"created_at"`
+	StartedAt  time.Time         `json:"started_at"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Mounts     []VolumeMount     `json:"mounts,omitempty"`
+	Ports      []PortMapping     `json:"ports,omitempty"`
+}
+
+// Stdio connects an exec'd command to streams. Nil fields use the
+// process's own stdin, stdout and stderr.
+type Stdio struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// WithDefaults returns s with nil streams replaced by the process's stdio
+func (s Stdio) WithDefaults() Stdio {
+	if s.Stdin == nil {
+		s.Stdin = os.Stdin
+	}
+	if s.Stdout == nil {
+		s.Stdout = os.Stdout
+	}
+	if s.Stderr == nil {
+		s.Stderr = os.Stderr
+	}
+	return s
+}
+
 // VolumeMount represents a volume mount
 type VolumeMount struct {
-	Source   string
-	Target   string
-	ReadOnly bool
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"read_only"`
 }
 
 // PortMapping represents a port mapping
 type PortMapping struct {
-	Container int
-	Host      int
+	Container int `json:"container"`
+	Host      int `json:"host"`
 }
@@ -0,0 +1,157 @@
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jedi4ever/addt/provider"
+)
+
+// sandboxSpec describes one bwrap invocation
+type sandboxSpec struct {
+	Hostname string
+	// System holds the pre-built mounts for the host base system
+	System []string
+	// Home is bound read-write at /home/addt
+	Home string
+	// Opt is bound read-only at /opt/addt
+	Opt     string
+	Volumes []provider.VolumeMount
+	WorkDir string
+	// ShareNet keeps the host network namespace (no network_mode: none)
+	ShareNet bool
+	// ReadOnly remounts the sandbox root read-only once everything is mounted
+	ReadOnly bool
+	// NewSession detaches from the controlling terminal (non-interactive runs)
+	NewSession bool
+	// SecretsFD and SeccompFD are inherited descriptors, 0 when unused
+	SecretsFD int
+	SeccompFD int
+	Command   []string
+}
+
+// hostSystemDirs are the host directories that make up the sandbox's base
+// system. Everything else (notably /home and /root) stays invisible.
+var hostSystemDirs = []string{"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/etc", "/nix"}
+
+// systemMounts returns bwrap arguments exposing the host base system
+// read-only. Merged-/usr symlinks such as /bin -> usr/bin are recreated as
+// symlinks rather than bound.
+func systemMounts() []string {
+	var args []string
+	for _, dir := range hostSystemDirs {
+		info, err := os.Lstat(dir)
+		if err != nil {
+			continue
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Readlink(dir); err == nil {
+				args = append(args, "--symlink", target, dir)
+			}
+			continue
+		}
+		args = append(args, "--ro-bind", dir, dir)
+	}
+
+	// systemd-resolved points /etc/resolv.conf into /run, which is not shared
+	if resolved, err := filepath.EvalSymlinks("/etc/resolv.conf"); err == nil && !strings.HasPrefix(resolved, "/etc/") {
+		dir := filepath.Dir(resolved)
+		args = append(args, "--ro-bind", dir, dir)
+	}
+	return args
+}
+
+// buildBwrapArgs builds the bwrap command line for spec. Environment
+// variables are not part of it: they are passed through the process
+// environment so secrets never appear in the host's process list.
+func buildBwrapArgs(spec sandboxSpec) []string {
+	args := []string{
+		"--die-with-parent",
+		"--unshare-user",
+		"--unshare-pid",
+		"--unshare-ipc",
+		"--unshare-uts",
+		"--unshare-cgroup-try",
+	}
+	if !spec.ShareNet {
+		args = append(args, "--unshare-net")
+	}
+	if spec.Hostname != "" {
+		args = append(args, "--hostname", spec.Hostname)
+	}
+	if spec.NewSession {
+		args = append(args, "--new-session")
+	}
+
+	args = append(args, spec.System...)
+	args = append(args,
+		"--proc", "/proc",
+		"--dev", "/dev",
+		"--tmpfs", "/tmp",
+		"--tmpfs", "/var/tmp",
+		"--bind", spec.Home, "/home/addt",
+	)
+	if spec.Opt != "" {
+		args = append(args, "--ro-bind", spec.Opt, "/opt/addt")
+	}
+
+	for _, vol := range spec.Volumes {
+		flag := "--bind"
+		if vol.ReadOnly {
+			flag = "--ro-bind"
+		}
+		args = append(args, flag, vol.Source, vol.Target)
+	}
+
+	if spec.SecretsFD > 0 {
+		// Own tmpfs so the entrypoint can scrub and delete the file even
+		// when the root is remounted read-only
+		args = append(args,
+			"--tmpfs", "/run/secrets",
+			"--file", fmt.Sprint(spec.SecretsFD), "/run/secrets/.secrets")
+	}
+	if spec.SeccompFD > 0 {
+		args = append(args, "--seccomp", fmt.Sprint(spec.SeccompFD))
+	}
+	if spec.ReadOnly {
+		args = append(args, "--remount-ro", "/")
+	}
+
+	workDir := spec.WorkDir
+	if workDir == "" {
+		workDir = "/workspace"
+	}
+	args = append(args, "--chdir", workDir, "--")
+	return append(args, spec.Command...)
+}
+
+// sandboxEnv returns the process environment for a sandbox: a minimal base
+// (bwrap passes its own environment through) followed by env in key order
+func sandboxEnv(env map[string]string) []string {
+	result := []string{
+		"HOME=/home/addt",
+		"USER=addt",
+		"LOGNAME=addt",
+		"SHELL=/bin/bash",
+		"NPM_CONFIG_PREFIX=/home/addt/.npm-global",
+		"PATH=/home/addt/.local/bin:/home/addt/.npm-global/bin:/home/addt/go/bin:/usr/local/go/bin:/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+	}
+	for _, name := range []string{"TERM", "LANG", "LC_ALL", "COLORTERM"} {
+		if v := os.Getenv(name); v != "" {
+			result = append(result, name+"="+v)
+		}
+	}
+
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		result = append(result, k+"="+env[k])
+	}
+	return result
+}
@@ -0,0 +1,129 @@
+package sandbox
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jedi4ever/addt/provider"
+)
+
+// hasArgs reports whether want appears as a contiguous run in args
+func hasArgs(args []string, want ...string) bool {
+	for i := 0; i+len(want) <= len(args); i++ {
+		if reflect.DeepEqual(args[i:i+len(want)], want) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBuildBwrapArgs_Run(t *testing.T) {
+	args := buildBwrapArgs(sandboxSpec{
+		Hostname: "addt-test",
+		System:   []string{"--ro-bind", "/usr", "/usr", "--symlink", "usr/bin", "/bin"},
+		Home:     "/state/run/addt-test/home",
+		Opt:      "/state/rootfs/img/opt",
+		Volumes: []provider.VolumeMount{
+			{Source: "/src/project", Target: "/workspace"},
+			{Source: "/home/u/.gitconfig", Target: "/home/addt/.gitconfig.host", ReadOnly: true},
+		},
+		ShareNet:  true,
+		SecretsFD: 3,
+		SeccompFD: 4,
+		Command:   []string{entrypointPath, "--model", "opus"},
+	})
+
+	for _, want := range [][]string{
+		{"--unshare-user"},
+		{"--unshare-pid"},
+		{"--die-with-parent"},
+		{"--hostname", "addt-test"},
+		{"--symlink", "usr/bin", "/bin"},
+		{"--bind", "/state/run/addt-test/home", "/home/addt"},
+		{"--ro-bind", "/state/rootfs/img/opt", "/opt/addt"},
+		{"--bind", "/src/project", "/workspace"},
+		{"--ro-bind", "/home/u/.gitconfig", "/home/addt/.gitconfig.host"},
+		{"--tmpfs", "/run/secrets", "--file", "3", "/run/secrets/.secrets"},
+		{"--seccomp", "4"},
+		{"--chdir", "/workspace", "--", entrypointPath, "--model", "opus"},
+	} {
+		if !hasArgs(args, want...) {
+			t.Errorf("args missing %v:\n%v", want, args)
+		}
+	}
+	for _, unwanted := range []string{"--unshare-net", "--new-session", "--remount-ro"} {
+		if hasArgs(args, unwanted) {
+			t.Errorf("args should not contain %s", unwanted)
+		}
+	}
+}
+
+func TestBuildBwrapArgs_Isolated(t *testing.T) {
+	args := buildBwrapArgs(sandboxSpec{
+		Home:       "/h",
+		WorkDir:    "/workspace/sub",
+		ReadOnly:   true,
+		NewSession: true,
+		Command:    []string{"true"},
+	})
+
+	if !hasArgs(args, "--unshare-net") {
+		t.Error("network_mode none should unshare the network namespace")
+	}
+	if !hasArgs(args, "--new-session") {
+		t.Error("non-interactive runs should start a new session")
+	}
+	if hasArgs(args, "--file") || hasArgs(args, "--seccomp") || hasArgs(args, "--ro-bind", "", "/opt/addt") {
+		t.Errorf("unused descriptors and mounts should be omitted: %v", args)
+	}
+	// The root is remounted read-only after every mount has been set up
+	tail := strings.Join(args[len(args)-6:], " ")
+	if tail != "--remount-ro / --chdir /workspace/sub -- true" {
+		t.Errorf("args tail = %q", tail)
+	}
+}
+
+func TestSandboxEnv(t *testing.T) {
+	t.Setenv("TERM", "xterm-256color")
+
+	env := sandboxEnv(map[string]string{"ZED": "1", "ADDT_COMMAND": "/bin/bash"})
+
+	if env[0] != "HOME=/home/addt" {
+		t.Errorf("env[0] = %q", env[0])
+	}
+	if !hasArgs(env, "TERM=xterm-256color") {
+		t.Error("TERM should be passed through")
+	}
+	if !hasArgs(env, "ADDT_COMMAND=/bin/bash", "ZED=1") {
+		t.Errorf("spec env should follow the base in key order: %v", env)
+	}
+	for _, e := range env {
+		if strings.HasPrefix(e, "SSH_AUTH_SOCK=") || strings.HasPrefix(e, "GH_TOKEN=") {
+			t.Errorf("host variable leaked into sandbox env: %s", e)
+		}
+	}
+}
+
+func TestLimitProperties(t *testing.T) {
+	got := limitProperties(200, "4gb", "1.5")
+	want := []string{"TasksMax=200", "MemoryMax=4G", "CPUQuota=150%"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("limitProperties() = %v, want %v", got, want)
+	}
+	if got := limitProperties(0, "", ""); got != nil {
+		t.Errorf("no limits should yield no properties, got %v", got)
+	}
+	if got := limitProperties(0, "512m", "lots"); !reflect.DeepEqual(got, []string{"MemoryMax=512M"}) {
+		t.Errorf("limitProperties() = %v", got)
+	}
+}
+
+func TestUlimitPair(t *testing.T) {
+	if got := ulimitPair("4096:8192"); got != "4096:8192" {
+		t.Errorf("ulimitPair() = %q", got)
+	}
+	if got := ulimitPair("256"); got != "256:256" {
+		t.Errorf("ulimitPair() = %q", got)
+	}
+}
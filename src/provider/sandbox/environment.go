@@ -0,0 +1,98 @@
+package sandbox
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/util"
+)
+
+// Access to existing environments. A sandbox has no daemon to exec into
+// or collect logs from; copies are served from the persistent home
+// directory, the only state that outlives a run.
+
+// Exec is not supported: sandboxes exist only for the duration of a run
+func (p *SandboxProvider) Exec(name string, cmd []string, tty bool, stdio provider.Stdio) error {
+	return fmt.Errorf("exec into %s: %w", name, provider.ErrNotSupported)
+}
+
+// Logs is not supported: sandbox output goes straight to the terminal
+func (p *SandboxProvider) Logs(name string, follow bool, out io.Writer) error {
+	return fmt.Errorf("logs of %s: %w", name, provider.ErrNotSupported)
+}
+
+// CopyTo copies a host file or directory into the environment's home
+func (p *SandboxProvider) CopyTo(name, hostPath, envPath string) error {
+	dst, err := homePath(name, envPath)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(hostPath); err != nil {
+		return err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(util.TarPath(hostPath, filepath.Base(hostPath), pw))
+	}()
+	err = util.UntarPath(pr, dst)
+	pr.Close()
+	return err
+}
+
+// CopyFrom copies a file or directory from the environment's home to the
+// host
+func (p *SandboxProvider) CopyFrom(name, envPath, hostPath string) error {
+	src, err := homePath(name, envPath)
+	if err != nil {
+		return err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(util.TarPath(src, filepath.Base(src), pw))
+	}()
+	err = util.UntarPath(pr, hostPath)
+	pr.Close()
+	return err
+}
+
+// homePath maps a path under /home/addt to the persistent home directory
+// that backs it
+func homePath(name, envPath string) (string, error) {
+	if info, err := os.Stat(envDir(name)); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("environment %s not found", name)
+	}
+	clean := path.Clean(envPath)
+	rel, ok := strings.CutPrefix(clean, "/home/addt")
+	if !ok || (rel != "" && !strings.HasPrefix(rel, "/")) {
+		return "", fmt.Errorf("copy %s:%s: only paths under /home/addt are kept between runs: %w", name, envPath, provider.ErrNotSupported)
+	}
+	return filepath.Join(envDir(name), "home", filepath.FromSlash(rel)), nil
+}
+
+// Inspect describes a persistent environment
+func (p *SandboxProvider) Inspect(name string) (*provider.EnvironmentInfo, error) {
+	stat, err := os.Stat(envDir(name))
+	if err != nil {
+		return nil, fmt.Errorf("environment %s not found", name)
+	}
+	info := &provider.EnvironmentInfo{
+		Name:       name,
+		Status:     "stopped",
+		Persistent: strings.HasPrefix(name, "addt-persistent-"),
+		CreatedAt:  stat.ModTime(),
+		Mounts: []provider.VolumeMount{
+			{Source: filepath.Join(envDir(name), "home"), Target: "/home/addt"},
+		},
+	}
+	if pid := readPid(name); pid > 0 {
+		info.ID = fmt.Sprint(pid)
+		info.Status = "running"
+		info.Running = true
+	}
+	return info, nil
+}
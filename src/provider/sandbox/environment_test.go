@@ -0,0 +1,46 @@
+package sandbox
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jedi4ever/addt/provider"
+)
+
+func TestCopy_PersistentHome(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+	name := "addt-persistent-myproject-1a2b3c4d"
+	home := filepath.Join(envDir(name), "home")
+	if err := os.MkdirAll(filepath.Join(home, ".config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	p := &SandboxProvider{}
+
+	src := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(src, []byte("hello"), 0644)
+	if err := p.CopyTo(name, src, "/home/addt/.config"); err != nil {
+		t.Fatalf("CopyTo() error = %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(home, ".config", "notes.txt")); err != nil || string(data) != "hello" {
+		t.Errorf("copied file = %q, %v", data, err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "back.txt")
+	if err := p.CopyFrom(name, "/home/addt/.config/notes.txt", dst); err != nil {
+		t.Fatalf("CopyFrom() error = %v", err)
+	}
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "hello" {
+		t.Errorf("copied back = %q, %v", data, err)
+	}
+
+	for _, envPath := range []string{"/workspace/x", "/home/addtx", "/home/addt/../../etc/passwd"} {
+		if err := p.CopyTo(name, src, envPath); !errors.Is(err, provider.ErrNotSupported) {
+			t.Errorf("CopyTo(%q) error = %v, want ErrNotSupported", envPath, err)
+		}
+	}
+	if err := p.CopyTo("addt-persistent-missing-00000000", src, "/home/addt"); err == nil {
+		t.Error("copy into a missing environment should fail")
+	}
+}
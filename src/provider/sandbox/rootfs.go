@@ -0,0 +1,268 @@
+package sandbox
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/extensions"
+	"github.com/jedi4ever/addt/util"
+)
+
+// The sandbox has no images. Instead each extension set gets a cached rootfs
+// directory under ~/.addt/sandbox/rootfs/<name>/ holding:
+//
+//	opt/   install.sh, sandbox-entrypoint.sh and extensions/ (read-only at /opt/addt)
+//	home/  the addt home directory after install.sh has run (copied per sandbox)
+//
+// The host's /usr, /etc and friends provide the base system, so tools the
+// extensions depend on (node, git, curl) must be installed on the host.
+
+// rootfsDir returns the cached rootfs directory for an image name
+func rootfsDir(imageName string) string {
+	return filepath.Join(sandboxDir(), "rootfs", imageName)
+}
+
+// DetermineImageName returns the cached rootfs name for the configured
+// extensions. Versions and asset hashes are encoded in the name, so any
+// change produces a new rootfs.
+func (p *SandboxProvider) DetermineImageName() string {
+	exts := p.extensionList()
+	if len(exts) == 0 {
+		return fmt.Sprintf("addt-sandbox-v%s_base-%s", p.config.AddtVersion, p.assetsHash())
+	}
+
+	var tagParts []string
+	for _, ext := range exts {
+		tagParts = append(tagParts, fmt.Sprintf("%s-%s", ext, p.getExtensionVersion(ext)))
+	}
+	return fmt.Sprintf("addt-sandbox-v%s_%s-%s", p.config.AddtVersion, strings.Join(tagParts, "_"), p.assetsHash())
+}
+
+// getExtensionVersion returns the configured version for an extension
+func (p *SandboxProvider) getExtensionVersion(extName string) string {
+	if ver, ok := p.config.ExtensionVersions[extName]; ok {
+		return ver
+	}
+	if extName == "claude" {
+		return "stable"
+	}
+	return "latest"
+}
+
+// assetsHash returns a short hash of everything that ends up in the rootfs
+func (p *SandboxProvider) assetsHash() string {
+	h := sha256.New()
+	h.Write(p.embeddedEntrypoint)
+	h.Write(p.embeddedInstallSh)
+	fs.WalkDir(p.embeddedExtensions, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := p.embeddedExtensions.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		h.Write([]byte(path))
+		h.Write(content)
+		return nil
+	})
+	for _, dir := range []string{extensions.GetLocalExtensionsDir(), extensions.GetExtraExtensionsDir()} {
+		if dir == "" {
+			continue
+		}
+		filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			relPath, _ := filepath.Rel(dir, path)
+			h.Write([]byte(relPath))
+			h.Write(content)
+			return nil
+		})
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))[:8]
+}
+
+// BuildIfNeeded prepares the cached rootfs for the configured extensions.
+// There is no separate base layer, so rebuildBase behaves like rebuild.
+func (p *SandboxProvider) BuildIfNeeded(rebuild bool, rebuildBase bool) error {
+	dir := rootfsDir(p.config.ImageName)
+	_, err := os.Stat(dir)
+	exists := err == nil
+	p.logger.Debugf("Checking rootfs: %s (exists: %v)", dir, exists)
+
+	if exists && !rebuild && !rebuildBase {
+		return nil
+	}
+	if exists {
+		fmt.Printf("Rebuilding %s...\n", p.config.ImageName)
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove rootfs: %w", err)
+		}
+	}
+	return p.buildRootfs(dir)
+}
+
+// buildRootfs populates a rootfs directory by running install.sh inside a
+// sandbox that has network access. The result is moved into place only when
+// the install succeeds, so a failed build never leaves a half-populated cache.
+func (p *SandboxProvider) buildRootfs(dir string) error {
+	startTime := time.Now()
+	util.PrintBuildStart(p.config.ImageName)
+
+	tmpDir := dir + ".tmp"
+	os.RemoveAll(tmpDir)
+	optDir := filepath.Join(tmpDir, "opt")
+	homeDir := filepath.Join(tmpDir, "home")
+	for _, d := range []string{filepath.Join(optDir, "extensions"), homeDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return fmt.Errorf("failed to create rootfs: %w", err)
+		}
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := os.WriteFile(filepath.Join(optDir, "install.sh"), p.embeddedInstallSh, 0755); err != nil {
+		return fmt.Errorf("failed to write install.sh: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(optDir, "sandbox-entrypoint.sh"), p.embeddedEntrypoint, 0755); err != nil {
+		return fmt.Errorf("failed to write entrypoint: %w", err)
+	}
+	if err := p.writeExtensions(filepath.Join(optDir, "extensions")); err != nil {
+		return err
+	}
+
+	var versionPairs []string
+	for _, ext := range p.extensionList() {
+		versionPairs = append(versionPairs, fmt.Sprintf("%s:%s", ext, p.getExtensionVersion(ext)))
+	}
+
+	args := buildBwrapArgs(sandboxSpec{
+		Hostname: "addt-build",
+		System:   systemMounts(),
+		Home:     homeDir,
+		Opt:      optDir,
+		ShareNet: true,
+		WorkDir:  "/home/addt",
+		Command:  []string{"bash", "/opt/addt/install.sh", strings.Join(p.extensionList(), ",")},
+	})
+	cmd := exec.Command("bwrap", args...)
+	cmd.Env = append(sandboxEnv(nil),
+		"EXTENSIONS_DIR=/opt/addt/extensions",
+		"METADATA_FILE=/home/addt/.addt/extensions.json",
+		"EXTENSION_VERSIONS="+strings.Join(versionPairs, ","),
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	p.logger.Debugf("Building rootfs: bwrap %v", args)
+	if err := cmd.Run(); err != nil {
+		util.PrintError(fmt.Sprintf("Failed to build rootfs: %v", err))
+		return fmt.Errorf("failed to build sandbox rootfs: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return fmt.Errorf("failed to create rootfs: %w", err)
+	}
+	if err := os.Rename(tmpDir, dir); err != nil {
+		return fmt.Errorf("failed to move rootfs into place: %w", err)
+	}
+
+	util.PrintBuildComplete(p.config.ImageName, time.Since(startTime))
+	return nil
+}
+
+// writeExtensions copies the embedded extensions, then local and extra
+// extension directories on top (replacing same-named embedded ones)
+func (p *SandboxProvider) writeExtensions(destDir string) error {
+	err := fs.WalkDir(p.embeddedExtensions, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == "." {
+			return nil
+		}
+		target := filepath.Join(destDir, path)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		content, err := p.embeddedExtensions.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, 0755)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write extensions: %w", err)
+	}
+
+	for _, srcDir := range []string{extensions.GetLocalExtensionsDir(), extensions.GetExtraExtensionsDir()} {
+		if srcDir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(srcDir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			srcExtDir := filepath.Join(srcDir, entry.Name())
+			if _, err := os.Stat(filepath.Join(srcExtDir, "config.yaml")); err != nil {
+				continue // Skip directories without config.yaml
+			}
+			destExtDir := filepath.Join(destDir, entry.Name())
+			os.RemoveAll(destExtDir)
+			if err := exec.Command("cp", "-a", srcExtDir, destExtDir).Run(); err != nil {
+				return fmt.Errorf("failed to copy extension %s: %w", entry.Name(), err)
+			}
+			fmt.Printf("  Including local extension: %s\n", entry.Name())
+		}
+	}
+	return nil
+}
+
+// extensionMetadata reads extensions.json from the cached rootfs
+func extensionMetadata(imageName string) map[string]extensions.ExtensionMetadata {
+	data, err := os.ReadFile(filepath.Join(rootfsDir(imageName), "home", ".addt", "extensions.json"))
+	if err != nil {
+		return nil
+	}
+	var config extensions.ExtensionsJSONConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil
+	}
+	return config.Extensions
+}
+
+// GetExtensionEnvVars returns all unique environment variables needed by
+// installed extensions, including their OTEL variables
+func (p *SandboxProvider) GetExtensionEnvVars(imageName string) []string {
+	envVarSet := make(map[string]bool)
+	for _, ext := range extensionMetadata(imageName) {
+		for _, envVar := range ext.EnvVars {
+			envVarSet[envVar] = true
+		}
+		for _, otelVar := range ext.OtelVars {
+			envVarSet[otelVar] = true
+		}
+	}
+
+	var envVars []string
+	for envVar := range envVarSet {
+		envVars = append(envVars, envVar)
+	}
+	sort.Strings(envVars)
+	return envVars
+}
@@ -0,0 +1,457 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/util"
+)
+
+const entrypointPath = "/opt/addt/sandbox-entrypoint.sh"
+
+// Run runs the extension entrypoint in a new sandbox
+func (p *SandboxProvider) Run(spec *provider.RunSpec) error {
+	return p.run(spec)
+}
+
+// Shell opens a bash shell in a new sandbox
+func (p *SandboxProvider) Shell(spec *provider.RunSpec) error {
+	fmt.Println("Opening bash shell in sandbox...")
+	if spec.Env == nil {
+		spec.Env = make(map[string]string)
+	}
+	// The entrypoint still runs, so secrets and extension setup work
+	spec.Env["ADDT_COMMAND"] = "/bin/bash"
+	return p.run(spec)
+}
+
+// run prepares the home directory, mounts and limits for spec, then runs
+// the entrypoint under bwrap attached to the terminal
+func (p *SandboxProvider) run(spec *provider.RunSpec) error {
+	sec := p.config.Security
+
+	// There is no network namespace to put iptables rules in, so rather than
+	// silently running unfiltered, refuse unless the network is cut off
+	if p.config.FirewallEnabled && sec.NetworkMode != "none" {
+		return fmt.Errorf("the sandbox provider cannot enforce firewall rules; set security.network_mode: none or use a container provider")
+	}
+
+	rootfs := rootfsDir(spec.ImageName)
+	if _, err := os.Stat(rootfs); err != nil {
+		return fmt.Errorf("sandbox rootfs %s not found (run addt build)", spec.ImageName)
+	}
+
+	homeDir, cleanup, err := p.prepareHome(spec, rootfs)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	currentUser, err := user.Current()
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+
+	env := make(map[string]string, len(spec.Env))
+	for k, v := range spec.Env {
+		env[k] = v
+	}
+
+	// Secrets travel through a pipe into a tmpfs file, never as env vars
+	var extraFiles []*os.File
+	secretsFD := 0
+	if sec.IsolateSecrets {
+		secretsJSON, err := p.prepareSecretsJSON(spec.ImageName, env)
+		if err != nil {
+			return err
+		}
+		if secretsJSON != nil {
+			r, err := pipeData(secretsJSON)
+			if err != nil {
+				return fmt.Errorf("failed to pass secrets: %w", err)
+			}
+			defer r.Close()
+			extraFiles = append(extraFiles, r)
+			secretsFD = 2 + len(extraFiles)
+		}
+	}
+
+	seccompFD := 0
+	filter, err := loadSeccompFilter(sec.SeccompProfile, runtime.GOARCH)
+	if err != nil {
+		return err
+	}
+	if filter != nil {
+		r, err := pipeData(filter)
+		if err != nil {
+			return fmt.Errorf("failed to pass seccomp filter: %w", err)
+		}
+		defer r.Close()
+		extraFiles = append(extraFiles, r)
+		seccompFD = 2 + len(extraFiles)
+	}
+
+	volumes := append([]provider.VolumeMount{}, spec.Volumes...)
+	volumes = append(volumes, p.extensionMounts(spec.ImageName, currentUser.HomeDir)...)
+	volumes = append(volumes, p.gitconfigMount(currentUser.HomeDir)...)
+	sshVolumes, sshEnv := p.sshForwarding(spec, currentUser.HomeDir)
+	volumes = append(volumes, sshVolumes...)
+	for k, v := range sshEnv {
+		env[k] = v
+	}
+
+	p.warnUnsupported(spec)
+
+	// The sandbox shares the host network, so services listen on the same
+	// port on both sides
+	if _, ok := env["ADDT_PORT_MAP"]; ok && len(spec.Ports) > 0 {
+		var mappings []string
+		for _, port := range spec.Ports {
+			mappings = append(mappings, fmt.Sprintf("%d:%d", port.Container, port.Container))
+		}
+		env["ADDT_PORT_MAP"] = strings.Join(mappings, ",")
+	}
+
+	// Ulimits and the time limit are enforced by the entrypoint
+	if sec.UlimitNofile != "" {
+		env["ADDT_ULIMIT_NOFILE"] = ulimitPair(sec.UlimitNofile)
+	}
+	if sec.UlimitNproc != "" {
+		env["ADDT_ULIMIT_NPROC"] = ulimitPair(sec.UlimitNproc)
+	}
+	if sec.TimeLimit > 0 {
+		env["ADDT_TIME_LIMIT_SECONDS"] = strconv.Itoa(sec.TimeLimit * 60)
+	}
+
+	args := buildBwrapArgs(sandboxSpec{
+		Hostname:   spec.Name,
+		System:     systemMounts(),
+		Home:       homeDir,
+		Opt:        filepath.Join(rootfs, "opt"),
+		Volumes:    volumes,
+		WorkDir:    spec.WorkDir,
+		ShareNet:   sec.NetworkMode != "none",
+		ReadOnly:   sec.ReadOnlyRootfs,
+		NewSession: !spec.Interactive,
+		SecretsFD:  secretsFD,
+		SeccompFD:  seccompFD,
+		Command:    append([]string{entrypointPath}, spec.Args...),
+	})
+
+	command := append(p.limitPrefix(spec), "bwrap")
+	cmd := exec.Command(command[0], append(command[1:], args...)...)
+	cmd.Env = sandboxEnv(env)
+	cmd.ExtraFiles = extraFiles
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	p.logger.Debugf("Executing: %v", cmd.Args)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start sandbox: %w", err)
+	}
+	if spec.Persistent && !p.IsRunning(spec.Name) {
+		pidFile := filepath.Join(envDir(spec.Name), "pid")
+		os.WriteFile(pidFile, []byte(strconv.Itoa(cmd.Process.Pid)), 0600)
+		defer os.Remove(pidFile)
+	}
+	return cmd.Wait()
+}
+
+// prepareHome returns the directory to bind at /home/addt. Persistent
+// environments keep theirs between runs; ephemeral ones get a throwaway copy.
+func (p *SandboxProvider) prepareHome(spec *provider.RunSpec, rootfs string) (string, func(), error) {
+	src := filepath.Join(rootfs, "home")
+
+	if spec.Persistent {
+		home := filepath.Join(envDir(spec.Name), "home")
+		if _, err := os.Stat(home); err == nil {
+			fmt.Printf("Found existing persistent sandbox: %s\n", spec.Name)
+			return home, func() {}, nil
+		}
+		fmt.Printf("Creating new persistent sandbox: %s\n", spec.Name)
+		if err := copyTree(src, home); err != nil {
+			os.RemoveAll(envDir(spec.Name))
+			return "", nil, err
+		}
+		return home, func() {}, nil
+	}
+
+	runDir := filepath.Join(sandboxDir(), "run", spec.Name)
+	cleanup := func() { os.RemoveAll(runDir) }
+	home := filepath.Join(runDir, "home")
+	if err := copyTree(src, home); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return home, cleanup, nil
+}
+
+// copyTree copies a directory tree, sharing extents where the filesystem
+// supports it
+func copyTree(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(dst), err)
+	}
+	if output, err := exec.Command("cp", "-a", "--reflink=auto", src, dst).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to copy sandbox home: %w\n%s", err, string(output))
+	}
+	return nil
+}
+
+// pipeData returns the read end of a pipe that yields data then EOF
+func pipeData(data []byte) (*os.File, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		w.Write(data)
+		w.Close()
+	}()
+	return r, nil
+}
+
+// prepareSecretsJSON moves extension secrets out of env into a JSON
+// document for the entrypoint. Returns nil when there is nothing to pass.
+func (p *SandboxProvider) prepareSecretsJSON(imageName string, env map[string]string) ([]byte, error) {
+	names := p.GetExtensionEnvVars(imageName)
+	if credVars := env["ADDT_CREDENTIAL_VARS"]; credVars != "" {
+		for _, v := range strings.Split(credVars, ",") {
+			names = append(names, strings.TrimSpace(v))
+		}
+	}
+
+	secrets := make(map[string]string)
+	for _, name := range names {
+		if value := env[name]; value != "" {
+			secrets[name] = value
+			delete(env, name)
+		}
+	}
+	if len(secrets) == 0 {
+		return nil, nil
+	}
+	// ADDT_CREDENTIAL_VARS is no longer needed — secrets are in the file
+	delete(env, "ADDT_CREDENTIAL_VARS")
+
+	data, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+	return data, nil
+}
+
+// ulimitPair normalizes a ulimit setting to soft:hard
+func ulimitPair(value string) string {
+	if strings.Contains(value, ":") {
+		return value
+	}
+	return value + ":" + value
+}
+
+// limitPrefix returns the command prefix that places the sandbox in a
+// transient systemd scope enforcing the pids, memory and CPU limits. bwrap
+// cannot set cgroup limits itself; without a systemd user session the
+// limits are reported as not enforced.
+func (p *SandboxProvider) limitPrefix(spec *provider.RunSpec) []string {
+	props := limitProperties(p.config.Security.PidsLimit, spec.ContainerMemory, spec.ContainerCPUs)
+	if len(props) == 0 {
+		return nil
+	}
+	if _, err := exec.LookPath("systemd-run"); err != nil || exec.Command("systemd-run", "--user", "--scope", "--quiet", "true").Run() != nil {
+		fmt.Fprintln(os.Stderr, "Warning: systemd user session not available, sandbox pids/memory/cpu limits are not enforced")
+		return nil
+	}
+
+	prefix := []string{"systemd-run", "--user", "--scope", "--quiet"}
+	for _, prop := range props {
+		prefix = append(prefix, "-p", prop)
+	}
+	return append(prefix, "--")
+}
+
+// limitProperties translates container resource limits to systemd unit
+// properties
+func limitProperties(pids int, memory, cpus string) []string {
+	var props []string
+	if pids > 0 {
+		props = append(props, fmt.Sprintf("TasksMax=%d", pids))
+	}
+	if memory != "" {
+		// Docker accepts 512m / 2g / 4gb; systemd wants 512M / 2G
+		m := strings.ToUpper(strings.TrimSuffix(strings.ToLower(memory), "b"))
+		props = append(props, "MemoryMax="+m)
+	}
+	if cpus != "" {
+		if n, err := strconv.ParseFloat(cpus, 64); err == nil && n > 0 {
+			props = append(props, fmt.Sprintf("CPUQuota=%d%%", int(n*100)))
+		}
+	}
+	return props
+}
+
+// gitconfigMount mounts the host .gitconfig where the entrypoint copies it from
+func (p *SandboxProvider) gitconfigMount(homeDir string) []provider.VolumeMount {
+	if !p.config.GitForwardConfig {
+		return nil
+	}
+	gitconfigPath := p.config.GitConfigPath
+	if gitconfigPath == "" {
+		gitconfigPath = filepath.Join(homeDir, ".gitconfig")
+	} else {
+		gitconfigPath = util.ExpandTilde(gitconfigPath)
+	}
+	if _, err := os.Stat(gitconfigPath); err != nil {
+		return nil
+	}
+	return []provider.VolumeMount{{Source: gitconfigPath, Target: "/home/addt/.gitconfig.host", ReadOnly: true}}
+}
+
+// sshForwarding returns mounts and env for SSH forwarding. Sockets can be
+// bound directly, so proxy mode runs the filtering proxy on a Unix socket.
+func (p *SandboxProvider) sshForwarding(spec *provider.RunSpec, homeDir string) ([]provider.VolumeMount, map[string]string) {
+	if !spec.SSHForwardKeys {
+		return nil, nil
+	}
+
+	sshDir := p.config.SSHDir
+	if sshDir == "" {
+		sshDir = filepath.Join(homeDir, ".ssh")
+	} else {
+		sshDir = util.ExpandTilde(sshDir)
+	}
+
+	if spec.SSHForwardMode == "keys" {
+		return []provider.VolumeMount{{Source: sshDir, Target: "/home/addt/.ssh", ReadOnly: true}}, nil
+	}
+	if spec.SSHForwardMode != "agent" && spec.SSHForwardMode != "proxy" {
+		return nil, nil
+	}
+
+	sshAuthSock := os.Getenv("SSH_AUTH_SOCK")
+	if sshAuthSock == "" {
+		fmt.Println("Warning: SSH_AUTH_SOCK not set, cannot forward SSH agent")
+		return nil, nil
+	}
+
+	socket := sshAuthSock
+	confirm := p.config.SSHSignPolicy == "confirm"
+	hostRules, err := security.ParseSSHHostRules(p.config.SSHAllowedHosts)
+	if err != nil {
+		fmt.Printf("Warning: %v, not forwarding SSH agent\n", err)
+		return nil, nil
+	}
+	if spec.SSHForwardMode == "proxy" || len(spec.SSHAllowedKeys) > 0 || confirm || len(hostRules) > 0 {
+		proxy, err := security.NewSSHProxyAgent(sshAuthSock, spec.SSHAllowedKeys)
+		if err != nil {
+			fmt.Printf("Warning: failed to create SSH proxy: %v\n", err)
+			return nil, nil
+		}
+		if err := proxy.Start(); err != nil {
+			fmt.Printf("Warning: failed to start SSH proxy: %v\n", err)
+			return nil, nil
+		}
+		p.sshProxy = proxy
+		socket = proxy.SocketPath()
+		if len(hostRules) > 0 {
+			proxy.SetAllowedHosts(hostRules, filepath.Join(sshDir, "known_hosts"))
+			fmt.Printf("SSH proxy: logins limited to %v\n", p.config.SSHAllowedHosts)
+		}
+		if confirm {
+			approver, err := security.NewSignApprover(spec.Name, p.config.Security.SignPrompt, time.Duration(p.config.Security.SignGrant)*time.Minute)
+			if err != nil {
+				// Not forwarding beats signing without asking
+				fmt.Printf("Warning: %v, not forwarding SSH agent\n", err)
+				return nil, nil
+			}
+			if err := approver.Ready(); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+			proxy.SetApprover(approver, filepath.Join(sshDir, "known_hosts"))
+			fmt.Printf("SSH proxy: each signature is confirmed at a %s prompt\n", approver.Method())
+		}
+		if len(spec.SSHAllowedKeys) > 0 {
+			fmt.Printf("SSH proxy active: only keys matching %v are accessible\n", spec.SSHAllowedKeys)
+		}
+	}
+
+	volumes := []provider.VolumeMount{{Source: socket, Target: "/ssh-agent"}}
+	// Public material only, so hosts can be verified without exposing keys
+	for _, name := range []string{"known_hosts", "config"} {
+		if path := filepath.Join(sshDir, name); fileExists(path) {
+			volumes = append(volumes, provider.VolumeMount{Source: path, Target: "/home/addt/.ssh/" + name, ReadOnly: true})
+		}
+	}
+	return volumes, map[string]string{"SSH_AUTH_SOCK": "/ssh-agent"}
+}
+
+// warnUnsupported reports requested features the sandbox cannot provide
+func (p *SandboxProvider) warnUnsupported(spec *provider.RunSpec) {
+	switch spec.DockerDindMode {
+	case "isolated", "true", "host":
+		fmt.Println("Warning: Docker-in-Docker is not available in the sandbox provider")
+	}
+	if spec.GPGForward != "" && spec.GPGForward != "off" && spec.GPGForward != "false" {
+		fmt.Println("Warning: GPG forwarding is not available in the sandbox provider")
+	}
+	if spec.TmuxForward || spec.HistoryPersist {
+		p.logger.Debug("Tmux forwarding and history persistence are not available in the sandbox provider")
+	}
+}
+
+// extensionMounts returns the config mounts of installed extensions, using
+// the same automount/readonly precedence as the container providers
+func (p *SandboxProvider) extensionMounts(imageName, homeDir string) []provider.VolumeMount {
+	var mounts []provider.VolumeMount
+	for extName, ext := range extensionMetadata(imageName) {
+		if ext.Config == nil {
+			continue
+		}
+
+		// Default is false - extensions must explicitly set mounts.automount: true
+		autoMount := ext.Config.Automount != nil && *ext.Config.Automount
+		if enabled, exists := p.config.ExtensionConfigAutomount[extName]; exists {
+			autoMount = enabled
+		}
+		if !autoMount {
+			continue
+		}
+
+		// Precedence: per-extension user config > global config > extension default
+		readonly := (ext.Config.Readonly != nil && *ext.Config.Readonly) || p.config.ConfigReadonly
+		if ro, exists := p.config.ExtensionConfigReadonly[extName]; exists {
+			readonly = ro
+		}
+
+		for _, m := range ext.Config.Mounts {
+			source := m.Source
+			if strings.HasPrefix(source, "~/") {
+				source = filepath.Join(homeDir, source[2:])
+			}
+			if !fileExists(source) {
+				// Create missing directories, skip missing files (e.g. ~/.claude.json on fresh install)
+				if strings.Contains(filepath.Base(source), ".") || os.MkdirAll(source, 0755) != nil {
+					continue
+				}
+			}
+			mounts = append(mounts, provider.VolumeMount{Source: source, Target: m.Target, ReadOnly: readonly})
+		}
+	}
+	return mounts
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
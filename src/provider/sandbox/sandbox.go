@@ -0,0 +1,278 @@
+// Package sandbox runs extensions in a bubblewrap (bwrap) process sandbox
+// instead of a container. It is meant for CI runners and locked-down VMs
+// where no container runtime is available but unprivileged user namespaces
+// are.
+package sandbox
+
+import (
+	"crypto/md5"
+	"embed"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jedi4ever/addt/config/security"
+	"github.com/jedi4ever/addt/provider"
+	"github.com/jedi4ever/addt/util"
+)
+
+// SandboxProvider implements the Provider interface with bubblewrap
+type SandboxProvider struct {
+	config             *provider.Config
+	embeddedEntrypoint []byte
+	embeddedInstallSh  []byte
+	embeddedExtensions embed.FS
+	logger             *util.ModuleLogger
+	sshProxy           *security.SSHProxyAgent
+}
+
+// NewSandboxProvider creates a new bubblewrap sandbox provider
+func NewSandboxProvider(cfg *provider.Config, entrypoint, installSh []byte, extensions embed.FS) (provider.Provider, error) {
+	return &SandboxProvider{
+		config:             cfg,
+		embeddedEntrypoint: entrypoint,
+		embeddedInstallSh:  installSh,
+		embeddedExtensions: extensions,
+		logger:             util.Log("sandbox"),
+	}, nil
+}
+
+// Initialize initializes the sandbox provider
+func (p *SandboxProvider) Initialize(cfg *provider.Config) error {
+	p.config = cfg
+	return p.CheckPrerequisites()
+}
+
+// GetName returns the provider name
+func (p *SandboxProvider) GetName() string {
+	return "sandbox"
+}
+
+// CheckPrerequisites verifies bwrap is installed and can create user namespaces
+func (p *SandboxProvider) CheckPrerequisites() error {
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("the sandbox provider requires Linux (bubblewrap uses Linux namespaces)")
+	}
+	if _, err := exec.LookPath("bwrap"); err != nil {
+		return fmt.Errorf("bubblewrap is not installed. Please install it (e.g. apt install bubblewrap)")
+	}
+	if output, err := exec.Command("bwrap", "--unshare-user", "--ro-bind", "/", "/", "true").CombinedOutput(); err != nil {
+		return fmt.Errorf("bwrap cannot create a user namespace (check kernel.unprivileged_userns_clone / apparmor_restrict_unprivileged_userns): %s",
+			strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Cleanup stops the SSH proxy if one was started
+func (p *SandboxProvider) Cleanup() error {
+	if p.sshProxy != nil {
+		p.sshProxy.Stop()
+		p.sshProxy = nil
+	}
+	return nil
+}
+
+// sandboxDir returns the root of the provider's state (~/.addt/sandbox)
+func sandboxDir() string {
+	return filepath.Join(util.GetAddtHome(), "sandbox")
+}
+
+// envDir returns the state directory of a persistent environment
+func envDir(name string) string {
+	return filepath.Join(sandboxDir(), "envs", name)
+}
+
+// Exists checks if a persistent environment exists
+func (p *SandboxProvider) Exists(name string) bool {
+	info, err := os.Stat(envDir(name))
+	return err == nil && info.IsDir()
+}
+
+// IsRunning checks if a sandbox process is alive for the environment
+func (p *SandboxProvider) IsRunning(name string) bool {
+	return readPid(name) > 0
+}
+
+// readPid returns the pid of the environment's running sandbox, or 0
+func readPid(name string) int {
+	data, err := os.ReadFile(filepath.Join(envDir(name), "pid"))
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0
+	}
+	if syscall.Kill(pid, 0) != nil {
+		return 0
+	}
+	return pid
+}
+
+// Start is a no-op: sandboxes start with each run
+func (p *SandboxProvider) Start(name string) error {
+	return nil
+}
+
+// Stop terminates the environment's running sandbox, if any
+func (p *SandboxProvider) Stop(name string) error {
+	pid := readPid(name)
+	if pid == 0 {
+		return nil
+	}
+	// The pid is bwrap's; --die-with-parent takes the sandbox down with it
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
+
+// Remove removes a persistent environment and its home directory
+func (p *SandboxProvider) Remove(name string) error {
+	if err := p.Stop(name); err != nil {
+		return err
+	}
+	return os.RemoveAll(envDir(name))
+}
+
+// List lists all persistent sandbox environments
+func (p *SandboxProvider) List() ([]provider.Environment, error) {
+	entries, err := os.ReadDir(filepath.Join(sandboxDir(), "envs"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var envs []provider.Environment
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "addt-persistent-") {
+			continue
+		}
+		status := "stopped"
+		if p.IsRunning(entry.Name()) {
+			status = "running"
+		}
+		createdAt := ""
+		if info, err := entry.Info(); err == nil {
+			createdAt = info.ModTime().Format("2006-01-02 15:04:05")
+		}
+		envs = append(envs, provider.Environment{
+			Name:      entry.Name(),
+			Status:    status,
+			CreatedAt: createdAt,
+		})
+	}
+	return envs, nil
+}
+
+// GeneratePersistentName generates a persistent environment name based on
+// working directory and extensions
+func (p *SandboxProvider) GeneratePersistentName() string {
+	workdir := p.config.Workdir
+	if workdir == "" {
+		var err error
+		workdir, err = os.Getwd()
+		if err != nil {
+			workdir = "/tmp"
+		}
+	}
+
+	// Sanitize directory name (lowercase, remove special chars, max 20 chars)
+	dirname := filepath.Base(workdir)
+	re := regexp.MustCompile(`[^a-z0-9-]+`)
+	dirname = strings.Trim(re.ReplaceAllString(strings.ToLower(dirname), "-"), "-")
+	if len(dirname) > 20 {
+		dirname = dirname[:20]
+	}
+
+	// Same workdir + same extensions = same environment; named worktree
+	// sessions each get their own
+	hashInput := workdir + "|" + strings.Join(p.extensionList(), ",")
+	if p.config.WorkdirWorktree && p.config.WorkdirWorktreeName != "" {
+		hashInput += "|worktree:" + p.config.WorkdirWorktreeName
+	}
+	hash := md5.Sum([]byte(hashInput))
+	return fmt.Sprintf("addt-persistent-%s-%x", dirname, hash[:4])
+}
+
+// GenerateEphemeralName generates a unique ephemeral sandbox name
+func (p *SandboxProvider) GenerateEphemeralName() string {
+	return fmt.Sprintf("addt-%s-%d", time.Now().Format("20060102-150405"), os.Getpid())
+}
+
+// extensionList returns the configured extensions, trimmed and sorted
+func (p *SandboxProvider) extensionList() []string {
+	var exts []string
+	for _, ext := range strings.Split(p.config.Extensions, ",") {
+		if ext = strings.TrimSpace(ext); ext != "" {
+			exts = append(exts, ext)
+		}
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// GetStatus returns a status string for display
+func (p *SandboxProvider) GetStatus(cfg *provider.Config, envName string) string {
+	parts := []string{p.GetName()}
+
+	var resources []string
+	if cfg.ContainerCPUs != "" {
+		resources = append(resources, fmt.Sprintf("cpu:%s", cfg.ContainerCPUs))
+	}
+	if cfg.ContainerMemory != "" {
+		resources = append(resources, fmt.Sprintf("mem:%s", cfg.ContainerMemory))
+	}
+	if len(resources) > 0 {
+		parts = append(parts, strings.Join(resources, " "))
+	}
+
+	if output, err := exec.Command("node", "--version").Output(); err == nil {
+		parts = append(parts, "Node "+strings.TrimPrefix(strings.TrimSpace(string(output)), "v"))
+	}
+
+	workdir := cfg.Workdir
+	if workdir == "" {
+		workdir, _ = os.Getwd()
+	}
+	if cfg.WorkdirAutomount {
+		if cfg.WorkdirReadonly {
+			parts = append(parts, fmt.Sprintf("%s [RO]", workdir))
+		} else if cfg.WorkdirWorktree {
+			parts = append(parts, fmt.Sprintf("%s [worktree]", workdir))
+		} else if cfg.WorkdirOverlay {
+			parts = append(parts, fmt.Sprintf("%s [overlay]", workdir))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s [RW]", workdir))
+		}
+	} else {
+		parts = append(parts, "[not mounted]")
+	}
+
+	if os.Getenv("GH_TOKEN") != "" {
+		parts = append(parts, "GH")
+	}
+	if cfg.SSHForwardKeys {
+		parts = append(parts, fmt.Sprintf("SSH:%s", cfg.SSHForwardMode))
+	}
+
+	// The sandbox shares the host network unless isolated
+	if cfg.Security.NetworkMode == "none" {
+		parts = append(parts, "net:none")
+	} else {
+		parts = append(parts, "net:host")
+	}
+
+	if cfg.Persistent {
+		parts = append(parts, "Persistent")
+	}
+
+	return strings.Join(parts, " | ")
+}
@@ -0,0 +1,319 @@
+package sandbox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jedi4ever/addt/assets"
+)
+
+// bwrap only accepts a compiled classic BPF program for --seccomp, so the
+// Docker-format JSON profiles used by the container providers are compiled
+// here. The program checks the architecture, then walks the rules in order;
+// anything that falls through gets the profile's default action.
+
+// seccompProfile is the subset of the Docker/OCI seccomp profile format
+// that can be expressed without libseccomp
+type seccompProfile struct {
+	DefaultAction   string        `json:"defaultAction"`
+	DefaultErrnoRet *uint32       `json:"defaultErrnoRet"`
+	Architectures   []string      `json:"architectures"`
+	ArchMap         []seccompArch `json:"archMap"`
+	Syscalls        []seccompRule `json:"syscalls"`
+}
+
+type seccompArch struct {
+	Architecture     string   `json:"architecture"`
+	SubArchitectures []string `json:"subArchitectures"`
+}
+
+type seccompRule struct {
+	Names    []string      `json:"names"`
+	Name     string        `json:"name"`
+	Action   string        `json:"action"`
+	ErrnoRet *uint32       `json:"errnoRet"`
+	Args     []seccompArg  `json:"args"`
+	Includes seccompFilter `json:"includes"`
+	Excludes seccompFilter `json:"excludes"`
+}
+
+type seccompArg struct {
+	Index    uint   `json:"index"`
+	Value    uint64 `json:"value"`
+	ValueTwo uint64 `json:"valueTwo"`
+	Op       string `json:"op"`
+}
+
+type seccompFilter struct {
+	Arches []string `json:"arches"`
+	Caps   []string `json:"caps"`
+}
+
+// seccompTarget describes one architecture the compiler can emit code for
+type seccompTarget struct {
+	// name is the SCMP_ARCH_* name used by profiles
+	name string
+	// arches are the short names used in includes/excludes
+	arches []string
+	// audit is the AUDIT_ARCH_* value the kernel reports in seccomp_data
+	audit    uint32
+	syscalls map[string]uint32
+	// x32 rejects the x32 ABI, which shares the x86_64 audit arch
+	x32 bool
+}
+
+var seccompTargets = map[string]seccompTarget{
+	"amd64": {name: "SCMP_ARCH_X86_64", arches: []string{"amd64", "x86_64"}, audit: 0xc000003e, syscalls: syscallsX86_64, x32: true},
+	"arm64": {name: "SCMP_ARCH_AARCH64", arches: []string{"arm64", "aarch64"}, audit: 0xc00000b7, syscalls: syscallsAarch64},
+}
+
+// Classic BPF opcodes and seccomp return values
+const (
+	bpfLdAbs = 0x20 // BPF_LD | BPF_W | BPF_ABS
+	bpfJeq   = 0x15 // BPF_JMP | BPF_JEQ | BPF_K
+	bpfJge   = 0x35 // BPF_JMP | BPF_JGE | BPF_K
+	bpfAnd   = 0x54 // BPF_ALU | BPF_AND | BPF_K
+	bpfRet   = 0x06 // BPF_RET | BPF_K
+
+	retKillProcess = 0x80000000
+	retKillThread  = 0x00000000
+	retTrap        = 0x00030000
+	retErrno       = 0x00050000
+	retLog         = 0x7ffc0000
+	retAllow       = 0x7fff0000
+
+	// Offsets into struct seccomp_data
+	offNr   = 0
+	offArch = 4
+	offArgs = 16
+
+	x32SyscallBit = 0x40000000
+	defaultErrno  = 1 // EPERM
+)
+
+type bpfInsn struct {
+	Code uint16
+	Jt   uint8
+	Jf   uint8
+	K    uint32
+}
+
+// loadSeccompFilter returns the compiled filter for the security.seccomp_profile
+// setting, or nil when the sandbox should run unconfined. Without a runtime to
+// supply a default profile, "" and "default" use the embedded restrictive one.
+func loadSeccompFilter(profile, goarch string) ([]byte, error) {
+	var data []byte
+	switch profile {
+	case "unconfined":
+		return nil, nil
+	case "", "default", "restrictive":
+		data = assets.SeccompRestrictive
+	default:
+		var err error
+		data, err = os.ReadFile(profile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read seccomp profile: %w", err)
+		}
+	}
+	return compileSeccomp(data, goarch)
+}
+
+// compileSeccomp compiles a JSON seccomp profile into a BPF program for goarch,
+// serialized as struct sock_filter entries
+func compileSeccomp(profileJSON []byte, goarch string) ([]byte, error) {
+	target, ok := seccompTargets[goarch]
+	if !ok {
+		return nil, fmt.Errorf("seccomp profiles are not supported on %s", goarch)
+	}
+
+	var profile seccompProfile
+	if err := json.Unmarshal(profileJSON, &profile); err != nil {
+		return nil, fmt.Errorf("invalid seccomp profile: %w", err)
+	}
+	if !profile.supports(target.name) {
+		return nil, fmt.Errorf("seccomp profile does not cover %s", target.name)
+	}
+
+	defaultRet, err := seccompAction(profile.DefaultAction, profile.DefaultErrnoRet)
+	if err != nil {
+		return nil, err
+	}
+
+	prog := []bpfInsn{
+		{Code: bpfLdAbs, K: offArch},
+		{Code: bpfJeq, Jt: 1, K: target.audit},
+		{Code: bpfRet, K: retKillProcess},
+		{Code: bpfLdAbs, K: offNr},
+	}
+	if target.x32 {
+		prog = append(prog,
+			bpfInsn{Code: bpfJge, Jf: 1, K: x32SyscallBit},
+			bpfInsn{Code: bpfRet, K: defaultRet},
+		)
+	}
+
+	for _, rule := range profile.Syscalls {
+		if !rule.Includes.matches(target, true) || rule.Excludes.matches(target, false) {
+			continue
+		}
+		ret, err := seccompAction(rule.Action, rule.ErrnoRet)
+		if err != nil {
+			return nil, err
+		}
+		names := rule.Names
+		if rule.Name != "" {
+			names = append(names, rule.Name)
+		}
+		for _, name := range names {
+			nr, ok := target.syscalls[name]
+			if !ok {
+				// Syscalls that don't exist on this architecture can't be called
+				continue
+			}
+			block, err := ruleBlock(nr, rule.Args, ret)
+			if err != nil {
+				return nil, fmt.Errorf("syscall %s: %w", name, err)
+			}
+			prog = append(prog, block...)
+		}
+	}
+
+	prog = append(prog, bpfInsn{Code: bpfRet, K: defaultRet})
+	if len(prog) > 4096 {
+		return nil, fmt.Errorf("seccomp profile compiles to %d instructions (limit 4096)", len(prog))
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, prog)
+	return buf.Bytes(), nil
+}
+
+// ruleBlock emits the instructions for one syscall rule. The accumulator
+// holds the syscall number on entry and on every exit that falls through.
+func ruleBlock(nr uint32, args []seccompArg, ret uint32) ([]bpfInsn, error) {
+	if len(args) == 0 {
+		return []bpfInsn{
+			{Code: bpfJeq, Jf: 1, K: nr},
+			{Code: bpfRet, K: ret},
+		}, nil
+	}
+
+	// Layout: match nr, check each argument (jumping to the reload on any
+	// mismatch), return the action, then reload nr for the next rule.
+	var checks []bpfInsn
+	for _, arg := range args {
+		if arg.Index > 5 {
+			return nil, fmt.Errorf("argument index %d out of range", arg.Index)
+		}
+		off := uint32(offArgs + 8*arg.Index)
+		var mask, want uint64
+		switch arg.Op {
+		case "SCMP_CMP_EQ":
+			mask, want = ^uint64(0), arg.Value
+		case "SCMP_CMP_MASKED_EQ":
+			mask, want = arg.Value, arg.ValueTwo
+		default:
+			return nil, fmt.Errorf("unsupported comparison %s", arg.Op)
+		}
+		// Little-endian: low word first, high word at +4
+		for _, word := range []struct {
+			off        uint32
+			mask, want uint32
+		}{
+			{off, uint32(mask), uint32(want)},
+			{off + 4, uint32(mask >> 32), uint32(want >> 32)},
+		} {
+			checks = append(checks, bpfInsn{Code: bpfLdAbs, K: word.off})
+			if word.mask != ^uint32(0) {
+				checks = append(checks, bpfInsn{Code: bpfAnd, K: word.mask})
+			}
+			// Jf is patched below once the block length is known
+			checks = append(checks, bpfInsn{Code: bpfJeq, K: word.want})
+		}
+	}
+
+	// checks + ret + reload
+	blockLen := 1 + len(checks) + 2
+	if blockLen > 255 {
+		return nil, fmt.Errorf("too many argument checks")
+	}
+	block := make([]bpfInsn, 0, blockLen)
+	block = append(block, bpfInsn{Code: bpfJeq, Jf: uint8(len(checks) + 1), K: nr})
+	for i, insn := range checks {
+		if insn.Code == bpfJeq {
+			// Skip to the reload: remaining checks plus the ret
+			insn.Jf = uint8(len(checks) - i)
+		}
+		block = append(block, insn)
+	}
+	block = append(block,
+		bpfInsn{Code: bpfRet, K: ret},
+		bpfInsn{Code: bpfLdAbs, K: offNr},
+	)
+	return block, nil
+}
+
+// seccompAction maps a profile action to a seccomp return value
+func seccompAction(action string, errnoRet *uint32) (uint32, error) {
+	switch action {
+	case "SCMP_ACT_ALLOW":
+		return retAllow, nil
+	case "SCMP_ACT_ERRNO":
+		errno := uint32(defaultErrno)
+		if errnoRet != nil {
+			errno = *errnoRet
+		}
+		return retErrno | (errno & 0xffff), nil
+	case "SCMP_ACT_KILL", "SCMP_ACT_KILL_THREAD":
+		return retKillThread, nil
+	case "SCMP_ACT_KILL_PROCESS":
+		return retKillProcess, nil
+	case "SCMP_ACT_TRAP":
+		return retTrap, nil
+	case "SCMP_ACT_LOG":
+		return retLog, nil
+	default:
+		return 0, fmt.Errorf("unsupported seccomp action %q", action)
+	}
+}
+
+// supports reports whether the profile lists arch (an empty list means any)
+func (p *seccompProfile) supports(arch string) bool {
+	if len(p.Architectures) == 0 && len(p.ArchMap) == 0 {
+		return true
+	}
+	for _, a := range p.Architectures {
+		if a == arch {
+			return true
+		}
+	}
+	for _, m := range p.ArchMap {
+		if m.Architecture == arch {
+			return true
+		}
+	}
+	return false
+}
+
+// matches evaluates an includes/excludes filter. The sandbox holds no
+// capabilities, so a capability condition never holds. An empty filter
+// yields empty.
+func (f seccompFilter) matches(target seccompTarget, empty bool) bool {
+	if len(f.Arches) == 0 && len(f.Caps) == 0 {
+		return empty
+	}
+	if len(f.Caps) > 0 {
+		return false
+	}
+	for _, a := range f.Arches {
+		for _, t := range target.arches {
+			if a == t {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package sandbox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/jedi4ever/addt/assets"
+)
+
+// runFilter interprets a compiled program against one syscall, returning the
+// seccomp action
+func runFilter(t *testing.T, prog []byte, audit, nr uint32, args [6]uint64) uint32 {
+	t.Helper()
+	insns := make([]bpfInsn, len(prog)/8)
+	if err := binary.Read(bytes.NewReader(prog), binary.LittleEndian, insns); err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 64)
+	binary.LittleEndian.PutUint32(data[offNr:], nr)
+	binary.LittleEndian.PutUint32(data[offArch:], audit)
+	for i, a := range args {
+		binary.LittleEndian.PutUint64(data[offArgs+8*i:], a)
+	}
+
+	var acc uint32
+	for pc := 0; pc < len(insns); pc++ {
+		in := insns[pc]
+		switch in.Code {
+		case bpfLdAbs:
+			acc = binary.LittleEndian.Uint32(data[in.K:])
+		case bpfAnd:
+			acc &= in.K
+		case bpfJeq, bpfJge:
+			taken := acc == in.K
+			if in.Code == bpfJge {
+				taken = acc >= in.K
+			}
+			if taken {
+				pc += int(in.Jt)
+			} else {
+				pc += int(in.Jf)
+			}
+		case bpfRet:
+			return in.K
+		default:
+			t.Fatalf("unexpected opcode %#x at %d", in.Code, pc)
+		}
+	}
+	t.Fatal("program fell off the end")
+	return 0
+}
+
+func TestCompileSeccomp_Restrictive(t *testing.T) {
+	prog, err := compileSeccomp(assets.SeccompRestrictive, "amd64")
+	if err != nil {
+		t.Fatalf("compileSeccomp() error = %v", err)
+	}
+	amd64 := seccompTargets["amd64"]
+	nr := func(name string) uint32 { return syscallsX86_64[name] }
+	eperm := uint32(retErrno | 1)
+
+	testCases := []struct {
+		name string
+		nr   uint32
+		args [6]uint64
+		want uint32
+	}{
+		{"read allowed", nr("read"), [6]uint64{}, retAllow},
+		{"mount denied", nr("mount"), [6]uint64{}, eperm},
+		{"personality(0) allowed", nr("personality"), [6]uint64{0}, retAllow},
+		{"personality(0xffffffff) allowed", nr("personality"), [6]uint64{0xffffffff}, retAllow},
+		{"personality(1) denied", nr("personality"), [6]uint64{1}, eperm},
+		{"personality with high bits denied", nr("personality"), [6]uint64{1 << 32}, eperm},
+		{"plain clone allowed", nr("clone"), [6]uint64{0x11}, retAllow},
+		{"clone(CLONE_NEWUSER) denied", nr("clone"), [6]uint64{0x10000000}, eperm},
+		{"x32 ABI denied", nr("read") | x32SyscallBit, [6]uint64{}, eperm},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := runFilter(t, prog, amd64.audit, tc.nr, tc.args); got != tc.want {
+				t.Errorf("action = %#x, want %#x", got, tc.want)
+			}
+		})
+	}
+
+	if got := runFilter(t, prog, seccompTargets["arm64"].audit, nr("read"), [6]uint64{}); got != retKillProcess {
+		t.Errorf("foreign architecture action = %#x, want kill", got)
+	}
+}
+
+func TestCompileSeccomp_Arm64(t *testing.T) {
+	prog, err := compileSeccomp(assets.SeccompRestrictive, "arm64")
+	if err != nil {
+		t.Fatalf("compileSeccomp() error = %v", err)
+	}
+	arm64 := seccompTargets["arm64"]
+	if got := runFilter(t, prog, arm64.audit, syscallsAarch64["openat"], [6]uint64{}); got != retAllow {
+		t.Errorf("openat action = %#x, want allow", got)
+	}
+}
+
+func TestCompileSeccomp_Errors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		profile string
+		goarch  string
+		want    string
+	}{
+		{"unknown arch", `{"defaultAction":"SCMP_ACT_ERRNO"}`, "riscv64", "not supported"},
+		{"bad json", `{`, "amd64", "invalid seccomp profile"},
+		{"arch not covered", `{"defaultAction":"SCMP_ACT_ERRNO","architectures":["SCMP_ARCH_AARCH64"]}`, "amd64", "does not cover"},
+		{"unknown action", `{"defaultAction":"SCMP_ACT_NOTIFY"}`, "amd64", "unsupported seccomp action"},
+		{"unsupported op", `{"defaultAction":"SCMP_ACT_ERRNO","syscalls":[{"names":["read"],"action":"SCMP_ACT_ALLOW","args":[{"index":0,"value":1,"op":"SCMP_CMP_GT"}]}]}`, "amd64", "unsupported comparison"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := compileSeccomp([]byte(tc.profile), tc.goarch)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("compileSeccomp() error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompileSeccomp_IncludesExcludes(t *testing.T) {
+	profile := `{"defaultAction":"SCMP_ACT_ERRNO","defaultErrnoRet":38,"syscalls":[
+		{"names":["ptrace"],"action":"SCMP_ACT_ALLOW","includes":{"caps":["CAP_SYS_PTRACE"]}},
+		{"names":["read"],"action":"SCMP_ACT_ALLOW","includes":{"arches":["arm64"]}},
+		{"names":["write"],"action":"SCMP_ACT_ALLOW","excludes":{"arches":["amd64"]}},
+		{"names":["getpid"],"action":"SCMP_ACT_ALLOW","excludes":{"caps":["CAP_SYS_ADMIN"]}}
+	]}`
+	prog, err := compileSeccomp([]byte(profile), "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit := seccompTargets["amd64"].audit
+	enosys := uint32(retErrno | 38)
+
+	for name, want := range map[string]uint32{"ptrace": enosys, "read": enosys, "write": enosys, "getpid": retAllow} {
+		if got := runFilter(t, prog, audit, syscallsX86_64[name], [6]uint64{}); got != want {
+			t.Errorf("%s action = %#x, want %#x", name, got, want)
+		}
+	}
+}
+
+func TestLoadSeccompFilter(t *testing.T) {
+	if prog, err := loadSeccompFilter("unconfined", "amd64"); err != nil || prog != nil {
+		t.Errorf("unconfined = %v, %v; want no filter", prog, err)
+	}
+	for _, profile := range []string{"", "default", "restrictive"} {
+		if prog, err := loadSeccompFilter(profile, "amd64"); err != nil || len(prog) == 0 {
+			t.Errorf("loadSeccompFilter(%q) = %d bytes, %v", profile, len(prog), err)
+		}
+	}
+	if _, err := loadSeccompFilter("/nonexistent/profile.json", "amd64"); err == nil {
+		t.Error("missing profile file should fail")
+	}
+}
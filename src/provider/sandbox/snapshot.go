@@ -0,0 +1,141 @@
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jedi4ever/addt/provider"
+)
+
+// Snapshots copy a persistent environment's home directory, the only state
+// that outlives a run, to ~/.addt/sandbox/snapshots/<name>/<tag>/home.
+
+// snapshotDir returns the directory of a snapshot, or of all snapshots of
+// name when tag is empty
+func snapshotDir(name, tag string) string {
+	return filepath.Join(sandboxDir(), "snapshots", name, tag)
+}
+
+// Snapshot copies the environment's home directory
+func (p *SandboxProvider) Snapshot(name, tag string, checkpoint bool) (*provider.Snapshot, error) {
+	if checkpoint {
+		return nil, fmt.Errorf("sandbox checkpoint: %w", provider.ErrNotSupported)
+	}
+	if err := provider.ValidateSnapshotTag(tag); err != nil {
+		return nil, err
+	}
+	if !p.Exists(name) {
+		return nil, fmt.Errorf("environment %s not found", name)
+	}
+	dir := snapshotDir(name, tag)
+	if _, err := os.Stat(dir); err == nil {
+		return nil, fmt.Errorf("snapshot %s of %s already exists", tag, name)
+	}
+	if err := copyTree(filepath.Join(envDir(name), "home"), filepath.Join(dir, "home")); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return snapshotInfo(name, tag)
+}
+
+// Restore replaces the environment's home directory with a snapshot's,
+// stopping a running sandbox first
+func (p *SandboxProvider) Restore(name, tag string) error {
+	if !strings.HasPrefix(name, "addt-persistent-") {
+		return fmt.Errorf("%s is not a persistent environment; only persistent environments can be restored", name)
+	}
+	if err := provider.ValidateSnapshotTag(tag); err != nil {
+		return err
+	}
+	src := filepath.Join(snapshotDir(name, tag), "home")
+	if _, err := os.Stat(src); err != nil {
+		return fmt.Errorf("snapshot %s of %s not found", tag, name)
+	}
+	if err := p.Stop(name); err != nil {
+		return err
+	}
+
+	// Copy next to the current home first, so a failed copy leaves it intact
+	home := filepath.Join(envDir(name), "home")
+	staged := home + ".restore"
+	os.RemoveAll(staged)
+	if err := copyTree(src, staged); err != nil {
+		os.RemoveAll(staged)
+		return err
+	}
+	if err := os.RemoveAll(home); err != nil {
+		return err
+	}
+	return os.Rename(staged, home)
+}
+
+// ListSnapshots lists the snapshots of an environment, or of all
+// environments when name is empty, oldest first
+func (p *SandboxProvider) ListSnapshots(name string) ([]provider.Snapshot, error) {
+	names := []string{name}
+	if name == "" {
+		entries, err := os.ReadDir(snapshotDir("", ""))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = nil
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+	}
+
+	var snapshots []provider.Snapshot
+	for _, env := range names {
+		entries, err := os.ReadDir(snapshotDir(env, ""))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var envSnapshots []provider.Snapshot
+		for _, entry := range entries {
+			if snapshot, err := snapshotInfo(env, entry.Name()); err == nil {
+				envSnapshots = append(envSnapshots, *snapshot)
+			}
+		}
+		sort.SliceStable(envSnapshots, func(i, j int) bool {
+			return envSnapshots[i].CreatedAt.Before(envSnapshots[j].CreatedAt)
+		})
+		snapshots = append(snapshots, envSnapshots...)
+	}
+	return snapshots, nil
+}
+
+// RemoveSnapshot removes a snapshot
+func (p *SandboxProvider) RemoveSnapshot(name, tag string) error {
+	if err := provider.ValidateSnapshotTag(tag); err != nil {
+		return err
+	}
+	dir := snapshotDir(name, tag)
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("snapshot %s of %s not found", tag, name)
+	}
+	return os.RemoveAll(dir)
+}
+
+// snapshotInfo describes a snapshot directory; its modification time is
+// when the snapshot was taken
+func snapshotInfo(name, tag string) (*provider.Snapshot, error) {
+	stat, err := os.Stat(snapshotDir(name, tag))
+	if err != nil {
+		return nil, err
+	}
+	return &provider.Snapshot{
+		Environment: name,
+		Tag:         tag,
+		Method:      provider.SnapshotCopy,
+		CreatedAt:   stat.ModTime(),
+	}, nil
+}
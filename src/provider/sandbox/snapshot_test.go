@@ -0,0 +1,51 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	t.Setenv("ADDT_HOME", t.TempDir())
+	name := "addt-persistent-myproject-1a2b3c4d"
+	home := filepath.Join(envDir(name), "home")
+	if err := os.MkdirAll(home, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(home, "state"), []byte("before"), 0644)
+	p := &SandboxProvider{}
+
+	if _, err := p.Snapshot(name, "before", false); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if _, err := p.Snapshot(name, "before", false); err == nil {
+		t.Error("a second snapshot with the same tag should fail")
+	}
+	if _, err := p.Snapshot(name, "../escape", false); err == nil {
+		t.Error("an invalid tag should be rejected")
+	}
+
+	os.WriteFile(filepath.Join(home, "state"), []byte("after"), 0644)
+	os.WriteFile(filepath.Join(home, "new"), []byte("x"), 0644)
+	if err := p.Restore(name, "before"); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(home, "state")); string(data) != "before" {
+		t.Errorf("restored state = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(home, "new")); !os.IsNotExist(err) {
+		t.Error("files created after the snapshot should be gone")
+	}
+
+	snapshots, err := p.ListSnapshots("")
+	if err != nil || len(snapshots) != 1 || snapshots[0].Environment != name || snapshots[0].Tag != "before" {
+		t.Fatalf("ListSnapshots() = %+v, %v", snapshots, err)
+	}
+	if err := p.RemoveSnapshot(name, "before"); err != nil {
+		t.Fatalf("RemoveSnapshot() error = %v", err)
+	}
+	if snapshots, _ := p.ListSnapshots(name); len(snapshots) != 0 {
+		t.Errorf("snapshots after removal = %+v", snapshots)
+	}
+}
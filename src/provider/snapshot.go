@@ -0,0 +1,43 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Snapshot methods
+const (
+	// SnapshotCommit saves the environment's filesystem as an image
+	SnapshotCommit = "commit"
+	// SnapshotCheckpoint saves the running processes too, using CRIU
+	SnapshotCheckpoint = "checkpoint"
+	// SnapshotCopy copies the environment's state directory (sandbox)
+	SnapshotCopy = "copy"
+)
+
+// Snapshot is a saved state of an environment
+type Snapshot struct {
+	Environment string    `json:"environment"`
+	Tag         string    `json:"tag"`
+	Method      string    `json:"method"`
+	Image       string    `json:"image,omitempty"` // for image-based snapshots
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// snapshotTagPattern follows the rules for image tags, so tags can be used
+// as such
+var snapshotTagPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// ValidateSnapshotTag checks a snapshot tag is usable by every provider
+func ValidateSnapshotTag(tag string) error {
+	if !snapshotTagPattern.MatchString(tag) {
+		return fmt.Errorf("invalid snapshot tag %q: use letters, digits, '_', '.' and '-' (not leading '.' or '-'), at most 128 characters", tag)
+	}
+	return nil
+}
+
+// DefaultSnapshotTag returns a timestamp tag for a snapshot taken at t
+func DefaultSnapshotTag(t time.Time) string {
+	return t.Format("20060102-150405")
+}
@@ -0,0 +1,123 @@
+package util
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTarPath_RoundTrip(t *testing.T) {
+	src := t.TempDir()
+	os.MkdirAll(filepath.Join(src, "sub"), 0755)
+	os.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(src, "sub", "b.sh"), []byte("b"), 0755)
+
+	var buf bytes.Buffer
+	if err := TarPath(src, "project", &buf); err != nil {
+		t.Fatalf("TarPath() error = %v", err)
+	}
+
+	// Existing directory: extracted inside it
+	dst := t.TempDir()
+	if err := UntarPath(bytes.NewReader(buf.Bytes()), dst); err != nil {
+		t.Fatalf("UntarPath() error = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "project", "sub", "b.sh")); string(data) != "b" {
+		t.Errorf("nested file = %q", data)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "project", "sub", "b.sh")); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("mode not preserved: %v %v", info, err)
+	}
+
+	// Missing destination: the top-level entry takes its name
+	renamed := filepath.Join(t.TempDir(), "copy")
+	if err := UntarPath(bytes.NewReader(buf.Bytes()), renamed); err != nil {
+		t.Fatalf("UntarPath() error = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(renamed, "a.txt")); string(data) != "a" {
+		t.Errorf("renamed copy = %q", data)
+	}
+}
+
+func TestTarPath_SingleFile(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "notes.md")
+	os.WriteFile(src, []byte("hello"), 0600)
+
+	var buf bytes.Buffer
+	if err := TarPath(src, "notes.md", &buf); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(t.TempDir(), "out.md")
+	if err := UntarPath(&buf, dst); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "hello" {
+		t.Errorf("copy = %q", data)
+	}
+}
+
+// hostileArchive builds an archive from (name, type, linkname) entries
+func hostileArchive(t *testing.T, entries ...[3]string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		h := &tar.Header{Name: e[0], Linkname: e[2], Mode: 0644}
+		switch e[1] {
+		case "dir":
+			h.Typeflag = tar.TypeDir
+		case "symlink":
+			h.Typeflag = tar.TypeSymlink
+		default:
+			h.Typeflag = tar.TypeReg
+			h.Size = int64(len("pwned"))
+		}
+		tw.WriteHeader(h)
+		if h.Typeflag == tar.TypeReg {
+			tw.Write([]byte("pwned"))
+		}
+	}
+	tw.Close()
+	return &buf
+}
+
+func TestUntarPath_RejectsEscapes(t *testing.T) {
+	outside := t.TempDir()
+
+	testCases := map[string]*bytes.Buffer{
+		"dot-dot": hostileArchive(t, [3]string{"out/../../evil", "file", ""}),
+		"through symlink": hostileArchive(t,
+			[3]string{"out/", "dir", ""},
+			[3]string{"out/link", "symlink", outside},
+			[3]string{"out/link/evil", "file", ""}),
+	}
+	for name, archive := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dst := t.TempDir()
+			if err := UntarPath(archive, dst); err == nil {
+				t.Error("expected an error")
+			}
+			if _, err := os.Stat(filepath.Join(outside, "evil")); err == nil {
+				t.Error("file written outside the destination")
+			}
+		})
+	}
+
+	// A file entry replaces a symlink instead of writing through it
+	target := filepath.Join(outside, "victim")
+	os.WriteFile(target, []byte("safe"), 0644)
+	dst := t.TempDir()
+	archive := hostileArchive(t,
+		[3]string{"out/", "dir", ""},
+		[3]string{"out/f", "symlink", target},
+		[3]string{"out/f", "file", ""})
+	if err := UntarPath(archive, dst); err != nil {
+		t.Fatalf("UntarPath() error = %v", err)
+	}
+	if data, _ := os.ReadFile(target); !strings.Contains(string(data), "safe") {
+		t.Errorf("wrote through symlink: %q", data)
+	}
+}
@@ -203,6 +203,42 @@ func RunBuildCommandWithEnv(command string, args, env []string) error {
 	return runner.Run()
 }
 
+// RunBuildFunc runs a build whose output is written by fn (e.g. a build
+// streamed from an API instead of a CLI), with the same progress indication
+// as RunBuildCommand
+func RunBuildFunc(fn func(out io.Writer) error) error {
+	br := NewBuildRunner("", nil)
+	br.startTime = time.Now()
+
+	if br.Verbose {
+		return fn(os.Stdout)
+	}
+
+	br.spinner = NewSpinner("Preparing build...")
+	br.spinner.Start()
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		br.processOutput(pr)
+		io.Copy(io.Discard, pr)
+		close(done)
+	}()
+
+	err := fn(pw)
+	pw.Close()
+	<-done
+
+	if err != nil {
+		br.spinner.StopWithError(fmt.Sprintf("Build failed: %v", err))
+		return err
+	}
+
+	elapsed := time.Since(br.startTime).Round(time.Second)
+	br.spinner.StopWithSuccess(fmt.Sprintf("Build completed in %s", elapsed))
+	return nil
+}
+
 // SimpleSpinnerRun runs a command with a simple spinner
 func SimpleSpinnerRun(message string, cmd *exec.Cmd) error {
 	spinner := NewSpinner(message)
@@ -6,3 +6,8 @@ func IsTerminal() bool {
 	// isatty() is implemented in platform-specific files (terminal_unix.go, terminal_windows.go)
 	return isatty(0) && isatty(1)
 }
+
+// IsTerminalFd checks if a file descriptor is a terminal
+func IsTerminalFd(fd int) bool {
+	return isatty(fd)
+}
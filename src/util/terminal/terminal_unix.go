@@ -4,6 +4,9 @@
 package terminal
 
 import (
+	"os"
+	"os/signal"
+
 	"golang.org/x/sys/unix"
 )
 
@@ -21,3 +24,33 @@ func GetTerminalSize() (int, int) {
 	}
 	return int(ws.Col), int(ws.Row)
 }
+
+// MakeRaw puts the terminal on fd into raw mode and returns a function that
+// restores the previous state
+func MakeRaw(fd int) (func(), error) {
+	old, err := unix.IoctlGetTermios(fd, ioctlReadTermios)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := *old
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Oflag &^= unix.OPOST
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlWriteTermios, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		unix.IoctlSetTermios(fd, ioctlWriteTermios, old)
+	}, nil
+}
+
+// NotifyResize relays terminal resize signals (SIGWINCH) to ch
+func NotifyResize(ch chan<- os.Signal) {
+	signal.Notify(ch, unix.SIGWINCH)
+}
@@ -36,3 +36,11 @@ func GetTerminalSize() (int, int) {
 	// For now, return reasonable defaults
 	return 80, 24
 }
+
+// MakeRaw is not supported on Windows; it leaves the console unchanged
+func MakeRaw(fd int) (func(), error) {
+	return func() {}, nil
+}
+
+// NotifyResize is a no-op on Windows (no SIGWINCH)
+func NotifyResize(ch chan<- os.Signal) {}